@@ -0,0 +1,190 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包用生成的 Token 序列检查语法的二义性, 供语言设计者使用.
+//
+// 同一段源码有两条得到 AST 的途径: parser.Parse, 以及把 parser.Fast 的结果推送给 ast.File.
+// 换行, 缩进, 注释和占位的规则分别实现在这两条途径中, 如果它们对同一输入得到不同的 AST 形状,
+// 说明规则存在冲突或者二义性. Search 随机生成输入, 找到冲突后将其最小化.
+package ambig
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Alphabet 是生成输入的缺省符号表, 包含换行, 缩进和注释.
+var Alphabet = []string{
+	"var", "const", "func", "proc", "if", "else", "out",
+	"a", "b", "int", "1", "'s'", "=", "+", ",", "(", ")", "{", "}",
+	"\n", "\t", "// c", "--- c ---",
+}
+
+// Node 是 AST 形状中的一个节点
+type Node struct {
+	Tok   token.Token
+	Kind  ast.Flag // 节点种类, 例如 ast.FDeclaration
+	Depth int
+}
+
+// Shape 返回 file 中非 trivia 节点的形状. trivia 的合并方式只影响排版, 不计入形状.
+func Shape(file *ast.File) []Node {
+	var nodes []Node
+	for _, n := range file.Nodes[1:] {
+		if ast.IsTrivia(n.Token()) {
+			continue
+		}
+		d := 0
+		for p := n.Parent(); p != nil && p.Parent() != nil; p = p.Parent() {
+			d++
+		}
+		nodes = append(nodes, Node{n.Token(), n.Kind(0) & 0x7F, d})
+	}
+	return nodes
+}
+
+// Conflict 是两种途径得到不同结果的输入
+type Conflict struct {
+	Src         string
+	Parse, Fast []Node // 两种途径的 AST 形状
+	ParseErr    error
+	FastErr     error
+}
+
+// String 返回冲突的源码和描述.
+func (c *Conflict) String() string {
+	return strconv.Quote(c.Src) + ": " + c.Reason()
+}
+
+// Reason 返回冲突的描述: 只有一种途径出错时是两者的错误, 否则是首个不同的节点.
+func (c *Conflict) Reason() string {
+	var b strings.Builder
+	switch {
+	case (c.ParseErr == nil) != (c.FastErr == nil):
+		b.WriteString("Parse error: " + errString(c.ParseErr) + ", Fast error: " + errString(c.FastErr))
+	default:
+		i := 0
+		for i < len(c.Parse) && i < len(c.Fast) && c.Parse[i] == c.Fast[i] {
+			i++
+		}
+		b.WriteString("node " + strconv.Itoa(i) + ": Parse " + nodeString(c.Parse, i) + ", Fast " + nodeString(c.Fast, i))
+	}
+	return b.String()
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "none"
+	}
+	return err.Error()
+}
+
+func nodeString(nodes []Node, i int) string {
+	if i >= len(nodes) {
+		return "end"
+	}
+	n := nodes[i]
+	return n.Tok.String() + "@" + strconv.Itoa(n.Depth)
+}
+
+// Check 返回 src 的冲突, 两种途径一致时返回 nil. 两种途径都出错不算冲突.
+func Check(src string) *Conflict {
+	c := &Conflict{Src: src}
+
+	file := ast.NewFile()
+	c.ParseErr = parser.Parse([]byte(src), file)
+	c.Parse = Shape(file)
+
+	file = ast.NewFile()
+	syms, err := parser.Fast([]byte(src), nil)
+	for i := 0; err == nil && i < len(syms); i++ {
+		// Parse 不推送 EOF
+		if syms[i].Tok != token.EOF {
+			err = file.Push(syms[i].Pos, syms[i].Tok, syms[i].Source)
+		}
+	}
+	c.FastErr = err
+	c.Fast = Shape(file)
+
+	switch {
+	case c.ParseErr != nil && c.FastErr != nil:
+		return nil
+	case c.ParseErr != nil || c.FastErr != nil:
+		return c
+	}
+	if len(c.Parse) != len(c.Fast) {
+		return c
+	}
+	for i := range c.Parse {
+		if c.Parse[i] != c.Fast[i] {
+			return c
+		}
+	}
+	return nil
+}
+
+// Render 把符号序列连接为源码, 换行和缩进两侧不加空格.
+func Render(syms []string) string {
+	var b strings.Builder
+	for i, s := range syms {
+		if i != 0 && !layout(s) && !layout(syms[i-1]) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+func layout(s string) bool { return s == "\n" || s == "\t" }
+
+// Minimize 删除 syms 中的符号, 直到再删除任何一个符号都不再产生冲突.
+func Minimize(syms []string) []string {
+	for changed := true; changed; {
+		changed = false
+		for i := 0; i < len(syms); i++ {
+			try := append(append([]string(nil), syms[:i]...), syms[i+1:]...)
+			if Check(Render(try)) != nil {
+				syms, changed = try, true
+				i--
+			}
+		}
+	}
+	return syms
+}
+
+// Search 用 rng 生成 n 个最多 size 个符号的输入, 返回最小化之后的冲突, 每种 Reason 只保留最短的一个.
+// alphabet 为 nil 时使用 Alphabet.
+func Search(rng *rand.Rand, n, size int, alphabet []string) []*Conflict {
+	if alphabet == nil {
+		alphabet = Alphabet
+	}
+	seen := map[string]int{} // Reason 到 found 中的序号
+	var found []*Conflict
+	syms := make([]string, 0, size)
+	for i := 0; i < n; i++ {
+		syms = syms[:0]
+		for j := rng.Intn(size) + 1; j > 0; j-- {
+			syms = append(syms, alphabet[rng.Intn(len(alphabet))])
+		}
+		if Check(Render(syms)) == nil {
+			continue
+		}
+		c := Check(Render(Minimize(append([]string(nil), syms...))))
+		if c == nil {
+			continue
+		}
+		if j, ok := seen[c.Reason()]; !ok {
+			seen[c.Reason()] = len(found)
+			found = append(found, c)
+		} else if len(c.Src) < len(found[j].Src) {
+			found[j] = c
+		}
+	}
+	return found
+}
@@ -0,0 +1,41 @@
+package ambig_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ambig"
+)
+
+func TestCheck(t *testing.T) {
+	for _, src := range []string{"", "var a = 1\n", "note\nvar a = (1 + b)\n", "var\n)", "var a\nb"} {
+		if c := ambig.Check(src); c != nil {
+			t.Fatal(c)
+		}
+	}
+	if c := ambig.Check("var\na"); c == nil {
+		t.Fatal("want conflict")
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	syms := ambig.Minimize([]string{"var", "a", "=", "1", "+", "b", "\n", "c", "c"})
+	src := ambig.Render(syms)
+	if ambig.Check(src) == nil || len(syms) > 4 {
+		t.Fatalf("%q", src)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	found := ambig.Search(rand.New(rand.NewSource(1)), 500, 8, nil)
+	if len(found) == 0 {
+		t.Fatal("want conflicts")
+	}
+	seen := map[string]bool{}
+	for _, c := range found {
+		if seen[c.Reason()] {
+			t.Fatal("duplicate", c)
+		}
+		seen[c.Reason()] = true
+	}
+}
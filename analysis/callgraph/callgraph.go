@@ -0,0 +1,141 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包从一个包的 parser.ParseSyntax 结果构建 func, proc 的调用图,
+// 并查询从入口可达的函数, 用于报告不可达的函数和删除无用的函数.
+//
+// 目前没有类型检查, 调用由 index.Idents 按名字解析: 函数体中引用同一个包的顶层函数,
+// 无论是调用还是作为值传递, 都是一条边. 这是保守的, 只会多算可达的函数.
+// 顶层 var 等声明的初始值中引用的函数总是可达的.
+package callgraph
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/index"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// File 是包中的一个文件
+type File struct {
+	Name   string
+	Syntax *ast.SourceFile
+}
+
+// Node 是一个 func 或 proc 声明
+type Node struct {
+	Name string
+	File string
+	Decl *ast.FuncDecl
+	Out  []*Edge // 按位置排序的调用
+	In   []*Edge // 被调用, 按调用者的文件和位置排序
+}
+
+// Edge 是 Caller 中位于 Pos 的对 Callee 的引用, Caller 为 nil 表示顶层声明的初始值.
+type Edge struct {
+	Caller *Node
+	Callee *Node
+	File   string
+	Pos    scanner.Pos // 在 File 中的偏移量
+}
+
+// Graph 是调用图
+type Graph struct {
+	Nodes []*Node          // 按文件名和位置排序
+	Init  []*Edge          // 顶层声明的初始值中的引用
+	names map[string]*Node // 同名的声明只取第一个
+}
+
+// New 返回 files 的调用图
+func New(files []File) *Graph {
+	files = append([]File(nil), files...)
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	g := &Graph{names: map[string]*Node{}}
+	idents := make([][]index.Ident, len(files))
+	for i, f := range files {
+		idents[i] = index.Idents(f.Syntax)
+		for _, d := range f.Syntax.Decls {
+			if fn, ok := d.(*ast.FuncDecl); ok && g.names[fn.Name.Name.Source] == nil {
+				n := &Node{Name: fn.Name.Name.Source, File: f.Name, Decl: fn}
+				g.Nodes = append(g.Nodes, n)
+				g.names[n.Name] = n
+			}
+		}
+	}
+
+	for i, f := range files {
+		for _, id := range idents[i] {
+			callee := g.names[id.Name]
+			if id.Decl || callee == nil || id.Scope != index.Package && id.Scope != index.Free {
+				continue
+			}
+			e := &Edge{Callee: callee, File: f.Name, Pos: id.Pos}
+			if e.Caller = g.enclosing(f.Name, id.Pos); e.Caller == nil {
+				g.Init = append(g.Init, e)
+			} else {
+				e.Caller.Out = append(e.Caller.Out, e)
+			}
+			callee.In = append(callee.In, e)
+		}
+	}
+	return g
+}
+
+// enclosing 返回文件 name 中包含 pos 的函数
+func (g *Graph) enclosing(name string, pos scanner.Pos) *Node {
+	for _, n := range g.Nodes {
+		if n.File == name && n.Decl.Pos() <= pos && pos < n.Decl.End() {
+			return n
+		}
+	}
+	return nil
+}
+
+// Lookup 返回名为 name 的函数, 没有时返回 nil.
+func (g *Graph) Lookup(name string) *Node {
+	return g.names[name]
+}
+
+// Entry 是默认的入口: main, pub 函数以及测试声明.
+func Entry(n *Node) bool {
+	return n.Name == "main" || n.Decl.Pub >= 0 || strings.HasPrefix(n.Name, ast.TestPrefix)
+}
+
+// Reachable 返回从满足 entry 的函数和 Init 可达的函数.
+func (g *Graph) Reachable(entry func(*Node) bool) map[*Node]bool {
+	seen := map[*Node]bool{}
+	var visit func(n *Node)
+	visit = func(n *Node) {
+		if seen[n] {
+			return
+		}
+		seen[n] = true
+		for _, e := range n.Out {
+			visit(e.Callee)
+		}
+	}
+	for _, e := range g.Init {
+		visit(e.Callee)
+	}
+	for _, n := range g.Nodes {
+		if entry(n) {
+			visit(n)
+		}
+	}
+	return seen
+}
+
+// Unreachable 返回从满足 entry 的函数和 Init 不可达的函数, 按文件名和位置排序.
+func (g *Graph) Unreachable(entry func(*Node) bool) (nodes []*Node) {
+	seen := g.Reachable(entry)
+	for _, n := range g.Nodes {
+		if !seen[n] {
+			nodes = append(nodes, n)
+		}
+	}
+	return
+}
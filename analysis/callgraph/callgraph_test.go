@@ -0,0 +1,50 @@
+package callgraph_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/analysis/callgraph"
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func parse(t *testing.T, src string) *ast.SourceFile {
+	sf, err := parser.ParseSyntax([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sf
+}
+
+func TestGraph(t *testing.T) {
+	g := callgraph.New([]callgraph.File{
+		{"b.zxx", parse(t, "proc helper [\n\tleaf()\n]\nproc leaf [\n]\nproc dead [\n\tdead()\n\tleaf()\n]\n")},
+		{"a.zxx", parse(t, "var int x = init()\nproc main [\n\thelper()\n]\nfunc init out int [\n\tout 1\n]\nproc other(int leaf) [\n\tleaf = 1\n]\n")},
+	})
+
+	var names []string
+	for _, n := range g.Nodes {
+		names = append(names, n.File+":"+n.Name)
+	}
+	if got := len(names); got != 6 || names[0] != "a.zxx:main" || names[3] != "b.zxx:helper" {
+		t.Fatal(names)
+	}
+	if leaf := g.Lookup("leaf"); len(leaf.In) != 2 || leaf.In[0].Caller.Name != "helper" || leaf.In[1].Caller.Name != "dead" {
+		t.Fatal(leaf.In)
+	}
+	// 参数 leaf 遮蔽了函数 leaf
+	if other := g.Lookup("other"); len(other.Out) != 0 {
+		t.Fatal(other.Out)
+	}
+	if len(g.Init) != 1 || g.Init[0].Callee.Name != "init" {
+		t.Fatal(g.Init)
+	}
+
+	var dead []string
+	for _, n := range g.Unreachable(callgraph.Entry) {
+		dead = append(dead, n.Name)
+	}
+	if len(dead) != 2 || dead[0] != "other" || dead[1] != "dead" {
+		t.Fatal(dead)
+	}
+}
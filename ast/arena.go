@@ -0,0 +1,161 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "sync"
+
+// arenaBlock 是 Arena 每次分配的同类节点个数
+const arenaBlock = 128
+
+// Arena 按块分配一个 File 的节点, 每块一次分配代替每个节点一次分配, 减少 GC 的压力.
+// Arena 来自池, 由 NewArenaFile 取得, 由 File.Release 归还, 归还后块和 Nodes 的内存被复用.
+type Arena struct {
+	decls  [][]Decl
+	chunks [][]Chunk
+	stmts  [][]Stmt
+	exprs  [][]Expr
+	texts  [][]Text
+	n      [5]int // 以上各类已分配的节点个数
+	nodes  []Node // 复用的 File.Nodes
+}
+
+var arenas = sync.Pool{New: func() interface{} { return new(Arena) }}
+
+// NewArenaFile 同 NewFile, 但节点从池中的 Arena 分配. 适合解析大量文件的长期运行的服务,
+// 文件不再使用时 (例如被逐出缓存) 调用 Release.
+func NewArenaFile() *File {
+	a := arenas.Get().(*Arena)
+	file := newFile(a.nodes)
+	file.arena = a
+	return file
+}
+
+// Release 把 NewArenaFile 创建的 b 的 Arena 归还到池, 之后 b 及其节点都不能再使用,
+// 包括之前取得的 Node. 快照复制了节点, 不受影响. 对 NewFile 创建的 File 无效果.
+func (b *File) Release() {
+	a := b.arena
+	if a == nil {
+		return
+	}
+	// 清除节点, 使源码字符串和 File 可以被回收
+	for i := range b.Nodes {
+		b.Nodes[i] = nil
+	}
+	a.nodes = b.Nodes[:0]
+	a.reset()
+	b.arena, b.Nodes, b.index = nil, nil, nil
+	b.Active, b.Last = b, b
+	arenas.Put(a)
+}
+
+func (a *Arena) reset() {
+	for i, blocks := range a.decls {
+		for j := range blocks[:used(a.n[0], i)] {
+			blocks[j] = Decl{}
+		}
+	}
+	for i, blocks := range a.chunks {
+		for j := range blocks[:used(a.n[1], i)] {
+			blocks[j] = Chunk{}
+		}
+	}
+	for i, blocks := range a.stmts {
+		for j := range blocks[:used(a.n[2], i)] {
+			blocks[j] = Stmt{}
+		}
+	}
+	for i, blocks := range a.exprs {
+		for j := range blocks[:used(a.n[3], i)] {
+			blocks[j] = Expr{}
+		}
+	}
+	for i, blocks := range a.texts {
+		for j := range blocks[:used(a.n[4], i)] {
+			blocks[j] = Text{}
+		}
+	}
+	a.n = [5]int{}
+}
+
+// used 返回共分配了 n 个节点时第 i 块中已使用的个数
+func used(n, i int) int {
+	n -= i * arenaBlock
+	if n < 0 {
+		return 0
+	}
+	if n > arenaBlock {
+		return arenaBlock
+	}
+	return n
+}
+
+// node 返回 Flag 为 base.Flag 的新节点, a 为 nil 时在堆上分配, 类型不明时返回 nil
+func (a *Arena) node(base Base) Node {
+	switch base.Flag & 0x7F {
+	case FDeclaration:
+		if a == nil {
+			return &Decl{base}
+		}
+		i := a.next(0)
+		if i/arenaBlock == len(a.decls) {
+			a.decls = append(a.decls, make([]Decl, arenaBlock))
+		}
+		n := &a.decls[i/arenaBlock][i%arenaBlock]
+		n.Base = base
+		return n
+	case FChunk:
+		if a == nil {
+			return &Chunk{base}
+		}
+		i := a.next(1)
+		if i/arenaBlock == len(a.chunks) {
+			a.chunks = append(a.chunks, make([]Chunk, arenaBlock))
+		}
+		n := &a.chunks[i/arenaBlock][i%arenaBlock]
+		n.Base = base
+		return n
+	case FStatement:
+		if a == nil {
+			return &Stmt{base}
+		}
+		i := a.next(2)
+		if i/arenaBlock == len(a.stmts) {
+			a.stmts = append(a.stmts, make([]Stmt, arenaBlock))
+		}
+		n := &a.stmts[i/arenaBlock][i%arenaBlock]
+		n.Base = base
+		return n
+	case FExpression:
+		if a == nil {
+			return &Expr{base}
+		}
+		i := a.next(3)
+		if i/arenaBlock == len(a.exprs) {
+			a.exprs = append(a.exprs, make([]Expr, arenaBlock))
+		}
+		n := &a.exprs[i/arenaBlock][i%arenaBlock]
+		n.Base = base
+		return n
+	case FText:
+		if a == nil {
+			return &Text{base}
+		}
+		i := a.next(4)
+		if i/arenaBlock == len(a.texts) {
+			a.texts = append(a.texts, make([]Text, arenaBlock))
+		}
+		n := &a.texts[i/arenaBlock][i%arenaBlock]
+		n.Base = base
+		return n
+	}
+	return nil
+}
+
+// next 返回第 k 类的下一个节点的序号
+func (a *Arena) next(k int) int {
+	i := a.n[k]
+	a.n[k]++
+	return i
+}
@@ -0,0 +1,51 @@
+package ast_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestArena(t *testing.T) {
+	src := []byte("var x = 1\n" + strings.Repeat("proc f [\n\tout x + [1, 2][0]\n]\n", 100))
+	heap := NewFile()
+	if err := parser.Parse(src, heap); err != nil {
+		t.Fatal(err)
+	}
+	c := &parser.Config{Mode: parser.ParseComments | parser.ParsePlaceholders, Arena: true}
+	var snap *File
+	for round := 0; round < 3; round++ {
+		file, err := c.Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Len() != heap.Len() || file.Len() < 1000 {
+			t.Fatal(round, file.Len(), heap.Len())
+		}
+		for i, n := range file.Nodes {
+			h := heap.Nodes[i]
+			if n.Token() != h.Token() || n.Text() != h.Text() || n.Kind(0) != h.Kind(0) ||
+				i != 0 && n.Parent().Id() != h.Parent().Id() {
+				t.Fatal(round, i, n.Text(), h.Text())
+			}
+		}
+		if snap == nil {
+			snap = file.Snapshot()
+		}
+		file.Release()
+		if file.Len() != 0 {
+			t.Fatal(file.Len())
+		}
+		file.Release()
+	}
+	// 快照复制了节点, 不受 Release 影响
+	if snap.Len() != heap.Len() || snap.Nodes[5].Text() != heap.Nodes[5].Text() {
+		t.Fatal(snap.Len())
+	}
+	heap.Release()
+	if heap.Len() != snap.Len() {
+		t.Fatal(heap.Len())
+	}
+}
@@ -52,6 +52,10 @@ type (
 		// 如果到了顶层, 返回 nil
 		Prev() Node
 
+		// Parent 返回包含该节点的容器节点, 即 File, Decl, Chunk 或 Stmt.
+		// File 的 Parent 为 nil.
+		Parent() Node
+
 		// Final 设置 FFinal 状态
 		Final() error
 
@@ -80,6 +84,9 @@ type (
 
 		Pos scanner.Pos
 
+		// Origin 是节点首个 Token 的来源
+		Origin Origin
+
 		// 上个节点序号
 		prev int
 
@@ -101,6 +108,13 @@ type (
 		Active Node // 活动节点
 		Last   Node // 最后的节点
 		expect Rule
+		origin Origin // PushSymbol 正在推送的 Token 来源
+		index  []int  // 按 Pos 排序的节点 Id, 参见 sortIndex
+		frozen bool   // 只读快照, 参见 Snapshot
+		arena  *Arena // 节点的分配器, 参见 NewArenaFile
+
+		// Version 是该文件的语言版本, 由 parser.Config.Parse 设置
+		Version string
 	}
 
 	// Decl 可包括声明语句 IsDeclare.
@@ -110,7 +124,8 @@ type (
 		Base
 	}
 
-	// Chunk 视觉上的块, Token 只能是 Left 或者 Right
+	// Chunk 视觉上的块, Token 只能是 LEFT, RIGHT, INTERPBEGIN 或者 INTERPEND.
+	// 插值字符串中的表达式是 INTERPBEGIN 的子节点.
 	Chunk struct {
 		Base
 	}
@@ -153,8 +168,7 @@ func (b Base) Prev() Node {
 	}
 
 	n := b.all.Nodes[b.Index-1]
-	tok := n.Token()
-	for n.Id() > 0 && tok > token.IDENT {
+	for n.Id() > 0 && n.Token() > token.IDENT {
 		n = b.all.Nodes[n.Id()-1]
 	}
 	if n.Id() == 0 {
@@ -164,13 +178,33 @@ func (b Base) Prev() Node {
 	return n
 }
 
+func (b Base) Parent() Node {
+	if b.Index == 0 {
+		return nil
+	}
+	return b.all.Nodes[b.prev]
+}
+
+// IsTrivia 返回 tok 是否为不影响语义的 Token, 即换行, 缩进, 占位, 注释.
+func IsTrivia(tok token.Token) bool {
+	return tok == token.NL || tok > token.IDENT
+}
+
 // ------------------- File -------------------
 
 func NewFile() (file *File) {
+	return newFile(nil)
+}
+
+// newFile 返回以 nodes 为 Nodes 的底层数组的 File, nodes 为空时新分配
+func newFile(nodes []Node) (file *File) {
 	file = new(File)
 	file.Flag = FFile
 	file.Tok = token.EOF
-	file.Nodes = make([]Node, 0, 1024)
+	file.Nodes = nodes[:0]
+	if cap(file.Nodes) == 0 {
+		file.Nodes = make([]Node, 0, 1024)
+	}
 	file.Nodes = append(file.Nodes, file)
 	file.Last = file
 	file.Active = file
@@ -179,43 +213,72 @@ func NewFile() (file *File) {
 
 func (b *File) Len() int { return len(b.Nodes) }
 
+// complete 返回上文至此换行是否可形成完整的语句.
+// 上一个干净节点是运算符, 赋值, 逗号, 点或者声明保留字时, 换行只是排版.
+func (b *File) complete() bool {
+	n := b.Last
+	for n.Id() > 0 && IsTrivia(n.Token()) {
+		n = b.Nodes[n.Id()-1]
+	}
+	tok := n.Token()
+	switch {
+	case n.Id() == 0, tok == token.LEFT, tok == token.INTERPBEGIN, tok == token.COMMA, tok == token.DOT,
+		tok == token.ASSIGN, tok.As(token.Operator), tok.As(token.Declare):
+		return false
+	}
+	return true
+}
+
+// close 结束所有活动的 Decl, Stmt 节点, 使 Active 回到 Chunk 或 File.
+func (b *File) close() (err error) {
+	for err == nil && b.Active.Kind(FDeclaration|FStatement) != 0 {
+		err = b.Active.Final()
+		b.Active = b.Active.Parent()
+	}
+	return
+}
+
 // file.add 做最后的检查, 并根据 Token, Flag 设置 Last, Active.
+//
+// Active 总是最内层未完结的容器节点: File, Decl, Chunk(LEFT) 或 Stmt.
+// 完整语句之后的 NL 结束活动的 Decl, Stmt; RIGHT 结束最近的 LEFT.
 func (b *File) add(base Base) (err error) {
 	var n, part Node
 
 	// NL 自动 Final
-	if base.Tok == token.NL {
-		last := b.Active.Token()
-		if last == token.RIGHT || last == token.IDENT ||
-			last.As(token.Literal) {
-
-			err = b.Active.Final()
-			if err != nil {
-				return err
-			}
-			base.Flag |= FFinal
+	if base.Tok == token.NL && b.complete() {
+		if err = b.close(); err != nil {
+			return err
+		}
+		base.Flag |= FFinal
+	}
+
+	// 分组自动结束
+	if base.Tok == token.RIGHT || base.Tok == token.INTERPEND {
+		if err = b.close(); err != nil {
+			return err
+		}
+		part = b.Active
+		if base.Tok == token.RIGHT && part.Token() != token.LEFT ||
+			base.Tok == token.INTERPEND && part.Token() != token.INTERPBEGIN {
+			return &PushError{base.Pos, base.Tok, RuleName(part), closing(part)}
 		}
+		base.Flag |= FFinal
 	}
 
 	base.Index = b.Len()
 	base.all = b
+	if base.Origin == OriginSource {
+		base.Origin = b.origin
+	}
 	base.prev = b.Active.Id()
 	base.Flag |= b.Active.Kind(StyleMask)
 
-	switch base.Flag & 0x7F {
-	case FDeclaration:
-		n = &Decl{base}
-	case FChunk:
-		n = &Chunk{base}
-	case FStatement:
-		n = &Stmt{base}
-	case FExpression:
-		n = &Expr{base}
-	case FText:
-		n = &Text{base}
-	}
+	n = b.arena.node(base)
 
-	if n == nil || n.Token() > token.PLACEHOLDER {
+	// 只有 Text 可以是注释, 空行
+	if n == nil || n.Token() > token.PLACEHOLDER &&
+		(n.Kind(FText) == 0 || n.Token() != token.COMMENT && n.Token() != token.EMPTYLINE) {
 		err = errors.New("ast: Oop! invalid Base")
 		return
 	}
@@ -223,30 +286,14 @@ func (b *File) add(base Base) (err error) {
 	b.Last = n
 	b.Nodes = append(b.Nodes, n)
 
-	// 分组自动结束
-	if base.Tok == token.RIGHT {
-		for part = b.Prev(); part != nil; part = part.Prev() {
-			if part.Token() == token.LEFT && part.Kind(FFinal) == 0 {
-				if err = n.Final(); err == nil {
-					err = part.Final()
-				}
-				if err != nil {
-					return
-				}
-				break
-			}
-		}
-
-		if part.Kind(FFinal) == 0 {
-			return errors.New("ast: Oop! Unpaired LEFT and RIGHT")
-		}
-
-		if b.Active = part.Prev(); b.Active == nil {
-			b.Active = b
-			return
-		}
+	switch {
+	case part != nil:
 		// 需要检查 =LEFT 的右侧
-		return
+		if err = part.Final(); err == nil {
+			b.Active = part.Parent()
+		}
+	case base.Tok == token.LEFT, base.Tok == token.INTERPBEGIN, base.Flag&(FDeclaration|FStatement) != 0:
+		b.Active = n
 	}
 
 	return
@@ -287,8 +334,11 @@ func (b *Expr) Final() error {
 // 解决所有的 PLACEHOLDER, INDENTATION, COMMENT, NL, EOF.
 // 并合并多个空行为 EMPTYLINE
 
-// File.Push 接收扫描到的 Token,
+// File.Push 接收扫描到的 Token, 拒绝时返回 *PushError.
 func (b *File) Push(pos scanner.Pos, tok token.Token, code string) error {
+	if b.frozen {
+		return errFrozen
+	}
 	var flag Flag
 	switch tok {
 	case token.NL:
@@ -310,20 +360,8 @@ func (b *File) Push(pos scanner.Pos, tok token.Token, code string) error {
 			return nil
 		}
 
-		last = b.Active.Token()
-		if last == token.RIGHT || last == token.IDENT ||
-			last.As(token.Literal) {
-
-			err := b.Active.Final()
-			if err != nil {
-				return err
-			}
-			flag |= FFinal
-		}
-
 		// 识别 Python 缩进风格
 
-	case token.RIGHT:
 	case token.COMMENT, token.COMMENTS:
 		flag = FText
 
@@ -339,13 +377,13 @@ func (b *File) Push(pos scanner.Pos, tok token.Token, code string) error {
 		// 统一处理右括号闭合
 		flag = FText
 	default:
-		if tok > token.PLACEHOLDER || b.Active.Kind(FFile|FBlock|FText) != 0 {
-			return errors.New("ast: Oop! invalid " + tok.String())
+		if tok > token.PLACEHOLDER || b.Active.Kind(FBlock|FText) != 0 {
+			return b.reject(pos, tok)
 		}
 
 		if b.expect != nil {
 			// expect 尝试生成 Text 节点, 并且 tok 不变
-			if _, pass := b.expect.Eat(tok); pass {
+			if _, pass := b.expect.Eat(tok); pass && tok != token.LEFT && tok != token.RIGHT {
 				flag = FText
 				b.expect = nil
 				break
@@ -361,7 +399,7 @@ func (b *File) Push(pos scanner.Pos, tok token.Token, code string) error {
 
 		b.Active.resolve(&base)
 		if base.Flag == 0 {
-			return errors.New("ast: Oop! invalid " + tok.String())
+			return b.reject(pos, tok)
 		}
 		return b.add(base)
 
@@ -370,7 +408,7 @@ func (b *File) Push(pos scanner.Pos, tok token.Token, code string) error {
 	}
 
 	if flag == 0 {
-		return errors.New("ast: Oop! invalid " + tok.String())
+		return b.reject(pos, tok)
 	}
 
 	return b.add(Base{
@@ -499,15 +537,37 @@ func (b *Decl) resolve(base *Base) {
 				token.VAR, token.CONST, token.STATIC}
 		}
 	}
+	if flag == 0 {
+		flag = contain(tok, false)
+	}
 	base.Flag = flag
 	return
 }
 
+// contain 返回容器节点中 tok 的缺省 Flag.
+// 声明头部不产生语句, 其中的 Statement 保留字被当做 Text.
+func contain(tok token.Token, stmt bool) Flag {
+	switch {
+	case tok == token.LEFT, tok == token.RIGHT,
+		tok == token.INTERPBEGIN, tok == token.INTERPEND:
+		return FChunk
+	case tok.As(token.Declare):
+		return FDeclaration
+	case stmt && tok.As(token.Statement):
+		return FStatement
+	}
+	return FText
+}
+
+// Chunk 可包括声明, 语句
 func (b *Chunk) resolve(base *Base) {
+	base.Flag = contain(base.Tok, true)
 	return
 }
 
+// Stmt 可包括子语句, 比如 else if
 func (b *Stmt) resolve(base *Base) {
+	base.Flag = contain(base.Tok, true)
 	return
 }
 
@@ -0,0 +1,138 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包改写 parser.ParseSyntax, parser.ParseExpr 产生的类型化语法树,
+// 用于检查的自动修复和源码迁移.
+//
+// Apply 遍历并就地替换, 删除节点. Match 按模式匹配节点, 模式本身也是语法树,
+// 通常由 parser.ParseExpr 解析, 例如把废弃的内置函数 oldlen(x) 改写为 len(x):
+//
+//	pattern, _ := parser.ParseExpr([]byte("oldlen(x)"))
+//	astutil.Apply(file, nil, func(c *astutil.Cursor) bool {
+//		if astutil.Match(pattern, c.Node(), astutil.Bindings{}) {
+//			call := c.Node().(*ast.CallExpr)
+//			call.Fun = &ast.Ident{Name: ast.Symbol{Pos: call.Fun.Pos(), Tok: token.IDENT, Source: "len"}}
+//		}
+//		return true
+//	})
+//
+// 改写之后可以用节点的 Pos, End 生成 edit.Edit 修改源码.
+package astutil
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ZxxLang/zxx/ast"
+)
+
+// ApplyFunc 在 Apply 遍历时被调用, 参见 Apply.
+type ApplyFunc func(*Cursor) bool
+
+// Cursor 是 Apply 遍历中的当前节点及其在父节点中的位置
+type Cursor struct {
+	parent ast.Syntax
+	name   string
+	index  int
+	field  reflect.Value // 保存当前节点的字段或者切片元素
+	node   ast.Syntax
+	delete bool
+}
+
+// Node 返回当前节点
+func (c *Cursor) Node() ast.Syntax { return c.node }
+
+// Parent 返回当前节点的父节点, 根节点的父节点是 nil.
+func (c *Cursor) Parent() ast.Syntax { return c.parent }
+
+// Name 返回父节点中保存当前节点的字段名, 例如 "Body", "Args", 根节点为空.
+func (c *Cursor) Name() string { return c.name }
+
+// Index 返回当前节点在切片字段中的序号, 不在切片中时返回 -1.
+func (c *Cursor) Index() int { return c.index }
+
+// Replace 用 n 替换当前节点, n 的类型不能保存在该字段中时 panic.
+func (c *Cursor) Replace(n ast.Syntax) {
+	v := reflect.ValueOf(n)
+	if n == nil || !v.Type().AssignableTo(c.field.Type()) {
+		panic(fmt.Sprintf("astutil: cannot replace %T with %T in %s", c.node, n, c.name))
+	}
+	c.field.Set(v)
+	c.node = n
+}
+
+// Delete 从切片字段中删除当前节点, 当前节点不在切片中时 panic.
+func (c *Cursor) Delete() {
+	if c.index < 0 {
+		panic(fmt.Sprintf("astutil: cannot delete %T in %s", c.node, c.name))
+	}
+	c.delete = true
+}
+
+var syntaxType = reflect.TypeOf((*ast.Syntax)(nil)).Elem()
+
+// abort 中止 Apply 的遍历
+type abort struct{}
+
+// Apply 深度优先遍历 root, 对每个非 nil 的节点先调用 pre, 再遍历子节点, 最后调用 post.
+// pre, post 可以为 nil. pre 返回 false 时跳过该节点的子节点和 post,
+// post 返回 false 时中止遍历. pre 替换节点之后遍历的是新节点的子节点.
+// 返回的是遍历之后的根节点, 根节点可能被替换.
+func Apply(root ast.Syntax, pre, post ApplyFunc) (result ast.Syntax) {
+	holder := &struct{ Root ast.Syntax }{root}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(abort); !ok {
+				panic(r)
+			}
+		}
+		result = holder.Root
+	}()
+	a := &application{pre: pre, post: post}
+	a.apply(nil, "", -1, reflect.ValueOf(holder).Elem().Field(0))
+	return
+}
+
+type application struct {
+	pre, post ApplyFunc
+}
+
+// apply 遍历保存在 field 中的节点, 返回节点是否被删除
+func (a *application) apply(parent ast.Syntax, name string, index int, field reflect.Value) bool {
+	if field.IsNil() {
+		return false
+	}
+	c := &Cursor{
+		parent: parent,
+		name:   name,
+		index:  index,
+		field:  field,
+		node:   field.Interface().(ast.Syntax),
+	}
+	if a.pre != nil && !a.pre(c) || c.delete {
+		return c.delete
+	}
+
+	n := c.node
+	v := reflect.ValueOf(n).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f, name := v.Field(i), v.Type().Field(i).Name
+		switch {
+		case f.Type().Implements(syntaxType):
+			a.apply(n, name, -1, f)
+		case f.Kind() == reflect.Slice && f.Type().Elem().Implements(syntaxType):
+			for j := 0; j < f.Len(); j++ {
+				if a.apply(n, name, j, f.Index(j)) {
+					f.Set(reflect.AppendSlice(f.Slice(0, j), f.Slice(j+1, f.Len())))
+					j--
+				}
+			}
+		}
+	}
+
+	if a.post != nil && !a.post(c) {
+		panic(abort{})
+	}
+	return c.delete
+}
@@ -0,0 +1,127 @@
+package astutil_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/astutil"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+const src = `proc main() [
+	x = oldlen(a) + oldlen(b + 1)
+	debug(x)
+	print(x)
+]
+`
+
+func expr(t *testing.T, s string) ast.Expression {
+	x, err := parser.ParseExpr([]byte(s))
+	if err != nil {
+		t.Fatal(s, err)
+	}
+	return x
+}
+
+func TestApply(t *testing.T) {
+	file, err := parser.ParseSyntax([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pattern := expr(t, "oldlen(x)")
+	debug := expr(t, "debug(x)")
+
+	var args []string
+	astutil.Apply(file, func(c *astutil.Cursor) bool {
+		if s, ok := c.Node().(*ast.ExprStmt); ok && astutil.Match(debug, s.X, astutil.Bindings{}) {
+			if c.Name() != "List" || c.Index() != 1 {
+				t.Errorf("debug at %s[%d]", c.Name(), c.Index())
+			}
+			c.Delete()
+		}
+		return true
+	}, func(c *astutil.Cursor) bool {
+		b := astutil.Bindings{}
+		if astutil.Match(pattern, c.Node(), b) {
+			x := b["x"].(ast.Expression)
+			args = append(args, src[x.Pos():x.End()])
+			call := c.Node().(*ast.CallExpr)
+			c.Replace(&ast.CallExpr{
+				Fun:    &ast.Ident{Name: ast.Symbol{Tok: token.IDENT, Source: "len"}},
+				Lparen: call.Lparen,
+				Args:   call.Args,
+				Rparen: call.Rparen,
+			})
+		}
+		return true
+	})
+
+	if len(args) != 2 || args[0] != "a" || args[1] != "b + 1" {
+		t.Fatalf("args = %q", args)
+	}
+	body := file.Decls[0].(*ast.FuncDecl).Body.List
+	if len(body) != 2 {
+		t.Fatalf("body has %d statements", len(body))
+	}
+	want := expr(t, "len(a) + len(b + 1)")
+	if !astutil.Match(want, body[0].(*ast.AssignStmt).Rhs[0], nil) {
+		t.Fatal("not rewritten")
+	}
+	if !astutil.Match(expr(t, "print(x)"), body[1].(*ast.ExprStmt).X, nil) {
+		t.Fatal("wrong statement deleted")
+	}
+}
+
+func TestApplyRoot(t *testing.T) {
+	x := expr(t, "a + b")
+	y := expr(t, "c")
+	got := astutil.Apply(x, func(c *astutil.Cursor) bool {
+		if c.Parent() == nil {
+			c.Replace(y)
+		}
+		return true
+	}, nil)
+	if got != y {
+		t.Fatalf("root = %#v", got)
+	}
+
+	n := 0
+	astutil.Apply(expr(t, "f(a, b, c)"), nil, func(c *astutil.Cursor) bool {
+		n++
+		return n < 2
+	})
+	if n != 2 {
+		t.Fatalf("post called %d times after abort", n)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, node string
+		want          bool
+	}{
+		{"x + x", "f(1) + f(1)", true},
+		{"x + x", "f(1) + f(2)", false},
+		{"x + y", "a + 'b'", true},
+		{"x + 1", "a + 2", false},
+		{"x.y", "a.b", false}, // MEMBER 不是通配符
+		{"fmt.print(x)", "fmt.print(a, b)", false},
+		{"[x, 2]", "[(a), 2]", true},
+		{"not x", "not not a", true},
+	}
+	for _, tt := range tests {
+		b := astutil.Bindings{}
+		if got := astutil.Match(expr(t, tt.pattern), expr(t, tt.node), b); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v", tt.pattern, tt.node, got)
+		}
+	}
+
+	if astutil.Match(expr(t, "x"), expr(t, "a"), nil) {
+		t.Error("wildcard matched without bindings")
+	}
+	b := astutil.Bindings{"x": expr(t, "a")}
+	if astutil.Match(expr(t, "x + 1"), expr(t, "b + 1"), b) {
+		t.Error("existing binding ignored")
+	}
+}
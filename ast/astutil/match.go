@@ -0,0 +1,121 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package astutil
+
+import (
+	"reflect"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Bindings 保存模式中的通配符匹配到的节点
+type Bindings map[string]ast.Syntax
+
+// IsWildcard 返回 n 是否为模式中的通配符, 即单个小写字母的标识符.
+func IsWildcard(n ast.Syntax) bool {
+	id, ok := n.(*ast.Ident)
+	if !ok || id.Name.Tok != token.IDENT || len(id.Name.Source) != 1 {
+		return false
+	}
+	c := id.Name.Source[0]
+	return c >= 'a' && c <= 'z'
+}
+
+var (
+	posType    = reflect.TypeOf(scanner.Pos(0))
+	symbolType = reflect.TypeOf(ast.Symbol{})
+	identType  = reflect.TypeOf((*ast.Ident)(nil))
+)
+
+// Match 返回 node 与 pattern 的结构是否相同, 比较 Token 和源码文本, 忽略位置.
+//
+// bindings 不为 nil 时, pattern 中的通配符匹配任意节点, 匹配到的节点记录在 bindings 中,
+// 同一个通配符出现多次时, 各处匹配到的节点必须相同. bindings 中已有的通配符也必须相同.
+// bindings 为 nil 时通配符只匹配同名的标识符, 即比较两个节点是否相同.
+// 匹配失败时 bindings 中可能留有部分记录.
+func Match(pattern, node ast.Syntax, bindings Bindings) bool {
+	if pattern == nil || node == nil {
+		return pattern == nil && node == nil
+	}
+	return match(reflect.ValueOf(pattern), reflect.ValueOf(node), bindings)
+}
+
+func match(p, n reflect.Value, b Bindings) bool {
+	if p.Kind() == reflect.Interface {
+		if p.IsNil() || n.IsNil() {
+			return p.IsNil() && n.IsNil()
+		}
+		p, n = p.Elem(), n.Elem()
+	}
+
+	if b != nil && p.Type() == identType && !p.IsNil() {
+		if pn := p.Interface().(*ast.Ident); IsWildcard(pn) {
+			if n.Kind() == reflect.Ptr && n.IsNil() {
+				return false
+			}
+			x := n.Interface().(ast.Syntax)
+			name := pn.Name.Source
+			if old, ok := b[name]; ok {
+				return Match(old, x, nil)
+			}
+			b[name] = x
+			return true
+		}
+	}
+
+	if p.Type() != n.Type() {
+		return false
+	}
+	switch p.Kind() {
+	case reflect.Ptr:
+		if p.IsNil() || n.IsNil() {
+			return p.IsNil() && n.IsNil()
+		}
+		return match(p.Elem(), n.Elem(), b)
+	case reflect.Slice:
+		if p.Len() != n.Len() {
+			return false
+		}
+		for i := 0; i < p.Len(); i++ {
+			if !match(p.Index(i), n.Index(i), b) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		if p.Type() == symbolType {
+			ps, ns := p.Interface().(ast.Symbol), n.Interface().(ast.Symbol)
+			return ps.Tok == ns.Tok && ps.Source == ns.Source
+		}
+		for i := 0; i < p.NumField(); i++ {
+			f := p.Type().Field(i)
+			if f.Type == posType {
+				if absent(f.Name, p.Field(i).Interface().(scanner.Pos)) !=
+					absent(f.Name, n.Field(i).Interface().(scanner.Pos)) {
+					return false
+				}
+				continue
+			}
+			if !match(p.Field(i), n.Field(i), b) {
+				return false
+			}
+		}
+		return true
+	}
+	return p.Interface() == n.Interface()
+}
+
+// absent 返回名为 name 的位置字段是否表示该 Token 不存在, 例如没有 pub, 没有参数表.
+func absent(name string, pos scanner.Pos) bool {
+	switch name {
+	case "Pub":
+		return pos < 0
+	case "Lparen", "Rparen":
+		return pos == 0
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"strings"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// BlankLinesBefore 返回节点 n 之前紧邻的空白行数量.
+//
+// 扫描器和 File.Push 会把连续的换行, 空白行合并为一个 NL, EMPTYLINE 或顶层 PLACEHOLDER,
+// BlankLinesBefore 从这些合并后的节点计算出行数, 注释和非空白的占位会中断计数.
+// 文件开头的空白行也被计算在内. n 是 File 或者 n 本身是空白时返回 0.
+func BlankLinesBefore(n Node) int {
+	b := base(n)
+	if b == nil || b.all == nil || n.Id() == 0 || blank(n) {
+		return 0
+	}
+	nodes := b.all.Nodes
+
+	lines, i := 0, n.Id()-1
+	for ; i > 0 && blank(nodes[i]); i-- {
+		lines += lineBreaks(nodes[i].Text())
+	}
+	// 首个换行结束的是上一个非空白行
+	if i > 0 && lines > 0 {
+		lines--
+	}
+	return lines
+}
+
+// blank 返回 n 是否只包含空白字符
+func blank(n Node) bool {
+	switch n.Token() {
+	case token.NL, token.EMPTYLINE, token.INDENTATION:
+		return true
+	case token.PLACEHOLDER:
+		return strings.TrimLeft(n.Text(), " \t\r\n") == ""
+	}
+	return false
+}
+
+// lineBreaks 返回 s 中换行符的数量, 换行符可以是 LF, CR 或 CRLF.
+func lineBreaks(s string) (n int) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+			n++
+		case '\n':
+			n++
+		}
+	}
+	return
+}
@@ -0,0 +1,33 @@
+package ast_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestBlankLinesBefore(t *testing.T) {
+	src := "\n\nvar x = 1\n\n\n  \nvar y = 2\n// c\n\nvar z = (\n\n\t1\n)\n"
+	file := NewFile()
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只列出有空白行的干净节点, 注释之后的 var z 没有
+	want := []string{"var 2", "var 3", "1 1"}
+	var got []string
+	for _, n := range file.Nodes {
+		if lines := BlankLinesBefore(n); lines != 0 && !IsTrivia(n.Token()) {
+			got = append(got, n.Text()+" "+strconv.Itoa(lines))
+		}
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatal(got)
+	}
+	if BlankLinesBefore(file) != 0 {
+		t.Fatal("file")
+	}
+}
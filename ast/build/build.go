@@ -0,0 +1,151 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包用 Go 代码构建 zxx 源码文件, 用于脚手架等代码生成工具.
+//
+//	f := build.NewFile(
+//		build.Var("int", "count", build.Int(0)),
+//		build.Func("sum").Result("int").Param("int", "a").Param("int", "b").
+//			Body(build.Out(build.Binary(build.Ident("a"), "+", build.Ident("b")))),
+//	)
+//	file, src, err := f.Build()
+//
+// 函数体使用 {} 块. 构建出的 ast.File 与用 parser.Parse 解析 src 得到的结果一致,
+// 节点的 Pos 是其在 src 中的偏移量.
+package build
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// emitter 生成源码并记录推送给 ast.File 的 Token
+type emitter struct {
+	buf  bytes.Buffer
+	syms []sym
+	err  error
+}
+
+type sym struct {
+	pos  scanner.Pos
+	tok  token.Token
+	code string
+}
+
+func (e *emitter) emit(tok token.Token, code string) {
+	// 扫描器把连续的换行当做一个 NL
+	if n := len(e.syms); tok == token.NL && n != 0 && e.syms[n-1].tok == token.NL {
+		e.syms[n-1].code += code
+		e.buf.WriteString(code)
+		return
+	}
+	e.syms = append(e.syms, sym{scanner.Pos(e.buf.Len()), tok, code})
+	e.buf.WriteString(code)
+}
+
+// word 输出保留字或者类型 code, 不是保留字时输出标识符
+func (e *emitter) word(code string) {
+	if tok := token.Lookup(code); tok != token.PLACEHOLDER {
+		e.emit(tok, code)
+		return
+	}
+	e.name(code)
+}
+
+// name 输出标识符或者成员 code, 不能是保留字
+func (e *emitter) name(code string) {
+	tok := token.Lookup(code)
+	if tok == token.PLACEHOLDER {
+		tok, _ = lexutil.Classify(code)
+	}
+	if tok != token.IDENT && tok != token.MEMBER && tok != token.MEMBERS {
+		e.fail("invalid name " + strconv.Quote(code))
+	}
+	e.emit(tok, code)
+}
+
+func (e *emitter) space() { e.buf.WriteByte(' ') }
+
+func (e *emitter) nl(depth int) {
+	e.emit(token.NL, "\n")
+	if depth > 0 {
+		e.emit(token.INDENTATION, strings.Repeat("\t", depth))
+	}
+}
+
+func (e *emitter) fail(msg string) {
+	if e.err == nil {
+		e.err = errors.New("build: " + msg)
+	}
+}
+
+// File 是构建中的源码文件
+type File struct {
+	decls []Decl
+}
+
+// NewFile 返回包含声明 decls 的 File.
+func NewFile(decls ...Decl) *File {
+	return &File{decls}
+}
+
+// Add 追加声明 decls.
+func (f *File) Add(decls ...Decl) *File {
+	f.decls = append(f.decls, decls...)
+	return f
+}
+
+// Build 返回构建的 ast.File 及其源码 src.
+func (f *File) Build() (file *ast.File, src []byte, err error) {
+	e := new(emitter)
+	for i, d := range f.decls {
+		if i != 0 {
+			e.emit(token.NL, "\n")
+		}
+		d.decl(e)
+	}
+	if e.err != nil {
+		return nil, nil, e.err
+	}
+
+	file = ast.NewFile()
+	for _, s := range e.syms {
+		if err = file.Push(s.pos, s.tok, s.code); err != nil {
+			return nil, nil, err
+		}
+	}
+	return file, e.buf.Bytes(), nil
+}
+
+// Decl 是顶层声明
+type Decl interface {
+	decl(*emitter)
+}
+
+// Stmt 是函数体中的语句, depth 是语句的缩进层级
+type Stmt interface {
+	stmt(e *emitter, depth int)
+}
+
+// Expr 是表达式
+type Expr interface {
+	expr(*emitter)
+}
+
+// operator 输出运算符
+func (e *emitter) operator(op string) {
+	tok := token.Lookup(op)
+	if op == "" || !tok.As(token.Operator) {
+		e.fail("invalid operator " + strconv.Quote(op))
+		return
+	}
+	e.emit(tok, op)
+}
@@ -0,0 +1,92 @@
+package build_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/build"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+func TestBuild(t *testing.T) {
+	f := build.NewFile(
+		build.Use("std/io"),
+		build.Var("int", "count", build.Int(-1)),
+		build.Const("", "name", build.String("it's")),
+		build.Func("sum").Pub().Result("int").Param("int", "a").Param("int", "b").
+			Body(build.Out(build.Binary(build.Ident("a"), "+", build.Ident("b")))),
+		build.Proc("main").Body(
+			build.Assign("count", build.Call("sum", build.Ident("count"), build.Float(1.5))),
+			build.If(build.Unary("not", build.Paren(build.Binary(build.Ident("count"), "==", build.Int(0)))),
+				build.Do(build.Call("echo", build.Ident("io.out"), build.Bool(true))),
+			).Else(build.Out(nil)),
+		),
+	)
+	file, src, err := f.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `use 'std/io'
+
+var int count = -1
+
+const name = "it's"
+
+pub func int sum(int a, int b) {
+	out a + b
+}
+
+proc main() {
+	count = sum(count, 1.5)
+	if not (count == 0) {
+		echo(io.out, true)
+	} else {
+		out
+	}
+}
+`
+	if string(src) != want {
+		t.Fatal(string(src))
+	}
+
+	// 与解析源码的结果一致
+	parsed := ast.NewFile()
+	if err = parser.Parse(src, parsed); err != nil {
+		t.Fatal(err)
+	}
+	if file.Len() != parsed.Len() {
+		t.Fatal(file.Len(), parsed.Len())
+	}
+	for i, n := range file.Nodes {
+		p := parsed.Nodes[i]
+		if n.Token() != p.Token() || n.Text() != p.Text() || n.Kind(0) != p.Kind(0) ||
+			n.Prev() != p.Prev() && n.Prev().Id() != p.Prev().Id() || pos(n) != pos(p) {
+			t.Fatal(i, n.Token(), n.Text(), p.Token(), p.Text())
+		}
+	}
+
+	if _, _, err = build.NewFile(build.Var("", "var", nil)).Build(); err == nil {
+		t.Fatal("want error")
+	}
+	if _, _, err = build.NewFile(build.Var("", "a", build.Binary(build.Int(1), "=", build.Int(2)))).Build(); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func pos(n ast.Node) scanner.Pos {
+	switch n := n.(type) {
+	case *ast.Decl:
+		return n.Pos
+	case *ast.Chunk:
+		return n.Pos
+	case *ast.Stmt:
+		return n.Pos
+	case *ast.Expr:
+		return n.Pos
+	case *ast.Text:
+		return n.Pos
+	}
+	return -1
+}
@@ -0,0 +1,139 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Use 返回引入 path 的 use 声明.
+func Use(path string) Decl {
+	return &use{path}
+}
+
+type use struct{ path string }
+
+func (d *use) decl(e *emitter) {
+	e.word("use")
+	e.space()
+	e.emit(token.VALSTRING, lexutil.Quote(d.path))
+	e.emit(token.NL, "\n")
+}
+
+// Var 返回 var 声明, typ 为空时省略类型, x 为 nil 时没有初值.
+func Var(typ, name string, x Expr) Decl {
+	return &value{"var", typ, name, x}
+}
+
+// Const 返回 const 声明, typ 为空时省略类型.
+func Const(typ, name string, x Expr) Decl {
+	return &value{"const", typ, name, x}
+}
+
+type value struct {
+	kind, typ, name string
+	x               Expr
+}
+
+func (d *value) decl(e *emitter) {
+	e.word(d.kind)
+	e.space()
+	if d.typ != "" {
+		e.word(d.typ)
+		e.space()
+	}
+	e.name(d.name)
+	if d.x != nil {
+		e.space()
+		e.emit(token.ASSIGN, "=")
+		e.space()
+		d.x.expr(e)
+	}
+	e.emit(token.NL, "\n")
+}
+
+// FuncDecl 是 func 或者 proc 声明
+type FuncDecl struct {
+	kind   string
+	pub    bool
+	result string
+	name   string
+	params [][2]string
+	body   []Stmt
+}
+
+// Func 返回名为 name 的 func 声明.
+func Func(name string) *FuncDecl {
+	return &FuncDecl{kind: "func", name: name}
+}
+
+// Proc 返回名为 name 的 proc 声明.
+func Proc(name string) *FuncDecl {
+	return &FuncDecl{kind: "proc", name: name}
+}
+
+// Pub 使声明公开.
+func (d *FuncDecl) Pub() *FuncDecl {
+	d.pub = true
+	return d
+}
+
+// Result 设置结果类型.
+func (d *FuncDecl) Result(typ string) *FuncDecl {
+	d.result = typ
+	return d
+}
+
+// Param 追加类型为 typ 的参数 name.
+func (d *FuncDecl) Param(typ, name string) *FuncDecl {
+	d.params = append(d.params, [2]string{typ, name})
+	return d
+}
+
+// Body 追加函数体语句.
+func (d *FuncDecl) Body(stmts ...Stmt) *FuncDecl {
+	d.body = append(d.body, stmts...)
+	return d
+}
+
+func (d *FuncDecl) decl(e *emitter) {
+	if d.pub {
+		e.word("pub")
+		e.space()
+	}
+	e.word(d.kind)
+	e.space()
+	if d.result != "" {
+		e.word(d.result)
+		e.space()
+	}
+	e.name(d.name)
+	e.emit(token.LEFT, "(")
+	for i, p := range d.params {
+		if i != 0 {
+			e.emit(token.COMMA, ",")
+			e.space()
+		}
+		e.word(p[0])
+		e.space()
+		e.name(p[1])
+	}
+	e.emit(token.RIGHT, ")")
+	block(e, d.body, 1)
+	e.emit(token.NL, "\n")
+}
+
+// block 输出 {} 语句块, depth 是块内语句的缩进层级
+func block(e *emitter, stmts []Stmt, depth int) {
+	e.space()
+	e.emit(token.LEFT, "{")
+	for _, s := range stmts {
+		e.nl(depth)
+		s.stmt(e, depth)
+	}
+	e.nl(depth - 1)
+	e.emit(token.RIGHT, "}")
+}
@@ -0,0 +1,130 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import (
+	"strconv"
+
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Ident 返回标识符或者成员 name, 例如 a, a.b.
+func Ident(name string) Expr {
+	return ident(name)
+}
+
+type ident string
+
+func (x ident) expr(e *emitter) { e.name(string(x)) }
+
+// literal 是已经格式化的字面值
+type literal struct {
+	tok  token.Token
+	code string
+}
+
+func (x literal) expr(e *emitter) { e.emit(x.tok, x.code) }
+
+// Int 返回整数字面值. 负数是 '-' 运算和正整数.
+func Int(i int64) Expr {
+	if i < 0 {
+		return &unary{"-", literal{token.VALINTEGER, strconv.FormatUint(uint64(-i), 10)}}
+	}
+	return literal{token.VALINTEGER, strconv.FormatInt(i, 10)}
+}
+
+// Float 返回浮点数字面值, f 必须是有限的非负数.
+func Float(f float64) Expr {
+	return literal{token.VALFLOAT, lexutil.FormatFloat(f)}
+}
+
+// String 返回字符串字面值.
+func String(s string) Expr {
+	return literal{token.VALSTRING, lexutil.Quote(s)}
+}
+
+// Bool 返回 true 或者 false.
+func Bool(b bool) Expr {
+	return literal{token.VALBOOL, strconv.FormatBool(b)}
+}
+
+// Null 返回 null.
+func Null() Expr {
+	return literal{token.NULL, "null"}
+}
+
+// Binary 返回二元运算 x op y, op 是运算符的源码, 例如 "+", "mod", "and".
+func Binary(x Expr, op string, y Expr) Expr {
+	return &binary{x, op, y}
+}
+
+type binary struct {
+	x  Expr
+	op string
+	y  Expr
+}
+
+func (x *binary) expr(e *emitter) {
+	x.x.expr(e)
+	e.space()
+	e.operator(x.op)
+	e.space()
+	x.y.expr(e)
+}
+
+// Unary 返回一元运算 op x, 例如 "-", "not".
+func Unary(op string, x Expr) Expr {
+	return &unary{op, x}
+}
+
+type unary struct {
+	op string
+	x  Expr
+}
+
+func (x *unary) expr(e *emitter) {
+	e.operator(x.op)
+	if n := len(x.op); n != 0 && x.op[n-1] >= 'a' && x.op[n-1] <= 'z' {
+		e.space()
+	}
+	x.x.expr(e)
+}
+
+// Paren 返回带括号的表达式 (x).
+func Paren(x Expr) Expr {
+	return &paren{x}
+}
+
+type paren struct{ x Expr }
+
+func (x *paren) expr(e *emitter) {
+	e.emit(token.LEFT, "(")
+	x.x.expr(e)
+	e.emit(token.RIGHT, ")")
+}
+
+// Call 返回函数调用 fn(args...).
+func Call(fn string, args ...Expr) Expr {
+	return &call{fn, args}
+}
+
+type call struct {
+	fn   string
+	args []Expr
+}
+
+func (x *call) expr(e *emitter) {
+	e.name(x.fn)
+	e.emit(token.LEFT, "(")
+	for i, a := range x.args {
+		if i != 0 {
+			e.emit(token.COMMA, ",")
+			e.space()
+		}
+		a.expr(e)
+	}
+	e.emit(token.RIGHT, ")")
+}
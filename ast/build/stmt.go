@@ -0,0 +1,78 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package build
+
+import "github.com/ZxxLang/zxx/token"
+
+// Out 返回输出结果的 out 语句, x 为 nil 时没有结果.
+func Out(x Expr) Stmt {
+	return &out{x}
+}
+
+type out struct{ x Expr }
+
+func (s *out) stmt(e *emitter, depth int) {
+	e.word("out")
+	if s.x != nil {
+		e.space()
+		s.x.expr(e)
+	}
+}
+
+// Assign 返回赋值语句 name = x.
+func Assign(name string, x Expr) Stmt {
+	return &assign{name, x}
+}
+
+type assign struct {
+	name string
+	x    Expr
+}
+
+func (s *assign) stmt(e *emitter, depth int) {
+	e.name(s.name)
+	e.space()
+	e.emit(token.ASSIGN, "=")
+	e.space()
+	s.x.expr(e)
+}
+
+// Do 返回表达式语句, 例如函数调用.
+func Do(x Expr) Stmt {
+	return &do{x}
+}
+
+type do struct{ x Expr }
+
+func (s *do) stmt(e *emitter, depth int) { s.x.expr(e) }
+
+// IfStmt 是 if 语句
+type IfStmt struct {
+	cond        Expr
+	then, other []Stmt
+}
+
+// If 返回条件为 cond 的 if 语句.
+func If(cond Expr, then ...Stmt) *IfStmt {
+	return &IfStmt{cond: cond, then: then}
+}
+
+// Else 设置 else 分支.
+func (s *IfStmt) Else(stmts ...Stmt) *IfStmt {
+	s.other = stmts
+	return s
+}
+
+func (s *IfStmt) stmt(e *emitter, depth int) {
+	e.word("if")
+	e.space()
+	s.cond.expr(e)
+	block(e, s.then, depth+1)
+	if s.other != nil {
+		e.space()
+		e.word("else")
+		block(e, s.other, depth+1)
+	}
+}
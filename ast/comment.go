@@ -0,0 +1,96 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "github.com/ZxxLang/zxx/token"
+
+// Comments 是某个 Decl 或 Stmt 节点拥有的注释节点.
+type Comments struct {
+	Doc      []Node // 声明体开始处的注释, 只有 Decl 拥有
+	Leading  []Node // 块中独占一行, 位于块的开始或者空行之后的注释, 属于其后的语句
+	Trailing []Node // 尾注释以及紧随其后的注释行
+}
+
+// CommentMap 关联注释节点与其所属的 Decl, Stmt 节点.
+//
+// Z 的注释是后置的并紧跟语句实体: 声明体 LEFT 之后首个干净节点之前的注释是该声明的 Doc,
+// 其它注释通常是上文语句的 Trailing. 块中独占一行的注释位于块的开始, 或者与上文语句之间有空行时,
+// 它和紧随其后的注释行是下一个语句的 Leading. 赋值, 调用等语句没有自己的节点,
+// 它们的 Leading 属于语句的首个节点. 块结束之前的这种注释仍是上文语句的 Trailing.
+// 顶层空行之后的注释已被 File.Push 转换为 PLACEHOLDER, 不属于任何节点.
+type CommentMap map[Node]*Comments
+
+// NewCommentMap 返回 file 中注释节点的关联关系.
+func NewCommentMap(file *File) CommentMap {
+	m := CommentMap{}
+	var (
+		clean   Node   // 上一个干净节点
+		newline bool   // clean 之后是否有换行
+		leading []Node // 等待下一个语句的注释
+	)
+	trailing := func(list ...Node) {
+		if owner := Owner(clean); owner != nil {
+			m.get(owner).Trailing = append(m.get(owner).Trailing, list...)
+		}
+	}
+
+	for _, n := range file.Nodes[1:] {
+		tok := n.Token()
+		if tok != token.COMMENT {
+			switch {
+			case tok == token.NL || tok == token.EMPTYLINE:
+				newline = true
+			case !IsTrivia(tok):
+				if len(leading) != 0 {
+					if tok == token.RIGHT {
+						trailing(leading...)
+					} else {
+						m.get(n).Leading = leading
+					}
+					leading = nil
+				}
+				clean, newline = n, false
+			}
+			continue
+		}
+
+		switch {
+		case clean == nil:
+		case len(leading) != 0:
+			leading = append(leading, n)
+		case clean.Token() == token.LEFT && clean.Parent() != nil && clean.Parent().Kind(FDeclaration) != 0:
+			decl := clean.Parent()
+			m.get(decl).Doc = append(m.get(decl).Doc, n)
+		case newline && (clean.Token() == token.LEFT || BlankLinesBefore(n) != 0):
+			leading = []Node{n}
+		default:
+			trailing(n)
+		}
+	}
+	if len(leading) != 0 {
+		trailing(leading...)
+	}
+	return m
+}
+
+func (m CommentMap) get(n Node) *Comments {
+	c := m[n]
+	if c == nil {
+		c = new(Comments)
+		m[n] = c
+	}
+	return c
+}
+
+// Owner 返回包含 n 的最内层 Decl 或 Stmt 节点, n 自身也可能是.
+// 如果 n 不属于任何 Decl, Stmt 返回 nil.
+func Owner(n Node) Node {
+	for ; n != nil; n = n.Parent() {
+		if n.Kind(FDeclaration|FStatement) != 0 {
+			return n
+		}
+	}
+	return nil
+}
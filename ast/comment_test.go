@@ -0,0 +1,114 @@
+package ast_test
+
+import (
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+const commented = `
+top placeholder
+
+use "os"	tail for use
+var int x = 1 // tail for x
+// placeholder at top level
+
+// placeholder after empty line
+proc sum int x,y out int [
+	// doc for sum
+	---
+	block doc
+	---
+	out x add y // tail for out
+	// more for out
+]
+`
+
+func TestCommentMap(t *testing.T) {
+	file := NewFile()
+	if err := parser.Parse([]byte(commented), file); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[token.Token][2][]string{
+		token.USE:  {nil, {"\ttail for use"}},
+		token.VAR:  {nil, {"// tail for x"}},
+		token.PROC: {{"// doc for sum", "---\n\tblock doc\n\t---"}, nil},
+		token.OUT:  {nil, {"// tail for out", "// more for out"}},
+	}
+
+	cmap := NewCommentMap(file)
+	if len(cmap) != len(want) {
+		t.Fatal(len(cmap), cmap)
+	}
+
+	for n, c := range cmap {
+		w, ok := want[n.Token()]
+		if !ok {
+			t.Fatal(n.Token())
+		}
+		for i, nodes := range [2][]Node{c.Doc, c.Trailing} {
+			if len(nodes) != len(w[i]) {
+				t.Fatal(n.Token(), i, nodes)
+			}
+			for j, comment := range nodes {
+				if comment.Text() != w[i][j] {
+					t.Fatalf("%s %d %q", n.Token(), i, comment.Text())
+				}
+			}
+		}
+	}
+}
+
+const leading = `proc f [
+	// doc for f
+	if x [
+		// lead y
+		// more for y
+		y = 1 // tail for y
+
+		// lead out
+		out 2
+		// tail for out
+
+		// end of block
+	]
+]
+`
+
+func TestCommentMapLeading(t *testing.T) {
+	file := NewFile()
+	if err := parser.Parse([]byte(leading), file); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[token.Token][3][]string{
+		token.PROC:  {{"// doc for f"}, nil, nil},
+		token.IDENT: {nil, {"// lead y", "// more for y"}, nil},
+		token.IF:    {nil, nil, {"// tail for y"}},
+		token.OUT:   {nil, {"// lead out"}, {"// tail for out", "// end of block"}},
+	}
+
+	cmap := NewCommentMap(file)
+	if len(cmap) != len(want) {
+		t.Fatal(len(cmap), cmap)
+	}
+	for n, c := range cmap {
+		w, ok := want[n.Token()]
+		if !ok || n.Token() == token.IDENT && n.Text() != "y" {
+			t.Fatal(n.Token(), n.Text())
+		}
+		for i, nodes := range [3][]Node{c.Doc, c.Leading, c.Trailing} {
+			if len(nodes) != len(w[i]) {
+				t.Fatal(n.Token(), i, nodes)
+			}
+			for j, comment := range nodes {
+				if comment.Text() != w[i][j] {
+					t.Fatalf("%s %d %q", n.Token(), i, comment.Text())
+				}
+			}
+		}
+	}
+}
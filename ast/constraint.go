@@ -0,0 +1,176 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"errors"
+	"strings"
+)
+
+// BuildPrefix 是构建约束注释的前缀, 注释必须在文件的首个声明之前:
+//
+//	// zxx:build linux && (amd64 || arm64) && !cgo
+//
+// 标签由字母, 数字, '_' 和 '.' 组成, 例如目标平台的操作系统和架构, 参见 parser.Target.
+const BuildPrefix = "zxx:build"
+
+// Constraint 是构建约束表达式, Eval 以 tag 判断每个标签是否成立.
+type Constraint interface {
+	Eval(tag func(name string) bool) bool
+	String() string
+}
+
+type (
+	// TagConstraint 是单个标签
+	TagConstraint struct {
+		Tag string
+	}
+
+	// NotConstraint 是 !X
+	NotConstraint struct {
+		X Constraint
+	}
+
+	// AndConstraint 是 X && Y
+	AndConstraint struct {
+		X, Y Constraint
+	}
+
+	// OrConstraint 是 X || Y
+	OrConstraint struct {
+		X, Y Constraint
+	}
+)
+
+func (c *TagConstraint) Eval(tag func(string) bool) bool { return tag(c.Tag) }
+func (c *NotConstraint) Eval(tag func(string) bool) bool { return !c.X.Eval(tag) }
+func (c *AndConstraint) Eval(tag func(string) bool) bool { return c.X.Eval(tag) && c.Y.Eval(tag) }
+func (c *OrConstraint) Eval(tag func(string) bool) bool  { return c.X.Eval(tag) || c.Y.Eval(tag) }
+
+func (c *TagConstraint) String() string { return c.Tag }
+func (c *NotConstraint) String() string { return "!" + paren(c.X) }
+func (c *AndConstraint) String() string { return paren(c.X) + " && " + paren(c.Y) }
+func (c *OrConstraint) String() string  { return c.X.String() + " || " + c.Y.String() }
+
+// paren 返回 c 作为 !, && 的操作数的写法, || 需要括号
+func paren(c Constraint) string {
+	if _, ok := c.(*OrConstraint); ok {
+		return "(" + c.String() + ")"
+	}
+	return c.String()
+}
+
+// IsConstraint 返回注释 text 是否是构建约束, text 包括 "//".
+func IsConstraint(text string) bool {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "//") {
+		return false
+	}
+	text = strings.TrimSpace(text[2:])
+	return text == BuildPrefix || strings.HasPrefix(text, BuildPrefix+" ")
+}
+
+// ParseConstraint 解析构建约束注释 text, text 包括 "//", 参见 IsConstraint.
+func ParseConstraint(text string) (Constraint, error) {
+	if !IsConstraint(text) {
+		return nil, errors.New("not a " + BuildPrefix + " comment")
+	}
+	text = strings.TrimSpace(text)
+	p := &constraintParser{s: strings.TrimSpace(text[2:])[len(BuildPrefix):]}
+	c := p.or()
+	if p.err == nil && p.next() != "" {
+		p.err = errors.New("unexpected " + p.tok + " in " + BuildPrefix + " constraint")
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return c, nil
+}
+
+// constraintParser 是构建约束的递归下降解析器
+type constraintParser struct {
+	s   string // 未扫描的部分
+	tok string // 当前 token, 结束时为空, 未取出时 peeked 为真
+	err error
+
+	peeked bool
+}
+
+// next 返回并取出下一个 token: 标签, "!", "&&", "||", "(", ")", 结束时为空
+func (p *constraintParser) next() string {
+	if p.peeked {
+		p.peeked = false
+		return p.tok
+	}
+	p.s = strings.TrimLeft(p.s, " \t")
+	n := 0
+	switch {
+	case p.s == "":
+	case strings.HasPrefix(p.s, "&&"), strings.HasPrefix(p.s, "||"):
+		n = 2
+	case p.s[0] == '!' || p.s[0] == '(' || p.s[0] == ')':
+		n = 1
+	default:
+		for n < len(p.s) && isTagByte(p.s[n]) {
+			n++
+		}
+		if n == 0 {
+			n = 1
+		}
+	}
+	p.tok, p.s = p.s[:n], p.s[n:]
+	return p.tok
+}
+
+func (p *constraintParser) peek() string {
+	tok := p.next()
+	p.peeked = true
+	return tok
+}
+
+func isTagByte(c byte) bool {
+	return c == '_' || c == '.' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func (p *constraintParser) or() Constraint {
+	x := p.and()
+	for p.err == nil && p.peek() == "||" {
+		p.next()
+		x = &OrConstraint{x, p.and()}
+	}
+	return x
+}
+
+func (p *constraintParser) and() Constraint {
+	x := p.not()
+	for p.err == nil && p.peek() == "&&" {
+		p.next()
+		x = &AndConstraint{x, p.not()}
+	}
+	return x
+}
+
+func (p *constraintParser) not() Constraint {
+	if p.err != nil {
+		return nil
+	}
+	switch tok := p.next(); {
+	case tok == "!":
+		return &NotConstraint{p.not()}
+	case tok == "(":
+		x := p.or()
+		if p.err == nil && p.next() != ")" {
+			p.err = errors.New("missing ) in " + BuildPrefix + " constraint")
+		}
+		return x
+	case tok == "":
+		p.err = errors.New("unexpected end of " + BuildPrefix + " constraint")
+	case isTagByte(tok[0]):
+		return &TagConstraint{tok}
+	default:
+		p.err = errors.New("unexpected " + tok + " in " + BuildPrefix + " constraint")
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+)
+
+func TestConstraint(t *testing.T) {
+	tags := map[string]bool{"linux": true, "amd64": true, "go1.x": true}
+	for src, want := range map[string]string{
+		"// zxx:build linux":                            "linux",
+		"//zxx:build !windows && (amd64 || arm64)":      "!windows && (amd64 || arm64)",
+		"// zxx:build linux && !(cgo || race) || plan9": "linux && !(cgo || race) || plan9",
+		"// zxx:build go1.x && !!linux":                 "go1.x && !!linux",
+	} {
+		c, err := ast.ParseConstraint(src)
+		if err != nil || c.String() != want {
+			t.Errorf("%s: %v %v", src, c, err)
+			continue
+		}
+		if !c.Eval(func(tag string) bool { return tags[tag] }) {
+			t.Errorf("%s is false", src)
+		}
+	}
+	for src, msg := range map[string]string{
+		"// zxx:build":              "unexpected end of zxx:build constraint",
+		"// zxx:build (linux":       "missing ) in zxx:build constraint",
+		"// zxx:build linux darwin": "unexpected darwin in zxx:build constraint",
+		"// zxx:build linux, amd64": "unexpected , in zxx:build constraint",
+		"// zxx:builder linux":      "not a zxx:build comment",
+	} {
+		if _, err := ast.ParseConstraint(src); err == nil || err.Error() != msg {
+			t.Errorf("%s: %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// ChangeKind 是顶层声明的变化种类
+type ChangeKind uint8
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Modified
+)
+
+var changeKinds = [...]string{"added", "removed", "modified"}
+
+func (k ChangeKind) String() string {
+	if int(k) < len(changeKinds) {
+		return changeKinds[k]
+	}
+	return "ChangeKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Change 是一个顶层声明的变化. Added 时 Old 为 nil, Removed 时 New 为 nil.
+type Change struct {
+	Kind     ChangeKind
+	Key      string // 声明保留字和名字, 例如 "proc sum", "use os"
+	Old, New Node   // 声明节点
+}
+
+// Diff 返回从 old 到 new 顶层声明的语义变化. 声明以保留字和名字匹配,
+// 只有换行, 缩进, 空白, 注释不同的声明被视为未改变, pub 修饰的增删是修改.
+//
+// 结果按 new 中的声明顺序排列, 被删除的声明按 old 中的顺序排在最后.
+func Diff(old, new *File) []Change {
+	olds := declPrints(old)
+	oldList := olds.list()
+	var changes []Change
+	for _, d := range declPrints(new).list() {
+		o, ok := olds[d.key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: Added, Key: d.key, New: d.node})
+		case o.print != d.print:
+			changes = append(changes, Change{Kind: Modified, Key: d.key, Old: o.node, New: d.node})
+		}
+		delete(olds, d.key)
+	}
+	for _, o := range oldList {
+		if _, ok := olds[o.key]; ok {
+			changes = append(changes, Change{Kind: Removed, Key: o.key, Old: o.node})
+		}
+	}
+	return changes
+}
+
+// declPrint 是顶层声明的键和语义指纹
+type declPrint struct {
+	key   string
+	print string // 非 IsTrivia 节点的 Token 和源码
+	node  Node
+	order int
+}
+
+type printMap map[string]declPrint
+
+// list 按书写顺序返回声明
+func (m printMap) list() []declPrint {
+	list := make([]declPrint, len(m))
+	for _, d := range m {
+		list[d.order] = d
+	}
+	return list
+}
+
+// declPrints 返回 file 的顶层声明, 重复的键加上序号 "#2", "#3" 区分.
+func declPrints(file *File) printMap {
+	m := printMap{}
+	seen := map[string]int{}
+	nodes := file.Nodes[1:]
+	for i, n := range nodes {
+		if n.Kind(FDeclaration) == 0 || n.Parent().Id() != 0 {
+			continue
+		}
+
+		tok, name := n.Token(), ""
+		var print strings.Builder
+		print.WriteString(n.Text())
+		for _, c := range nodes[i+1:] {
+			if !inside(c, n) {
+				break
+			}
+			t := c.Token()
+			if tok == token.PUB && t != token.PUB && t.As(token.Declare) {
+				tok = t
+			}
+			if name == "" && (t == token.IDENT || t == token.VALSTRING) {
+				name = strings.Trim(c.Text(), `"'`)
+			}
+			if !IsTrivia(t) {
+				print.WriteByte(0)
+				print.WriteString(strconv.Itoa(int(t)))
+				print.WriteByte(' ')
+				print.WriteString(c.Text())
+			}
+		}
+
+		key := tok.String() + " " + name
+		if seen[key]++; seen[key] > 1 {
+			key += "#" + strconv.Itoa(seen[key])
+		}
+		m[key] = declPrint{key, print.String(), n, len(m)}
+	}
+	return m
+}
+
+// inside 返回 n 是否是 decl 的后代
+func inside(n, decl Node) bool {
+	for n = n.Parent(); n != nil; n = n.Parent() {
+		if n == decl {
+			return true
+		}
+	}
+	return false
+}
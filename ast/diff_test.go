@@ -0,0 +1,59 @@
+package ast_test
+
+import (
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestDiff(t *testing.T) {
+	const old = `use "os"
+var int x = 1
+proc f [
+	out x
+]
+proc g [
+	out 1
+]
+`
+	// f 只改变了注释和空白, x 增加了 pub, g 被删除, h 是新增的
+	const new = `use "os"
+pub var int x = 1
+proc f [
+	// 说明
+
+	out   x
+]
+proc h [
+	out 2
+]
+`
+	parse := func(src string) *File {
+		file := NewFile()
+		if err := parser.Parse([]byte(src), file); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	changes := Diff(parse(old), parse(new))
+	want := []struct {
+		kind ChangeKind
+		key  string
+	}{
+		{Modified, "var x"},
+		{Added, "proc h"},
+		{Removed, "proc g"},
+	}
+	if len(changes) != len(want) {
+		t.Fatal(changes)
+	}
+	for i, c := range changes {
+		if c.Kind != want[i].kind || c.Key != want[i].key {
+			t.Fatal(i, c.Kind, c.Key)
+		}
+	}
+	if len(Diff(parse(old), parse(old))) != 0 {
+		t.Fatal("same file")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"strings"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// GoImportPrefix 是引入 Go 包的 use 路径前缀. 引入 Go 包的文件中没有函数体的
+// func 声明是该包中同名外部函数的声明, 例如
+//
+//	use "go:strings"
+//	pub func ToUpper(string s) string
+//
+// cmd/zxxbind 可以从 Go 包生成这样的声明.
+const GoImportPrefix = "go:"
+
+// GoImport 返回 use 声明 n 引入的 Go 包路径, n 不是引入 Go 包的 use 声明时 ok 为 false.
+func GoImport(n Node) (path string, ok bool) {
+	if n.Token() != token.USE {
+		return
+	}
+	for _, c := range descendants(n) {
+		if c.Token() == token.VALSTRING {
+			path = strings.Trim(c.Text(), `"'`)
+			if strings.HasPrefix(path, GoImportPrefix) {
+				return path[len(GoImportPrefix):], true
+			}
+			return "", false
+		}
+	}
+	return
+}
+
+// IsExtern 返回 n 是否是没有函数体的 func 声明, 包括 pub func.
+func IsExtern(n Node) bool {
+	fn := n
+	if n.Token() == token.PUB {
+		fn = nil
+		for _, c := range descendants(n) {
+			if c.Token().As(token.Declare) && c.Token() != token.PUB {
+				fn = c
+				break
+			}
+		}
+	}
+	if fn == nil || fn.Token() != token.FUNC || fn.Kind(FDeclaration) == 0 {
+		return false
+	}
+
+	// 函数体是直属的 '{' 或 '[', '(' 是参数列表
+	for _, c := range descendants(n) {
+		if c.Token() == token.LEFT && (c.Parent() == n || c.Parent() == fn) && c.Text() != "(" {
+			return false
+		}
+	}
+	return true
+}
+
+// descendants 返回 n 的全部后代节点, 节点按顺序排列, 后代是连续的.
+func descendants(n Node) []Node {
+	b := base(n)
+	if b == nil || b.all == nil {
+		return nil
+	}
+	nodes := b.all.Nodes[n.Id()+1:]
+	for i, c := range nodes {
+		if !inside(c, n) {
+			return nodes[:i]
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,28 @@
+package ast_test
+
+import (
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestIsExtern(t *testing.T) {
+	file := NewFile()
+	src := "use \"go:strings\"\npub func ToUpper(string s) string\nfunc f(int a) int {\n\tout a\n}\nproc g [\n]\n"
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+	var got []bool
+	for _, n := range file.Nodes[1:] {
+		if n.Kind(FDeclaration) != 0 && n.Parent().Id() == 0 {
+			got = append(got, IsExtern(n))
+		}
+	}
+	if len(got) != 4 || got[0] || !got[1] || got[2] || got[3] {
+		t.Fatal(got)
+	}
+	if path, ok := GoImport(file.Nodes[1]); !ok || path != "strings" {
+		t.Fatal(path, ok)
+	}
+}
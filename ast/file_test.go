@@ -0,0 +1,83 @@
+package ast_test
+
+import (
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// parent 返回 file 中第一个源码为 code 的节点的容器的源码, 容器是 File 时返回 "file"
+func parent(t *testing.T, file *File, code string) string {
+	for _, n := range file.Nodes[1:] {
+		if n.Text() != code {
+			continue
+		}
+		p := n.Parent()
+		if p.Id() == 0 {
+			return "file"
+		}
+		return p.Text()
+	}
+	t.Fatal("missing", code)
+	return ""
+}
+
+func TestFileActive(t *testing.T) {
+	src := "var a = 1 +\n\t2\nproc f [\n\tout x\n]\nvar b = (3)\n"
+	file := NewFile()
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range [][2]string{
+		{"a", "var"},     // 声明之中
+		{"2", "var"},     // 运算符之后的换行只是排版
+		{"proc", "file"}, // 换行结束完整的声明
+		{"out", "["},     // 块之中的语句
+		{"x", "out"},
+		{"b", "var"}, // 右括号结束块, 回到 File
+		{"3", "("},
+	} {
+		if got := parent(t, file, s[0]); got != s[1] {
+			t.Fatal(s[0], got)
+		}
+	}
+	if file.Active != file {
+		t.Fatal(file.Active.Token())
+	}
+}
+
+func TestFileUnpaired(t *testing.T) {
+	file := NewFile()
+	if err := file.Push(scanner.Pos(0), token.VAR, "var"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Push(scanner.Pos(4), token.IDENT, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Push(scanner.Pos(5), token.RIGHT, ")"); err == nil {
+		t.Fatal("want unpaired error")
+	}
+}
+
+func TestParentAndTrivia(t *testing.T) {
+	file := NewFile()
+	if err := parser.Parse([]byte("var a = 1 // c\n"), file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Parent() != nil {
+		t.Fatal("File has no parent")
+	}
+	for _, tok := range []token.Token{token.NL, token.COMMENT, token.PLACEHOLDER, token.INDENTATION} {
+		if !IsTrivia(tok) {
+			t.Error(tok)
+		}
+	}
+	for _, tok := range []token.Token{token.IDENT, token.VAR, token.LEFT, token.VALINTEGER} {
+		if IsTrivia(tok) {
+			t.Error(tok)
+		}
+	}
+}
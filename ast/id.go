@@ -0,0 +1,94 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// ID 是声明的稳定标识, 由声明的种类和限定名散列得到.
+// ID 与位置和排版无关, 重新格式化或者修改其它声明时不变, 改名, 改变种类或者移到其它包时改变.
+// 代码审查工具, 文档站点和构建缓存等外部工具可以用它关联数据.
+type ID uint64
+
+// NewID 返回种类为 kind, 限定名为 name 的声明的 ID.
+func NewID(kind, name string) ID {
+	sum := sha256.Sum256([]byte(kind + "\x00" + name))
+	return ID(binary.BigEndian.Uint64(sum[:8]))
+}
+
+// String 返回 16 位十六进制的 id
+func (id ID) String() string { return fmt.Sprintf("%016x", uint64(id)) }
+
+// DeclID 是一个声明及其 ID
+type DeclID struct {
+	ID   ID
+	Kind string // 声明的保留字, 例如 "proc", "var", 类型的字段是 "field", 枚举的变体是 "variant"
+	Name string // 限定名, 例如 "geo.Point.x"
+	Node Syntax // *FuncDecl, *TypeDecl, *Variant, 或者声明项和字段的 *Ident
+}
+
+// DeclIDs 返回 f 中的顶层声明以及类型的字段和变体的 ID, 按源码顺序, pkg 是包名.
+// use 声明和函数体中的声明没有 ID, 同名的重复声明有相同的 ID.
+func DeclIDs(pkg string, f *SourceFile) []DeclID {
+	var list []DeclID
+	add := func(kind, name string, n Syntax) {
+		list = append(list, DeclID{NewID(kind, name), kind, name, n})
+	}
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *GenDecl:
+			if d.Tok == token.USE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				for _, name := range spec.Names {
+					add(d.Tok.String(), qualify(pkg, name.Name.Source), name)
+				}
+			}
+		case *FuncDecl:
+			if d.Name != nil {
+				add(d.Tok.String(), qualify(pkg, d.Name.Name.Source), d)
+			}
+		case *TypeDecl:
+			if d.Name == nil {
+				continue
+			}
+			typ := qualify(pkg, d.Name.Name.Source)
+			add("type", typ, d)
+			for _, spec := range d.Fields {
+				for _, name := range spec.Names {
+					add("field", typ+"."+name.Name.Source, name)
+				}
+			}
+			for _, v := range d.Variants {
+				add("variant", typ+"."+v.Name.Name.Source, v)
+			}
+		}
+	}
+	return list
+}
+
+// ID 返回包 pkg 中的 x 的 ID, 同 DeclIDs.
+func (x *FuncDecl) ID(pkg string) ID {
+	return NewID(x.Tok.String(), qualify(pkg, x.Name.Name.Source))
+}
+
+// ID 返回包 pkg 中的 x 的 ID, 同 DeclIDs.
+func (x *TypeDecl) ID(pkg string) ID {
+	return NewID("type", qualify(pkg, x.Name.Name.Source))
+}
+
+// qualify 返回包 pkg 中的 name 的限定名
+func qualify(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
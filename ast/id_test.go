@@ -0,0 +1,60 @@
+package ast_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestDeclIDs(t *testing.T) {
+	src := `use fmt 'fmt'
+
+var a, b = 1, 2
+
+type Point [
+	int x
+	int y
+]
+
+type Shape enum [
+	Circle(int r)
+	Empty
+]
+
+pub proc main [
+	var local = 1
+]
+`
+	ids := func(src string) (names []string, list []DeclID) {
+		f, err := parser.ParseSyntax([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		list = DeclIDs("geo", f)
+		for _, d := range list {
+			names = append(names, d.Kind+" "+d.Name)
+		}
+		return
+	}
+	names, list := ids(src)
+	want := []string{"var geo.a", "var geo.b", "type geo.Point", "field geo.Point.x", "field geo.Point.y",
+		"type geo.Shape", "variant geo.Shape.Circle", "variant geo.Shape.Empty", "proc geo.main"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatal(names)
+	}
+	if list[8].ID != list[8].Node.(*FuncDecl).ID("geo") || list[2].ID != list[2].Node.(*TypeDecl).ID("geo") ||
+		list[0].ID != NewID("var", "geo.a") || len(list[0].ID.String()) != 16 {
+		t.Fatal(list)
+	}
+
+	// 重新排版, 修改其它声明不改变 ID
+	_, again := ids("\n\nproc main [\n\tprint(1)\n]\nvar  a = 3\ntype Point [\n\tint x\n]\n")
+	if again[0].ID != list[8].ID || again[1].ID != list[0].ID || again[3].ID != list[3].ID {
+		t.Fatal(again)
+	}
+	if NewID("func", "geo.main") == list[8].ID || NewID("proc", "main") == list[8].ID {
+		t.Fatal("want different ID")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"sort"
+
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// sortIndex 返回按 Pos 排序的节点 Id, 不含 File 本身. 节点通常已按 Pos 排列,
+// 但合成的节点使用触发合成的位置, 因此需要单独排序. 结果缓存到 File 再次改变为止.
+func (b *File) sortIndex() []int {
+	if len(b.index) == len(b.Nodes)-1 {
+		return b.index
+	}
+	b.index = make([]int, len(b.Nodes)-1)
+	for i := range b.index {
+		b.index[i] = i + 1
+	}
+	sort.SliceStable(b.index, func(i, j int) bool {
+		return base(b.Nodes[b.index[i]]).Pos < base(b.Nodes[b.index[j]]).Pos
+	})
+	return b.index
+}
+
+// around 返回位于 pos 或者 pos 之前的最后一个节点, 以及其后的节点, 没有时为 nil
+func (b *File) around(pos scanner.Pos) (prev, next Node) {
+	index := b.sortIndex()
+	i := sort.Search(len(index), func(i int) bool {
+		return base(b.Nodes[index[i]]).Pos > pos
+	})
+	if i != 0 {
+		prev = b.Nodes[index[i-1]]
+	}
+	if i < len(index) {
+		next = b.Nodes[index[i]]
+	}
+	return
+}
+
+// NodeAt 返回源码覆盖 pos 的节点, pos 位于节点之间的空白时返回 nil.
+// 查找是对排序索引的二分查找, 索引在首次查询时建立, 因此并发查询前应先查询一次,
+// 或者使用 Snapshot.
+func (b *File) NodeAt(pos scanner.Pos) Node {
+	if n, _ := b.around(pos); n != nil && covers(n, pos) {
+		return n
+	}
+	return nil
+}
+
+// EnclosingPath 返回从 File 到 pos 所在节点的路径, 即 pos 所在节点及其全部祖先,
+// 首个元素总是 b. pos 位于空白时路径结束于前后两个节点最近的共同祖先,
+// 之后没有节点时结束于之前节点的父节点.
+func (b *File) EnclosingPath(pos scanner.Pos) []Node {
+	prev, next := b.around(pos)
+	if prev == nil {
+		return []Node{b}
+	}
+	path := ancestors(prev)
+	if covers(prev, pos) {
+		return path
+	}
+	if next == nil {
+		return path[:len(path)-1]
+	}
+	other := ancestors(next)
+	i := 0
+	for i < len(path) && i < len(other) && path[i] == other[i] {
+		i++
+	}
+	return path[:i]
+}
+
+// covers 返回 n 的源码是否覆盖 pos
+func covers(n Node, pos scanner.Pos) bool {
+	bn := base(n)
+	return bn.Pos <= pos && pos < bn.Pos.Offset(len(bn.Source))
+}
+
+// ancestors 返回从 File 到 n 的路径, 包括 n
+func ancestors(n Node) (path []Node) {
+	for ; n != nil; n = n.Parent() {
+		path = append(path, n)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return
+}
@@ -0,0 +1,53 @@
+package ast_test
+
+import (
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func TestNodeAt(t *testing.T) {
+	src := "var x = 1\nproc f [\n\tout x + 2\n]\n"
+	file := NewFile()
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []struct {
+		pos  int
+		tok  token.Token
+		text string
+		path []token.Token
+	}{
+		{0, token.VAR, "var", []token.Token{token.EOF, token.VAR}},
+		{4, token.IDENT, "x", nil},
+		{9, token.NL, "\n", nil},
+		{30, token.RIGHT, "]", []token.Token{token.EOF, token.PROC, token.LEFT, token.RIGHT}},
+		{24, token.IDENT, "x", []token.Token{token.EOF, token.PROC, token.LEFT, token.OUT, token.IDENT}},
+		{3, token.EOF, "", []token.Token{token.EOF, token.VAR}},
+	} {
+		n := file.NodeAt(scanner.Pos(s.pos))
+		if s.text == "" {
+			if n != nil {
+				t.Fatal(s.pos, n.Token())
+			}
+		} else if n == nil || n.Token() != s.tok || n.Text() != s.text {
+			t.Fatal(s.pos, n)
+		}
+		if s.path == nil {
+			continue
+		}
+		path := file.EnclosingPath(scanner.Pos(s.pos))
+		if len(path) != len(s.path) {
+			t.Fatal(s.pos, len(path))
+		}
+		for i, n := range path {
+			if n.Token() != s.path[i] {
+				t.Fatal(s.pos, i, n.Token())
+			}
+		}
+	}
+}
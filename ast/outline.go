@@ -0,0 +1,130 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// RegionKind 是可折叠区域的种类
+type RegionKind uint8
+
+const (
+	DeclRegion    RegionKind = iota // 声明, 包括函数体中的声明
+	CommentRegion                   // 跨行的注释和顶层占位文本
+	LiteralRegion                   // 跨行的字符串
+)
+
+var regionKinds = [...]string{"decl", "comment", "literal"}
+
+func (k RegionKind) String() string {
+	if int(k) < len(regionKinds) {
+		return regionKinds[k]
+	}
+	return "RegionKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Region 是源码 [Pos, End) 区间的可折叠区域, 区间跨越多行, 不包括最后的换行.
+type Region struct {
+	Kind     RegionKind
+	Pos, End scanner.Pos
+	Level    int    // 嵌套层级, 顶层为 0
+	Name     string // 声明的保留字和名字, 例如 "proc sum", 其它区域为空
+	Node     Node
+}
+
+// Outline 返回 file 中按 Pos 排列的可折叠区域, 编辑器可以据此实现折叠和面包屑导航.
+// 外层区域排在它包含的区域之前.
+func Outline(file *File) []Region {
+	var regions []Region
+	for _, n := range file.Nodes[1:] {
+		var r Region
+		switch tok := n.Token(); {
+		case n.Kind(FDeclaration) != 0:
+			if p := n.Parent(); p != nil && p.Token() == token.PUB {
+				continue
+			}
+			r = declRegion(n)
+		case tok == token.COMMENT || tok == token.COMMENTS || tok == token.PLACEHOLDER:
+			r = textRegion(CommentRegion, n)
+		case tok == token.VALSTRING || tok == token.STRINGLIT:
+			r = textRegion(LiteralRegion, n)
+		default:
+			continue
+		}
+		if r.Node != nil {
+			regions = append(regions, r)
+		}
+	}
+
+	sort.SliceStable(regions, func(i, j int) bool {
+		if regions[i].Pos != regions[j].Pos {
+			return regions[i].Pos < regions[j].Pos
+		}
+		return regions[i].End > regions[j].End
+	})
+	var open []scanner.Pos // 包含当前区域的各层区域的 End
+	for i := range regions {
+		for len(open) != 0 && open[len(open)-1] <= regions[i].Pos {
+			open = open[:len(open)-1]
+		}
+		regions[i].Level = len(open)
+		open = append(open, regions[i].End)
+	}
+	return regions
+}
+
+// declRegion 返回跨越多行的声明 n 的区域, 否则返回零值
+func declRegion(n Node) Region {
+	bn := base(n)
+	end := bn.Pos.Offset(len(bn.Source))
+	tok, name := n.Token(), ""
+	nodes := descendants(n)
+	for _, c := range nodes {
+		t := c.Token()
+		if tok == token.PUB && t != token.PUB && t.As(token.Declare) {
+			tok = t
+		}
+		if name == "" && (t == token.IDENT || t == token.VALSTRING) {
+			name = strings.Trim(c.Text(), `"'`)
+		}
+		if t == token.NL || t == token.INDENTATION || t == token.EMPTYLINE {
+			continue
+		}
+		bc := base(c)
+		if e := bc.Pos.Offset(len(strings.TrimRight(bc.Source, "\r\n"))); e > end {
+			end = e
+		}
+	}
+
+	// 区间内有换行时跨越多行
+	for _, c := range nodes {
+		bc := base(c)
+		if i := strings.IndexByte(bc.Source, '\n'); i != -1 && bc.Pos.Offset(i) < end {
+			if name != "" {
+				name = tok.String() + " " + name
+			} else {
+				name = tok.String()
+			}
+			return Region{Kind: DeclRegion, Pos: bn.Pos, End: end, Name: name, Node: n}
+		}
+	}
+	return Region{}
+}
+
+// textRegion 返回源码跨越多行的节点 n 的区域, 否则返回零值
+func textRegion(kind RegionKind, n Node) Region {
+	bn := base(n)
+	text := strings.TrimRight(bn.Source, "\r\n")
+	if !strings.ContainsRune(text, '\n') {
+		return Region{}
+	}
+	return Region{Kind: kind, Pos: bn.Pos, End: bn.Pos.Offset(len(text)), Node: n}
+}
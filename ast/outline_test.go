@@ -0,0 +1,37 @@
+package ast_test
+
+import (
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestOutline(t *testing.T) {
+	src := "/*\nblock\n*/\npub proc f out int [\n\tvar s = \"a\n  b\"\n\tout 1\n]\nvar x = 1\n"
+	file := NewFile()
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		kind  RegionKind
+		text  string
+		level int
+		name  string
+	}{
+		{CommentRegion, "/*\nblock\n*/", 0, ""},
+		{DeclRegion, src[12 : len(src)-11], 0, "proc f"},
+		{DeclRegion, "var s = \"a\n  b\"", 1, "var s"},
+		{LiteralRegion, "\"a\n  b\"", 2, ""},
+	}
+	regions := Outline(file)
+	if len(regions) != len(want) {
+		t.Fatal(regions)
+	}
+	for i, r := range regions {
+		w := want[i]
+		if r.Kind != w.kind || src[r.Pos:r.End] != w.text || r.Level != w.level || r.Name != w.name {
+			t.Fatalf("%d: %v %q %d %q", i, r.Kind, src[r.Pos:r.End], r.Level, r.Name)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "sort"
+
+// Package 是一个目录中全部源码文件的解析结果, 由 parser.ParseDir 产生.
+// 解析失败的文件仍然保留在 Files 中, 内容截止到出错的位置.
+type Package struct {
+	Name   string           // 目录名
+	Files  map[string]*File // 文件路径到 File
+	Errors map[string]error // 文件路径到解析错误, 没有错误的文件不在其中
+}
+
+// Names 返回排序后的文件路径.
+func (p *Package) Names() []string {
+	names := make([]string, 0, len(p.Files))
+	for name := range p.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
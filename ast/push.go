@@ -0,0 +1,85 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// PushError 是 File.Push 拒绝 Token 的原因.
+// Push 只在 Token 不能成为任何节点时拒绝它, 之后 File 保持不变, 可以继续推送其它 Token.
+type PushError struct {
+	Pos      scanner.Pos
+	Found    token.Token   // 被拒绝的 Token
+	Rule     string        // 拒绝它的语法规则, 参见 RuleName
+	Expected []token.Token // 该处可以接受的 Token, 未知时为空
+}
+
+// Error 返回 "ast: expected X, Y, found Z in RULE at offset N" 形式的消息,
+// Expected 为空时是 "ast: unexpected Z in RULE at offset N".
+func (e *PushError) Error() string {
+	var b strings.Builder
+	b.WriteString("ast: ")
+	if len(e.Expected) == 0 {
+		b.WriteString("unexpected " + e.Found.String())
+	} else {
+		b.WriteString("expected ")
+		for i, tok := range e.Expected {
+			if i != 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(tok.String())
+		}
+		b.WriteString(", found " + e.Found.String())
+	}
+	b.WriteString(" in " + e.Rule + " at offset " + strconv.Itoa(int(e.Pos)))
+	return b.String()
+}
+
+// RuleName 返回容器节点 n 对应的语法规则, 例如 "file", "proc declaration",
+// "if statement" 或 "[ group".
+func RuleName(n Node) string {
+	switch {
+	case n.Kind(FFile) != 0:
+		return "file"
+	case n.Kind(FDeclaration) != 0:
+		return n.Token().String() + " declaration"
+	case n.Kind(FStatement) != 0:
+		return n.Token().String() + " statement"
+	case n.Kind(FChunk) != 0:
+		return n.Token().String() + " group"
+	case n.Kind(FBlock) != 0:
+		return "block"
+	}
+	return "text"
+}
+
+// topLevel 是 File 接受的 Token, 与 File.resolve 一致
+var topLevel = []token.Token{token.USE, token.PUB, token.CONST, token.VAR,
+	token.TYPE, token.STATIC, token.FUNC, token.PROC}
+
+// reject 返回活动节点拒绝位于 pos 的 tok 的错误
+func (b *File) reject(pos scanner.Pos, tok token.Token) *PushError {
+	e := &PushError{Pos: pos, Found: tok, Rule: RuleName(b.Active)}
+	if b.Active == Node(b) {
+		e.Expected = append([]token.Token(nil), topLevel...)
+	}
+	return e
+}
+
+// closing 返回结束分组 n 的 Token, n 不是分组时返回 nil
+func closing(n Node) []token.Token {
+	switch n.Token() {
+	case token.LEFT:
+		return []token.Token{token.RIGHT}
+	case token.INTERPBEGIN:
+		return []token.Token{token.INTERPEND}
+	}
+	return nil
+}
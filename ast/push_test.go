@@ -0,0 +1,35 @@
+package ast_test
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func TestPushError(t *testing.T) {
+	file := NewFile()
+	e, ok := file.Push(3, token.IDENT, "x").(*PushError)
+	if !ok || e.Pos != 3 || e.Found != token.IDENT || e.Rule != "file" || len(e.Expected) == 0 || e.Expected[len(e.Expected)-1] != token.PROC {
+		t.Fatal(e)
+	}
+	// 被拒绝的 Token 不改变 File
+	if file.Len() != 1 || file.Active != Node(file) {
+		t.Fatal(file.Len())
+	}
+
+	for i, tok := range []token.Token{token.PROC, token.IDENT, token.LEFT, token.INTERPBEGIN} {
+		if err := file.Push(scanner.Pos(i*2), tok, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e, ok = file.Push(18, token.RIGHT, "]").(*PushError)
+	if !ok || e.Rule != "INTERPBEGIN group" || !reflect.DeepEqual(e.Expected, []token.Token{token.INTERPEND}) {
+		t.Fatal(e)
+	}
+	if want := "ast: expected INTERPEND, found RIGHT in INTERPBEGIN group at offset 18"; e.Error() != want {
+		t.Fatal(e.Error())
+	}
+}
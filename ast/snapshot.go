@@ -0,0 +1,61 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "errors"
+
+var errFrozen = errors.New("ast: Push to read-only snapshot")
+
+// Snapshot 返回 b 当前状态的只读副本, 副本可以被多个 goroutine 同时读取,
+// 而 b 继续由一个 goroutine 推送 Token. 节点被复制, 源码字符串被共享.
+//
+// 长期运行的服务, 例如语言服务器, 应在每次修改后发布新的快照, 读者持有旧快照不受影响.
+// 快照的 Push 返回错误, 快照的 Snapshot 返回它自己.
+// 解析得到的 File 和 SourceFile 都不应在发布后修改, 需要修改时先复制.
+func (b *File) Snapshot() *File {
+	if b.frozen {
+		return b
+	}
+	s := &File{Base: b.Base, Version: b.Version, frozen: true}
+	s.Nodes = make([]Node, len(b.Nodes))
+	s.Nodes[0] = s
+	for i, n := range b.Nodes[1:] {
+		s.Nodes[i+1] = copyNode(n, s)
+	}
+	s.Active, s.Last = s.Nodes[b.Active.Id()], s.Nodes[b.Last.Id()]
+	// 预先建立索引, 之后的查询不再写入
+	s.sortIndex()
+	return s
+}
+
+// IsSnapshot 返回 b 是否为只读快照
+func (b *File) IsSnapshot() bool { return b.frozen }
+
+// copyNode 返回属于 all 的 n 的副本
+func copyNode(n Node, all *File) Node {
+	switch n := n.(type) {
+	case *Decl:
+		c := *n
+		c.all = all
+		return &c
+	case *Chunk:
+		c := *n
+		c.all = all
+		return &c
+	case *Stmt:
+		c := *n
+		c.all = all
+		return &c
+	case *Expr:
+		c := *n
+		c.all = all
+		return &c
+	case *Text:
+		c := *n
+		c.all = all
+		return &c
+	}
+	panic("ast: Oop! unknown node")
+}
@@ -0,0 +1,58 @@
+package ast_test
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func TestSnapshot(t *testing.T) {
+	file := NewFile()
+	syms, err := parser.Fast([]byte("var x = 1\nproc f [\n\tout x\n]\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sym := range syms[:5] {
+		if err := file.PushSymbol(sym); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snap := file.Snapshot()
+	if !snap.IsSnapshot() || file.IsSnapshot() || snap.Snapshot() != snap || snap.Len() != 6 {
+		t.Fatal(snap.Len())
+	}
+	if err := snap.Push(10, token.NL, "\n"); err == nil {
+		t.Fatal("Push to snapshot")
+	}
+
+	// 读者并发查询快照, 写者继续推送
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				n := snap.NodeAt(4)
+				if n == nil || n.Text() != "x" || n.Parent().Token() != token.VAR || n.Parent().Parent() != Node(snap) {
+					t.Error(n)
+					return
+				}
+			}
+		}()
+	}
+	for _, sym := range syms[5:] {
+		if err := file.PushSymbol(sym); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	if snap.Len() != 6 || file.Len() == 6 || file.NodeAt(scanner.Pos(len("var x = 1\nproc f [\n\tout "))).Text() != "x" {
+		t.Fatal(snap.Len(), file.Len())
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Origin 表示 Token 的来源. 非 OriginSource 的 Token 是合成的, 其 Pos 是触发合成的位置,
+// 诊断信息可以据此说明代码的来历, 格式化工具应该跳过它们.
+type Origin uint8
+
+const (
+	OriginSource  Origin = iota // 来自源码
+	OriginMacro                 // 宏展开, 常量替换
+	OriginInclude               // 包含文件
+	OriginFix                   // 快速修复插入
+)
+
+var origins = [...]string{"source", "macro", "include", "fix"}
+
+func (o Origin) String() string {
+	if int(o) < len(origins) {
+		return origins[o]
+	}
+	return "Origin(" + strconv.Itoa(int(o)) + ")"
+}
+
+// Symbol 是带位置的 Token, 通常由 parser.Fast 产生.
+type Symbol struct {
+	Pos    scanner.Pos
+	Tok    token.Token
+	Source string
+	Origin Origin
+}
+
+// End 返回 s 之后的位置. 合成的 Symbol 的 End 没有意义.
+func (s Symbol) End() scanner.Pos {
+	return s.Pos.Offset(len(s.Source))
+}
+
+// Lines 返回 s 跨越的行数, 即 Source 中的换行数加 1, 结尾的换行不计.
+// 例如合并了块注释的 PLACEHOLDER 跨越多行, 折叠时无需重新扫描.
+func (s Symbol) Lines() int {
+	return strings.Count(strings.TrimSuffix(s.Source, "\n"), "\n") + 1
+}
+
+// Spans 按行拆分 s, 每行一个 Symbol, Tok 和 Origin 与 s 相同, 换行符属于它之前的行.
+// 单行和合成的 Symbol 返回只含 s 的切片.
+func (s Symbol) Spans() []Symbol {
+	if s.Origin != OriginSource || s.Lines() == 1 {
+		return []Symbol{s}
+	}
+	spans := make([]Symbol, 0, s.Lines())
+	pos, src := s.Pos, s.Source
+	for src != "" {
+		n := strings.IndexByte(src, '\n') + 1
+		if n == 0 {
+			n = len(src)
+		}
+		spans = append(spans, Symbol{Pos: pos, Tok: s.Tok, Source: src[:n], Origin: s.Origin})
+		pos, src = pos.Offset(n), src[n:]
+	}
+	return spans
+}
+
+// PushSymbol 同 Push, 但新建的节点记录 sym.Origin.
+// 被合并到已有节点的 Token 不改变该节点的 Origin.
+func (b *File) PushSymbol(sym Symbol) error {
+	b.origin = sym.Origin
+	err := b.Push(sym.Pos, sym.Tok, sym.Source)
+	b.origin = OriginSource
+	return err
+}
+
+// OriginOf 返回节点 n 的来源.
+func OriginOf(n Node) Origin {
+	if b := base(n); b != nil {
+		return b.Origin
+	}
+	return OriginSource
+}
+
+// FromSymbols 把 Symbol 序列 syms 构建为 File, 无需重新扫描源码.
+//
+// 与 parser.Parse 一样, 顶层的非声明 Symbol 合并为一个 PLACEHOLDER,
+// 相邻 Symbol 之间的间隔按 Pos 补为空格, 因为 Fast 只丢弃 SPACES.
+// EOF 被忽略. EMPTYLINE 直接作为 Text 节点, 因此 ToSymbols 的结果可以还原.
+// 合并的顶层占位总是 OriginSource.
+//
+// 出错时返回已构建的部分 File.
+func FromSymbols(syms []Symbol) (file *File, err error) {
+	var top []Symbol // 待合并的顶层占位
+	file = NewFile()
+
+	flush := func() error {
+		if len(top) == 0 {
+			return nil
+		}
+		var b strings.Builder
+		end := top[0].Pos
+		for _, sym := range top {
+			if gap := int(sym.Pos - end); gap > 0 {
+				b.WriteString(strings.Repeat(" ", gap))
+			}
+			b.WriteString(sym.Source)
+			end = sym.Pos + scanner.Pos(len(sym.Source))
+		}
+		pos := top[0].Pos
+		top = top[:0]
+		return file.Push(pos, token.PLACEHOLDER, b.String())
+	}
+
+	for _, sym := range syms {
+		switch {
+		case sym.Tok == token.EOF:
+			continue
+		case file.Active == file && !sym.Tok.As(token.Declare):
+			top = append(top, sym)
+			continue
+		}
+		if err = flush(); err != nil {
+			return
+		}
+		if sym.Tok == token.EMPTYLINE {
+			err = file.add(Base{Flag: FText, Tok: sym.Tok, Pos: sym.Pos, Source: sym.Source, Origin: sym.Origin})
+		} else {
+			err = file.PushSymbol(sym)
+		}
+		if err != nil {
+			return
+		}
+	}
+	err = flush()
+	return
+}
+
+// ToSymbols 按节点顺序把 file 展开为 Symbol 序列, 不含 File 本身.
+// 合并过的节点, 例如 EMPTYLINE, 展开为一个 Symbol.
+func ToSymbols(file *File) []Symbol {
+	syms := make([]Symbol, 0, file.Len()-1)
+	for _, n := range file.Nodes[1:] {
+		b := base(n)
+		syms = append(syms, Symbol{Pos: b.Pos, Tok: b.Tok, Source: b.Source, Origin: b.Origin})
+	}
+	return syms
+}
+
+// base 返回节点 n 的 Base.
+func base(n Node) *Base {
+	switch n := n.(type) {
+	case *Decl:
+		return &n.Base
+	case *Chunk:
+		return &n.Base
+	case *Stmt:
+		return &n.Base
+	case *Expr:
+		return &n.Base
+	case *Text:
+		return &n.Base
+	case *File:
+		return &n.Base
+	}
+	return nil
+}
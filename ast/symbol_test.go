@@ -0,0 +1,80 @@
+package ast_test
+
+import (
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+var symbolSources = []string{
+	"",
+	"var int x = 1\n",
+	"top placeholder\nuse \"os\"\nvar x = 1 + 2\n",
+	"func sum(int a, int b) int {\n\tout a + b\n}\n",
+	"var x = 1\n\n\n  \nvar y = \"a{x}b\"\n",
+}
+
+type symbolNode struct {
+	Symbol
+	kind   Flag
+	parent int
+}
+
+func shape(file *File) (s []symbolNode) {
+	for i, sym := range ToSymbols(file) {
+		n := file.Nodes[i+1]
+		s = append(s, symbolNode{sym, n.Kind(0xFF), n.Parent().Id()})
+	}
+	return
+}
+
+func TestSymbols(t *testing.T) {
+	for _, src := range symbolSources {
+		file := NewFile()
+		if err := parser.Parse([]byte(src), file); err != nil {
+			t.Fatal(src, err)
+		}
+		got, err := FromSymbols(ToSymbols(file))
+		if err != nil {
+			t.Fatal(src, err)
+		}
+		want, have := shape(file), shape(got)
+		if len(want) != len(have) {
+			t.Fatal(src, want, have)
+		}
+		for i := range want {
+			if want[i] != have[i] {
+				t.Fatal(src, i, want[i], have[i])
+			}
+		}
+	}
+}
+
+func TestFromSymbols(t *testing.T) {
+	src := "top placeholder\nvar x = 1\nnot a decl  here\nvar y = x\n"
+	syms, err := parser.Fast([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := FromSymbols(syms)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var texts []string
+	for _, n := range file.Nodes[1:] {
+		if n.Parent() == Node(file) {
+			texts = append(texts, n.Text())
+		}
+	}
+	want := []string{"top placeholder\n", "var", "\n", "not a decl  here\n", "var", "\n"}
+	if len(texts) != len(want) {
+		t.Fatal(texts)
+	}
+	for i := range want {
+		if texts[i] != want[i] {
+			t.Fatalf("%q", texts)
+		}
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Syntax 是类型化的语法树节点, 由 parser.ParseSyntax 产生.
+//
+// File 的节点是扁平的 Token, 需要按结构分支的工具, 例如检查, 重构,
+// 可以对 Syntax 做类型分支. Expression 也是 Syntax.
+// 换行, 缩进, 注释和占位文本不在 Syntax 之中.
+type Syntax interface {
+	Pos() scanner.Pos // 首个 Token 的位置
+	End() scanner.Pos // 最后一个 Token 之后的位置
+}
+
+type (
+	// SourceFile 是一个文件的顶层声明
+	SourceFile struct {
+		Header *Header    // 开头的 shebang 行和 front-matter 块, 都没有时为 nil
+		Build  Constraint // 首个声明之前的 zxx:build 构建约束, 没有时为 nil
+		Decls  []Syntax
+	}
+
+	// Header 是源码开头的 shebang 行 #!... 和之后由 +++ 行定界的 front-matter 块.
+	// 二者对语义没有影响, 供运行脚本和读取元数据的工具使用, front-matter 的格式由工具决定.
+	Header struct {
+		From, To    scanner.Pos // 源码区间 [From, To)
+		Shebang     string      // #! 之后到行尾, 没有时为空
+		FrontMatter string      // 定界行之间的源码, 包括最后的换行, 没有时为空
+		HasFront    bool        // 是否有 front-matter 块, 块可以为空
+	}
+
+	// BadSyntax 是无法识别的一段源码 [From, To)
+	BadSyntax struct {
+		From, To scanner.Pos
+	}
+
+	// GenDecl 是 use, const, static, var 声明.
+	// 分组写法 var (...), var [...] 或者 var {...} 的 Lparen, Rparen 是括号的位置, 否则为 0.
+	GenDecl struct {
+		Pub    scanner.Pos // pub 的位置, 没有 pub 时为 -1
+		TokPos scanner.Pos
+		Tok    token.Token
+		Type   []Symbol // 分组之前各项共用的类型, 例如 var datetime (...) 的 datetime, 可以为空
+		Lparen scanner.Pos
+		Specs  []*ValueSpec
+		Rparen scanner.Pos
+	}
+
+	// ValueSpec 是一个声明项 Type Names = Values.
+	// use 声明项的 Names 是可选的别名, Values 是路径字符串. 类型的匿名字段只有 Type.
+	ValueSpec struct {
+		Type   []Symbol // 类型 Token, 可以为空
+		Names  []*Ident
+		Values []Expression
+	}
+
+	// FuncDecl 是 func, proc 声明, proc 的 Name 可以是 MEMBER, 即类型方法 T.name.
+	// 参数表不带括号时 Lparen, Rparen 为 0, func 声明的这种参数没有名字. 没有函数体时 Body 为 nil.
+	FuncDecl struct {
+		Pub        scanner.Pos // pub 的位置, 没有 pub 时为 -1
+		TokPos     scanner.Pos
+		Tok        token.Token
+		Name       *Ident
+		TypeParams *TypeParams // 名字之后的类型参数, 没有时为 nil
+		Lparen     scanner.Pos
+		Params     []*Field
+		Rparen     scanner.Pos
+		Results    []Symbol // out 之后的类型 Token, 或者 func int f(...) 中名字之前的结果类型
+		Body       *BlockStmt
+	}
+
+	// TypeDecl 是 type Name Type, type Name [ 字段 ] 或者 type Name enum [ 变体 ], 块也可以是 { },
+	// 名字之后可以有类型参数. 字段的写法同 var 声明项, 变体每行一个.
+	// 有块时 Type 为空, 否则 Lbrack, Rbrack 为 0. 不是枚举时 Enum 为 0.
+	TypeDecl struct {
+		Pub        scanner.Pos // pub 的位置, 没有 pub 时为 -1
+		TokPos     scanner.Pos
+		Name       *Ident
+		TypeParams *TypeParams
+		Type       []Symbol
+		Enum       scanner.Pos
+		Lbrack     scanner.Pos
+		Fields     []*ValueSpec
+		Variants   []*Variant
+		Rbrack     scanner.Pos
+	}
+
+	// Variant 是枚举的变体 Name 或者带有关联值的 Name(Type Name, ...).
+	// 没有关联值时 Lparen, Rparen 为 0.
+	Variant struct {
+		Name   *Ident
+		Lparen scanner.Pos
+		Params []*Field
+		Rparen scanner.Pos
+	}
+
+	// TypeParams 是紧随名字的 [Constraint Name, ...], Field.Type 是可选的约束
+	TypeParams struct {
+		Lbrack scanner.Pos
+		List   []*Field
+		Rbrack scanner.Pos
+	}
+
+	// Field 是参数 Type Name, 不带括号的 func 参数没有 Name
+	Field struct {
+		Type []Symbol
+		Name *Ident
+	}
+
+	// BlockStmt 是 [ List ] 或者 { List }
+	BlockStmt struct {
+		Lbrack scanner.Pos
+		List   []Syntax
+		Rbrack scanner.Pos
+	}
+
+	// ExprStmt 是单独的表达式, 通常是调用
+	ExprStmt struct {
+		X Expression
+	}
+
+	// AssignStmt 是 Lhs = Rhs, 以及 Lhs++, Lhs-- 此时 Rhs 为空
+	AssignStmt struct {
+		Lhs    []Expression
+		TokPos scanner.Pos
+		Tok    token.Token // ASSIGN, INC 或 DEC
+		Rhs    []Expression
+	}
+
+	// IfStmt 是 if Cond Body else Else, Else 是 nil, *BlockStmt 或 *IfStmt
+	IfStmt struct {
+		If   scanner.Pos
+		Cond Expression
+		Body *BlockStmt
+		Else Syntax
+	}
+
+	// ForStmt 是 for Init; Cond; Post Body, 三者都可以为 nil
+	ForStmt struct {
+		For  scanner.Pos
+		Init Syntax
+		Cond Expression
+		Post Syntax
+		Body *BlockStmt
+	}
+
+	// RangeStmt 是 for X as Key Value Body, Value 可以为 nil.
+	// 列表绑定下标和元素, 映射绑定键和值, 字符串绑定字节偏移量和字符, 范围 a..b 是整数列表.
+	RangeStmt struct {
+		For   scanner.Pos
+		X     Expression
+		As    scanner.Pos
+		Key   *Ident
+		Value *Ident
+		Body  *BlockStmt
+	}
+
+	// SwitchStmt 是 switch Tag Body, Body 中的 case, default 是 *CaseClause
+	SwitchStmt struct {
+		Switch scanner.Pos
+		Tag    Expression
+		Body   *BlockStmt
+	}
+
+	// CaseClause 是 case List: 或者 default:, 之后的语句属于同一个 Body
+	CaseClause struct {
+		TokPos scanner.Pos
+		Tok    token.Token // CASE 或 DEFAULT
+		List   []Expression
+		Colon  scanner.Pos
+	}
+
+	// BranchStmt 是 break, continue, goto 以及可选的 Label
+	BranchStmt struct {
+		TokPos scanner.Pos
+		Tok    token.Token
+		Label  *Ident
+	}
+
+	// GoStmt 是 go Call 或 defer Call
+	GoStmt struct {
+		TokPos scanner.Pos
+		Tok    token.Token // GO 或 DEFER
+		Call   Expression
+	}
+
+	// OutStmt 是 out Results
+	OutStmt struct {
+		Out     scanner.Pos
+		Results []Expression
+	}
+)
+
+// symEnd 返回 sym 之后的位置
+func symEnd(sym Symbol) scanner.Pos { return sym.Pos.Offset(len(sym.Source)) }
+
+func (x *SourceFile) Pos() scanner.Pos {
+	if len(x.Decls) == 0 {
+		return 0
+	}
+	return x.Decls[0].Pos()
+}
+
+func (x *SourceFile) End() scanner.Pos {
+	if len(x.Decls) == 0 {
+		return 0
+	}
+	return x.Decls[len(x.Decls)-1].End()
+}
+
+func (x *BadSyntax) Pos() scanner.Pos { return x.From }
+func (x *BadSyntax) End() scanner.Pos { return x.To }
+
+func (x *GenDecl) Pos() scanner.Pos {
+	if x.Pub >= 0 {
+		return x.Pub
+	}
+	return x.TokPos
+}
+
+func (x *GenDecl) End() scanner.Pos {
+	switch {
+	case x.Lparen != 0:
+		return x.Rparen + 1
+	case len(x.Specs) != 0:
+		return x.Specs[len(x.Specs)-1].End()
+	}
+	return x.TokPos.Offset(len(x.Tok.String()))
+}
+
+func (x *ValueSpec) Pos() scanner.Pos {
+	switch {
+	case len(x.Type) != 0:
+		return x.Type[0].Pos
+	case len(x.Names) != 0:
+		return x.Names[0].Pos()
+	case len(x.Values) != 0:
+		return x.Values[0].Pos()
+	}
+	return 0
+}
+
+func (x *ValueSpec) End() scanner.Pos {
+	switch {
+	case len(x.Values) != 0:
+		return x.Values[len(x.Values)-1].End()
+	case len(x.Names) != 0:
+		return x.Names[len(x.Names)-1].End()
+	case len(x.Type) != 0:
+		return symEnd(x.Type[len(x.Type)-1])
+	}
+	return 0
+}
+
+func (x *FuncDecl) Pos() scanner.Pos {
+	if x.Pub >= 0 {
+		return x.Pub
+	}
+	return x.TokPos
+}
+
+func (x *FuncDecl) End() scanner.Pos {
+	switch {
+	case x.Body != nil:
+		return x.Body.End()
+	case len(x.Results) != 0 && (x.Name == nil || x.Results[0].Pos > x.Name.Pos()):
+		return symEnd(x.Results[len(x.Results)-1])
+	case x.Lparen != 0:
+		return x.Rparen + 1
+	case len(x.Params) != 0:
+		return x.Params[len(x.Params)-1].End()
+	case x.TypeParams != nil:
+		return x.TypeParams.End()
+	case x.Name != nil:
+		return x.Name.End()
+	}
+	return x.TokPos.Offset(len(x.Tok.String()))
+}
+
+// ResultTypes 返回各个结果的类型 Token, 即 Results 按括号之外的逗号分开, 没有结果时返回 nil.
+func (x *FuncDecl) ResultTypes() [][]Symbol {
+	var list [][]Symbol
+	depth, start := 0, 0
+	for i, sym := range x.Results {
+		switch sym.Tok {
+		case token.LEFT:
+			depth++
+		case token.RIGHT:
+			depth--
+		case token.COMMA:
+			if depth == 0 {
+				list = append(list, x.Results[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(x.Results) {
+		list = append(list, x.Results[start:])
+	}
+	return list
+}
+
+func (x *TypeDecl) Pos() scanner.Pos {
+	if x.Pub >= 0 {
+		return x.Pub
+	}
+	return x.TokPos
+}
+
+func (x *TypeDecl) End() scanner.Pos {
+	switch {
+	case x.Lbrack != 0:
+		return x.Rbrack + 1
+	case len(x.Type) != 0:
+		return symEnd(x.Type[len(x.Type)-1])
+	case x.TypeParams != nil:
+		return x.TypeParams.End()
+	case x.Name != nil:
+		return x.Name.End()
+	}
+	return x.TokPos.Offset(len("type"))
+}
+
+func (x *Variant) Pos() scanner.Pos { return x.Name.Pos() }
+
+func (x *Variant) End() scanner.Pos {
+	if x.Lparen != 0 {
+		return x.Rparen + 1
+	}
+	return x.Name.End()
+}
+
+func (x *TypeParams) Pos() scanner.Pos { return x.Lbrack }
+func (x *TypeParams) End() scanner.Pos { return x.Rbrack + 1 }
+
+func (x *Field) Pos() scanner.Pos {
+	if len(x.Type) != 0 {
+		return x.Type[0].Pos
+	}
+	return x.Name.Pos()
+}
+
+func (x *Field) End() scanner.Pos {
+	if x.Name != nil {
+		return x.Name.End()
+	}
+	return symEnd(x.Type[len(x.Type)-1])
+}
+
+func (x *BlockStmt) Pos() scanner.Pos  { return x.Lbrack }
+func (x *BlockStmt) End() scanner.Pos  { return x.Rbrack + 1 }
+func (x *ExprStmt) Pos() scanner.Pos   { return x.X.Pos() }
+func (x *ExprStmt) End() scanner.Pos   { return x.X.End() }
+func (x *AssignStmt) Pos() scanner.Pos { return x.Lhs[0].Pos() }
+
+func (x *AssignStmt) End() scanner.Pos {
+	if len(x.Rhs) == 0 {
+		return x.TokPos.Offset(2)
+	}
+	return x.Rhs[len(x.Rhs)-1].End()
+}
+
+func (x *IfStmt) Pos() scanner.Pos { return x.If }
+
+func (x *IfStmt) End() scanner.Pos {
+	if x.Else != nil {
+		return x.Else.End()
+	}
+	return x.Body.End()
+}
+
+func (x *ForStmt) Pos() scanner.Pos    { return x.For }
+func (x *ForStmt) End() scanner.Pos    { return x.Body.End() }
+func (x *RangeStmt) Pos() scanner.Pos  { return x.For }
+func (x *RangeStmt) End() scanner.Pos  { return x.Body.End() }
+func (x *SwitchStmt) Pos() scanner.Pos { return x.Switch }
+func (x *SwitchStmt) End() scanner.Pos { return x.Body.End() }
+func (x *CaseClause) Pos() scanner.Pos { return x.TokPos }
+func (x *CaseClause) End() scanner.Pos { return x.Colon + 1 }
+func (x *BranchStmt) Pos() scanner.Pos { return x.TokPos }
+
+func (x *BranchStmt) End() scanner.Pos {
+	if x.Label != nil {
+		return x.Label.End()
+	}
+	return x.TokPos.Offset(len(x.Tok.String()))
+}
+
+func (x *GoStmt) Pos() scanner.Pos  { return x.TokPos }
+func (x *GoStmt) End() scanner.Pos  { return x.Call.End() }
+func (x *OutStmt) Pos() scanner.Pos { return x.Out }
+
+func (x *OutStmt) End() scanner.Pos {
+	if len(x.Results) != 0 {
+		return x.Results[len(x.Results)-1].End()
+	}
+	return x.Out.Offset(len("out"))
+}
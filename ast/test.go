@@ -0,0 +1,92 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"strings"
+
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// TestPrefix 是测试声明的名字前缀. 测试声明是名字以 TestPrefix 开始的顶层 proc,
+// 例如
+//
+//	proc testSum out bool [
+//		out 1 + 2 == 3
+//	]
+//
+// zxx test 执行测试声明.
+const TestPrefix = "test"
+
+// TestName 返回测试声明 n 的名字, n 不是测试声明时 ok 为 false.
+func TestName(n Node) (name string, ok bool) {
+	fn := declared(n)
+	if fn == nil || fn.Token() != token.PROC || n.Parent() == nil || n.Parent().Id() != 0 {
+		return
+	}
+	for _, c := range descendants(n) {
+		if c.Token() == token.IDENT {
+			name = c.Text()
+			return name, strings.HasPrefix(name, TestPrefix)
+		}
+	}
+	return
+}
+
+// Body 返回 proc, func 声明 n 的函数体中直属的语句和声明节点, 没有函数体时返回 nil.
+func Body(n Node) (stmts []Node) {
+	fn := declared(n)
+	if fn == nil || fn.Token() != token.PROC && fn.Token() != token.FUNC {
+		return nil
+	}
+	var body Node
+	for _, c := range descendants(n) {
+		if body != nil && c.Parent() == body && c.Kind(FDeclaration|FChunk|FStatement) != 0 && c.Token() != token.RIGHT {
+			stmts = append(stmts, c)
+		}
+		if c.Token() == token.LEFT && (c.Parent() == n || c.Parent() == fn) && c.Text() != "(" {
+			body = c
+		}
+	}
+	return
+}
+
+// Tail 返回语句 n 的保留字之后的源码及其位置, 例如 out 语句的表达式.
+// 节点之间的空白写作一个空格, 行尾的换行和注释不属于结果.
+func Tail(n Node) (pos scanner.Pos, src string) {
+	var b strings.Builder
+	end := scanner.Pos(-1)
+	for _, c := range descendants(n) {
+		if IsTrivia(c.Token()) {
+			continue
+		}
+		bc := base(c)
+		if end == -1 {
+			pos = bc.Pos
+		} else if bc.Pos != end {
+			b.WriteByte(' ')
+		}
+		b.WriteString(bc.Source)
+		end = bc.Pos.Offset(len(bc.Source))
+	}
+	return pos, b.String()
+}
+
+// declared 返回声明 n 的保留字节点, pub 之后的保留字优先
+func declared(n Node) Node {
+	if n == nil || n.Kind(FDeclaration) == 0 {
+		return nil
+	}
+	if n.Token() != token.PUB {
+		return n
+	}
+	for _, c := range descendants(n) {
+		if c.Token().As(token.Declare) && c.Token() != token.PUB {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package ast_test
+
+import (
+	"testing"
+
+	. "github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestTestName(t *testing.T) {
+	file := NewFile()
+	src := "proc testSum out bool [\n\tout 1+2 == 3 // c\n]\npub proc testB out bool [\n\tvar x = 1\n\tout len(x[0]) > 1\n]\nproc helper [\n]\n"
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var bodies [][]Node
+	for _, n := range file.Nodes[1:] {
+		if n.Kind(FDeclaration) == 0 || n.Parent().Id() != 0 {
+			continue
+		}
+		if name, ok := TestName(n); ok {
+			names = append(names, name)
+			bodies = append(bodies, Body(n))
+		}
+	}
+	if len(names) != 2 || names[0] != "testSum" || names[1] != "testB" {
+		t.Fatal(names)
+	}
+	if len(bodies[0]) != 1 || len(bodies[1]) != 2 || bodies[1][0].Text() != "var" {
+		t.Fatal(bodies)
+	}
+	if pos, s := Tail(bodies[0][0]); pos != 29 || s != "1+2 == 3" {
+		t.Fatal(pos, s)
+	}
+	if _, s := Tail(bodies[1][1]); s != "len(x[0]) > 1" {
+		t.Fatal(s)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ast
+
+import "github.com/ZxxLang/zxx/scanner"
+
+// Expression 是结构化的表达式树, 由 parser.ParseExpr 产生.
+//
+// File 中的表达式是扁平的 Token 序列, 需要运算结构的工具, 例如求值, 类型检查,
+// 可以用 parser.ParseExprSymbols 把一段 Symbol 解析为 Expression.
+type Expression interface {
+	Pos() scanner.Pos // 首个 Token 的位置
+	End() scanner.Pos // 最后一个 Token 之后的位置
+	expression()
+}
+
+type (
+	// BasicLit 是字面值: null, 字符串, 整数, 浮点数, datetime, bool, 或者插值字符串的片段 STRINGLIT
+	BasicLit struct {
+		Value Symbol
+	}
+
+	// Ident 是名字或者成员, Tok 可以是 IDENT, MEMBER, MEMBERS, 用作值的类型是类型保留字
+	Ident struct {
+		Name Symbol
+	}
+
+	// ParenExpr 是 (X)
+	ParenExpr struct {
+		Lparen scanner.Pos
+		X      Expression
+		Rparen scanner.Pos
+	}
+
+	// ListExpr 是 [a, b]
+	ListExpr struct {
+		Lbrack scanner.Pos
+		Elems  []Expression
+		Rbrack scanner.Pos
+	}
+
+	// MapExpr 是 [k: v, ...] 或者 {k: v, ...}, 空映射是 [:] 或者 {}
+	MapExpr struct {
+		Lbrack scanner.Pos
+		Elems  []*KeyValueExpr
+		Rbrack scanner.Pos
+	}
+
+	// KeyValueExpr 是映射中的 Key: Value
+	KeyValueExpr struct {
+		Key   Expression
+		Colon scanner.Pos
+		Value Expression
+	}
+
+	// InterpExpr 是插值字符串 "x {a + 1} y", Parts 中的片段和插值表达式交替出现, 首尾都是片段.
+	// 片段是 Tok 为 STRINGLIT 的 *BasicLit, 首尾片段包括引号, 相邻的插值之间是空的片段.
+	InterpExpr struct {
+		Parts []Expression
+	}
+
+	// UnaryExpr 是 Op X, Op 可以是 SUB, PLUS, NOT, ANTI
+	UnaryExpr struct {
+		Op Symbol
+		X  Expression
+	}
+
+	// BinaryExpr 是 X Op Y
+	BinaryExpr struct {
+		X  Expression
+		Op Symbol
+		Y  Expression
+	}
+
+	// CallExpr 是 Fun(Args), '(' 紧随 Fun.
+	// 语句中的命令调用 Fun Arg, Arg... 没有括号, 此时 Lparen, Rparen 为 0.
+	CallExpr struct {
+		Fun    Expression
+		Lparen scanner.Pos
+		Args   []Expression
+		Rparen scanner.Pos
+	}
+
+	// IndexExpr 是 X[Index], '[' 紧随 X
+	IndexExpr struct {
+		X      Expression
+		Lbrack scanner.Pos
+		Index  Expression
+		Rbrack scanner.Pos
+	}
+)
+
+func (x *BasicLit) Pos() scanner.Pos     { return x.Value.Pos }
+func (x *Ident) Pos() scanner.Pos        { return x.Name.Pos }
+func (x *ParenExpr) Pos() scanner.Pos    { return x.Lparen }
+func (x *ListExpr) Pos() scanner.Pos     { return x.Lbrack }
+func (x *MapExpr) Pos() scanner.Pos      { return x.Lbrack }
+func (x *KeyValueExpr) Pos() scanner.Pos { return x.Key.Pos() }
+func (x *InterpExpr) Pos() scanner.Pos   { return x.Parts[0].Pos() }
+func (x *UnaryExpr) Pos() scanner.Pos    { return x.Op.Pos }
+func (x *BinaryExpr) Pos() scanner.Pos   { return x.X.Pos() }
+func (x *CallExpr) Pos() scanner.Pos     { return x.Fun.Pos() }
+func (x *IndexExpr) Pos() scanner.Pos    { return x.X.Pos() }
+
+func (x *BasicLit) End() scanner.Pos     { return x.Value.Pos.Offset(len(x.Value.Source)) }
+func (x *Ident) End() scanner.Pos        { return x.Name.Pos.Offset(len(x.Name.Source)) }
+func (x *ParenExpr) End() scanner.Pos    { return x.Rparen + 1 }
+func (x *ListExpr) End() scanner.Pos     { return x.Rbrack + 1 }
+func (x *MapExpr) End() scanner.Pos      { return x.Rbrack + 1 }
+func (x *KeyValueExpr) End() scanner.Pos { return x.Value.End() }
+func (x *InterpExpr) End() scanner.Pos   { return x.Parts[len(x.Parts)-1].End() }
+func (x *UnaryExpr) End() scanner.Pos    { return x.X.End() }
+func (x *BinaryExpr) End() scanner.Pos   { return x.Y.End() }
+func (x *IndexExpr) End() scanner.Pos    { return x.Rbrack + 1 }
+
+func (x *CallExpr) End() scanner.Pos {
+	if x.Rparen == 0 && len(x.Args) != 0 {
+		return x.Args[len(x.Args)-1].End()
+	}
+	return x.Rparen + 1
+}
+
+func (*BasicLit) expression()     {}
+func (*Ident) expression()        {}
+func (*ParenExpr) expression()    {}
+func (*ListExpr) expression()     {}
+func (*MapExpr) expression()      {}
+func (*KeyValueExpr) expression() {}
+func (*InterpExpr) expression()   {}
+func (*UnaryExpr) expression()    {}
+func (*BinaryExpr) expression()   {}
+func (*CallExpr) expression()     {}
+func (*IndexExpr) expression()    {}
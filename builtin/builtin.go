@@ -0,0 +1,225 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包登记 zxx 的内置函数, 每个函数有类型化的签名, eval 和 vm 使用的实现,
+// 以及 Go 后端对应的运行时调用.
+//
+// 类型检查可以用 Lookup 取得签名并用 Sig.Check 检查实参的类型, 用 Binary 推导运算的类型,
+// 求值时把 Env 返回的环境与宿主的环境合并即可调用内置函数.
+// 实现在调用之前按签名检查实参, 错误信息与类型检查一致.
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ZxxLang/zxx/constant"
+	"github.com/ZxxLang/zxx/eval"
+)
+
+// 签名中的类型名与 zxx 的类型关键字相同, Any 表示任意类型, Null 是 null 的类型.
+// int 可以用于 f64 参数, null 只能用于 Any 参数.
+const (
+	Any      = "any"
+	Null     = "null"
+	Bool     = "bool"
+	Int      = "int"
+	F64      = "f64"
+	String   = "string"
+	Datetime = "datetime"
+	Duration = "duration"
+	Decimal  = "decimal"
+	Array    = "array"
+	Map      = "map"
+	Function = "func"
+	Channel  = "chan"
+)
+
+// Sig 是函数签名, Variadic 时最后一个参数可以重复零次或多次.
+type Sig struct {
+	Params   []string
+	Variadic bool
+	Result   string // 没有结果时为空
+}
+
+func (s Sig) String() string {
+	params := append([]string(nil), s.Params...)
+	if s.Variadic {
+		params[len(params)-1] = "..." + params[len(params)-1]
+	}
+	str := "func(" + strings.Join(params, ", ") + ")"
+	if s.Result != "" {
+		str += " " + s.Result
+	}
+	return str
+}
+
+// Check 检查类型为 args 的实参能否用于签名 s, args 中的 Any 表示类型未知, 总是可以.
+func (s Sig) Check(args []string) error {
+	n := len(s.Params)
+	if s.Variadic && len(args) < n-1 || !s.Variadic && len(args) != n {
+		return errors.New("wrong number of arguments, want " + s.String())
+	}
+	for i, arg := range args {
+		want := s.Params[n-1]
+		if i < n {
+			want = s.Params[i]
+		}
+		if !assignable(arg, want) {
+			return errors.New("cannot use " + arg + " as " + want + " argument")
+		}
+	}
+	return nil
+}
+
+func assignable(typ, to string) bool {
+	return typ == to || to == Any || typ == Any || typ == Int && to == F64
+}
+
+// TypeOf 返回求值结果 v 的类型名, 环境中未经转换的 Go 值返回其 Go 类型名.
+func TypeOf(v eval.Value) string {
+	switch v.(type) {
+	case nil:
+		return Null
+	case bool:
+		return Bool
+	case int64:
+		return Int
+	case float64:
+		return F64
+	case string:
+		return String
+	case time.Time:
+		return Datetime
+	case time.Duration:
+		return Duration
+	case constant.Value:
+		return Decimal
+	case []eval.Value:
+		return Array
+	case map[string]eval.Value:
+		return Map
+	case eval.Func:
+		return Function
+	case *Chan:
+		return Channel
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Go 是 Go 后端生成的调用, Import 是需要导入的包, 可以为空.
+// Call 是调用表达式的模板, $1, $2 ... 是对应的实参, $* 是逗号分隔的全部实参,
+// 例如 "strings.ToUpper($1)", "$1.AddDate(0, 0, int($2))".
+type Go struct {
+	Import string
+	Call   string
+}
+
+// Expand 返回以 args 为实参的 Go 表达式, args 是实参的 Go 代码.
+func (g Go) Expand(args []string) string {
+	var b strings.Builder
+	s := g.Call
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i+1 == len(s) {
+			break
+		}
+		b.WriteString(s[:i])
+		s = s[i+1:]
+		switch c := s[0]; {
+		case c == '*':
+			b.WriteString(strings.Join(args, ", "))
+			s = s[1:]
+		case c >= '1' && c <= '9':
+			if n := int(c - '1'); n < len(args) {
+				b.WriteString(args[n])
+			}
+			s = s[1:]
+		default:
+			b.WriteByte('$')
+		}
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// Func 是一个内置函数
+type Func struct {
+	Name string
+	Sig  Sig
+	Go   Go
+
+	// StringGo 是第一个实参为 string 时 Go 后端生成的调用, Call 为空时使用 Go.
+	// 例如字符串的 len 是字符个数, 不是 Go 中的字节数.
+	StringGo Go
+
+	// Impl 是 eval, vm 使用的实现, 实参已经按 Sig 检查, int 实参未转换为 f64.
+	// out 是 print 等函数的输出.
+	Impl func(out io.Writer, args []eval.Value) (eval.Value, error)
+}
+
+// GoCall 返回实参类型为 types 时 Go 后端生成的调用
+func (f *Func) GoCall(types []string) Go {
+	if f.StringGo.Call != "" && len(types) != 0 && types[0] == String {
+		return f.StringGo
+	}
+	return f.Go
+}
+
+var registry = map[string]*Func{}
+
+// Register 登记内置函数 f, 名字重复时 panic. 嵌入者可以登记自己的函数.
+func Register(f *Func) {
+	if _, ok := registry[f.Name]; ok {
+		panic("builtin: duplicate function " + f.Name)
+	}
+	registry[f.Name] = f
+}
+
+// Lookup 返回名为 name 的内置函数
+func Lookup(name string) (*Func, bool) {
+	f, ok := registry[name]
+	return f, ok
+}
+
+// Names 按字母顺序返回全部内置函数的名字
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Env 返回包含全部内置函数的环境, 输出写到 out, out 为 nil 时写到 os.Stdout.
+func Env(out io.Writer) map[string]eval.Value {
+	if out == nil {
+		out = os.Stdout
+	}
+	env := make(map[string]eval.Value, len(registry))
+	for name, f := range registry {
+		env[name] = f.bind(out)
+	}
+	return env
+}
+
+// bind 返回输出到 out 的 eval.Func
+func (f *Func) bind(out io.Writer) eval.Func {
+	return func(args ...eval.Value) (eval.Value, error) {
+		types := make([]string, len(args))
+		for i, arg := range args {
+			types[i] = TypeOf(arg)
+		}
+		if err := f.Sig.Check(types); err != nil {
+			return nil, errors.New(f.Name + ": " + err.Error())
+		}
+		return f.Impl(out, args)
+	}
+}
@@ -0,0 +1,162 @@
+package builtin_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/ZxxLang/zxx/builtin"
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/token"
+	"github.com/ZxxLang/zxx/vm"
+)
+
+func TestEnv(t *testing.T) {
+	var out bytes.Buffer
+	env := builtin.Env(&out)
+	env["d"] = time.Date(2016, 2, 28, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		src  string
+		want eval.Value
+	}{
+		{"len('abc') + len([1, 2])", int64(5)},
+		{"sqrt(16) + abs(-1.5) + floor(2)", 7.5},
+		{"pow(2, 10)", 1024.0},
+		{"upper(trim(' ab ')) + lower('C')", "ABc"},
+		{"contains('hello', 'ell') and not contains('a', 'b')", true},
+		{"join(split('a,b,c', ','), '-')", "a-b-c"},
+		{"replace('aaa', 'a', 'b')", "bbb"},
+		{"month(adddays(d, 2)) * 100 + day(adddays(d, 2))", int64(301)},
+		{"year(now()) >= 2016", true},
+		{"len('中文ab') * 10 + bytelen('中文ab')", int64(48)},
+		{"substr('你好世界', 1, 3) + join(runes('中a'), '|')", "好世中|a"},
+		{"indexof('你好世界', '世') * 10 + indexof('ab', 'c')", int64(19)},
+		{"startswith('中文', '中') and endswith('中文', '文')", true},
+		{"repeat('哈', 3)", "哈哈哈"},
+	}
+	for _, tt := range tests {
+		got, err := eval.Expr(tt.src, env)
+		if err != nil || got != tt.want {
+			t.Errorf("eval %s = %v, %v; want %v", tt.src, got, err, tt.want)
+		}
+		p, err := vm.CompileString(tt.src)
+		if err == nil {
+			got, err = vm.Run(p, env)
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("vm %s = %v, %v; want %v", tt.src, got, err, tt.want)
+		}
+	}
+
+	if _, err := eval.Expr("print('a', 1)", env); err != nil || out.String() != "a 1\n" {
+		t.Fatalf("%q %v", out.String(), err)
+	}
+	for _, src := range []string{"len(1)", "upper(1)", "pow(1)", "join([1], '')", "sqrt(len)",
+		"substr('中文', 1, 3)", "substr('ab', 2, 1)", "repeat('a', -1)"} {
+		if _, err := eval.Expr(src, env); err == nil {
+			t.Errorf("%s: no error", src)
+		}
+	}
+}
+
+func TestChan(t *testing.T) {
+	env := builtin.Env(nil)
+	c, err := eval.Expr("chan(2)", env)
+	if err != nil || builtin.TypeOf(c) != builtin.Channel {
+		t.Fatal(c, err)
+	}
+	env["c"] = c
+	for _, src := range []string{"send(c, 1)", "send(c, 'a')", "close(c)"} {
+		if _, err := eval.Expr(src, env); err != nil {
+			t.Fatal(src, err)
+		}
+	}
+	for _, want := range []eval.Value{int64(1), "a", nil} {
+		if v, err := eval.Expr("recv(c)", env); err != nil || v != want {
+			t.Errorf("recv = %v, %v; want %v", v, err, want)
+		}
+	}
+	for _, src := range []string{"close(c)", "send(c, 2)", "chan(-1)", "recv(1)"} {
+		if _, err := eval.Expr(src, env); err == nil {
+			t.Errorf("%s: no error", src)
+		}
+	}
+}
+
+func TestSig(t *testing.T) {
+	f, ok := builtin.Lookup("replace")
+	if !ok || f.Sig.String() != "func(string, string, string) string" {
+		t.Fatal(f)
+	}
+	if err := f.Sig.Check([]string{builtin.String, builtin.Any, builtin.String}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Sig.Check([]string{builtin.String, builtin.Int, builtin.String}); err == nil {
+		t.Fatal("int accepted as string")
+	}
+	l, _ := builtin.Lookup("len")
+	if got := l.GoCall([]string{builtin.String}).Expand([]string{"s"}); got != "int64(utf8.RuneCountInString(s))" {
+		t.Fatal(got)
+	}
+	if got := l.GoCall([]string{builtin.Array}).Expand([]string{"a"}); got != "int64(len(a))" {
+		t.Fatal(got)
+	}
+	p, _ := builtin.Lookup("print")
+	if p.Sig.String() != "func(...any)" || p.Sig.Check(nil) != nil {
+		t.Fatal(p.Sig)
+	}
+	if s, _ := builtin.Lookup("sqrt"); s.Sig.Check([]string{builtin.Int}) != nil {
+		t.Fatal("int not accepted as f64")
+	}
+
+	if got := f.Go.Expand([]string{"s", `"a"`, `"b"`}); got != `strings.ReplaceAll(s, "a", "b")` {
+		t.Fatal(got)
+	}
+	if got := p.Go.Expand([]string{"a", "b"}); got != "fmt.Println(a, b)" {
+		t.Fatal(got)
+	}
+	if len(builtin.Names()) < 10 || builtin.Names()[0] != "abs" {
+		t.Fatal(builtin.Names())
+	}
+}
+
+func TestBinary(t *testing.T) {
+	tests := []struct {
+		x    string
+		op   token.Token
+		y    string
+		want string
+	}{
+		{builtin.Datetime, token.ADD, builtin.Duration, builtin.Datetime},
+		{builtin.Datetime, token.SUB, builtin.Duration, builtin.Datetime},
+		{builtin.Duration, token.PLUS, builtin.Datetime, builtin.Datetime},
+		{builtin.Datetime, token.SUB, builtin.Datetime, builtin.Duration},
+		{builtin.Duration, token.MULSIGN, builtin.Int, builtin.Duration},
+		{builtin.F64, token.MUL, builtin.Duration, builtin.Duration},
+		{builtin.Duration, token.DIV, builtin.Duration, builtin.F64},
+		{builtin.Datetime, token.LSS, builtin.Datetime, builtin.Bool},
+		{builtin.Int, token.SHL, builtin.Int, builtin.Int},
+		{builtin.Int, token.ADD, builtin.F64, builtin.F64},
+		{builtin.Any, token.SUB, builtin.Int, builtin.Any},
+		{builtin.Datetime, token.ADD, builtin.Datetime, ""},
+		{builtin.Duration, token.SUB, builtin.Datetime, ""},
+		{builtin.Int, token.DIV, builtin.Duration, ""},
+		{builtin.F64, token.MOD, builtin.Int, ""},
+	}
+	for _, tt := range tests {
+		got, err := builtin.Binary(tt.op, tt.x, tt.y)
+		if got != tt.want || (err == nil) != (tt.want != "") {
+			t.Errorf("%s %s %s = %q, %v", tt.x, tt.op, tt.y, got, err)
+		}
+	}
+
+	env := builtin.Env(nil)
+	env["d"] = time.Date(2016, 2, 28, 0, 0, 0, 0, time.UTC)
+	v, err := eval.Expr("d + days(1.5) - hours(12) == adddays(d, 1)", env)
+	if err != nil || v != true {
+		t.Fatal(v, err)
+	}
+	if v, err = eval.Expr("minutes(1) / seconds(15)", env); err != nil || v != 4.0 {
+		t.Fatal(v, err)
+	}
+}
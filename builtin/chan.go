@@ -0,0 +1,79 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package builtin
+
+import (
+	"errors"
+	"io"
+
+	"github.com/ZxxLang/zxx/eval"
+)
+
+// Chan 是 chan 内置函数创建的通道, 元素可以是任意值.
+// 与 go 语句配合使用, 可以在多个 goroutine 中同时收发.
+type Chan struct {
+	c chan eval.Value
+}
+
+// maxBuffer 是通道缓冲区大小的上限
+const maxBuffer = 1 << 20
+
+func init() {
+	for _, f := range chanFuncs {
+		Register(f)
+	}
+}
+
+var chanFuncs = []*Func{
+	{
+		Name: "chan",
+		Sig:  sig(Channel, Int),
+		Go:   Go{"", "make(chan any, $1)"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			n := args[0].(int64)
+			if n < 0 || n > maxBuffer {
+				return nil, errors.New("chan: buffer size out of range")
+			}
+			return &Chan{make(chan eval.Value, n)}, nil
+		},
+	},
+	{
+		Name: "send",
+		Sig:  sig("", Channel, Any),
+		Go:   Go{"", "$1 <- $2"},
+		Impl: func(_ io.Writer, args []eval.Value) (v eval.Value, err error) {
+			defer func() {
+				if recover() != nil {
+					err = errors.New("send: send on closed channel")
+				}
+			}()
+			args[0].(*Chan).c <- args[1]
+			return nil, nil
+		},
+	},
+	{
+		Name: "recv",
+		Sig:  sig(Any, Channel),
+		Go:   Go{"", "<-$1"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			// 已关闭并且为空的通道返回 null
+			return <-args[0].(*Chan).c, nil
+		},
+	},
+	{
+		Name: "close",
+		Sig:  sig("", Channel),
+		Go:   Go{"", "close($1)"},
+		Impl: func(_ io.Writer, args []eval.Value) (v eval.Value, err error) {
+			defer func() {
+				if recover() != nil {
+					err = errors.New("close: close of closed channel")
+				}
+			}()
+			close(args[0].(*Chan).c)
+			return nil, nil
+		},
+	},
+}
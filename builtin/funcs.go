@@ -0,0 +1,206 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package builtin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ZxxLang/zxx/eval"
+)
+
+func init() {
+	for _, f := range funcs {
+		Register(f)
+	}
+}
+
+// sig 返回参数为 params 结果为 result 的签名
+func sig(result string, params ...string) Sig {
+	return Sig{Params: params, Result: result}
+}
+
+// f64 返回 int 或 f64 实参的 float64 值
+func f64(v eval.Value) float64 {
+	x, _ := eval.Float(v)
+	return x
+}
+
+// math1 返回 f64 -> f64 的内置函数
+func math1(name string, fn func(float64) float64, goCall string) *Func {
+	return &Func{
+		Name: name,
+		Sig:  sig(F64, F64),
+		Go:   Go{"math", goCall},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return fn(f64(args[0])), nil
+		},
+	}
+}
+
+// str1 返回 string -> string 的内置函数
+func str1(name string, fn func(string) string, goCall string) *Func {
+	return &Func{
+		Name: name,
+		Sig:  sig(String, String),
+		Go:   Go{"strings", goCall},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return fn(args[0].(string)), nil
+		},
+	}
+}
+
+// span 返回 f64 -> duration 的内置函数, unit 是单位时长
+func span(name string, unit time.Duration, goUnit string) *Func {
+	return &Func{
+		Name: name,
+		Sig:  sig(Duration, F64),
+		Go:   Go{"time", "time.Duration($1 * float64(" + goUnit + "))"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			d := f64(args[0]) * float64(unit)
+			if math.IsNaN(d) || d > math.MaxInt64 || d < math.MinInt64 {
+				return nil, errors.New(name + ": duration overflow")
+			}
+			return time.Duration(d), nil
+		},
+	}
+}
+
+// date1 返回 datetime -> int 的内置函数
+func date1(name string, fn func(time.Time) int, goCall string) *Func {
+	return &Func{
+		Name: name,
+		Sig:  sig(Int, Datetime),
+		Go:   Go{"", goCall},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return int64(fn(args[0].(time.Time))), nil
+		},
+	}
+}
+
+var funcs = []*Func{
+	{
+		Name: "print",
+		Sig:  Sig{Params: []string{Any}, Variadic: true},
+		Go:   Go{"fmt", "fmt.Println($*)"},
+		Impl: func(out io.Writer, args []eval.Value) (eval.Value, error) {
+			list := make([]interface{}, len(args))
+			for i, x := range args {
+				list[i] = x
+			}
+			_, err := fmt.Fprintln(out, list...)
+			return nil, err
+		},
+	},
+	{
+		Name:     "len",
+		Sig:      sig(Int, Any),
+		Go:       Go{"", "int64(len($1))"},
+		StringGo: Go{"unicode/utf8", "int64(utf8.RuneCountInString($1))"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			switch x := args[0].(type) {
+			case string:
+				return int64(utf8.RuneCountInString(x)), nil
+			case []eval.Value:
+				return int64(len(x)), nil
+			case map[string]eval.Value:
+				return int64(len(x)), nil
+			}
+			return nil, errors.New("len: invalid argument type " + TypeOf(args[0]))
+		},
+	},
+
+	math1("abs", math.Abs, "math.Abs($1)"),
+	math1("floor", math.Floor, "math.Floor($1)"),
+	math1("ceil", math.Ceil, "math.Ceil($1)"),
+	math1("sqrt", math.Sqrt, "math.Sqrt($1)"),
+	{
+		Name: "pow",
+		Sig:  sig(F64, F64, F64),
+		Go:   Go{"math", "math.Pow($1, $2)"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return math.Pow(f64(args[0]), f64(args[1])), nil
+		},
+	},
+
+	str1("upper", strings.ToUpper, "strings.ToUpper($1)"),
+	str1("lower", strings.ToLower, "strings.ToLower($1)"),
+	str1("trim", strings.TrimSpace, "strings.TrimSpace($1)"),
+	{
+		Name: "contains",
+		Sig:  sig(Bool, String, String),
+		Go:   Go{"strings", "strings.Contains($1, $2)"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return strings.Contains(args[0].(string), args[1].(string)), nil
+		},
+	},
+	{
+		Name: "replace",
+		Sig:  sig(String, String, String, String),
+		Go:   Go{"strings", "strings.ReplaceAll($1, $2, $3)"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return strings.Replace(args[0].(string), args[1].(string), args[2].(string), -1), nil
+		},
+	},
+	{
+		Name: "split",
+		Sig:  sig(Array, String, String),
+		Go:   Go{"strings", "strings.Split($1, $2)"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			parts := strings.Split(args[0].(string), args[1].(string))
+			list := make([]eval.Value, len(parts))
+			for i, s := range parts {
+				list[i] = s
+			}
+			return list, nil
+		},
+	},
+	{
+		Name: "join",
+		Sig:  sig(String, Array, String),
+		Go:   Go{"strings", "strings.Join($1, $2)"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			list := args[0].([]eval.Value)
+			parts := make([]string, len(list))
+			for i, x := range list {
+				s, ok := x.(string)
+				if !ok {
+					return nil, errors.New("join: element is " + TypeOf(x) + ", not string")
+				}
+				parts[i] = s
+			}
+			return strings.Join(parts, args[1].(string)), nil
+		},
+	},
+
+	{
+		Name: "now",
+		Sig:  sig(Datetime),
+		Go:   Go{"time", "time.Now()"},
+		Impl: func(io.Writer, []eval.Value) (eval.Value, error) {
+			return time.Now(), nil
+		},
+	},
+	date1("year", time.Time.Year, "int64($1.Year())"),
+	date1("month", func(t time.Time) int { return int(t.Month()) }, "int64($1.Month())"),
+	date1("day", time.Time.Day, "int64($1.Day())"),
+	span("seconds", time.Second, "time.Second"),
+	span("minutes", time.Minute, "time.Minute"),
+	span("hours", time.Hour, "time.Hour"),
+	span("days", 24*time.Hour, "24 * time.Hour"),
+	{
+		Name: "adddays",
+		Sig:  sig(Datetime, Datetime, Int),
+		Go:   Go{"", "$1.AddDate(0, 0, int($2))"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return args[0].(time.Time).AddDate(0, 0, int(args[1].(int64))), nil
+		},
+	},
+}
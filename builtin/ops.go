@@ -0,0 +1,114 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package builtin
+
+import (
+	"errors"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Binary 返回二元运算 x op y 的结果类型, x, y 是操作数的类型, 规则与 eval.Binary 一致.
+// 操作数的类型为 Any 时结果也是 Any.
+//
+//	datetime ± duration  datetime
+//	duration + datetime  datetime
+//	datetime - datetime  duration
+//	duration ± duration  duration
+//	duration * 数值      duration, 数值 * duration 相同
+//	duration / 数值      duration
+//	duration / duration  f64
+//	decimal 与 decimal, int 运算  decimal, 不能与 f64 混合运算
+func Binary(op token.Token, x, y string) (string, error) {
+	invalid := errors.New("invalid operation " + x + " " + op.String() + " " + y)
+	switch op {
+	case token.EQL, token.NEQ, token.HAS:
+		return Bool, nil
+	case token.AND, token.OR:
+		if x == y {
+			return x, nil
+		}
+		return Any, nil
+	}
+	if x == Any || y == Any {
+		return Any, nil
+	}
+
+	cmp := false
+	switch op {
+	case token.LSS, token.LEQ, token.GTR, token.GEQ:
+		cmp = true
+	}
+	num := func(t string) bool { return t == Int || t == F64 }
+	add := op == token.ADD || op == token.PLUS
+	sub := op == token.SUB
+	mul := op == token.MUL || op == token.MULSIGN
+	div := op == token.DIV || op == token.DIVSIGN
+
+	switch {
+	case x == String && y == String:
+		switch {
+		case cmp:
+			return Bool, nil
+		case add || sub:
+			return String, nil
+		}
+	case x == Datetime && y == Datetime:
+		switch {
+		case cmp:
+			return Bool, nil
+		case sub:
+			return Duration, nil
+		}
+	case x == Datetime && y == Duration, x == Duration && y == Datetime:
+		if add || sub && x == Datetime {
+			return Datetime, nil
+		}
+	case x == Duration && y == Duration:
+		switch {
+		case cmp:
+			return Bool, nil
+		case add || sub:
+			return Duration, nil
+		case div:
+			return F64, nil
+		}
+	case x == Duration && num(y), num(x) && y == Duration:
+		if mul || div && x == Duration {
+			return Duration, nil
+		}
+	case x == Decimal && (y == Decimal || y == Int), x == Int && y == Decimal:
+		switch {
+		case cmp:
+			return Bool, nil
+		case add || sub || mul || div:
+			return Decimal, nil
+		}
+	case num(x) && num(y):
+		switch {
+		case cmp:
+			return Bool, nil
+		case x == Int && y == Int:
+			if intOp(op) {
+				return Int, nil
+			}
+		case add || sub || mul || div:
+			return F64, nil
+		}
+	}
+	return "", invalid
+}
+
+// intOp 返回 op 是否为整数运算
+func intOp(op token.Token) bool {
+	switch op {
+	case token.ADD, token.PLUS, token.SUB, token.MUL, token.MULSIGN,
+		token.DIV, token.DIVSIGN, token.MOD, token.REM,
+		token.BITAND, token.BITOR, token.XOR,
+		token.SHL, token.SHLSIGN, token.SHR, token.SHRSIGN:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package builtin
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ZxxLang/zxx/eval"
+)
+
+// 字符串是 UTF-8 编码的文本, len, 下标, substr 和 indexof 都以字符 (rune) 计算,
+// 不是字节. 中文等多字节字符的长度是 1, bytelen 返回字节数.
+
+// maxRepeat 是 repeat 结果的字节数上限
+const maxRepeat = 1 << 24
+
+func init() {
+	for _, f := range stringFuncs {
+		Register(f)
+	}
+}
+
+var stringFuncs = []*Func{
+	{
+		Name: "bytelen",
+		Sig:  sig(Int, String),
+		Go:   Go{"", "int64(len($1))"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return int64(len(args[0].(string))), nil
+		},
+	},
+	{
+		Name: "runes",
+		Sig:  sig(Array, String),
+		Go:   Go{"strings", `strings.Split($1, "")`},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return eval.Runes(args[0].(string)), nil
+		},
+	},
+	{
+		Name: "substr",
+		Sig:  sig(String, String, Int, Int),
+		Go:   Go{"", "string([]rune($1)[$2:$3])"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			s := args[0].(string)
+			i, j := args[1].(int64), args[2].(int64)
+			if i < 0 || j < i || j > int64(utf8.RuneCountInString(s)) {
+				return nil, errors.New("substr: index out of range")
+			}
+			return s[offset(s, i):offset(s, j)], nil
+		},
+	},
+	{
+		Name: "indexof",
+		Sig:  sig(Int, String, String),
+		Go: Go{"strings", "func(s, t string) int64 { if i := strings.Index(s, t); i >= 0 { " +
+			"return int64(len([]rune(s[:i]))) }; return -1 }($1, $2)"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			s := args[0].(string)
+			i := strings.Index(s, args[1].(string))
+			if i < 0 {
+				return int64(-1), nil
+			}
+			return int64(utf8.RuneCountInString(s[:i])), nil
+		},
+	},
+	{
+		Name: "startswith",
+		Sig:  sig(Bool, String, String),
+		Go:   Go{"strings", "strings.HasPrefix($1, $2)"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return strings.HasPrefix(args[0].(string), args[1].(string)), nil
+		},
+	},
+	{
+		Name: "endswith",
+		Sig:  sig(Bool, String, String),
+		Go:   Go{"strings", "strings.HasSuffix($1, $2)"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			return strings.HasSuffix(args[0].(string), args[1].(string)), nil
+		},
+	},
+	{
+		Name: "repeat",
+		Sig:  sig(String, String, Int),
+		Go:   Go{"strings", "strings.Repeat($1, int($2))"},
+		Impl: func(_ io.Writer, args []eval.Value) (eval.Value, error) {
+			s, n := args[0].(string), args[1].(int64)
+			if n < 0 {
+				return nil, errors.New("repeat: negative count")
+			}
+			if len(s) != 0 && n > maxRepeat/int64(len(s)) {
+				return nil, errors.New("repeat: result too long")
+			}
+			return strings.Repeat(s, int(n)), nil
+		},
+	},
+}
+
+// offset 返回 s 中第 n 个字符的字节偏移量, n 等于字符数时返回 len(s)
+func offset(s string, n int64) int {
+	i := 0
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
@@ -0,0 +1,179 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包在磁盘上缓存解析结果, 使未修改的文件无需重新解析.
+//
+// 缓存项以源码和 Version 的 SHA-256 为键, 内容是 ast.ToSymbols 的结果,
+// 读取时用 ast.FromSymbols 还原, 无需重新扫描源码. 缓存目录超过容量时
+// 按最近使用时间淘汰. 目前只有解析结果, 类型信息等将来按同样的键保存.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+// Version 是缓存格式和工具链的版本, 参与计算键, 改变 ast 或 parser 的输出时需要更新.
+const Version = "zxx-cache-1"
+
+// ext 是缓存项的文件扩展名, 淘汰时只删除此类文件
+const ext = ".ast"
+
+// Cache 是目录 Dir 中的缓存, 可以并发使用.
+type Cache struct {
+	Dir string
+	Max int64 // 缓存项的总字节数上限, 0 表示不限制
+
+	mu   sync.Mutex
+	size int64 // 估计的总字节数, 小于 0 表示未知
+}
+
+// entry 是缓存项的内容
+type entry struct {
+	Version string
+	Symbols []ast.Symbol
+}
+
+// Open 返回目录 dir 中容量为 max 字节的缓存, 目录不存在时创建.
+func Open(dir string, max int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: dir, Max: max, size: -1}, nil
+}
+
+// Key 返回源码 src 的缓存键
+func Key(src []byte) string {
+	h := sha256.New()
+	h.Write([]byte(Version))
+	h.Write([]byte{0})
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key[:2], key+ext)
+}
+
+// Get 返回 src 缓存的 File. 缓存项不存在或者损坏时 ok 为 false.
+func (c *Cache) Get(src []byte) (file *ast.File, ok bool) {
+	name := c.path(Key(src))
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, false
+	}
+	var e entry
+	if gob.NewDecoder(bytes.NewReader(data)).Decode(&e) != nil || e.Version != Version {
+		return nil, false
+	}
+	if file, err = ast.FromSymbols(e.Symbols); err != nil {
+		return nil, false
+	}
+	// 修改时间即最近使用时间
+	now := time.Now()
+	os.Chtimes(name, now, now)
+	return file, true
+}
+
+// Put 缓存 src 的解析结果 file, 然后按容量淘汰最久未使用的缓存项.
+func (c *Cache) Put(src []byte, file *ast.File) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entry{Version, ast.ToSymbols(file)}); err != nil {
+		return err
+	}
+
+	name := c.path(Key(src))
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		return err
+	}
+	// 先写临时文件再改名, 并发的 Get 不会读到写了一半的缓存项
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size >= 0 {
+		c.size += int64(buf.Len())
+	}
+	if c.Max > 0 && (c.size < 0 || c.size > c.Max) {
+		return c.trim()
+	}
+	return nil
+}
+
+// Parse 返回 src 的 File, 优先使用缓存, 否则用 parser.Parse 解析并缓存.
+// 写缓存失败不影响结果.
+func (c *Cache) Parse(src []byte) (*ast.File, error) {
+	if file, ok := c.Get(src); ok {
+		return file, nil
+	}
+	file := ast.NewFile()
+	if err := parser.Parse(src, file); err != nil {
+		return file, err
+	}
+	c.Put(src, file)
+	return file, nil
+}
+
+// trim 删除最久未使用的缓存项, 直到总字节数不超过 Max
+func (c *Cache) trim() error {
+	type item struct {
+		name string
+		size int64
+		used time.Time
+	}
+	var items []item
+	var total int64
+	err := filepath.Walk(c.Dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(name, ext) {
+			items = append(items, item{name, info.Size(), info.ModTime()})
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].used.Before(items[j].used) })
+	for _, it := range items {
+		if total <= c.Max {
+			break
+		}
+		if err := os.Remove(it.name); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= it.size
+	}
+	c.size = total
+	return nil
+}
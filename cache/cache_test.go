@@ -0,0 +1,79 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZxxLang/zxx/ast"
+	. "github.com/ZxxLang/zxx/cache"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestCache(t *testing.T) {
+	c, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := []byte("top\nvar x = 1 + 2\nfunc f() {\n\tout x\n}\n")
+	if _, ok := c.Get(src); ok {
+		t.Fatal("empty cache hit")
+	}
+	want, err := c.Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.Get(src)
+	if !ok || got.Len() != want.Len() {
+		t.Fatal(ok, got, want)
+	}
+	for i, sym := range ast.ToSymbols(want) {
+		if ast.ToSymbols(got)[i] != sym {
+			t.Fatal(i, sym)
+		}
+	}
+	if _, ok := c.Get(append(src, '\n')); ok {
+		t.Fatal("changed source hit")
+	}
+}
+
+func TestTrim(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcs := [][]byte{[]byte("var a = 1\n"), []byte("var b = 2\n"), []byte("var c = 3\n")}
+	for i, src := range srcs[:2] {
+		file := ast.NewFile()
+		if err := parser.Parse(src, file); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Put(src, file); err != nil {
+			t.Fatal(err)
+		}
+		// a 比 b 更久未使用
+		key := Key(src)
+		old := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(filepath.Join(dir, key[:2], key+".ast"), old, old)
+	}
+	info, err := os.Stat(filepath.Join(dir, Key(srcs[0])[:2], Key(srcs[0])+".ast"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 容量只够两项, 使用 a 之后加入 c, 淘汰 b
+	c.Max = 2*info.Size() + info.Size()/2
+	if _, ok := c.Get(srcs[0]); !ok {
+		t.Fatal("miss a")
+	}
+	if _, err := c.Parse(srcs[2]); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{true, false, true} {
+		if _, ok := c.Get(srcs[i]); ok != want {
+			t.Fatal(i, ok)
+		}
+	}
+}
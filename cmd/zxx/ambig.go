@@ -0,0 +1,42 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+
+	"github.com/ZxxLang/zxx/ambig"
+)
+
+func init() {
+	commands["ambig"] = &command{
+		usage: "ambig [-n count] [-size n] [-seed n]",
+		run:   runAmbig,
+	}
+}
+
+func runAmbig(args []string) int {
+	flags := flag.NewFlagSet("ambig", flag.ExitOnError)
+	n := flags.Int("n", 10000, "number of generated inputs")
+	size := flags.Int("size", 8, "maximum symbols per input")
+	seed := flags.Int64("seed", 1, "random seed")
+	flags.Parse(args)
+	if *n <= 0 || *size <= 0 {
+		fmt.Fprintln(os.Stderr, "usage: zxx", commands["ambig"].usage)
+		return 2
+	}
+
+	found := ambig.Search(rand.New(rand.NewSource(*seed)), *n, *size, nil)
+	for _, c := range found {
+		fmt.Println(c)
+	}
+	if len(found) != 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,188 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func init() {
+	commands["ast"] = &command{
+		usage: "ast [-format tree|json|dot] file...",
+		run:   runAST,
+	}
+}
+
+func runAST(args []string) int {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	format := flags.String("format", "tree", "output format: tree, json or dot")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: zxx", commands["ast"].usage)
+		return 2
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = dumpAST(os.Stdout, src, *format)
+		}
+		if err != nil {
+			report(path, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// astNode 是输出的语法树节点. Symbol 是叶子, 只有 Tok, Source 和 Pos.
+type astNode struct {
+	Field    string     `json:"field,omitempty"` // 在父节点中的字段, 例如 "Decls[0]"
+	Type     string     `json:"type"`
+	Pos      string     `json:"pos"` // line:column
+	End      string     `json:"end,omitempty"`
+	Tok      string     `json:"tok,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	Children []*astNode `json:"children,omitempty"`
+}
+
+// dumpAST 按格式 format 在 w 上输出源码 src 的类型化语法树
+func dumpAST(w io.Writer, src []byte, format string) error {
+	file, err := parser.ParseSyntax(src)
+	if err != nil {
+		return err
+	}
+	f := scanner.NewFileSet().AddFile("", src)
+	position := func(pos scanner.Pos) string {
+		return f.Position(f.Pos(int(pos))).String("")
+	}
+	root := newASTNode("", reflect.ValueOf(file), position)
+
+	switch format {
+	case "tree":
+		writeTree(w, root, 0)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(root)
+	case "dot":
+		fmt.Fprintln(w, "digraph ast {\n\tnode [shape=box, fontname=monospace];")
+		writeDot(w, root, new(int))
+		fmt.Fprintln(w, "}")
+	default:
+		return errors.New("unknown format " + strconv.Quote(format))
+	}
+	return nil
+}
+
+var (
+	symbolType = reflect.TypeOf(ast.Symbol{})
+	tokenType  = reflect.TypeOf(token.Token(0))
+)
+
+// newASTNode 返回节点 v 的 astNode, v 是语法树节点的指针或 ast.Symbol.
+// 节点的 Token 字段记为 Tok, 其它位置字段被省略, 它们可以从子节点的位置得知.
+func newASTNode(field string, v reflect.Value, position func(scanner.Pos) string) *astNode {
+	if v.Type() == symbolType {
+		sym := v.Interface().(ast.Symbol)
+		return &astNode{field, "Symbol", position(sym.Pos), "", sym.Tok.String(), sym.Source, nil}
+	}
+
+	x := v.Interface().(interface {
+		Pos() scanner.Pos
+		End() scanner.Pos
+	})
+	n := &astNode{Field: field, Type: v.Elem().Type().Name(), Pos: position(x.Pos()), End: position(x.End())}
+
+	s := v.Elem()
+	for i := 0; i < s.NumField(); i++ {
+		name, fv := s.Type().Field(i).Name, s.Field(i)
+		switch {
+		case fv.Type() == tokenType:
+			n.Tok = token.Token(fv.Int()).String()
+		case fv.Kind() == reflect.Slice:
+			for j := 0; j < fv.Len(); j++ {
+				if child := dumpValue(name+"["+strconv.Itoa(j)+"]", fv.Index(j), position); child != nil {
+					n.Children = append(n.Children, child)
+				}
+			}
+		default:
+			if child := dumpValue(name, fv, position); child != nil {
+				n.Children = append(n.Children, child)
+			}
+		}
+	}
+	return n
+}
+
+// dumpValue 返回字段值 v 的 astNode, v 不是节点或者为 nil 时返回 nil
+func dumpValue(field string, v reflect.Value, position func(scanner.Pos) string) *astNode {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch {
+	case !v.IsValid():
+		return nil
+	case v.Type() == symbolType:
+	case v.Kind() != reflect.Ptr || v.IsNil():
+		return nil
+	}
+	return newASTNode(field, v, position)
+}
+
+// writeTree 以缩进 depth 层的树形输出节点 n
+func writeTree(w io.Writer, n *astNode, depth int) {
+	var b strings.Builder
+	b.WriteString(strings.Repeat("  ", depth))
+	if n.Field != "" {
+		b.WriteString(n.Field + ": ")
+	}
+	b.WriteString(n.label(" "))
+	fmt.Fprintln(w, b.String())
+	for _, child := range n.Children {
+		writeTree(w, child, depth+1)
+	}
+}
+
+// writeDot 输出 Graphviz dot 格式的节点 n 及其到子节点的边, id 是下一个节点的编号
+func writeDot(w io.Writer, n *astNode, id *int) int {
+	self := *id
+	*id++
+	fmt.Fprintf(w, "\tn%d [label=%s];\n", self, strconv.Quote(n.label("\n")))
+	for _, child := range n.Children {
+		c := writeDot(w, child, id)
+		fmt.Fprintf(w, "\tn%d -> n%d [label=%s];\n", self, c, strconv.Quote(child.Field))
+	}
+	return self
+}
+
+// label 返回节点 n 的描述, sep 分隔类型, Token 和位置
+func (n *astNode) label(sep string) string {
+	s := n.Type
+	if n.Type == "Symbol" {
+		s = n.Tok + " " + strconv.Quote(n.Source)
+	} else if n.Tok != "" {
+		s += " " + n.Tok
+	}
+	if n.End != "" {
+		return s + sep + n.Pos + "-" + n.End
+	}
+	return s + sep + n.Pos
+}
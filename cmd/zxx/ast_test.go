@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDumpAST(t *testing.T) {
+	src := []byte("var x = f(1)\n")
+	var out strings.Builder
+	if err := dumpAST(&out, src, "tree"); err != nil {
+		t.Fatal(err)
+	}
+	want := `SourceFile 1:1-1:13
+  Decls[0]: GenDecl var 1:1-1:13
+    Specs[0]: ValueSpec 1:5-1:13
+      Names[0]: Ident 1:5-1:6
+        Name: IDENT "x" 1:5
+      Values[0]: CallExpr 1:9-1:13
+        Fun: Ident 1:9-1:10
+          Name: IDENT "f" 1:9
+        Args[0]: BasicLit 1:11-1:12
+          Value: VALINTEGER "1" 1:11
+`
+	if out.String() != want {
+		t.Fatalf("%s", out.String())
+	}
+
+	out.Reset()
+	if err := dumpAST(&out, src, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var root astNode
+	if err := json.Unmarshal([]byte(out.String()), &root); err != nil {
+		t.Fatal(err)
+	}
+	if decl := root.Children[0]; decl.Field != "Decls[0]" || decl.Tok != "var" || decl.End != "1:13" {
+		t.Fatalf("%+v", decl)
+	}
+
+	out.Reset()
+	if err := dumpAST(&out, src, "dot"); err != nil {
+		t.Fatal(err)
+	}
+	if s := out.String(); !strings.HasPrefix(s, "digraph ast {") ||
+		!strings.Contains(s, `n9 [label="VALINTEGER \"1\"\n1:11"];`) || !strings.Contains(s, `n8 -> n9 [label="Value"];`) {
+		t.Fatal(s)
+	}
+
+	if err := dumpAST(&out, src, "xml"); err == nil {
+		t.Fatal("unknown format")
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/doc"
+	"github.com/ZxxLang/zxx/highlight"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+func init() {
+	commands["cat"] = &command{
+		usage: "cat [-n] [-color=false] [-decls] file...",
+		run:   runCat,
+	}
+}
+
+// catOptions 是 zxx cat 的选项
+type catOptions struct {
+	number bool // 输出行号
+	color  bool // ANSI 着色
+	decls  bool // 在源码之前列出声明的 file:line 跳转位置
+}
+
+func runCat(args []string) int {
+	flags := flag.NewFlagSet("cat", flag.ExitOnError)
+	var opts catOptions
+	flags.BoolVar(&opts.number, "n", false, "number the output lines")
+	flags.BoolVar(&opts.color, "color", true, "highlight the syntax with ANSI escape sequences")
+	flags.BoolVar(&opts.decls, "decls", false, "list declarations as file:line jump targets before the source")
+	flags.Parse(args)
+
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = cat(os.Stdout, path, src, opts)
+		}
+		if err != nil {
+			report(path, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// cat 按 opts 在 w 上输出文件 path 的源码 src
+func cat(w io.Writer, path string, src []byte, opts catOptions) error {
+	var buf bytes.Buffer
+	if opts.decls {
+		file := ast.NewFile()
+		if err := parser.Parse(src, file); err != nil {
+			return err
+		}
+		lines := scanner.NewFileSet().AddFile(path, src)
+		for _, d := range doc.New(path, file).Decls {
+			fmt.Fprintf(&buf, "%s: %s %s\n", lines.Position(d.Pos).String(path), d.Tok, strings.Join(d.Names, ", "))
+		}
+		buf.WriteByte('\n')
+	}
+
+	text := src
+	if opts.color {
+		var colored bytes.Buffer
+		if err := highlight.ANSI(&colored, src); err != nil {
+			return err
+		}
+		text = colored.Bytes()
+	}
+	if !opts.number {
+		buf.Write(text)
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+
+	lines := strings.SplitAfter(string(text), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	width := len(strconv.Itoa(len(lines)))
+	for i, line := range lines {
+		fmt.Fprintf(&buf, "%*d  %s", width, i+1, line)
+	}
+	if len(lines) != 0 && !strings.HasSuffix(lines[len(lines)-1], "\n") {
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCat(t *testing.T) {
+	src := []byte("var a = 1\n\nproc f [\n]\n")
+	var out strings.Builder
+	if err := cat(&out, "a.zxx", src, catOptions{number: true, decls: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := "a.zxx:1:1: var a\na.zxx:3:1: proc f\n\n1  var a = 1\n2  \n3  proc f [\n4  ]\n"
+	if out.String() != want {
+		t.Fatalf("%q", out.String())
+	}
+
+	out.Reset()
+	if err := cat(&out, "a.zxx", []byte("var s = 'a\n  b'"), catOptions{number: true, color: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "\x1b[32m'a\x1b[0m\n2  \x1b[32m  b'\x1b[0m\n") {
+		t.Fatalf("%q", out.String())
+	}
+}
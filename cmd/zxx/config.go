@@ -0,0 +1,86 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ZxxLang/zxx/config"
+)
+
+func init() {
+	commands["config"] = &command{
+		usage: "config vet -schema file [file or dir...]",
+		run:   runConfig,
+	}
+}
+
+func runConfig(args []string) int {
+	if len(args) == 0 || args[0] != "vet" {
+		fmt.Fprintln(os.Stderr, "usage: zxx", commands["config"].usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config vet", flag.ExitOnError)
+	schema := flags.String("schema", "", "schema document")
+	ext := flags.String("ext", ".zxx", "file extension when walking directories")
+	flags.Parse(args[1:])
+	if *schema == "" {
+		fmt.Fprintln(os.Stderr, "zxx config vet: missing -schema")
+		return 2
+	}
+
+	src, err := ioutil.ReadFile(*schema)
+	if err == nil {
+		var s *config.Schema
+		if s, err = config.ParseSchema(src); err == nil {
+			return vet(s, flags.Args(), *ext)
+		}
+	}
+	report(*schema, err)
+	return 1
+}
+
+// vet 检查 paths 中的文件, 目录被递归遍历. 返回 1 如果有错误.
+func vet(s *config.Schema, paths []string, ext string) int {
+	code := 0
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || path != root && !strings.HasSuffix(path, ext) {
+				return nil
+			}
+			src, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, err := range s.Validate(src) {
+				report(path, err)
+				code = 1
+			}
+			return nil
+		})
+		if err != nil {
+			report(root, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+func report(filename string, err error) {
+	if e, ok := err.(*config.Error); ok {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", e.Pos.String(filename), e.Msg)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/ZxxLang/zxx/ir"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+func init() {
+	commands["ir"] = &command{
+		usage: "ir [-O0 | -O1 | -O2] [-dump] [-m] file...",
+		run:   runIR,
+	}
+}
+
+// optLevel 是 -O0, -O1, -O2 中的一个, 多个时最后一个有效.
+type optLevel struct {
+	level *int
+	n     int
+}
+
+func (o optLevel) String() string {
+	if o.level != nil && *o.level == o.n {
+		return "true"
+	}
+	return "false"
+}
+
+func (o optLevel) Set(s string) error {
+	on, err := strconv.ParseBool(s)
+	if err == nil && on {
+		*o.level = o.n
+	}
+	return err
+}
+
+func (o optLevel) IsBoolFlag() bool { return true }
+
+func runIR(args []string) int {
+	flags := flag.NewFlagSet("ir", flag.ExitOnError)
+	c := &ir.Pipeline{Level: 1}
+	for n, help := range []string{"no optimization", "constant propagation and dead code elimination (default)", "also inline small functions"} {
+		flags.Var(optLevel{&c.Level, n}, "O"+strconv.Itoa(n), help)
+	}
+	dump := flags.Bool("dump", false, "print the IR before and after each pass that changes it")
+	escapes := flags.Bool("m", false, "print escape analysis decisions instead of the IR")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: zxx", commands["ir"].usage)
+		return 2
+	}
+	if *dump {
+		c.Dump = os.Stderr
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = checkVersion(path, src)
+		}
+		if err == nil {
+			err = dumpIR(os.Stdout, path, src, c, *escapes)
+		}
+		if err != nil {
+			report(path, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// dumpIR 在 w 上输出文件 path 的源码 src 经过 c 优化的 IR,
+// escapes 为真时改为按位置输出每个分配是否逃逸.
+func dumpIR(w io.Writer, path string, src []byte, c *ir.Pipeline, escapes bool) error {
+	sf, err := parser.ParseSyntax(src)
+	if err != nil {
+		return err
+	}
+	p, err := ir.BuildFile(sf)
+	if err != nil {
+		return err
+	}
+	c.Run(p)
+	if !escapes {
+		_, err = io.WriteString(w, p.String())
+		return err
+	}
+
+	var list []ir.Escape
+	for _, f := range p.Funcs {
+		list = append(list, ir.Escapes(f)...)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Value.Pos < list[j].Value.Pos })
+	lines := scanner.NewFileSet().AddFile(path, src)
+	for _, e := range list {
+		if _, err = fmt.Fprintf(w, "%s: %v\n", lines.Position(e.Value.Pos).String(path), e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ir"
+)
+
+func TestIR(t *testing.T) {
+	src := []byte("func one out int [\n\tout 1\n]\n\nproc main [\n\tfmt.print(one() + 1)\n]\n")
+	var out, dump strings.Builder
+	if err := dumpIR(&out, "a.zxx", src, &ir.Pipeline{Level: 2, Dump: &dump}, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "func one()\nb0:\n\tv0 = const 1\n\treturn v0\n\n" +
+		"func main()\nb0:\n\tv0 = load fmt.print\n\tv4 = const 2\n\tv5 = call v0 v4\n\treturn\n"
+	if out.String() != want {
+		t.Fatalf("%q", out.String())
+	}
+	if !strings.HasPrefix(dump.String(), "-- before inline, round 1\nfunc main()\n") {
+		t.Fatalf("%q", dump.String())
+	}
+
+	out.Reset()
+	if err := dumpIR(&out, "a.zxx", src, &ir.Pipeline{Level: 0}, false); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "call v1") {
+		t.Fatalf("-O0 inlined:\n%s", out.String())
+	}
+}
+
+func TestIREscapes(t *testing.T) {
+	src := []byte("func pair(int a) out list [\n\tvar p = [a, [a]]\n\tvar q = [1]\n\tq[0] = [2]\n\tout p\n]\n")
+	var out strings.Builder
+	if err := dumpIR(&out, "a.zxx", src, &ir.Pipeline{Level: 1}, true); err != nil {
+		t.Fatal(err)
+	}
+	want := "a.zxx:2:10: list escapes to heap: returned\n" +
+		"a.zxx:2:14: list escapes to heap: returned\n" +
+		"a.zxx:3:10: list does not escape\n" +
+		"a.zxx:4:9: list does not escape\n"
+	if out.String() != want {
+		t.Fatalf("%q", out.String())
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/platform"
+)
+
+//go:embed lessons/*.txt
+var lessonFiles embed.FS
+
+func init() {
+	commands["learn"] = &command{
+		usage: "learn [-list] [-check] [lesson]",
+		run:   runLearn,
+	}
+}
+
+// lesson 是一课教程. 文件的首行是 "# 标题", 之后是说明,
+// "solution: " 行是参考答案, "want: " 行是期望的输出.
+type lesson struct {
+	name     string
+	title    string
+	text     string
+	solution string
+	want     string
+}
+
+// lessons 返回按文件名排序的全部课程
+func lessons() ([]lesson, error) {
+	names, err := lessonFiles.ReadDir("lessons")
+	if err != nil {
+		return nil, err
+	}
+	var all []lesson
+	for _, e := range names {
+		src, err := lessonFiles.ReadFile(path.Join("lessons", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		l, err := parseLesson(strings.TrimSuffix(e.Name(), ".txt"), string(src))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, l)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+	return all, nil
+}
+
+func parseLesson(name, src string) (l lesson, err error) {
+	l.name = name
+	var text []string
+	for _, line := range platform.Lines(strings.TrimSpace(src)) {
+		switch {
+		case strings.HasPrefix(line, "# ") && l.title == "":
+			l.title = line[2:]
+		case strings.HasPrefix(line, "solution: "):
+			l.solution = line[len("solution: "):]
+		case strings.HasPrefix(line, "want: "):
+			l.want = line[len("want: "):]
+		default:
+			text = append(text, line)
+		}
+	}
+	l.text = strings.TrimSpace(strings.Join(text, "\n"))
+	if l.title == "" || l.solution == "" || l.want == "" {
+		err = errors.New("lesson " + name + ": missing title, solution or want")
+	}
+	return
+}
+
+func runLearn(args []string) int {
+	flags := flag.NewFlagSet("learn", flag.ExitOnError)
+	list := flags.Bool("list", false, "list lessons")
+	check := flags.Bool("check", false, "check that every solution produces the wanted output")
+	flags.Parse(args)
+
+	all, err := lessons()
+	if err != nil {
+		report("learn", err)
+		return 1
+	}
+
+	switch {
+	case *list:
+		for i, l := range all {
+			fmt.Printf("%2d  %s\n", i+1, l.title)
+		}
+		return 0
+	case *check:
+		code := 0
+		for _, l := range all {
+			if got, err := show(eval.Expr(l.solution, nil)); err != nil || got != l.want {
+				fmt.Printf("%s: %s = %s, want %s\n", l.name, l.solution, outcome(got, err), l.want)
+				code = 1
+			}
+		}
+		return code
+	}
+
+	start := 1
+	if flags.NArg() != 0 {
+		start, err = strconv.Atoi(flags.Arg(0))
+		if err != nil || start < 1 || start > len(all) {
+			fmt.Fprintf(os.Stderr, "zxx learn: lesson must be 1 to %d\n", len(all))
+			return 2
+		}
+	}
+	learn(all[start-1:], start, os.Stdin, os.Stdout)
+	return 0
+}
+
+// learn 在 w 上依次讲解课程 all, 从 r 读取答案直到输出与期望一致.
+// 输入 hint 显示参考答案, skip 跳过本课.
+func learn(all []lesson, first int, r io.Reader, w io.Writer) {
+	in := bufio.NewScanner(r)
+	for i, l := range all {
+		fmt.Fprintf(w, "\n%d. %s\n\n%s\n\n", first+i, l.title, l.text)
+		for {
+			fmt.Fprint(w, "> ")
+			if !in.Scan() {
+				fmt.Fprintln(w)
+				return
+			}
+			line := strings.TrimSpace(in.Text())
+			switch line {
+			case "":
+				continue
+			case "hint":
+				fmt.Fprintln(w, l.solution)
+				continue
+			case "skip":
+			default:
+				got, err := show(eval.Expr(line, nil))
+				fmt.Fprintln(w, outcome(got, err))
+				if err != nil || got != l.want {
+					fmt.Fprintf(w, "want %s, type hint for the solution or skip\n", l.want)
+					continue
+				}
+				fmt.Fprintln(w, "ok")
+			}
+			break
+		}
+	}
+	fmt.Fprintln(w, "\nall lessons done")
+}
+
+func outcome(got string, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return got
+}
+
+// show 返回值 v 的 zxx 字面值写法, 用于与课程的期望输出比较
+func show(v eval.Value, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return lexutil.Quote(v), nil
+	case float64:
+		return lexutil.FormatFloat(v), nil
+	case time.Time:
+		return v.Format("20060102T15:04:05Z07:00"), nil
+	case []eval.Value:
+		items := make([]string, len(v))
+		for i, x := range v {
+			if items[i], err = show(x, nil); err != nil {
+				return "", err
+			}
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]eval.Value:
+		// 与映射字面值的写法相同, 键按字典序
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			return "[:]", nil
+		}
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			x, err := show(v[k], nil)
+			if err != nil {
+				return "", err
+			}
+			items[i] = lexutil.Quote(k) + ": " + x
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case eval.Tuple:
+		// 与 out a, b 的写法相同
+		items := make([]string, len(v))
+		for i, x := range v {
+			if items[i], err = show(x, nil); err != nil {
+				return "", err
+			}
+		}
+		return strings.Join(items, ", "), nil
+	}
+	return fmt.Sprint(v), nil
+}
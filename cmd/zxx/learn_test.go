@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/eval"
+)
+
+func TestLessons(t *testing.T) {
+	all, err := lessons()
+	if err != nil || len(all) == 0 {
+		t.Fatal(err)
+	}
+	for _, l := range all {
+		if got, err := show(eval.Expr(l.solution, nil)); err != nil || got != l.want {
+			t.Fatal(l.name, got, err)
+		}
+	}
+
+	// 错误的答案重试, 正确的答案进入下一课
+	var out strings.Builder
+	learn(all[:2], 1, strings.NewReader("1 +\n"+all[0].solution+"\nskip\n"), &out)
+	s := out.String()
+	if !strings.Contains(s, "unexpected EOF") || !strings.Contains(s, "\nok\n") || !strings.HasSuffix(s, "all lessons done\n") {
+		t.Fatal(s)
+	}
+
+	// Windows 上检出的课程文件可能使用 CRLF
+	l, err := parseLesson("crlf", "# 加法\r\n\r\n计算 1 + 2.\r\nsolution: 1 + 2\r\nwant: 3\r\n")
+	if err != nil || l.title != "加法" || l.solution != "1 + 2" || l.want != "3" || strings.Contains(l.text, "\r") {
+		t.Fatalf("%q %v", l, err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// zxx 是 zxx 源码的命令行工具.
+//
+// 用法:
+//
+//	zxx command [arguments]
+//
+// 命令:
+//
+//	ambig       用生成的输入检查语法的二义性
+//	ast         输出类型化的语法树, -format 可以是 tree, json, dot
+//	cat         着色输出源码, -n 输出行号, -decls 列出声明的跳转位置
+//	config vet  按 schema 检查配置文档
+//	ir          输出 func, proc 的 SSA 中间表示, -O0, -O1, -O2 选择优化级别, -dump 输出每个 pass 前后的 IR, -m 输出逃逸分析
+//	learn       交互式教程, 逐课求值并检查输出
+//	mod         init 创建 zxx.mod, tidy 删除未使用的依赖, download 下载依赖并校验 zxx.sum, vendor 复制依赖到 vendor 目录
+//	new         从内置模板生成项目骨架, -list 列出模板
+//	parse       输出 AST 节点, -trace 输出解析过程
+//	stats       统计 Token, 节点数, -mem 报告内存占用
+//	stats tools 查看本地记录的子命令用时, -enable 开始记录, 记录从不上传
+//	test        执行示例和 test 开头的 proc, -run 过滤, -json 输出 JSON, -bench 执行基准测试
+//	tool prof   在 vm 上反复执行测试的 out 表达式, 按源码行报告执行的指令数, -o 输出 pprof 格式的 profile
+//	vet         按 ID 可配置严重程度的诊断, -severity id=level 覆盖项目配置, -format 可以是 text, json, sarif, pretty, -target 按 zxx:build 选择导入的文件, -rules 列出诊断
+//	watch       监视文件, 重新检查改变的文件, -fmt 格式化, -test 执行示例和测试
+//
+// parse, ir, test, vet 和 watch 使用文件所在目录的项目配置 zxx.toml 中的语言版本,
+// vet 还使用其中的 lint 段和导入搜索路径, 参见 config.LoadProject.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// command 是一个子命令, run 返回进程退出码
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+var commands = map[string]*command{}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd := commands[os.Args[1]]
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "zxx: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	start := time.Now()
+	code := cmd.run(os.Args[2:])
+	recordUsage(usageFile(), usageRecord{os.Args[1], start, time.Since(start), code})
+	os.Exit(code)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: zxx command [arguments]\n\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "\t%s\n", commands[name].usage)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ZxxLang/zxx/mod"
+)
+
+func init() {
+	commands["mod"] = &command{
+		usage: "mod init path | mod tidy | mod download | mod vendor",
+		run:   runMod,
+	}
+}
+
+func runMod(args []string) int {
+	switch {
+	case len(args) == 2 && args[0] == "init":
+		return modInit(".", args[1])
+	case len(args) == 1 && (args[0] == "tidy" || args[0] == "download" || args[0] == "vendor"):
+		root := mod.FindRoot(".")
+		if root == "" {
+			fmt.Fprintln(os.Stderr, "zxx mod: no "+mod.File+" found, run zxx mod init first")
+			return 1
+		}
+		cache, err := mod.CacheDir()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "zxx mod:", err)
+			return 1
+		}
+		return modSync(root, cache, args[0])
+	}
+	fmt.Fprintln(os.Stderr, "usage: zxx", commands["mod"].usage)
+	return 2
+}
+
+// modInit 在目录 dir 中创建模块 path 的 zxx.mod
+func modInit(dir, path string) int {
+	name := filepath.Join(dir, mod.File)
+	if _, err := os.Stat(name); err == nil {
+		fmt.Fprintln(os.Stderr, "zxx mod:", name, "already exists")
+		return 1
+	}
+	src, err := (&mod.Manifest{Module: path}).Format()
+	if err == nil {
+		err = ioutil.WriteFile(name, src, 0644)
+	}
+	if err != nil {
+		report(name, err)
+		return 1
+	}
+	return 0
+}
+
+// modSync 执行子命令 cmd: 下载模块 root 的依赖到 cache 并更新 zxx.sum.
+// tidy 先删除未使用的依赖, 报告缺少的依赖并改写 zxx.mod, vendor 之后重建 vendor 目录.
+func modSync(root, cache, cmd string) int {
+	tidy := cmd == "tidy"
+	m, err := mod.Load(root)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zxx mod:", err)
+		return 1
+	}
+	sumName := filepath.Join(root, mod.SumFile)
+	sums := mod.Sums{}
+	if src, err := ioutil.ReadFile(sumName); err == nil {
+		if sums, err = mod.ParseSums(src); err != nil {
+			report(sumName, err)
+			return 1
+		}
+	} else if !os.IsNotExist(err) {
+		report(sumName, err)
+		return 1
+	}
+
+	code := 0
+	if tidy {
+		uses, err := moduleImports(root)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "zxx mod:", err)
+			return 1
+		}
+		for _, path := range m.Tidy(uses) {
+			fmt.Fprintln(os.Stderr, "zxx mod: no require for", path)
+			code = 1
+		}
+		src, err := m.Format()
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(root, mod.File), src, 0644)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "zxx mod:", err)
+			return 1
+		}
+	}
+
+	used := mod.Sums{}
+	if cmd == "vendor" {
+		if err := mod.Vendor(root, cache, m, sums); err != nil {
+			fmt.Fprintln(os.Stderr, "zxx mod:", err)
+			return 1
+		}
+	}
+	for _, r := range m.Require {
+		if _, err := mod.Download(cache, r, sums); err != nil {
+			fmt.Fprintln(os.Stderr, "zxx mod:", err)
+			return 1
+		}
+		key := r.Path + "@" + r.Version
+		used[key] = sums[key]
+	}
+	if tidy {
+		sums = used
+	}
+	if err := ioutil.WriteFile(sumName, sums.Format(), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "zxx mod:", err)
+		return 1
+	}
+	return code
+}
+
+// moduleImports 返回模块 root 中全部 .zxx 文件的 use 路径.
+// 以 '.' 或 '_' 开头的目录, 根目录中的 vendor 和含有 zxx.mod 的子模块被跳过.
+func moduleImports(root string) ([]string, error) {
+	var uses []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path == root {
+				return nil
+			}
+			if strings.HasPrefix(info.Name(), ".") || strings.HasPrefix(info.Name(), "_") || path == filepath.Join(root, mod.VendorDir) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, mod.File)); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".zxx") {
+			return nil
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		uses = append(uses, mod.Imports(src)...)
+		return nil
+	})
+	return uses, err
+}
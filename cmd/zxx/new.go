@@ -0,0 +1,130 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/template"
+	"github.com/ZxxLang/zxx/writefile"
+)
+
+// 每个子目录是一个项目模板, 文件内容是 template 包的模板,
+// 文件名中的 _name_ 被替换为项目名.
+//
+//go:embed all:skeletons
+var skeletonFiles embed.FS
+
+func init() {
+	commands["new"] = &command{
+		usage: "new [-list] [-name name] [-author name] template [dir]",
+		run:   runNew,
+	}
+}
+
+// packageName 是 README 规定的包名格式
+var packageName = regexp.MustCompile(`^[a-z]+[a-z0-9]*$`)
+
+func runNew(args []string) int {
+	flags := flag.NewFlagSet("new", flag.ExitOnError)
+	list := flags.Bool("list", false, "list templates")
+	name := flags.String("name", "", "project name, default is the base name of dir")
+	author := flags.String("author", "", "author written to the manifest")
+	flags.Parse(args)
+
+	if *list {
+		names, _ := fs.ReadDir(skeletonFiles, "skeletons")
+		for _, e := range names {
+			fmt.Println(e.Name())
+		}
+		return 0
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		fmt.Fprintln(os.Stderr, "usage: zxx", commands["new"].usage)
+		return 2
+	}
+
+	dir := flags.Arg(1)
+	if dir == "" {
+		dir = *name
+	}
+	if *name == "" {
+		*name = filepath.Base(dir)
+	}
+	if dir == "" {
+		fmt.Fprintln(os.Stderr, "zxx new: missing dir or -name")
+		return 2
+	}
+	files, err := scaffold(flags.Arg(0), dir, map[string]eval.Value{"name": *name, "author": *author})
+	if err != nil {
+		report("new", err)
+		return 1
+	}
+	for _, f := range files {
+		fmt.Println(f)
+	}
+	return 0
+}
+
+// scaffold 在目录 dir 中生成模板 kind 的项目, 返回生成的文件. env 是模板的变量,
+// 其中 name 必须是合法的包名. 已经存在的文件不会被覆盖, 此时不生成任何文件.
+func scaffold(kind, dir string, env map[string]eval.Value) ([]string, error) {
+	name, _ := env["name"].(string)
+	if !packageName.MatchString(name) {
+		return nil, fmt.Errorf("invalid project name %q, want %s", name, packageName)
+	}
+	root := path.Join("skeletons", kind)
+	if _, err := fs.Stat(skeletonFiles, root); err != nil || kind == "" || strings.ContainsAny(kind, "/.") {
+		return nil, errors.New("unknown template " + kind + ", see zxx new -list")
+	}
+
+	// 先渲染全部文件, 出错时不留下半成品
+	outputs := map[string][]byte{}
+	var files []string
+	err := fs.WalkDir(skeletonFiles, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		src, err := skeletonFiles.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		t, err := template.Parse(p, string(src))
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err = t.Execute(&out, env); err != nil {
+			return err
+		}
+		rel := strings.Replace(strings.TrimPrefix(p, root+"/"), "_name_", name, -1)
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if _, err := os.Stat(target); err == nil {
+			return errors.New(target + " already exists")
+		}
+		outputs[target] = out.Bytes()
+		files = append(files, target)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writefile.Files(outputs, writefile.Options{}); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/doc"
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestScaffold(t *testing.T) {
+	for _, kind := range []string{"app", "lib"} {
+		dir := filepath.Join(t.TempDir(), "hello")
+		env := map[string]eval.Value{"name": "hello", "author": "someone"}
+		files, err := scaffold(kind, dir, env)
+		if err != nil {
+			t.Fatal(kind, err)
+		}
+		r := &reporter{w: &strings.Builder{}}
+		for _, f := range files {
+			src, err := ioutil.ReadFile(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch filepath.Ext(f) {
+			case ".json":
+				var v map[string]interface{}
+				if err := json.Unmarshal(src, &v); err != nil || v["name"] != "hello" && v["files.eol"] == nil {
+					t.Fatal(f, err, string(src))
+				}
+			case ".zxx":
+				file := ast.NewFile()
+				if err := parser.Parse(src, file); err != nil {
+					t.Fatal(f, err)
+				}
+				runExamples(doc.New(f, file), r)
+				runTests(f, src, file, r)
+			}
+		}
+		if r.total < 1 || r.failed != 0 {
+			t.Fatal(kind, r.total, r.failed)
+		}
+		if kind == "lib" && !strings.HasSuffix(files[0], filepath.Join("hello", ".editorconfig")) {
+			t.Fatal(files)
+		}
+
+		// 不覆盖已经存在的文件
+		if _, err := scaffold(kind, dir, env); err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := scaffold("app", t.TempDir(), map[string]eval.Value{"name": "Hello"}); err == nil {
+		t.Fatal("want invalid name")
+	}
+	if _, err := scaffold("../skeletons", t.TempDir(), map[string]eval.Value{"name": "x"}); err == nil {
+		t.Fatal("want unknown template")
+	}
+}
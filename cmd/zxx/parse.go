@@ -0,0 +1,120 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/config"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func init() {
+	commands["parse"] = &command{
+		usage: "parse [-trace] [-max-errors n] file...",
+		run:   runParse,
+	}
+}
+
+// traceEvent 是 -trace 输出的 JSON 行
+type traceEvent struct {
+	Kind   string `json:"kind"`
+	Pos    int    `json:"pos"`
+	Tok    string `json:"tok,omitempty"`
+	Source string `json:"source,omitempty"`
+	Depth  int    `json:"depth"`
+	Msg    string `json:"msg,omitempty"`
+}
+
+func runParse(args []string) int {
+	flags := flag.NewFlagSet("parse", flag.ExitOnError)
+	trace := flags.Bool("trace", false, "print parser decisions as JSON lines")
+	maxErrors := flags.Int("max-errors", 10, "errors to collect before stopping")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: zxx", commands["parse"].usage)
+		return 2
+	}
+
+	code := 0
+	enc := json.NewEncoder(os.Stdout)
+	for _, name := range flags.Args() {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			report(name, err)
+			code = 1
+			continue
+		}
+		version, err := projectVersion(name)
+		if err != nil {
+			report(name, err)
+			code = 1
+			continue
+		}
+
+		c := &parser.Config{Mode: parser.ParseComments | parser.ParsePlaceholders, MaxErrors: *maxErrors, Version: version}
+		if *trace {
+			c.Trace = func(ev parser.TraceEvent) {
+				e := traceEvent{ev.Kind.String(), int(ev.Pos), "", ev.Source, ev.Depth, ev.Msg}
+				if ev.Kind != parser.TraceRecover {
+					e.Tok = ev.Tok.String()
+				}
+				enc.Encode(e)
+			}
+		}
+		file, err := c.Parse(src)
+		if !*trace {
+			for i, n := range file.Nodes[1:] {
+				fmt.Printf("%4d %*s%-12v %q\n", i+1, 2*depth(n), "", n.Token(), n.Text())
+			}
+		}
+		if list, ok := err.(parser.ErrorList); ok {
+			for _, err := range list {
+				report(name, err)
+			}
+			code = 1
+		} else if err != nil {
+			report(name, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// projectVersion 返回文件 name 所在项目的默认语言版本, 参见 config.LoadProject
+func projectVersion(name string) (string, error) {
+	p, err := config.LoadProject(filepath.Dir(name))
+	if err != nil {
+		return "", err
+	}
+	return p.Version, nil
+}
+
+// checkVersion 返回 src 中文件 name 的语言版本不支持的语法的错误.
+// 版本取自 src 头部的版本指示, 没有时取自项目配置, 参见 parser.CheckVersion.
+func checkVersion(name string, src []byte) error {
+	version, ok, err := parser.HeaderVersion(src)
+	if err == nil && !ok {
+		version, err = projectVersion(name)
+	}
+	if err != nil {
+		return err
+	}
+	return parser.CheckVersion(src, version)
+}
+
+// depth 返回节点的嵌套深度, 顶层节点为 0
+func depth(n interface{ Parent() ast.Node }) (d int) {
+	for p := n.Parent(); p != nil && p.Parent() != nil; p = p.Parent() {
+		d++
+	}
+	return
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// ports 是必须能够交叉编译的目标平台
+var ports = []struct{ goos, goarch string }{
+	{"windows", "arm64"},
+	{"windows", "amd64"},
+	{"js", "wasm"},
+	{"linux", "386"},
+	{"darwin", "arm64"},
+}
+
+var portsFlag = flag.Bool("ports", false, "cross compile all packages for the ports")
+
+// TestPorts 交叉编译全部包, 需要 -ports 才执行, 例如 go test -run Ports -ports.
+// -short 时总是跳过. 各平台的路径和换行规则由 platform 包的测试覆盖, 不需要交叉编译.
+func TestPorts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("cross compiling in short mode")
+	}
+	if !*portsFlag {
+		t.Skip("cross compiling needs -ports")
+	}
+	gocmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, p := range ports {
+		p := p
+		t.Run(p.goos+"/"+p.goarch, func(t *testing.T) {
+			cmd := exec.Command(gocmd, "build", "-o", os.DevNull, "../../...")
+			cmd.Env = append(os.Environ(), "GOOS="+p.goos, "GOARCH="+p.goarch, "CGO_ENABLED=0")
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%v\n%s", err, out)
+			}
+		})
+	}
+}
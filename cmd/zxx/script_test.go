@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 设置了 scriptEnv 的测试进程就是 zxx 命令, 脚本以子进程的方式执行它.
+const scriptEnv = "ZXX_SCRIPT_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(scriptEnv) != "" {
+		main()
+	}
+	os.Exit(m.Run())
+}
+
+// TestScripts 执行 testdata/script 中的脚本, 每个脚本是一个 txtar 风格的归档:
+// 首个 "-- name --" 之前是命令, 之后的每一节是写入临时工作目录的文件.
+//
+//	# 注释
+//	zxx args...        执行 zxx, 退出码必须为 0, 以 '!' 开始时必须非 0
+//	stdout regexp      上一个命令的标准输出匹配 regexp, 以 '!' 开始时不能匹配
+//	stderr regexp      同 stdout, 检查标准错误
+//	cmp file want      文件 file 的内容与 want 相同, file 可以是 stdout 或 stderr
+//	exists file        文件 file 存在, 以 '!' 开始时不能存在
+//
+// 参数按空白分割, 可以用单引号包含空白.
+func TestScripts(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "script", "*.txt"))
+	if err != nil || len(files) == 0 {
+		t.Fatal("no scripts", err)
+	}
+	for _, file := range files {
+		file := file
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".txt"), func(t *testing.T) {
+			t.Parallel()
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			runScript(t, file, data)
+		})
+	}
+}
+
+// archive 把 txtar 风格的 data 分割为开头的注释和文件
+func archive(data []byte) (comment string, files map[string][]byte, names []string) {
+	files = map[string][]byte{}
+	var name string
+	var buf []byte
+	flush := func() {
+		if name == "" {
+			comment = string(buf)
+		} else {
+			files[name] = buf
+			names = append(names, name)
+		}
+		buf = nil
+	}
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		s := strings.TrimSpace(string(line))
+		if strings.HasPrefix(s, "-- ") && strings.HasSuffix(s, " --") && len(s) > 6 {
+			flush()
+			name = strings.TrimSpace(s[3 : len(s)-3])
+			continue
+		}
+		buf = append(buf, line...)
+	}
+	flush()
+	return
+}
+
+func runScript(t *testing.T, file string, data []byte) {
+	comment, files, names := archive(data)
+	work := t.TempDir()
+	for _, name := range names {
+		path := filepath.Join(work, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, files[name], 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr string
+	read := func(name string) string {
+		switch name {
+		case "stdout":
+			return stdout
+		case "stderr":
+			return stderr
+		}
+		data, err := ioutil.ReadFile(filepath.Join(work, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	for i, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		where := file + ":" + strconv.Itoa(i+1) + ": " + line
+		neg := line[0] == '!'
+		args := fields(strings.TrimSpace(strings.TrimPrefix(line, "!")))
+
+		switch args[0] {
+		case "zxx":
+			cmd := exec.Command(self, args[1:]...)
+			cmd.Dir = work
+			cmd.Env = append(os.Environ(), scriptEnv+"=1", "HOME="+work, "XDG_CONFIG_HOME="+filepath.Join(work, ".config"))
+			var out, errs bytes.Buffer
+			cmd.Stdout, cmd.Stderr = &out, &errs
+			err := cmd.Run()
+			stdout, stderr = out.String(), errs.String()
+			if _, ok := err.(*exec.ExitError); err != nil && !ok {
+				t.Fatal(where, err)
+			}
+			if neg != (err != nil) {
+				t.Fatalf("%s: exit %v\nstdout:\n%s\nstderr:\n%s", where, err, stdout, stderr)
+			}
+		case "stdout", "stderr":
+			if len(args) != 2 {
+				t.Fatal(where, "want one regexp")
+			}
+			re, err := regexp.Compile("(?m)" + args[1])
+			if err != nil {
+				t.Fatal(where, err)
+			}
+			if got := read(args[0]); re.MatchString(got) == neg {
+				t.Fatalf("%s: %s is\n%s", where, args[0], got)
+			}
+		case "cmp":
+			if len(args) != 3 || neg {
+				t.Fatal(where, "want cmp file want")
+			}
+			if got, want := read(args[1]), read(args[2]); got != want {
+				t.Fatalf("%s:\n%s\nwant:\n%s", where, got, want)
+			}
+		case "exists":
+			if len(args) != 2 {
+				t.Fatal(where, "want one file")
+			}
+			if _, err := os.Stat(filepath.Join(work, filepath.FromSlash(args[1]))); (err == nil) == neg {
+				t.Fatal(where, err)
+			}
+		default:
+			t.Fatal(where, "unknown command")
+		}
+	}
+}
+
+// fields 按空白分割 s, 单引号中的空白不分割
+func fields(s string) (args []string) {
+	var arg []byte
+	quoted, inArg := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'':
+			quoted, inArg = !quoted, true
+		case !quoted && (c == ' ' || c == '\t'):
+			if inArg {
+				args = append(args, string(arg))
+				arg, inArg = nil, false
+			}
+		default:
+			arg, inArg = append(arg, c), true
+		}
+	}
+	if inArg {
+		args = append(args, string(arg))
+	}
+	return
+}
@@ -0,0 +1,148 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+func init() {
+	commands["stats"] = &command{
+		usage: "stats [-mem] [file or dir...] | stats tools [-enable | -disable | -reset]",
+		run:   runStats,
+	}
+}
+
+// stat 是单个文件的统计结果
+type stat struct {
+	size     int
+	tokens   int    // Fast 产生的 Symbol 数
+	nodes    int    // ast.File 的节点数, 不含 File 本身
+	retained uint64 // 解析后保留的堆字节数, 含源码
+	nodeCap  int    // ast.File.Nodes 的容量
+	lines    int    // 行索引的行数
+}
+
+func runStats(args []string) int {
+	if len(args) != 0 && args[0] == "tools" {
+		return runTools(args[1:])
+	}
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	mem := flags.Bool("mem", false, "report retained bytes and cache sizes")
+	ext := flags.String("ext", ".zxx", "file extension when walking directories")
+	flags.Parse(args)
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	code := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	if *mem {
+		fmt.Fprintln(w, "bytes\ttokens\tnodes\tretained\tnodes cap\tlines\t")
+	} else {
+		fmt.Fprintln(w, "bytes\ttokens\tnodes\t")
+	}
+
+	var total stat
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || path != root && !strings.HasSuffix(path, *ext) {
+				return nil
+			}
+			st, err := stats(path, *mem)
+			if err != nil {
+				report(path, err)
+				code = 1
+				return nil
+			}
+			if *mem {
+				fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\t%d\t %s\n",
+					st.size, st.tokens, st.nodes, st.retained, st.nodeCap, st.lines, path)
+			} else {
+				fmt.Fprintf(w, "%d\t%d\t%d\t %s\n", st.size, st.tokens, st.nodes, path)
+			}
+			total.size += st.size
+			total.tokens += st.tokens
+			total.nodes += st.nodes
+			total.retained += st.retained
+			total.nodeCap += st.nodeCap
+			total.lines += st.lines
+			return nil
+		})
+		if err != nil {
+			report(root, err)
+			code = 1
+		}
+	}
+
+	if *mem {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%d\t%d\t total\n",
+			total.size, total.tokens, total.nodes, total.retained, total.nodeCap, total.lines)
+	} else {
+		fmt.Fprintf(w, "%d\t%d\t%d\t total\n", total.size, total.tokens, total.nodes)
+	}
+	w.Flush()
+	return code
+}
+
+// stats 统计文件 path. mem 为 true 时测量解析后保留的堆字节数,
+// 测量前后各执行一次 GC, 所以比较慢, 结果是近似值.
+func stats(path string, mem bool) (st stat, err error) {
+	var before, after runtime.MemStats
+	if mem {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	syms, err := parser.Fast(src, nil)
+	if err != nil {
+		return
+	}
+	file := ast.NewFile()
+	if err = parser.Parse(src, file); err != nil {
+		return
+	}
+	lines := scanner.NewFileSet().AddFile(path, src)
+
+	st.size = len(src)
+	st.tokens = len(syms)
+	st.nodes = file.Len() - 1
+	st.nodeCap = cap(file.Nodes)
+	st.lines = lines.LineCount()
+
+	if mem {
+		syms = nil
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		if after.HeapAlloc > before.HeapAlloc {
+			st.retained = after.HeapAlloc - before.HeapAlloc
+		}
+	}
+
+	runtime.KeepAlive(src)
+	runtime.KeepAlive(file)
+	runtime.KeepAlive(lines)
+	return
+}
@@ -0,0 +1,298 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/diagfmt"
+	"github.com/ZxxLang/zxx/doc"
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+	"github.com/ZxxLang/zxx/vm"
+)
+
+func init() {
+	commands["test"] = &command{
+		usage: "test [-v] [-run regexp] [-json] [-bench regexp] [-benchtime d] [file or dir...]",
+		run:   runTest,
+	}
+}
+
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "print every example and test")
+	run := flags.String("run", "", "run only examples and tests matching the regular expression")
+	jsonOut := flags.Bool("json", false, "print results as a stream of JSON events")
+	bench := flags.String("bench", "", "run benchmarks matching the regular expression")
+	benchtime := flags.Duration("benchtime", time.Second, "run each benchmark for about this long")
+	ext := flags.String("ext", ".zxx", "file extension when walking directories")
+	flags.Parse(args)
+
+	var filter, match *regexp.Regexp
+	for _, x := range []struct {
+		expr string
+		re   **regexp.Regexp
+	}{{*run, &filter}, {*bench, &match}} {
+		if x.expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(x.expr)
+		if err != nil {
+			report("test", err)
+			return 2
+		}
+		*x.re = re
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	r := &reporter{w: os.Stdout, verbose: *verbose, json: *jsonOut, filter: filter}
+	code := 0
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || path != root && !strings.HasSuffix(path, *ext) {
+				return nil
+			}
+			src, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			version, err := projectVersion(path)
+			if err != nil {
+				report(path, err)
+				code = 1
+				return nil
+			}
+			file, err := (&parser.Config{Mode: parser.ParseComments | parser.ParsePlaceholders, Version: version}).Parse(src)
+			if err != nil {
+				report(path, err)
+				code = 1
+				return nil
+			}
+			p := doc.New(path, file)
+			failed := r.failed
+			runExamples(p, r)
+			runTests(path, src, file, r)
+			if match != nil && r.failed == failed {
+				r.failed += runBenchmarks(p, match, *benchtime, os.Stdout)
+			}
+			return nil
+		})
+		if err != nil {
+			report(root, err)
+			code = 1
+		}
+	}
+
+	if r.failed != 0 {
+		if !r.json {
+			fmt.Printf("FAIL\t%d of %d examples and tests failed\n", r.failed, r.total)
+		}
+		return 1
+	}
+	if code == 0 && !r.json {
+		fmt.Printf("ok\t%d examples and tests\n", r.total)
+	}
+	return code
+}
+
+// event 是一个示例或测试的结果, -json 时每个 event 输出为一行 JSON.
+type event struct {
+	Action string // pass, fail 或 skip
+	Test   string
+	Pos    string `json:",omitempty"` // 出错位置, 格式为 file:line:column
+	Output string `json:",omitempty"`
+
+	snippet string // 出错位置的源码片段, 参见 diagfmt, 只用于文本输出
+}
+
+// reporter 在 w 上报告结果并计数, filter 不为 nil 时只执行名字与之匹配的示例和测试.
+type reporter struct {
+	w             io.Writer
+	verbose, json bool
+	filter        *regexp.Regexp
+	total, failed int
+}
+
+// match 返回是否执行名为 name 的示例或测试
+func (r *reporter) match(name string) bool {
+	return r.filter == nil || r.filter.MatchString(name)
+}
+
+func (r *reporter) report(e event) {
+	r.total++
+	if e.Action == "fail" {
+		r.failed++
+	}
+	if r.json {
+		b, _ := json.Marshal(e)
+		fmt.Fprintf(r.w, "%s\n", b)
+		return
+	}
+	if e.Action != "fail" && !r.verbose {
+		return
+	}
+	fmt.Fprintf(r.w, "--- %s: %s\n", strings.ToUpper(e.Action), e.Test)
+	switch {
+	case e.snippet != "":
+		for _, line := range strings.SplitAfter(strings.TrimSuffix(e.snippet, "\n"), "\n") {
+			fmt.Fprintf(r.w, "\t%s", line)
+		}
+		fmt.Fprintln(r.w)
+	case e.Pos != "":
+		fmt.Fprintf(r.w, "\t%s: %s\n", e.Pos, e.Output)
+	case e.Output != "":
+		fmt.Fprintf(r.w, "\t%s\n", e.Output)
+	}
+}
+
+// runExamples 执行 p 中全部声明的示例, 通过 r 报告结果.
+// 没有 Output 行的示例只需要求值成功.
+func runExamples(p *doc.Package, r *reporter) {
+	for _, d := range p.Decls {
+		name := strings.Join(d.Names, ", ")
+		if !r.match(name) {
+			continue
+		}
+		for _, e := range d.Examples {
+			ev := event{Action: "pass", Test: p.Name + " " + name + ": " + e.Code}
+			got, err := show(eval.Expr(e.Code, nil))
+			switch {
+			case err != nil:
+				ev.Action, ev.Output = "fail", err.Error()
+			case e.Has && got != e.Output:
+				ev.Action, ev.Output = "fail", "got  "+got+"\n\twant "+e.Output
+			}
+			r.report(ev)
+		}
+	}
+}
+
+// runTests 执行 file 中名字以 ast.TestPrefix 开始的 proc 声明, 通过 r 报告结果.
+// 测试体中的每个 out 语句的值必须为 true. 目前只能对表达式求值,
+// 含有其它语句的测试被跳过.
+func runTests(path string, src []byte, file *ast.File, r *reporter) {
+	lines := scanner.NewFileSet().AddFile(path, src)
+	at := func(pos scanner.Pos) string {
+		return lines.Position(pos).String(path)
+	}
+	// snippet 返回标出 pos 至 end 的源码片段
+	snippet := func(pos, end scanner.Pos, msg string) string {
+		var b strings.Builder
+		(&diagfmt.Renderer{}).Render(&b, lines, src, diagfmt.Message{Pos: pos, End: end, Msg: msg})
+		return b.String()
+	}
+	for _, n := range file.Nodes[1:] {
+		name, ok := ast.TestName(n)
+		if !ok || !r.match(name) {
+			continue
+		}
+		ev := event{Action: "pass", Test: name}
+		stmts := ast.Body(n)
+		if len(stmts) == 0 {
+			ev.Action, ev.Output = "fail", "missing out statement"
+		}
+		for _, stmt := range stmts {
+			if stmt.Token() != token.OUT {
+				ev.Action, ev.Output = "skip", "unsupported statement "+stmt.Token().String()
+				break
+			}
+		}
+		for _, stmt := range stmts {
+			if ev.Action != "pass" {
+				break
+			}
+			pos, expr := ast.Tail(stmt)
+			v, err := eval.Expr(expr, nil)
+			switch {
+			case err != nil:
+				msg := err.Error()
+				if e, ok := err.(*eval.Error); ok {
+					pos, msg = pos.Offset(e.Offset), e.Msg
+				}
+				ev.Action, ev.Pos, ev.Output = "fail", at(pos), msg
+				ev.snippet = snippet(pos, pos, msg)
+			case v != true:
+				got, _ := show(v, nil)
+				ev.Action, ev.Pos, ev.Output = "fail", at(pos), expr+" is "+got
+				ev.snippet = snippet(pos, pos.Offset(len(expr)), ev.Output)
+			}
+		}
+		r.report(ev)
+	}
+}
+
+// runBenchmarks 执行 p 中名字与 match 匹配的声明的基准测试, 在 w 上报告每次执行的耗时,
+// 返回失败数. 表达式只编译一次, 次数按 benchtime 校准.
+func runBenchmarks(p *doc.Package, match *regexp.Regexp, benchtime time.Duration, w io.Writer) (failed int) {
+	for _, d := range p.Decls {
+		name := strings.Join(d.Names, ", ")
+		if !match.MatchString(name) {
+			continue
+		}
+		for _, src := range d.Benchmarks {
+			n, elapsed, err := benchmark(src, benchtime)
+			if err != nil {
+				fmt.Fprintf(w, "--- FAIL: %s %s: %s\n\t%v\n", p.Name, name, src, err)
+				failed++
+				continue
+			}
+			fmt.Fprintf(w, "%s %s: %s\t%d\t%d ns/op\n", p.Name, name, src, n, elapsed.Nanoseconds()/int64(n))
+		}
+	}
+	return
+}
+
+// benchmark 把 src 编译为字节码, 在虚拟机上反复执行直到耗时达到 benchtime, 返回执行次数和总耗时.
+// 与 go test 相同, 每轮按前一轮的速度预测次数, 增长不超过 100 倍.
+func benchmark(src string, benchtime time.Duration) (n int, elapsed time.Duration, err error) {
+	prog, err := vm.CompileString(src)
+	if err != nil {
+		return
+	}
+	for n = 1; ; {
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			if _, err = vm.Run(prog, nil); err != nil {
+				return
+			}
+		}
+		elapsed = time.Since(start)
+		if elapsed >= benchtime || n >= 1e9 {
+			return
+		}
+
+		next := 100 * n
+		if perOp := elapsed.Nanoseconds() / int64(n); perOp != 0 {
+			next = int(int64(benchtime) * 6 / 5 / perOp)
+		}
+		if next > 100*n {
+			next = 100 * n
+		}
+		if next <= n {
+			next = n + 1
+		}
+		n = next
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/doc"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestRunExamples(t *testing.T) {
+	const src = `proc f [
+	// Example: 1 + 2
+	// Output: 3
+	// Example: "a" + "b"
+	// Output: 'ab'
+	// Example: 2 * 3
+	// Output: 5
+]
+`
+	file := ast.NewFile()
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	r := &reporter{w: &out}
+	runExamples(doc.New("demo", file), r)
+	if r.total != 3 || r.failed != 1 || !strings.Contains(out.String(), "--- FAIL: demo f: 2 * 3\n\tgot  6\n\twant 5") {
+		t.Fatal(r.total, r.failed, out.String())
+	}
+}
+
+func TestRunBenchmarks(t *testing.T) {
+	const src = `proc f [
+	// Benchmark: 1 + 2 * 3
+]
+proc g [
+	// Benchmark: 1 +
+]
+proc h [
+	// Benchmark: "n = {1 + 2}"
+	// Benchmark: 1 div 0
+]
+`
+	file := ast.NewFile()
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	failed := runBenchmarks(doc.New("demo", file), regexp.MustCompile("."), time.Millisecond, &out)
+	if failed != 2 || !strings.Contains(out.String(), "demo f: 1 + 2 * 3\t") || !strings.Contains(out.String(), " ns/op\n") ||
+		!strings.Contains(out.String(), "demo h: \"n = {1 + 2}\"\t") || !strings.Contains(out.String(), "--- FAIL: demo h: 1 div 0\n\teval: 2: division by zero\n") {
+		t.Fatal(failed, out.String())
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	const src = `proc testSum out bool [
+	out 1 + 2 == 3
+]
+proc testBad out bool [
+	out 1 + 2 == 4
+]
+proc testVar out bool [
+	var x = 1
+	out x == 1
+]
+proc testErr out bool [
+	out 1 + 'a'
+]
+proc helper [
+]
+`
+	file := ast.NewFile()
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	r := &reporter{w: &out, verbose: true}
+	runTests("demo.zxx", []byte(src), file, r)
+	want := "--- PASS: testSum\n" +
+		"--- FAIL: testBad\n\tdemo.zxx:5:6: 1 + 2 == 4 is false\n" +
+		"\t   5 |     out 1 + 2 == 4\n" +
+		"\t     |         ^~~~~~~~~~\n" +
+		"--- SKIP: testVar\n\tunsupported statement var\n" +
+		"--- FAIL: testErr\n\tdemo.zxx:12:8: invalid operation +\n" +
+		"\t  12 |     out 1 + 'a'\n" +
+		"\t     |           ^\n"
+	if r.total != 4 || r.failed != 2 || out.String() != want {
+		t.Fatal(r.total, r.failed, out.String())
+	}
+
+	out.Reset()
+	r = &reporter{w: &out, json: true, filter: regexp.MustCompile("Bad")}
+	runTests("demo.zxx", []byte(src), file, r)
+	if got := out.String(); got != `{"Action":"fail","Test":"testBad","Pos":"demo.zxx:5:6","Output":"1 + 2 == 4 is false"}`+"\n" {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/builtin"
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+	"github.com/ZxxLang/zxx/vm"
+)
+
+func init() {
+	commands["tool"] = &command{
+		usage: "tool prof [-n count] [-top k] [-o file] [-run regexp] file...",
+		run:   runTool,
+	}
+}
+
+func runTool(args []string) int {
+	if len(args) == 0 || args[0] != "prof" {
+		fmt.Fprintln(os.Stderr, "usage: zxx", commands["tool"].usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("tool prof", flag.ExitOnError)
+	n := flags.Int("n", 1000, "evaluate every out statement this many times")
+	top := flags.Int("top", 10, "print this many hottest lines, 0 prints all")
+	out := flags.String("o", "", "also write a pprof profile (gzipped profile.proto) to this file")
+	run := flags.String("run", "", "profile only tests matching the regular expression")
+	flags.Parse(args[1:])
+	if flags.NArg() == 0 || *n < 1 {
+		fmt.Fprintln(os.Stderr, "usage: zxx", commands["tool"].usage)
+		return 2
+	}
+	var filter *regexp.Regexp
+	if *run != "" {
+		re, err := regexp.Compile(*run)
+		if err != nil {
+			report("tool prof", err)
+			return 2
+		}
+		filter = re
+	}
+
+	pr := newProfiler()
+	code := 0
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = pr.profile(path, src, filter, *n)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+	}
+	if err := pr.print(os.Stdout, *top); err != nil {
+		report("tool prof", err)
+		return 1
+	}
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err == nil {
+			err = pr.prof.WritePprof(f)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			report(*out, err)
+			return 1
+		}
+	}
+	return code
+}
+
+// origin 是编译为程序的 out 表达式在文件中的位置
+type origin struct {
+	lines *scanner.File
+	pos   scanner.Pos
+}
+
+// profiler 在 vm 上反复执行测试中的 out 表达式, 把指令的执行次数映射回源文件的行.
+type profiler struct {
+	prof    vm.Profile
+	env     map[string]eval.Value
+	origins map[*vm.Program]origin
+	srcs    map[string][]byte
+	lines   map[string]*scanner.File
+}
+
+func newProfiler() *profiler {
+	pr := &profiler{
+		env:     builtin.Env(ioutil.Discard),
+		origins: map[*vm.Program]origin{},
+		srcs:    map[string][]byte{},
+		lines:   map[string]*scanner.File{},
+	}
+	// ast.Tail 把节点之间的空白合并为一个空格, 表达式内的偏移量只用来计算列,
+	// 跨行的表达式全部计入它开始的行.
+	pr.prof.Locate = func(p *vm.Program, offset int) (string, token.Position) {
+		o := pr.origins[p]
+		pos := o.lines.Position(o.pos)
+		pos.Offset += offset
+		pos.Column += offset
+		return o.lines.Name(), pos
+	}
+	return pr
+}
+
+// profile 执行文件 path 中名字与 filter 匹配的测试 n 次, 与 zxx test 相同, 跳过含有 out 以外语句的测试.
+// 表达式可以调用内置函数, print 等的输出被丢弃.
+// 返回解析, 编译或者求值的第一个错误, 包括文件名和位置, 出错的测试不再执行.
+func (pr *profiler) profile(path string, src []byte, filter *regexp.Regexp, n int) error {
+	file := ast.NewFile()
+	if err := parser.Parse(src, file); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	lines := scanner.NewFileSet().AddFile(path, src)
+	pr.srcs[path], pr.lines[path] = src, lines
+
+	var first error
+	for _, node := range file.Nodes[1:] {
+		name, ok := ast.TestName(node)
+		if !ok || filter != nil && !filter.MatchString(name) {
+			continue
+		}
+		var progs []*vm.Program
+		stmts := ast.Body(node)
+		for _, stmt := range stmts {
+			if stmt.Token() != token.OUT {
+				progs = nil
+				break
+			}
+			pos, expr := ast.Tail(stmt)
+			p, err := vm.CompileFile(path, expr)
+			if err != nil {
+				return fmt.Errorf("%s: %v", lines.Position(pos).String(path), err)
+			}
+			pr.origins[p] = origin{lines, pos}
+			progs = append(progs, p)
+		}
+		if err := pr.run(progs, n); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// run 依次执行 progs n 遍, 求值的错误按 Locate 换算为源文件中的位置
+func (pr *profiler) run(progs []*vm.Program, n int) error {
+	for i := 0; i < n; i++ {
+		for _, p := range progs {
+			_, err := pr.prof.Run(p, pr.env)
+			if e, ok := err.(*eval.Error); ok {
+				file, pos := pr.prof.Locate(p, e.Offset)
+				return fmt.Errorf("%s: %s", pos.String(file), e.Msg)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// print 在 w 上输出最热的 top 行, 每行是执行的指令数, 占比, 位置和源码.
+func (pr *profiler) print(w io.Writer, top int) error {
+	lines := pr.prof.Lines()
+	var total uint64
+	for _, l := range lines {
+		total += l.Count
+	}
+	if top > 0 && len(lines) > top {
+		lines = lines[:top]
+	}
+	if _, err := fmt.Fprintf(w, "%d instructions\n", total); err != nil {
+		return err
+	}
+	for _, l := range lines {
+		text := ""
+		if f := pr.lines[l.File]; f != nil && l.Line > 0 {
+			src := pr.srcs[l.File][f.Offset(f.LineStart(l.Line)):]
+			if i := bytes.IndexAny(src, "\r\n"); i >= 0 {
+				src = src[:i]
+			}
+			text = string(bytes.TrimSpace(src))
+		}
+		pct := float64(l.Count) * 100 / float64(total)
+		if _, err := fmt.Fprintf(w, "%10d %6.2f%%  %s:%d  %s\n", l.Count, pct, l.File, l.Line, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestProfiler(t *testing.T) {
+	const src = `proc testA [
+	out 1 + 2 == 3
+	out len('abc') > 1 and len('abc') == 3
+]
+proc testB [
+	out 1 + 'a'
+]
+proc testSkip [
+	var x = 1
+	out x == 1
+]
+`
+	pr := newProfiler()
+	err := pr.profile("a.zxx", []byte(src), nil, 5)
+	if err == nil || err.Error() != "a.zxx:6:8: invalid operation +" {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := pr.print(&b, 2); err != nil {
+		t.Fatal(err)
+	}
+	want := `93 instructions
+        60  64.52%  a.zxx:3  out len('abc') > 1 and len('abc') == 3
+        30  32.26%  a.zxx:2  out 1 + 2 == 3
+`
+	if b.String() != want {
+		t.Fatal(b.String())
+	}
+
+	pr = newProfiler()
+	if err := pr.profile("a.zxx", []byte(src), regexp.MustCompile("A"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if lines := pr.prof.Lines(); len(lines) != 2 {
+		t.Fatal(lines)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// 使用统计只保存在本地, 从不上传. 统计文件存在时才记录, zxx stats tools -enable 创建它.
+
+// usageRecord 是一次子命令的执行, 统计文件的每一行是一个 JSON 编码的 usageRecord.
+type usageRecord struct {
+	Command  string        `json:"cmd"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"ns"`
+	Code     int           `json:"code"`
+}
+
+// usageFile 返回统计文件的路径, 无法确定用户配置目录时为空.
+func usageFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "zxx", "usage.jsonl")
+}
+
+// recordUsage 在统计文件 path 的末尾追加 r, 文件不存在时什么也不做.
+func recordUsage(path string, r usageRecord) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(r)
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// readUsage 读取统计文件 path 中的记录, 跳过无法解码的行.
+func readUsage(path string) (records []usageRecord, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		var r usageRecord
+		if json.Unmarshal(lines.Bytes(), &r) == nil && r.Command != "" {
+			records = append(records, r)
+		}
+	}
+	return records, lines.Err()
+}
+
+// summarize 在 w 上按总用时从多到少输出每个子命令的执行次数, 失败次数和用时.
+func summarize(w io.Writer, records []usageRecord) {
+	type summary struct {
+		command      string
+		runs, failed int
+		total, max   time.Duration
+	}
+	byCommand := map[string]*summary{}
+	var list []*summary
+	for _, r := range records {
+		s := byCommand[r.Command]
+		if s == nil {
+			s = &summary{command: r.Command}
+			byCommand[r.Command] = s
+			list = append(list, s)
+		}
+		s.runs++
+		if r.Code != 0 {
+			s.failed++
+		}
+		s.total += r.Duration
+		if r.Duration > s.max {
+			s.max = r.Duration
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].total > list[j].total })
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "runs\tfailed\ttotal\tmean\tmax\t")
+	for _, s := range list {
+		mean := s.total / time.Duration(s.runs)
+		fmt.Fprintf(tw, "%d\t%d\t%v\t%v\t%v\t %s\n", s.runs, s.failed,
+			s.total.Round(time.Millisecond), mean.Round(time.Millisecond), s.max.Round(time.Millisecond), s.command)
+	}
+	tw.Flush()
+}
+
+// runTools 实现 zxx stats tools
+func runTools(args []string) int {
+	flags := flag.NewFlagSet("stats tools", flag.ExitOnError)
+	enable := flags.Bool("enable", false, "start recording subcommand runs in the local stats file")
+	disable := flags.Bool("disable", false, "stop recording and remove the local stats file")
+	reset := flags.Bool("reset", false, "clear the recorded runs")
+	flags.Parse(args)
+
+	path := usageFile()
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "zxx stats tools: cannot determine the user config directory")
+		return 1
+	}
+	var err error
+	switch {
+	case *disable:
+		if err = os.Remove(path); os.IsNotExist(err) {
+			err = nil
+		}
+	case *enable || *reset:
+		if _, e := os.Stat(path); *reset && os.IsNotExist(e) {
+			break
+		}
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			mode := os.O_WRONLY | os.O_CREATE
+			if *reset {
+				mode |= os.O_TRUNC
+			}
+			var f *os.File
+			if f, err = os.OpenFile(path, mode, 0644); err == nil {
+				err = f.Close()
+			}
+		}
+	default:
+		records, err := readUsage(path)
+		if os.IsNotExist(err) {
+			fmt.Fprintln(os.Stderr, "usage statistics are disabled, enable them with: zxx stats tools -enable")
+			return 0
+		}
+		if err != nil {
+			report(path, err)
+			return 1
+		}
+		summarize(os.Stdout, records)
+		return 0
+	}
+	if err != nil {
+		report(path, err)
+		return 1
+	}
+	return 0
+}
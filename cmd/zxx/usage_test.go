@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+
+	// 没有统计文件时不记录
+	if err := recordUsage(path, usageRecord{Command: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("recorded without opt-in")
+	}
+
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, r := range []usageRecord{
+		{"parse", now, 10 * time.Millisecond, 0},
+		{"test", now, 300 * time.Millisecond, 1},
+		{"test", now, 100 * time.Millisecond, 0},
+	} {
+		if err := recordUsage(path, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := readUsage(path)
+	if err != nil || len(records) != 3 || records[1].Duration != 300*time.Millisecond || records[1].Code != 1 {
+		t.Fatal(records, err)
+	}
+
+	var out strings.Builder
+	summarize(&out, records)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[1], " test") || !strings.Contains(lines[1], "400ms") ||
+		!strings.Contains(lines[1], "200ms") || !strings.HasSuffix(lines[2], " parse") {
+		t.Fatal(out.String())
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ZxxLang/zxx/config"
+	"github.com/ZxxLang/zxx/diag"
+	"github.com/ZxxLang/zxx/mod"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/platform"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+func init() {
+	commands["vet"] = &command{
+		usage: "vet [-severity id=level,...] [-format text|json|sarif|pretty] [-target os/arch] [-rules] file...",
+		run:   runVet,
+	}
+}
+
+func runVet(args []string) int {
+	flags := flag.NewFlagSet("vet", flag.ExitOnError)
+	var override diag.Config
+	flags.Var(&override, "severity", "comma-separated id=level, level is off, hint, info, warning or error")
+	format := flags.String("format", "text", "output format: text, json (one object per line), sarif or pretty (text with source snippets)")
+	rules := flags.Bool("rules", false, "list the diagnostics and their default severities")
+	targetFlag := flags.String("target", "", "os/arch[,tag...] for zxx:build constraints of imported packages, default is the current platform")
+	flags.Parse(args)
+	if *rules {
+		for _, r := range diag.Rules() {
+			fmt.Printf("%-16s %-8v %s\n", r.ID, r.Severity, r.Doc)
+		}
+		return 0
+	}
+	f, err := diag.ParseFormat(*format)
+	target := parser.DefaultTarget()
+	if err == nil && *targetFlag != "" {
+		target, err = parser.ParseTarget(*targetFlag)
+	}
+	if err != nil || flags.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: zxx", commands["vet"].usage)
+		return 2
+	}
+
+	// 命令行参数优先于第一个文件所在项目的 lint 段, 项目的语言版本用于全部文件
+	p, err := config.LoadProject(filepath.Dir(flags.Arg(0)))
+	if err != nil {
+		report(flags.Arg(0), err)
+		return 2
+	}
+	c, err := diag.FromLint(&p.Lint)
+	if err != nil {
+		report(filepath.Join(p.Root, config.ProjectFile), err)
+		return 2
+	}
+	for id, sev := range override.Severity {
+		c.Severity[id] = sev
+	}
+
+	var files []diag.File
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			report(path, err)
+			return 2
+		}
+		files = append(files, diag.File{Name: path, Src: src, Version: p.Version})
+	}
+	// use 路径先按模块清单解析, 再在项目的导入搜索路径中查找
+	var m *mod.Manifest
+	var cache string
+	root := mod.FindRoot(filepath.Dir(flags.Arg(0)))
+	if root != "" {
+		if m, err = mod.Load(root); err == nil {
+			cache, err = mod.CacheDir()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "zxx vet:", err)
+			return 2
+		}
+	}
+	failed, err := lint(os.Stdout, files, loader(p.Path, m, root, cache, target), c, f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "zxx vet:", err)
+		return 2
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// lint 在 w 上以格式 f 按 c 输出包 files 的诊断, 返回是否有 Error 级别的诊断.
+// load 加载 use 引入的包, 为 nil 时不检查对其它包的引用.
+func lint(w io.Writer, files []diag.File, load diag.Loader, c *diag.Config, f diag.Format) (bool, error) {
+	var ig diag.Ignores
+	fset := scanner.NewFileSet()
+	p := &diag.Printer{Format: f, Files: map[string]*scanner.File{}, Sources: map[string][]byte{}}
+	p.Renderer.Context = 1
+	for _, file := range files {
+		ig.Add(file.Name, file.Src) // 扫描错误已是 syntax 诊断
+		p.Files[file.Name] = fset.AddFile(file.Name, file.Src)
+		p.Sources[file.Name] = file.Src
+	}
+	list := diag.Check(files)
+	if load != nil {
+		more, err := diag.CheckImports(files, load)
+		if err != nil {
+			return false, err
+		}
+		list = append(list, more...)
+	}
+	list = c.Apply(list, &ig)
+	failed := false
+	for _, d := range list {
+		failed = failed || d.Severity == diag.Error
+	}
+	return failed, p.Print(w, list)
+}
+
+// loader 返回查找 use 路径的 Loader, 包是找到的第一个目录中构建约束对 target 成立的 .zxx 文件.
+// 目录 dirs 中大小写与 use 路径不同的目录是错误, 参见 platform.CheckCase.
+// m 不为 nil 时先按模块 root 的清单 m 在模块和缓存 cache 中查找, 然后在目录 dirs 中依次查找.
+func loader(dirs []string, m *mod.Manifest, root, cache string, target *parser.Target) diag.Loader {
+	return func(path string) ([]diag.File, error) {
+		var candidates []string
+		if m != nil {
+			if dir, ok := m.Dir(root, cache, path); ok {
+				candidates = append(candidates, dir)
+			}
+		}
+		for _, dir := range dirs {
+			// 不区分大小写的文件系统上大小写不同的 use 路径也能找到, 但是在其它平台上找不到
+			var ce *platform.CaseError
+			if err := platform.CheckCase(os.DirFS(dir), path); errors.As(err, &ce) {
+				return nil, err
+			}
+			candidates = append(candidates, filepath.Join(dir, filepath.FromSlash(path)))
+		}
+		for _, dir := range candidates {
+			infos, err := ioutil.ReadDir(dir)
+			if err != nil {
+				continue
+			}
+			var files []diag.File
+			for _, info := range infos {
+				if info.IsDir() || !strings.HasSuffix(info.Name(), ".zxx") {
+					continue
+				}
+				name := filepath.Join(dir, info.Name())
+				src, err := ioutil.ReadFile(name)
+				if err != nil {
+					return nil, err
+				}
+				if c, err := parser.HeaderBuild(src); err == nil && !target.Match(c) {
+					continue
+				}
+				files = append(files, diag.File{Name: name, Src: src})
+			}
+			return files, nil
+		}
+		return nil, nil
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/diag"
+	"github.com/ZxxLang/zxx/mod"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestLint(t *testing.T) {
+	files := []diag.File{
+		{Name: "a.zxx", Src: []byte("proc main [\n]\n\nproc old [\n]\n\nproc older [\n] // zxx:ignore unused-func\n")},
+		{Name: "b.zxx", Src: []byte("var a = (\n--- x")},
+	}
+	var out strings.Builder
+	if failed, err := lint(&out, files, nil, &diag.Config{}, diag.Text); err != nil || !failed {
+		t.Fatal(failed, err)
+	}
+	if !strings.HasPrefix(out.String(), "a.zxx:4:6: warning: proc old is unused [unused-func]\nb.zxx:") {
+		t.Fatalf("%q", out.String())
+	}
+
+	out.Reset()
+	c := &diag.Config{}
+	c.Set("syntax=info,unused-func=off")
+	if failed, _ := lint(&out, files, nil, c, diag.JSON); failed || strings.Count(out.String(), "\n") != 1 || !strings.HasPrefix(out.String(), `{"ID":"syntax","Severity":"info","File":"b.zxx",`) {
+		t.Fatalf("%q", out.String())
+	}
+
+	out.Reset()
+	lint(&out, files[:1], nil, &diag.Config{}, diag.Pretty)
+	if out.String() != "a.zxx:4:6: warning: proc old is unused [unused-func]\n   3 |\n   4 | proc old [\n     |      ^~~\n   5 | ]\n" {
+		t.Fatalf("%q", out.String())
+	}
+}
+
+func TestLintImports(t *testing.T) {
+	util := []diag.File{{Name: "util/a.zxx", Src: []byte("pub proc Helper [\n]\n\nproc helper [\n]\n")}}
+	load := func(path string) ([]diag.File, error) {
+		if path == "util" {
+			return util, nil
+		}
+		return nil, nil
+	}
+	files := []diag.File{{Name: "main.zxx", Src: []byte("use 'util'\nuse 'fmt'\n\nproc main [\n\tutil.helper()\n\tutil.Helper()\n\tfmt.print(1)\n]\n")}}
+	var out strings.Builder
+	if failed, err := lint(&out, files, load, &diag.Config{}, diag.Text); err != nil || !failed {
+		t.Fatal(failed, err)
+	}
+	if want := "main.zxx:5:2: error: cannot refer to unexported member util.helper, did you mean util.Helper? [unexported]\n"; out.String() != want {
+		t.Fatalf("%q", out.String())
+	}
+}
+
+func TestLoader(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "zxx-vet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	lib := filepath.Join(tmp, "cache", "example.org", "lib@v1.0.0", "util")
+	os.MkdirAll(lib, 0755)
+	ioutil.WriteFile(filepath.Join(lib, "a.zxx"), []byte("pub proc Helper [\n]\n"), 0644)
+	shared := filepath.Join(tmp, "shared", "text")
+	os.MkdirAll(shared, 0755)
+	ioutil.WriteFile(filepath.Join(shared, "b.zxx"), []byte("proc x [\n]\n"), 0644)
+	ioutil.WriteFile(filepath.Join(shared, "b_windows.zxx"), []byte("// zxx:build windows\nproc y [\n]\n"), 0644)
+
+	m := &mod.Manifest{Module: "example.org/app", Require: []mod.Require{{Path: "example.org/lib", Version: "v1.0.0"}}}
+	load := loader([]string{filepath.Join(tmp, "shared")}, m, filepath.Join(tmp, "app"), filepath.Join(tmp, "cache"), &parser.Target{OS: "linux", Arch: "amd64"})
+	for path, want := range map[string]string{
+		"example.org/lib/util": filepath.Join(lib, "a.zxx"),
+		"text":                 filepath.Join(shared, "b.zxx"),
+		"example.org/app/none": "",
+	} {
+		files, err := load(path)
+		if err != nil || want == "" && len(files) != 0 || want != "" && (len(files) != 1 || files[0].Name != want) {
+			t.Errorf("%s: %v %v", path, files, err)
+		}
+	}
+
+	// 只在不区分大小写的文件系统上能找到的 use 路径
+	if _, err := load("Text"); err == nil || err.Error() != "platform: Text differs in case from text on disk" {
+		t.Fatal(err)
+	}
+}
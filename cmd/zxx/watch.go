@@ -0,0 +1,167 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ZxxLang/zxx/doc"
+	"github.com/ZxxLang/zxx/format"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/writefile"
+)
+
+func init() {
+	commands["watch"] = &command{
+		usage: "watch [-interval d] [-fmt] [-test] [-ext .zxx] [file or dir...]",
+		run:   runWatch,
+	}
+}
+
+func runWatch(args []string) int {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	interval := flags.Duration("interval", 300*time.Millisecond, "how often to look for changed files")
+	w := &watcher{out: os.Stdout, seen: map[string]stamp{}}
+	flags.BoolVar(&w.fmt, "fmt", false, "format changed files in place")
+	flags.BoolVar(&w.test, "test", false, "run examples and tests of changed files")
+	flags.StringVar(&w.ext, "ext", ".zxx", "file extension when walking directories")
+	flags.IntVar(&w.maxErrors, "max-errors", 10, "errors to report per file")
+	flags.Parse(args)
+
+	w.paths = flags.Args()
+	if len(w.paths) == 0 {
+		w.paths = []string{"."}
+	}
+	for {
+		w.poll()
+		time.Sleep(*interval)
+	}
+}
+
+// stamp 用于判断文件是否改变
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// watcher 轮询 paths 下的文件, 只重新检查改变的文件.
+// 没有使用操作系统的文件通知, 轮询只需要 stat, 对源码目录足够快.
+type watcher struct {
+	out       io.Writer
+	paths     []string
+	ext       string
+	fmt, test bool
+	maxErrors int
+
+	seen map[string]stamp // 已检查的文件
+}
+
+// poll 检查一次全部文件, 返回改变的文件数. 删除的文件被遗忘.
+func (w *watcher) poll() (changed int) {
+	exists := map[string]bool{}
+	for _, root := range w.paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || path != root && !strings.HasSuffix(path, w.ext) {
+				return nil
+			}
+			exists[path] = true
+			st := stamp{info.Size(), info.ModTime()}
+			if old, ok := w.seen[path]; ok && old == st {
+				return nil
+			}
+			changed++
+			w.seen[path] = w.check(path, st)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(w.out, "%s: %v\n", root, err)
+		}
+	}
+	for path := range w.seen {
+		if !exists[path] {
+			delete(w.seen, path)
+		}
+	}
+	return
+}
+
+// check 检查文件 path 并输出诊断, 返回检查之后的 stamp, 格式化会改变它.
+func (w *watcher) check(path string, st stamp) stamp {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(w.out, "%s: %v\n", path, err)
+		return st
+	}
+
+	if w.fmt {
+		if res, err := format.Source(src); err == nil && !bytes.Equal(res, src) {
+			if err = writefile.File(path, res, writefile.Options{}); err != nil {
+				fmt.Fprintf(w.out, "%s: %v\n", path, err)
+				return st
+			}
+			fmt.Fprintf(w.out, "formatted\t%s\n", path)
+			if info, err := os.Stat(path); err == nil {
+				st = stamp{info.Size(), info.ModTime()}
+			}
+			src = res
+		}
+	}
+
+	version, err := projectVersion(path)
+	if err != nil {
+		fmt.Fprintf(w.out, "%s: %v\n", path, err)
+		return st
+	}
+	file, err := (&parser.Config{Mode: parser.ParseComments | parser.ParsePlaceholders, MaxErrors: w.maxErrors, Version: version}).Parse(src)
+	if err != nil {
+		w.diagnose(path, src, err)
+		return st
+	}
+	if w.test {
+		r := &reporter{w: w.out}
+		runExamples(doc.New(path, file), r)
+		runTests(path, src, file, r)
+		if r.failed != 0 {
+			fmt.Fprintf(w.out, "FAIL\t%s\t%d of %d examples and tests failed\n", path, r.failed, r.total)
+			return st
+		}
+	}
+	fmt.Fprintf(w.out, "ok\t%s\n", path)
+	return st
+}
+
+// diagnose 按位置顺序输出文件 path 的解析错误 err
+func (w *watcher) diagnose(path string, src []byte, err error) {
+	list, ok := err.(parser.ErrorList)
+	if !ok {
+		list = parser.ErrorList{err}
+	}
+	lines := scanner.NewFileSet().AddFile(path, src)
+	sort.SliceStable(list, func(i, j int) bool {
+		a, aok := list[i].(*parser.Error)
+		b, bok := list[j].(*parser.Error)
+		return aok && bok && a.Pos < b.Pos
+	})
+	for _, err := range list {
+		if e, ok := err.(*parser.Error); ok {
+			fmt.Fprintf(w.out, "%s: %s\n", lines.Position(e.Pos).String(path), e.Msg)
+		} else {
+			fmt.Fprintf(w.out, "%s: %v\n", path, err)
+		}
+	}
+}
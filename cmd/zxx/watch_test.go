@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zxx-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.zxx"), filepath.Join(dir, "b.zxx")
+	write := func(name, src string, mtime time.Time) {
+		if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	write(a, "var x = 1\n", now)
+	write(b, "var y = 2\n", now)
+
+	var out strings.Builder
+	w := &watcher{out: &out, paths: []string{dir}, ext: ".zxx", maxErrors: 10, seen: map[string]stamp{}}
+	if n := w.poll(); n != 2 || strings.Count(out.String(), "ok\t") != 2 {
+		t.Fatal(n, out.String())
+	}
+	out.Reset()
+	if n := w.poll(); n != 0 || out.Len() != 0 {
+		t.Fatal(n, out.String())
+	}
+
+	write(a, "var x = 0x\n", now.Add(time.Second))
+	if n := w.poll(); n != 1 || !strings.HasPrefix(out.String(), a+": ") {
+		t.Fatal(n, out.String())
+	}
+
+	out.Reset()
+	os.Remove(b)
+	w.poll()
+	if _, ok := w.seen[b]; ok || out.Len() != 0 {
+		t.Fatal(out.String())
+	}
+
+	// 项目配置的语言版本用于解析
+	write(filepath.Join(dir, "zxx.toml"), "version = '1'\n", now)
+	write(a, "var x = 1\nvar s = \"{x}\"\n", now.Add(2*time.Second))
+	if n := w.poll(); n != 1 || !strings.Contains(out.String(), "requires language version 2") {
+		t.Fatal(n, out.String())
+	}
+}
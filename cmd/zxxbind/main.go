@@ -0,0 +1,172 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// zxxbind 从 Go 包生成 zxx 外部函数的声明, 参见 ast.GoImportPrefix.
+//
+// 用法:
+//
+//	zxxbind [-o file] [-v] importpath
+//
+// 只生成参数和结果都是基础类型或 time.Time 的导出函数, 结果可以附带 error,
+// 其它函数被跳过, -v 输出跳过的函数及原因.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/importer"
+	gotoken "go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func main() {
+	out := flag.String("o", "", "write to file instead of stdout")
+	verbose := flag.Bool("v", false, "report skipped functions")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: zxxbind [flags] importpath")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pkg, err := importer.ForCompiler(gotoken.NewFileSet(), "source", nil).Import(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	var buf bytes.Buffer
+	for _, msg := range bind(pkg, &buf) {
+		if *verbose {
+			fmt.Fprintln(os.Stderr, "zxxbind: skip", msg)
+		}
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(buf.Bytes())
+	} else {
+		err = ioutil.WriteFile(*out, buf.Bytes(), 0666)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "zxxbind:", err)
+	os.Exit(1)
+}
+
+// bind 在 w 上输出 pkg 中导出函数的声明, 返回被跳过的函数及原因
+func bind(pkg *types.Package, w io.Writer) (skipped []string) {
+	fmt.Fprintf(w, "// Code generated by zxxbind from Go package %s; DO NOT EDIT.\n\n", pkg.Path())
+	fmt.Fprintf(w, "use %s\n", strconv.Quote(ast.GoImportPrefix+pkg.Path()))
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		fn, ok := scope.Lookup(name).(*types.Func)
+		if !ok || !fn.Exported() {
+			continue
+		}
+		decl, err := declare(fn)
+		if err != nil {
+			skipped = append(skipped, name+": "+err.Error())
+			continue
+		}
+		fmt.Fprintln(w, decl)
+	}
+	return
+}
+
+// declare 返回函数 fn 的 zxx 声明
+func declare(fn *types.Func) (string, error) {
+	sig := fn.Type().(*types.Signature)
+	if sig.Variadic() {
+		return "", errors.New("variadic")
+	}
+
+	s := "pub func " + fn.Name() + "("
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		p := params.At(i)
+		typ, ok := zxxType(p.Type())
+		if !ok {
+			return "", errors.New("unsupported parameter type " + p.Type().String())
+		}
+		// 参数名不能是 zxx 保留字
+		name := p.Name()
+		if name == "" || name == "_" || token.Lookup(name) != token.PLACEHOLDER {
+			name = "a" + strconv.Itoa(i)
+		}
+		if i != 0 {
+			s += ", "
+		}
+		s += typ + " " + name
+	}
+	s += ")"
+
+	results := sig.Results()
+	n := results.Len()
+	if n != 0 && isError(results.At(n-1).Type()) {
+		n--
+	}
+	switch n {
+	case 0:
+	case 1:
+		typ, ok := zxxType(results.At(0).Type())
+		if !ok {
+			return "", errors.New("unsupported result type " + results.At(0).Type().String())
+		}
+		s += " " + typ
+	default:
+		return "", errors.New("multiple results")
+	}
+	return s, nil
+}
+
+var basics = map[types.BasicKind]string{
+	types.Bool:    "bool",
+	types.String:  "string",
+	types.Int:     "int",
+	types.Int8:    "i8",
+	types.Int16:   "i16",
+	types.Int32:   "i32",
+	types.Int64:   "i64",
+	types.Uint:    "uint",
+	types.Uint8:   "u8",
+	types.Uint16:  "u16",
+	types.Uint32:  "u32",
+	types.Uint64:  "u64",
+	types.Float32: "f32",
+	types.Float64: "f64",
+}
+
+// zxxType 返回 Go 类型 t 对应的 zxx 类型
+func zxxType(t types.Type) (string, bool) {
+	switch t := t.(type) {
+	case *types.Basic:
+		s, ok := basics[t.Kind()]
+		return s, ok
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time" {
+			return "datetime", true
+		}
+	}
+	return "", false
+}
+
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"go/importer"
+	gotoken "go/token"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestBind(t *testing.T) {
+	pkg, err := importer.ForCompiler(gotoken.NewFileSet(), "source", nil).Import("strconv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	skipped := bind(pkg, &buf)
+	src := buf.String()
+	for _, s := range []string{
+		"use \"go:strconv\"\n",
+		"pub func Itoa(int i) string\n",
+		"pub func Atoi(string s) int\n",
+		"pub func ParseBool(string str) bool\n",
+	} {
+		if !strings.Contains(src, s) {
+			t.Fatal(s, "\n", src)
+		}
+	}
+	if !strings.Contains(strings.Join(skipped, "\n"), "AppendInt: unsupported parameter type []byte") {
+		t.Fatal(skipped)
+	}
+
+	file := ast.NewFile()
+	if err := parser.Parse(buf.Bytes(), file); err != nil {
+		t.Fatal(err)
+	}
+	uses, externs := 0, 0
+	for _, n := range file.Nodes[1:] {
+		if n.Parent().Id() != 0 {
+			continue
+		}
+		if path, ok := ast.GoImport(n); ok && path == "strconv" {
+			uses++
+		}
+		if ast.IsExtern(n) {
+			externs++
+		}
+	}
+	if uses != 1 || externs != strings.Count(src, "pub func ") {
+		t.Fatal(uses, externs)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// zxxdoc 提取 zxx 源码的声明文档.
+//
+// 用法:
+//
+//	zxxdoc [-format text|markdown|html] [-name name] file...
+//
+// 缺省包名是首个文件所在目录的名字.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/doc"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func main() {
+	format := flag.String("format", "text", "output format: text, markdown or html")
+	name := flag.String("name", "", "package name, defaults to the directory of the first file")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: zxxdoc [flags] file...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var files []*ast.File
+	for _, filename := range flag.Args() {
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			fatal(err)
+		}
+		file := ast.NewFile()
+		if err = parser.Parse(src, file); err != nil {
+			fatal(fmt.Errorf("%s: %v", filename, err))
+		}
+		files = append(files, file)
+	}
+
+	if *name == "" {
+		abs, err := filepath.Abs(flag.Arg(0))
+		if err != nil {
+			fatal(err)
+		}
+		*name = filepath.Base(filepath.Dir(abs))
+	}
+
+	p := doc.New(*name, files...)
+	var err error
+	switch *format {
+	case "text":
+		err = p.Text(os.Stdout)
+	case "markdown", "md":
+		err = p.Markdown(os.Stdout)
+	case "html":
+		err = p.HTML(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "zxxdoc:", err)
+	os.Exit(1)
+}
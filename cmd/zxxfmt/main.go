@@ -0,0 +1,246 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// zxxfmt 格式化 zxx 源码.
+//
+// 用法:
+//
+//	zxxfmt [-l] [-d] [-w] [-backup] [-fix] [-literals] [-quote single|double] [-indent tabs|spaces[:n]] [-eol lf|crlf] [file or dir...]
+//
+// 没有参数时格式化标准输入. 目录被递归遍历, 只处理 .zxx 文件.
+// 使用 -fix 时, 先把非法的 UTF-8 编码替换为 U+FFFD, 再应用解析错误建议的修改,
+// 例如补全缺少的引号, 统一混搭的缩进.
+// 使用 -literals 时, 统一字面值的写法, 例如十六进制数字大写, 数字按位分组, 优先使用单引号.
+// 使用 -quote 时, 字符串在值不变的前提下统一使用单引号或双引号.
+// 使用 -indent 时, 每行的缩进改用 TAB 或 n 个空格一级, 例如 -indent=spaces:4 -w 迁移整个仓库.
+// 使用 -eol 时, 换行统一为 LF 或 CRLF, 默认是 LF. 字符串中的换行也一同改变, 值不变.
+// 使用 -w 时, 文件先写入临时文件再改名替换, 出错或崩溃不会留下写了一半的文件,
+// 同时使用 -backup 时原文件保存为 name.orig. -d 只输出 diff, 不修改文件.
+// 没有在命令行给出的 -literals, -quote, -indent, -eol 取自文件所在目录的项目配置 zxx.toml 的 format 表,
+// 参见 config.LoadProject.
+// 使用 -l 或 -d 时, 如果有文件需要格式化, 退出码为 1, 便于 CI 检查.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/config"
+	"github.com/ZxxLang/zxx/format"
+	"github.com/ZxxLang/zxx/writefile"
+)
+
+var (
+	list  = flag.Bool("l", false, "list files whose formatting differs from zxxfmt's")
+	diff  = flag.Bool("d", false, "display diffs instead of rewriting files")
+	write = flag.Bool("w", false, "write result to source file instead of stdout")
+	fix   = flag.Bool("fix", false, "apply fixes suggested by parse errors before formatting")
+
+	backup = flag.Bool("backup", false, "with -w, keep the original file as name.orig")
+
+	literals = flag.Bool("literals", false, "normalize the spelling of literals")
+	quote    = flag.String("quote", "", "convert strings to `single|double` quotes when the value permits")
+	indent   = flag.String("indent", "", "rewrite indentation as `tabs|spaces[:n]`, n defaults to 4")
+	eol      = flag.String("eol", "", "write line endings as `lf|crlf`, defaults to lf")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: zxxfmt [flags] [file or dir...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if err := (style{*literals, *quote, *indent, *eol}).check(); err != nil {
+		report(err)
+		os.Exit(2)
+	}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	os.Exit(run(flag.Args()))
+}
+
+// set 是命令行给出的参数
+var set = map[string]bool{}
+
+// style 是格式化一个文件的风格
+type style struct {
+	literals           bool
+	quote, indent, eol string
+}
+
+func (s style) check() error {
+	if _, ok := quotes[s.quote]; s.quote != "" && !ok {
+		return fmt.Errorf("invalid -quote %q, want single or double", s.quote)
+	}
+	if _, ok := parseIndent(s.indent); s.indent != "" && !ok {
+		return fmt.Errorf("invalid -indent %q, want tabs or spaces[:n]", s.indent)
+	}
+	if _, err := format.ParseEOL(s.eol); s.eol != "" && err != nil {
+		return fmt.Errorf("invalid -eol %q, want lf or crlf", s.eol)
+	}
+	return nil
+}
+
+// styles 缓存每个目录的风格
+var styles = map[string]style{}
+
+// styleOf 返回目录 dir 中的文件的风格, 命令行参数优先于项目配置
+func styleOf(dir string) (style, error) {
+	if s, ok := styles[dir]; ok {
+		return s, nil
+	}
+	p, err := config.LoadProject(dir)
+	if err != nil {
+		return style{}, err
+	}
+	s := style{p.Format.Literals, p.Format.Quote, p.Format.Indent, p.Format.EOL}
+	if set["literals"] {
+		s.literals = *literals
+	}
+	if set["quote"] {
+		s.quote = *quote
+	}
+	if set["indent"] {
+		s.indent = *indent
+	}
+	if set["eol"] {
+		s.eol = *eol
+	}
+	if err = s.check(); err != nil {
+		return style{}, fmt.Errorf("%s: %v", filepath.Join(p.Root, config.ProjectFile), err)
+	}
+	styles[dir] = s
+	return s, nil
+}
+
+// run 返回退出码: 2 表示出错, 1 表示 -l, -d 模式下有文件需要格式化
+func run(paths []string) int {
+	if len(paths) == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "zxxfmt: cannot use -w with standard input")
+			return 2
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err == nil {
+			var changed bool
+			var s style
+			if s, err = styleOf("."); err == nil {
+				if changed, err = process("<standard input>", src, s); err == nil {
+					return exit(changed)
+				}
+			}
+		}
+		report(err)
+		return 2
+	}
+
+	code, changed := 0, false
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || path != root && !strings.HasSuffix(path, ".zxx") {
+				return nil
+			}
+			s, err := styleOf(filepath.Dir(path))
+			var src []byte
+			if err == nil {
+				src, err = ioutil.ReadFile(path)
+			}
+			if err == nil {
+				var c bool
+				c, err = process(path, src, s)
+				changed = changed || c
+			}
+			if err != nil {
+				report(err)
+				code = 2
+			}
+			return nil
+		})
+		if err != nil {
+			report(err)
+			code = 2
+		}
+	}
+	if code == 0 {
+		code = exit(changed)
+	}
+	return code
+}
+
+// exit 返回没有错误时的退出码
+func exit(changed bool) int {
+	if changed && (*list || *diff) {
+		return 1
+	}
+	return 0
+}
+
+// config 返回风格 s 对应的 format.Config, s 已经通过 check
+func (s style) config() *format.Config {
+	c := &format.Config{Fix: *fix, Literals: s.literals, Quote: quotes[s.quote], EOL: format.EOL(s.eol)}
+	if s.indent != "" {
+		in, _ := parseIndent(s.indent)
+		c.Indent = &in
+	}
+	return c
+}
+
+// process 按风格 s 格式化文件 name 的源码 src, 返回是否需要格式化
+func process(name string, src []byte, s style) (bool, error) {
+	res, err := s.config().Format(src)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", name, err)
+	}
+	changed := !bytes.Equal(src, res)
+
+	if *list && changed {
+		fmt.Println(name)
+	}
+	if *write && changed {
+		if err = writefile.File(name, res, writefile.Options{Backup: *backup}); err != nil {
+			return changed, err
+		}
+	}
+	if *diff && changed {
+		os.Stdout.Write(writefile.Unified(name, src, res))
+	}
+	if !*list && !*write && !*diff {
+		os.Stdout.Write(res)
+	}
+	return changed, nil
+}
+
+// quotes 是 -quote 的取值对应的引号
+var quotes = map[string]byte{"single": '\'', "double": '"'}
+
+// parseIndent 解析 -indent 的取值 tabs[:n] 或 spaces[:n]
+func parseIndent(s string) (in format.Indent, ok bool) {
+	style, width := s, "4"
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		style, width = s[:i], s[i+1:]
+	}
+	switch style {
+	case "tabs":
+		in.Style = format.Tabs
+	case "spaces":
+		in.Style = format.Spaces
+	default:
+		return
+	}
+	n, err := strconv.Atoi(width)
+	in.Width = n
+	return in, err == nil && n > 0
+}
+
+func report(err error) {
+	fmt.Fprintln(os.Stderr, "zxxfmt:", err)
+}
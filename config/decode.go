@@ -0,0 +1,260 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/token"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Decode 求值配置文档 src 并把顶层声明解码到 v, v 必须是非 nil 指针.
+//
+// 记录可以解码到 struct, map[string]T 或 interface{}, 列表可以解码到
+// slice, array 或 interface{}. struct 字段名优先使用 `zxx:"name"` 标签,
+// 否则不区分大小写的匹配字段名, 标签 `zxx:"-"` 忽略该字段.
+// 没有对应字段的名字被忽略. 解码到 interface{} 时记录为
+// map[string]interface{}, 列表为 []interface{}, 整数为 int64.
+//
+// 错误总是 *Error 类型, 携带出错值的位置.
+func Decode(src []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("config: Decode requires a non-nil pointer")
+	}
+	d, err := parse(src)
+	if err != nil {
+		return err
+	}
+	return d.assign(d.root, rv.Elem())
+}
+
+func (d *doc) mismatch(x *value, rv reflect.Value) error {
+	what := "value"
+	switch {
+	case x.kind == list:
+		what = "list"
+	case x.kind == record:
+		what = "record"
+	case x.val == nil:
+		what = "null"
+	default:
+		what = reflect.TypeOf(x.val).String()
+	}
+	return d.errorf(x.pos, "cannot decode", what, "into", rv.Type().String())
+}
+
+// assign 把 x 解码到可设置的 rv
+func (d *doc) assign(x *value, rv reflect.Value) error {
+	if x.kind == scalar && x.val == nil {
+		switch rv.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		return d.mismatch(x, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return d.assign(x, rv.Elem())
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return d.mismatch(x, rv)
+		}
+		rv.Set(reflect.ValueOf(x.natural()))
+		return nil
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return d.assignTime(x, rv)
+		}
+		if x.kind != record {
+			return d.mismatch(x, rv)
+		}
+		return d.assignStruct(x, rv)
+	case reflect.Map:
+		if x.kind != record || rv.Type().Key().Kind() != reflect.String {
+			return d.mismatch(x, rv)
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		typ := rv.Type()
+		for _, key := range x.keys {
+			elem := reflect.New(typ.Elem()).Elem()
+			if err := d.assign(x.rec[key], elem); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(key).Convert(typ.Key()), elem)
+		}
+		return nil
+	case reflect.Slice:
+		if x.kind != list {
+			return d.mismatch(x, rv)
+		}
+		rv.Set(reflect.MakeSlice(rv.Type(), len(x.list), len(x.list)))
+		return d.assignList(x, rv)
+	case reflect.Array:
+		if x.kind != list || len(x.list) > rv.Len() {
+			return d.mismatch(x, rv)
+		}
+		return d.assignList(x, rv)
+	}
+
+	if x.kind != scalar {
+		return d.mismatch(x, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		b, ok := x.val.(bool)
+		if !ok {
+			return d.mismatch(x, rv)
+		}
+		rv.SetBool(b)
+	case reflect.String:
+		s, ok := x.val.(string)
+		if !ok {
+			return d.mismatch(x, rv)
+		}
+		rv.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := x.val.(int64)
+		if !ok {
+			return d.mismatch(x, rv)
+		}
+		if rv.OverflowInt(i) {
+			return d.errorf(x.pos, "value", fmt.Sprintf("%v", i), "overflows", rv.Type().String())
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := x.val.(int64)
+		if !ok {
+			return d.mismatch(x, rv)
+		}
+		if i < 0 || rv.OverflowUint(uint64(i)) {
+			return d.errorf(x.pos, "value", fmt.Sprintf("%v", i), "overflows", rv.Type().String())
+		}
+		rv.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		f, ok := float(x)
+		if !ok {
+			return d.mismatch(x, rv)
+		}
+		rv.SetFloat(f)
+	default:
+		return d.mismatch(x, rv)
+	}
+	return nil
+}
+
+func (d *doc) assignList(x *value, rv reflect.Value) error {
+	for i, item := range x.list {
+		if err := d.assign(item, rv.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignTime 解码 datetime 字面值, 8 位整数被视作日期
+func (d *doc) assignTime(x *value, rv reflect.Value) error {
+	switch v := x.val.(type) {
+	case time.Time:
+		rv.Set(reflect.ValueOf(v))
+		return nil
+	case int64:
+		if len(x.src) == 8 {
+			if t, err := eval.Literal(token.VALDATETIME, x.src); err == nil {
+				rv.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+	}
+	return d.mismatch(x, rv)
+}
+
+func (d *doc) assignStruct(x *value, rv reflect.Value) error {
+	typ := rv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		name := field.Tag.Get("zxx")
+		if name == "-" {
+			continue
+		}
+		if j := strings.IndexByte(name, ','); j != -1 {
+			name = name[:j]
+		}
+
+		// 未命名的嵌入 struct 字段被展开
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := d.assignStruct(x, rv.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		item := x.rec[name]
+		if name == "" {
+			item = x.fold(field.Name)
+		}
+		if item == nil {
+			continue
+		}
+		if err := d.assign(item, rv.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fold 返回不区分大小写匹配 key 的记录成员, 精确匹配优先
+func (x *value) fold(key string) *value {
+	if item := x.rec[key]; item != nil {
+		return item
+	}
+	for _, k := range x.keys {
+		if strings.EqualFold(k, key) {
+			return x.rec[k]
+		}
+	}
+	return nil
+}
+
+// natural 返回 x 对应的 Go 值
+func (x *value) natural() interface{} {
+	switch x.kind {
+	case list:
+		v := make([]interface{}, len(x.list))
+		for i, item := range x.list {
+			v[i] = item.natural()
+		}
+		return v
+	case record:
+		v := make(map[string]interface{}, len(x.keys))
+		for _, key := range x.keys {
+			v[key] = x.rec[key].natural()
+		}
+		return v
+	}
+	return x.val
+}
@@ -0,0 +1,125 @@
+package config_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ZxxLang/zxx/config"
+)
+
+const server = `
+服务配置, 首个声明之前是占位文本
+
+const base = 8000
+var string name = 'zxx' + "-" + 'server'
+var (
+	port = base + 80
+	debug = not true
+	ratio = 0.5 *
+		(1 + 3)
+)
+var hosts = [
+	'a.example.com'
+	'b.example.com'
+]
+var limits = {
+	read: 0x10,
+	write = ~-0b110
+}
+var tls = [cert = 'x.pem', key = 'x.key']
+var datetime start = 20160204T21:49Z
+var day = 20160204
+var extra = [1, 'two', [three = 3.0]]
+`
+
+type TLS struct {
+	Cert string
+	Key  string `zxx:"key"`
+}
+
+type Server struct {
+	Name   string
+	Port   uint16
+	Debug  bool
+	Ratio  float64
+	Hosts  []string
+	Limits map[string]int
+	TLS    *TLS `zxx:"tls"`
+	Start  time.Time
+	Day    time.Time
+	Extra  interface{}
+	Skip   int `zxx:"-"`
+}
+
+func TestDecode(t *testing.T) {
+	var s Server
+	if err := config.Decode([]byte(server), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Server{
+		Name:   "zxx-server",
+		Port:   8080,
+		Ratio:  2,
+		Hosts:  []string{"a.example.com", "b.example.com"},
+		Limits: map[string]int{"read": 16, "write": 5},
+		TLS:    &TLS{"x.pem", "x.key"},
+		Start:  time.Date(2016, 2, 4, 21, 49, 0, 0, time.UTC),
+		Day:    time.Date(2016, 2, 4, 0, 0, 0, 0, time.Local),
+		Extra: []interface{}{int64(1), "two",
+			map[string]interface{}{"three": 3.0}},
+	}
+	if !s.Start.Equal(want.Start) || !s.Day.Equal(want.Day) {
+		t.Fatal(s.Start, s.Day)
+	}
+	s.Start, s.Day = want.Start, want.Day
+	if !reflect.DeepEqual(s, want) {
+		t.Fatalf("%#v", s)
+	}
+}
+
+func TestDecodeInterp(t *testing.T) {
+	var s Server
+	if err := config.Decode([]byte("const base = 8000\nvar name = \"port {base + 80}\""), &s); err != nil || s.Name != "port 8080" {
+		t.Fatal(s.Name, err)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	var s Server
+	for _, src := range []string{
+		"var port = 70000",
+		"var name = 1",
+		"var port = unknown",
+		"var a = 1\nvar a = 2",
+		"var hosts = [1,\n2",
+		"var port = 1 / 0",
+		"var = 1",
+		"var port = 1 +",
+		"var port = (1\n",
+		"var port = 1 2",
+		"var name = \"{[1]}\"",
+	} {
+		err := config.Decode([]byte(src), &s)
+		if _, ok := err.(*config.Error); !ok {
+			t.Fatal(src, err)
+		}
+	}
+
+	err := config.Decode([]byte("var x = 1\nvar port = 'p'"), &s)
+	if err == nil || err.Error() != "2:12: config: cannot decode string into uint16" {
+		t.Fatal(err)
+	}
+
+	// 计算得到的值没有源码, 错误中是值本身
+	for src, want := range map[string]string{
+		"var port = 70000":         "1:12: config: value 70000 overflows uint16",
+		"var port = 60000 + 10000": "1:12: config: value 70000 overflows uint16",
+		"var port = -1":            "1:12: config: value -1 overflows uint16",
+	} {
+		if err := config.Decode([]byte(src), &s); err == nil || err.Error() != want {
+			t.Errorf("%s: %v", src, err)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Encoder 把 Go 值编码为规范的 zxx 配置文档.
+//
+// 顶层的每个成员生成一行 var 声明, 记录使用 {key: value} 写法,
+// 只包含标量的列表写在一行, 其它列表和记录每个成员一行, 使用 TAB 缩进.
+// 字段名和 Decode 的规则相同. map 的键按字典序排列, struct 按字段顺序.
+type Encoder struct {
+	// Comment 返回配置项 path 的尾注释, 空字符串表示没有注释.
+	// path 的格式为 server.hosts[1], 多行注释依次写在该项之后.
+	Comment func(path string) string
+}
+
+// Encode 使用缺省的 Encoder 编码 v, v 必须是 struct 或 map[string]T.
+func Encode(v interface{}) ([]byte, error) {
+	return new(Encoder).Encode(v)
+}
+
+// Encode 编码 v, v 必须是 struct 或 map[string]T, 也可以是它们的指针.
+func (e *Encoder) Encode(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct && rv.Kind() != reflect.Map || rv.Type() == timeType {
+		return nil, errors.New("config: Encode requires a struct or map")
+	}
+
+	enc := &encoder{Encoder: e}
+	err := enc.members(rv, func(key string, item reflect.Value) error {
+		if !isIdent(key) {
+			return errors.New("config: invalid name " + strconv.Quote(key))
+		}
+		enc.WriteString("var " + key + " = ")
+		return enc.value(item, key, 0)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return enc.Bytes(), nil
+}
+
+type encoder struct {
+	*Encoder
+	bytes.Buffer
+}
+
+// members 按顺序对记录 rv 的每个成员调用 fn
+func (e *encoder) members(rv reflect.Value, fn func(string, reflect.Value) error) error {
+	if rv.Kind() == reflect.Map {
+		if rv.Type().Key().Kind() != reflect.String {
+			return errors.New("config: unsupported map key type " + rv.Type().Key().String())
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			if err := fn(key.String(), rv.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	typ := rv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name := field.Tag.Get("zxx")
+		if j := strings.IndexByte(name, ','); j != -1 {
+			name = name[:j]
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := e.members(rv.Field(i), fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if err := fn(name, rv.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value 编码 rv 及其尾注释和换行, 当前行已经写入了键
+func (e *encoder) value(rv reflect.Value, path string, depth int) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			e.WriteString("null")
+			e.comment(path, depth)
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct, reflect.Map:
+		if rv.Type() == timeType {
+			break
+		}
+		if rv.Kind() == reflect.Map && rv.IsNil() {
+			e.WriteString("null")
+			e.comment(path, depth)
+			return nil
+		}
+		e.WriteString("{")
+		e.comment(path, depth)
+		err := e.members(rv, func(key string, item reflect.Value) error {
+			e.indent(depth + 1)
+			if isIdent(key) {
+				e.WriteString(key + ": ")
+			} else {
+				e.WriteString(lexutil.Quote(key) + ": ")
+			}
+			return e.value(item, path+"."+key, depth+1)
+		})
+		if err != nil {
+			return err
+		}
+		e.indent(depth)
+		e.WriteString("}\n")
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			e.WriteString("null")
+			e.comment(path, depth)
+			return nil
+		}
+		if e.inline(rv, path) {
+			e.WriteString("[")
+			for i := 0; i < rv.Len(); i++ {
+				if i != 0 {
+					e.WriteString(", ")
+				}
+				if err := e.scalar(rv.Index(i)); err != nil {
+					return err
+				}
+			}
+			e.WriteString("]")
+			e.comment(path, depth)
+			return nil
+		}
+		e.WriteString("[")
+		e.comment(path, depth)
+		for i := 0; i < rv.Len(); i++ {
+			e.indent(depth + 1)
+			if err := e.value(rv.Index(i), path+"["+strconv.Itoa(i)+"]", depth+1); err != nil {
+				return err
+			}
+		}
+		e.indent(depth)
+		e.WriteString("]\n")
+		return nil
+	}
+
+	if err := e.scalar(rv); err != nil {
+		return err
+	}
+	e.comment(path, depth)
+	return nil
+}
+
+// inline 返回列表是否可以写在一行: 元素都是标量并且没有注释
+func (e *encoder) inline(rv reflect.Value, path string) bool {
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i)
+		for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+			if item.IsNil() {
+				break
+			}
+			item = item.Elem()
+		}
+		switch item.Kind() {
+		case reflect.Struct:
+			if item.Type() != timeType {
+				return false
+			}
+		case reflect.Map, reflect.Slice, reflect.Array:
+			return false
+		}
+		if e.Comment != nil && e.Comment(path+"["+strconv.Itoa(i)+"]") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *encoder) scalar(rv reflect.Value) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		e.WriteString(strconv.FormatBool(rv.Bool()))
+	case reflect.String:
+		e.WriteString(lexutil.Quote(rv.String()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.WriteString(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return errors.New("config: value overflows int64")
+		}
+		e.WriteString(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		e.WriteString(lexutil.FormatFloat(rv.Float()))
+	case reflect.Struct:
+		if rv.Type() != timeType {
+			return errors.New("config: unsupported type " + rv.Type().String())
+		}
+		e.WriteString(formatDatetime(rv.Interface().(time.Time)))
+	default:
+		return errors.New("config: unsupported type " + rv.Type().String())
+	}
+	return nil
+}
+
+// comment 写入 path 的尾注释和换行
+func (e *encoder) comment(path string, depth int) {
+	var text string
+	if e.Comment != nil {
+		text = e.Comment(path)
+	}
+	for i, line := range strings.Split(text, "\n") {
+		if i != 0 {
+			e.indent(depth)
+		} else if line != "" {
+			e.WriteByte(' ')
+		}
+		if line != "" {
+			e.WriteString("// " + line)
+		}
+		e.WriteByte('\n')
+	}
+}
+
+func (e *encoder) indent(depth int) {
+	e.WriteString(strings.Repeat("\t", depth))
+}
+
+// isIdent 返回 s 是否为合法的标识符, 即可以作为声明的名字
+func isIdent(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for _, c := range s {
+		if c != '_' && !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return token.Lookup(s) == token.PLACEHOLDER
+}
+
+// formatDatetime 使用基本格式, time.Local 不带时区, 其它时区转换为 UTC
+func formatDatetime(t time.Time) string {
+	if t.Location() == time.Local {
+		return t.Format("20060102T15:04:05")
+	}
+	return t.UTC().Format("20060102T15:04:05Z")
+}
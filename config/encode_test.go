@@ -0,0 +1,83 @@
+package config_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ZxxLang/zxx/config"
+)
+
+func TestEncode(t *testing.T) {
+	s := Server{
+		Name:   "it's",
+		Port:   8080,
+		Ratio:  1e-7,
+		Hosts:  []string{"a", "b\n{c}"},
+		Limits: map[string]int{"write": 5, "read": 16},
+		TLS:    &TLS{"x.pem", "x.key"},
+		Start:  time.Date(2016, 2, 4, 21, 49, 33, 0, time.UTC),
+		Day:    time.Date(2016, 2, 4, 0, 0, 0, 0, time.Local),
+		Extra:  []interface{}{int64(-1), math.Inf(1), map[string]interface{}{"my key": nil}},
+	}
+
+	enc := &config.Encoder{Comment: func(path string) string {
+		switch path {
+		case "Port":
+			return "listen port"
+		case "tls.Cert":
+			return "certificate\nin PEM format"
+		}
+		return ""
+	}}
+	src, err := enc.Encode(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `var Name = "it's"
+var Port = 8080 // listen port
+var Debug = false
+var Ratio = 0.0000001
+var Hosts = ['a', "b\n\{c}"]
+var Limits = {
+	read: 16
+	write: 5
+}
+var tls = {
+	Cert: 'x.pem' // certificate
+	// in PEM format
+	key: 'x.key'
+}
+var Start = 20160204T21:49:33Z
+var Day = 20160204T00:00:00
+var Extra = [
+	-1
+	infinite
+	{
+		'my key': null
+	}
+]
+`
+	if string(src) != want {
+		t.Fatal(string(src))
+	}
+
+	var got Server
+	if err = config.Decode(src, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Start.Equal(s.Start) || !got.Day.Equal(s.Day) {
+		t.Fatal(got.Start, got.Day)
+	}
+	got.Start, got.Day = s.Start, s.Day
+	s.Extra = []interface{}{int64(-1), math.Inf(1), map[string]interface{}{"my key": nil}}
+	if !reflect.DeepEqual(got, s) {
+		t.Fatalf("%#v", got)
+	}
+
+	if _, err = config.Encode(map[string]int{"var": 1}); err == nil {
+		t.Fatal("want error")
+	}
+}
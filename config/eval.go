@@ -0,0 +1,288 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// expr 解析并求值一个值. 以 '[' 或 '{' 开始的列表和记录由 list, record 解析,
+// 以便记录书写位置, 其它表达式由 parser.ParseExprSymbols 解析后求值.
+// 二元运算符之后, 以及括号之内可以换行.
+func (d *doc) expr() (*value, error) {
+	if sym := d.peek(0); sym.Tok == token.LEFT && sym.Source != "(" {
+		d.next()
+		if sym.Source == "{" || d.isRecord() {
+			return d.record(sym)
+		}
+		return d.list(sym)
+	}
+
+	start := d.i
+	cont := false // 上一个 Symbol 是二元运算符, 其后的换行不结束表达式
+scan:
+	for depth := 0; ; d.next() {
+		sym := d.peek(0)
+		switch sym.Tok {
+		case token.EOF:
+			break scan
+		case token.LEFT:
+			depth++
+		case token.RIGHT:
+			if depth--; depth < 0 {
+				break scan
+			}
+		case token.NL:
+			if depth == 0 && !cont {
+				break scan
+			}
+			continue
+		case token.COMMA, token.SEMICOLON:
+			if depth == 0 {
+				break scan
+			}
+		}
+		cont = sym.Tok.BinaryPrecedence() != 0
+	}
+	if start == d.i {
+		return nil, d.unexpected(d.peek(0))
+	}
+
+	x, err := parser.ParseExprSymbols(d.syms[start:d.i])
+	if err != nil {
+		if e, ok := err.(*parser.Error); ok {
+			msg := strings.TrimPrefix(e.Msg, "parser: ")
+			return nil, d.errorf(e.Pos, strings.TrimSuffix(msg, " at offset "+strconv.Itoa(int(e.Pos))))
+		}
+		return nil, &Error{Msg: err.Error()}
+	}
+	return d.eval(x)
+}
+
+// eval 求值表达式树 x
+func (d *doc) eval(x ast.Expression) (*value, error) {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		return d.literal(x.Value)
+	case *ast.Ident:
+		return d.lookup(x.Name)
+	case *ast.ParenExpr:
+		return d.eval(x.X)
+	case *ast.InterpExpr:
+		var b strings.Builder
+		for _, part := range x.Parts {
+			v, err := d.eval(part)
+			if err != nil {
+				return nil, err
+			}
+			s, err := eval.Text(v.val)
+			if err != nil || v.kind != scalar {
+				return nil, d.errorf(part.Pos(), "invalid operand for interpolation")
+			}
+			b.WriteString(s)
+		}
+		return &value{pos: x.Pos(), val: b.String()}, nil
+	case *ast.UnaryExpr:
+		v, err := d.eval(x.X)
+		if err != nil {
+			return nil, err
+		}
+		if x.Op.Tok == token.NOT {
+			return &value{pos: x.Op.Pos, val: !truth(v)}, nil
+		}
+		r, err := eval.Unary(x.Op.Tok, v.val)
+		if err != nil {
+			return nil, d.errorf(x.Op.Pos, err.Error())
+		}
+		return &value{pos: x.Op.Pos, val: r}, nil
+	case *ast.BinaryExpr:
+		a, err := d.eval(x.X)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.eval(x.Y)
+		if err != nil {
+			return nil, err
+		}
+		return d.binary(x.Op, a, b)
+	case *ast.ListExpr:
+		v := &value{pos: x.Lbrack, kind: list, right: x.Rbrack}
+		for _, elem := range x.Elems {
+			item, err := d.eval(elem)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, item)
+			v.items = append(v.items, span{elem.Pos(), elem.Pos(), elem.End()})
+		}
+		return v, nil
+	case *ast.MapExpr:
+		v := newRecord(x.Lbrack)
+		v.right, v.colon = x.Rbrack, true
+		for _, kv := range x.Elems {
+			k, err := d.eval(kv.Key)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.val.(string)
+			if !ok || k.kind != scalar {
+				return nil, d.errorf(kv.Key.Pos(), "record key must be string")
+			}
+			if _, ok := v.rec[key]; ok {
+				return nil, d.errorf(kv.Key.Pos(), "duplicate key", key)
+			}
+			item, err := d.eval(kv.Value)
+			if err != nil {
+				return nil, err
+			}
+			v.set(key, item, span{kv.Key.Pos(), kv.Value.Pos(), kv.Value.End()})
+		}
+		return v, nil
+	}
+	return nil, d.errorf(x.Pos(), "unsupported expression")
+}
+
+// isRecord 返回 '[' 之后是否为记录写法
+func (d *doc) isRecord() bool {
+	i := 0
+	for d.peek(i).Tok == token.NL {
+		i++
+	}
+	if !isKey(d.peek(i)) {
+		return false
+	}
+	next := d.peek(i + 1).Tok
+	return next == token.ASSIGN || next == token.COLON
+}
+
+// isKey 返回 sym 是否可以作为记录的键
+func isKey(sym parser.Symbol) bool {
+	switch sym.Tok {
+	case token.IDENT, token.VALSTRING:
+		return true
+	}
+	// 保留字也可以作为键
+	for _, c := range sym.Source {
+		if c != '_' && !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return sym.Source != "" && !(sym.Source[0] >= '0' && sym.Source[0] <= '9')
+}
+
+func (d *doc) list(left parser.Symbol) (*value, error) {
+	x := &value{pos: left.Pos, kind: list}
+	for d.skipSep(); !d.isRight(); d.skipSep() {
+		if d.tok() == token.EOF {
+			return nil, d.unexpected(d.peek(0))
+		}
+		start := d.peek(0).Pos
+		item, err := d.expr()
+		if err != nil {
+			return nil, err
+		}
+		x.list = append(x.list, item)
+		x.items = append(x.items, span{start, start, d.end()})
+	}
+	x.right = d.next().Pos
+	return x, nil
+}
+
+func (d *doc) record(left parser.Symbol) (*value, error) {
+	x := newRecord(left.Pos)
+	for d.skipSep(); !d.isRight(); d.skipSep() {
+		sym := d.next()
+		if !isKey(sym) {
+			return nil, d.unexpected(sym)
+		}
+		key := sym.Source
+		if sym.Tok == token.VALSTRING {
+			k, err := d.literal(sym)
+			if err != nil {
+				return nil, err
+			}
+			key = k.val.(string)
+		}
+		if _, ok := x.rec[key]; ok {
+			return nil, d.errorf(sym.Pos, "duplicate key", key)
+		}
+
+		eq := d.next()
+		if eq.Tok != token.ASSIGN && eq.Tok != token.COLON {
+			return nil, d.unexpected(eq)
+		}
+		x.colon = eq.Tok == token.COLON
+		d.skipNL()
+
+		start := d.peek(0).Pos
+		item, err := d.expr()
+		if err != nil {
+			return nil, err
+		}
+		x.set(key, item, span{sym.Pos, start, d.end()})
+	}
+	x.right = d.next().Pos
+	return x, nil
+}
+
+// lookup 返回前文声明的名字或其成员的值
+func (d *doc) lookup(sym parser.Symbol) (*value, error) {
+	x := d.root
+	for _, name := range strings.Split(sym.Source, ".") {
+		if x.kind != record || x.rec[name] == nil {
+			return nil, d.errorf(sym.Pos, "undefined", sym.Source)
+		}
+		x = x.rec[name]
+	}
+	return x, nil
+}
+
+// truth 返回 x 的真值, 列表和记录总是真
+func truth(x *value) bool {
+	return x.kind != scalar || eval.Truth(x.val)
+}
+
+func (d *doc) binary(op parser.Symbol, x, y *value) (*value, error) {
+	switch op.Tok {
+	case token.AND:
+		if !truth(x) {
+			return x, nil
+		}
+		return y, nil
+	case token.OR:
+		if truth(x) {
+			return x, nil
+		}
+		return y, nil
+	}
+
+	if x.kind != scalar || y.kind != scalar {
+		return nil, d.errorf(op.Pos, "invalid operation", op.Source)
+	}
+	v, err := eval.Binary(op.Tok, x.val, y.val)
+	if err != nil {
+		return nil, d.errorf(op.Pos, err.Error())
+	}
+	return &value{pos: x.pos, val: v}, nil
+}
+
+func float(x *value) (float64, bool) {
+	return eval.Float(x.val)
+}
+
+// literal 返回字面值 sym 的值
+func (d *doc) literal(sym parser.Symbol) (*value, error) {
+	v, err := eval.Literal(sym.Tok, sym.Source)
+	if err != nil {
+		return nil, d.errorf(sym.Pos, err.Error())
+	}
+	return &value{pos: sym.Pos, val: v, src: sym.Source}, nil
+}
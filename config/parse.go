@@ -0,0 +1,251 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包把 zxx 当做配置语言使用, 求值受限的声明式子集并解码到 Go 值.
+//
+// 配置文档由 var, const 声明组成, 类型可省略, 分组写法也是合法的:
+//
+//	var string name = 'zxx'
+//	const base = 8000
+//	var (
+//		port = base + 80
+//		debug = true
+//	)
+//	var server = {
+//		host: 'localhost'
+//		ports = [80, 443]
+//	}
+//
+// 值可以是字面值, 列表 [a, b], 记录 {k = v} 或 {k: v} 或 [k = v],
+// 对前文名字的引用以及简单的运算表达式. 求值没有副作用.
+// 首个声明之前的文本是占位, 可以用来书写说明.
+package config
+
+import (
+	"strings"
+
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Error 表示带位置的配置错误.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Pos.String("") + ": " + e.Msg
+}
+
+// value 是求值结果, 保留位置以便解码时报告错误.
+//
+// 标量 val 的类型为 int64, float64, string, bool, time.Time 或 nil.
+// 列表使用 list, 记录使用 keys 保持书写顺序.
+type value struct {
+	pos  scanner.Pos
+	kind kind
+	val  interface{}
+	src  string // 标量的字面值源码
+	list []*value
+	keys []string
+	rec  map[string]*value
+
+	// 以下记录书写位置, 用于 Patch
+	spans map[string]span // 记录成员
+	items []span          // 列表元素
+	right scanner.Pos     // 右括号, 顶层记录为 -1
+	colon bool            // 记录最后一个成员是否使用 ':' 写法
+}
+
+// span 是成员在源码中的位置, 值占据 [start, end)
+type span struct {
+	key, start, end scanner.Pos
+}
+
+type kind int
+
+const (
+	scalar kind = iota
+	list
+	record
+)
+
+func newRecord(pos scanner.Pos) *value {
+	return &value{pos: pos, kind: record, rec: map[string]*value{}, spans: map[string]span{}}
+}
+
+func (v *value) set(key string, x *value, at span) {
+	if _, ok := v.rec[key]; !ok {
+		v.keys = append(v.keys, key)
+	}
+	v.rec[key] = x
+	v.spans[key] = at
+}
+
+// doc 是文档解析状态
+type doc struct {
+	file *scanner.File
+	syms []parser.Symbol
+	i    int
+	root *value
+	last parser.Symbol // 上一个 next 返回的 Symbol
+}
+
+// parse 解析 src, 顶层声明保存在 root 记录中
+func parse(src []byte) (*doc, error) {
+	d := &doc{file: scanner.NewFileSet().AddFile("", src)}
+
+	syms, err := parser.Fast(src, nil)
+	if err != nil {
+		return nil, &Error{Msg: err.Error()}
+	}
+	// 剔除占位, 注释和缩进, 换行是分隔符
+	for _, sym := range syms {
+		switch sym.Tok {
+		case token.PLACEHOLDER, token.COMMENT, token.COMMENTS, token.INDENTATION:
+		default:
+			d.syms = append(d.syms, sym)
+		}
+	}
+
+	d.root = newRecord(0)
+	d.root.right = -1
+	for d.skipSep(); d.tok() != token.EOF; d.skipSep() {
+		if err = d.decl(); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (d *doc) peek(n int) parser.Symbol {
+	if d.i+n < len(d.syms) {
+		return d.syms[d.i+n]
+	}
+	return parser.Symbol{Pos: scanner.Pos(d.file.Size()), Tok: token.EOF}
+}
+
+func (d *doc) tok() token.Token { return d.peek(0).Tok }
+
+func (d *doc) next() parser.Symbol {
+	sym := d.peek(0)
+	if d.i < len(d.syms) {
+		d.i++
+		d.last = sym
+	}
+	return sym
+}
+
+// end 返回上一个 Symbol 的结束位置
+func (d *doc) end() scanner.Pos {
+	return d.last.Pos + scanner.Pos(len(d.last.Source))
+}
+
+func (d *doc) errorf(pos scanner.Pos, msg ...string) error {
+	return &Error{d.file.Position(pos), "config: " + strings.Join(msg, " ")}
+}
+
+func (d *doc) unexpected(sym parser.Symbol) error {
+	if sym.Tok == token.EOF {
+		return d.errorf(sym.Pos, "unexpected EOF")
+	}
+	return d.errorf(sym.Pos, "unexpected", sym.Tok.String(), "'"+sym.Source+"'")
+}
+
+// skipNL 跳过换行
+func (d *doc) skipNL() {
+	for d.tok() == token.NL {
+		d.i++
+	}
+}
+
+// skipSep 跳过换行和分隔符
+func (d *doc) skipSep() {
+	for d.tok() == token.NL || d.tok() == token.COMMA || d.tok() == token.SEMICOLON {
+		d.i++
+	}
+}
+
+func (d *doc) isRight() bool {
+	return d.tok() == token.RIGHT
+}
+
+// decl 解析 var, const 声明
+func (d *doc) decl() error {
+	sym := d.next()
+	if sym.Tok != token.VAR && sym.Tok != token.CONST {
+		return d.unexpected(sym)
+	}
+
+	if d.tok() != token.LEFT {
+		return d.entry(d.root)
+	}
+
+	d.next()
+	for d.skipSep(); !d.isRight(); d.skipSep() {
+		if d.tok() == token.EOF {
+			return d.unexpected(d.peek(0))
+		}
+		if err := d.entry(d.root); err != nil {
+			return err
+		}
+	}
+	d.next()
+	return nil
+}
+
+// entry 解析 [type] name = value
+func (d *doc) entry(rec *value) error {
+	d.skipType()
+
+	sym := d.next()
+	if sym.Tok != token.IDENT {
+		return d.unexpected(sym)
+	}
+	if _, ok := rec.rec[sym.Source]; ok {
+		return d.errorf(sym.Pos, "duplicate name", sym.Source)
+	}
+
+	if eq := d.next(); eq.Tok != token.ASSIGN {
+		return d.unexpected(eq)
+	}
+
+	d.skipNL()
+	start := d.peek(0).Pos
+	x, err := d.expr()
+	if err == nil {
+		rec.set(sym.Source, x, span{sym.Pos, start, d.end()})
+	}
+	return err
+}
+
+// skipType 跳过可选的类型, 例如 int, array[int], map[string,int], T
+func (d *doc) skipType() {
+	if d.tok() == token.IDENT || d.tok() == token.MEMBER {
+		if next := d.peek(1).Tok; next == token.IDENT {
+			d.next()
+		}
+		return
+	}
+	if !d.tok().As(token.Type) {
+		return
+	}
+	d.next()
+	if d.tok() != token.LEFT {
+		return
+	}
+	for depth := 0; d.tok() != token.EOF; {
+		switch d.next().Tok {
+		case token.LEFT:
+			depth++
+		case token.RIGHT:
+			depth--
+		}
+		if depth == 0 {
+			return
+		}
+	}
+}
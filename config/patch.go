@@ -0,0 +1,200 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/edit"
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// Patch 把 updates 中的值写入配置文档 src, 返回修改后的文档.
+//
+// updates 的键是配置项路径, 例如 port, server.host, hosts[1], 值的编码同 Encode.
+// 只有被更新的值会被替换, 其它部分的注释, 顺序和排版保持不变.
+// 不存在的记录成员被追加到记录末尾并沿用最后一个成员的 '=' 或 ':' 写法,
+// 不存在的列表元素是错误.
+func Patch(src []byte, updates map[string]interface{}) ([]byte, error) {
+	d, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(updates))
+	for path := range updates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	buf := edit.NewBuffer(src)
+	for _, path := range paths {
+		if err = d.patch(buf, src, path, updates[path]); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes()
+}
+
+func (d *doc) patch(buf *edit.Buffer, src []byte, path string, v interface{}) error {
+	keys, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	x := d.root
+	for i, key := range keys {
+		var at span
+		var ok bool
+		switch k := key.(type) {
+		case string:
+			if x.kind != record {
+				return errors.New("config: " + path + ": not a record")
+			}
+			at, ok = x.spans[k]
+			if !ok && i == len(keys)-1 {
+				return d.insert(buf, src, x, k, path, v)
+			}
+		case int:
+			if x.kind != list {
+				return errors.New("config: " + path + ": not a list")
+			}
+			if ok = k < len(x.items); ok {
+				at = x.items[k]
+			}
+		}
+		if !ok {
+			return errors.New("config: " + path + ": undefined")
+		}
+
+		if i == len(keys)-1 {
+			s, err := encodeValue(v, path, depth(src, at.key))
+			if err != nil {
+				return err
+			}
+			buf.Replace(int(at.start), int(at.end), s)
+			return nil
+		}
+
+		if k, ok := key.(string); ok {
+			x = x.rec[k]
+		} else {
+			x = x.list[key.(int)]
+		}
+		// 引用前文名字的值不在此处书写
+		if x.kind != scalar && (x.pos < at.start || x.pos >= at.end) {
+			return errors.New("config: " + path + ": cannot patch through reference")
+		}
+	}
+	return nil
+}
+
+// insert 在记录 x 的末尾追加成员 key
+func (d *doc) insert(buf *edit.Buffer, src []byte, x *value, key, path string, v interface{}) error {
+	if x.right == -1 {
+		if !isIdent(key) {
+			return errors.New("config: invalid name " + strconv.Quote(key))
+		}
+		s, err := encodeValue(v, path, 0)
+		if err != nil {
+			return err
+		}
+		if len(src) != 0 && src[len(src)-1] != '\n' {
+			s = "\n" + "var " + key + " = " + s + "\n"
+		} else {
+			s = "var " + key + " = " + s + "\n"
+		}
+		buf.Insert(len(src), s)
+		return nil
+	}
+
+	name := key
+	if !isIdent(key) {
+		name = lexutil.Quote(key)
+	}
+	sep := " = "
+	if x.colon {
+		sep = ": "
+	}
+
+	right := int(x.right)
+	if bytes.IndexByte(src[x.pos:right], '\n') == -1 {
+		s, err := encodeValue(v, path, depth(src, x.pos))
+		if err != nil {
+			return err
+		}
+		if len(x.keys) != 0 {
+			name = ", " + name
+		}
+		buf.Insert(right, name+sep+s)
+		return nil
+	}
+
+	// 多行记录, 在右括号所在行之前插入一行
+	line := bytes.LastIndexByte(src[:right], '\n') + 1
+	n := depth(src, x.right) + 1
+	s, err := encodeValue(v, path, n)
+	if err != nil {
+		return err
+	}
+	buf.Insert(line, strings.Repeat("\t", n)+name+sep+s+"\n")
+	return nil
+}
+
+// depth 返回 pos 所在行的前导 TAB 数量
+func depth(src []byte, pos scanner.Pos) int {
+	line := bytes.LastIndexByte(src[:pos], '\n') + 1
+	n := 0
+	for line+n < len(src) && src[line+n] == '\t' {
+		n++
+	}
+	return n
+}
+
+// encodeValue 以缩进深度 depth 编码 v, 不包括最后的换行
+func encodeValue(v interface{}, path string, depth int) (string, error) {
+	e := &encoder{Encoder: new(Encoder)}
+	if err := e.value(reflect.ValueOf(&v).Elem(), path, depth); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(e.String(), "\n"), nil
+}
+
+// splitPath 把 server.hosts[1] 分解为 "server", "hosts", 1
+func splitPath(path string) (keys []interface{}, err error) {
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		if i := strings.IndexByte(part, '['); i != -1 {
+			name, part = part[:i], part[i:]
+		} else {
+			part = ""
+		}
+		if name == "" && (len(keys) == 0 || part == "") {
+			return nil, errors.New("config: invalid path " + strconv.Quote(path))
+		}
+		if name != "" {
+			keys = append(keys, name)
+		}
+		for part != "" {
+			end := strings.IndexByte(part, ']')
+			if part[0] != '[' || end == -1 {
+				return nil, errors.New("config: invalid path " + strconv.Quote(path))
+			}
+			i, err := strconv.Atoi(part[1:end])
+			if err != nil || i < 0 {
+				return nil, errors.New("config: invalid path " + strconv.Quote(path))
+			}
+			keys = append(keys, i)
+			part = part[end+1:]
+		}
+	}
+	return
+}
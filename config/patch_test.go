@@ -0,0 +1,60 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/config"
+)
+
+const patchSrc = `服务配置
+
+const base = 8000
+var port = base + 80 // 监听端口
+var hosts = ['a', 'b'] // 主机
+var server = {
+	// 服务
+	host: 'localhost' // 主机名
+	tls = [cert = 'x.pem']
+}
+`
+
+func TestPatch(t *testing.T) {
+	out, err := config.Patch([]byte(patchSrc), map[string]interface{}{
+		"port":           9090,
+		"hosts[1]":       "c",
+		"server.host":    "example.com",
+		"server.tls.key": "x.key",
+		"server.timeout": 30,
+		"server.limits":  map[string]int{"read": 1},
+		"debug":          true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `服务配置
+
+const base = 8000
+var port = 9090 // 监听端口
+var hosts = ['a', 'c'] // 主机
+var server = {
+	// 服务
+	host: 'example.com' // 主机名
+	tls = [cert = 'x.pem', key = 'x.key']
+	limits = {
+		read: 1
+	}
+	timeout = 30
+}
+var debug = true
+`
+	if string(out) != want {
+		t.Fatal(string(out))
+	}
+
+	for _, path := range []string{"hosts[2]", "base.x", "server..host", "[0]"} {
+		if _, err = config.Patch([]byte(patchSrc), map[string]interface{}{path: 1}); err == nil {
+			t.Fatal(path)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// ProjectFile 是项目配置文件的文件名, 内容是 TOML 文档, 参见 DecodeTOML:
+//
+//	root = true
+//	path = ["lib", "../shared"]
+//	target = "go"
+//	version = "1"
+//
+//	[format]
+//	indent = "spaces:4"
+//	quote = "single"
+//
+//	[lint]
+//	disable = ["shadow"]
+//	severity = {unused-func = "error"}
+const ProjectFile = "zxx.toml"
+
+// Project 是工具共用的项目配置, 字段为空表示使用工具的默认值.
+type Project struct {
+	// Root 是最外层配置文件所在的目录, 即项目根目录.
+	Root string `zxx:"-"`
+
+	// Stop 为 true 的配置文件是项目根, 不再向上查找.
+	Stop bool `zxx:"root"`
+
+	Format Format
+	Lint   Lint
+
+	// Path 是导入搜索路径, 相对路径相对于声明它的配置文件所在的目录, 加载后是绝对路径.
+	Path []string
+
+	// Target 是编译的目标后端.
+	Target string
+
+	// Version 是项目默认的语言版本, 文件头部的版本指示优先, 参见 parser.CheckVersion.
+	Version string
+}
+
+// Format 是 zxxfmt 的风格, 取值和同名的命令行参数相同.
+type Format struct {
+	Indent   string // tabs[:n] 或 spaces[:n]
+	Quote    string // single 或 double
+	EOL      string // lf 或 crlf
+	Literals bool
+}
+
+// Lint 启用或禁用检查, Disable 优先. Severity 按诊断的 ID 设置严重程度, 参见 diag 包.
+type Lint struct {
+	Enable   []string
+	Disable  []string
+	Severity map[string]string
+}
+
+// Enabled 返回检查 name 是否启用, def 是检查的默认状态.
+func (l *Lint) Enabled(name string, def bool) bool {
+	for _, s := range l.Disable {
+		if s == name {
+			return false
+		}
+	}
+	for _, s := range l.Enable {
+		if s == name {
+			return true
+		}
+	}
+	return def
+}
+
+// LoadProject 从目录 dir 向上查找 ProjectFile, 直到遇到 root = true 的配置文件或者文件系统的根.
+// 找到的配置文件从外到内依次解码, 内层目录的配置覆盖外层的同名字段, 实现按目录的定制.
+// 没有找到配置文件时返回零值的 Project, Root 为空.
+func LoadProject(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for {
+		name := filepath.Join(dir, ProjectFile)
+		if _, err := os.Stat(name); err == nil {
+			names = append(names, name)
+			var top struct {
+				Stop bool `zxx:"root"`
+			}
+			if err = decodeFile(name, &top); err != nil {
+				return nil, err
+			}
+			if top.Stop {
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	p := new(Project)
+	for i := len(names) - 1; i >= 0; i-- {
+		dir := filepath.Dir(names[i])
+		if p.Root == "" {
+			p.Root = dir
+		}
+		old := p.Path
+		p.Path = nil
+		if err := decodeFile(names[i], p); err != nil {
+			return nil, err
+		}
+		if p.Path == nil {
+			p.Path = old
+		}
+		for j, path := range p.Path {
+			if !filepath.IsAbs(path) {
+				p.Path[j] = filepath.Join(dir, path)
+			}
+		}
+	}
+	return p, nil
+}
+
+// decodeFile 解码配置文件 name 到 v, 错误信息包括文件名.
+func decodeFile(name string, v interface{}) error {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	if err = DecodeTOML(src, v); err != nil {
+		if e, ok := err.(*Error); ok {
+			return errors.New(e.Pos.String(name) + ": " + e.Msg)
+		}
+	}
+	return err
+}
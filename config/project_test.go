@@ -0,0 +1,60 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ZxxLang/zxx/config"
+)
+
+func TestLoadProject(t *testing.T) {
+	top := t.TempDir()
+	root := filepath.Join(top, "app")
+	sub := filepath.Join(root, "lib", "util")
+	if err := os.MkdirAll(sub, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for dir, src := range map[string]string{
+		top:  "target = 'c'\n",
+		root: "root = true\npath = ['vendor']\ntarget = 'go'\n\n[format]\nindent = 'tabs'\nquote = 'single'\n\n[lint]\ndisable = ['shadow']\n",
+		sub:  "format.quote = 'double'\r\nlint = {enable = ['shadow', 'unused']}\r\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, config.ProjectFile), []byte(src), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p, err := config.LoadProject(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &config.Project{
+		Root:   root,
+		Stop:   true,
+		Format: config.Format{Indent: "tabs", Quote: "double"},
+		Lint:   config.Lint{Enable: []string{"shadow", "unused"}, Disable: []string{"shadow"}},
+		Path:   []string{filepath.Join(root, "vendor")},
+		Target: "go",
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Fatalf("%+v", p)
+	}
+	if p.Lint.Enabled("shadow", true) || !p.Lint.Enabled("unused", false) || p.Lint.Enabled("other", false) {
+		t.Fatal("Lint.Enabled")
+	}
+
+	if p, err = config.LoadProject(top); err != nil || p.Root != top || p.Target != "c" {
+		t.Fatal(p, err)
+	}
+
+	if err = ioutil.WriteFile(filepath.Join(sub, config.ProjectFile), []byte("format = 1\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	msg := filepath.Join(sub, config.ProjectFile) + ":1:10: config: cannot decode int64 into config.Format"
+	if _, err = config.LoadProject(sub); err == nil || err.Error() != msg {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema 描述配置文档的结构, 用于 Validate 检查.
+//
+// Schema 本身也是配置文档, 每个顶层声明描述同名的配置项:
+//
+//	var port = [type = 'int', required = true, min = 1, max = 65535]
+//	var mode = [type = 'string', enum = ['dev', 'prod']]
+//	var hosts = [type = 'list', items = [type = 'string']]
+//	var server = [type = 'record', fields = [
+//		host = [type = 'string', required = true]
+//	]]
+//
+// 可用的属性有:
+//
+//	type      any, int, float, string, bool, datetime, list, record, 缺省为 any
+//	required  是否必须出现
+//	min, max  数值的范围, 字符串和列表的长度范围
+//	enum      可选值列表
+//	items     列表元素的描述
+//	fields    记录成员的描述
+//	open      为 true 时记录允许未描述的成员, 顶层总是封闭的
+type Schema struct {
+	root *field
+}
+
+type field struct {
+	typ      string
+	required bool
+	open     bool
+	min, max *float64
+	enum     []*value
+	items    *field
+	fields   map[string]*field
+	keys     []string
+}
+
+var types = map[string]bool{
+	"any": true, "int": true, "float": true, "string": true,
+	"bool": true, "datetime": true, "list": true, "record": true,
+}
+
+// ParseSchema 解析 Schema 文档
+func ParseSchema(src []byte) (*Schema, error) {
+	d, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	root, err := d.field(d.root, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root}, nil
+}
+
+// field 把描述记录 x 转换为 field. 顶层 root 的成员直接是 fields.
+func (d *doc) field(x *value, root bool) (*field, error) {
+	if x.kind != record {
+		return nil, d.errorf(x.pos, "schema must be a record")
+	}
+	f := &field{typ: "any"}
+
+	fields := x
+	if root {
+		f.typ = "record"
+	} else {
+		fields = nil
+		for _, key := range x.keys {
+			item := x.rec[key]
+			var ok bool
+			switch key {
+			case "type":
+				f.typ, ok = item.val.(string)
+				ok = ok && types[f.typ]
+			case "required":
+				f.required, ok = item.val.(bool)
+			case "open":
+				f.open, ok = item.val.(bool)
+			case "min", "max":
+				var n float64
+				if n, ok = float(item); ok {
+					if key == "min" {
+						f.min = &n
+					} else {
+						f.max = &n
+					}
+				}
+			case "enum":
+				f.enum, ok = item.list, item.kind == list
+			case "items":
+				f.items, ok = nil, item.kind == record
+				if ok {
+					var err error
+					if f.items, err = d.field(item, false); err != nil {
+						return nil, err
+					}
+				}
+			case "fields":
+				fields, ok = item, item.kind == record
+			default:
+				return nil, d.errorf(item.pos, "unknown schema attribute", key)
+			}
+			if !ok {
+				return nil, d.errorf(item.pos, "invalid schema attribute", key)
+			}
+		}
+		if fields != nil && f.typ == "any" {
+			f.typ = "record"
+		}
+		if f.items != nil && f.typ == "any" {
+			f.typ = "list"
+		}
+	}
+
+	if fields != nil {
+		f.fields = map[string]*field{}
+		for _, key := range fields.keys {
+			sub, err := d.field(fields.rec[key], false)
+			if err != nil {
+				return nil, err
+			}
+			f.keys = append(f.keys, key)
+			f.fields[key] = sub
+		}
+	}
+	return f, nil
+}
+
+// Validate 求值配置文档 src 并按 s 检查, 返回全部错误.
+// 错误总是 *Error 类型, 按位置排序.
+func (s *Schema) Validate(src []byte) []error {
+	d, err := parse(src)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []*Error
+	d.check(s.root, d.root, "", &errs)
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Pos.Offset < errs[j].Pos.Offset
+	})
+
+	list := make([]error, len(errs))
+	for i, e := range errs {
+		list[i] = e
+	}
+	return list
+}
+
+func (d *doc) check(f *field, x *value, path string, errs *[]*Error) {
+	report := func(msg ...string) {
+		prefix := path
+		if prefix == "" {
+			prefix = "document"
+		}
+		*errs = append(*errs, d.errorf(x.pos, append([]string{prefix + ":"}, msg...)...).(*Error))
+	}
+
+	if !f.is(x) {
+		report("want", f.typ, "got", describe(x))
+		return
+	}
+
+	if f.enum != nil {
+		match := false
+		for _, e := range f.enum {
+			if equal(e, x) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			srcs := make([]string, len(f.enum))
+			for i, e := range f.enum {
+				srcs[i] = e.String()
+			}
+			report("value", x.String(), "not in", "["+strings.Join(srcs, ", ")+"]")
+		}
+	}
+
+	if f.min != nil || f.max != nil {
+		n, what := 0.0, "value"
+		switch v := x.val.(type) {
+		case string:
+			n, what = float64(len([]rune(v))), "length"
+		default:
+			if x.kind == list {
+				n, what = float64(len(x.list)), "length"
+			} else {
+				n, _ = float(x)
+			}
+		}
+		if f.min != nil && n < *f.min || f.max != nil && n > *f.max {
+			report(what, format(n), "out of range", "["+bound(f.min)+", "+bound(f.max)+"]")
+		}
+	}
+
+	if x.kind == list && f.items != nil {
+		for i, item := range x.list {
+			d.check(f.items, item, path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	}
+
+	if x.kind != record || f.fields == nil {
+		return
+	}
+
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+	for _, key := range f.keys {
+		sub := f.fields[key]
+		if item := x.rec[key]; item != nil {
+			d.check(sub, item, prefix+key, errs)
+		} else if sub.required {
+			report("missing required", prefix+key)
+		}
+	}
+	if f.open {
+		return
+	}
+	for _, key := range x.keys {
+		if f.fields[key] == nil {
+			*errs = append(*errs, d.errorf(x.rec[key].pos, "unknown", prefix+key).(*Error))
+		}
+	}
+}
+
+// is 返回 x 是否满足 f 的类型
+func (f *field) is(x *value) bool {
+	switch f.typ {
+	case "any":
+		return true
+	case "list":
+		return x.kind == list
+	case "record":
+		return x.kind == record
+	}
+	if x.kind != scalar {
+		return false
+	}
+	switch x.val.(type) {
+	case int64:
+		return f.typ == "int" || f.typ == "float" || f.typ == "datetime" && len(x.src) == 8
+	case float64:
+		return f.typ == "float"
+	case string:
+		return f.typ == "string"
+	case bool:
+		return f.typ == "bool"
+	case time.Time:
+		return f.typ == "datetime"
+	}
+	return false
+}
+
+func describe(x *value) string {
+	switch {
+	case x.kind == list:
+		return "list"
+	case x.kind == record:
+		return "record"
+	}
+	switch x.val.(type) {
+	case int64:
+		return "int"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case time.Time:
+		return "datetime"
+	}
+	return "null"
+}
+
+// String 返回标量的源码, 计算得到的值使用 Go 格式
+func (x *value) String() string {
+	if x.src != "" || x.kind != scalar {
+		return x.src
+	}
+	switch v := x.val.(type) {
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return format(v)
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(x.val)
+}
+
+func equal(a, b *value) bool {
+	if a.kind != scalar || b.kind != scalar {
+		return false
+	}
+	if f, ok := float(a); ok {
+		g, ok := float(b)
+		return ok && f == g
+	}
+	if t, ok := a.val.(time.Time); ok {
+		u, ok := b.val.(time.Time)
+		return ok && t.Equal(u)
+	}
+	return a.val == b.val
+}
+
+func format(n float64) string {
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
+
+func bound(n *float64) string {
+	if n == nil {
+		return ""
+	}
+	return format(*n)
+}
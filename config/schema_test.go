@@ -0,0 +1,66 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/config"
+)
+
+const schema = `
+var port = [type = 'int', required = true, min = 1, max = 65535]
+var mode = [type = 'string', enum = ['dev', 'prod']]
+var hosts = [type = 'list', min = 1, items = [type = 'string']]
+var server = [fields = [
+	host = [type = 'string', required = true]
+	extra = [open = true, fields = {}]
+]]
+`
+
+func TestValidate(t *testing.T) {
+	s, err := config.ParseSchema([]byte(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := s.Validate([]byte(`
+var port = 80
+var mode = 'prod'
+var hosts = ['a']
+var server = [host = 'h', extra = [any = 1]]
+`))
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	errs = s.Validate([]byte(`var port = 70000
+var mode = 'test'
+var hosts = ['a', 1]
+var server = [name = 'x']
+var other = 1
+`))
+	want := []string{
+		"1:12: config: port: value 70000 out of range [1, 65535]",
+		"2:12: config: mode: value 'test' not in ['dev', 'prod']",
+		"3:19: config: hosts[1]: want string got int",
+		"4:14: config: server: missing required server.host",
+		"4:22: config: unknown server.name",
+		"5:13: config: unknown other",
+	}
+	if len(errs) != len(want) {
+		t.Fatal(errs)
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Fatal(i, err)
+		}
+	}
+
+	errs = s.Validate([]byte("var mode = 'dev'"))
+	if len(errs) != 1 || errs[0].Error() != "1:1: config: document: missing required port" {
+		t.Fatal(errs)
+	}
+
+	if _, err = config.ParseSchema([]byte("var port = [type = 'integer']")); err == nil {
+		t.Fatal("want error")
+	}
+}
@@ -0,0 +1,633 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// DecodeTOML 解析 TOML v1.0 文档 src 并把顶层表解码到 v, 解码规则和错误同 Decode.
+// 项目配置文件 ProjectFile 使用这种格式.
+//
+// 表可以解码到 struct 或 map, 数组和表数组可以解码到 slice. 整数为 int64,
+// 浮点数为 float64, 日期时间和日期为 time.Time, 没有时区偏移的使用 time.Local.
+// 不支持单独的本地时间. 多行字符串中的 CRLF 被统一为 LF.
+func DecodeTOML(src []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("config: DecodeTOML requires a non-nil pointer")
+	}
+	d, err := parseTOML(src)
+	if err != nil {
+		return err
+	}
+	return d.assign(d.root, rv.Elem())
+}
+
+// tomlParser 是 TOML 文档的解析状态, 结果保存在 doc 的 root 中.
+// 只用于解码, 不记录 Patch 需要的书写位置.
+type tomlParser struct {
+	d   *doc
+	src []byte
+	i   int
+
+	defined map[*value]bool // 表头定义过的表
+	inline  map[*value]bool // 内联表, 之后不能再添加成员
+	arrays  map[*value]bool // [[name]] 定义的表数组
+}
+
+// parseTOML 解析 src, 顶层表保存在 root 记录中
+func parseTOML(src []byte) (*doc, error) {
+	t := &tomlParser{
+		d:       &doc{file: scanner.NewFileSet().AddFile("", src), root: newRecord(0)},
+		src:     src,
+		defined: map[*value]bool{},
+		inline:  map[*value]bool{},
+		arrays:  map[*value]bool{},
+	}
+	for i := 0; i < len(src); {
+		r, n := utf8.DecodeRune(src[i:])
+		if r == utf8.RuneError && n == 1 {
+			return nil, t.d.errorf(scanner.Pos(i), "invalid UTF-8 encoding")
+		}
+		i += n
+	}
+	// Windows 的编辑器常在文件开头写入 BOM
+	if bytes.HasPrefix(src, []byte("\xef\xbb\xbf")) {
+		t.i = 3
+	}
+
+	tbl := t.d.root
+	for t.space(); t.i < len(src); t.space() {
+		var err error
+		if src[t.i] == '[' {
+			tbl, err = t.header()
+		} else {
+			err = t.keyval(tbl)
+		}
+		if err == nil {
+			err = t.eol()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t.d, nil
+}
+
+func (t *tomlParser) pos() scanner.Pos { return scanner.Pos(t.i) }
+
+func (t *tomlParser) unexpected() error {
+	if t.i >= len(t.src) {
+		return t.d.errorf(t.pos(), "unexpected EOF")
+	}
+	r, _ := utf8.DecodeRune(t.src[t.i:])
+	return t.d.errorf(t.pos(), "unexpected", strconv.QuoteRune(r))
+}
+
+// skip 跳过字节 c, 返回是否跳过
+func (t *tomlParser) skip(c byte) bool {
+	if t.i < len(t.src) && t.src[t.i] == c {
+		t.i++
+		return true
+	}
+	return false
+}
+
+// ws 跳过空格和 TAB
+func (t *tomlParser) ws() {
+	for t.i < len(t.src) && (t.src[t.i] == ' ' || t.src[t.i] == '\t') {
+		t.i++
+	}
+}
+
+// newline 跳过一个 LF 或 CRLF, 返回是否跳过
+func (t *tomlParser) newline() bool {
+	if t.skip('\n') {
+		return true
+	}
+	if t.i+1 < len(t.src) && t.src[t.i] == '\r' && t.src[t.i+1] == '\n' {
+		t.i += 2
+		return true
+	}
+	return false
+}
+
+// comment 跳过注释, 不包括行尾的换行
+func (t *tomlParser) comment() {
+	if !t.skip('#') {
+		return
+	}
+	for t.i < len(t.src) && t.src[t.i] != '\n' && t.src[t.i] != '\r' {
+		t.i++
+	}
+}
+
+// space 跳过空白, 注释和换行
+func (t *tomlParser) space() {
+	for {
+		t.ws()
+		t.comment()
+		if !t.newline() {
+			return
+		}
+	}
+}
+
+// eol 跳过行尾的空白和注释, 之后必须是换行或者 EOF
+func (t *tomlParser) eol() error {
+	t.ws()
+	t.comment()
+	if t.i < len(t.src) && !t.newline() {
+		return t.unexpected()
+	}
+	return nil
+}
+
+// header 解析表头 [name] 或 [[name]], 返回之后的键值对所属的表
+func (t *tomlParser) header() (*value, error) {
+	pos := t.pos()
+	t.i++
+	array := t.skip('[')
+	keys, err := t.key()
+	if err != nil {
+		return nil, err
+	}
+	if !t.skip(']') || array && !t.skip(']') {
+		return nil, t.unexpected()
+	}
+
+	tbl := t.d.root
+	last := len(keys) - 1
+	for _, key := range keys[:last] {
+		if tbl, err = t.table(tbl, key, pos); err != nil {
+			return nil, err
+		}
+	}
+	x := tbl.rec[keys[last]]
+	if array {
+		if x == nil {
+			x = &value{pos: pos, kind: list}
+			t.arrays[x] = true
+			tbl.set(keys[last], x, span{})
+		}
+		if t.arrays[x] {
+			item := newRecord(pos)
+			x.list = append(x.list, item)
+			return item, nil
+		}
+	} else if x == nil {
+		x = newRecord(pos)
+		tbl.set(keys[last], x, span{})
+		t.defined[x] = true
+		return x, nil
+	} else if x.kind == record && !t.defined[x] && !t.inline[x] {
+		t.defined[x] = true
+		return x, nil
+	}
+	return nil, t.d.errorf(pos, "table", strconv.Quote(strings.Join(keys, ".")), "is already defined")
+}
+
+// table 返回 tbl 中名为 key 的表, 不存在时创建隐式的表, 表数组返回最后一个元素.
+func (t *tomlParser) table(tbl *value, key string, pos scanner.Pos) (*value, error) {
+	x := tbl.rec[key]
+	switch {
+	case x == nil:
+		x = newRecord(pos)
+		tbl.set(key, x, span{})
+		return x, nil
+	case x.kind == record && !t.inline[x]:
+		return x, nil
+	case t.arrays[x]:
+		return x.list[len(x.list)-1], nil
+	}
+	return nil, t.d.errorf(pos, "key", strconv.Quote(key), "is already defined")
+}
+
+// keyval 解析键值对 key = value 并保存到 tbl
+func (t *tomlParser) keyval(tbl *value) error {
+	pos := t.pos()
+	keys, err := t.key()
+	if err != nil {
+		return err
+	}
+	if !t.skip('=') {
+		return t.unexpected()
+	}
+	t.ws()
+	x, err := t.value()
+	if err != nil {
+		return err
+	}
+
+	last := len(keys) - 1
+	for _, key := range keys[:last] {
+		if tbl, err = t.table(tbl, key, pos); err != nil {
+			return err
+		}
+	}
+	if _, ok := tbl.rec[keys[last]]; ok {
+		return t.d.errorf(pos, "duplicate key", strconv.Quote(strings.Join(keys, ".")))
+	}
+	tbl.set(keys[last], x, span{})
+	return nil
+}
+
+// key 解析可能用 '.' 连接的键, 包括前后的空白
+func (t *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		t.ws()
+		var key string
+		var err error
+		switch {
+		case t.i >= len(t.src):
+			return nil, t.unexpected()
+		case t.src[t.i] == '"':
+			key, err = t.basic()
+		case t.src[t.i] == '\'':
+			key, err = t.literal()
+		default:
+			start := t.i
+			for t.i < len(t.src) && isBare(t.src[t.i]) {
+				t.i++
+			}
+			if start == t.i {
+				return nil, t.unexpected()
+			}
+			key = string(t.src[start:t.i])
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		t.ws()
+		if !t.skip('.') {
+			return keys, nil
+		}
+	}
+}
+
+// value 解析一个值
+func (t *tomlParser) value() (*value, error) {
+	start := t.i
+	var v interface{}
+	var err error
+	switch {
+	case t.i >= len(t.src):
+		return nil, t.unexpected()
+	case t.src[t.i] == '[':
+		return t.array()
+	case t.src[t.i] == '{':
+		return t.inlineTable()
+	case t.src[t.i] == '"' || t.src[t.i] == '\'':
+		v, err = t.str()
+	default:
+		v, err = t.scalar()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &value{pos: scanner.Pos(start), val: v, src: string(t.src[start:t.i])}, nil
+}
+
+// array 解析数组, 元素之间可以有换行和注释, 允许末尾的逗号
+func (t *tomlParser) array() (*value, error) {
+	x := &value{pos: t.pos(), kind: list}
+	t.i++
+	for {
+		t.space()
+		if t.skip(']') {
+			return x, nil
+		}
+		item, err := t.value()
+		if err != nil {
+			return nil, err
+		}
+		x.list = append(x.list, item)
+		t.space()
+		if t.skip(']') {
+			return x, nil
+		}
+		if !t.skip(',') {
+			return nil, t.unexpected()
+		}
+	}
+}
+
+// inlineTable 解析内联表 {k = v, ...}, 内联表必须写在一行中
+func (t *tomlParser) inlineTable() (*value, error) {
+	x := newRecord(t.pos())
+	t.i++
+	t.ws()
+	if !t.skip('}') {
+		for {
+			if err := t.keyval(x); err != nil {
+				return nil, err
+			}
+			t.ws()
+			if t.skip('}') {
+				break
+			}
+			if !t.skip(',') {
+				return nil, t.unexpected()
+			}
+		}
+	}
+	t.freeze(x)
+	return x, nil
+}
+
+// freeze 禁止向内联表 x 及其中用带点的键定义的表添加成员
+func (t *tomlParser) freeze(x *value) {
+	t.inline[x] = true
+	for _, item := range x.rec {
+		if item.kind == record {
+			t.freeze(item)
+		}
+	}
+}
+
+// str 解析四种字符串
+func (t *tomlParser) str() (string, error) {
+	q := t.src[t.i]
+	if bytes.HasPrefix(t.src[t.i:], []byte{q, q, q}) {
+		return t.multiline(q)
+	}
+	if q == '"' {
+		return t.basic()
+	}
+	return t.literal()
+}
+
+// basic 解析单行的双引号字符串
+func (t *tomlParser) basic() (string, error) {
+	start := t.pos()
+	t.i++
+	var b strings.Builder
+	for t.i < len(t.src) {
+		switch c := t.src[t.i]; {
+		case c == '"':
+			t.i++
+			return b.String(), nil
+		case c == '\\':
+			if err := t.escape(&b); err != nil {
+				return "", err
+			}
+		case c == '\n' || c == '\r':
+			return "", t.d.errorf(start, "unterminated string")
+		case isControl(c):
+			return "", t.d.errorf(t.pos(), "control character in string")
+		default:
+			b.WriteByte(c)
+			t.i++
+		}
+	}
+	return "", t.d.errorf(start, "unterminated string")
+}
+
+// literal 解析单行的单引号字符串, 没有转义
+func (t *tomlParser) literal() (string, error) {
+	start := t.pos()
+	t.i++
+	for i := t.i; i < len(t.src); i++ {
+		switch c := t.src[i]; {
+		case c == '\'':
+			s := string(t.src[t.i:i])
+			t.i = i + 1
+			return s, nil
+		case c == '\n' || c == '\r':
+			return "", t.d.errorf(start, "unterminated string")
+		case isControl(c):
+			return "", t.d.errorf(scanner.Pos(i), "control character in string")
+		}
+	}
+	return "", t.d.errorf(start, "unterminated string")
+}
+
+// multiline 解析三个引号 q 的多行字符串, 紧跟开头引号的换行被忽略.
+// 结尾的引号之前可以再有至多两个引号.
+func (t *tomlParser) multiline(q byte) (string, error) {
+	start := t.pos()
+	t.i += 3
+	t.newline()
+	var b strings.Builder
+	for t.i < len(t.src) {
+		if t.newline() {
+			b.WriteByte('\n')
+			continue
+		}
+		switch c := t.src[t.i]; {
+		case c == q && bytes.HasPrefix(t.src[t.i:], []byte{q, q, q}):
+			n := 3
+			for n < 5 && t.i+n < len(t.src) && t.src[t.i+n] == q {
+				n++
+			}
+			b.WriteString(strings.Repeat(string(q), n-3))
+			t.i += n
+			return b.String(), nil
+		case c == '\\' && q == '"':
+			if t.trim() {
+				continue
+			}
+			if err := t.escape(&b); err != nil {
+				return "", err
+			}
+		case isControl(c):
+			return "", t.d.errorf(t.pos(), "control character in string")
+		default:
+			b.WriteByte(c)
+			t.i++
+		}
+	}
+	return "", t.d.errorf(start, "unterminated string")
+}
+
+// trim 跳过行尾的反斜杠以及之后的空白和换行, 不是行尾时不跳过
+func (t *tomlParser) trim() bool {
+	i := t.i
+	t.i++
+	t.ws()
+	if !t.newline() {
+		t.i = i
+		return false
+	}
+	for t.ws(); t.newline(); t.ws() {
+	}
+	return true
+}
+
+// escape 解析 '\' 开始的转义序列并写入 b
+func (t *tomlParser) escape(b *strings.Builder) error {
+	pos := t.pos()
+	t.i++
+	if t.i >= len(t.src) {
+		return t.unexpected()
+	}
+	c := t.src[t.i]
+	t.i++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if t.i+n <= len(t.src) {
+			r, err := strconv.ParseUint(string(t.src[t.i:t.i+n]), 16, 32)
+			if err == nil && utf8.ValidRune(rune(r)) {
+				b.WriteRune(rune(r))
+				t.i += n
+				return nil
+			}
+		}
+		fallthrough
+	default:
+		return t.d.errorf(pos, "invalid escape sequence", strconv.Quote(string(t.src[pos:t.i])))
+	}
+	return nil
+}
+
+// scalar 解析布尔值, 数值和日期时间
+func (t *tomlParser) scalar() (interface{}, error) {
+	start := t.i
+	for t.i < len(t.src) && isScalar(t.src[t.i]) {
+		t.i++
+	}
+	// 日期和时间之间可以是空格
+	if t.i-start == 10 && t.i+1 < len(t.src) && t.src[t.i] == ' ' && isDigit(t.src[t.i+1]) {
+		for t.i++; t.i < len(t.src) && isScalar(t.src[t.i]); t.i++ {
+		}
+	}
+	s := string(t.src[start:t.i])
+	pos := scanner.Pos(start)
+	switch s {
+	case "":
+		return nil, t.unexpected()
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	if len(s) >= 10 && s[4] == '-' && s[7] == '-' {
+		return t.datetime(pos, s)
+	}
+	if len(s) >= 8 && s[2] == ':' {
+		return nil, t.d.errorf(pos, "local time", s, "is not supported")
+	}
+	return t.number(pos, s)
+}
+
+// tomlTimes 是日期时间的格式, 日期和时间之间的空格已替换为 T
+var tomlTimes = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func (t *tomlParser) datetime(pos scanner.Pos, s string) (interface{}, error) {
+	u := strings.ToUpper(s)
+	if len(u) > 10 && u[10] == ' ' {
+		u = u[:10] + "T" + u[11:]
+	}
+	for _, layout := range tomlTimes {
+		if v, err := time.ParseInLocation(layout, u, time.Local); err == nil {
+			return v, nil
+		}
+	}
+	return nil, t.d.errorf(pos, "invalid datetime", s)
+}
+
+// number 解析整数和浮点数. '_' 必须位于两个数字之间, 十六进制数中可以是十六进制数字,
+// 因此不能紧接前缀和指数. 十进制数不能有多余的前导 0.
+func (t *tomlParser) number(pos scanner.Pos, s string) (interface{}, error) {
+	invalid := t.d.errorf(pos, "invalid number", s)
+	digit := isDigit
+	if strings.HasPrefix(s, "0x") {
+		digit = isHexDigit
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '_' && (i == 0 || i == len(s)-1 || !digit(s[i-1]) || !digit(s[i+1])) {
+			return nil, invalid
+		}
+	}
+	n := strings.Replace(s, "_", "", -1)
+
+	if len(n) > 2 && n[0] == '0' && (n[1] == 'x' || n[1] == 'o' || n[1] == 'b') {
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[n[1]]
+		if n[2] == '+' || n[2] == '-' {
+			return nil, invalid
+		}
+		v, err := strconv.ParseInt(n[2:], base, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return v, nil
+	}
+
+	digits := n
+	if digits != "" && (digits[0] == '+' || digits[0] == '-') {
+		digits = digits[1:]
+	}
+	if digits == "" || !isDigit(digits[0]) || len(digits) > 1 && digits[0] == '0' && isDigit(digits[1]) {
+		return nil, invalid
+	}
+	if strings.ContainsAny(digits, ".eE") {
+		if i := strings.IndexByte(digits, '.'); i != -1 && (i+1 == len(digits) || !isDigit(digits[i+1])) {
+			return nil, invalid
+		}
+		v, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		return v, nil
+	}
+	v, err := strconv.ParseInt(n, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	return v, nil
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func isHexDigit(c byte) bool { return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F' }
+
+func isAlnum(c byte) bool { return isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+
+// isBare 返回 c 是否可以用在不加引号的键中
+func isBare(c byte) bool { return isAlnum(c) || c == '_' || c == '-' }
+
+// isScalar 返回 c 是否可以用在数值和日期时间中
+func isScalar(c byte) bool { return isBare(c) || c == '+' || c == '.' || c == ':' }
+
+// isControl 返回 c 是否是字符串中不允许的控制字符, TAB 除外
+func isControl(c byte) bool { return c < 0x20 && c != '\t' || c == 0x7f }
@@ -0,0 +1,140 @@
+package config_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ZxxLang/zxx/config"
+)
+
+const serverTOML = "\xef\xbb\xbf" + `# 服务配置
+name = "zxx-\u0073erver"
+port = 8_080
+debug = false
+ratio = 2.0
+hosts = [
+	'a.example.com', # 注释
+	"b.example.com",
+]
+start = 2016-02-04T21:49:00Z
+day = 2016-02-04
+extra = [1, 'two', {three = 3e0}]
+
+[limits]
+read = 0x10
+"write" = 0b101
+
+[tls]
+cert = """
+x.pem"""
+key = 'x.key'
+`
+
+func TestDecodeTOML(t *testing.T) {
+	var s Server
+	if err := config.DecodeTOML([]byte(serverTOML), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Server{
+		Name:   "zxx-server",
+		Port:   8080,
+		Ratio:  2,
+		Hosts:  []string{"a.example.com", "b.example.com"},
+		Limits: map[string]int{"read": 16, "write": 5},
+		TLS:    &TLS{"x.pem", "x.key"},
+		Start:  time.Date(2016, 2, 4, 21, 49, 0, 0, time.UTC),
+		Day:    time.Date(2016, 2, 4, 0, 0, 0, 0, time.Local),
+		Extra: []interface{}{int64(1), "two",
+			map[string]interface{}{"three": 3.0}},
+	}
+	if !s.Start.Equal(want.Start) || !s.Day.Equal(want.Day) {
+		t.Fatal(s.Start, s.Day)
+	}
+	s.Start, s.Day = want.Start, want.Day
+	if !reflect.DeepEqual(s, want) {
+		t.Fatalf("%#v", s)
+	}
+}
+
+func TestDecodeTOMLValues(t *testing.T) {
+	for src, want := range map[string]interface{}{
+		`v = 'C:\Users\zxx'`:                "C:\\Users\\zxx",
+		`v = "tab\there \"q\" \U0001F600"`:  "tab\there \"q\" \U0001F600",
+		"v = '''\r\nline 1\r\nline 2'''":    "line 1\nline 2",
+		"v = \"\"\"a \\\n\t  b\"\"\"\"\"":   "a b\"\"",
+		`v = -0.5e-3`:                       -0.5e-3,
+		`v = +17`:                           int64(17),
+		`v = 0o755`:                         int64(0755),
+		`v = 0xdead_BEEF`:                   int64(0xdeadbeef),
+		`v = 1_0.2_5e1_0`:                   10.25e10,
+		`v = -inf`:                          math.Inf(-1),
+		`v = true`:                          true,
+		`v = []`:                            []interface{}{},
+		`v = {a.b = 1}`:                     map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}},
+		"[[v]]\nx = 1\n[[v]]\n[v.y]\nz = 2": []interface{}{map[string]interface{}{"x": int64(1)}, map[string]interface{}{"y": map[string]interface{}{"z": int64(2)}}},
+		"[v.a]\n[v]\nb = 1":                 map[string]interface{}{"a": map[string]interface{}{}, "b": int64(1)},
+		"v = 1979-05-27 07:32:00.5-07:00":   time.Date(1979, 5, 27, 14, 32, 0, 5e8, time.UTC),
+	} {
+		var v struct{ V interface{} }
+		if err := config.DecodeTOML([]byte(src), &v); err != nil {
+			t.Fatal(src, err)
+		}
+		if tm, ok := v.V.(time.Time); ok && tm.Equal(want.(time.Time)) {
+			continue
+		}
+		if !reflect.DeepEqual(v.V, want) {
+			t.Fatalf("%s: %#v", src, v.V)
+		}
+	}
+}
+
+func TestDecodeTOMLError(t *testing.T) {
+	for src, want := range map[string]string{
+		"port = 70000":                 "1:8: config: value 70000 overflows uint16",
+		"name = 1":                     "1:8: config: cannot decode int64 into string",
+		"a = 1\na = 2":                 "2:1: config: duplicate key \"a\"",
+		"a = 1\n[a]":                   "2:1: config: table \"a\" is already defined",
+		"[a]\n[a]":                     "2:1: config: table \"a\" is already defined",
+		"a = {b = 1}\na.c = 2":         "2:1: config: key \"a\" is already defined",
+		"a = [1]\n[[a]]":               "2:1: config: table \"a\" is already defined",
+		"a = 1 b = 2":                  "1:7: config: unexpected 'b'",
+		"a = [1\n2]":                   "2:1: config: unexpected '2'",
+		"a = {b = 1,\n}":               "1:12: config: unexpected '\\n'",
+		"a = \"x":                      "1:5: config: unterminated string",
+		"a = 'x\ny'":                   "1:5: config: unterminated string",
+		"a = \"\\q\"":                  "1:6: config: invalid escape sequence \"\\\\q\"",
+		"a = 012":                      "1:5: config: invalid number 012",
+		"a = 1__0":                     "1:5: config: invalid number 1__0",
+		"a = 0x_1":                     "1:5: config: invalid number 0x_1",
+		"a = 0b_1":                     "1:5: config: invalid number 0b_1",
+		"a = 1e_5":                     "1:5: config: invalid number 1e_5",
+		"a = 1_.5":                     "1:5: config: invalid number 1_.5",
+		"a = 1.":                       "1:5: config: invalid number 1.",
+		"a = 07:32:00":                 "1:5: config: local time 07:32:00 is not supported",
+		"a = 2016-13-01":               "1:5: config: invalid datetime 2016-13-01",
+		"a = \xff":                     "1:5: config: invalid UTF-8 encoding",
+		"= 1":                          "1:1: config: unexpected '='",
+		"a =":                          "1:4: config: unexpected EOF",
+		"a = 1\r":                      "1:6: config: unexpected '\\r'",
+		"[a":                           "1:3: config: unexpected EOF",
+		"a = \"\"\"x":                  "1:5: config: unterminated string",
+		"a = \"\x01\"":                 "1:6: config: control character in string",
+		"[[a]]\n[a.b]\nc = 1\n[[a.b]]": "4:1: config: table \"a.b\" is already defined",
+	} {
+		var v struct {
+			Port uint16
+			Name string
+		}
+		err := config.DecodeTOML([]byte(src), &v)
+		if _, ok := err.(*config.Error); !ok || err.Error() != want {
+			t.Errorf("%q: %v", src, err)
+		}
+	}
+
+	if err := config.DecodeTOML(nil, nil); err == nil {
+		t.Fatal("want error")
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包实现任意精度的常量运算, 用于常量折叠和类型检查, 以及 eval 的 decimal 值.
+//
+// 常量有三种: Int 是整数字面值, Float 是浮点数字面值, Decimal 是以 'd' 结尾的
+// 十进制数字面值, 例如 19.99d, 100d. 所有值用有理数精确表示, 运算没有舍入和溢出,
+// 需要 int64, float64 时由 Int64, Float64 检查或转换.
+//
+// Int 与 Float 运算的结果是 Float, Int 与 Decimal 运算的结果是 Decimal,
+// Float 与 Decimal 不能混合运算, 以免二进制浮点数的误差进入精确的十进制计算.
+package constant
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Kind 是常量的种类
+type Kind uint8
+
+const (
+	Int Kind = iota
+	Float
+	Decimal
+)
+
+var kinds = [...]string{"int", "float", "decimal"}
+
+func (k Kind) String() string {
+	if int(k) < len(kinds) {
+		return kinds[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Value 是不可变的常量, 零值是 Int 0.
+type Value struct {
+	kind Kind
+	r    *big.Rat // nil 表示 0, 创建之后不被修改
+}
+
+// Make 返回字面值 Token tok 的源码 lit 对应的常量, tok 是 VALINTEGER 或 VALFLOAT.
+func Make(tok token.Token, lit string) (Value, error) {
+	if t, err := lexutil.Classify(lit); err != nil || t != tok || tok != token.VALINTEGER && tok != token.VALFLOAT {
+		return Value{}, errors.New("constant: invalid " + tok.String() + " " + lit)
+	}
+	kind := Float
+	if tok == token.VALINTEGER {
+		kind = Int
+	}
+	lit = strings.Replace(lit, "_", "", -1)
+	if strings.HasSuffix(lit, "d") {
+		kind, lit = Decimal, lit[:len(lit)-1]
+	}
+
+	r := new(big.Rat)
+	if kind == Int {
+		// 前导 0 不表示八进制
+		base := 10
+		if len(lit) > 2 && lit[0] == '0' && strings.IndexByte("xob", lit[1]) >= 0 {
+			base = 0
+		}
+		i, ok := new(big.Int).SetString(lit, base)
+		if !ok {
+			return Value{}, errors.New("constant: invalid " + tok.String() + " " + lit)
+		}
+		return Value{kind, r.SetInt(i)}, nil
+	}
+	if _, ok := r.SetString(lit); !ok {
+		return Value{}, errors.New("constant: invalid " + tok.String() + " " + lit)
+	}
+	return Value{kind, r}, nil
+}
+
+// MakeInt64 返回 Int 常量 x
+func MakeInt64(x int64) Value {
+	return Value{Int, new(big.Rat).SetInt64(x)}
+}
+
+// MakeFloat64 返回 Float 常量 x, x 是 NaN 或无穷时 ok 为 false.
+func MakeFloat64(x float64) (v Value, ok bool) {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return Value{}, false
+	}
+	return Value{Float, new(big.Rat).SetFloat64(x)}, true
+}
+
+// MakeDecimal 解析十进制数 s, 可以没有 'd' 后缀, 返回 Decimal 常量.
+func MakeDecimal(s string) (Value, error) {
+	return Make(token.VALFLOAT, strings.TrimSuffix(s, "d")+"d")
+}
+
+func (v Value) rat() *big.Rat {
+	if v.r == nil {
+		return new(big.Rat)
+	}
+	return v.r
+}
+
+// Kind 返回 v 的种类
+func (v Value) Kind() Kind { return v.kind }
+
+// Sign 返回 v 的符号 -1, 0 或 1
+func (v Value) Sign() int { return v.rat().Sign() }
+
+// Int64 返回 v 的 int64 值, v 不是整数或者溢出时 ok 为 false.
+func (v Value) Int64() (x int64, ok bool) {
+	r := v.rat()
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return r.Num().Int64(), true
+}
+
+// Float64 返回最接近 v 的 float64 值, exact 表示是否没有误差.
+func (v Value) Float64() (x float64, exact bool) {
+	return v.rat().Float64()
+}
+
+// Rat 返回 v 的有理数值的副本
+func (v Value) Rat() *big.Rat {
+	return new(big.Rat).Set(v.rat())
+}
+
+// maxDigits 是无限小数显示的小数位数
+const maxDigits = 34
+
+// String 返回 v 的十进制表示. Int 是整数; Float 同 strconv 'g' 格式;
+// Decimal 是精确的小数, 无限小数保留 34 位小数, 不带 'd' 后缀.
+func (v Value) String() string {
+	r := v.rat()
+	switch v.kind {
+	case Int:
+		return r.Num().String()
+	case Float:
+		f, _ := r.Float64()
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	return r.FloatString(fraction(r.Denom()))
+}
+
+// fraction 返回分母为 d 的小数需要的小数位数, 无限小数返回 maxDigits
+func fraction(d *big.Int) int {
+	d = new(big.Int).Set(d)
+	two, five := big.NewInt(2), big.NewInt(5)
+	m := new(big.Int)
+	n2, n5 := 0, 0
+	for d.QuoRem(d, two, m); m.Sign() == 0; d.QuoRem(d, two, m) {
+		n2++
+	}
+	d.Mul(d, two).Add(d, m)
+	for d.QuoRem(d, five, m); m.Sign() == 0; d.QuoRem(d, five, m) {
+		n5++
+	}
+	d.Mul(d, five).Add(d, m)
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return maxDigits
+	}
+	if n2 > n5 {
+		return n2
+	}
+	return n5
+}
+
+// kindOf 返回 x, y 运算结果的种类
+func kindOf(x, y Value) (Kind, error) {
+	switch {
+	case x.kind == y.kind:
+		return x.kind, nil
+	case x.kind == Int:
+		return y.kind, nil
+	case y.kind == Int:
+		return x.kind, nil
+	}
+	return 0, errors.New("mixed float and decimal")
+}
+
+// Unary 返回 op x 的值, op 可以是 SUB, PLUS.
+func Unary(op token.Token, x Value) (Value, error) {
+	switch op {
+	case token.PLUS:
+		return x, nil
+	case token.SUB:
+		return Value{x.kind, new(big.Rat).Neg(x.rat())}, nil
+	}
+	return Value{}, errors.New("invalid operation " + op.String() + " on " + x.kind.String())
+}
+
+// Compare 返回 x 与 y 比较的结果 -1, 0 或 1.
+func Compare(x, y Value) (int, error) {
+	if _, err := kindOf(x, y); err != nil {
+		return 0, err
+	}
+	return x.rat().Cmp(y.rat()), nil
+}
+
+// Binary 返回 x op y 的值. Int 的 '/' 和 div 是截断除法, 位运算和移位只用于 Int.
+// 比较运算应当使用 Compare.
+func Binary(op token.Token, x, y Value) (Value, error) {
+	kind, err := kindOf(x, y)
+	if err != nil {
+		return Value{}, err
+	}
+	a, b := x.rat(), y.rat()
+	r := new(big.Rat)
+	switch op {
+	case token.ADD, token.PLUS:
+		return Value{kind, r.Add(a, b)}, nil
+	case token.SUB:
+		return Value{kind, r.Sub(a, b)}, nil
+	case token.MUL, token.MULSIGN:
+		return Value{kind, r.Mul(a, b)}, nil
+	}
+	if kind == Int {
+		return integer(op, a.Num(), b.Num())
+	}
+	if op == token.DIV || op == token.DIVSIGN {
+		if b.Sign() == 0 {
+			return Value{}, errors.New("division by zero")
+		}
+		return Value{kind, r.Quo(a, b)}, nil
+	}
+	return Value{}, errors.New("invalid operation " + op.String() + " on " + kind.String())
+}
+
+// maxShift 是移位运算的上限
+const maxShift = 4096
+
+func integer(op token.Token, a, b *big.Int) (Value, error) {
+	z := new(big.Int)
+	switch op {
+	case token.DIV, token.DIVSIGN, token.MOD, token.REM:
+		if b.Sign() == 0 {
+			return Value{}, errors.New("division by zero")
+		}
+		switch op {
+		case token.MOD:
+			z.Rem(a, b)
+		case token.REM:
+			z.Rem(new(big.Int).Abs(a), new(big.Int).Abs(b))
+		default:
+			z.Quo(a, b)
+		}
+	case token.BITAND:
+		z.And(a, b)
+	case token.BITOR:
+		z.Or(a, b)
+	case token.XOR:
+		z.Xor(a, b)
+	case token.SHL, token.SHLSIGN, token.SHR, token.SHRSIGN:
+		if b.Sign() < 0 || b.Cmp(big.NewInt(maxShift)) > 0 {
+			return Value{}, errors.New("invalid shift count")
+		}
+		if op == token.SHL || op == token.SHLSIGN {
+			z.Lsh(a, uint(b.Int64()))
+		} else {
+			z.Rsh(a, uint(b.Int64()))
+		}
+	default:
+		return Value{}, errors.New("invalid operation " + op.String() + " on int")
+	}
+	return Value{Int, new(big.Rat).SetInt(z)}, nil
+}
@@ -0,0 +1,120 @@
+package constant_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ZxxLang/zxx/constant"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func TestMake(t *testing.T) {
+	for _, tt := range []struct {
+		tok       token.Token
+		lit, want string
+		kind      constant.Kind
+	}{
+		{token.VALINTEGER, "123456789012345678901234567890", "123456789012345678901234567890", constant.Int},
+		{token.VALINTEGER, "0xFF", "255", constant.Int},
+		{token.VALINTEGER, "0b1010_1010", "170", constant.Int},
+		{token.VALINTEGER, "0755", "755", constant.Int},
+		{token.VALFLOAT, "1.5e10", "1.5e+10", constant.Float},
+		{token.VALFLOAT, "19.99d", "19.99", constant.Decimal},
+		{token.VALFLOAT, "1_000.500d", "1000.5", constant.Decimal},
+		{token.VALFLOAT, "1.25e2d", "125", constant.Decimal},
+		{token.VALFLOAT, "100d", "100", constant.Decimal},
+	} {
+		v, err := constant.Make(tt.tok, tt.lit)
+		if err != nil || v.Kind() != tt.kind || v.String() != tt.want {
+			t.Errorf("Make(%s) = %s %s, %v", tt.lit, v.Kind(), v, err)
+		}
+	}
+
+	for _, lit := range []string{"1.5", "abc", "1x"} {
+		if _, err := constant.Make(token.VALINTEGER, lit); err == nil {
+			t.Errorf("Make(%s): no error", lit)
+		}
+	}
+}
+
+func TestBinary(t *testing.T) {
+	var mustMake func(string) constant.Value
+	mustMake = func(lit string) constant.Value {
+		if lit[0] == '-' {
+			v, _ := constant.Unary(token.SUB, mustMake(lit[1:]))
+			return v
+		}
+		tok := token.VALINTEGER
+		for _, c := range lit {
+			if c == '.' || c == 'd' || c == 'e' {
+				tok = token.VALFLOAT
+			}
+		}
+		v, err := constant.Make(tok, lit)
+		if err != nil {
+			t.Fatal(lit, err)
+		}
+		return v
+	}
+	for _, tt := range []struct {
+		x    string
+		op   token.Token
+		y    string
+		want string
+	}{
+		{"9223372036854775807", token.ADD, "1", "9223372036854775808"},
+		{"4294967296", token.MUL, "4294967296", "18446744073709551616"},
+		{"7", token.DIV, "2", "3"},
+		{"-7", token.MOD, "2", "-1"},
+		{"-7", token.REM, "2", "1"},
+		{"1", token.SHL, "100", "1267650600228229401496703205376"},
+		{"0.1d", token.ADD, "0.2d", "0.3"},
+		{"1d", token.DIV, "8", "0.125"},
+		{"2d", token.DIV, "3", "0.6666666666666666666666666666666667"},
+		{"1.5", token.MUL, "2", "3"},
+	} {
+		v, err := constant.Binary(tt.op, mustMake(tt.x), mustMake(tt.y))
+		if err != nil || v.String() != tt.want {
+			t.Errorf("%s %s %s = %s, %v", tt.x, tt.op, tt.y, v, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		x  string
+		op token.Token
+		y  string
+	}{
+		{"0.1d", token.ADD, "0.1"},
+		{"1", token.DIV, "0"},
+		{"1.5d", token.MOD, "1"},
+		{"1", token.SHL, "-1"},
+	} {
+		if _, err := constant.Binary(tt.op, mustMake(tt.x), mustMake(tt.y)); err == nil {
+			t.Errorf("%s %s %s: no error", tt.x, tt.op, tt.y)
+		}
+	}
+
+	if c, err := constant.Compare(mustMake("0.30d"), mustMake("0.3d")); c != 0 || err != nil {
+		t.Error("Compare", c, err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	if _, ok := constant.MakeInt64(math.MaxInt64).Int64(); !ok {
+		t.Error("MaxInt64")
+	}
+	big, _ := constant.Binary(token.ADD, constant.MakeInt64(math.MaxInt64), constant.MakeInt64(1))
+	if _, ok := big.Int64(); ok {
+		t.Error("overflow not detected")
+	}
+	if _, ok := constant.MakeFloat64(math.NaN()); ok {
+		t.Error("NaN")
+	}
+	d, err := constant.MakeDecimal("0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, exact := d.Float64(); f != 0.1 || exact {
+		t.Error("Float64", f, exact)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cst
+
+import (
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// builder 把 Symbol 序列和它们之间被丢弃的空白组织为树
+type builder struct {
+	leaves []*Green
+	i      int
+}
+
+// build 返回 src 的 File 节点, syms 是 src 的 Symbol 序列
+func build(src []byte, syms []parser.Symbol) *Green {
+	b := &builder{leaves: make([]*Green, 0, len(syms))}
+	cur := 0
+	for _, sym := range syms {
+		pos := int(sym.Pos)
+		if pos > cur {
+			b.leaves = append(b.leaves, gap(string(src[cur:pos])))
+		}
+		b.leaves = append(b.leaves, NewToken(sym.Tok, sym.Source))
+		cur = pos + len(sym.Source)
+	}
+	if cur < len(src) {
+		b.leaves = append(b.leaves, gap(string(src[cur:])))
+	}
+	return NewNode(File, b.list(false)...)
+}
+
+// gap 返回 Token 之间被丢弃的文本的叶子节点
+func gap(s string) *Green {
+	switch {
+	case strings.Trim(s, " ") == "":
+		return NewToken(token.SPACES, s)
+	case strings.Trim(s, "\t") == "":
+		return NewToken(token.TABS, s)
+	}
+	return NewToken(token.PLACEHOLDER, s)
+}
+
+// closing 返回 tok 是否结束分组
+func closing(tok token.Token) bool {
+	return tok == token.RIGHT || tok == token.INTERPEND
+}
+
+// list 返回一组语句及其间的 Trivia, inGroup 时遇到结束括号返回, 结束括号不被消耗.
+func (b *builder) list(inGroup bool) (list []*Green) {
+	for b.i < len(b.leaves) {
+		g := b.leaves[b.i]
+		switch {
+		case inGroup && closing(g.tok):
+			return
+		case ast.IsTrivia(g.tok):
+			list = append(list, g)
+			b.i++
+		default:
+			list = append(list, b.stmt(inGroup))
+		}
+	}
+	return
+}
+
+// stmt 返回一个语句, 行尾的 Token 需要续行时包括之后的行
+func (b *builder) stmt(inGroup bool) *Green {
+	var list []*Green
+	last := token.EOF // 最后一个非 Trivia 的 Token
+	for b.i < len(b.leaves) {
+		g := b.leaves[b.i]
+		switch {
+		case closing(g.tok):
+			if inGroup {
+				return b.node(list)
+			}
+			// 多余的结束括号属于所在的语句
+		case g.tok == token.NL:
+			if !continued(last) {
+				return b.node(list)
+			}
+		case g.tok == token.LEFT || g.tok == token.INTERPBEGIN:
+			list, last = append(list, b.group()), token.RIGHT
+			continue
+		}
+		if !ast.IsTrivia(g.tok) {
+			last = g.tok
+		}
+		list = append(list, g)
+		b.i++
+	}
+	return b.node(list)
+}
+
+// group 返回从开始括号到结束括号的分组, 源码未闭合时到文件结尾为止
+func (b *builder) group() *Green {
+	list := []*Green{b.leaves[b.i]}
+	b.i++
+	list = append(list, b.list(true)...)
+	if b.i < len(b.leaves) {
+		list = append(list, b.leaves[b.i])
+		b.i++
+	}
+	return NewNode(Group, list...)
+}
+
+// node 返回 list 组成的语句或声明
+func (b *builder) node(list []*Green) *Green {
+	if list[0].tok.As(token.Declare) {
+		return NewNode(Decl, list...)
+	}
+	return NewNode(Stmt, list...)
+}
+
+// continued 返回行尾的 tok 之后是否是续行, 与 parser.ParseSyntax 相同
+func continued(tok token.Token) bool {
+	return tok == token.LEFT || tok == token.COMMA || tok == token.DOT ||
+		tok == token.ASSIGN || tok.As(token.Operator) || tok.As(token.Declare)
+}
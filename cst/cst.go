@@ -0,0 +1,207 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包实现无损的具体语法树, 源码的每个字节, 包括缩进, 占位, 注释和分隔空白,
+// 都是树中的 Token, 树的 Text 与源码完全相同, 供格式化和重构工具往返改写源码.
+//
+// 树分为两层:
+//
+// Green 是不可变的节点, 只记录种类, 文本和宽度, 不记录位置和父节点,
+// 相同的子树可以在多棵树之间共享, 修改时只需重建从根到被修改节点的路径.
+//
+// Node 在 Green 之上按需计算位置和父节点, 用于遍历和定位.
+//
+// 结构只有四种: 文件, 语句, 声明和括号分组. 语句是不续行的一行 Token,
+// 以声明关键字开始的语句是声明. 分组是配对的括号, 以及插值字符串中的 {...},
+// 分组之内的行也是语句. 换行, 缩进和占位属于外层, 行尾注释属于语句.
+package cst
+
+import (
+	"strings"
+
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Kind 是 Green 节点的种类
+type Kind uint8
+
+const (
+	Token Kind = iota // 叶子节点, 参见 Green.Token
+	File
+	Decl
+	Stmt
+	Group
+)
+
+var kinds = [...]string{"Token", "File", "Decl", "Stmt", "Group"}
+
+func (k Kind) String() string {
+	if int(k) < len(kinds) {
+		return kinds[k]
+	}
+	return "Kind(?)"
+}
+
+// Green 是不可变的节点, 创建之后不能修改.
+type Green struct {
+	kind     Kind
+	tok      token.Token
+	text     string
+	children []*Green
+	width    int
+}
+
+// NewToken 返回文本为 text 的叶子节点.
+// 源码中 Token 之间的空白是 SPACES, TABS, 其它无法归类的字节是 PLACEHOLDER.
+func NewToken(tok token.Token, text string) *Green {
+	return &Green{kind: Token, tok: tok, text: text, width: len(text)}
+}
+
+// NewNode 返回种类为 kind 的非叶子节点, children 被复制.
+func NewNode(kind Kind, children ...*Green) *Green {
+	g := &Green{kind: kind, children: append([]*Green(nil), children...)}
+	for _, c := range children {
+		g.width += c.width
+	}
+	return g
+}
+
+func (g *Green) Kind() Kind { return g.kind }
+
+// Token 返回叶子节点的 Token, 非叶子节点返回 EOF.
+func (g *Green) Token() token.Token { return g.tok }
+
+// Width 返回节点文本的字节数
+func (g *Green) Width() int { return g.width }
+
+// Len 返回子节点的个数
+func (g *Green) Len() int { return len(g.children) }
+
+// Child 返回第 i 个子节点
+func (g *Green) Child(i int) *Green { return g.children[i] }
+
+// Text 返回节点的全部文本
+func (g *Green) Text() string {
+	if g.kind == Token {
+		return g.text
+	}
+	var b strings.Builder
+	b.Grow(g.width)
+	g.write(&b)
+	return b.String()
+}
+
+func (g *Green) write(b *strings.Builder) {
+	if g.kind == Token {
+		b.WriteString(g.text)
+		return
+	}
+	for _, c := range g.children {
+		c.write(b)
+	}
+}
+
+// With 返回第 i 个子节点替换为 c 的新节点, 其它子节点被共享.
+func (g *Green) With(i int, c *Green) *Green {
+	children := append([]*Green(nil), g.children...)
+	children[i] = c
+	return NewNode(g.kind, children...)
+}
+
+// Node 是 Green 在树中的位置, 只读, 由 Parse, NewRoot 和 Node 的方法产生.
+type Node struct {
+	green  *Green
+	parent *Node
+	index  int // 在 parent 中的序号
+	pos    scanner.Pos
+}
+
+// NewRoot 返回以 g 为根的树, 根的位置为 0.
+func NewRoot(g *Green) *Node {
+	return &Node{green: g}
+}
+
+// Parse 解析 src 并返回无损的树, 允许 SPACES, TABS 混搭缩进.
+// 返回的 Text 与 src 相同.
+func Parse(src []byte) (*Node, error) {
+	syms, err := parser.FastMixed(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	return NewRoot(build(src, syms)), nil
+}
+
+func (n *Node) Green() *Green      { return n.green }
+func (n *Node) Kind() Kind         { return n.green.kind }
+func (n *Node) Token() token.Token { return n.green.tok }
+func (n *Node) Text() string       { return n.green.Text() }
+func (n *Node) Pos() scanner.Pos   { return n.pos }
+func (n *Node) End() scanner.Pos   { return n.pos.Offset(n.green.width) }
+func (n *Node) Width() int         { return n.green.width }
+func (n *Node) Parent() *Node      { return n.parent }
+func (n *Node) Index() int         { return n.index }
+func (n *Node) Len() int           { return len(n.green.children) }
+func (n *Node) Children() (list []*Node) {
+	for i := range n.green.children {
+		list = append(list, n.Child(i))
+	}
+	return
+}
+
+// Child 返回第 i 个子节点
+func (n *Node) Child(i int) *Node {
+	pos := n.pos
+	for _, c := range n.green.children[:i] {
+		pos = pos.Offset(c.width)
+	}
+	return &Node{green: n.green.children[i], parent: n, index: i, pos: pos}
+}
+
+// Root 返回 n 所在的树的根
+func (n *Node) Root() *Node {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+// TokenAt 返回包含位置 pos 的叶子节点, pos 不在 n 之内时返回 nil.
+func (n *Node) TokenAt(pos scanner.Pos) *Node {
+	if pos < n.pos || pos >= n.End() {
+		return nil
+	}
+	for n.green.kind != Token {
+		at := n.pos
+		for i, c := range n.green.children {
+			if end := at.Offset(c.width); pos < end {
+				n = &Node{green: c, parent: n, index: i, pos: at}
+				break
+			}
+			at = at.Offset(c.width)
+		}
+	}
+	return n
+}
+
+// Tokens 按顺序返回 n 之内的全部叶子节点
+func (n *Node) Tokens() (list []*Node) {
+	if n.green.kind == Token {
+		return []*Node{n}
+	}
+	for _, c := range n.Children() {
+		list = append(list, c.Tokens()...)
+	}
+	return
+}
+
+// Replace 返回把 n 替换为 g 之后的新树的根, 原来的树不变.
+func (n *Node) Replace(g *Green) *Node {
+	for n.parent != nil {
+		g = n.parent.green.With(n.index, g)
+		n = n.parent
+	}
+	return NewRoot(g)
+}
@@ -0,0 +1,132 @@
+package cst_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/cst"
+	"github.com/ZxxLang/zxx/token"
+)
+
+var roundTrip = []string{
+	"",
+	"\n\n",
+	"\ufeffproc main() [\n\techo 'hi'\n]\n",
+	"use fmt 'fmt'\r\n\r\nproc main() [\r\n\tfmt.print(1)\r\n]\r\n",
+	`--- 文件注释
+---
+
+pub const (
+	a   = 1 // 注释
+	b,  c = 2,  3
+)
+
+这是顶层占位文本
+
+var int x = f(1,
+	  2)
+proc main(int n) out int [
+	if n > 1 [
+	    x = 'a{n + 1}b{ x }c'
+	]
+	out n
+]   `,
+	"var a = (1 + 2]\n]\n",
+	"proc f() [\n\tx = [1, [2\n",
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, src := range roundTrip {
+		root, err := cst.Parse([]byte(src))
+		if err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+		if got := root.Text(); got != src {
+			t.Fatalf("Text() = %q, want %q", got, src)
+		}
+		var b strings.Builder
+		end := root.Pos()
+		for _, n := range root.Tokens() {
+			if n.Pos() != end {
+				t.Fatalf("%q: token %q at %d, want %d", src, n.Text(), n.Pos(), end)
+			}
+			if at := root.TokenAt(n.Pos()); n.Width() != 0 && at.Pos() != n.Pos() {
+				t.Fatalf("%q: TokenAt(%d) = %q", src, n.Pos(), at.Text())
+			}
+			b.WriteString(n.Text())
+			end = n.End()
+		}
+		if b.String() != src {
+			t.Fatalf("tokens = %q, want %q", b.String(), src)
+		}
+	}
+}
+
+func TestStructure(t *testing.T) {
+	src := "pub var a = 1 // c\n\nproc f() [\n\tx = f(1,\n\t\t2)\n]\n"
+	root, err := cst.Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, n := range root.Children() {
+		if n.Kind() == cst.Token {
+			kinds = append(kinds, n.Token().String())
+		} else {
+			kinds = append(kinds, n.Kind().String()+" "+n.Text())
+		}
+	}
+	want := []string{
+		"Decl pub var a = 1 // c",
+		"NEWLINE",
+		"Decl proc f() [\n\tx = f(1,\n\t\t2)\n]",
+		"NEWLINE",
+	}
+	if strings.Join(kinds, "|") != strings.Join(want, "|") {
+		t.Fatalf("got\n%q\nwant\n%q", kinds, want)
+	}
+
+	// proc 的函数体是 Group, 其中的语句续行到 2)
+	body := root.Child(2).Child(root.Child(2).Len() - 1)
+	if body.Kind() != cst.Group || body.Child(0).Text() != "[" {
+		t.Fatalf("body = %v %q", body.Kind(), body.Text())
+	}
+	var stmts []string
+	for _, n := range body.Children() {
+		if n.Kind() == cst.Stmt {
+			stmts = append(stmts, n.Text())
+		}
+	}
+	if len(stmts) != 1 || stmts[0] != "x = f(1,\n\t\t2)" {
+		t.Fatalf("stmts = %q", stmts)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	src := "var a = 1\nvar b = a\n"
+	root, err := cst.Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := root.Child(2)
+	var n *cst.Node
+	for _, tok := range old.Tokens() {
+		if tok.Token() == token.IDENT && tok.Text() == "a" {
+			n = tok
+		}
+	}
+	if n == nil {
+		t.Fatal("no ident")
+	}
+	nroot := n.Replace(cst.NewToken(token.IDENT, "alpha"))
+	if got, want := nroot.Text(), "var a = 1\nvar b = alpha\n"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if root.Text() != src {
+		t.Fatal("original tree modified")
+	}
+	// 未修改的子树被共享
+	if nroot.Child(0).Green() != root.Child(0).Green() {
+		t.Fatal("unchanged subtree not shared")
+	}
+}
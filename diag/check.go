@@ -0,0 +1,75 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"github.com/ZxxLang/zxx/analysis/callgraph"
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+// 内置诊断的 ID
+const (
+	Syntax     = "syntax"      // 解析错误
+	UnusedFunc = "unused-func" // 从 main, pub 函数和测试不可达的函数
+)
+
+func init() {
+	Register(&Rule{Syntax, Error, "source cannot be parsed"})
+	Register(&Rule{UnusedFunc, Warning, "func or proc is not reachable from main, pub functions or tests"})
+}
+
+// File 是包中的一个文件
+type File struct {
+	Name    string
+	Src     []byte
+	Version string // 项目默认的语言版本, 文件头部的版本指示优先, 为空时是最新版本
+}
+
+// Check 返回包 files 的内置诊断, 严重程度是默认值, 调用者用 Config.Apply 调整.
+// 有解析错误的文件只参与不依赖语法树的检查, 例如混用的换行, 顶层占位和语言版本.
+func Check(files []File) []Diagnostic {
+	var (
+		list  []Diagnostic
+		funcs []callgraph.File
+	)
+	for _, f := range files {
+		list = append(list, checkEOL(f)...)
+		list = append(list, checkPlaceholders(f)...)
+		list = append(list, checkVersion(f)...)
+		err := parser.Parse(f.Src, ast.NewFile())
+		if err == nil {
+			// 无法识别的语句是 BadSyntax, 不影响调用图
+			if sf, _ := parser.ParseSyntax(f.Src); sf != nil {
+				funcs = append(funcs, callgraph.File{Name: f.Name, Syntax: sf})
+			}
+			continue
+		}
+		errs, ok := err.(parser.ErrorList)
+		if !ok {
+			errs = parser.ErrorList{err}
+		}
+		for _, err := range errs {
+			if e, ok := err.(*parser.Error); ok {
+				d := New(Syntax, f.Name, e.Pos, e.Msg)
+				d.Fixes = e.Fixes
+				list = append(list, d)
+			} else {
+				list = append(list, New(Syntax, f.Name, 0, err.Error()))
+			}
+		}
+	}
+
+	for _, n := range callgraph.New(funcs).Unreachable(callgraph.Entry) {
+		list = append(list, New(UnusedFunc, n.File, n.Decl.Name.Pos(), n.Decl.Tok.String()+" "+n.Name+" is unused"))
+	}
+	list = append(list, checkResults(funcs)...)
+	list = append(list, checkTypeArgs(funcs)...)
+	list = append(list, checkEnums(funcs)...)
+	list = append(list, checkSwitches(funcs)...)
+	list = append(list, checkRangeVars(funcs)...)
+	list = append(list, checkRaces(funcs)...)
+	return append(list, checkDeferLoops(funcs)...)
+}
@@ -0,0 +1,51 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"github.com/ZxxLang/zxx/analysis/callgraph"
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/astutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// DeferLoop 是循环中的 defer 的诊断的 ID
+const DeferLoop = "defer-loop"
+
+func init() {
+	Register(&Rule{DeferLoop, Warning, "defer in a loop runs only when the func returns"})
+}
+
+// checkDeferLoops 检查 for 循环体中的 defer 语句.
+// 登记的调用在函数返回时才执行, 每次迭代打开的资源会一直保留到循环结束之后.
+func checkDeferLoops(files []callgraph.File) []Diagnostic {
+	var list []Diagnostic
+	for _, f := range files {
+		var funcs []*ast.FuncDecl
+		loops := 0
+		astutil.Apply(f.Syntax, func(c *astutil.Cursor) bool {
+			switch n := c.Node().(type) {
+			case *ast.FuncDecl:
+				funcs = append(funcs, n)
+			case *ast.ForStmt, *ast.RangeStmt:
+				loops++
+			case *ast.GoStmt:
+				if n.Tok == token.DEFER && loops != 0 {
+					list = append(list, New(DeferLoop, f.Name, n.Pos(), "defer in loop runs only when "+funcName(funcs[len(funcs)-1])+" returns"))
+				}
+			}
+			return true
+		}, func(c *astutil.Cursor) bool {
+			switch c.Node().(type) {
+			case *ast.FuncDecl:
+				funcs = funcs[:len(funcs)-1]
+			case *ast.ForStmt, *ast.RangeStmt:
+				loops--
+			}
+			return true
+		})
+	}
+	return list
+}
@@ -0,0 +1,187 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包统一解析器和检查的诊断: 每种诊断是一个有稳定 ID 和默认严重程度的 Rule,
+// 调用者用 Config 按 ID 提高, 降低或关闭其严重程度, 源码中的 zxx:ignore 注释关闭所在语句的诊断.
+//
+// Config 可以来自项目配置文件的 lint 段, 命令行参数 id=severity, 或者编辑器的设置.
+package diag
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ZxxLang/zxx/config"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// Severity 是诊断的严重程度, Off 表示关闭.
+type Severity uint8
+
+const (
+	Off Severity = iota
+	Hint
+	Info
+	Warning
+	Error
+)
+
+var severities = [...]string{"off", "hint", "info", "warning", "error"}
+
+func (s Severity) String() string {
+	if int(s) < len(severities) {
+		return severities[s]
+	}
+	return "severity"
+}
+
+// ParseSeverity 返回名为 s 的 Severity, 例如 "warning".
+func ParseSeverity(s string) (Severity, error) {
+	for i, name := range severities {
+		if s == name {
+			return Severity(i), nil
+		}
+	}
+	return Off, errors.New("diag: unknown severity " + s)
+}
+
+// Rule 是一种诊断
+type Rule struct {
+	ID       string   // 稳定的标识, 例如 "syntax", 不随消息改变
+	Severity Severity // 默认的严重程度
+	Doc      string   // 一行说明
+}
+
+var (
+	mu    sync.RWMutex
+	rules = map[string]*Rule{}
+)
+
+// Register 注册 r, ID 重复时 panic. 产生诊断的包在 init 中注册它的 Rule.
+func Register(r *Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+	if rules[r.ID] != nil {
+		panic("diag: rule " + r.ID + " registered twice")
+	}
+	rules[r.ID] = r
+}
+
+// Lookup 返回 ID 为 id 的 Rule, 没有时返回 nil.
+func Lookup(id string) *Rule {
+	mu.RLock()
+	defer mu.RUnlock()
+	return rules[id]
+}
+
+// Rules 返回已注册的 Rule, 按 ID 排序.
+func Rules() []*Rule {
+	mu.RLock()
+	list := make([]*Rule, 0, len(rules))
+	for _, r := range rules {
+		list = append(list, r)
+	}
+	mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Diagnostic 是文件 File 中位于 Pos 的一条诊断
+type Diagnostic struct {
+	ID       string
+	File     string
+	Pos      scanner.Pos
+	Msg      string
+	Severity Severity
+	Fixes    []parser.TextEdit // 建议的修改, 可以用 parser.ApplyEdits 应用
+}
+
+// New 返回 Rule id 的诊断, 严重程度是其默认值, 未注册的 id 为 Error.
+func New(id, file string, pos scanner.Pos, msg string) Diagnostic {
+	sev := Error
+	if r := Lookup(id); r != nil {
+		sev = r.Severity
+	}
+	return Diagnostic{id, file, pos, msg, sev, nil}
+}
+
+// Config 按 ID 调整诊断的严重程度, 零值不做调整.
+type Config struct {
+	Severity map[string]Severity
+}
+
+// String 返回 Set 接受的形式, 按 ID 排序.
+func (c *Config) String() string {
+	var list []string
+	for id, sev := range c.Severity {
+		list = append(list, id+"="+sev.String())
+	}
+	sort.Strings(list)
+	return strings.Join(list, ",")
+}
+
+// Set 解析以逗号分隔的 id=severity 并加入 c, 可以用作 flag.Value.
+func (c *Config) Set(s string) error {
+	for _, item := range strings.Split(s, ",") {
+		i := strings.IndexByte(item, '=')
+		if i <= 0 {
+			return errors.New("diag: want id=severity, got " + item)
+		}
+		sev, err := ParseSeverity(item[i+1:])
+		if err != nil {
+			return err
+		}
+		if c.Severity == nil {
+			c.Severity = map[string]Severity{}
+		}
+		c.Severity[item[:i]] = sev
+	}
+	return nil
+}
+
+// FromLint 返回项目配置的 lint 段对应的 Config.
+// Disable 中的 ID 关闭, Enable 中默认关闭的 ID 为 Warning, Severity 中的值优先.
+func FromLint(l *config.Lint) (*Config, error) {
+	c := &Config{Severity: map[string]Severity{}}
+	for _, id := range l.Enable {
+		if r := Lookup(id); r != nil && r.Severity == Off {
+			c.Severity[id] = Warning
+		}
+	}
+	for _, id := range l.Disable {
+		c.Severity[id] = Off
+	}
+	for id, s := range l.Severity {
+		sev, err := ParseSeverity(s)
+		if err != nil {
+			return nil, err
+		}
+		c.Severity[id] = sev
+	}
+	return c, nil
+}
+
+// Apply 按 c 调整 list 中的严重程度, 删除关闭的和被 ignore 忽略的诊断, 结果按文件和位置排序.
+// ignore 可以为 nil.
+func (c *Config) Apply(list []Diagnostic, ignore *Ignores) []Diagnostic {
+	var out []Diagnostic
+	for _, d := range list {
+		if sev, ok := c.Severity[d.ID]; ok {
+			d.Severity = sev
+		}
+		if d.Severity != Off && !ignore.Ignored(d) {
+			out = append(out, d)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
+		}
+		return out[i].Pos < out[j].Pos
+	})
+	return out
+}
@@ -0,0 +1,362 @@
+package diag_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/config"
+	"github.com/ZxxLang/zxx/diag"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+const src = `proc main [
+	used()
+]
+
+proc used [
+]
+
+proc old [
+	// zxx:ignore unused-func
+]
+
+proc older [
+] // zxx:ignore syntax
+
+proc oldest [
+] // zxx:ignore
+`
+
+func ids(list []diag.Diagnostic) (s []string) {
+	for _, d := range list {
+		s = append(s, d.ID+" "+d.Severity.String()+" "+d.Msg)
+	}
+	return
+}
+
+func TestCheck(t *testing.T) {
+	files := []diag.File{{Name: "a.zxx", Src: []byte(src)}, {Name: "b.zxx", Src: []byte("var a = (\n--- x")}}
+	list := diag.Check(files)
+
+	var ig diag.Ignores
+	if err := ig.Add(files[0].Name, files[0].Src); err != nil {
+		t.Fatal(err)
+	}
+	if err := ig.Add(files[1].Name, files[1].Src); err == nil {
+		t.Fatal("want error")
+	}
+	got := ids(new(diag.Config).Apply(list, &ig))
+	want := []string{"unused-func warning proc older is unused"}
+	if len(got) != 2 || !reflect.DeepEqual(got[:1], want) || got[1][:13] != "syntax error " {
+		t.Fatal(got)
+	}
+
+	var c diag.Config
+	if err := c.Set("unused-func=error,syntax=off"); err != nil {
+		t.Fatal(err)
+	}
+	if c.String() != "syntax=off,unused-func=error" {
+		t.Fatal(c.String())
+	}
+	got = ids(c.Apply(list, &ig))
+	if want := []string{"unused-func error proc older is unused"}; !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+	if err := c.Set("syntax"); err == nil {
+		t.Fatal("want error")
+	}
+
+	l, err := diag.FromLint(&config.Lint{Disable: []string{diag.UnusedFunc}, Severity: map[string]string{"syntax": "hint"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = ids(l.Apply(list, nil))
+	if len(got) != 1 || got[0][:12] != "syntax hint " {
+		t.Fatal(got)
+	}
+}
+
+func TestResultCount(t *testing.T) {
+	src := `func pair out int, int [
+	out 1, 2
+]
+
+func one out int [
+	out 1, 2
+]
+
+func fwd out int, int [
+	out pair()
+]
+
+proc main [
+	var a, b = pair()
+	var c = pair()
+	a, b, c = pair()
+	fmt.print(pair() + one() + fwd())
+	pair()
+]
+`
+	var got []string
+	for _, d := range diag.Check([]diag.File{{Name: "a.zxx", Src: []byte(src)}}) {
+		got = append(got, d.Msg)
+	}
+	want := []string{
+		"out has 2 values but one returns 1 value",
+		"assignment mismatch: 1 name but pair returns 2 values",
+		"assignment mismatch: 3 names but pair returns 2 values",
+		"multiple-value pair() in single-value context",
+		"multiple-value fwd() in single-value context",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestTypeArgs(t *testing.T) {
+	src := `type Pair[K, V] [
+	K key
+	V value
+]
+
+type Ints list[int]
+
+func first[T](Pair[T, int] p, Pair[T] q) out Pair [
+	out p
+]
+
+proc main [
+	Pair[int, Pair[int, string]] p
+	first(p, p)
+]
+`
+	var got []string
+	for _, d := range diag.Check([]diag.File{{Name: "a.zxx", Src: []byte(src)}}) {
+		if d.ID == diag.TypeArgs {
+			got = append(got, d.Msg)
+		}
+	}
+	want := []string{
+		"Pair needs 2 type arguments, got 1",
+		"Pair needs 2 type arguments, got 0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestSwitchCases(t *testing.T) {
+	src := `const (
+	red = 1
+	green = 2
+	blue = 3
+)
+
+proc paint(bool ok, int c) [
+	switch ok [
+	case true:
+		c = red
+	]
+	switch c [
+	case red:
+	case blue:
+	]
+	switch c [
+	case red, green, blue:
+	]
+	switch c [
+	case red:
+	default:
+	]
+	switch c [
+	case red, c:
+	]
+]
+`
+	var got []string
+	for _, d := range diag.Check([]diag.File{{Name: "a.zxx", Src: []byte(src)}}) {
+		if d.ID == diag.SwitchCases {
+			got = append(got, d.Msg)
+		}
+	}
+	want := []string{
+		"switch is missing 1 case: false",
+		"switch is missing 1 case: green",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestEnumVariant(t *testing.T) {
+	src := `type Shape enum [
+	circle(float r)
+	rect(float w, float h)
+	empty
+]
+
+proc main(Shape s) [
+	s = Shape.circle(1.5)
+	s = Shape.rect(1.5)
+	s = Shape.square
+	switch s [
+	case Shape.circle, Shape.empty:
+	]
+]
+`
+	var got []string
+	for _, d := range diag.Check([]diag.File{{Name: "a.zxx", Src: []byte(src)}}) {
+		if d.ID != diag.UnusedFunc {
+			got = append(got, d.Msg)
+		}
+	}
+	want := []string{
+		"Shape.rect takes 2 values, got 1",
+		"Shape has no variant square",
+		"switch is missing 1 case: Shape.rect",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestRangeAssign(t *testing.T) {
+	src := `proc main(list xs) [
+	for xs as i x [
+		x = x + 1
+		i++
+		for xs as x [
+			x = 0
+		]
+	]
+	for 1..3 as i [
+		fmt.print(i)
+	]
+]
+`
+	var got []string
+	for _, d := range diag.Check([]diag.File{{Name: "a.zxx", Src: []byte(src)}}) {
+		if d.ID == diag.RangeAssign {
+			got = append(got, d.Msg)
+		}
+	}
+	want := []string{
+		"assignment to loop variable x does not affect the iteration",
+		"assignment to loop variable i does not affect the iteration",
+		"assignment to loop variable x does not affect the iteration",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestGoRace(t *testing.T) {
+	src := `var total = 0
+var done = 0
+
+proc work(int n) [
+	total = total + n
+	done = 1
+]
+
+proc count(int n) [
+	var total = n
+	total++
+]
+
+proc main [
+	go work(1)
+	go count(2)
+	fmt.print(total)
+]
+`
+	var got []string
+	for _, d := range diag.Check([]diag.File{{Name: "a.zxx", Src: []byte(src)}}) {
+		if d.ID == diag.GoRace {
+			got = append(got, d.Msg)
+		}
+	}
+	want := []string{"work started by go writes package variable total that is also used elsewhere"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestDeferLoop(t *testing.T) {
+	src := `proc main(list names) [
+	defer print('done')
+	for names as name [
+		var f = open(name)
+		defer close(f)
+	]
+]
+`
+	var got []string
+	for _, d := range diag.Check([]diag.File{{Name: "a.zxx", Src: []byte(src)}}) {
+		if d.ID == diag.DeferLoop {
+			got = append(got, d.Msg)
+		}
+	}
+	want := []string{"defer in loop runs only when main returns"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestMixedEOL(t *testing.T) {
+	src := []byte("proc main [\r\n\tprint(1)\n]\r\n")
+	list := diag.Check([]diag.File{{Name: "a.zxx", Src: src}, {Name: "b.zxx", Src: []byte("proc main [\r\n]\r\n")}})
+	if len(list) != 1 || list[0].ID != diag.MixedEOL || list[0].Msg != "mixed line endings: 2 CRLF, 1 LF" || list[0].Pos != 22 {
+		t.Fatal(list)
+	}
+	out, err := parser.ApplyEdits(src, list[0].Fixes)
+	if err != nil || string(out) != "proc main [\r\n\tprint(1)\r\n]\r\n" {
+		t.Fatalf("%q %v", out, err)
+	}
+
+	var b strings.Builder
+	if err := (&diag.Printer{Format: diag.JSON}).Print(&b, list); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"ID":"mixed-eol","Severity":"warning","File":"a.zxx","Offset":22,"Msg":"mixed line endings: 2 CRLF, 1 LF","Fixes":[{"Offset":22,"End":23,"NewText":"\r\n"}]}` + "\n"
+	if b.String() != want {
+		t.Fatal(b.String())
+	}
+}
+
+func TestTopPlaceholder(t *testing.T) {
+	src := "Intro\nproc main [\n]\nNote\nvar a = 1\n\tindented\n"
+	var got []string
+	for _, d := range diag.Check([]diag.File{{Name: "a.zxx", Src: []byte(src)}}) {
+		if d.ID == diag.TopPlaceholder {
+			got = append(got, src[d.Pos:d.Pos+4])
+		}
+	}
+	if want := []string{"Note"}; !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	src := []byte("var s = \"a {1}\"\n")
+	files := []diag.File{
+		{Name: "a.zxx", Src: src, Version: "1"},
+		{Name: "b.zxx", Src: append([]byte("use \"-version=2\"\n"), src...), Version: "1"},
+		{Name: "c.zxx", Src: src},
+		{Name: "d.zxx", Src: []byte("var a = 1\n"), Version: "9"},
+	}
+	var got []string
+	for _, d := range diag.Check(files) {
+		if d.ID == diag.Version {
+			got = append(got, d.File+" "+d.Msg)
+		}
+	}
+	want := []string{
+		"a.zxx parser: string interpolation requires language version 2, file uses version 1",
+		`d.zxx parser: unknown language version "9"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+}
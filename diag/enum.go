@@ -0,0 +1,86 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/analysis/callgraph"
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/astutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// EnumVariant 是枚举变体不存在或者关联值个数不符的诊断的 ID
+const EnumVariant = "enum-variant"
+
+func init() {
+	Register(&Rule{EnumVariant, Error, "enum has no such variant or the variant is built with the wrong number of values"})
+}
+
+// enumVariants 返回包 files 中的枚举, 以类型名和变体名索引
+func enumVariants(files []callgraph.File) map[string]map[string]*ast.Variant {
+	enums := map[string]map[string]*ast.Variant{}
+	for _, f := range files {
+		for _, d := range f.Syntax.Decls {
+			if t, ok := d.(*ast.TypeDecl); ok && t.Enum != 0 {
+				variants := map[string]*ast.Variant{}
+				for _, v := range t.Variants {
+					variants[v.Name.Name.Source] = v
+				}
+				enums[t.Name.Name.Source] = variants
+			}
+		}
+	}
+	return enums
+}
+
+// checkEnums 检查 Type.Name 形式的变体引用和构造 Type.Name(values).
+func checkEnums(files []callgraph.File) []Diagnostic {
+	enums := enumVariants(files)
+	var list []Diagnostic
+	for _, f := range files {
+		report := func(pos ast.Syntax, msg string) {
+			list = append(list, New(EnumVariant, f.Name, pos.Pos(), msg))
+		}
+		// lookup 返回 id 引用的变体, id 不是枚举的成员时返回 false
+		lookup := func(id *ast.Ident) (*ast.Variant, bool) {
+			if id.Name.Tok != token.MEMBER {
+				return nil, false
+			}
+			i := strings.IndexByte(id.Name.Source, '.')
+			variants, ok := enums[id.Name.Source[:i]]
+			if !ok {
+				return nil, false
+			}
+			v := variants[id.Name.Source[i+1:]]
+			if v == nil {
+				report(id, id.Name.Source[:i]+" has no variant "+id.Name.Source[i+1:])
+			}
+			return v, true
+		}
+		checked := map[*ast.Ident]bool{}
+		astutil.Apply(f.Syntax, func(c *astutil.Cursor) bool {
+			switch n := c.Node().(type) {
+			case *ast.CallExpr:
+				id, ok := n.Fun.(*ast.Ident)
+				if !ok {
+					break
+				}
+				checked[id] = true
+				if v, ok := lookup(id); ok && v != nil && len(n.Args) != len(v.Params) {
+					report(n, id.Name.Source+" takes "+plural(len(v.Params), "value")+", got "+strconv.Itoa(len(n.Args)))
+				}
+			case *ast.Ident:
+				if !checked[n] {
+					lookup(n)
+				}
+			}
+			return true
+		}, nil)
+	}
+	return list
+}
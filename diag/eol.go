@@ -0,0 +1,41 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ZxxLang/zxx/format"
+)
+
+// MixedEOL 是混用换行的诊断的 ID
+const MixedEOL = "mixed-eol"
+
+func init() {
+	Register(&Rule{MixedEOL, Warning, "file mixes LF, CRLF or CR line endings"})
+}
+
+// checkEOL 检查 f 是否混用了多种换行. 诊断位于第一个少数派换行,
+// Fixes 把全部换行统一为多数派, 同 zxxfmt -eol.
+func checkEOL(f File) []Diagnostic {
+	n := format.CountEndings(f.Src)
+	if !n.Mixed() {
+		return nil
+	}
+	var counts []string
+	for _, c := range []struct {
+		name string
+		n    int
+	}{{"CRLF", n.CRLF}, {"LF", n.LF}, {"CR", n.CR}} {
+		if c.n != 0 {
+			counts = append(counts, fmt.Sprint(c.n, " ", c.name))
+		}
+	}
+	fixes := format.EOLEdits(f.Src, n.Majority())
+	d := New(MixedEOL, f.Name, fixes[0].Pos, "mixed line endings: "+strings.Join(counts, ", "))
+	d.Fixes = fixes
+	return []Diagnostic{d}
+}
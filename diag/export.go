@@ -0,0 +1,140 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/index"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Unexported 是引用其它包未公开的名字的诊断的 ID
+const Unexported = "unexported"
+
+func init() {
+	Register(&Rule{Unexported, Error, "reference to a member of another package that is not declared pub"})
+}
+
+// Loader 返回 use 路径 path 对应的包的文件, 找不到时返回 nil, 此时不检查对它的引用.
+type Loader func(path string) ([]File, error)
+
+// CheckImports 检查包 files 对 use 引入的包的成员的引用, 只能引用 pub 声明的顶层名字.
+// 引入 Go 包的 use 不检查. 每个路径只加载一次, 返回 load 的第一个错误.
+func CheckImports(files []File, load Loader) ([]Diagnostic, error) {
+	pkgs := map[string]map[string]bool{}
+	var list []Diagnostic
+	for _, f := range files {
+		sf, _ := parser.ParseSyntax(f.Src)
+		if sf == nil {
+			continue
+		}
+		// paths 是 use 声明的名字的声明处到路径
+		paths := map[scanner.Pos]string{}
+		for _, d := range sf.Decls {
+			d, ok := d.(*ast.GenDecl)
+			if !ok || d.Tok != token.USE {
+				continue
+			}
+			for _, spec := range d.Specs {
+				lit, ok := first(spec.Values).(*ast.BasicLit)
+				if !ok || len(lit.Value.Source) <= 2 {
+					continue
+				}
+				path := lit.Value.Source[1 : len(lit.Value.Source)-1]
+				if strings.HasPrefix(path, ast.GoImportPrefix) {
+					continue
+				}
+				if len(spec.Names) != 0 {
+					paths[spec.Names[0].Name.Pos] = path
+				} else {
+					paths[lit.Value.Pos] = path
+				}
+			}
+		}
+
+		for _, id := range index.Idents(sf) {
+			path, ok := paths[id.Def]
+			if id.Scope != index.File || id.Decl || !ok {
+				continue
+			}
+			names, ok := pkgs[path]
+			if !ok {
+				pkg, err := load(path)
+				if err != nil {
+					return list, err
+				}
+				names = declared(pkg)
+				pkgs[path] = names
+			}
+			member := memberAt(f.Src, id)
+			if pub, ok := names[member]; !ok || pub {
+				continue
+			}
+			msg := "cannot refer to unexported member " + id.Name + "." + member
+			var exports []string
+			for name, pub := range names {
+				if pub {
+					exports = append(exports, name)
+				}
+			}
+			sort.Strings(exports)
+			if s, ok := parser.SuggestName(member, exports); ok {
+				msg += ", did you mean " + id.Name + "." + s + "?"
+			}
+			list = append(list, New(Unexported, f.Name, id.Pos, msg))
+		}
+	}
+	return list, nil
+}
+
+// declared 返回包 files 的顶层名字是否为 pub
+func declared(files []File) map[string]bool {
+	names := map[string]bool{}
+	for _, f := range files {
+		sf, _ := parser.ParseSyntax(f.Src)
+		if sf == nil {
+			continue
+		}
+		for _, id := range index.Idents(sf) {
+			if id.Decl && id.Scope == index.Package {
+				names[id.Name] = names[id.Name] || id.Pub
+			}
+		}
+	}
+	return names
+}
+
+// memberAt 返回 src 中位于 id 的名字之后的成员名, 例如 m.helper.x 中的 helper
+func memberAt(src []byte, id index.Ident) string {
+	i := int(id.Pos) + len(id.Name)
+	if i >= len(src) || src[i] != '.' {
+		return ""
+	}
+	s := src[i+1:]
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRune(s[n:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		n += size
+	}
+	return string(s[:n])
+}
+
+// first 返回 list 的第一个表达式, list 为空时返回 nil
+func first(list []ast.Expression) ast.Expression {
+	if len(list) == 0 {
+		return nil
+	}
+	return list[0]
+}
@@ -0,0 +1,215 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ZxxLang/zxx/diagfmt"
+	"github.com/ZxxLang/zxx/platform"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Format 是诊断的输出格式
+type Format string
+
+const (
+	Text   Format = "text"   // 每行 file:line:column: severity: msg [id]
+	JSON   Format = "json"   // 每行一个 JSON 对象, 字段同 jsonDiagnostic
+	SARIF  Format = "sarif"  // SARIF 2.1.0 日志, 可上传到 GitHub code scanning
+	Pretty Format = "pretty" // 同 Text, 之后是源码片段, 参见 diagfmt
+)
+
+// ParseFormat 返回名为 s 的 Format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case Text, JSON, SARIF, Pretty:
+		return f, nil
+	}
+	return "", errors.New("diag: unknown format " + s)
+}
+
+// Printer 以 Format 输出诊断.
+// Files 按文件名提供行列信息, 不在其中的文件只输出字节偏移量.
+// Pretty 格式由 Renderer 按 Sources 中的源码渲染, 没有源码的文件同 Text 格式.
+type Printer struct {
+	Format   Format
+	Files    map[string]*scanner.File
+	Sources  map[string][]byte
+	Renderer diagfmt.Renderer
+}
+
+// position 返回 d 的行列, 未知时行号为 0.
+func (p *Printer) position(d Diagnostic) token.Position {
+	if f := p.Files[d.File]; f != nil {
+		return f.Position(d.Pos)
+	}
+	return token.Position{Offset: int(d.Pos)}
+}
+
+// jsonDiagnostic 是 JSON 格式的一行
+type jsonDiagnostic struct {
+	ID       string
+	Severity string
+	File     string
+	Line     int `json:",omitempty"`
+	Column   int `json:",omitempty"`
+	Offset   int
+	Msg      string
+	Fixes    []jsonEdit `json:",omitempty"`
+}
+
+// jsonEdit 是 JSON 格式中的一个建议修改, 用字节偏移量表示替换的范围
+type jsonEdit struct {
+	Offset  int
+	End     int
+	NewText string
+}
+
+// Print 在 w 上输出 list.
+func (p *Printer) Print(w io.Writer, list []Diagnostic) error {
+	switch p.Format {
+	case Text, "":
+		for _, d := range list {
+			pos := p.position(d)
+			at := d.File + ":+" + fmt.Sprint(pos.Offset)
+			if pos.IsValid() {
+				at = fmt.Sprintf("%s:%d:%d", d.File, pos.Line, pos.Column)
+			}
+			if _, err := fmt.Fprintf(w, "%s: %v: %s [%s]\n", at, d.Severity, d.Msg, d.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	case JSON:
+		enc := json.NewEncoder(w)
+		for _, d := range list {
+			pos := p.position(d)
+			var fixes []jsonEdit
+			for _, e := range d.Fixes {
+				fixes = append(fixes, jsonEdit{int(e.Pos), int(e.End), e.NewText})
+			}
+			if err := enc.Encode(jsonDiagnostic{d.ID, d.Severity.String(), d.File, pos.Line, pos.Column, pos.Offset, d.Msg, fixes}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Pretty:
+		for _, d := range list {
+			f, src := p.Files[d.File], p.Sources[d.File]
+			if f == nil || src == nil {
+				if err := (&Printer{Format: Text}).Print(w, []Diagnostic{d}); err != nil {
+					return err
+				}
+				continue
+			}
+			m := diagfmt.Message{Pos: d.Pos, Severity: d.Severity.String(), Msg: d.Msg + " [" + d.ID + "]"}
+			if err := p.Renderer.Render(w, f, src, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	case SARIF:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p.sarif(list))
+	}
+	return errors.New("diag: unknown format " + string(p.Format))
+}
+
+// 以下是 SARIF 2.1.0 中用到的部分, 参见 https://docs.oasis-open.org/sarif/sarif/v2.1.0/
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region sarifRegion `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+	ByteOffset  int `json:"byteOffset"`
+}
+
+// level 返回 SARIF 中对应的 level, Hint 和 Info 都是 note.
+func (s Severity) level() string {
+	switch s {
+	case Off:
+		return "none"
+	case Hint, Info:
+		return "note"
+	}
+	return s.String()
+}
+
+// sarif 返回 list 的 SARIF 日志, rules 包含全部已注册的 Rule.
+func (p *Printer) sarif(list []Diagnostic) *sarifLog {
+	driver := sarifDriver{Name: "zxx", InformationURI: "https://github.com/ZxxLang/zxx"}
+	for _, r := range Rules() {
+		x := sarifRule{ID: r.ID, ShortDescription: sarifMessage{r.Doc}}
+		x.DefaultConfiguration.Level = r.Severity.level()
+		driver.Rules = append(driver.Rules, x)
+	}
+	results := []sarifResult{}
+	for _, d := range list {
+		pos := p.position(d)
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = platform.Local.URI(d.File)
+		loc.PhysicalLocation.Region = sarifRegion{pos.Line, pos.Column, pos.Offset}
+		results = append(results, sarifResult{d.ID, d.Severity.level(), sarifMessage{d.Msg}, []sarifLocation{loc}})
+	}
+	return &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{sarifTool{driver}, results}},
+	}
+}
@@ -0,0 +1,78 @@
+package diag_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/diag"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+func TestPrint(t *testing.T) {
+	src := []byte("proc main [\n]\n\nproc old [\n]\n")
+	list := diag.Check([]diag.File{{Name: "dir/a.zxx", Src: src}})
+	list = append(list, diag.Diagnostic{ID: diag.Syntax, File: "b.zxx", Pos: 3, Msg: "bad", Severity: diag.Info})
+	p := &diag.Printer{Format: diag.Text, Files: map[string]*scanner.File{
+		"dir/a.zxx": scanner.NewFileSet().AddFile("dir/a.zxx", src),
+	}}
+
+	var out strings.Builder
+	if err := p.Print(&out, list); err != nil {
+		t.Fatal(err)
+	}
+	want := "dir/a.zxx:4:6: warning: proc old is unused [unused-func]\nb.zxx:+3: info: bad [syntax]\n"
+	if out.String() != want {
+		t.Fatalf("%q", out.String())
+	}
+
+	out.Reset()
+	p.Format = diag.JSON
+	if err := p.Print(&out, list); err != nil {
+		t.Fatal(err)
+	}
+	want = `{"ID":"unused-func","Severity":"warning","File":"dir/a.zxx","Line":4,"Column":6,"Offset":20,"Msg":"proc old is unused"}` + "\n" +
+		`{"ID":"syntax","Severity":"info","File":"b.zxx","Offset":3,"Msg":"bad"}` + "\n"
+	if out.String() != want {
+		t.Fatalf("%s", out.String())
+	}
+
+	out.Reset()
+	p.Format = diag.SARIF
+	if err := p.Print(&out, list); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct{ ID string }
+				}
+			}
+			Results []struct {
+				RuleID, Level string
+				Locations     []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine, StartColumn int }
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(out.String()), &log); err != nil {
+		t.Fatal(err)
+	}
+	run := log.Runs[0]
+	loc := run.Results[0].Locations[0].PhysicalLocation
+	if log.Version != "2.1.0" || len(run.Tool.Driver.Rules) < 2 || len(run.Results) != 2 ||
+		run.Results[0].Level != "warning" || run.Results[1].Level != "note" ||
+		loc.ArtifactLocation.URI != "dir/a.zxx" || loc.Region.StartLine != 4 || loc.Region.StartColumn != 6 {
+		t.Fatal(out.String())
+	}
+
+	if _, err := diag.ParseFormat("xml"); err == nil {
+		t.Fatal("want error")
+	}
+}
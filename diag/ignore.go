@@ -0,0 +1,132 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/astutil"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// IgnorePrefix 是忽略诊断的注释的前缀, 之后是以空白分隔的 ID, 没有 ID 时忽略全部诊断.
+const IgnorePrefix = "zxx:ignore"
+
+// Ignores 是各个文件中的 zxx:ignore 注释忽略的范围, 零值可用.
+//
+// 与 ast.CommentMap 相同, 注释属于它之前的语句或声明, 即在同一行的尾部或者之后的注释行.
+// 块的开始处, 块中首个语句之前的注释属于拥有该块的语句或声明, 例如整个 proc.
+type Ignores struct {
+	files map[string][]ignored
+}
+
+// ignored 是 [from, to) 中被忽略的 ID
+type ignored struct {
+	from, to scanner.Pos
+	ids      []string
+}
+
+// Add 记录文件 file 的源码 src 中的 zxx:ignore 注释, 只返回扫描错误.
+func (ig *Ignores) Add(file string, src []byte) error {
+	syms, err := parser.Fast(src, nil)
+	if err != nil {
+		return err
+	}
+	var list []ignored
+	for _, sym := range syms {
+		// 注释是 COMMENT 或者 PLACEHOLDER, 后者可能包含行首的缩进
+		text := strings.TrimSpace(sym.Source)
+		if sym.Tok != token.COMMENT && sym.Tok != token.PLACEHOLDER || !strings.HasPrefix(text, "//") {
+			continue
+		}
+		text = strings.TrimSpace(text[2:])
+		if text == IgnorePrefix || strings.HasPrefix(text, IgnorePrefix+" ") {
+			list = append(list, ignored{from: sym.Pos, ids: strings.Fields(text[len(IgnorePrefix):])})
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	// 语句和声明, 以及块所属的语句或声明
+	sf, _ := parser.ParseSyntaxSymbols(syms)
+	var stmts []ast.Syntax
+	owner := map[*ast.BlockStmt]ast.Syntax{}
+	astutil.Apply(sf, func(c *astutil.Cursor) bool {
+		switch n := c.Node().(type) {
+		case ast.Expression:
+			return false
+		case *ast.SourceFile:
+		case *ast.BlockStmt:
+			owner[n] = c.Parent()
+			stmts = append(stmts, n)
+		default:
+			stmts = append(stmts, n)
+		}
+		return true
+	}, nil)
+
+	out := list[:0]
+	for _, x := range list {
+		if n := attached(x.from, stmts, owner); n != nil {
+			x.from, x.to = n.Pos(), n.End()
+			out = append(out, x)
+		}
+	}
+	if ig.files == nil {
+		ig.files = map[string][]ignored{}
+	}
+	ig.files[file] = append(ig.files[file], out...)
+	return nil
+}
+
+// attached 返回位于 pos 的注释所属的语句或声明, 没有时返回 nil.
+func attached(pos scanner.Pos, stmts []ast.Syntax, owner map[*ast.BlockStmt]ast.Syntax) ast.Syntax {
+	var prev ast.Syntax // 之前结束最晚的最外层语句
+	var block *ast.BlockStmt
+	for _, n := range stmts {
+		if n.End() <= pos {
+			if prev == nil || n.End() > prev.End() || n.End() == prev.End() && n.Pos() < prev.Pos() {
+				prev = n
+			}
+		} else if b, ok := n.(*ast.BlockStmt); ok && b.Lbrack < pos && (block == nil || b.Lbrack > block.Lbrack) {
+			block = b
+		}
+	}
+	if block != nil && (prev == nil || prev.End() <= block.Lbrack) {
+		if n := owner[block]; n != nil {
+			if _, ok := n.(*ast.SourceFile); !ok {
+				return n
+			}
+		}
+		return block
+	}
+	return prev
+}
+
+// Ignored 返回 d 是否被忽略, ig 可以为 nil.
+func (ig *Ignores) Ignored(d Diagnostic) bool {
+	if ig == nil {
+		return false
+	}
+	for _, x := range ig.files[d.File] {
+		if x.from <= d.Pos && d.Pos < x.to && (len(x.ids) == 0 || contains(x.ids, d.ID)) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// TopPlaceholder 是声明之后的顶层占位的诊断的 ID
+const TopPlaceholder = "top-placeholder"
+
+func init() {
+	Register(&Rule{TopPlaceholder, Info, "top-level text after a declaration is a placeholder, older tools scanned it as code"})
+}
+
+// checkPlaceholders 报告首个声明之后的顶层占位, 用于迁移.
+// parser.Fast 以前只识别源码开头的占位, 之后的顶层文本被当做代码扫描, 例如被 zxxfmt -literals 改写.
+// 现在它按 parser.Lexer 的规则识别为占位, 与 parser.Parse 一致.
+func checkPlaceholders(f File) []Diagnostic {
+	var list []Diagnostic
+	lex := parser.NewLexer(f.Src)
+	for first := true; ; first = false {
+		sym, err := lex.Next()
+		if sym.Tok == token.EOF {
+			return list
+		}
+		if sym.Tok == token.PLACEHOLDER && err == nil && !first {
+			list = append(list, New(TopPlaceholder, f.Name, sym.Pos, "top-level text is a placeholder, not code"))
+		}
+	}
+}
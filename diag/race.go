@@ -0,0 +1,136 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"sort"
+
+	"github.com/ZxxLang/zxx/analysis/callgraph"
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/astutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// GoRace 是 go 启动的函数写包级变量的诊断的 ID
+const GoRace = "go-race"
+
+func init() {
+	Register(&Rule{GoRace, Warning, "func started by go writes a package variable that other code also uses"})
+}
+
+// checkRaces 检查 go f(...) 启动的包中函数 f 是否直接给包级 var, static 变量赋值.
+// 同一个变量在 f 之外还被读写时, 没有同步就是数据竞争. 通道不是变量, 不在此列.
+func checkRaces(files []callgraph.File) []Diagnostic {
+	vars := map[string]bool{}
+	funcs := map[string]*ast.FuncDecl{}
+	for _, f := range files {
+		for _, d := range f.Syntax.Decls {
+			switch d := d.(type) {
+			case *ast.GenDecl:
+				if d.Tok == token.VAR || d.Tok == token.STATIC {
+					for _, spec := range d.Specs {
+						for _, id := range spec.Names {
+							vars[id.Name.Source] = true
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if d.Name != nil {
+					funcs[d.Name.Name.Source] = d
+				}
+			}
+		}
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+
+	// uses 是包级变量名到引用它的函数
+	uses := map[string]map[*ast.FuncDecl]bool{}
+	for _, fn := range funcs {
+		for name := range globals(fn, vars, false) {
+			if uses[name] == nil {
+				uses[name] = map[*ast.FuncDecl]bool{}
+			}
+			uses[name][fn] = true
+		}
+	}
+
+	var list []Diagnostic
+	for _, f := range files {
+		astutil.Apply(f.Syntax, func(c *astutil.Cursor) bool {
+			s, ok := c.Node().(*ast.GoStmt)
+			if !ok || s.Tok != token.GO {
+				return true
+			}
+			call, ok := s.Call.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			id, ok := call.Fun.(*ast.Ident)
+			if !ok || funcs[id.Name.Source] == nil {
+				return true
+			}
+			fn := funcs[id.Name.Source]
+			var names []string
+			for name := range globals(fn, vars, true) {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if len(uses[name]) > 1 {
+					list = append(list, New(GoRace, f.Name, s.Pos(), id.Name.Source+" started by go writes package variable "+name+" that is also used elsewhere"))
+				}
+			}
+			return true
+		}, nil)
+	}
+	return list
+}
+
+// globals 返回 fn 中引用的包级变量, written 为真时只返回被赋值的.
+// 参数和函数体中用 var, static 声明的同名局部变量不算.
+func globals(fn *ast.FuncDecl, vars map[string]bool, written bool) map[string]bool {
+	found := map[string]bool{}
+	if fn.Body == nil {
+		return found
+	}
+	locals := map[string]bool{}
+	for _, p := range fn.Params {
+		if p.Name != nil {
+			locals[p.Name.Name.Source] = true
+		}
+	}
+	add := func(x ast.Expression) {
+		if id, ok := x.(*ast.Ident); ok && id.Name.Tok == token.IDENT && vars[id.Name.Source] {
+			found[id.Name.Source] = true
+		}
+	}
+	astutil.Apply(fn.Body, func(c *astutil.Cursor) bool {
+		switch n := c.Node().(type) {
+		case *ast.GenDecl:
+			for _, spec := range n.Specs {
+				for _, id := range spec.Names {
+					locals[id.Name.Source] = true
+				}
+			}
+		case *ast.AssignStmt:
+			if written {
+				for _, x := range n.Lhs {
+					add(x)
+				}
+			}
+		case *ast.Ident:
+			if !written {
+				add(n)
+			}
+		}
+		return true
+	}, nil)
+	for name := range locals {
+		delete(found, name)
+	}
+	return found
+}
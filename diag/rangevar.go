@@ -0,0 +1,55 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"github.com/ZxxLang/zxx/analysis/callgraph"
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/astutil"
+)
+
+// RangeAssign 是给遍历的循环变量赋值的诊断的 ID
+const RangeAssign = "range-assign"
+
+func init() {
+	Register(&Rule{RangeAssign, Warning, "assignment to a for ... as variable does not affect the iteration"})
+}
+
+// checkRangeVars 检查 for X as Key Value 的循环体中对 Key, Value 的赋值.
+// 每次迭代重新绑定循环变量, 赋值只在本次迭代中有效, 通常是错误.
+func checkRangeVars(files []callgraph.File) []Diagnostic {
+	var list []Diagnostic
+	for _, f := range files {
+		astutil.Apply(f.Syntax, func(c *astutil.Cursor) bool {
+			r, ok := c.Node().(*ast.RangeStmt)
+			if !ok {
+				return true
+			}
+			vars := map[string]bool{r.Key.Name.Source: true}
+			if r.Value != nil {
+				vars[r.Value.Name.Source] = true
+			}
+			astutil.Apply(r.Body, func(c *astutil.Cursor) bool {
+				switch n := c.Node().(type) {
+				case *ast.RangeStmt:
+					// 内层循环重新声明的同名变量不是外层的
+					delete(vars, n.Key.Name.Source)
+					if n.Value != nil {
+						delete(vars, n.Value.Name.Source)
+					}
+				case *ast.AssignStmt:
+					for _, x := range n.Lhs {
+						if id, ok := x.(*ast.Ident); ok && vars[id.Name.Source] {
+							list = append(list, New(RangeAssign, f.Name, id.Pos(), "assignment to loop variable "+id.Name.Source+" does not affect the iteration"))
+						}
+					}
+				}
+				return true
+			}, nil)
+			return true
+		}, nil)
+	}
+	return list
+}
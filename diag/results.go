@@ -0,0 +1,117 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"strconv"
+
+	"github.com/ZxxLang/zxx/analysis/callgraph"
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/astutil"
+)
+
+// ResultCount 是结果个数不符的诊断的 ID
+const ResultCount = "result-count"
+
+func init() {
+	Register(&Rule{ResultCount, Error, "number of values does not match the results of a func"})
+}
+
+// checkResults 检查包 files 中 out 语句, 解构赋值和调用的结果个数.
+// 只检查按名字直接调用包中声明了结果类型的 func, proc.
+func checkResults(files []callgraph.File) []Diagnostic {
+	results := map[string]int{}
+	for _, f := range files {
+		for _, d := range f.Syntax.Decls {
+			if fn, ok := d.(*ast.FuncDecl); ok && fn.Name != nil && len(fn.Results) != 0 {
+				results[fn.Name.Name.Source] = len(fn.ResultTypes())
+			}
+		}
+	}
+	// count 返回 x 是包中函数的调用时的结果个数, 否则返回 -1
+	count := func(x ast.Expression) (string, int) {
+		if call, ok := x.(*ast.CallExpr); ok {
+			if id, ok := call.Fun.(*ast.Ident); ok {
+				if n, ok := results[id.Name.Source]; ok {
+					return id.Name.Source, n
+				}
+			}
+		}
+		return "", -1
+	}
+
+	var list []Diagnostic
+	for _, f := range files {
+		report := func(pos ast.Syntax, msg string) {
+			list = append(list, New(ResultCount, f.Name, pos.Pos(), msg))
+		}
+		// multi 是可以有多个结果的调用, 即解构赋值唯一的右侧和 out 唯一的值
+		multi := map[ast.Expression]bool{}
+		assign := func(names int, values []ast.Expression) {
+			if len(values) != 1 {
+				return
+			}
+			name, n := count(values[0])
+			if n >= 0 && n != names {
+				report(values[0], "assignment mismatch: "+plural(names, "name")+" but "+name+" returns "+plural(n, "value"))
+			}
+			multi[values[0]] = true
+		}
+		var funcs []*ast.FuncDecl
+		astutil.Apply(f.Syntax, func(c *astutil.Cursor) bool {
+			switch n := c.Node().(type) {
+			case *ast.FuncDecl:
+				funcs = append(funcs, n)
+			case *ast.ExprStmt:
+				multi[n.X] = true
+			case *ast.AssignStmt:
+				assign(len(n.Lhs), n.Rhs)
+			case *ast.ValueSpec:
+				if len(n.Values) != 0 {
+					assign(len(n.Names), n.Values)
+				}
+			case *ast.OutStmt:
+				fn := funcs[len(funcs)-1]
+				want := len(fn.ResultTypes())
+				if len(n.Results) == 1 {
+					if _, got := count(n.Results[0]); got == want {
+						multi[n.Results[0]] = true
+						break
+					}
+				}
+				if want != 0 && len(n.Results) != want {
+					report(n, "out has "+plural(len(n.Results), "value")+" but "+funcName(fn)+" returns "+plural(want, "value"))
+				}
+			case *ast.CallExpr:
+				if name, k := count(n); k > 1 && !multi[n] {
+					report(n, "multiple-value "+name+"() in single-value context")
+				}
+			}
+			return true
+		}, func(c *astutil.Cursor) bool {
+			if _, ok := c.Node().(*ast.FuncDecl); ok {
+				funcs = funcs[:len(funcs)-1]
+			}
+			return true
+		})
+	}
+	return list
+}
+
+// funcName 返回 fn 的名字, 匿名时为 fn.Tok
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Name == nil {
+		return fn.Tok.String()
+	}
+	return fn.Name.Name.Source
+}
+
+// plural 返回 "n word" 或者 "n words"
+func plural(n int, word string) string {
+	if n != 1 {
+		word += "s"
+	}
+	return strconv.Itoa(n) + " " + word
+}
@@ -0,0 +1,107 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"strings"
+
+	"github.com/ZxxLang/zxx/analysis/callgraph"
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/astutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// SwitchCases 是 switch 没有覆盖全部取值的诊断的 ID
+const SwitchCases = "switch-cases"
+
+func init() {
+	Register(&Rule{SwitchCases, Warning, "switch without default does not cover every value of a bool, const group or enum"})
+}
+
+// checkSwitches 检查没有 default 的 switch 是否覆盖了全部取值.
+// 没有类型信息, 取值集合由 case 推断: 全是 true, false 时是 bool,
+// 全是包中同一个分组 const (...) 声明的名字时是该分组,
+// 全是同一个枚举的 Type.Name 时是它的变体.
+func checkSwitches(files []callgraph.File) []Diagnostic {
+	// group 是 const 名字或者枚举变体所在分组的全部名字
+	group := map[string][]string{}
+	for _, f := range files {
+		for _, d := range f.Syntax.Decls {
+			var names []string
+			switch d := d.(type) {
+			case *ast.GenDecl:
+				if d.Tok != token.CONST || d.Lparen == 0 {
+					continue
+				}
+				for _, spec := range d.Specs {
+					for _, id := range spec.Names {
+						names = append(names, id.Name.Source)
+					}
+				}
+			case *ast.TypeDecl:
+				for _, v := range d.Variants {
+					names = append(names, d.Name.Name.Source+"."+v.Name.Name.Source)
+				}
+			}
+			for _, name := range names {
+				group[name] = names
+			}
+		}
+	}
+
+	var list []Diagnostic
+	for _, f := range files {
+		astutil.Apply(f.Syntax, func(c *astutil.Cursor) bool {
+			if s, ok := c.Node().(*ast.SwitchStmt); ok && s.Tag != nil {
+				if missing := uncovered(s, group); len(missing) != 0 {
+					list = append(list, New(SwitchCases, f.Name, s.Pos(), "switch is missing "+plural(len(missing), "case")+": "+strings.Join(missing, ", ")))
+				}
+			}
+			return true
+		}, nil)
+	}
+	return list
+}
+
+// uncovered 返回 s 的取值集合中没有 case 的值, 有 default 或者无法推断集合时返回 nil
+func uncovered(s *ast.SwitchStmt, group map[string][]string) []string {
+	var (
+		all     []string
+		covered = map[string]bool{}
+	)
+	for _, st := range s.Body.List {
+		cc, ok := st.(*ast.CaseClause)
+		if !ok {
+			continue
+		}
+		if cc.Tok == token.DEFAULT {
+			return nil
+		}
+		for _, x := range cc.List {
+			var name string
+			var values []string
+			switch x := x.(type) {
+			case *ast.BasicLit:
+				if x.Value.Tok == token.VALBOOL {
+					name, values = x.Value.Source, []string{"true", "false"}
+				}
+			case *ast.Ident:
+				name, values = x.Name.Source, group[x.Name.Source]
+			}
+			if values == nil || all != nil && values[0] != all[0] {
+				return nil
+			}
+			all = values
+			covered[name] = true
+		}
+	}
+	var missing []string
+	for _, v := range all {
+		if !covered[v] {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
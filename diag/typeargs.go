@@ -0,0 +1,96 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"strconv"
+
+	"github.com/ZxxLang/zxx/analysis/callgraph"
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/astutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// TypeArgs 是类型实参个数不符的诊断的 ID
+const TypeArgs = "type-args"
+
+func init() {
+	Register(&Rule{TypeArgs, Error, "number of type arguments does not match the type parameters"})
+}
+
+// checkTypeArgs 检查包 files 的类型 Token 中泛型类型的实例化, 例如 Pair[int, string].
+// 实参是紧随类型名字的 [ ] 中逗号分隔的部分, 只检查包中声明的泛型类型.
+func checkTypeArgs(files []callgraph.File) []Diagnostic {
+	params := map[string]int{}
+	for _, f := range files {
+		for _, d := range f.Syntax.Decls {
+			if t, ok := d.(*ast.TypeDecl); ok && t.TypeParams != nil {
+				params[t.Name.Name.Source] = len(t.TypeParams.List)
+			}
+		}
+	}
+
+	var list []Diagnostic
+	for _, f := range files {
+		check := func(syms []ast.Symbol) {
+			for i, sym := range syms {
+				want, ok := params[sym.Source]
+				if !ok || sym.Tok != token.IDENT {
+					continue
+				}
+				got := 0
+				if i+1 < len(syms) && syms[i+1].Source == "[" && syms[i+1].Pos == sym.Pos.Offset(len(sym.Source)) {
+					got = typeArgs(syms[i+1:])
+				}
+				if got != want {
+					list = append(list, New(TypeArgs, f.Name, sym.Pos, sym.Source+" needs "+plural(want, "type argument")+", got "+strconv.Itoa(got)))
+				}
+			}
+		}
+		astutil.Apply(f.Syntax, func(c *astutil.Cursor) bool {
+			switch n := c.Node().(type) {
+			case *ast.GenDecl:
+				check(n.Type)
+			case *ast.ValueSpec:
+				check(n.Type)
+			case *ast.Field:
+				check(n.Type)
+			case *ast.TypeDecl:
+				check(n.Type)
+			}
+			return true
+		}, func(c *astutil.Cursor) bool {
+			// 结果在参数之后
+			if fn, ok := c.Node().(*ast.FuncDecl); ok {
+				check(fn.Results)
+			}
+			return true
+		})
+	}
+	return list
+}
+
+// typeArgs 返回以 [ 开头的 syms 中, 与之配对的 ] 之前逗号分隔的部分的个数
+func typeArgs(syms []ast.Symbol) int {
+	if len(syms) > 1 && syms[1].Tok == token.RIGHT {
+		return 0
+	}
+	depth, n := 0, 1
+	for _, sym := range syms {
+		switch sym.Tok {
+		case token.LEFT:
+			depth++
+		case token.RIGHT:
+			if depth--; depth == 0 {
+				return n
+			}
+		case token.COMMA:
+			if depth == 1 {
+				n++
+			}
+		}
+	}
+	return n
+}
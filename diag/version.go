@@ -0,0 +1,45 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import "github.com/ZxxLang/zxx/parser"
+
+// Version 是语言版本的诊断的 ID
+const Version = "version"
+
+func init() {
+	Register(&Rule{Version, Error, "syntax requires a newer language version, or the version is unknown"})
+}
+
+// checkVersion 检查 f 的语言版本是否合法, 以及是否使用了该版本不支持的语法, 参见 parser.CheckVersion.
+// 版本取自文件头部的版本指示, 没有时是 f.Version.
+func checkVersion(f File) []Diagnostic {
+	v, ok, err := parser.HeaderVersion(f.Src)
+	if err != nil && !ok {
+		return nil // 扫描错误已是 syntax 诊断
+	}
+	if !ok {
+		v = f.Version
+	}
+	if err == nil {
+		err = parser.CheckVersion(f.Src, v)
+	}
+	if err == nil {
+		return nil
+	}
+	errs, ok := err.(parser.ErrorList)
+	if !ok {
+		errs = parser.ErrorList{err}
+	}
+	var list []Diagnostic
+	for _, err := range errs {
+		if e, ok := err.(*parser.Error); ok {
+			list = append(list, New(Version, f.Name, e.Pos, e.Msg))
+		} else {
+			list = append(list, New(Version, f.Name, 0, err.Error()))
+		}
+	}
+	return list
+}
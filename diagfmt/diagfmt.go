@@ -0,0 +1,208 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包把错误和诊断渲染为带源码片段的文本, 供命令行工具和测试执行器使用.
+//
+// 每条消息是一行 file:line:column: severity: msg, 之后是带行号的出错行及其上下文行,
+// 出错行之下用 ^~~~ 标出范围:
+//
+//	a.zxx:2:6: error: undefined: total
+//	   1 | proc main [
+//	   2 |     out total + 1
+//	     |         ^~~~~
+//	   3 | ]
+//
+// 输出只取决于消息和源码: TAB 按 TabWidth 展开为空格, 全角字符占两列,
+// 着色是可选的 ANSI 转义序列.
+package diagfmt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Message 是一条要渲染的消息
+type Message struct {
+	Pos      scanner.Pos
+	End      scanner.Pos // 范围的结束位置, 不大于 Pos 时标出 Pos 处的单词或者一个字符
+	Severity string      // 例如 "error", "warning", 为空时省略
+	Msg      string
+}
+
+// Renderer 渲染 Message. 零值可以使用, 没有上下文行, 不着色, TAB 宽度为 4.
+type Renderer struct {
+	Context  int  // 出错行之前和之后显示的行数
+	Color    bool // 使用 ANSI 转义序列着色
+	TabWidth int  // TAB 展开的宽度, 不大于 0 时为 4
+}
+
+// ANSI 转义序列
+const (
+	bold  = "\x1b[1m"
+	blue  = "\x1b[34m"
+	reset = "\x1b[0m"
+)
+
+// severityColors 是严重程度的颜色, 未列出的为青色
+var severityColors = map[string]string{
+	"error":   "\x1b[1;31m",
+	"warning": "\x1b[1;33m",
+}
+
+func (r *Renderer) color(code, s string) string {
+	if !r.Color || s == "" {
+		return s
+	}
+	return code + s + reset
+}
+
+func (r *Renderer) severityColor(sev string) string {
+	if c, ok := severityColors[sev]; ok {
+		return c
+	}
+	return "\x1b[1;36m"
+}
+
+// Render 在 w 上输出文件 file 中的 m, src 是添加 file 时的源码.
+// m.Pos 不属于 file 时只输出消息行, 位置是 file 的名字.
+func (r *Renderer) Render(w io.Writer, file *scanner.File, src []byte, m Message) error {
+	var b bytes.Buffer
+	pos := file.Position(m.Pos)
+	b.WriteString(r.color(bold, pos.String(file.Name())+":"))
+	if m.Severity != "" {
+		b.WriteString(" " + r.color(r.severityColor(m.Severity), m.Severity+":"))
+	}
+	b.WriteString(" " + m.Msg + "\n")
+	if pos.IsValid() {
+		r.snippet(&b, file, src, pos, m)
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// snippet 输出 pos 所在行, 上下文行和标出范围的一行
+func (r *Renderer) snippet(b *bytes.Buffer, file *scanner.File, src []byte, pos token.Position, m Message) {
+	first, last := pos.Line-r.Context, pos.Line+r.Context
+	if first < 1 {
+		first = 1
+	}
+	n := file.LineCount()
+	if n > pos.Line && file.Offset(file.LineStart(n)) == len(src) {
+		n-- // 结尾换行之后的空行
+	}
+	if last > n {
+		last = n
+	}
+	width := len(fmt.Sprint(last))
+	if width < 3 {
+		width = 3
+	}
+	gutter := func(s string) string {
+		return r.color(blue, fmt.Sprintf("%*s |", width+1, s))
+	}
+	for line := first; line <= last; line++ {
+		text := lineText(file, src, line)
+		b.WriteString(gutter(fmt.Sprint(line)))
+		if text != "" {
+			b.WriteString(" " + r.expand(text))
+		}
+		b.WriteByte('\n')
+		if line != pos.Line {
+			continue
+		}
+		// 标出范围, 跨行时标到行尾
+		start := pos.Column - 1
+		if start > len(text) {
+			start = len(text) // 位于 CRLF 中间
+		}
+		end := start + span(text[start:])
+		if e := file.Position(m.End); m.End > m.Pos && e.IsValid() {
+			end = len(text)
+			if e.Line == pos.Line && e.Column-1 < end {
+				end = e.Column - 1
+			}
+		}
+		pad := r.width(text[:start])
+		marks := r.width(text[:end]) - pad
+		if marks < 1 {
+			marks = 1
+		}
+		b.WriteString(gutter("") + " " + strings.Repeat(" ", pad))
+		b.WriteString(r.color(r.severityColor(m.Severity), "^"+strings.Repeat("~", marks-1)))
+		b.WriteByte('\n')
+	}
+}
+
+// lineText 返回第 line 行的源码, 不包括换行符
+func lineText(file *scanner.File, src []byte, line int) string {
+	start := file.Offset(file.LineStart(line))
+	end := len(src)
+	if line < file.LineCount() {
+		end = file.Offset(file.LineStart(line + 1))
+	}
+	return strings.TrimRight(string(src[start:end]), "\r\n")
+}
+
+// span 返回 s 开头的单词的字节长度, 不是单词时是一个字符的长度
+func span(s string) int {
+	n := 0
+	for n < len(s) {
+		c, size := utf8.DecodeRuneInString(s[n:])
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			break
+		}
+		n += size
+	}
+	if n == 0 && s != "" {
+		_, n = utf8.DecodeRuneInString(s)
+	}
+	return n
+}
+
+func (r *Renderer) tabWidth() int {
+	if r.TabWidth <= 0 {
+		return 4
+	}
+	return r.TabWidth
+}
+
+// width 返回行首的 s 在终端中显示的宽度, TAB 前进到下一个 TabWidth 的整数倍
+func (r *Renderer) width(s string) int {
+	col := 0
+	for _, c := range s {
+		if c == '\t' {
+			col += r.tabWidth() - col%r.tabWidth()
+			continue
+		}
+		col += scanner.RuneWidth(c)
+	}
+	return col
+}
+
+// expand 返回把 TAB 展开为空格的行 s, 与 width 的计算一致
+func (r *Renderer) expand(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for _, c := range s {
+		if c == '\t' {
+			n := r.tabWidth() - col%r.tabWidth()
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(c)
+		col += scanner.RuneWidth(c)
+	}
+	return b.String()
+}
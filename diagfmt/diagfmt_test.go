@@ -0,0 +1,58 @@
+package diagfmt_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/diagfmt"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+func TestRender(t *testing.T) {
+	src := []byte("proc main [\n\tout total + 1\n\tout '中文' + x\r\n]\n")
+	file := scanner.NewFileSet().AddFile("a.zxx", src)
+	for _, c := range []struct {
+		r    diagfmt.Renderer
+		m    diagfmt.Message
+		want string
+	}{
+		{diagfmt.Renderer{Context: 1}, diagfmt.Message{Pos: 17, Severity: "error", Msg: "undefined: total"},
+			"a.zxx:2:6: error: undefined: total\n" +
+				"   1 | proc main [\n" +
+				"   2 |     out total + 1\n" +
+				"     |         ^~~~~\n" +
+				"   3 |     out '中文' + x\n"},
+		{diagfmt.Renderer{TabWidth: 2}, diagfmt.Message{Pos: 17, End: 26, Msg: "bad"},
+			"a.zxx:2:6: bad\n" +
+				"   2 |   out total + 1\n" +
+				"     |       ^~~~~~~~~\n"},
+		{diagfmt.Renderer{Context: 5}, diagfmt.Message{Pos: 41, Severity: "warning", Msg: "mismatched types"},
+			"a.zxx:3:15: warning: mismatched types\n" +
+				"   1 | proc main [\n" +
+				"   2 |     out total + 1\n" +
+				"   3 |     out '中文' + x\n" +
+				"     |                ^\n" +
+				"   4 | ]\n"},
+		{diagfmt.Renderer{}, diagfmt.Message{Pos: 43, End: 100, Msg: "to the end"},
+			"a.zxx:3:17: to the end\n" +
+				"   3 |     out '中文' + x\n" +
+				"     |                  ^\n"},
+		{diagfmt.Renderer{}, diagfmt.Message{Pos: 45, Msg: "crlf"},
+			"a.zxx:3:19: crlf\n" +
+				"   3 |     out '中文' + x\n" +
+				"     |                   ^\n"},
+		{diagfmt.Renderer{}, diagfmt.Message{Pos: 1000, Msg: "no position"}, "a.zxx: no position\n"},
+		{diagfmt.Renderer{Color: true}, diagfmt.Message{Pos: 0, Severity: "error", Msg: "x"},
+			"\x1b[1ma.zxx:1:1:\x1b[0m \x1b[1;31merror:\x1b[0m x\n" +
+				"\x1b[34m   1 |\x1b[0m proc main [\n" +
+				"\x1b[34m     |\x1b[0m \x1b[1;31m^~~~\x1b[0m\n"},
+	} {
+		var b strings.Builder
+		if err := c.r.Render(&b, file, src, c.m); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != c.want {
+			t.Errorf("%+v:\n%s\nwant:\n%s", c.m, b.String(), c.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包从 ast.File 提取顶层声明及其文档注释, 并渲染为文本, Markdown 或 HTML.
+//
+// 文档注释来自 ast.CommentMap: 声明体开始处的注释以及声明的尾注释.
+// 文档和签名中出现的包内名字会被解析为交叉引用.
+package doc
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Decl 是一个顶层声明的文档
+type Decl struct {
+	Tok   token.Token // 声明保留字, PUB 之后的保留字优先
+	Pub   bool        // 是否有 pub 修饰
+	Names []string    // 声明的名字
+	Decl  string      // 声明签名源码, 不包括 proc, func 的函数体
+	Doc   string      // 剔除注释标记后的文档, 不包括示例和基准测试
+	Pos   scanner.Pos
+
+	Examples   []Example // 文档中的示例
+	Benchmarks []string  // 文档中基准测试的表达式
+}
+
+// Package 是一组源码文件的文档
+type Package struct {
+	Name  string
+	Doc   string // 首个文件的顶层占位文本
+	Decls []*Decl
+
+	names map[string]*Decl
+}
+
+// New 返回 files 的文档, 声明保持书写顺序.
+func New(name string, files ...*ast.File) *Package {
+	p := &Package{Name: name, names: map[string]*Decl{}}
+	for i, file := range files {
+		cmap := ast.NewCommentMap(file)
+		for _, n := range file.Nodes[1:] {
+			if i == 0 && p.Doc == "" && len(p.Decls) == 0 && n.Token() == token.PLACEHOLDER {
+				p.Doc = strings.TrimSpace(n.Text())
+				continue
+			}
+			if n.Kind(ast.FDeclaration) == 0 || n.Parent().Id() != 0 {
+				continue
+			}
+			d := newDecl(file, n, cmap)
+			p.Decls = append(p.Decls, d)
+			for _, name := range d.Names {
+				if p.names[name] == nil {
+					p.names[name] = d
+				}
+			}
+		}
+	}
+	return p
+}
+
+// Lookup 返回声明 name 的 Decl, 如果没有返回 nil.
+func (p *Package) Lookup(name string) *Decl {
+	return p.names[name]
+}
+
+// Names 返回排序后的全部声明名字
+func (p *Package) Names() []string {
+	names := make([]string, 0, len(p.names))
+	for name := range p.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// within 返回 n 是否在 decl 之内
+func within(n, decl ast.Node) bool {
+	for ; n != nil; n = n.Parent() {
+		if n == decl {
+			return true
+		}
+	}
+	return false
+}
+
+func newDecl(file *ast.File, decl ast.Node, cmap ast.CommentMap) *Decl {
+	d := &Decl{Tok: decl.Token(), Pos: pos(decl)}
+
+	// 节点按顺序排列, 声明的全部节点是连续的
+	var nodes []ast.Node
+	for _, n := range file.Nodes[decl.Id()+1:] {
+		if !within(n.Parent(), decl) {
+			break
+		}
+		nodes = append(nodes, n)
+	}
+
+	if d.Tok == token.PUB {
+		d.Pub = true
+		for _, n := range nodes {
+			if n.Token().As(token.Declare) && n.Token() != token.PUB {
+				d.Tok = n.Token()
+				break
+			}
+		}
+	}
+
+	// proc, func 的签名不包括函数体, 即最后一个直属的 LEFT 及其之后
+	if d.Tok == token.PROC || d.Tok == token.FUNC {
+		body := len(nodes)
+		for i, n := range nodes {
+			if n.Token() == token.LEFT && (n.Parent() == decl || n.Parent().Token() == d.Tok) {
+				body = i
+			}
+		}
+		nodes = nodes[:body]
+	}
+
+	d.Decl = signature(decl, nodes)
+	d.Names = names(d.Tok, nodes)
+
+	var comments []ast.Node
+	for _, n := range file.Nodes[decl.Id():] {
+		if n != decl && !within(n.Parent(), decl) {
+			break
+		}
+		if c := cmap[n]; c != nil && (n == decl || n.Token().As(token.Declare)) {
+			comments = append(comments, c.Doc...)
+			comments = append(comments, c.Trailing...)
+		}
+	}
+	d.Doc, d.Examples, d.Benchmarks = examples(text(comments))
+	return d
+}
+
+// signature 返回声明的规范源码.
+// 同一行的词法元素之间保留最多一个空格, 换行后按分组深度缩进.
+func signature(decl ast.Node, nodes []ast.Node) string {
+	buf := []byte(decl.Text())
+	end := pos(decl) + scanner.Pos(len(decl.Text()))
+	depth := 0
+	nl := false
+	for _, n := range nodes {
+		tok := n.Token()
+		if tok == token.NL || tok == token.EMPTYLINE {
+			nl = true
+			continue
+		}
+		if ast.IsTrivia(tok) {
+			continue
+		}
+
+		if tok == token.RIGHT {
+			depth--
+		}
+		if nl {
+			buf = append(buf, '\n')
+			buf = append(buf, strings.Repeat("\t", depth)...)
+		} else if pos(n) != end {
+			buf = append(buf, ' ')
+		}
+		if tok == token.LEFT {
+			depth++
+		}
+		nl = false
+		buf = append(buf, n.Text()...)
+		end = pos(n) + scanner.Pos(len(n.Text()))
+	}
+	return string(buf)
+}
+
+// pos 返回节点在源码中的偏移量
+func pos(n ast.Node) scanner.Pos {
+	switch n := n.(type) {
+	case *ast.Decl:
+		return n.Pos
+	case *ast.Chunk:
+		return n.Pos
+	case *ast.Stmt:
+		return n.Pos
+	case *ast.Expr:
+		return n.Pos
+	case *ast.Text:
+		return n.Pos
+	}
+	return 0
+}
+
+// names 返回声明的名字.
+// use 声明的是字符串路径, var, const 在赋值号左侧, 其它为首个 IDENT.
+func names(tok token.Token, nodes []ast.Node) (names []string) {
+	var clean []ast.Node
+	for _, n := range nodes {
+		if n.Token() == token.NL || !ast.IsTrivia(n.Token()) {
+			clean = append(clean, n)
+		}
+	}
+
+	switch tok {
+	case token.USE:
+		for _, n := range clean {
+			if n.Token() == token.VALSTRING {
+				names = append(names, strings.Trim(n.Text(), `"'`))
+			}
+		}
+		return
+	case token.VAR, token.CONST:
+	default:
+		for _, n := range clean {
+			if n.Token() == token.IDENT {
+				return []string{n.Text()}
+			}
+		}
+		return
+	}
+
+	value, depth := false, 0
+	for i, n := range clean {
+		switch n.Token() {
+		case token.LEFT:
+			depth++
+		case token.RIGHT:
+			depth--
+		case token.ASSIGN:
+			value = true
+		case token.NL:
+			// 分组内的换行分隔声明项
+			if depth <= 1 {
+				value = false
+			}
+		case token.IDENT:
+			if value {
+				break
+			}
+			if i+1 < len(clean) && clean[i+1].Token() == token.IDENT {
+				break
+			}
+			names = append(names, n.Text())
+		}
+	}
+	return
+}
+
+// text 返回注释节点剔除注释标记后的文本
+func text(comments []ast.Node) string {
+	var lines []string
+	for _, c := range comments {
+		src := c.Text()
+		switch {
+		case strings.HasPrefix(src, "---"):
+			for _, line := range strings.Split(strings.Trim(src, "-"), "\n") {
+				lines = append(lines, strings.TrimSpace(line))
+			}
+		case strings.HasPrefix(src, "//"):
+			lines = append(lines, strings.TrimSpace(src[2:]))
+		default:
+			lines = append(lines, strings.TrimSpace(src))
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,113 @@
+package doc_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	. "github.com/ZxxLang/zxx/doc"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+const src = `包的说明
+
+use "os"
+var int x = 1 // x 的说明
+var (
+	a, b = 1, f(c, d)
+	T y
+)
+pub proc sum Point p out int [
+	// 返回 p 的 x 与 y 的和
+	out p.x add p.y
+]
+type Point [
+	int x
+	int y
+]
+`
+
+func TestNew(t *testing.T) {
+	file := ast.NewFile()
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+
+	p := New("demo", file)
+	if p.Doc != "包的说明" || len(p.Decls) != 5 {
+		t.Fatal(p.Doc, len(p.Decls))
+	}
+
+	want := []struct {
+		tok   token.Token
+		names string
+		decl  string
+		doc   string
+	}{
+		{token.USE, "os", `use "os"`, ""},
+		{token.VAR, "x", "var int x = 1", "x 的说明"},
+		{token.VAR, "a,b,y", "var (\n\ta, b = 1, f(c, d)\n\tT y\n)", ""},
+		{token.PROC, "sum", "pub proc sum Point p out int", "返回 p 的 x 与 y 的和"},
+		{token.TYPE, "Point", "type Point [\n\tint x\n\tint y\n]", ""},
+	}
+	for i, d := range p.Decls {
+		w := want[i]
+		if d.Tok != w.tok || strings.Join(d.Names, ",") != w.names ||
+			d.Decl != w.decl || d.Doc != w.doc {
+			t.Fatalf("%d %s %v %q %q", i, d.Tok, d.Names, d.Decl, d.Doc)
+		}
+	}
+	if !p.Decls[3].Pub || p.Lookup("y") != p.Decls[2] {
+		t.Fatal(p.Decls[3].Pub, p.Lookup("y"))
+	}
+
+	var buf bytes.Buffer
+	if err := p.HTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<pre>pub proc sum <a href="#Point">Point</a> p out int</pre>`,
+		`<p>返回 p 的 <a href="#x">x</a> 与 <a href="#y">y</a> 的和</p>`,
+		`<pre>use &#34;os&#34;</pre>`,
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Fatal(s, "\n", buf.String())
+		}
+	}
+}
+
+func TestExamples(t *testing.T) {
+	const src = `proc sum int a, int b out int [
+	// 返回 a 与 b 的和
+	// Example: 1 + 2
+	// Output: 3
+	// Example: [1, 2]
+	// Benchmark: 1 + 2
+	out a + b
+]
+`
+	file := ast.NewFile()
+	if err := parser.Parse([]byte(src), file); err != nil {
+		t.Fatal(err)
+	}
+	d := New("demo", file).Decls[0]
+	if d.Doc != "返回 a 与 b 的和" || len(d.Examples) != 2 || len(d.Benchmarks) != 1 {
+		t.Fatalf("%q %v %v", d.Doc, d.Examples, d.Benchmarks)
+	}
+	if e := d.Examples[0]; e.Code != "1 + 2" || e.Output != "3" || !e.Has {
+		t.Fatal(e)
+	}
+	if e := d.Examples[1]; e.Code != "[1, 2]" || e.Has {
+		t.Fatal(e)
+	}
+
+	var buf bytes.Buffer
+	if err := New("demo", file).Markdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "```\n1 + 2\n// Output: 3\n```\n") {
+		t.Fatal(buf.String())
+	}
+}
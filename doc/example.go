@@ -0,0 +1,62 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package doc
+
+import "strings"
+
+// Example 是文档注释中可执行的示例, 写作
+//
+//	// Example: 1 + 2 * 3
+//	// Output: 7
+//
+// Output 行可以省略, 此时示例只需要求值成功. zxx test 执行示例并比较输出.
+type Example struct {
+	Code   string // 表达式源码
+	Output string // 期望输出的 zxx 字面值写法
+	Has    bool   // 是否有 Output 行
+}
+
+const (
+	exampleMark   = "Example:"
+	outputMark    = "Output:"
+	benchmarkMark = "Benchmark:"
+)
+
+// examples 从文档 doc 中分离示例和基准测试, 返回剩余的文档, 示例和基准测试的表达式.
+// 基准测试写作
+//
+//	// Benchmark: fib(20)
+//
+// 由 zxx test -bench 编译为字节码, 在虚拟机上反复执行并报告每次的耗时.
+func examples(doc string) (string, []Example, []string) {
+	if !strings.Contains(doc, exampleMark) && !strings.Contains(doc, benchmarkMark) {
+		return doc, nil, nil
+	}
+
+	var lines, benchmarks []string
+	var list []Example
+	for _, line := range strings.Split(doc, "\n") {
+		switch {
+		case strings.HasPrefix(line, benchmarkMark):
+			benchmarks = append(benchmarks, strings.TrimSpace(line[len(benchmarkMark):]))
+		case strings.HasPrefix(line, exampleMark):
+			list = append(list, Example{Code: strings.TrimSpace(line[len(exampleMark):])})
+		case strings.HasPrefix(line, outputMark) && len(list) != 0 && !list[len(list)-1].Has:
+			list[len(list)-1].Output = strings.TrimSpace(line[len(outputMark):])
+			list[len(list)-1].Has = true
+		default:
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), list, benchmarks
+}
+
+// text 返回示例的展示文本
+func (e Example) text() string {
+	if !e.Has {
+		return e.Code
+	}
+	return e.Code + "\n// Output: " + e.Output
+}
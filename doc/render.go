@@ -0,0 +1,128 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package doc
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Text 以纯文本格式输出文档
+func (p *Package) Text(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString(p.Name + "\n")
+	if p.Doc != "" {
+		buf.WriteString("\n" + indent(p.Doc, "    ") + "\n")
+	}
+	for _, d := range p.Decls {
+		buf.WriteString("\n" + d.Decl + "\n")
+		if d.Doc != "" {
+			buf.WriteString(indent(d.Doc, "    ") + "\n")
+		}
+		for _, e := range d.Examples {
+			buf.WriteString(indent(e.text(), "    ") + "\n")
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Markdown 以 Markdown 格式输出文档, 交叉引用是页内链接
+func (p *Package) Markdown(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("# " + p.Name + "\n")
+	if p.Doc != "" {
+		buf.WriteString("\n" + p.Doc + "\n")
+	}
+	for _, d := range p.Decls {
+		buf.WriteString("\n## " + d.Tok.String() + " " + strings.Join(d.Names, ", ") + "\n")
+		for _, name := range d.Names {
+			buf.WriteString(`<a name="` + html.EscapeString(name) + `"></a>`)
+		}
+		buf.WriteString("\n\n```\n" + d.Decl + "\n```\n")
+		if d.Doc != "" {
+			buf.WriteString("\n" + p.link(d.Doc, d, false, func(name string) string {
+				return "[" + name + "](#" + name + ")"
+			}, nil) + "\n")
+		}
+		for _, e := range d.Examples {
+			buf.WriteString("\n```\n" + e.text() + "\n```\n")
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// HTML 以 HTML 片段格式输出文档, 交叉引用是页内链接
+func (p *Package) HTML(w io.Writer) error {
+	var buf bytes.Buffer
+	ref := func(name string) string {
+		name = html.EscapeString(name)
+		return `<a href="#` + name + `">` + name + `</a>`
+	}
+
+	buf.WriteString("<h1>" + html.EscapeString(p.Name) + "</h1>\n")
+	if p.Doc != "" {
+		buf.WriteString("<p>" + html.EscapeString(p.Doc) + "</p>\n")
+	}
+	for _, d := range p.Decls {
+		buf.WriteString("<h2>")
+		for _, name := range d.Names {
+			buf.WriteString(`<a id="` + html.EscapeString(name) + `"></a>`)
+		}
+		buf.WriteString(d.Tok.String() + " " + html.EscapeString(strings.Join(d.Names, ", ")) + "</h2>\n")
+		buf.WriteString("<pre>" + p.link(d.Decl, d, true, ref, html.EscapeString) + "</pre>\n")
+		if d.Doc != "" {
+			buf.WriteString("<p>" + p.link(d.Doc, d, false, ref, html.EscapeString) + "</p>\n")
+		}
+		for _, e := range d.Examples {
+			buf.WriteString(`<pre class="example">` + html.EscapeString(e.text()) + "</pre>\n")
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// link 把 s 中引用其它声明的名字替换为 ref(name), 其它文本使用 escape 转义.
+// 签名中的参数名容易和其它声明重名, 因此 typeOnly 为 true 时只引用 type 声明.
+func (p *Package) link(s string, self *Decl, typeOnly bool, ref, escape func(string) string) string {
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+	var buf bytes.Buffer
+	for len(s) != 0 {
+		i := 0
+		for i < len(s) && !isWord(s[i]) {
+			i++
+		}
+		buf.WriteString(escape(s[:i]))
+		s = s[i:]
+
+		i = 0
+		for i < len(s) && isWord(s[i]) {
+			i++
+		}
+		word := s[:i]
+		s = s[i:]
+		if d := p.names[word]; d != nil && d != self && (!typeOnly || d.Tok == token.TYPE) {
+			buf.WriteString(ref(word))
+		} else {
+			buf.WriteString(escape(word))
+		}
+	}
+	return buf.String()
+}
+
+func isWord(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.Replace(s, "\n", "\n"+prefix, -1)
+}
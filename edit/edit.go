@@ -0,0 +1,75 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包实现对源码的文本编辑, 编辑使用原始源码中的字节偏移量.
+package edit
+
+import (
+	"errors"
+	"sort"
+)
+
+// Edit 表示把原始源码 [Start, End) 替换为 New.
+// Start == End 时是插入, New 为空时是删除.
+type Edit struct {
+	Start, End int
+	New        string
+}
+
+// Buffer 收集对 src 的编辑, 编辑之间不能重叠.
+type Buffer struct {
+	src   []byte
+	edits []Edit
+}
+
+// NewBuffer 返回编辑 src 的 Buffer, src 不会被修改.
+func NewBuffer(src []byte) *Buffer {
+	return &Buffer{src: src}
+}
+
+// Insert 在偏移量 pos 处插入 s
+func (b *Buffer) Insert(pos int, s string) {
+	b.edits = append(b.edits, Edit{pos, pos, s})
+}
+
+// Delete 删除 [start, end)
+func (b *Buffer) Delete(start, end int) {
+	b.edits = append(b.edits, Edit{start, end, ""})
+}
+
+// Replace 把 [start, end) 替换为 s
+func (b *Buffer) Replace(start, end int, s string) {
+	b.edits = append(b.edits, Edit{start, end, s})
+}
+
+// Edits 返回按位置排序的编辑, 同位置的插入保持添加顺序.
+func (b *Buffer) Edits() []Edit {
+	edits := append([]Edit(nil), b.edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Start < edits[j].Start ||
+			edits[i].Start == edits[j].Start && edits[i].End < edits[j].End
+	})
+	return edits
+}
+
+// Bytes 返回应用全部编辑后的源码
+func (b *Buffer) Bytes() ([]byte, error) {
+	return Apply(b.src, b.Edits())
+}
+
+// Apply 把按位置排序的 edits 应用到 src, 返回新的源码.
+// 编辑越界或者重叠时返回错误.
+func Apply(src []byte, edits []Edit) ([]byte, error) {
+	out := make([]byte, 0, len(src))
+	offset := 0
+	for _, e := range edits {
+		if e.Start < offset || e.End < e.Start || e.End > len(src) {
+			return nil, errors.New("edit: overlapping or invalid edit")
+		}
+		out = append(out, src[offset:e.Start]...)
+		out = append(out, e.New...)
+		offset = e.End
+	}
+	return append(out, src[offset:]...), nil
+}
@@ -0,0 +1,25 @@
+package edit_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/edit"
+)
+
+func TestBuffer(t *testing.T) {
+	b := edit.NewBuffer([]byte("var int x = 1"))
+	b.Replace(12, 13, "2")
+	b.Insert(0, "// x\n")
+	b.Replace(4, 7, "i8")
+	b.Insert(0, "\n")
+
+	out, err := b.Bytes()
+	if err != nil || string(out) != "// x\n\nvar i8 x = 2" {
+		t.Fatal(string(out), err)
+	}
+
+	b.Delete(5, 10)
+	if _, err = b.Bytes(); err == nil {
+		t.Fatal("want overlapping error")
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"errors"
+	"sort"
+	"unicode/utf8"
+)
+
+// 本文件实现列表和记录的构造与遍历, 以及字符串按字符的拆分.
+
+// maxSpan 是范围 a..b 的元素个数上限
+const maxSpan = 1 << 20
+
+// Map 返回映射字面值 [k: v, ...] 的值, kv 是交替的键和值.
+// 映射即记录 map[string]Value, 键必须是字符串, 重复的键以后者为准.
+func Map(kv ...Value) (Value, error) {
+	m := make(map[string]Value, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			return nil, errors.New("map key must be string")
+		}
+		m[key] = kv[i+1]
+	}
+	return m, nil
+}
+
+// span 返回 a..b 的值, 即从 a 到 b 的整数列表, 包括 b. a 大于 b 时为空列表.
+func span(a, b Value) (Value, error) {
+	lo, ok := a.(int64)
+	hi, ok2 := b.(int64)
+	if !ok || !ok2 {
+		return nil, errors.New("invalid operation .. on non-integer")
+	}
+	if lo > hi {
+		return []Value{}, nil
+	}
+	if uint64(hi-lo) >= maxSpan {
+		return nil, &kindError{ErrLimit, "range too large"}
+	}
+	list := make([]Value, 0, hi-lo+1)
+	for i := lo; ; i++ {
+		list = append(list, i)
+		if i == hi {
+			return list, nil
+		}
+	}
+}
+
+// Entries 返回 for x as key value 依次绑定的键和值:
+// 列表的下标和元素, 记录按字典序的键和值, 字符串中每个字符的下标和该字符,
+// null 没有元素. 整数范围 a..b 是列表, 其元素即 value.
+func Entries(x Value) (keys, values []Value, err error) {
+	switch v := x.(type) {
+	case nil:
+	case []Value:
+		for i, item := range v {
+			keys = append(keys, int64(i))
+			values = append(values, Normalize(item))
+		}
+	case map[string]Value:
+		names := make([]string, 0, len(v))
+		for k := range v {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			keys = append(keys, k)
+			values = append(values, Normalize(v[k]))
+		}
+	case string:
+		for i, c := range Runes(v) {
+			keys = append(keys, int64(i))
+			values = append(values, c)
+		}
+	default:
+		return nil, nil, errors.New("cannot iterate over value")
+	}
+	return keys, values, nil
+}
+
+// Runes 返回 s 中的字符, 每个字符是一个字符串. 字符串的下标和长度都以字符计算,
+// 不是合法 UTF-8 的字节单独作为一个字符.
+func Runes(s string) []Value {
+	list := make([]Value, 0, len(s))
+	for i := 0; i < len(s); {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		list = append(list, s[i:i+size])
+		i += size
+	}
+	return list
+}
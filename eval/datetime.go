@@ -0,0 +1,105 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// 本文件实现 datetime 和 duration 的运算:
+//
+//	datetime ± duration  datetime
+//	duration + datetime  datetime
+//	datetime - datetime  duration
+//	duration ± duration  duration
+//	duration * 数值      duration, 数值 * duration 相同
+//	duration / 数值      duration
+//	duration / duration  float
+//
+// 以及同类值之间的比较. duration 没有字面值, 由环境或内置函数产生.
+
+// datetime 返回 a op y 的值, 不支持的运算返回 nil
+func datetime(op token.Token, a time.Time, y Value) (Value, error) {
+	switch b := y.(type) {
+	case time.Duration:
+		switch op {
+		case token.ADD, token.PLUS:
+			return a.Add(b), nil
+		case token.SUB:
+			return a.Add(-b), nil
+		}
+		return nil, errors.New("invalid operation datetime " + op.String() + " duration")
+	case time.Time:
+		switch op {
+		case token.SUB:
+			return a.Sub(b), nil
+		case token.LSS:
+			return a.Before(b), nil
+		case token.LEQ:
+			return !a.After(b), nil
+		case token.GTR:
+			return a.After(b), nil
+		case token.GEQ:
+			return !a.Before(b), nil
+		}
+		return nil, errors.New("invalid operation " + op.String() + " on datetime")
+	}
+	return nil, nil
+}
+
+// duration 返回 a op y 的值, 不支持的运算返回 nil
+func duration(op token.Token, a time.Duration, y Value) (Value, error) {
+	switch b := y.(type) {
+	case time.Time:
+		if op == token.ADD || op == token.PLUS {
+			return b.Add(a), nil
+		}
+		return nil, errors.New("invalid operation duration " + op.String() + " datetime")
+	case time.Duration:
+		switch op {
+		case token.ADD, token.PLUS:
+			return a + b, nil
+		case token.SUB:
+			return a - b, nil
+		case token.DIV, token.DIVSIGN:
+			if b == 0 {
+				return nil, ErrDivideByZero
+			}
+			return float64(a) / float64(b), nil
+		}
+		if v := compare(op, float64(a), float64(b)); v != nil {
+			return v, nil
+		}
+		return nil, errors.New("invalid operation " + op.String() + " on duration")
+	}
+
+	f, ok := Float(y)
+	if !ok {
+		return nil, nil
+	}
+	switch op {
+	case token.MUL, token.MULSIGN:
+		return scale(a, f)
+	case token.DIV, token.DIVSIGN:
+		if f == 0 {
+			return nil, ErrDivideByZero
+		}
+		return scale(a, 1/f)
+	}
+	return nil, errors.New("invalid operation duration " + op.String() + " number")
+}
+
+// scale 返回 a * f, 溢出是错误
+func scale(a time.Duration, f float64) (Value, error) {
+	x := float64(a) * f
+	if math.IsNaN(x) || x > math.MaxInt64 || x < math.MinInt64 {
+		return nil, errors.New("duration overflow")
+	}
+	return time.Duration(x), nil
+}
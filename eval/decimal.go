@@ -0,0 +1,49 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"errors"
+
+	"github.com/ZxxLang/zxx/constant"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// 本文件实现 decimal 的运算. decimal 是 constant.Value, 由 19.99d 这样的字面值产生,
+// 与 decimal, 整数运算的结果是精确的 decimal, 与浮点数混合运算是错误.
+
+// toDecimal 返回 x 的 constant.Value, x 不是 decimal 或整数时 ok 为 false.
+func toDecimal(x Value) (v constant.Value, ok bool) {
+	switch x := x.(type) {
+	case constant.Value:
+		return x, true
+	case int64:
+		return constant.MakeInt64(x), true
+	}
+	return
+}
+
+// decimal 返回 x op y 的值, x, y 至少有一个是 decimal.
+func decimal(op token.Token, x, y Value) (Value, error) {
+	a, aok := toDecimal(x)
+	b, bok := toDecimal(y)
+	if !aok || !bok {
+		return nil, errors.New("invalid operation " + op.String() + " on decimal")
+	}
+
+	switch op {
+	case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+		c, err := constant.Compare(a, b)
+		if err != nil {
+			return nil, err
+		}
+		return compare(op, float64(c), 0), nil
+	}
+	v, err := constant.Binary(op, a, b)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
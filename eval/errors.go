@@ -0,0 +1,124 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// 运行时错误的种类, 用 errors.Is 判断 *Error 的种类.
+// 其它运算错误, 例如操作数的类型不符, 没有种类.
+var (
+	ErrUndefined    = errors.New("undefined")                              // 名字或成员不存在
+	ErrNilMember    = errors.New("member of null")                         // 访问 null 的成员
+	ErrIndexRange   = errors.New("index out of range")                     // 下标越界
+	ErrDivideByZero = errors.New("division by zero")                       // 整数或时长除以零
+	ErrNotFunc      = errors.New("cannot call non-function")               // 调用的不是 Func
+	ErrMultiValue   = errors.New("multiple-value in single-value context") // 在单值上下文中使用 Tuple
+	ErrLimit        = errors.New("limit exceeded")                         // 超出 Limits, 不能恢复
+)
+
+// kindError 是种类为 kind 的错误, 消息是 msg
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+func (e *kindError) Unwrap() error { return e.kind }
+
+// Frame 是调用栈中的一层
+type Frame struct {
+	Func   string // 所在的函数, 是调用者使用的名字, 最外层为空
+	File   string // 表达式所在的文件, 可以为空
+	Offset int    // 在表达式源码中的字节偏移量
+	Line   int    // 从 1 开始, 源码未知时为 0
+	Column int    // 从 1 开始的字节列号
+}
+
+// NewFrame 返回文件 file 中源码 src 的偏移量 offset 处的 Frame, src 为空时不计算行列.
+func NewFrame(file, src string, offset int) Frame {
+	f := Frame{File: file, Offset: offset}
+	if src != "" && offset <= len(src) {
+		f.Line = strings.Count(src[:offset], "\n") + 1
+		f.Column = offset - strings.LastIndexByte(src[:offset], '\n')
+	}
+	return f
+}
+
+// String 返回 file:line:column, 行号未知时为 file:+offset.
+func (f Frame) String() string {
+	s := f.File
+	if f.Line == 0 {
+		return s + ":+" + strconv.Itoa(f.Offset)
+	}
+	return s + ":" + strconv.Itoa(f.Line) + ":" + strconv.Itoa(f.Column)
+}
+
+// Unwrap 返回错误的原因
+func (e *Error) Unwrap() error { return e.Err }
+
+// Trace 返回错误消息和调用栈, 每层一行, 由内向外:
+//
+//	eval: index out of range
+//		in f at a.zxx:1:9
+//		at b.zxx:2:3
+func (e *Error) Trace() string {
+	var b strings.Builder
+	b.WriteString("eval: " + e.Msg)
+	for _, f := range e.Stack {
+		b.WriteString("\n\t")
+		if f.Func != "" {
+			b.WriteString("in " + f.Func + " ")
+		}
+		b.WriteString("at " + f.String())
+	}
+	return b.String()
+}
+
+// Wrap 返回在 at 处发生的原因为 err 的 *Error.
+//
+// err 是环境的函数 name 中嵌套求值的 *Error 时, 保留其消息和原因,
+// 调用栈的最外层记为 name, 再延长到调用处 at.
+func Wrap(err error, name string, at Frame) *Error {
+	if e, ok := err.(*Error); ok {
+		stack := append([]Frame(nil), e.Stack...)
+		if len(stack) == 0 {
+			stack = append(stack, Frame{Offset: e.Offset})
+		}
+		stack[len(stack)-1].Func = name
+		return &Error{at.Offset, e.Msg, e.Err, append(stack, at)}
+	}
+	return &Error{at.Offset, err.Error(), err, []Frame{at}}
+}
+
+// MultiValue 返回在单值上下文中使用函数 name 的多个结果的错误, 种类是 ErrMultiValue.
+func MultiValue(name string) error {
+	if name == "" {
+		return &kindError{ErrMultiValue, ErrMultiValue.Error()}
+	}
+	return &kindError{ErrMultiValue, "multiple-value " + name + "() in single-value context"}
+}
+
+// Lookup 返回成员路径 names 在环境 env 中的值, 例如 user.age 为 ["user", "age"].
+// 不存在时错误的种类是 ErrUndefined, 中间的值为 null 时是 ErrNilMember.
+func Lookup(env map[string]Value, names []string) (Value, error) {
+	var v Value = env
+	for i, name := range names {
+		rec, ok := v.(map[string]Value)
+		if ok {
+			v, ok = rec[name]
+		} else if v == nil {
+			return nil, &kindError{ErrNilMember, "member " + name + " of null " + strings.Join(names[:i], ".")}
+		}
+		if !ok {
+			return nil, &kindError{ErrUndefined, "undefined " + strings.Join(names, ".")}
+		}
+		v = Normalize(v)
+	}
+	return v, nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包求值单个 zxx 表达式, 适合嵌入规则引擎, 特性开关等场景.
+//
+// 表达式可以使用字面值, 列表 [a, b], 映射 ['k': v], 环境中的名字和成员 user.age,
+// 下标 list[0], record['key'], 环境提供的函数调用 f(a, b), 插值字符串 "age {user.age}",
+// 以及 zxx 运算符:
+//
+//	user.age >= 18 and user.country == 'cn' or user.tags has 'beta'
+//
+// 求值没有副作用, 并受 Limits 限制. 需要反复求值的表达式应当用 Compile 编译一次.
+package eval
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Value 是表达式的值, 可以是
+//
+//	nil, bool, int64, float64, string, time.Time, time.Duration,
+//	constant.Value, []Value, map[string]Value, Func, Tuple
+//
+// datetime, duration 和 decimal 的运算参见 Binary, decimal 的值是 constant.Value.
+//
+// 环境中的其它整数和浮点数类型也被接受, 它们被转换为 int64, float64.
+type Value interface{}
+
+// Func 是环境提供的函数
+type Func func(args ...Value) (Value, error)
+
+// Tuple 是有多个结果的函数返回的值, 例如 out a, b.
+// Tuple 只能是整个表达式的值, 用作运算数, 参数或者元素的错误种类是 ErrMultiValue,
+// 调用者按结果的个数解构, 而不是作为列表使用.
+type Tuple []Value
+
+// Error 是带位置的表达式错误, Offset 是表达式源码中的字节偏移量.
+//
+// 求值时的错误有调用栈 Stack, 只在环境的函数中嵌套求值出错时多于一层,
+// 此时 Offset 是最外层的调用处, Msg 和 Err 来自最内层.
+type Error struct {
+	Offset int
+	Msg    string
+	Err    error   // 错误的原因, 例如 ErrIndexRange 或者环境的函数返回的错误, 可以为 nil
+	Stack  []Frame // 由内向外, 解析错误没有调用栈
+}
+
+func (e *Error) Error() string {
+	return "eval: " + strconv.Itoa(e.Offset) + ": " + e.Msg
+}
+
+// Limits 限制表达式的规模和求值代价, 零值表示不限制.
+type Limits struct {
+	MaxSource int // 源码字节数
+	MaxDepth  int // 语法嵌套深度
+	MaxSteps  int // 求值步数, 每个节点和函数调用计一步
+	MaxString int // 字符串运算结果的字节数
+
+	// Watchdog 非 nil 时在 MaxSteps 用完时被调用, steps 是已用的步数,
+	// offset 是正在求值的节点在源码中的偏移量. 返回值大于 0 时追加相应的步数
+	// 继续求值, 否则求值以 "too many steps" 错误停止, 错误的 Offset 即 offset.
+	Watchdog func(steps, offset int) (extra int)
+}
+
+// DefaultLimits 是 Expr 使用的限制
+var DefaultLimits = Limits{
+	MaxSource: 4096,
+	MaxDepth:  64,
+	MaxSteps:  10000,
+	MaxString: 1 << 16,
+}
+
+// Expr 使用 DefaultLimits 求值表达式 src, 名字在 env 中查找.
+func Expr(src string, env map[string]Value) (Value, error) {
+	return DefaultLimits.Expr(src, env)
+}
+
+// Expr 在限制 l 下求值表达式 src, 名字在 env 中查找.
+func (l Limits) Expr(src string, env map[string]Value) (Value, error) {
+	p, err := l.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return p.Eval(env)
+}
+
+// Program 是编译后的表达式, 创建后不再改变, 可以被多个 goroutine 同时求值.
+type Program struct {
+	file   string
+	src    string
+	root   *node
+	limits Limits
+}
+
+// Compile 使用 DefaultLimits 编译表达式 src.
+func Compile(src string) (*Program, error) {
+	return DefaultLimits.Compile(src)
+}
+
+// Compile 在限制 l 下编译表达式 src, 求值时同样受 l 限制.
+func (l Limits) Compile(src string) (*Program, error) {
+	return l.CompileFile("", src)
+}
+
+// CompileFile 同 Compile, file 是表达式所在的文件, 用于求值错误的调用栈.
+func (l Limits) CompileFile(file, src string) (*Program, error) {
+	n, err := l.parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{file, src, n, l}, nil
+}
+
+// String 返回表达式源码.
+func (p *Program) String() string {
+	return p.src
+}
+
+// Eval 求值表达式, 名字在 env 中查找.
+func (p *Program) Eval(env map[string]Value) (Value, error) {
+	m := &machine{p: p, env: env, budget: p.limits.MaxSteps}
+	return m.eval(p.root)
+}
+
+// Hooks 是求值过程中的事件回调, 宿主程序可以据此审计, 跟踪或者限制函数调用.
+// 未设置的回调没有开销. 表达式没有语句, 因此没有语句事件.
+type Hooks struct {
+	// OnCall 在调用环境提供的函数之前被调用, name 是函数的名字, 不是名字时为空.
+	// 返回非 nil 时不调用该函数, 求值以该错误停止, 错误的 Offset 是调用的位置.
+	OnCall func(name string, args []Value, offset int) error
+
+	// OnReturn 在函数返回之后被调用
+	OnReturn func(name string, v Value, err error)
+
+	// OnError 在求值以错误结束时被调用一次
+	OnError func(err *Error)
+
+	// Recover 在运行时错误发生处被调用, 返回 ok 时出错的运算或调用的结果是 v, 求值继续,
+	// 类似 Go 的 recover. 错误的种类是 ErrLimit 时不被调用.
+	Recover func(err *Error) (v Value, ok bool)
+}
+
+// EvalHooks 同 Eval, 求值过程中调用 h 中的回调, h 可以为 nil.
+func (p *Program) EvalHooks(env map[string]Value, h *Hooks) (Value, error) {
+	m := &machine{p: p, env: env, budget: p.limits.MaxSteps, hooks: h}
+	v, err := m.eval(p.root)
+	if err != nil && h != nil && h.OnError != nil {
+		if e, ok := err.(*Error); ok {
+			h.OnError(e)
+		}
+	}
+	return v, err
+}
+
+// node 是表达式语法树节点
+type node struct {
+	sym  parser.Symbol // 运算符, 字面值, 名字或者左括号
+	val  Value         // 字面值
+	x, y *node         // 操作数, 下标或者被调用的函数
+	list []*node       // 列表元素, 映射中交替的键和值, 插值字符串中交替的片段和表达式或者参数
+	kind kind
+}
+
+type kind int
+
+const (
+	literal kind = iota
+	name
+	unary
+	binary
+	list
+	mapping
+	interp
+	index
+	call
+)
+
+func (l Limits) parse(src string) (*node, error) {
+	if l.MaxSource != 0 && len(src) > l.MaxSource {
+		return nil, &Error{Msg: "expression too long"}
+	}
+	x, err := parser.ParseExpr([]byte(src))
+	if err != nil {
+		e := &Error{Msg: strings.TrimPrefix(err.Error(), "parser: ")}
+		if pe, ok := err.(*parser.Error); ok {
+			e.Offset = int(pe.Pos)
+			e.Msg = strings.TrimSuffix(e.Msg, " at offset "+strconv.Itoa(e.Offset))
+		}
+		return nil, e
+	}
+	return l.node(x, 1)
+}
+
+// node 把 parser.ParseExpr 产生的表达式树 x 转换为求值用的节点, depth 是 x 的嵌套深度.
+// 二元运算的左操作数与运算同深度, 因此 a + b + c 这样的长链不算嵌套.
+func (l Limits) node(x ast.Expression, depth int) (*node, error) {
+	if l.MaxDepth != 0 && depth > l.MaxDepth {
+		return nil, &Error{Offset: int(x.Pos()), Msg: "expression nested too deeply"}
+	}
+	depth++
+
+	var (
+		n   *node
+		err error
+	)
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		v, err := Literal(x.Value.Tok, x.Value.Source)
+		if err != nil {
+			return nil, &Error{Offset: int(x.Value.Pos), Msg: err.Error()}
+		}
+		return &node{sym: x.Value, val: v}, nil
+	case *ast.Ident:
+		return &node{sym: x.Name, kind: name}, nil
+	case *ast.ParenExpr:
+		return l.node(x.X, depth)
+	case *ast.UnaryExpr:
+		n = &node{sym: x.Op, kind: unary}
+		n.x, err = l.node(x.X, depth)
+	case *ast.BinaryExpr:
+		n = &node{sym: x.Op, kind: binary}
+		if n.x, err = l.node(x.X, depth-1); err == nil {
+			n.y, err = l.node(x.Y, depth)
+		}
+	case *ast.ListExpr:
+		n = &node{sym: left(x.Lbrack, "["), kind: list}
+		n.list, err = l.nodes(x.Elems, depth)
+	case *ast.MapExpr:
+		n = &node{sym: left(x.Lbrack, "["), kind: mapping}
+		elems := make([]ast.Expression, 0, 2*len(x.Elems))
+		for _, kv := range x.Elems {
+			elems = append(elems, kv.Key, kv.Value)
+		}
+		n.list, err = l.nodes(elems, depth)
+	case *ast.InterpExpr:
+		n = &node{sym: x.Parts[0].(*ast.BasicLit).Value, kind: interp}
+		n.list, err = l.nodes(x.Parts, depth)
+	case *ast.CallExpr:
+		n = &node{sym: left(x.Lparen, "("), kind: call}
+		if n.x, err = l.node(x.Fun, depth-1); err == nil {
+			n.list, err = l.nodes(x.Args, depth)
+		}
+	case *ast.IndexExpr:
+		n = &node{sym: left(x.Lbrack, "["), kind: index}
+		if n.x, err = l.node(x.X, depth-1); err == nil {
+			n.y, err = l.node(x.Index, depth)
+		}
+	default:
+		return nil, &Error{Offset: int(x.Pos()), Msg: "unsupported expression"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// nodes 转换深度为 depth 的表达式列表
+func (l Limits) nodes(list []ast.Expression, depth int) ([]*node, error) {
+	ns := make([]*node, len(list))
+	for i, x := range list {
+		var err error
+		if ns[i], err = l.node(x, depth); err != nil {
+			return nil, err
+		}
+	}
+	return ns, nil
+}
+
+func left(pos scanner.Pos, source string) parser.Symbol {
+	return parser.Symbol{Pos: pos, Tok: token.LEFT, Source: source}
+}
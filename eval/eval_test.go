@@ -0,0 +1,461 @@
+package eval_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZxxLang/zxx/constant"
+	"github.com/ZxxLang/zxx/eval"
+)
+
+func TestExpr(t *testing.T) {
+	env := map[string]eval.Value{
+		"user": map[string]eval.Value{
+			"age":     20,
+			"country": "cn",
+			"tags":    []eval.Value{"beta", "vip"},
+		},
+		"limit": 3.5,
+		"max": eval.Func(func(args ...eval.Value) (eval.Value, error) {
+			if len(args) == 0 {
+				return nil, errors.New("max of nothing")
+			}
+			m := args[0].(int64)
+			for _, a := range args[1:] {
+				if a.(int64) > m {
+					m = a.(int64)
+				}
+			}
+			return m, nil
+		}),
+	}
+
+	for src, want := range map[string]eval.Value{
+		"1 + 2 * 3":       int64(7),
+		"(1 + 2) * 3":     int64(9),
+		"-2 + 10 div 3":   int64(1),
+		"7 mod -3":        int64(1),
+		"7 rem -3":        int64(1),
+		"1 + 0.5":         1.5,
+		"1_000 + 0o10":    int64(1008),
+		"'a' + 'b' - 'c'": "abc",
+		"user.age >= 18 and user.country == 'cn'":   true,
+		"not user.age > 18 or user.tags has 'beta'": true,
+		"user.tags[1]":                 "vip",
+		"user['country']":              "cn",
+		"max(1, user.age, 3)":          int64(20),
+		"limit > 3 and limit":          3.5,
+		"null == null":                 true,
+		"[1, 2] has 2":                 true,
+		"['a': 1, 'b': user.age]['b']": int64(20),
+		"['a':1, 'b':user.age]['b']":   int64(20),
+		"[:] has 'a'":                  false,
+		"1..3 has 3 and not 3..1":      true,
+		"20160204 < 20160205":          true,
+		"0x10 | 0b1":                   int64(17),
+		"~0x0f & 0xff":                 int64(0xf0),
+		`"x {user.age+1} y"`:           "x 21 y",
+		`"{null}{1.5}{true}\{"`:        "null1.5true{",
+		`"\u{4e2d}{1}\u{41}"`:          "中1A",
+		"'中文abc'[1] + 'a中'[1]":         "文中",
+	} {
+		got, err := eval.Expr(src, env)
+		if err != nil || got != want {
+			t.Fatalf("%s: %#v %v", src, got, err)
+		}
+	}
+
+	for src, msg := range map[string]string{
+		"1 +":          "eval: 3: unexpected EOF",
+		"1 div 0":      "eval: 2: division by zero",
+		"unknown":      "eval: 0: undefined unknown",
+		"user.tags[5]": "eval: 9: index out of range",
+		"max()":        "eval: 3: max of nothing",
+		"'a' * 2":      "eval: 4: invalid operation *",
+		"(1":           "eval: 2: unexpected EOF",
+		"1 2":          "eval: 2: unexpected VALINTEGER '2'",
+		"~'a'":         "eval: 0: invalid operand for ~",
+		`"a{[1]}"`:     "eval: 3: invalid operand for interpolation",
+		"[null: 'a']":  "eval: 0: map key must be string",
+		"0..2_000_000": "eval: 1: range too large",
+		"'中文'[2]":      "eval: 8: index out of range",
+	} {
+		_, err := eval.Expr(src, env)
+		if err == nil || err.Error() != msg {
+			t.Fatalf("%s: %v", src, err)
+		}
+	}
+}
+
+func TestIntegers(t *testing.T) {
+	// 大于 2^53 的整数不能精确地表示为 float64
+	for src, want := range map[string]eval.Value{
+		"9007199254740993 == 9007199254740992":    false,
+		"9007199254740993 != 9007199254740992":    true,
+		"9007199254740993 > 9007199254740992":     true,
+		"9007199254740992 < 9007199254740993":     true,
+		"9007199254740993 <= 9007199254740992":    false,
+		"[9007199254740993] has 9007199254740992": false,
+		"9007199254740993 == 9007199254740992.0":  true,
+		"(-9223372036854775807 - 1) rem 10":       int64(8),
+		"7 rem (-9223372036854775807 - 1)":        int64(7),
+		"(-9223372036854775807 - 1) div 1":        int64(math.MinInt64),
+		"-9223372036854775807":                    int64(-math.MaxInt64),
+	} {
+		got, err := eval.Expr(src, nil)
+		if err != nil || got != want {
+			t.Fatalf("%s: %#v %v", src, got, err)
+		}
+	}
+
+	for src, msg := range map[string]string{
+		"(-9223372036854775807 - 1) div -1": "eval: 27: integer overflow",
+		"(-9223372036854775807 - 1) mod -1": "eval: 27: integer overflow",
+		"-(-9223372036854775807 - 1)":       "eval: 0: integer overflow",
+	} {
+		_, err := eval.Expr(src, nil)
+		if err == nil || err.Error() != msg {
+			t.Fatalf("%s: %v", src, err)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	l := eval.Limits{MaxSource: 64, MaxDepth: 8, MaxSteps: 10, MaxString: 4}
+	for _, src := range []string{
+		strings.Repeat("1 + ", 20) + "1",
+		strings.Repeat("(", 10) + "1" + strings.Repeat(")", 10),
+		"1 + 1 + 1 + 1 + 1 + 1",
+		"'ab' + 'cde'",
+	} {
+		if _, err := l.Expr(src, nil); err == nil {
+			t.Fatal(src)
+		}
+	}
+	if v, err := l.Expr("'ab' + 'cd'", nil); err != nil || v != "abcd" {
+		t.Fatal(v, err)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	p, err := eval.Compile("user.age >= 18 and user.tags has 'beta'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(age int) {
+			defer wg.Done()
+			env := map[string]eval.Value{
+				"user": map[string]eval.Value{"age": age, "tags": []eval.Value{"beta"}},
+			}
+			v, err := p.Eval(env)
+			if err != nil || v != (age >= 18) {
+				t.Error(age, v, err)
+			}
+		}(14 + i)
+	}
+	wg.Wait()
+
+	if _, err = eval.Compile("1 +"); err == nil {
+		t.Fatal("Compile 1 +")
+	}
+}
+
+var benchEnv = map[string]eval.Value{
+	"user": map[string]eval.Value{"age": 20, "country": "cn", "tags": []eval.Value{"beta", "vip"}},
+}
+
+const benchSrc = "user.age >= 18 and user.country == 'cn' or user.tags has 'beta'"
+
+func BenchmarkExpr(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := eval.Expr(benchSrc, benchEnv); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProgram(b *testing.B) {
+	p, err := eval.Compile(benchSrc)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Eval(benchEnv); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkProgramParallel(b *testing.B) {
+	p, err := eval.Compile(benchSrc)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := p.Eval(benchEnv); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestWatchdog(t *testing.T) {
+	src := strings.Repeat("1 + ", 20) + "1"
+	calls := 0
+	l := eval.Limits{MaxSteps: 10, Watchdog: func(steps, offset int) int {
+		if calls++; steps != 10*calls {
+			t.Fatal(steps)
+		}
+		if calls < 5 {
+			return 10
+		}
+		return 0
+	}}
+	if v, err := l.Expr(src, nil); err != nil || v != int64(21) {
+		t.Fatal(v, err, calls)
+	}
+
+	// 拒绝追加时停止, 错误报告正在求值的位置
+	l.Watchdog = func(steps, offset int) int { return 0 }
+	_, err := l.Expr(src, nil)
+	e, ok := err.(*eval.Error)
+	if !ok || e.Msg != "too many steps" || src[e.Offset] != '1' && src[e.Offset] != '+' {
+		t.Fatal(err)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	double := eval.Func(func(args ...eval.Value) (eval.Value, error) {
+		return args[0].(int64) * 2, nil
+	})
+	env := map[string]eval.Value{"double": double, "secret": double}
+	p, err := eval.Compile("double(double(1)) + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log []string
+	h := &eval.Hooks{
+		OnCall: func(name string, args []eval.Value, offset int) error {
+			log = append(log, "call "+name)
+			return nil
+		},
+		OnReturn: func(name string, v eval.Value, err error) {
+			log = append(log, "return "+name)
+		},
+	}
+	if v, err := p.EvalHooks(env, h); err != nil || v != int64(5) {
+		t.Fatal(v, err)
+	}
+	if strings.Join(log, ",") != "call double,return double,call double,return double" {
+		t.Fatal(log)
+	}
+
+	// OnCall 拒绝调用
+	var failed *eval.Error
+	h = &eval.Hooks{
+		OnCall: func(name string, args []eval.Value, offset int) error {
+			if name == "secret" {
+				return errors.New("forbidden")
+			}
+			return nil
+		},
+		OnError: func(err *eval.Error) { failed = err },
+	}
+	p, _ = eval.Compile("1 + secret(2)")
+	if _, err := p.EvalHooks(env, h); err == nil || failed == nil || failed.Offset != 10 || failed.Msg != "forbidden" {
+		t.Fatal(err, failed)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	inner, err := eval.DefaultLimits.CompileFile("inner.zxx", "[1, 2][\n  n]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pick := eval.Func(func(args ...eval.Value) (eval.Value, error) {
+		return inner.Eval(map[string]eval.Value{"n": args[0]})
+	})
+	outer, _ := eval.DefaultLimits.CompileFile("outer.zxx", "1 +\npick(5)")
+	_, err = outer.Eval(map[string]eval.Value{"pick": pick})
+	e, ok := err.(*eval.Error)
+	if !ok || !errors.Is(err, eval.ErrIndexRange) || e.Offset != 8 {
+		t.Fatal(err)
+	}
+	if want := "eval: index out of range\n\tin pick at inner.zxx:1:7\n\tat outer.zxx:2:5"; e.Trace() != want {
+		t.Fatalf("%q", e.Trace())
+	}
+
+	for src, kind := range map[string]error{
+		"user.age":  eval.ErrNilMember,
+		"nobody":    eval.ErrUndefined,
+		"1 div 0":   eval.ErrDivideByZero,
+		"user()":    eval.ErrNotFunc,
+		"fail(1)":   errFail,
+		"[1][0][0]": nil,
+	} {
+		_, err := eval.Expr(src, map[string]eval.Value{"user": nil, "fail": eval.Func(fail)})
+		if err == nil || kind != nil && !errors.Is(err, kind) {
+			t.Errorf("%s: %v", src, err)
+		}
+	}
+	if _, err := eval.Expr("user.age", map[string]eval.Value{"user": nil}); err.Error() != "eval: 0: member age of null user" {
+		t.Fatal(err)
+	}
+}
+
+var errFail = errors.New("fail")
+
+func fail(args ...eval.Value) (eval.Value, error) { return nil, errFail }
+
+func TestRecover(t *testing.T) {
+	p, _ := eval.Compile("[1, 2][5] or fail() or 3")
+	var recovered []string
+	h := &eval.Hooks{Recover: func(err *eval.Error) (eval.Value, bool) {
+		recovered = append(recovered, err.Msg)
+		return nil, true
+	}}
+	v, err := p.EvalHooks(map[string]eval.Value{"fail": eval.Func(fail)}, h)
+	if err != nil || v != int64(3) || strings.Join(recovered, ",") != "index out of range,fail" {
+		t.Fatal(v, err, recovered)
+	}
+
+	// 超出限制的错误不能恢复
+	l := eval.Limits{MaxSteps: 2}
+	p, _ = l.Compile("1 + 2 + 3")
+	if _, err := p.EvalHooks(nil, h); !errors.Is(err, eval.ErrLimit) {
+		t.Fatal(err)
+	}
+}
+
+func TestTuple(t *testing.T) {
+	env := map[string]eval.Value{"pair": eval.Func(func(args ...eval.Value) (eval.Value, error) {
+		return eval.Tuple{1, "a"}, nil
+	})}
+	if v, err := eval.Expr("pair()", env); err != nil || !reflect.DeepEqual(v, eval.Tuple{1, "a"}) {
+		t.Fatal(v, err)
+	}
+	for _, src := range []string{"pair() + 1", "[pair()]", "pair()[0]"} {
+		_, err := eval.Expr(src, env)
+		if !errors.Is(err, eval.ErrMultiValue) || !strings.Contains(err.Error(), "multiple-value pair() in single-value context") {
+			t.Errorf("%s: %v", src, err)
+		}
+	}
+}
+
+func TestDatetime(t *testing.T) {
+	env := map[string]eval.Value{
+		"hour": time.Hour,
+		"day":  24 * time.Hour,
+		"due":  time.Date(2016, 2, 4, 10, 0, 0, 0, time.UTC),
+	}
+	for src, want := range map[string]eval.Value{
+		"20160204T12:00Z - due":           2 * time.Hour,
+		"due + day":                       time.Date(2016, 2, 5, 10, 0, 0, 0, time.UTC),
+		"hour + due == due - -hour":       true,
+		"due - hour * 1.5 < due":          true,
+		"2 * hour + day / 4":              8 * time.Hour,
+		"day / hour":                      24.0,
+		"20160205T10:00Z - due == day":    true,
+		"20160205T10:00Z - due > hour":    true,
+		"not (due - due)":                 true,
+		"(20160204T10:00Z - due) == hour": false,
+	} {
+		got, err := eval.Expr(src, env)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if g, ok := got.(time.Time); ok {
+			if !g.Equal(want.(time.Time)) {
+				t.Fatalf("%s: %v", src, got)
+			}
+		} else if got != want {
+			t.Fatalf("%s: %#v", src, got)
+		}
+	}
+
+	for _, src := range []string{"due + due", "hour - due", "hour / 0", "due * 2", "hour + 1", "hour * 1e300"} {
+		if _, err := eval.Expr(src, env); err == nil {
+			t.Fatalf("%s: no error", src)
+		}
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	env := map[string]eval.Value{"n": 3}
+	for src, want := range map[string]string{
+		"0.1d + 0.2d":        "0.3",
+		"19.99d * n":         "59.97",
+		"1d / 3":             "0.3333333333333333333333333333333333",
+		"-1_000.50d - 0.5d":  "-1001",
+		"100d / 8 * 2":       "25",
+		"1.5e2d + 1":         "151",
+		"(0.1d + 0.2d) * -1": "-0.3",
+	} {
+		got, err := eval.Expr(src, env)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if d, ok := got.(constant.Value); !ok || d.String() != want {
+			t.Fatalf("%s: %#v", src, got)
+		}
+	}
+
+	for src, want := range map[string]eval.Value{
+		"0.1d + 0.2d == 0.3d": true,
+		"1.00d == 1":          true,
+		"0.5d < 1":            true,
+		"not 0d":              true,
+		"0.01d and 'x'":       "x",
+	} {
+		got, err := eval.Expr(src, env)
+		if err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+		if got != want {
+			t.Fatalf("%s: %#v", src, got)
+		}
+	}
+
+	for _, src := range []string{
+		"0.1d + 0.1", "0.1d == 0.1", "1d / 0", "1d % 2", "'a' + 1d",
+		"9223372036854775808",
+		"9223372036854775807 + 1", "-9223372036854775807 - 2", "4294967296 * 4294967296",
+	} {
+		if _, err := eval.Expr(src, env); err == nil {
+			t.Fatalf("%s: no error", src)
+		}
+	}
+}
+
+func TestEntries(t *testing.T) {
+	for _, tt := range []struct {
+		x            eval.Value
+		keys, values []eval.Value
+	}{
+		{[]eval.Value{"a", 2}, []eval.Value{int64(0), int64(1)}, []eval.Value{"a", int64(2)}},
+		{map[string]eval.Value{"b": 1, "a": 2}, []eval.Value{"a", "b"}, []eval.Value{int64(2), int64(1)}},
+		{"中a\xff", []eval.Value{int64(0), int64(1), int64(2)}, []eval.Value{"中", "a", "\xff"}},
+		{nil, nil, nil},
+	} {
+		keys, values, err := eval.Entries(tt.x)
+		if err != nil || !reflect.DeepEqual(keys, tt.keys) || !reflect.DeepEqual(values, tt.values) {
+			t.Fatalf("%v: %v %v %v", tt.x, keys, values, err)
+		}
+	}
+	if _, _, err := eval.Entries(int64(3)); err == nil {
+		t.Fatal("want error")
+	}
+}
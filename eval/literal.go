@@ -0,0 +1,41 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/constant"
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Literal 返回字面值 Token tok 的源码 source 对应的值.
+// tok 可以是 NULL, VALSTRING, VALINTEGER, VALFLOAT, VALDATETIME, VALBOOL,
+// 或者插值字符串的片段 STRINGLIT, 片段的值不包括首尾的引号.
+// 以 'd' 结尾的十进制数的值是 constant.Value, 超出 int64 的整数是错误.
+func Literal(tok token.Token, source string) (v Value, err error) {
+	switch {
+	case tok == token.VALSTRING:
+		v, err = lexutil.Unquote(source)
+	case tok == token.STRINGLIT:
+		v, err = lexutil.Segment(source)
+	case tok == token.VALDATETIME:
+		v, err = lexutil.ParseDatetime(source)
+	case tok == token.VALFLOAT && strings.HasSuffix(source, "d"):
+		v, err = constant.Make(tok, source)
+	default:
+		v, err = token.Value(tok, source)
+		if e, ok := err.(*strconv.NumError); ok && e.Err == strconv.ErrRange && tok == token.VALINTEGER {
+			return nil, errors.New("integer " + source + " overflows int64")
+		}
+	}
+	if err != nil {
+		return nil, errors.New("invalid " + tok.String() + " " + source)
+	}
+	return
+}
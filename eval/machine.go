@@ -0,0 +1,218 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// machine 是一次求值的状态
+type machine struct {
+	p      *Program
+	env    map[string]Value
+	steps  int
+	budget int // 可用的步数, 初始为 MaxSteps, Watchdog 可以追加
+	hooks  *Hooks
+	raised *Error // 最近在节点处发生, 尚未交给 Hooks.Recover 的错误
+}
+
+// fail 返回在节点 n 处发生的原因为 err 的错误, name 是调用的环境的函数名.
+func (m *machine) fail(n *node, err error, name string) error {
+	m.raised = Wrap(err, name, NewFrame(m.p.file, m.p.src, int(n.sym.Pos)))
+	return m.raised
+}
+
+// eval 求值节点 n, 在 n 处发生的错误可以由 Hooks.Recover 恢复.
+func (m *machine) eval(n *node) (Value, error) {
+	v, err := m.step(n)
+	if e, ok := err.(*Error); ok && e == m.raised {
+		m.raised = nil
+		if m.hooks != nil && m.hooks.Recover != nil && !errors.Is(e, ErrLimit) {
+			if r, ok := m.hooks.Recover(e); ok {
+				return Normalize(r), nil
+			}
+		}
+	}
+	return v, err
+}
+
+func (m *machine) step(n *node) (Value, error) {
+	if m.steps++; m.p.limits.MaxSteps != 0 && m.steps > m.budget {
+		extra := 0
+		if m.p.limits.Watchdog != nil {
+			extra = m.p.limits.Watchdog(m.steps-1, int(n.sym.Pos))
+		}
+		if extra <= 0 {
+			return nil, m.fail(n, &kindError{ErrLimit, "too many steps"}, "")
+		}
+		m.budget += extra
+	}
+
+	switch n.kind {
+	case literal:
+		return n.val, nil
+	case name:
+		return m.lookup(n)
+	case unary:
+		x, err := m.eval(n.x)
+		if err == nil {
+			if x, err = Unary(n.sym.Tok, x); err != nil {
+				err = m.fail(n, err, "")
+			}
+		}
+		return x, err
+	case binary:
+		return m.binary(n)
+	case list, mapping:
+		v := make([]Value, len(n.list))
+		for i, item := range n.list {
+			x, err := m.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = x
+		}
+		if n.kind == list {
+			return v, nil
+		}
+		x, err := Map(v...)
+		if err != nil {
+			return nil, m.fail(n, err, "")
+		}
+		return x, nil
+	case interp:
+		return m.interp(n)
+	case index:
+		return m.index(n)
+	case call:
+		return m.call(n)
+	}
+	return nil, m.fail(n, errors.New("invalid expression"), "")
+}
+
+func (m *machine) binary(n *node) (Value, error) {
+	x, err := m.eval(n.x)
+	if err != nil {
+		return nil, err
+	}
+
+	// 短路求值
+	switch op := n.sym.Tok; {
+	case op == token.AND && !Truth(x), op == token.OR && Truth(x):
+		return x, nil
+	}
+
+	y, err := m.eval(n.y)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := Binary(n.sym.Tok, x, y)
+	if err != nil {
+		return nil, m.fail(n, err, "")
+	}
+	if s, ok := v.(string); ok && m.p.limits.MaxString != 0 && len(s) > m.p.limits.MaxString {
+		return nil, m.fail(n, &kindError{ErrLimit, "string too long"}, "")
+	}
+	return v, nil
+}
+
+// interp 连接插值字符串的片段和表达式的文本, 参见 Text
+func (m *machine) interp(n *node) (Value, error) {
+	var b strings.Builder
+	for _, part := range n.list {
+		x, err := m.eval(part)
+		if err != nil {
+			return nil, err
+		}
+		s, err := Text(x)
+		if err != nil {
+			return nil, m.fail(part, err, "")
+		}
+		if b.WriteString(s); m.p.limits.MaxString != 0 && b.Len() > m.p.limits.MaxString {
+			return nil, m.fail(n, &kindError{ErrLimit, "string too long"}, "")
+		}
+	}
+	return b.String(), nil
+}
+
+// lookup 返回名字或成员在环境中的值
+func (m *machine) lookup(n *node) (Value, error) {
+	v, err := Lookup(m.env, strings.Split(n.sym.Source, "."))
+	if err != nil {
+		return nil, m.fail(n, err, "")
+	}
+	return v, nil
+}
+
+func (m *machine) index(n *node) (Value, error) {
+	x, err := m.eval(n.x)
+	if err != nil {
+		return nil, err
+	}
+	i, err := m.eval(n.y)
+	if err != nil {
+		return nil, err
+	}
+	v, err := Index(x, i)
+	if err != nil {
+		return nil, m.fail(n, err, "")
+	}
+	return v, nil
+}
+
+func (m *machine) call(n *node) (Value, error) {
+	fn, err := m.eval(n.x)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := fn.(Func)
+	if !ok {
+		return nil, m.fail(n, ErrNotFunc, "")
+	}
+
+	args := make([]Value, len(n.list))
+	for i, item := range n.list {
+		if args[i], err = m.eval(item); err != nil {
+			return nil, err
+		}
+	}
+	fname := ""
+	if n.x.kind == name {
+		fname = n.x.sym.Source
+	}
+	if m.hooks == nil {
+		v, err := f(args...)
+		if err != nil {
+			return nil, m.fail(n, err, fname)
+		}
+		return m.single(n, Normalize(v), fname)
+	}
+
+	if m.hooks.OnCall != nil {
+		if err = m.hooks.OnCall(fname, args, int(n.sym.Pos)); err != nil {
+			return nil, m.fail(n, err, "")
+		}
+	}
+	v, err := f(args...)
+	if m.hooks.OnReturn != nil {
+		m.hooks.OnReturn(fname, v, err)
+	}
+	if err != nil {
+		return nil, m.fail(n, err, fname)
+	}
+	return m.single(n, Normalize(v), fname)
+}
+
+// single 返回调用 n 的结果 v, 只有整个表达式可以是 Tuple.
+func (m *machine) single(n *node, v Value, fname string) (Value, error) {
+	if _, ok := v.(Tuple); ok && n != m.p.root {
+		return nil, m.fail(n, MultiValue(fname), "")
+	}
+	return v, nil
+}
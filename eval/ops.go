@@ -0,0 +1,422 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package eval
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ZxxLang/zxx/constant"
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// 本文件实现标量运算, 返回的错误不包含位置, 由调用者补充.
+
+// Truth 返回 x 的真值: false, null, 0, 空字符串, 空列表和空记录为假.
+func Truth(x Value) bool {
+	switch v := x.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []Value:
+		return len(v) != 0
+	case map[string]Value:
+		return len(v) != 0
+	case time.Duration:
+		return v != 0
+	case constant.Value:
+		return v.Sign() != 0
+	}
+	return true
+}
+
+// Text 返回标量 x 在插值字符串中的文本, 字符串是它本身, null 是 "null",
+// 浮点数同 lexutil.FormatFloat, datetime 是 RFC 3339 格式. 列表, 记录和函数是错误.
+func Text(x Value) (string, error) {
+	switch v := x.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return lexutil.FormatFloat(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return v.String(), nil
+	case constant.Value:
+		return v.String(), nil
+	}
+	return "", errors.New("invalid operand for interpolation")
+}
+
+// Unary 返回一元运算 op x 的值, op 可以是 SUB, PLUS, NOT, ANTI. ANTI 是整数的位反, SUB, PLUS 的操作数可以是 duration, decimal.
+func Unary(op token.Token, x Value) (Value, error) {
+	switch op {
+	case token.NOT:
+		return !Truth(x), nil
+	case token.ANTI:
+		if v, ok := x.(int64); ok {
+			return ^v, nil
+		}
+	case token.SUB, token.PLUS:
+		switch v := x.(type) {
+		case int64:
+			if op == token.SUB {
+				if v == math.MinInt64 {
+					return nil, errIntOverflow
+				}
+				v = -v
+			}
+			return v, nil
+		case float64:
+			if op == token.SUB {
+				v = -v
+			}
+			return v, nil
+		case time.Duration:
+			if op == token.SUB {
+				v = -v
+			}
+			return v, nil
+		case constant.Value:
+			return constant.Unary(op, v)
+		}
+	}
+	return nil, errors.New("invalid operand for " + op.String())
+}
+
+// Binary 返回二元运算 x op y 的值.
+//
+// 整数运算的结果是整数, 整数和浮点数混合运算的结果是浮点数.
+// '+' 和 '-' 都可以连接字符串. AND, OR 返回决定结果的操作数.
+// HAS 返回列表 x 是否包含 y, 记录 x 是否有键 y, 或字符串 x 是否包含 y.
+// DOTDOT 返回整数范围的列表, 参见 collection.go.
+// datetime 和 duration 可以相加减, duration 可以乘除数值, 参见 datetime.go.
+// decimal 与 decimal, 整数运算的结果是 decimal, 参见 decimal.go.
+// 整数运算溢出是错误.
+func Binary(op token.Token, x, y Value) (Value, error) {
+	switch op {
+	case token.HAS:
+		return has(x, y)
+	case token.DOTDOT:
+		return span(x, y)
+	case token.AND:
+		if !Truth(x) {
+			return x, nil
+		}
+		return y, nil
+	case token.OR:
+		if Truth(x) {
+			return x, nil
+		}
+		return y, nil
+	case token.EQL, token.NEQ:
+		if eq, ok := equal(x, y); ok {
+			return eq == (op == token.EQL), nil
+		}
+	}
+
+	switch a := x.(type) {
+	case string:
+		b, ok := y.(string)
+		if !ok {
+			break
+		}
+		switch op {
+		case token.ADD, token.PLUS, token.SUB:
+			return a + b, nil
+		case token.LSS:
+			return a < b, nil
+		case token.LEQ:
+			return a <= b, nil
+		case token.GTR:
+			return a > b, nil
+		case token.GEQ:
+			return a >= b, nil
+		}
+		return nil, errors.New("invalid operation " + op.String() + " on string")
+	case time.Time:
+		if v, err := datetime(op, a, y); v != nil || err != nil {
+			return v, err
+		}
+	case time.Duration:
+		if v, err := duration(op, a, y); v != nil || err != nil {
+			return v, err
+		}
+	}
+	if b, ok := y.(time.Duration); ok && (op == token.MUL || op == token.MULSIGN) {
+		if v, err := duration(op, b, x); v != nil || err != nil {
+			return v, err
+		}
+	}
+
+	_, xd := x.(constant.Value)
+	_, yd := y.(constant.Value)
+	if xd || yd {
+		return decimal(op, x, y)
+	}
+
+	a, aok := x.(int64)
+	b, bok := y.(int64)
+	if aok && bok {
+		return integer(op, a, b)
+	}
+
+	f, fok := Float(x)
+	g, gok := Float(y)
+	if !fok || !gok {
+		return nil, errors.New("invalid operation " + op.String())
+	}
+	switch op {
+	case token.ADD, token.PLUS:
+		return f + g, nil
+	case token.SUB:
+		return f - g, nil
+	case token.MUL, token.MULSIGN:
+		return f * g, nil
+	case token.DIV, token.DIVSIGN:
+		return f / g, nil
+	}
+	if v := compare(op, f, g); v != nil {
+		return v, nil
+	}
+	return nil, errors.New("invalid operation " + op.String() + " on float")
+}
+
+var errIntOverflow = errors.New("integer overflow")
+
+func integer(op token.Token, a, b int64) (Value, error) {
+	switch op {
+	case token.ADD, token.PLUS:
+		if c := a + b; (c > a) == (b > 0) {
+			return c, nil
+		}
+		return nil, errIntOverflow
+	case token.SUB:
+		if c := a - b; (c < a) == (b > 0) {
+			return c, nil
+		}
+		return nil, errIntOverflow
+	case token.MUL, token.MULSIGN:
+		c := a * b
+		if a != 0 && (c/a != b || a == -1 && b == math.MinInt64 || b == -1 && a == math.MinInt64) {
+			return nil, errIntOverflow
+		}
+		return c, nil
+	case token.DIV, token.DIVSIGN, token.MOD, token.REM:
+		if b == 0 {
+			return nil, ErrDivideByZero
+		}
+		switch {
+		case op == token.REM:
+			return int64(abs(a) % abs(b)), nil
+		case a == math.MinInt64 && b == -1:
+			return nil, errIntOverflow
+		case op == token.MOD:
+			return a % b, nil
+		}
+		return a / b, nil
+	case token.BITAND:
+		return a & b, nil
+	case token.BITOR:
+		return a | b, nil
+	case token.XOR:
+		return a ^ b, nil
+	case token.SHL, token.SHLSIGN, token.SHR, token.SHRSIGN:
+		if b < 0 || b > 63 {
+			return nil, errors.New("invalid shift count")
+		}
+		if op == token.SHL || op == token.SHLSIGN {
+			return a << uint(b), nil
+		}
+		return a >> uint(b), nil
+	}
+	switch op {
+	case token.EQL:
+		return a == b, nil
+	case token.NEQ:
+		return a != b, nil
+	case token.LSS:
+		return a < b, nil
+	case token.LEQ:
+		return a <= b, nil
+	case token.GTR:
+		return a > b, nil
+	case token.GEQ:
+		return a >= b, nil
+	}
+	return nil, errors.New("invalid operation " + op.String() + " on integer")
+}
+
+// abs 返回 a 的绝对值, 用 uint64 表示使得 math.MinInt64 的绝对值不溢出
+func abs(a int64) uint64 {
+	if a < 0 {
+		return -uint64(a)
+	}
+	return uint64(a)
+}
+
+// Float 返回数值 x 的 float64 值, 如果 x 不是 int64 或 float64 返回 false.
+func Float(x Value) (float64, bool) {
+	switch v := x.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// equal 返回标量 x, y 是否相等, 不可比较时 ok 为 false.
+func equal(x, y Value) (eq, ok bool) {
+	if x == nil || y == nil {
+		return x == nil && y == nil, true
+	}
+	a, aok := x.(int64)
+	b, bok := y.(int64)
+	if aok && bok {
+		return a == b, true
+	}
+	if f, ok := Float(x); ok {
+		g, ok := Float(y)
+		return f == g, ok
+	}
+	switch a := x.(type) {
+	case string:
+		b, ok := y.(string)
+		return a == b, ok
+	case bool:
+		b, ok := y.(bool)
+		return a == b, ok
+	case time.Time:
+		b, ok := y.(time.Time)
+		return a.Equal(b), ok
+	case time.Duration:
+		b, ok := y.(time.Duration)
+		return a == b, ok
+	case constant.Value:
+		b, ok := toDecimal(y)
+		if !ok {
+			return false, false
+		}
+		c, err := constant.Compare(a, b)
+		return c == 0, err == nil
+	}
+	return false, false
+}
+
+// compare 返回比较运算结果, 非比较运算返回 nil
+func compare(op token.Token, a, b float64) Value {
+	switch op {
+	case token.EQL:
+		return a == b
+	case token.NEQ:
+		return a != b
+	case token.LSS:
+		return a < b
+	case token.LEQ:
+		return a <= b
+	case token.GTR:
+		return a > b
+	case token.GEQ:
+		return a >= b
+	}
+	return nil
+}
+
+func has(x, y Value) (Value, error) {
+	switch v := x.(type) {
+	case []Value:
+		for _, item := range v {
+			if eq, ok := equal(item, y); ok && eq {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]Value:
+		if key, ok := y.(string); ok {
+			_, ok = v[key]
+			return ok, nil
+		}
+	case string:
+		if s, ok := y.(string); ok {
+			return strings.Contains(v, s), nil
+		}
+	}
+	return nil, errors.New("invalid operation " + token.HAS.String())
+}
+
+// Index 返回下标运算 x[i] 的值. 列表和字符串的下标是整数, 记录的下标是字符串,
+// 记录中不存在的键返回 nil. 字符串的下标以字符计算, 结果是该字符的字符串, 参见 Runes.
+func Index(x, i Value) (Value, error) {
+	switch v := x.(type) {
+	case []Value:
+		if k, ok := i.(int64); ok {
+			if k < 0 || k >= int64(len(v)) {
+				return nil, ErrIndexRange
+			}
+			return Normalize(v[k]), nil
+		}
+	case map[string]Value:
+		if k, ok := i.(string); ok {
+			return Normalize(v[k]), nil
+		}
+	case string:
+		if k, ok := i.(int64); ok {
+			for n := int64(0); len(v) != 0; n++ {
+				_, size := utf8.DecodeRuneInString(v)
+				if n == k {
+					return v[:size], nil
+				}
+				v = v[size:]
+			}
+			return nil, ErrIndexRange
+		}
+	}
+	return nil, errors.New("invalid index")
+}
+
+// Normalize 把环境中的 Go 整数和浮点数类型转换为 int64, float64,
+// func(args ...Value) (Value, error) 转换为 Func.
+func Normalize(v Value) Value {
+	switch x := v.(type) {
+	case nil, bool, int64, float64, string, []Value, map[string]Value, Func, Tuple, time.Time, time.Duration,
+		constant.Value:
+		return v
+	case int:
+		return int64(x)
+	case func(args ...Value) (Value, error):
+		return Func(x)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return v
+}
@@ -0,0 +1,164 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"bytes"
+	"io/fs"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/ZxxLang/zxx/parser"
+)
+
+// Config 是 Config.Format 和 Dir 的选项, 零值只做 Source 的规范化.
+type Config struct {
+	Fix      bool    // 先把非法的 UTF-8 编码替换为 U+FFFD, 再应用解析错误建议的修改
+	Literals bool    // 统一字面值的写法, 参见 Literals
+	Quote    byte    // 字符串统一使用的引号, 参见 Quotes, 为 0 时不改变
+	Indent   *Indent // 缩进风格, 参见 Reindent, 为 nil 时不改变
+	EOL      EOL     // 换行, 为空时是 LF
+
+	Workers int  // Dir 同时格式化的文件数, 不大于 0 时为 runtime.GOMAXPROCS(0)
+	DryRun  bool // Dir 不写回文件, 只报告结果
+
+	// Write 写回 Dir 中需要格式化的文件, path 是在 fsys 中的路径, 为 nil 时同 DryRun.
+	// 可能被多个 goroutine 同时调用.
+	Write func(path string, out []byte) error
+}
+
+// maxFixes 是 Fix 收集的最多错误数
+const maxFixes = 100
+
+// Format 按 c 格式化 src, 依次是 Fix, Literals, Quote, Indent, Source 和 EOL.
+func (c *Config) Format(src []byte) ([]byte, error) {
+	var err error
+	if c.Fix {
+		src, _ = parser.RepairUTF8(src)
+		_, err = (&parser.Config{MaxErrors: maxFixes}).Parse(src)
+		if fixes := parser.Fixes(err); len(fixes) != 0 {
+			if src, err = parser.ApplyEdits(src, fixes); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c.Literals {
+		if src, err = Literals(src); err != nil {
+			return nil, err
+		}
+	}
+	if c.Quote != 0 {
+		if src, err = Quotes(src, c.Quote); err != nil {
+			return nil, err
+		}
+	}
+	if c.Indent != nil {
+		if src, err = Reindent(src, *c.Indent); err != nil {
+			return nil, err
+		}
+	}
+	if src, err = Source(src); err != nil {
+		return nil, err
+	}
+	if c.EOL == CRLF {
+		src = LineEndings(src, CRLF)
+	}
+	return src, nil
+}
+
+// Status 是 Dir 中一个文件的结果
+type Status uint8
+
+const (
+	Unchanged Status = iota // 已经是格式化的
+	Changed                 // 需要格式化, 不是 DryRun 时已经写回
+	Failed                  // 读取, 格式化或者写回出错
+)
+
+var statuses = [...]string{"unchanged", "changed", "failed"}
+
+func (s Status) String() string {
+	if int(s) < len(statuses) {
+		return statuses[s]
+	}
+	return "status"
+}
+
+// Result 是 Dir 中一个文件的结果
+type Result struct {
+	Path   string // 在 fsys 中的路径
+	Status Status
+	Src    []byte // 原来的源码, 读取出错时为 nil
+	Out    []byte // 格式化后的源码, 格式化出错时为 nil
+	Err    error  // Failed 的原因
+}
+
+// Dir 按 cfg 并发格式化 fsys 中 root 之下的 .zxx 文件, root 是文件时总是格式化它.
+// 返回的结果按路径排序, 每个文件一个. 只有遍历目录的错误被返回, 此时结果为 nil,
+// 单个文件的错误记录在它的 Result 中, 不影响其它文件.
+func Dir(fsys fs.FS, root string, cfg Config) ([]Result, error) {
+	var paths []string
+	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && (path == root || strings.HasSuffix(path, ".zxx")) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	results := make([]Result, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = cfg.file(fsys, paths[i])
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results, nil
+}
+
+// file 格式化 fsys 中的文件 path, 需要时写回
+func (c *Config) file(fsys fs.FS, path string) Result {
+	r := Result{Path: path, Status: Failed}
+	if r.Src, r.Err = fs.ReadFile(fsys, path); r.Err != nil {
+		return r
+	}
+	if r.Out, r.Err = c.Format(r.Src); r.Err != nil {
+		return r
+	}
+	if bytes.Equal(r.Src, r.Out) {
+		r.Status = Unchanged
+		return r
+	}
+	if !c.DryRun && c.Write != nil {
+		if r.Err = c.Write(path, r.Out); r.Err != nil {
+			return r
+		}
+	}
+	r.Status = Changed
+	return r
+}
@@ -0,0 +1,116 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"errors"
+
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// EOL 是写出文件时使用的换行.
+//
+// 扫描器把 CRLF 和单独的 CR 都当作一个换行, 字符串值中的源码换行也统一为 LF,
+// 因此改变换行不改变 Token 和字符串的值.
+type EOL string
+
+const (
+	LF   EOL = "lf"
+	CRLF EOL = "crlf"
+)
+
+// ParseEOL 返回名为 s 的 EOL, 例如 "crlf".
+func ParseEOL(s string) (EOL, error) {
+	switch e := EOL(s); e {
+	case LF, CRLF:
+		return e, nil
+	}
+	return "", errors.New("format: unknown line ending " + s)
+}
+
+func (e EOL) bytes() string {
+	if e == CRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// Endings 是源码中每种换行的个数
+type Endings struct {
+	LF, CRLF, CR int
+}
+
+// CountEndings 返回 src 中每种换行的个数.
+func CountEndings(src []byte) (n Endings) {
+	for i := 0; i < len(src); i++ {
+		switch {
+		case src[i] == '\n':
+			n.LF++
+		case src[i] != '\r':
+		case i+1 < len(src) && src[i+1] == '\n':
+			n.CRLF++
+			i++
+		default:
+			n.CR++
+		}
+	}
+	return
+}
+
+// Mixed 返回是否混用了多种换行.
+func (n Endings) Mixed() bool {
+	kinds := 0
+	for _, c := range [...]int{n.LF, n.CRLF, n.CR} {
+		if c != 0 {
+			kinds++
+		}
+	}
+	return kinds > 1
+}
+
+// Majority 返回多数换行对应的 EOL, CRLF 多于其它换行之和时是 CRLF, 否则是 LF.
+func (n Endings) Majority() EOL {
+	if n.CRLF > n.LF+n.CR {
+		return CRLF
+	}
+	return LF
+}
+
+// EOLEdits 返回把 src 中的换行统一为 eol 的修改, 按位置排序, 可以用 parser.ApplyEdits 应用.
+func EOLEdits(src []byte, eol EOL) []parser.TextEdit {
+	var edits []parser.TextEdit
+	want := eol.bytes()
+	for i := 0; i < len(src); i++ {
+		if src[i] != '\n' && src[i] != '\r' {
+			continue
+		}
+		end := i + 1
+		if src[i] == '\r' && end < len(src) && src[end] == '\n' {
+			end++
+		}
+		if string(src[i:end]) != want {
+			edits = append(edits, parser.TextEdit{Pos: scanner.Pos(i), End: scanner.Pos(end), NewText: want})
+		}
+		i = end - 1
+	}
+	return edits
+}
+
+// LineEndings 返回把换行统一为 eol 的 src, 没有需要修改的换行时返回 src.
+func LineEndings(src []byte, eol EOL) []byte {
+	edits := EOLEdits(src, eol)
+	if len(edits) == 0 {
+		return src
+	}
+	out := make([]byte, 0, len(src)+len(edits))
+	last := 0
+	for _, e := range edits {
+		out = append(out, src[last:e.Pos]...)
+		out = append(out, e.NewText...)
+		last = int(e.End)
+	}
+	return append(out, src[last:]...)
+}
@@ -0,0 +1,97 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包实现 zxx 源码的格式化.
+//
+// 目前只做排版层面的规范化, 不改变 Token:
+//
+//	换行统一为 LF
+//	删除行尾的空格和制表符
+//	连续的空行合并为一行, 删除文件首尾的空行
+//	文件以一个换行结尾
+//
+// 跨行字符串的内容保持原样, 只有其中的换行也统一为 LF, 字符串的值不变.
+// 写出 CRLF 换行的文件参见 LineEndings.
+package format
+
+import (
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Source 返回格式化后的 src. 如果 src 不能被扫描, 返回错误.
+func Source(src []byte) ([]byte, error) {
+	syms, err := parser.Fast(src, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 跨行字符串的字节区间, 除换行外原样输出
+	var keep [][2]int
+	for _, sym := range syms {
+		if (sym.Tok == token.VALSTRING || sym.Tok == token.STRINGLIT) && multiline(sym.Source) {
+			keep = append(keep, [2]int{int(sym.Pos), int(sym.Pos) + len(sym.Source)})
+		}
+	}
+
+	out := make([]byte, 0, len(src)+1)
+	fixed := 0     // out 中原样输出的部分不能被删减
+	lineStart := 0 // out 中当前行的开始
+	blanks := 0    // 连续的空行数
+	for i := 0; i < len(src); {
+		if len(keep) != 0 && i == keep[0][0] {
+			out = append(out, LineEndings(src[i:keep[0][1]], LF)...)
+			i, fixed, blanks = keep[0][1], len(out), 0
+			keep = keep[1:]
+			continue
+		}
+
+		c := src[i]
+		i++
+		if c != '\n' && c != '\r' {
+			out = append(out, c)
+			continue
+		}
+		if c == '\r' && i < len(src) && src[i] == '\n' {
+			i++
+		}
+
+		out = trim(out, fixed)
+		if len(out) == lineStart {
+			if blanks++; blanks > 1 || len(out) == 0 {
+				continue
+			}
+		} else {
+			blanks = 0
+		}
+		out = append(out, '\n')
+		lineStart = len(out)
+	}
+
+	out = trim(out, fixed)
+	for len(out) > fixed && out[len(out)-1] == '\n' {
+		out = out[:len(out)-1]
+	}
+	if len(out) != 0 {
+		out = append(out, '\n')
+	}
+	return out, nil
+}
+
+// trim 删除 out 末尾 fixed 之后的空格和制表符
+func trim(out []byte, fixed int) []byte {
+	for len(out) > fixed && (out[len(out)-1] == ' ' || out[len(out)-1] == '\t') {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+func multiline(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' || s[i] == '\r' {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package format_test
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ZxxLang/zxx/format"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestSource(t *testing.T) {
+	for _, s := range [][2]string{
+		{"", ""},
+		{"var a = 1", "var a = 1\n"},
+		{"\n\nvar a = 1  \r\n\r\n\r\n\r\nvar b = 2\t\n\n\n", "var a = 1\n\nvar b = 2\n"},
+		{"proc main()\n\techo 'a  \n  b'  \n", "proc main()\n\techo 'a  \n  b'\n"},
+		{"var s = \"x {a}  \n\n\n y\"\n", "var s = \"x {a}  \n\n\n y\"\n"},
+		{"note \r\n--- c ---  \rvar a = 1\r", "note\n--- c ---\nvar a = 1\n"},
+		{"var s = 'a  \r\n b\r c'\r\n", "var s = 'a  \n b\n c'\n"},
+	} {
+		out, err := format.Source([]byte(s[0]))
+		if err != nil {
+			t.Fatal(s[0], err)
+		}
+		if string(out) != s[1] {
+			t.Fatalf("%q", out)
+		}
+		// 格式化是幂等的
+		if again, _ := format.Source(out); string(again) != string(out) {
+			t.Fatalf("%q", again)
+		}
+	}
+
+	if _, err := format.Source([]byte("var a = 'x")); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	src := []byte("var a = 1\r\nvar s = 'x\n y'\rvar b = 2\r\n")
+	n := format.CountEndings(src)
+	if n != (format.Endings{LF: 1, CRLF: 2, CR: 1}) || !n.Mixed() || n.Majority() != format.LF {
+		t.Fatal(n, n.Mixed(), n.Majority())
+	}
+	if n = format.CountEndings([]byte("a\r\nb\r\n\n")); n.Majority() != format.CRLF {
+		t.Fatal(n)
+	}
+	if format.CountEndings([]byte("a\r\nb\r\n")).Mixed() {
+		t.Fatal("want not mixed")
+	}
+
+	if out := format.LineEndings(src, format.CRLF); string(out) != "var a = 1\r\nvar s = 'x\r\n y'\r\nvar b = 2\r\n" {
+		t.Fatalf("%q", out)
+	}
+	edits := format.EOLEdits(src, format.LF)
+	if len(edits) != 3 || edits[0].Pos != 9 || edits[0].End != 11 || edits[0].NewText != "\n" {
+		t.Fatal(edits)
+	}
+	out, err := parser.ApplyEdits(src, edits)
+	if err != nil || string(out) != "var a = 1\nvar s = 'x\n y'\nvar b = 2\n" {
+		t.Fatalf("%q %v", out, err)
+	}
+	if !bytes.Equal(format.LineEndings(out, format.LF), out) {
+		t.Fatal("want unchanged")
+	}
+
+	if e, err := format.ParseEOL("crlf"); e != format.CRLF || err != nil {
+		t.Fatal(e, err)
+	}
+	if _, err := format.ParseEOL("cr"); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	for _, s := range [][2]string{
+		{"var a = 0xff_ff + 0x1fffff\n", "var a = 0xFFFF + 0x1F_FFFF\n"},
+		{"var a = 1000000 + 1_0_0 + 10000 + 0b1010101\n", "var a = 1_000_000 + 100 + 10_000 + 0b101_0101\n"},
+		{"var a = 20160204\n", "var a = 20160204\n"},
+		{"var a = 1_0.5e+021 + 12345.678\n", "var a = 10.5e21 + 12_345.678\n"},
+		{"var a = 1e-05 + 2e+3 + 1.5e-0\n", "var a = 1e-5 + 2e3 + 1.5e0\n"},
+		{"var a = 20160204T21:49+08 + 20160204T2149\n", "var a = 20160204T21:49:00+08:00 + 20160204T21:49:00\n"},
+		{"var a = 21:49 + 20160204T + 20160204Z\n", "var a = 21:49:00 + 20160204T + 20160204TZ\n"},
+		{"var s = \"abc\" + \"a\\tb\" + `x\\t` + 'y'\n", "var s = 'abc' + \"a\\tb\" + `x\\t` + 'y'\n"},
+		{"var s = \"a\n  b\"\n", "var s = \"a\n  b\"\n"},
+	} {
+		out, err := format.Literals([]byte(s[0]))
+		if err != nil {
+			t.Fatal(s[0], err)
+		}
+		if string(out) != s[1] {
+			t.Fatalf("%q", out)
+		}
+		if again, _ := format.Literals(out); string(again) != string(out) {
+			t.Fatalf("%q", again)
+		}
+	}
+}
+
+func TestQuotes(t *testing.T) {
+	src := "var s = \"abc\" + 'x{y}' + \"it's\" + \"a\\tb\" + `r` + \"i{s}\" + 'p\\q'\n"
+	for quote, want := range map[byte]string{
+		'\'': "var s = 'abc' + 'x{y}' + \"it's\" + \"a\\tb\" + `r` + \"i{s}\" + 'p\\q'\n",
+		'"':  "var s = \"abc\" + \"x\\{y}\" + \"it's\" + \"a\\tb\" + `r` + \"i{s}\" + \"p\\\\q\"\n",
+	} {
+		out, err := format.Quotes([]byte(src), quote)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != want {
+			t.Fatalf("%c %q", quote, out)
+		}
+	}
+	if _, err := format.Quotes([]byte(src), '`'); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestReindent(t *testing.T) {
+	for _, s := range []struct {
+		src    string
+		indent format.Indent
+		want   string
+	}{
+		{"var a = (\n\t1\n\t\t2\n)\n", format.Indent{format.Spaces, 4}, "var a = (\n    1\n        2\n)\n"},
+		{"var a = (\n  1\n    2\n   3\n)\n", format.Indent{format.Spaces, 4}, "var a = (\n    1\n        2\n     3\n)\n"},
+		{"var a = (\n  1\n    2\n   3\n)\n", format.Indent{format.Tabs, 4}, "var a = (\n\t1\n\t\t2\n\t\t3\n)\n"},
+		{"var a = (\n\t1\n    \t2\n)\n", format.Indent{format.Tabs, 4}, "var a = (\n\t1\n\t\t2\n)\n"},
+		{"proc main()\n\techo 'a\n\t  b'\n", format.Indent{format.Spaces, 2}, "proc main()\n  echo 'a\n\t  b'\n"},
+		{"proc main()\n\t// c\n\n\techo 1\n", format.Indent{format.Spaces, 4}, "proc main()\n    // c\n\n    echo 1\n"},
+	} {
+		out, err := format.Reindent([]byte(s.src), s.indent)
+		if err != nil {
+			t.Fatal(s.src, err)
+		}
+		if string(out) != s.want {
+			t.Fatalf("%q", out)
+		}
+		if again, _ := format.Reindent(out, s.indent); string(again) != string(out) {
+			t.Fatalf("%q", again)
+		}
+	}
+	if _, err := format.Reindent(nil, format.Indent{format.Spaces, 0}); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.zxx":       {Data: []byte("var a = 0xff  \n")},
+		"b.zxx":       {Data: []byte("var b = 1\n")},
+		"sub/c.zxx":   {Data: []byte("var c = 'x\n")},
+		"sub/d.zxx":   {Data: []byte("\n\nvar d = 2")},
+		"sub/note.md": {Data: []byte("note  \n")},
+	}
+	var mu sync.Mutex
+	written := map[string]string{}
+	cfg := format.Config{Literals: true, Workers: 2, Write: func(path string, out []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if path == "sub/d.zxx" {
+			return errors.New("read-only")
+		}
+		written[path] = string(out)
+		return nil
+	}}
+	results, err := format.Dir(fsys, ".", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Path+" "+r.Status.String())
+	}
+	want := []string{"a.zxx changed", "b.zxx unchanged", "sub/c.zxx failed", "sub/d.zxx failed"}
+	if len(got) != len(want) {
+		t.Fatal(got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatal(got)
+		}
+	}
+	if len(written) != 1 || written["a.zxx"] != "var a = 0xFF\n" || results[3].Err.Error() != "read-only" {
+		t.Fatal(written, results[3].Err)
+	}
+
+	cfg.DryRun = true
+	written = map[string]string{}
+	if results, err = format.Dir(fsys, "sub/d.zxx", cfg); err != nil || len(results) != 1 ||
+		results[0].Status != format.Changed || string(results[0].Out) != "var d = 2\n" || len(written) != 0 {
+		t.Fatal(results, err)
+	}
+	if _, err = format.Dir(fsys, "missing", cfg); err == nil {
+		t.Fatal("want error")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// IndentStyle 是缩进使用的字符
+type IndentStyle int
+
+const (
+	Tabs   IndentStyle = iota // 每级缩进一个 TAB
+	Spaces                    // 每级缩进 Width 个空格
+)
+
+// Indent 是转换的目标缩进风格.
+// Width 是 Spaces 风格每级缩进的空格数, 源码中没有纯空格缩进时也是一个 TAB 相当的空格数.
+type Indent struct {
+	Style IndentStyle
+	Width int
+}
+
+// IndentEdits 返回把 src 中每行的缩进改为 indent 风格的修改, 包括续行和混搭的缩进.
+//
+// 源码中只由空格组成的缩进, 以最小的宽度作为一级, TAB 前进到一级的整数倍.
+// 转换为 Spaces 时, 不足一级的部分作为对齐保留, 转换为 Tabs 时向上取整.
+// 注释行的缩进同样被转换, 跨行字符串和块注释的内容保持原样. 如果 src 不能被扫描, 返回错误.
+func IndentEdits(src []byte, indent Indent) ([]parser.TextEdit, error) {
+	if indent.Style != Tabs && indent.Style != Spaces || indent.Width <= 0 {
+		return nil, errors.New("format: invalid indent")
+	}
+	syms, err := parser.FastMixed(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	// 代码行的缩进是 INDENTATION, 注释行和空白行的缩进在 PLACEHOLDER 之中
+	var starts []scanner.Pos
+	for _, sym := range syms {
+		switch sym.Tok {
+		case token.INDENTATION:
+			starts = append(starts, sym.Pos)
+		case token.PLACEHOLDER:
+			for i := 0; i < len(sym.Source); i++ {
+				if i == 0 && (sym.Pos == 0 || src[sym.Pos-1] == '\n') || i != 0 && sym.Source[i-1] == '\n' {
+					starts = append(starts, sym.Pos.Offset(i))
+				}
+			}
+		}
+	}
+
+	unit := 0
+	olds := make([]string, len(starts))
+	for i, pos := range starts {
+		end, spaces := int(pos), true
+		for ; end < len(src) && (src[end] == ' ' || src[end] == '\t'); end++ {
+			spaces = spaces && src[end] == ' '
+		}
+		olds[i] = string(src[pos:end])
+		// 空白行不参与判断
+		if spaces && end < len(src) && src[end] != '\n' && src[end] != '\r' &&
+			(unit == 0 || len(olds[i]) < unit) {
+			unit = len(olds[i])
+		}
+	}
+	if unit == 0 {
+		unit = indent.Width
+	}
+
+	var edits []parser.TextEdit
+	for i, pos := range starts {
+		width := 0
+		for j := 0; j < len(olds[i]); j++ {
+			if olds[i][j] == '\t' {
+				width += unit - width%unit
+			} else {
+				width++
+			}
+		}
+		text := strings.Repeat("\t", (width+unit-1)/unit)
+		if indent.Style == Spaces {
+			text = strings.Repeat(" ", width/unit*indent.Width+width%unit)
+		}
+		if text != olds[i] {
+			edits = append(edits, parser.TextEdit{
+				Pos:     pos,
+				End:     pos.Offset(len(olds[i])),
+				NewText: text,
+			})
+		}
+	}
+	return edits, nil
+}
+
+// Reindent 返回缩进改为 indent 风格之后的 src, 参见 IndentEdits.
+func Reindent(src []byte, indent Indent) ([]byte, error) {
+	edits, err := IndentEdits(src, indent)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ApplyEdits(src, edits)
+}
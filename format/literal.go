@@ -0,0 +1,179 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Literals 返回字面值写法统一之后的 src, 字面值的值不变:
+//
+//	0xff_ff         0xFFFF, 十六进制数字大写
+//	1000000, 1_0_0  1_000_000, 100, 十进制超过 4 位时每 3 位分组, 其它进制每 4 位
+//	1_0.5e+021      10.5e21, 指数去掉 '+' 和前导零
+//	20160204T2149   20160204T21:49:00, 时间为 HH:MM:SS, 时区为 Z 或 +HH:MM
+//	"abc"           'abc', 优先使用不需要转义的单引号
+//
+// 8 位的十进制整数可能表示日期, 保持原样. 跨行字符串和原始字符串保持原样.
+// 如果 src 不能被扫描, 返回错误.
+func Literals(src []byte) ([]byte, error) {
+	syms, err := parser.Fast(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	var edits []parser.TextEdit
+	for _, sym := range syms {
+		lit := literal(sym.Tok, sym.Source)
+		if lit != sym.Source && same(sym.Tok, sym.Source, lit) {
+			edits = append(edits, parser.TextEdit{
+				Pos:     sym.Pos,
+				End:     sym.Pos.Offset(len(sym.Source)),
+				NewText: lit,
+			})
+		}
+	}
+	return parser.ApplyEdits(src, edits)
+}
+
+// literal 返回字面值 lit 的规范写法
+func literal(tok token.Token, lit string) string {
+	switch tok {
+	case token.VALINTEGER:
+		return integer(lit)
+	case token.VALFLOAT:
+		return float(lit)
+	case token.VALDATETIME:
+		return datetime(lit)
+	case token.VALSTRING:
+		if lit == "" || lit[0] == '`' || multiline(lit) {
+			return lit
+		}
+		if s, err := lexutil.Unquote(lit); err == nil {
+			return lexutil.Quote(s)
+		}
+	}
+	return lit
+}
+
+// same 返回 lit 和 canon 是否是值相同的同类字面值
+func same(tok token.Token, lit, canon string) bool {
+	if t, err := lexutil.Classify(canon); err != nil || t != tok {
+		return false
+	}
+	var x, y interface{}
+	var err1, err2 error
+	switch tok {
+	case token.VALSTRING:
+		x, err1 = lexutil.Unquote(lit)
+		y, err2 = lexutil.Unquote(canon)
+	case token.VALDATETIME:
+		a, err1 := lexutil.ParseDatetime(lit)
+		b, err2 := lexutil.ParseDatetime(canon)
+		return err1 == nil && err2 == nil && a.Equal(b)
+	default:
+		x, err1 = token.Value(tok, lit)
+		y, err2 = token.Value(tok, canon)
+	}
+	return err1 == nil && err2 == nil && reflect.DeepEqual(x, y)
+}
+
+func integer(lit string) string {
+	lit = strings.Replace(lit, "_", "", -1)
+	if len(lit) > 2 && lit[0] == '0' && (lit[1] == 'x' || lit[1] == 'o' || lit[1] == 'b') {
+		return lit[:2] + group(strings.ToUpper(lit[2:]), 4)
+	}
+	if len(lit) == 8 {
+		return lit
+	}
+	return group(lit, 3)
+}
+
+func float(lit string) string {
+	if lit == "nan" || lit == "infinite" {
+		return lit
+	}
+	lit = strings.Replace(lit, "_", "", -1)
+	mantissa, exp := lit, ""
+	if i := strings.IndexByte(lit, 'e'); i != -1 {
+		mantissa, exp = lit[:i], strings.TrimPrefix(lit[i+1:], "+")
+		sign := ""
+		if strings.HasPrefix(exp, "-") {
+			sign, exp = "-", exp[1:]
+		}
+		if exp = strings.TrimLeft(exp, "0"); exp == "" {
+			exp, sign = "0", ""
+		}
+		exp = "e" + sign + exp
+	}
+	whole, frac := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i != -1 {
+		whole, frac = mantissa[:i], mantissa[i:]
+	}
+	return group(whole, 3) + frac + exp
+}
+
+// group 在数字超过 4 位时从右向左每 n 位插入分隔符 '_'
+func group(digits string, n int) string {
+	if len(digits) <= 4 {
+		return digits
+	}
+	var b strings.Builder
+	for i := 0; i < len(digits); i++ {
+		if i != 0 && (len(digits)-i)%n == 0 {
+			b.WriteByte('_')
+		}
+		b.WriteByte(digits[i])
+	}
+	return b.String()
+}
+
+func datetime(lit string) string {
+	var b strings.Builder
+	rest := lit
+	n := 0
+	for n < len(rest) && rest[n] >= '0' && rest[n] <= '9' {
+		n++
+	}
+	if n == 8 {
+		b.WriteString(rest[:8])
+		rest = strings.TrimPrefix(rest[8:], "T")
+	}
+
+	i := strings.IndexAny(rest, "Z+")
+	if i == -1 {
+		i = len(rest)
+	}
+	clock, zone := strings.Replace(rest[:i], ":", "", -1), rest[i:]
+	if len(clock) != 0 && len(clock) != 4 && len(clock) != 6 {
+		return lit
+	}
+	if clock != "" {
+		if b.Len() != 0 {
+			b.WriteByte('T')
+		}
+		clock += "0000"[:6-len(clock)]
+		b.WriteString(clock[:2] + ":" + clock[2:4] + ":" + clock[4:6])
+	}
+
+	if strings.HasPrefix(zone, "+") {
+		zone = strings.Replace(zone[1:], ":", "", -1)
+		if len(zone) != 2 && len(zone) != 4 {
+			return lit
+		}
+		zone += "00"[:4-len(zone)]
+		zone = "+" + zone[:2] + ":" + zone[2:]
+	}
+	if clock == "" && b.Len() != 0 {
+		// 没有时间的日期保留 T, 否则是整数
+		b.WriteByte('T')
+	}
+	b.WriteString(zone)
+	return b.String()
+}
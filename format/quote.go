@@ -0,0 +1,68 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"errors"
+
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// QuoteEdits 返回把 src 中的单双引号字符串改用引号 quote 的修改, quote 是单引号或双引号.
+// 内容按需重新转义, 值发生变化的字符串不被修改, 例如包含单引号的字符串不能使用单引号,
+// 插值字符串, 跨行字符串和原始字符串也保持原样. 编辑器可以把它们作为快速修复.
+func QuoteEdits(src []byte, quote byte) ([]parser.TextEdit, error) {
+	if quote != '\'' && quote != '"' {
+		return nil, errors.New("format: invalid quote " + string(quote))
+	}
+	syms, err := parser.Fast(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	var edits []parser.TextEdit
+	for _, sym := range syms {
+		if lit, ok := requote(sym, quote); ok {
+			edits = append(edits, parser.TextEdit{
+				Pos:     sym.Pos,
+				End:     sym.Pos.Offset(len(sym.Source)),
+				NewText: lit,
+			})
+		}
+	}
+	return edits, nil
+}
+
+// Quotes 返回字符串统一使用引号 quote 之后的 src, 参见 QuoteEdits.
+func Quotes(src []byte, quote byte) ([]byte, error) {
+	edits, err := QuoteEdits(src, quote)
+	if err != nil {
+		return nil, err
+	}
+	return parser.ApplyEdits(src, edits)
+}
+
+// requote 返回 sym 改用引号 quote 的写法, 不需要或不能修改时 ok 为 false.
+func requote(sym parser.Symbol, quote byte) (lit string, ok bool) {
+	old := sym.Source
+	if sym.Tok != token.VALSTRING || old == "" || old[0] == quote || old[0] == '`' || multiline(old) {
+		return
+	}
+	s, err := lexutil.Unquote(old)
+	if err != nil {
+		return
+	}
+	if quote == '\'' {
+		lit, ok = lexutil.QuoteSingle(s)
+	} else {
+		lit, ok = lexutil.QuoteDouble(s), true
+	}
+	if ok {
+		v, err := lexutil.Unquote(lit)
+		ok = err == nil && v == s
+	}
+	return
+}
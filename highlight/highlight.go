@@ -0,0 +1,164 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包基于 parser.Fast 对源码进行语法高亮分类,
+// 并以 HTML, ANSI 终端着色和 LSP semantic tokens 三种形式输出.
+// Resolve 用 index.Idents 得到的符号表区分名字的声明处和引用处.
+package highlight
+
+import (
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Class 是高亮分类
+type Class int
+
+const (
+	Plain    Class = iota // 换行, 缩进等不着色的文本
+	Keyword               // 声明, 语句保留字和预定义类型
+	Literal               // 字面值
+	Comment               // 注释和占位文本
+	Ident                 // 标识符和成员
+	Operator              // 运算符, 赋值, 分界和成对符号
+	DeclName              // 声明的名字
+)
+
+var classNames = [...]string{
+	Plain:    "plain",
+	Keyword:  "keyword",
+	Literal:  "literal",
+	Comment:  "comment",
+	Ident:    "ident",
+	Operator: "operator",
+	DeclName: "decl",
+}
+
+func (c Class) String() string {
+	if c >= 0 && int(c) < len(classNames) {
+		return classNames[c]
+	}
+	return "plain"
+}
+
+// Span 是一段分类后的源码
+type Span struct {
+	Pos    scanner.Pos
+	Tok    token.Token
+	Source string
+	Class  Class
+	Mods   Modifier // Spans 只设置 DeclName 的 Declaration, 参见 Resolve
+}
+
+// End 返回 Span 的结束位置
+func (s Span) End() scanner.Pos { return s.Pos + scanner.Pos(len(s.Source)) }
+
+// Spans 返回 src 中除 Plain 之外的全部 Span, 按 Pos 排序.
+//
+// 声明的名字使用启发式规则识别: 声明保留字之后, 在 '=', '(' 或换行之前的最后一个标识符,
+// 逗号分隔多个名字. 分组声明中每一行都是一个声明.
+func Spans(src []byte) ([]Span, error) {
+	syms, err := parser.Fast(src, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		spans []Span
+		decl  bool // 正在声明, 等待名字
+		name  = -1 // 候选名字在 spans 中的序号
+		depth int  // 声明中类型参数的括号深度
+		group int  // 分组声明的括号深度, 0 表示不在分组中
+		level int  // 当前括号深度
+		prev  token.Token
+	)
+
+	// done 确定候选名字
+	done := func() {
+		if name >= 0 {
+			spans[name].Class, spans[name].Mods = DeclName, Declaration
+			name = -1
+		}
+	}
+
+	for _, sym := range syms {
+		class := classify(sym.Tok)
+		switch tok := sym.Tok; {
+		case tok == token.LEFT:
+			level++
+			switch {
+			case decl && prev.As(token.Declare):
+				group, decl = level, false
+			case decl && name >= 0:
+				done()
+				decl = false
+			case decl:
+				depth++
+			}
+		case tok == token.RIGHT:
+			if depth > 0 {
+				depth--
+			} else if level == group {
+				group = 0
+			}
+			level--
+		case tok == token.NL:
+			done()
+			decl = group != 0 && level == group
+		case !decl || depth > 0:
+		case tok.As(token.Declare):
+			// pub var 等连续的声明保留字
+		case tok == token.ASSIGN:
+			done()
+			decl = false
+		case tok == token.COMMA:
+			done()
+		case tok == token.IDENT || isWord(tok, sym.Source):
+			if name >= 0 {
+				spans[name].Class, spans[name].Mods = Ident, 0
+			}
+			name, class = len(spans), Ident
+		}
+
+		if tok := sym.Tok; tok.As(token.Declare) {
+			decl, depth, name = true, 0, -1
+		}
+		if !trivia(sym.Tok) {
+			prev = sym.Tok
+		}
+		if class != Plain {
+			spans = append(spans, Span{sym.Pos, sym.Tok, sym.Source, class, 0})
+		}
+	}
+	done()
+	return spans, nil
+}
+
+// trivia 返回 tok 是否为不影响声明识别的 Token
+func trivia(tok token.Token) bool {
+	return tok != token.NL && tok.Is(token.ClassTrivia)
+}
+
+// isWord 返回由字母组成的运算符保留字 tok 是否可以作为声明的名字, 例如 func add
+func isWord(tok token.Token, code string) bool {
+	return tok.As(token.Operator) && code != "" && (code[0] >= 'a' && code[0] <= 'z' || code[0] >= 'A' && code[0] <= 'Z')
+}
+
+// classify 返回 tok 的分类
+func classify(tok token.Token) Class {
+	switch {
+	case tok.Is(token.ClassDeclare | token.ClassStatement | token.ClassType):
+		return Keyword
+	case tok.Is(token.ClassLiteral):
+		return Literal
+	case tok == token.COMMENT, tok == token.COMMENTS, tok == token.PLACEHOLDER, tok == token.EMPTYLINE:
+		return Comment
+	case tok == token.EOF, tok.Is(token.ClassTrivia):
+		return Plain
+	case tok == token.IDENT, tok == token.MEMBER, tok == token.MEMBERS, tok == token.SUGAR:
+		return Ident
+	}
+	return Operator
+}
@@ -0,0 +1,144 @@
+package highlight_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ZxxLang/zxx/highlight"
+)
+
+func TestSpans(t *testing.T) {
+	src := []byte(`note
+var (
+	string name = "a{b}c" // c
+	int z, i = 4
+)
+var map[string,int] m
+func int add(int a, int b)
+	out a + b
+`)
+	spans, err := highlight.Spans(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decls, idents []string
+	for _, s := range spans {
+		switch s.Class {
+		case highlight.DeclName:
+			decls = append(decls, s.Source)
+		case highlight.Ident:
+			idents = append(idents, s.Source)
+		}
+	}
+	if want := []string{"name", "z", "i", "m", "add"}; !reflect.DeepEqual(decls, want) {
+		t.Fatal(decls)
+	}
+	if want := []string{"b", "a", "b", "a", "b"}; !reflect.DeepEqual(idents, want) {
+		t.Fatal(idents)
+	}
+	if spans[0].Class != highlight.Comment || spans[1].Class != highlight.Keyword {
+		t.Fatal(spans[:2])
+	}
+}
+
+func TestRender(t *testing.T) {
+	src := []byte("var a = 'x<y'\n")
+
+	var buf bytes.Buffer
+	if err := highlight.HTML(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	want := `<span class="zxx-keyword">var</span> <span class="zxx-decl">a</span> ` +
+		`<span class="zxx-operator">=</span> <span class="zxx-literal">&#39;x&lt;y&#39;</span>` + "\n"
+	if buf.String() != want {
+		t.Fatal(buf.String())
+	}
+
+	buf.Reset()
+	if err := highlight.ANSI(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	want = "\x1b[1;34mvar\x1b[0m \x1b[1;36ma\x1b[0m \x1b[33m=\x1b[0m \x1b[32m'x<y'\x1b[0m\n"
+	if buf.String() != want {
+		t.Fatalf("%q", buf.String())
+	}
+
+	// 跨行的字符串逐行着色
+	buf.Reset()
+	if err := highlight.ANSI(&buf, []byte("var s = 'a\n\n  b'\n")); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\x1b[32m'a\x1b[0m\n\n\x1b[32m  b'\x1b[0m\n")) {
+		t.Fatalf("%q", buf.String())
+	}
+}
+
+func TestSemantic(t *testing.T) {
+	// 跨行的块注释被拆分, 列以 UTF-16 为单位
+	src := []byte("var a = '名'\n---\n😀\n---\n")
+	data, err := highlight.Semantic(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint32{
+		0, 0, 3, 0, 0, // var
+		0, 4, 1, 4, 1, // a
+		0, 2, 1, 5, 0, // =
+		0, 2, 3, 1, 0, // '名'
+		1, 0, 3, 3, 0, // ---
+		1, 0, 2, 3, 0, // 😀
+		1, 0, 3, 3, 0, // ---
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Fatal(data)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	src := []byte(`use 'fmt'
+var int total = 0 // Deprecated: 使用 sum
+proc count(int n) [
+	for i = 0; i < n; i++ [
+		total = total + i
+	]
+	fmt.print(n, missing)
+	print(n)
+]
+`)
+	names := &highlight.Names{Known: func(name string) bool { return name == "print" }}
+	spans, err := highlight.Resolve(src, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range spans {
+		if s.Class != highlight.Ident && s.Class != highlight.DeclName {
+			continue
+		}
+		mod := ""
+		if s.Mods&highlight.Declaration != 0 {
+			mod += "D"
+		}
+		if s.Mods&highlight.Deprecated != 0 {
+			mod += "X"
+		}
+		if s.Mods&highlight.Unresolved != 0 {
+			mod += "?"
+		}
+		got = append(got, s.Source+mod)
+	}
+	want := []string{"totalDX", "countD", "nD", "iD", "i", "n", "i", "totalX", "totalX", "i",
+		"fmt.print", "n", "missing?", "print", "n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+
+	// 没有 names 时不标记 unresolved
+	spans, _ = highlight.Resolve(src, nil)
+	for _, s := range spans {
+		if s.Source == "missing" && s.Mods != 0 {
+			t.Fatal(s)
+		}
+	}
+}
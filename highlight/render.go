@@ -0,0 +1,149 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package highlight
+
+import (
+	"bytes"
+	"html"
+	"io"
+
+	"github.com/ZxxLang/zxx/position"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// render 依次输出 spans 之间的文本和 spans, 由 span 决定 Span 的输出
+func render(w io.Writer, src []byte, text func(*bytes.Buffer, string), span func(*bytes.Buffer, Span)) error {
+	spans, err := Spans(src)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	offset := 0
+	for _, s := range spans {
+		text(&buf, string(src[offset:s.Pos]))
+		span(&buf, s)
+		offset = int(s.End())
+	}
+	text(&buf, string(src[offset:]))
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// HTML 以 HTML 片段格式输出 src, 每个 Span 是 CSS 类名为 "zxx-" + Class.String() 的 span 元素.
+// 输出不包括外层的 pre 元素.
+func HTML(w io.Writer, src []byte) error {
+	return render(w, src, func(buf *bytes.Buffer, s string) {
+		buf.WriteString(html.EscapeString(s))
+	}, func(buf *bytes.Buffer, s Span) {
+		buf.WriteString(`<span class="zxx-` + s.Class.String() + `">`)
+		buf.WriteString(html.EscapeString(s.Source))
+		buf.WriteString("</span>")
+	})
+}
+
+// ANSI 以 ANSI 转义序列着色的文本格式输出 src, 用于终端显示. 标识符不着色.
+// 跨行的 Span 逐行着色, 每一行的转义序列都是闭合的, 可以在行首插入行号等内容.
+func ANSI(w io.Writer, src []byte) error {
+	return render(w, src, func(buf *bytes.Buffer, s string) {
+		buf.WriteString(s)
+	}, func(buf *bytes.Buffer, s Span) {
+		color := ansiColors[s.Class]
+		if color == "" {
+			buf.WriteString(s.Source)
+			return
+		}
+		last := 0
+		for _, seg := range lines(s.Source) {
+			buf.WriteString(s.Source[last:seg[0]])
+			buf.WriteString("\x1b[" + color + "m" + s.Source[seg[0]:seg[1]] + "\x1b[0m")
+			last = seg[1]
+		}
+		buf.WriteString(s.Source[last:])
+	})
+}
+
+var ansiColors = [...]string{
+	Keyword:  "1;34",
+	Literal:  "32",
+	Comment:  "90",
+	Ident:    "",
+	Operator: "33",
+	DeclName: "1;36",
+}
+
+// LSP semantic tokens 的图例, Semantic 返回的类型是其中的序号, 修饰位的第 i 位对应 TokenModifiers[i]
+var (
+	TokenTypes     = []string{"keyword", "string", "number", "comment", "variable", "operator"}
+	TokenModifiers = []string{"declaration", "deprecated", "unresolved"}
+)
+
+// Semantic 返回 src 的 LSP semantic tokens 数据, 使用 TokenTypes, TokenModifiers 图例.
+// 每个 token 由 5 个整数组成: 相对上个 token 的行号差, 起始列差, 长度, 类型, 修饰位.
+// 列和长度以 UTF-16 编码单元为单位. 跨行的注释, 字符串被拆分为每行一个 token.
+// 修饰位即 Span.Mods, 来自 Resolve(src, nil).
+func Semantic(src []byte) ([]uint32, error) {
+	return SemanticNames(src, nil)
+}
+
+// SemanticNames 同 Semantic, 但用 names 解析文件之外的名字, 引用未声明的名字有 unresolved 修饰.
+func SemanticNames(src []byte, names *Names) ([]uint32, error) {
+	spans, err := Resolve(src, names)
+	if err != nil {
+		return nil, err
+	}
+
+	file := scanner.NewFileSet().AddFile("", src)
+	conv := position.New(file, src)
+	data := make([]uint32, 0, len(spans)*5)
+	var lastLine, lastChar int
+	for _, s := range spans {
+		typ, mod := semantic(s), uint32(s.Mods)
+		start := int(s.Pos)
+		for _, seg := range lines(s.Source) {
+			line, char, _ := conv.Offset(file.Pos(start+seg[0]), position.UTF16)
+			_, end, _ := conv.Offset(file.Pos(start+seg[1]), position.UTF16)
+			if line != lastLine {
+				lastChar = 0
+			}
+			data = append(data, uint32(line-lastLine), uint32(char-lastChar), uint32(end-char), typ, mod)
+			lastLine, lastChar = line, char
+		}
+	}
+	return data, nil
+}
+
+// semantic 返回 s 在 TokenTypes 中的类型
+func semantic(s Span) uint32 {
+	switch s.Class {
+	case Keyword:
+		return 0
+	case Literal:
+		if s.Tok == token.VALSTRING || s.Tok == token.STRINGLIT {
+			return 1
+		}
+		return 2
+	case Comment:
+		return 3
+	case DeclName, Ident:
+		return 4
+	}
+	return 5
+}
+
+// lines 返回 s 中每一行非空内容的字节区间, 不包括换行符
+func lines(s string) (segs [][2]int) {
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && s[i] != '\n' && s[i] != '\r' {
+			continue
+		}
+		if i > start {
+			segs = append(segs, [2]int{start, i})
+		}
+		start = i + 1
+	}
+	return
+}
@@ -0,0 +1,90 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package highlight
+
+import (
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/doc"
+	"github.com/ZxxLang/zxx/index"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// Modifier 是 Span 的修饰位
+type Modifier uint8
+
+const (
+	Declaration Modifier = 1 << iota // 名字的声明处, 未设置时是引用处
+	Deprecated                       // 引用或声明了废弃的名字
+	Unresolved                       // 引用了未声明的名字
+)
+
+// Names 是文件之外的名字表, 例如同一个包的其它文件和内置函数.
+type Names struct {
+	Known      func(name string) bool // 返回 name 是否已声明
+	Deprecated func(name string) bool // 返回已声明的 name 是否废弃, 可以为 nil
+}
+
+// Resolve 同 Spans, 并用文件的符号表设置标识符的 Mods.
+//
+// 符号表即 index.Idents 的结果. 文档含有以 "Deprecated:" 开始的行的顶层声明是废弃的.
+// names 为 nil 时不设置 Unresolved, 因为文件之外的名字未知.
+func Resolve(src []byte, names *Names) ([]Span, error) {
+	spans, err := Spans(src)
+	if err != nil {
+		return nil, err
+	}
+	// 无法识别的声明和语句是 BadSyntax, 其中的名字不被解析
+	sf, _ := parser.ParseSyntax(src)
+	if sf == nil {
+		return spans, nil
+	}
+
+	deprecated := map[string]bool{} // 文件中废弃的顶层名字
+	file := ast.NewFile()
+	if parser.Parse(src, file) == nil {
+		for _, d := range doc.New("", file).Decls {
+			if isDeprecated(d.Doc) {
+				for _, name := range d.Names {
+					deprecated[name] = true
+				}
+			}
+		}
+	}
+
+	mods := map[scanner.Pos]Modifier{}
+	for _, id := range index.Idents(sf) {
+		var m Modifier
+		if id.Decl {
+			m |= Declaration
+		}
+		switch {
+		case id.Scope == index.Package && deprecated[id.Name]:
+			m |= Deprecated
+		case id.Scope != index.Free || names == nil:
+		case names.Known == nil || !names.Known(id.Name):
+			m |= Unresolved
+		case names.Deprecated != nil && names.Deprecated(id.Name):
+			m |= Deprecated
+		}
+		mods[id.Pos] |= m
+	}
+	for i, s := range spans {
+		spans[i].Mods |= mods[s.Pos]
+	}
+	return spans, nil
+}
+
+// isDeprecated 返回文档 doc 是否含有以 "Deprecated:" 开始的行
+func isDeprecated(doc string) bool {
+	for _, line := range strings.Split(doc, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "Deprecated:") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,324 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ast/astutil"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Scope 是名字的作用域
+type Scope uint8
+
+const (
+	Free    Scope = iota // 文件中未声明, 可能来自同一个包的其它文件或者内置
+	Package              // 顶层声明, 在包的全部文件中可见
+	File                 // use 声明的名字, 只在所在文件中可见
+	Local                // 参数, 函数体中的声明和循环变量
+)
+
+var scopes = [...]string{"free", "package", "file", "local"}
+
+func (s Scope) String() string {
+	if int(s) < len(scopes) {
+		return scopes[s]
+	}
+	return "scope"
+}
+
+// Ident 是文件中名字的一次出现
+type Ident struct {
+	Name  string      // 名字, 成员只取首段
+	Pos   scanner.Pos // 在文件中的偏移量
+	Decl  bool        // 是否为声明处
+	Scope Scope       // 所引用的名字的作用域
+	Def   scanner.Pos // 同一文件中声明处的偏移量, Free 时为 -1
+	Pub   bool        // 是否为 pub 的顶层声明处, 其它包只能引用这样的名字
+}
+
+// Idents 返回 sf 中全部名字的出现, 按 Pos 排序.
+//
+// 顶层名字可以先使用后声明, 内层的名字遮蔽外层. for 初始化语句中首次赋值的名字是循环变量.
+// 没有别名的 use 声明以路径的最后一段为名字, 声明处是路径字符串.
+// 标签, BadSyntax 中的名字不被记录.
+func Idents(sf *ast.SourceFile) []Ident {
+	r := new(resolver)
+	r.file(sf)
+	sort.SliceStable(r.idents, func(i, j int) bool { return r.idents[i].Pos < r.idents[j].Pos })
+	return r.idents
+}
+
+// binding 是作用域中的名字
+type binding struct {
+	scope Scope
+	def   scanner.Pos
+}
+
+type scope struct {
+	outer *scope
+	names map[string]binding
+}
+
+func (s *scope) lookup(name string) (b binding, ok bool) {
+	for ; s != nil; s = s.outer {
+		if b, ok = s.names[name]; ok {
+			return
+		}
+	}
+	return
+}
+
+// resolver 遍历 ParseSyntax 的结果, 记录名字的出现
+type resolver struct {
+	scope  *scope
+	idents []Ident
+}
+
+func (r *resolver) open()  { r.scope = &scope{r.scope, map[string]binding{}} }
+func (r *resolver) close() { r.scope = r.scope.outer }
+
+// define 在当前作用域声明 sym
+func (r *resolver) define(sym ast.Symbol, s Scope) {
+	r.scope.names[sym.Source] = binding{s, sym.Pos}
+	r.idents = append(r.idents, Ident{sym.Source, sym.Pos, true, s, sym.Pos, false})
+}
+
+// export 声明顶层名字 sym, pub 是 pub 的位置, 没有时为 -1
+func (r *resolver) export(sym ast.Symbol, pub scanner.Pos) {
+	r.define(sym, Package)
+	r.idents[len(r.idents)-1].Pub = pub >= 0
+}
+
+// use 记录位于 sym 的引用, 成员只解析首段
+func (r *resolver) use(sym ast.Symbol) {
+	if sym.Tok != token.IDENT && sym.Tok != token.MEMBER && sym.Tok != token.MEMBERS {
+		return
+	}
+	name := sym.Source
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" {
+		return
+	}
+	b, ok := r.scope.lookup(name)
+	if !ok {
+		b = binding{Free, -1}
+	}
+	r.idents = append(r.idents, Ident{name, sym.Pos, false, b.scope, b.def, false})
+}
+
+// types 记录类型 Token 中的名字
+func (r *resolver) types(syms []ast.Symbol) {
+	for _, sym := range syms {
+		r.use(sym)
+	}
+}
+
+func (r *resolver) expr(x ast.Expression) {
+	if x == nil {
+		return
+	}
+	astutil.Apply(x, func(c *astutil.Cursor) bool {
+		if id, ok := c.Node().(*ast.Ident); ok {
+			r.use(id.Name)
+		}
+		return true
+	}, nil)
+}
+
+func (r *resolver) exprs(list []ast.Expression) {
+	for _, x := range list {
+		r.expr(x)
+	}
+}
+
+// file 先声明全部顶层名字, 再解析声明的内容
+func (r *resolver) file(sf *ast.SourceFile) {
+	r.open()
+	for _, d := range sf.Decls {
+		switch d := d.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				if d.Tok != token.USE {
+					for _, id := range spec.Names {
+						r.export(id.Name, d.Pub)
+					}
+					continue
+				}
+				if len(spec.Names) != 0 {
+					r.define(spec.Names[0].Name, File)
+				} else if lit, ok := first(spec.Values).(*ast.BasicLit); ok && len(lit.Value.Source) > 2 {
+					// 没有别名时用路径的最后一段
+					path := lit.Value.Source[1 : len(lit.Value.Source)-1]
+					r.scope.names[path[strings.LastIndexByte(path, '/')+1:]] = binding{File, lit.Value.Pos}
+				}
+			}
+		case *ast.FuncDecl:
+			r.export(d.Name.Name, d.Pub)
+		case *ast.TypeDecl:
+			r.export(d.Name.Name, d.Pub)
+		}
+	}
+	for _, d := range sf.Decls {
+		switch d := d.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.USE {
+				r.types(d.Type)
+				for _, spec := range d.Specs {
+					r.types(spec.Type)
+					r.exprs(spec.Values)
+				}
+			}
+		case *ast.FuncDecl:
+			r.open()
+			r.typeParams(d.TypeParams)
+			for _, f := range d.Params {
+				r.types(f.Type)
+				if f.Name != nil {
+					r.define(f.Name.Name, Local)
+				}
+			}
+			r.types(d.Results)
+			r.block(d.Body)
+			r.close()
+		case *ast.TypeDecl:
+			r.open()
+			r.typeParams(d.TypeParams)
+			r.types(d.Type)
+			for _, spec := range d.Fields {
+				r.types(spec.Type)
+				r.exprs(spec.Values)
+			}
+			r.variants(d.Variants)
+			r.close()
+		}
+	}
+	r.close()
+}
+
+// variants 记录枚举变体关联值的类型, 变体名只能以 Type.Name 访问, 不是声明
+func (r *resolver) variants(list []*ast.Variant) {
+	for _, v := range list {
+		for _, f := range v.Params {
+			r.types(f.Type)
+		}
+	}
+}
+
+// typeParams 在当前作用域声明类型参数, 约束在声明之前解析
+func (r *resolver) typeParams(tp *ast.TypeParams) {
+	if tp == nil {
+		return
+	}
+	for _, f := range tp.List {
+		r.types(f.Type)
+	}
+	for _, f := range tp.List {
+		r.define(f.Name.Name, Local)
+	}
+}
+
+// first 返回 list 的第一个表达式, list 为空时返回 nil
+func first(list []ast.Expression) ast.Expression {
+	if len(list) == 0 {
+		return nil
+	}
+	return list[0]
+}
+
+func (r *resolver) block(b *ast.BlockStmt) {
+	if b == nil {
+		return
+	}
+	r.open()
+	for _, s := range b.List {
+		r.stmt(s)
+	}
+	r.close()
+}
+
+func (r *resolver) stmt(s ast.Syntax) {
+	switch s := s.(type) {
+	case *ast.GenDecl:
+		r.types(s.Type)
+		for _, spec := range s.Specs {
+			r.types(spec.Type)
+			r.exprs(spec.Values)
+			for _, id := range spec.Names {
+				r.define(id.Name, Local)
+			}
+		}
+	case *ast.TypeDecl:
+		r.define(s.Name.Name, Local)
+		r.open()
+		r.typeParams(s.TypeParams)
+		r.types(s.Type)
+		for _, spec := range s.Fields {
+			r.types(spec.Type)
+		}
+		r.variants(s.Variants)
+		r.close()
+	case *ast.ExprStmt:
+		r.expr(s.X)
+	case *ast.AssignStmt:
+		r.exprs(s.Rhs)
+		r.exprs(s.Lhs)
+	case *ast.IfStmt:
+		r.expr(s.Cond)
+		r.block(s.Body)
+		if s.Else != nil {
+			r.stmt(s.Else)
+		}
+	case *ast.BlockStmt:
+		r.block(s)
+	case *ast.ForStmt:
+		r.open()
+		if init, ok := s.Init.(*ast.AssignStmt); ok && init.Tok == token.ASSIGN {
+			// 首次赋值的名字是循环变量
+			r.exprs(init.Rhs)
+			for _, x := range init.Lhs {
+				if id, ok := x.(*ast.Ident); ok && id.Name.Tok == token.IDENT {
+					if _, ok := r.scope.lookup(id.Name.Source); !ok {
+						r.define(id.Name, Local)
+						continue
+					}
+				}
+				r.expr(x)
+			}
+		} else if s.Init != nil {
+			r.stmt(s.Init)
+		}
+		r.expr(s.Cond)
+		if s.Post != nil {
+			r.stmt(s.Post)
+		}
+		r.block(s.Body)
+		r.close()
+	case *ast.RangeStmt:
+		r.expr(s.X)
+		r.open()
+		r.define(s.Key.Name, Local)
+		if s.Value != nil {
+			r.define(s.Value.Name, Local)
+		}
+		r.block(s.Body)
+		r.close()
+	case *ast.SwitchStmt:
+		r.expr(s.Tag)
+		r.block(s.Body)
+	case *ast.CaseClause:
+		r.exprs(s.List)
+	case *ast.GoStmt:
+		r.expr(s.Call)
+	case *ast.OutStmt:
+		r.exprs(s.Results)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包建立一个包的全部文件的名字索引, 用于跳转到定义, 查找引用和重命名.
+//
+// 每个文件的名字出现由 Idents 从 parser.ParseSyntax 的结果得到.
+// 顶层名字在包的全部文件中可见, 文件中未声明的名字按名字匹配其它文件的顶层声明.
+// 目前没有导入解析, use 声明的名字只在所在文件中解析, 不跨越包.
+// 其它包只能引用 pub 声明的顶层名字, 参见 Exports.
+//
+// Index 以文件内容的散列为键增量更新, 可以保存到磁盘, 载入后只需重新索引改变的文件.
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// Version 是索引格式的版本, 改变 Idents 的结果时需要更新.
+const Version = "zxx-index-2"
+
+// Location 是名字在文件 File 中的位置
+type Location struct {
+	File string
+	Pos  scanner.Pos // 在文件中的偏移量
+}
+
+// Index 是一组文件的名字索引, 可以并发使用.
+type Index struct {
+	mu    sync.RWMutex
+	files map[string]*file
+}
+
+// file 是一个文件的索引
+type file struct {
+	Sum    [sha256.Size]byte // 文件内容的散列
+	Idents []Ident
+}
+
+// New 返回空的 Index
+func New() *Index {
+	return &Index{files: map[string]*file{}}
+}
+
+// Update 索引文件 name 的内容 src, 内容未改变时直接返回 false.
+// 无法识别的声明和语句中的名字不被索引, 其错误不影响结果, 只有扫描错误被返回,
+// 此时保留文件之前的索引.
+func (x *Index) Update(name string, src []byte) (changed bool, err error) {
+	sum := sha256.Sum256(src)
+	x.mu.RLock()
+	old := x.files[name]
+	x.mu.RUnlock()
+	if old != nil && old.Sum == sum {
+		return false, nil
+	}
+
+	syms, err := parser.Fast(src, nil)
+	if err != nil {
+		return false, err
+	}
+	sf, _ := parser.ParseSyntaxSymbols(syms)
+	f := &file{sum, Idents(sf)}
+
+	x.mu.Lock()
+	x.files[name] = f
+	x.mu.Unlock()
+	return true, nil
+}
+
+// Remove 删除文件 name 的索引
+func (x *Index) Remove(name string) {
+	x.mu.Lock()
+	delete(x.files, name)
+	x.mu.Unlock()
+}
+
+// Files 返回已索引的文件名, 按名字排序.
+func (x *Index) Files() []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	names := make([]string, 0, len(x.files))
+	for name := range x.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// At 返回文件 name 中位于 pos 的名字, pos 可以是名字中的任何位置.
+func (x *Index) At(name string, pos scanner.Pos) (Ident, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.at(name, pos)
+}
+
+func (x *Index) at(name string, pos scanner.Pos) (Ident, bool) {
+	f := x.files[name]
+	if f == nil {
+		return Ident{}, false
+	}
+	i := sort.Search(len(f.Idents), func(i int) bool { return f.Idents[i].Pos > pos }) - 1
+	if i < 0 || pos >= f.Idents[i].Pos.Offset(len(f.Idents[i].Name)) {
+		return Ident{}, false
+	}
+	return f.Idents[i], true
+}
+
+// Definition 返回文件 name 中位于 pos 的名字的声明处.
+// 顶层名字和文件中未声明的名字可能有多个声明处, 结果按文件名和位置排序.
+func (x *Index) Definition(name string, pos scanner.Pos) []Location {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	id, ok := x.at(name, pos)
+	if !ok {
+		return nil
+	}
+	if id.Scope == File || id.Scope == Local {
+		return []Location{{name, id.Def}}
+	}
+	return x.collect(func(_ string, v Ident) bool {
+		return v.Decl && v.Scope == Package && v.Name == id.Name
+	})
+}
+
+// References 返回文件 name 中位于 pos 的名字的全部出现, 包括声明处, 按文件名和位置排序.
+// 局部名字和 use 声明的名字只在所在文件中查找.
+func (x *Index) References(name string, pos scanner.Pos) []Location {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	id, ok := x.at(name, pos)
+	if !ok {
+		return nil
+	}
+	if id.Scope == File || id.Scope == Local {
+		var locs []Location
+		for _, v := range x.files[name].Idents {
+			if v.Scope == id.Scope && v.Def == id.Def && v.Name == id.Name {
+				locs = append(locs, Location{name, v.Pos})
+			}
+		}
+		return locs
+	}
+	return x.collect(func(_ string, v Ident) bool {
+		return (v.Scope == Package || v.Scope == Free) && v.Name == id.Name
+	})
+}
+
+// Exports 返回 pub 声明的顶层名字, 按名字排序.
+// 其它包只能引用这些名字, 为其它包补全时不应列出别的顶层名字.
+func (x *Index) Exports() []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	seen := map[string]bool{}
+	var names []string
+	for _, f := range x.files {
+		for _, v := range f.Idents {
+			if v.Pub && !seen[v.Name] {
+				seen[v.Name] = true
+				names = append(names, v.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// collect 返回全部文件中满足 match 的名字的位置
+func (x *Index) collect(match func(string, Ident) bool) (locs []Location) {
+	for name, f := range x.files {
+		for _, v := range f.Idents {
+			if match(name, v) {
+				locs = append(locs, Location{name, v.Pos})
+			}
+		}
+	}
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].File != locs[j].File {
+			return locs[i].File < locs[j].File
+		}
+		return locs[i].Pos < locs[j].Pos
+	})
+	return
+}
+
+// store 是保存到磁盘的内容
+type store struct {
+	Version string
+	Files   map[string]*file
+}
+
+// Save 把索引写入 w
+func (x *Index) Save(w io.Writer) error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return gob.NewEncoder(w).Encode(store{Version, x.files})
+}
+
+// Load 读取 Save 写入的索引, 版本不同时返回错误, 调用者应当重新建立索引.
+func Load(r io.Reader) (*Index, error) {
+	var s store
+	if err := gob.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version != Version {
+		return nil, errors.New("index: version " + s.Version + " is not " + Version)
+	}
+	if s.Files == nil {
+		s.Files = map[string]*file{}
+	}
+	return &Index{files: s.Files}, nil
+}
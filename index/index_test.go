@@ -0,0 +1,113 @@
+package index_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/index"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+const (
+	aSrc = `var int total = 0
+proc count(int n) [
+	for i = 0; i < n; i++ [
+		total = total + i
+	]
+]
+`
+	bSrc = `use 'fmt'
+proc show [
+	fmt.print(total)
+	count(1)
+]
+`
+)
+
+// at 返回 src 中第 n 个 s 的位置
+func at(src, s string, n int) scanner.Pos {
+	off := 0
+	for ; n > 0; n-- {
+		off += strings.Index(src[off:], s) + 1
+	}
+	return scanner.Pos(off + strings.Index(src[off:], s))
+}
+
+func TestIdents(t *testing.T) {
+	x := index.New()
+	if _, err := x.Update("a.zxx", []byte(aSrc)); err != nil {
+		t.Fatal(err)
+	}
+	id, ok := x.At("a.zxx", at(aSrc, "i < n", 0))
+	if !ok || id.Name != "i" || id.Decl || id.Scope != index.Local || id.Def != at(aSrc, "i = 0", 0) {
+		t.Fatal(id, ok)
+	}
+	if id, ok := x.At("a.zxx", at(aSrc, "total", 0)+2); !ok || !id.Decl || id.Scope != index.Package {
+		t.Fatal(id, ok)
+	}
+	if _, ok := x.At("a.zxx", at(aSrc, "=", 0)); ok {
+		t.Fatal("= is not a name")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	x := index.New()
+	for _, f := range []struct{ name, src string }{{"a.zxx", aSrc}, {"b.zxx", bSrc}} {
+		if changed, err := x.Update(f.name, []byte(f.src)); err != nil || !changed {
+			t.Fatal(f.name, changed, err)
+		}
+	}
+	if changed, _ := x.Update("a.zxx", []byte(aSrc)); changed {
+		t.Fatal("unchanged file is indexed again")
+	}
+
+	// 跨文件的定义和引用
+	def := x.Definition("b.zxx", at(bSrc, "total", 0))
+	if want := []index.Location{{"a.zxx", at(aSrc, "total", 0)}}; !reflect.DeepEqual(def, want) {
+		t.Fatal(def)
+	}
+	refs := x.References("a.zxx", at(aSrc, "total", 0))
+	want := []index.Location{
+		{"a.zxx", at(aSrc, "total", 0)}, {"a.zxx", at(aSrc, "total", 1)}, {"a.zxx", at(aSrc, "total", 2)},
+		{"b.zxx", at(bSrc, "total", 0)},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Fatal(refs)
+	}
+
+	// use 声明的名字只在所在文件中
+	if def := x.Definition("b.zxx", at(bSrc, "fmt.print", 0)); len(def) != 1 || def[0].Pos != at(bSrc, "'fmt'", 0) {
+		t.Fatal(def)
+	}
+
+	var buf bytes.Buffer
+	if err := x.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	y, err := index.Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := y.Update("b.zxx", []byte(bSrc)); changed || !reflect.DeepEqual(y.Files(), []string{"a.zxx", "b.zxx"}) {
+		t.Fatal(changed, y.Files())
+	}
+	if refs := y.References("b.zxx", at(bSrc, "count", 0)); len(refs) != 2 || refs[0].File != "a.zxx" {
+		t.Fatal(refs)
+	}
+
+	y.Remove("a.zxx")
+	if def := y.Definition("b.zxx", at(bSrc, "total", 0)); len(def) != 0 {
+		t.Fatal(def)
+	}
+}
+
+func TestExports(t *testing.T) {
+	x := index.New()
+	x.Update("a.zxx", []byte(aSrc))
+	x.Update("c.zxx", []byte("pub var int limit = 1\npub type Point [\n\tint x\n]\npub proc Show [\n]\nproc hide [\n]\n"))
+	if got := x.Exports(); !reflect.DeepEqual(got, []string{"Point", "Show", "limit"}) {
+		t.Fatal(got)
+	}
+}
@@ -0,0 +1,814 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ir
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Build 把 fn 降低为 Func, 结果未经优化.
+//
+// 局部变量在读取处直接构造 SSA 形式, 只在需要时插入 OpPhi, 未初始化的局部变量是 null.
+// and, or 按短路语义降低为分支, 结果是决定结果的操作数. switch 降低为依次比较的分支,
+// 其中的 break 与 Go 不同, 作用于外层的循环. go, defer 调用是 OpGo, OpDefer, goto 和标签尚不支持.
+// IR 的值不带静态类型, 类型参数被忽略, 泛型函数只降低一次, 不做单态化.
+func Build(fn *ast.FuncDecl) (*Func, error) {
+	if fn.Body == nil {
+		return nil, errors.New("ir: func " + fn.Name.Name.Source + " has no body")
+	}
+	b := &builder{
+		f:          &Func{Name: fn.Name.Name.Source},
+		defs:       map[*Block]map[*variable]*Value{},
+		incomplete: map[*Block][]phi{},
+		sealed:     map[*Block]bool{},
+	}
+	b.open()
+	b.b = b.f.newBlock()
+	b.seal(b.b)
+	for _, p := range fn.Params {
+		if p.Name == nil {
+			return nil, errorf(p.Pos(), "unnamed parameter")
+		}
+		v := b.f.newValue(b.b, OpParam, p.Name.Pos())
+		v.Name = p.Name.Name.Source
+		b.f.Params = append(b.f.Params, v)
+		b.write(b.declare(v.Name), v)
+	}
+	if err := b.block(fn.Body); err != nil {
+		return nil, err
+	}
+	if !b.terminated() {
+		b.b.Kind = Return
+	}
+	b.close()
+	removeUnreachable(b.f)
+	return b.f, nil
+}
+
+// variable 是一个局部变量, 同名的变量在不同的作用域中是不同的 variable.
+type variable struct {
+	name string
+}
+
+type scope struct {
+	outer *scope
+	names map[string]*variable
+}
+
+// phi 是所在块未封闭时为 v 创建的 OpPhi, 封闭时补充参数.
+type phi struct {
+	v   *variable
+	phi *Value
+}
+
+// loop 是 break, continue 的目标
+type loop struct {
+	brk, cont *Block
+}
+
+// builder 的 SSA 构造即 Braun 等人的 "Simple and Efficient Construction of SSA Form":
+// 块的全部前驱已知时封闭, 未封闭的块中读取的变量先用不完全的 OpPhi 占位.
+type builder struct {
+	f          *Func
+	b          *Block // 当前块
+	scope      *scope
+	loops      []loop
+	defs       map[*Block]map[*variable]*Value // 变量在块末尾的值
+	incomplete map[*Block][]phi
+	sealed     map[*Block]bool
+}
+
+func (b *builder) open()  { b.scope = &scope{b.scope, map[string]*variable{}} }
+func (b *builder) close() { b.scope = b.scope.outer }
+
+func (b *builder) declare(name string) *variable {
+	v := &variable{name}
+	b.scope.names[name] = v
+	return v
+}
+
+func (b *builder) lookup(name string) *variable {
+	for s := b.scope; s != nil; s = s.outer {
+		if v := s.names[name]; v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// errorf 返回位于 pos 的错误
+func errorf(pos scanner.Pos, msg ...string) error {
+	return errors.New("ir: " + strings.Join(msg, " ") + " at offset " + strconv.Itoa(int(pos)))
+}
+
+// terminated 返回当前块是否已经结束
+func (b *builder) terminated() bool {
+	return b.b.Kind != Jump || len(b.b.Succs) != 0
+}
+
+// jump 结束当前块并跳转到 to
+func (b *builder) jump(to *Block) {
+	b.b.Kind = Jump
+	addEdge(b.b, to)
+}
+
+// branch 结束当前块, cond 为真时跳转到 yes, 否则到 no.
+func (b *builder) branch(cond *Value, yes, no *Block) {
+	b.b.Kind = If
+	b.b.Control = cond
+	addEdge(b.b, yes)
+	addEdge(b.b, no)
+}
+
+// dead 在 out, break, continue 之后开始一个不可达的块, 其中的代码最后被删除.
+func (b *builder) dead() {
+	b.b = b.f.newBlock()
+	b.seal(b.b)
+}
+
+func addEdge(from, to *Block) {
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+func (b *builder) write(v *variable, val *Value) {
+	b.writeIn(b.b, v, val)
+}
+
+func (b *builder) writeIn(blk *Block, v *variable, val *Value) {
+	m := b.defs[blk]
+	if m == nil {
+		m = map[*variable]*Value{}
+		b.defs[blk] = m
+	}
+	m[v] = val
+}
+
+func (b *builder) read(v *variable, blk *Block) *Value {
+	if val := b.defs[blk][v]; val != nil {
+		return val
+	}
+	var val *Value
+	switch {
+	case !b.sealed[blk]:
+		val = b.newPhi(blk)
+		b.incomplete[blk] = append(b.incomplete[blk], phi{v, val})
+	case len(blk.Preds) == 0:
+		// 不可达的块中读取, 或者读取尚未赋值的变量
+		val = b.f.newValue(blk, OpConst, 0)
+	case len(blk.Preds) == 1:
+		val = b.read(v, blk.Preds[0])
+	default:
+		val = b.newPhi(blk)
+		b.writeIn(blk, v, val)
+		val = b.phiArgs(v, val)
+	}
+	b.writeIn(blk, v, val)
+	return val
+}
+
+// newPhi 在 blk 的最前面插入 OpPhi
+func (b *builder) newPhi(blk *Block) *Value {
+	v := b.f.newValue(nil, OpPhi, 0)
+	v.Block = blk
+	blk.Values = append([]*Value{v}, blk.Values...)
+	return v
+}
+
+func (b *builder) phiArgs(v *variable, p *Value) *Value {
+	for _, pred := range p.Block.Preds {
+		p.Args = append(p.Args, b.read(v, pred))
+	}
+	return b.trivial(p)
+}
+
+// trivial 删除参数只有一个不同值的 OpPhi p, 返回替代 p 的值.
+func (b *builder) trivial(p *Value) *Value {
+	same := trivialArg(p)
+	if same == p {
+		return p
+	}
+	if same == nil {
+		same = b.f.newValue(nil, OpConst, p.Pos)
+		same.Block = p.Block
+		p.Block.Values = append([]*Value{same}, p.Block.Values...)
+	}
+	users := b.f.replace(p, same)
+	for _, m := range b.defs {
+		for v, val := range m {
+			if val == p {
+				m[v] = same
+			}
+		}
+	}
+	for blk, list := range b.incomplete {
+		for i := range list {
+			if list[i].phi == p {
+				b.incomplete[blk][i].phi = same
+			}
+		}
+	}
+	for _, u := range users {
+		if u.Op == OpPhi && u != p {
+			b.trivial(u)
+		}
+	}
+	return same
+}
+
+// trivialArg 返回 OpPhi p 除自身外唯一的参数, 没有参数时返回 nil, 不唯一时返回 p.
+func trivialArg(p *Value) *Value {
+	var same *Value
+	for _, a := range p.Args {
+		if a == same || a == p {
+			continue
+		}
+		if same != nil {
+			return p
+		}
+		same = a
+	}
+	return same
+}
+
+// seal 封闭全部前驱已知的块 blk
+func (b *builder) seal(blk *Block) {
+	list := b.incomplete[blk]
+	delete(b.incomplete, blk)
+	b.sealed[blk] = true
+	for _, p := range list {
+		if p.phi.Op == OpPhi && p.phi.Block == blk && len(p.phi.Args) == 0 {
+			b.phiArgs(p.v, p.phi)
+		}
+	}
+}
+
+func (b *builder) block(s *ast.BlockStmt) error {
+	b.open()
+	defer b.close()
+	for _, s := range s.List {
+		if err := b.stmt(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *builder) stmt(s ast.Syntax) error {
+	switch s := s.(type) {
+	case *ast.GenDecl:
+		return b.decl(s)
+	case *ast.TypeDecl:
+		return errorf(s.Pos(), "unsupported local type")
+	case *ast.ExprStmt:
+		_, err := b.expr(s.X)
+		return err
+	case *ast.AssignStmt:
+		return b.assign(s, false)
+	case *ast.BlockStmt:
+		return b.block(s)
+	case *ast.IfStmt:
+		return b.ifStmt(s)
+	case *ast.RangeStmt:
+		return b.rangeStmt(s)
+	case *ast.ForStmt:
+		return b.forStmt(s)
+	case *ast.SwitchStmt:
+		return b.switchStmt(s)
+	case *ast.OutStmt:
+		results, err := b.exprs(s.Results)
+		if err != nil {
+			return err
+		}
+		b.b.Kind = Return
+		b.b.Results = results
+		b.dead()
+	case *ast.BranchStmt:
+		if s.Label != nil || s.Tok != token.BREAK && s.Tok != token.CONTINUE {
+			return errorf(s.Pos(), "unsupported", s.Tok.String())
+		}
+		if len(b.loops) == 0 {
+			return errorf(s.Pos(), s.Tok.String(), "is not in a loop")
+		}
+		l := b.loops[len(b.loops)-1]
+		if s.Tok == token.BREAK {
+			b.jump(l.brk)
+		} else {
+			b.jump(l.cont)
+		}
+		b.dead()
+	case *ast.GoStmt:
+		call, ok := s.Call.(*ast.CallExpr)
+		if !ok {
+			return errorf(s.Pos(), s.Tok.String(), "requires a call")
+		}
+		fun, err := b.expr(call.Fun)
+		if err != nil {
+			return err
+		}
+		args, err := b.exprs(call.Args)
+		if err != nil {
+			return err
+		}
+		op := OpGo
+		if s.Tok == token.DEFER {
+			op = OpDefer
+		}
+		b.f.newValue(b.b, op, s.Pos(), append([]*Value{fun}, args...)...)
+	case *ast.CaseClause:
+		return errorf(s.Pos(), s.Tok.String(), "is not in a switch")
+	default:
+		return errorf(s.Pos(), "unsupported statement")
+	}
+	return nil
+}
+
+// decl 声明局部变量, 初始值在声明名字之前求值.
+func (b *builder) decl(d *ast.GenDecl) error {
+	if d.Tok == token.USE {
+		return errorf(d.Pos(), "unsupported local", d.Tok.String())
+	}
+	for _, spec := range d.Specs {
+		values, err := b.results(spec.Values, len(spec.Names))
+		if err != nil {
+			return err
+		}
+		if len(values) != 0 && len(values) != len(spec.Names) {
+			return errorf(d.Pos(), "assignment count mismatch")
+		}
+		for i, id := range spec.Names {
+			var val *Value
+			if len(values) == 0 {
+				val = b.f.newValue(b.b, OpConst, id.Pos())
+			} else {
+				val = values[i]
+			}
+			b.write(b.declare(id.Name.Source), val)
+		}
+	}
+	return nil
+}
+
+// assign 降低赋值语句, loopVar 为真时首次赋值的名字声明为局部变量.
+func (b *builder) assign(s *ast.AssignStmt, loopVar bool) error {
+	if s.Tok == token.INC || s.Tok == token.DEC {
+		op := token.PLUS
+		if s.Tok == token.DEC {
+			op = token.SUB
+		}
+		for _, x := range s.Lhs {
+			old, err := b.expr(x)
+			if err != nil {
+				return err
+			}
+			one := b.f.newValue(b.b, OpConst, s.TokPos)
+			one.Const = int64(1)
+			val := b.f.newValue(b.b, OpBinary, s.TokPos, old, one)
+			val.Tok = op
+			if err = b.store(x, val, false); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	values, err := b.results(s.Rhs, len(s.Lhs))
+	if err != nil {
+		return err
+	}
+	if len(values) != len(s.Lhs) {
+		return errorf(s.TokPos, "assignment count mismatch")
+	}
+	for i, x := range s.Lhs {
+		if err = b.store(x, values[i], loopVar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// results 返回赋给 n 个名字的 list 的值, list 是唯一的调用时按 n 个结果解构, 例如 a, b = f().
+// 结果的个数在运行时检查.
+func (b *builder) results(list []ast.Expression, n int) ([]*Value, error) {
+	values, err := b.exprs(list)
+	if err != nil || len(values) != 1 || n < 2 || values[0].Op != OpCall {
+		return values, err
+	}
+	call := values[0]
+	values = make([]*Value, n)
+	for i := range values {
+		values[i] = b.f.newValue(b.b, OpExtract, call.Pos, call)
+		values[i].Index = i
+	}
+	return values, nil
+}
+
+// store 把 val 写入 x
+func (b *builder) store(x ast.Expression, val *Value, define bool) error {
+	switch x := x.(type) {
+	case *ast.Ident:
+		name := x.Name.Source
+		if x.Name.Tok == token.IDENT {
+			v := b.lookup(name)
+			if v == nil && define {
+				v = b.declare(name)
+			}
+			if v != nil {
+				b.write(v, val)
+				return nil
+			}
+		}
+		if i := strings.IndexByte(name, '.'); i > 0 && b.lookup(name[:i]) != nil {
+			return errorf(x.Pos(), "unsupported assignment to member of local", name[:i])
+		}
+		v := b.f.newValue(b.b, OpStore, x.Pos(), val)
+		v.Name = name
+		return nil
+	case *ast.ParenExpr:
+		return b.store(x.X, val, define)
+	case *ast.IndexExpr:
+		obj, err := b.expr(x.X)
+		if err != nil {
+			return err
+		}
+		i, err := b.expr(x.Index)
+		if err == nil {
+			b.f.newValue(b.b, OpSetIndex, x.Lbrack, obj, i, val)
+		}
+		return err
+	}
+	return errorf(x.Pos(), "cannot assign to expression")
+}
+
+func (b *builder) ifStmt(s *ast.IfStmt) error {
+	cond, err := b.expr(s.Cond)
+	if err != nil {
+		return err
+	}
+	then, els, done := b.f.newBlock(), b.f.newBlock(), (*Block)(nil)
+	b.branch(cond, then, els)
+	b.seal(then)
+
+	b.b = then
+	if err = b.block(s.Body); err != nil {
+		return err
+	}
+	if s.Else == nil {
+		done = els
+	} else {
+		done = b.f.newBlock()
+		b.seal(els)
+	}
+	if !b.terminated() {
+		b.jump(done)
+	}
+	if s.Else != nil {
+		b.b = els
+		if err = b.stmt(s.Else); err != nil {
+			return err
+		}
+		if !b.terminated() {
+			b.jump(done)
+		}
+	}
+	b.seal(done)
+	b.b = done
+	return nil
+}
+
+func (b *builder) forStmt(s *ast.ForStmt) error {
+	b.open()
+	defer b.close()
+	if init, ok := s.Init.(*ast.AssignStmt); ok && init.Tok == token.ASSIGN {
+		if err := b.assign(init, true); err != nil {
+			return err
+		}
+	} else if s.Init != nil {
+		if err := b.stmt(s.Init); err != nil {
+			return err
+		}
+	}
+
+	head, body, post, done := b.f.newBlock(), b.f.newBlock(), b.f.newBlock(), b.f.newBlock()
+	b.jump(head)
+	b.b = head
+	if s.Cond == nil {
+		b.jump(body)
+	} else {
+		cond, err := b.expr(s.Cond)
+		if err != nil {
+			return err
+		}
+		b.branch(cond, body, done)
+	}
+	b.seal(body)
+
+	b.b = body
+	b.loops = append(b.loops, loop{done, post})
+	err := b.block(s.Body)
+	b.loops = b.loops[:len(b.loops)-1]
+	if err != nil {
+		return err
+	}
+	if !b.terminated() {
+		b.jump(post)
+	}
+	b.seal(post)
+
+	b.b = post
+	if s.Post != nil {
+		if err = b.stmt(s.Post); err != nil {
+			return err
+		}
+	}
+	b.jump(head)
+	b.seal(head)
+	b.seal(done)
+	b.b = done
+	return nil
+}
+
+// rangeStmt 降低为按下标的循环, 下标是名字不可能出现在源码中的局部变量.
+// 循环变量在每次迭代开始时从 OpRange 的键列表和值列表中读取.
+func (b *builder) rangeStmt(s *ast.RangeStmt) error {
+	x, err := b.expr(s.X)
+	if err != nil {
+		return err
+	}
+	b.open()
+	defer b.close()
+	r := b.f.newValue(b.b, OpRange, s.X.Pos(), x)
+	var parts [3]*Value // 键列表, 值列表和个数
+	for i := range parts {
+		parts[i] = b.f.newValue(b.b, OpExtract, s.X.Pos(), r)
+		parts[i].Index = i
+	}
+	index := b.declare("for index")
+	zero := b.f.newValue(b.b, OpConst, s.For)
+	zero.Const = int64(0)
+	b.write(index, zero)
+
+	head, body, post, done := b.f.newBlock(), b.f.newBlock(), b.f.newBlock(), b.f.newBlock()
+	b.jump(head)
+	b.b = head
+	i := b.read(index, head)
+	cond := b.f.newValue(b.b, OpBinary, s.As, i, parts[2])
+	cond.Tok = token.LSS
+	b.branch(cond, body, done)
+	b.seal(body)
+
+	b.b = body
+	b.write(b.declare(s.Key.Name.Source), b.f.newValue(b.b, OpIndex, s.Key.Pos(), parts[0], i))
+	if s.Value != nil {
+		b.write(b.declare(s.Value.Name.Source), b.f.newValue(b.b, OpIndex, s.Value.Pos(), parts[1], i))
+	}
+	b.loops = append(b.loops, loop{done, post})
+	err = b.block(s.Body)
+	b.loops = b.loops[:len(b.loops)-1]
+	if err != nil {
+		return err
+	}
+	if !b.terminated() {
+		b.jump(post)
+	}
+	b.seal(post)
+
+	b.b = post
+	one := b.f.newValue(b.b, OpConst, s.For)
+	one.Const = int64(1)
+	next := b.f.newValue(b.b, OpBinary, s.For, b.read(index, post), one)
+	next.Tok = token.PLUS
+	b.write(index, next)
+	b.jump(head)
+	b.seal(head)
+	b.seal(done)
+	b.b = done
+	return nil
+}
+
+// switchStmt 先依次比较全部 case, 再降低各个 case 的语句.
+// 没有 Tag 时 case 的表达式是条件.
+func (b *builder) switchStmt(s *ast.SwitchStmt) error {
+	var tag *Value
+	if s.Tag != nil {
+		var err error
+		if tag, err = b.expr(s.Tag); err != nil {
+			return err
+		}
+	}
+
+	type clause struct {
+		block *Block
+		list  []ast.Syntax
+	}
+	var (
+		clauses []*clause
+		def     *clause
+	)
+	for _, st := range s.Body.List {
+		if cc, ok := st.(*ast.CaseClause); ok {
+			c := &clause{block: b.f.newBlock()}
+			clauses = append(clauses, c)
+			if cc.Tok == token.DEFAULT {
+				def = c
+				continue
+			}
+			for _, x := range cc.List {
+				val, err := b.expr(x)
+				if err != nil {
+					return err
+				}
+				if tag != nil {
+					eq := b.f.newValue(b.b, OpBinary, x.Pos(), tag, val)
+					eq.Tok = token.EQL
+					val = eq
+				}
+				next := b.f.newBlock()
+				b.branch(val, c.block, next)
+				b.seal(next)
+				b.b = next
+			}
+			continue
+		}
+		if len(clauses) == 0 {
+			return errorf(st.Pos(), "statement before case in switch")
+		}
+		c := clauses[len(clauses)-1]
+		c.list = append(c.list, st)
+	}
+
+	done := b.f.newBlock()
+	if def != nil {
+		b.jump(def.block)
+	} else {
+		b.jump(done)
+	}
+	for _, c := range clauses {
+		b.seal(c.block)
+		b.b = c.block
+		b.open()
+		for _, st := range c.list {
+			if err := b.stmt(st); err != nil {
+				return err
+			}
+		}
+		b.close()
+		if !b.terminated() {
+			b.jump(done)
+		}
+	}
+	b.seal(done)
+	b.b = done
+	return nil
+}
+
+func (b *builder) exprs(list []ast.Expression) ([]*Value, error) {
+	var values []*Value
+	for _, x := range list {
+		v, err := b.expr(x)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (b *builder) expr(x ast.Expression) (*Value, error) {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		c, err := eval.Literal(x.Value.Tok, x.Value.Source)
+		if err != nil {
+			return nil, errorf(x.Pos(), err.Error())
+		}
+		v := b.f.newValue(b.b, OpConst, x.Pos())
+		v.Const = c
+		return v, nil
+	case *ast.Ident:
+		return b.ident(x), nil
+	case *ast.ParenExpr:
+		return b.expr(x.X)
+	case *ast.ListExpr:
+		elems, err := b.exprs(x.Elems)
+		if err != nil {
+			return nil, err
+		}
+		return b.f.newValue(b.b, OpList, x.Pos(), elems...), nil
+	case *ast.MapExpr:
+		var kv []*Value
+		for _, e := range x.Elems {
+			k, err := b.expr(e.Key)
+			if err != nil {
+				return nil, err
+			}
+			v, err := b.expr(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			kv = append(kv, k, v)
+		}
+		return b.f.newValue(b.b, OpMap, x.Pos(), kv...), nil
+	case *ast.InterpExpr:
+		parts, err := b.exprs(x.Parts)
+		if err != nil {
+			return nil, err
+		}
+		return b.f.newValue(b.b, OpInterp, x.Pos(), parts...), nil
+	case *ast.UnaryExpr:
+		v, err := b.expr(x.X)
+		if err != nil {
+			return nil, err
+		}
+		v = b.f.newValue(b.b, OpUnary, x.Pos(), v)
+		v.Tok = x.Op.Tok
+		return v, nil
+	case *ast.BinaryExpr:
+		l, err := b.expr(x.X)
+		if err != nil {
+			return nil, err
+		}
+		if x.Op.Tok == token.AND || x.Op.Tok == token.OR {
+			return b.shortCircuit(x, l)
+		}
+		r, err := b.expr(x.Y)
+		if err != nil {
+			return nil, err
+		}
+		v := b.f.newValue(b.b, OpBinary, x.Op.Pos, l, r)
+		v.Tok = x.Op.Tok
+		return v, nil
+	case *ast.IndexExpr:
+		obj, err := b.expr(x.X)
+		if err != nil {
+			return nil, err
+		}
+		i, err := b.expr(x.Index)
+		if err != nil {
+			return nil, err
+		}
+		return b.f.newValue(b.b, OpIndex, x.Lbrack, obj, i), nil
+	case *ast.CallExpr:
+		fun, err := b.expr(x.Fun)
+		if err != nil {
+			return nil, err
+		}
+		args, err := b.exprs(x.Args)
+		if err != nil {
+			return nil, err
+		}
+		return b.f.newValue(b.b, OpCall, x.Lparen, append([]*Value{fun}, args...)...), nil
+	}
+	return nil, errorf(x.Pos(), "unsupported expression")
+}
+
+// ident 读取名字, 局部变量的成员是 OpMember.
+func (b *builder) ident(x *ast.Ident) *Value {
+	name := x.Name.Source
+	head, rest := name, ""
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		head, rest = name[:i], name[i+1:]
+	}
+	if v := b.lookup(head); v != nil && (x.Name.Tok == token.IDENT || rest != "") {
+		val := b.read(v, b.b)
+		if rest == "" {
+			return val
+		}
+		m := b.f.newValue(b.b, OpMember, x.Pos(), val)
+		m.Name = rest
+		return m
+	}
+	v := b.f.newValue(b.b, OpLoad, x.Pos())
+	v.Name = name
+	return v
+}
+
+// shortCircuit 降低 l and Y, l or Y, 结果是决定结果的操作数.
+func (b *builder) shortCircuit(x *ast.BinaryExpr, l *Value) (*Value, error) {
+	right, done := b.f.newBlock(), b.f.newBlock()
+	if x.Op.Tok == token.AND {
+		b.branch(l, right, done)
+	} else {
+		b.branch(l, done, right)
+	}
+	b.seal(right)
+	b.b = right
+	r, err := b.expr(x.Y)
+	if err != nil {
+		return nil, err
+	}
+	b.jump(done)
+	b.seal(done)
+	b.b = done
+	p := b.newPhi(done)
+	p.Pos = x.Op.Pos
+	p.Args = []*Value{l, r}
+	return p, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ir
+
+// Escape 是一个分配的逃逸分析结果
+type Escape struct {
+	Value   *Value // 分配的指令, OpList 或者 OpMap
+	Escapes bool   // 为真时必须分配在堆上, 否则可以分配在栈上
+	Reason  string // 逃逸的原因
+}
+
+func (e Escape) String() string {
+	if e.Escapes {
+		return e.Value.Op.String() + " escapes to heap: " + e.Reason
+	}
+	return e.Value.Op.String() + " does not escape"
+}
+
+// Escapes 分析 f 中的分配是否逃逸出 f 的调用, 按块和指令的顺序返回.
+//
+// 分析是过程内和保守的: 返回的值, 传给调用的参数, 写入非局部名字的值逃逸,
+// 写入元素的值在容器逃逸或者容器不是 f 中的分配时逃逸.
+// 其它指令的结果逃逸时, 其操作数也逃逸, 因为结果可能是操作数或者含有操作数.
+func Escapes(f *Func) []Escape {
+	reason := map[*Value]string{}
+	var work []*Value
+	escape := func(v *Value, why string) {
+		if _, ok := reason[v]; !ok {
+			reason[v] = why
+			work = append(work, v)
+		}
+	}
+
+	owned := ownedValues(f)
+	flows := map[*Value][]*Value{} // 值逃逸时随之逃逸的值
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			switch v.Op {
+			case OpStore:
+				escape(v.Args[0], "stored to "+v.Name)
+			case OpCall, OpGo, OpDefer:
+				for _, a := range v.Args[1:] {
+					escape(a, "passed to "+v.Op.String())
+				}
+			case OpSetIndex:
+				if owned[v.Args[0]] {
+					flows[v.Args[0]] = append(flows[v.Args[0]], v.Args[2])
+				} else {
+					escape(v.Args[2], "stored into non-local value")
+				}
+			default:
+				flows[v] = append(flows[v], v.Args...)
+			}
+		}
+		for _, v := range b.Results {
+			escape(v, "returned")
+		}
+	}
+
+	for len(work) != 0 {
+		v := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, a := range flows[v] {
+			escape(a, reason[v])
+		}
+	}
+
+	var list []Escape
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if v.Op == OpList || v.Op == OpMap {
+				why, ok := reason[v]
+				list = append(list, Escape{v, ok, why})
+			}
+		}
+	}
+	return list
+}
+
+// ownedValues 返回只可能是 f 中的分配的值, 即 OpList, OpMap 以及参数都是这样的值的 OpPhi.
+func ownedValues(f *Func) map[*Value]bool {
+	owned := map[*Value]bool{}
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if v.Op == OpList || v.Op == OpMap || v.Op == OpPhi {
+				owned[v] = true
+			}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for v := range owned {
+			if v.Op != OpPhi {
+				continue
+			}
+			for _, a := range v.Args {
+				if !owned[a] {
+					delete(owned, v)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return owned
+}
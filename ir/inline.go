@@ -0,0 +1,154 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ir
+
+// MaxInline 是可以内联的函数的最多指令数
+const MaxInline = 32
+
+// Inline 把 f 中对 p 的小函数的直接调用替换为函数体的副本, 返回 f 是否改变.
+//
+// 被调用者由 OpLoad 的名字确定, 必须不直接调用自身, 没有 defer, 参数个数相符,
+// 每个 return 的结果不多于一个且个数相同. 内联一层, 更深的调用由 Pipeline 的下一轮内联.
+func Inline(p *Program, f *Func) (changed bool) {
+	var calls []*Value
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if v.Op != OpCall || v.Args[0].Op != OpLoad {
+				continue
+			}
+			if g := p.Func(v.Args[0].Name); g != nil && g != f && len(g.Params) == len(v.Args)-1 && inlinable(g) {
+				calls = append(calls, v)
+			}
+		}
+	}
+	loads := map[*Value]bool{}
+	for _, v := range calls {
+		loads[v.Args[0]] = true
+		inline(f, v, p.Func(v.Args[0].Name))
+	}
+	// 读取 p 中的函数不会出错, 不再使用的可以删除
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			for _, a := range v.Args {
+				delete(loads, a)
+			}
+		}
+	}
+	for v := range loads {
+		f.replace(v, nil)
+	}
+	return len(calls) != 0
+}
+
+// inlinable 返回 g 是否足够小并且满足内联的条件
+func inlinable(g *Func) bool {
+	n, results := 0, -1
+	for _, b := range g.Blocks {
+		n += len(b.Values)
+		for _, v := range b.Values {
+			if v.Op == OpCall && v.Args[0].Op == OpLoad && v.Args[0].Name == g.Name || v.Op == OpDefer {
+				return false
+			}
+		}
+		if b.Kind == Return {
+			if len(b.Results) > 1 || results >= 0 && len(b.Results) != results {
+				return false
+			}
+			results = len(b.Results)
+		}
+	}
+	return n <= MaxInline
+}
+
+// inline 在调用 v 处展开 g: v 所在的块在 v 处分开, 之前的部分跳转到 g 的入口的副本,
+// g 的 return 跳转到之后的部分, 结果由 OpPhi 合并.
+func inline(f *Func, v *Value, g *Func) {
+	blocks := map[*Block]*Block{}
+	for _, gb := range g.Blocks {
+		blocks[gb] = f.newBlock()
+	}
+	b := v.Block
+	after := f.newBlock()
+	for i, x := range b.Values {
+		if x == v {
+			after.Values = append(after.Values, b.Values[i+1:]...)
+			b.Values = b.Values[:i]
+			break
+		}
+	}
+	for _, x := range after.Values {
+		x.Block = after
+	}
+	after.Kind, after.Control, after.Results, after.Succs = b.Kind, b.Control, b.Results, b.Succs
+	for _, s := range after.Succs {
+		for i, pred := range s.Preds {
+			if pred == b {
+				s.Preds[i] = after
+			}
+		}
+	}
+	b.Kind, b.Control, b.Results, b.Succs = Jump, nil, nil, nil
+
+	values := map[*Value]*Value{}
+	for i, param := range g.Params {
+		values[param] = v.Args[i+1]
+	}
+	for _, gb := range g.Blocks {
+		nb := blocks[gb]
+		for _, x := range gb.Values {
+			if x.Op == OpParam {
+				continue
+			}
+			nx := f.newValue(nb, x.Op, x.Pos)
+			nx.Tok, nx.Name, nx.Const = x.Tok, x.Name, x.Const
+			values[x] = nx
+		}
+	}
+
+	var results []*Value
+	for _, gb := range g.Blocks {
+		nb := blocks[gb]
+		for _, x := range gb.Values {
+			if nx := values[x]; x.Op != OpParam {
+				for _, a := range x.Args {
+					nx.Args = append(nx.Args, values[a])
+				}
+			}
+		}
+		for _, pred := range gb.Preds {
+			nb.Preds = append(nb.Preds, blocks[pred])
+		}
+		if gb.Kind == Return {
+			nb.Kind = Jump
+			nb.Succs = []*Block{after}
+			after.Preds = append(after.Preds, nb)
+			if len(gb.Results) != 0 {
+				results = append(results, values[gb.Results[0]])
+			}
+			continue
+		}
+		nb.Kind = gb.Kind
+		nb.Control = values[gb.Control]
+		for _, s := range gb.Succs {
+			nb.Succs = append(nb.Succs, blocks[s])
+		}
+	}
+	addEdge(b, blocks[g.Blocks[0]])
+
+	var result *Value
+	switch {
+	case len(results) == 0:
+		result = f.newValue(nil, OpConst, v.Pos)
+	case len(results) == 1:
+		result = results[0]
+	default:
+		result = f.newValue(nil, OpPhi, v.Pos, results...)
+	}
+	if result.Block == nil {
+		result.Block = after
+		after.Values = append([]*Value{result}, after.Values...)
+	}
+	f.replace(v, result)
+}
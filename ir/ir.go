@@ -0,0 +1,210 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包把 func, proc 声明降低为 SSA 形式的中间表示, 由基本块组成,
+// 供虚拟机和各个后端共用, 常量传播和死代码删除等优化只需在 IR 上实现一次.
+//
+// 目前没有类型检查, Build 的输入是 parser.ParseSyntax 的结果, 值没有类型,
+// 运算的语义与 eval 相同. 参数, 函数体中的声明和循环变量是局部变量, 转换为 SSA 值,
+// 其它名字通过 OpLoad, OpStore 访问.
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Op 是指令的操作
+type Op uint8
+
+const (
+	OpConst    Op = iota // 常量 Const
+	OpParam              // 参数 Name
+	OpLoad               // 读取非局部的名字 Name, 可以是成员路径
+	OpStore              // 把 Args[0] 写入非局部的名字 Name
+	OpMember             // Args[0] 的成员路径 Name
+	OpUnary              // Tok Args[0]
+	OpBinary             // Args[0] Tok Args[1]
+	OpIndex              // Args[0][Args[1]]
+	OpSetIndex           // Args[0][Args[1]] = Args[2]
+	OpCall               // 以 Args[1:] 调用 Args[0]
+	OpList               // 由 Args 组成的列表
+	OpMap                // 由 Args 中交替的键和值组成的映射
+	OpPhi                // 按所在块的 Preds 顺序选取 Args
+	OpExtract            // 有多个结果的调用或 OpRange Args[0] 的第 Index 个结果
+	OpRange              // 遍历 Args[0] 的键列表, 值列表和个数, 参见 eval.Entries
+	OpGo                 // 在新的 goroutine 中以 Args[1:] 调用 Args[0], 没有结果
+	OpDefer              // 登记以 Args[1:] 调用 Args[0], 实参此时求值, 没有结果
+	OpInterp             // 插值字符串, 连接 Args 的文本, 参见 eval.Text
+)
+
+var ops = [...]string{
+	"const", "param", "load", "store", "member", "unary", "binary",
+	"index", "setindex", "call", "list", "map", "phi", "extract", "range", "go", "defer", "interp",
+}
+
+func (op Op) String() string {
+	if int(op) < len(ops) {
+		return ops[op]
+	}
+	return "Op(" + strconv.Itoa(int(op)) + ")"
+}
+
+// Value 是一条指令及其结果
+type Value struct {
+	ID    int
+	Op    Op
+	Tok   token.Token // OpUnary, OpBinary 的运算符
+	Name  string      // OpParam, OpLoad, OpStore, OpMember 的名字
+	Const eval.Value  // OpConst 的值
+	Index int         // OpExtract 的结果序号, 从 0 开始
+	Args  []*Value
+	Block *Block
+	Pos   scanner.Pos // 对应的源码位置
+}
+
+func (v *Value) String() string {
+	return "v" + strconv.Itoa(v.ID)
+}
+
+// pure 返回删除未使用的 v 是否不改变语义, 可能在运行时出错的指令不是纯的.
+func (v *Value) pure() bool {
+	switch v.Op {
+	case OpConst, OpParam, OpList, OpPhi, OpExtract:
+		return true
+	}
+	return false
+}
+
+// Kind 是基本块的结束方式
+type Kind uint8
+
+const (
+	Jump   Kind = iota // 跳转到 Succs[0]
+	If                 // Control 为真时跳转到 Succs[0], 否则到 Succs[1]
+	Return             // 按登记的相反顺序执行 OpDefer 的调用, 然后返回 Results
+)
+
+// Block 是基本块
+type Block struct {
+	Index   int
+	Values  []*Value // OpPhi 总在最前面
+	Preds   []*Block
+	Succs   []*Block
+	Kind    Kind
+	Control *Value
+	Results []*Value
+}
+
+func (b *Block) String() string {
+	return "b" + strconv.Itoa(b.Index)
+}
+
+// Func 是一个函数, Blocks[0] 是入口.
+type Func struct {
+	Name   string
+	Params []*Value
+	Blocks []*Block
+	nextID int
+}
+
+func (f *Func) newValue(b *Block, op Op, pos scanner.Pos, args ...*Value) *Value {
+	v := &Value{ID: f.nextID, Op: op, Args: args, Block: b, Pos: pos}
+	f.nextID++
+	if b != nil {
+		b.Values = append(b.Values, v)
+	}
+	return v
+}
+
+func (f *Func) newBlock() *Block {
+	b := &Block{Index: len(f.Blocks)}
+	f.Blocks = append(f.Blocks, b)
+	return b
+}
+
+// String 返回 f 的文本形式, 用于调试和测试.
+func (f *Func) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "func %s(", f.Name)
+	for i, p := range f.Params {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		fmt.Fprintf(&buf, "%v %s", p, p.Name)
+	}
+	buf.WriteString(")\n")
+	for _, b := range f.Blocks {
+		fmt.Fprintf(&buf, "%v:", b)
+		if len(b.Preds) != 0 {
+			fmt.Fprintf(&buf, " <- %v", join(b.Preds))
+		}
+		buf.WriteByte('\n')
+		for _, v := range b.Values {
+			if v.Op != OpParam {
+				fmt.Fprintf(&buf, "\t%s\n", v.LongString())
+			}
+		}
+		switch b.Kind {
+		case Jump:
+			fmt.Fprintf(&buf, "\tjump %v\n", b.Succs[0])
+		case If:
+			fmt.Fprintf(&buf, "\tif %v %v %v\n", b.Control, b.Succs[0], b.Succs[1])
+		case Return:
+			buf.WriteString("\treturn")
+			if len(b.Results) != 0 {
+				fmt.Fprintf(&buf, " %v", join(b.Results))
+			}
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.String()
+}
+
+// LongString 返回 v 的指令形式, 例如 v3 = binary + v1 v2.
+func (v *Value) LongString() string {
+	s := v.Op.String()
+	if v.Op != OpStore && v.Op != OpSetIndex {
+		s = v.String() + " = " + s
+	}
+	switch v.Op {
+	case OpConst:
+		if str, ok := v.Const.(string); ok {
+			return s + " " + strconv.Quote(str)
+		}
+		return s + " " + fmt.Sprint(v.Const)
+	case OpUnary, OpBinary:
+		s += " " + v.Tok.String()
+	case OpParam, OpLoad, OpStore, OpMember:
+		s += " " + v.Name
+	case OpExtract:
+		s += " " + strconv.Itoa(v.Index)
+	}
+	if len(v.Args) != 0 {
+		s += " " + join(v.Args)
+	}
+	return s
+}
+
+// join 以空格连接 list 的 String
+func join(list interface{}) string {
+	var s []string
+	switch list := list.(type) {
+	case []*Value:
+		for _, v := range list {
+			s = append(s, v.String())
+		}
+	case []*Block:
+		for _, b := range list {
+			s = append(s, b.String())
+		}
+	}
+	return strings.Join(s, " ")
+}
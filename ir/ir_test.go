@@ -0,0 +1,286 @@
+package ir_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/ir"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func build(t *testing.T, src string) (*ir.Func, error) {
+	t.Helper()
+	sf, err := parser.ParseSyntax([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ir.Build(sf.Decls[0].(*ast.FuncDecl))
+}
+
+func TestOptimize(t *testing.T) {
+	for _, tt := range []struct{ src, want string }{
+		{`func f(int n) out int [
+	var int k = 2 * 3
+	if k > 5 [
+		n = n + k
+	] else [
+		fmt.print(n)
+	]
+	out n
+]`, `func f(v0 n)
+b0:
+	v3 = const 6
+	v6 = binary + v0 v3
+	return v6
+`},
+		{`proc sum(int n) out int [
+	var int total = 0
+	for i = 0; i < n; i++ [
+		if i == 3 [
+			continue
+		]
+		total = total + i
+	]
+	out total
+]`, `func sum(v0 n)
+b0:
+	v1 = const 0
+	v2 = const 0
+	jump b1
+b1: <- b0 b3
+	v9 = phi v1 v20
+	v3 = phi v2 v16
+	v5 = binary < v3 v0
+	if v5 b2 b4
+b2: <- b1
+	v6 = const 3
+	v7 = binary == v3 v6
+	if v7 b5 b6
+b3: <- b5 b6
+	v20 = phi v9 v13
+	v15 = const 1
+	v16 = binary + v3 v15
+	jump b1
+b4: <- b1
+	return v9
+b5: <- b2
+	jump b3
+b6: <- b2
+	v13 = binary + v9 v3
+	jump b3
+`},
+		{`func g(int x) [
+	var y = x > 0 and 1 == 2
+	z = y or x
+]`, `func g(v0 x)
+b0:
+	v1 = const 0
+	v2 = binary > v0 v1
+	if v2 b1 b2
+b1: <- b0
+	v5 = const false
+	jump b2
+b2: <- b0 b1
+	v6 = phi v2 v5
+	if v6 b4 b3
+b3: <- b2
+	jump b4
+b4: <- b2 b3
+	v8 = phi v6 v0
+	store z v8
+	return
+`},
+	} {
+		f, err := build(t, tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ir.Optimize(f)
+		if got := f.String(); got != tt.want {
+			t.Errorf("%s\ngot:\n%s\nwant:\n%s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestBuildError(t *testing.T) {
+	for _, tt := range []struct{ src, err string }{
+		{"proc f [\n\tbreak\n]", "break is not in a loop"},
+		{"proc f [\n\tgoto done\n]", "unsupported goto"},
+		{"proc f [\n\tvar a, b = 1\n]", "assignment count mismatch"},
+		{"proc f(int p) [\n\tp.x = 1\n]", "member of local p"},
+	} {
+		if _, err := build(t, tt.src); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: got %v, want %s", tt.src, err, tt.err)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	f, err := build(t, "func swap(int x) out int, int [\n\tvar a, b = pair(x)\n\ta, b = b, a\n\tout a, b\n]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir.Optimize(f)
+	want := "func swap(v0 x)\nb0:\n\tv1 = load pair\n\tv2 = call v1 v0\n\tv3 = extract 0 v2\n\tv4 = extract 1 v2\n\treturn v4 v3\n"
+	if f.String() != want {
+		t.Fatalf("%q", f.String())
+	}
+}
+
+func TestInterp(t *testing.T) {
+	f, err := build(t, "func greet(string name) out string [\n\tout \"hi {name}!\"\n]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir.Optimize(f)
+	want := "func greet(v0 name)\nb0:\n\tv1 = const \"hi \"\n\tv2 = const \"!\"\n\tv3 = interp v1 v0 v2\n\treturn v3\n"
+	if f.String() != want {
+		t.Fatalf("%q", f.String())
+	}
+}
+
+func TestRange(t *testing.T) {
+	f, err := build(t, "func sum(list xs) out int [\n\tvar total = 0\n\tfor xs as i x [\n\t\ttotal = total + x\n\t]\n\tout total\n]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir.Optimize(f)
+	want := `func sum(v0 xs)
+b0:
+	v1 = const 0
+	v2 = range v0
+	v3 = extract 0 v2
+	v4 = extract 1 v2
+	v5 = extract 2 v2
+	v6 = const 0
+	jump b1
+b1: <- b0 b2
+	v11 = phi v1 v12
+	v7 = phi v6 v14
+	v8 = binary < v7 v5
+	if v8 b2 b3
+b2: <- b1
+	v9 = index v3 v7
+	v10 = index v4 v7
+	v12 = binary + v11 v10
+	v13 = const 1
+	v14 = binary + v7 v13
+	jump b1
+b3: <- b1
+	return v11
+`
+	if f.String() != want {
+		t.Fatalf("%s", f)
+	}
+}
+
+func TestDefer(t *testing.T) {
+	sf, err := parser.ParseSyntax([]byte(`proc save(string name) [
+	var f = open(name)
+	defer close(f)
+	write(f, 1)
+]
+
+proc main [
+	save('a')
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ir.BuildFile(sf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	(&ir.Pipeline{Level: 2}).Run(p)
+	want := `func save(v0 name)
+b0:
+	v1 = load open
+	v2 = call v1 v0
+	v3 = load close
+	v4 = defer v3 v2
+	v5 = load write
+	v6 = const 1
+	v7 = call v5 v2 v6
+	return
+`
+	if got := p.Func("save").String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	// 有 defer 的函数不能内联
+	if got := p.Func("main").String(); !strings.Contains(got, "call") {
+		t.Errorf("save inlined:\n%s", got)
+	}
+}
+
+func TestInline(t *testing.T) {
+	sf, err := parser.ParseSyntax([]byte(`func abs(int x) out int [
+	if x < 0 [
+		out -x
+	]
+	out x
+]
+
+proc main(int n) [
+	fmt.print(abs(n))
+]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := ir.BuildFile(sf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	(&ir.Pipeline{Level: 2}).Run(p)
+	want := `func main(v0 n)
+b0:
+	v1 = load fmt.print
+	v5 = const 0
+	v6 = binary < v0 v5
+	if v6 b1 b2
+b1: <- b0
+	v7 = unary - v0
+	jump b3
+b2: <- b0
+	jump b3
+b3: <- b1 b2
+	v8 = phi v7 v0
+	v4 = call v1 v8
+	return
+`
+	if got := p.Func("main").String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEscapes(t *testing.T) {
+	f, err := build(t, `proc f(list p) [
+	p[0] = [1]
+	print([2])
+	total = [[3]]
+	var a = [4]
+	var b = a[0]
+	var m = ['k': [5]]
+	go send(c, [6])
+]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range ir.Escapes(f) {
+		got = append(got, e.String())
+	}
+	want := []string{
+		"list escapes to heap: stored into non-local value",
+		"list escapes to heap: passed to call",
+		"list escapes to heap: stored to total",
+		"list escapes to heap: stored to total",
+		"list does not escape",
+		"list does not escape",
+		"map does not escape",
+		"list escapes to heap: passed to go",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %q", got)
+	}
+}
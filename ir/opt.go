@@ -0,0 +1,265 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ir
+
+import (
+	"github.com/ZxxLang/zxx/eval"
+)
+
+// Optimize 反复进行常量传播和死代码删除, 直到 f 不再改变.
+func Optimize(f *Func) {
+	for Propagate(f) || DeadCode(f) {
+	}
+}
+
+// Propagate 进行常量传播, 返回 f 是否改变.
+//
+// 操作数都是常量的 OpUnary, OpBinary 在编译时用 eval 求值, 出错的保留到运行时.
+// 参数只有一个不同值的 OpPhi 被替换, 条件为常量的分支改为跳转, 删除不可达的块.
+func Propagate(f *Func) (changed bool) {
+	for again := true; again; {
+		again = false
+		for _, b := range f.Blocks {
+			for _, v := range b.Values {
+				if fold(v) {
+					again = true
+				}
+			}
+			if b.Kind == If && b.Control.Op == OpConst {
+				taken, dropped := b.Succs[0], b.Succs[1]
+				if !eval.Truth(b.Control.Const) {
+					taken, dropped = dropped, taken
+				}
+				removeEdge(b, dropped)
+				b.Kind, b.Control, b.Succs = Jump, nil, []*Block{taken}
+				again = true
+			}
+		}
+		for _, b := range f.Blocks {
+			for _, v := range append([]*Value(nil), b.Values...) {
+				if v.Op != OpPhi {
+					continue
+				}
+				if same := trivialArg(v); same != v && same != nil {
+					f.replace(v, same)
+					again = true
+				}
+			}
+		}
+		if removeUnreachable(f) {
+			again = true
+		}
+		changed = changed || again
+	}
+	return
+}
+
+// fold 把操作数都是常量的 v 改为常量
+func fold(v *Value) bool {
+	if v.Op != OpUnary && v.Op != OpBinary {
+		return false
+	}
+	for _, a := range v.Args {
+		if a.Op != OpConst {
+			return false
+		}
+	}
+	var (
+		c   eval.Value
+		err error
+	)
+	if v.Op == OpUnary {
+		c, err = eval.Unary(v.Tok, v.Args[0].Const)
+	} else {
+		c, err = eval.Binary(v.Tok, v.Args[0].Const, v.Args[1].Const)
+	}
+	if err != nil {
+		return false
+	}
+	v.Op, v.Const, v.Args = OpConst, c, nil
+	return true
+}
+
+// DeadCode 删除结果未被使用的纯指令, 合并只有一个前驱和后继的块, 返回 f 是否改变.
+func DeadCode(f *Func) (changed bool) {
+	changed = fuse(f)
+	live := map[*Value]bool{}
+	var work []*Value
+	mark := func(v *Value) {
+		if v != nil && !live[v] {
+			live[v] = true
+			work = append(work, v)
+		}
+	}
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			if !v.pure() {
+				mark(v)
+			}
+		}
+		mark(b.Control)
+		for _, v := range b.Results {
+			mark(v)
+		}
+	}
+	for _, v := range f.Params {
+		mark(v)
+	}
+	for len(work) != 0 {
+		v := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, a := range v.Args {
+			mark(a)
+		}
+	}
+	for _, b := range f.Blocks {
+		values := b.Values[:0]
+		for _, v := range b.Values {
+			if live[v] {
+				values = append(values, v)
+			} else {
+				changed = true
+			}
+		}
+		for i := len(values); i < len(b.Values); i++ {
+			b.Values[i] = nil
+		}
+		b.Values = values
+	}
+	return
+}
+
+// fuse 把以跳转结束的块与唯一前驱是它的后继合并
+func fuse(f *Func) (changed bool) {
+	for _, b := range f.Blocks {
+		for b.Kind == Jump && len(b.Succs) == 1 {
+			s := b.Succs[0]
+			if s == b || s == f.Blocks[0] || len(s.Preds) != 1 {
+				break
+			}
+			for _, v := range append([]*Value(nil), s.Values...) {
+				if v.Op == OpPhi {
+					f.replace(v, v.Args[0])
+				}
+			}
+			for _, v := range s.Values {
+				v.Block = b
+			}
+			b.Values = append(b.Values, s.Values...)
+			b.Kind, b.Control, b.Results, b.Succs = s.Kind, s.Control, s.Results, s.Succs
+			for _, t := range s.Succs {
+				for i, p := range t.Preds {
+					if p == s {
+						t.Preds[i] = b
+					}
+				}
+			}
+			s.Values, s.Succs, s.Preds = nil, nil, nil
+			changed = true
+		}
+	}
+	if changed {
+		blocks := f.Blocks[:0]
+		for _, b := range f.Blocks {
+			if b == f.Blocks[0] || len(b.Preds) != 0 {
+				b.Index = len(blocks)
+				blocks = append(blocks, b)
+			}
+		}
+		f.Blocks = blocks
+	}
+	return
+}
+
+// replace 把 f 中对 old 的使用替换为 val 并删除 old, 返回使用了 old 的指令.
+func (f *Func) replace(old, val *Value) (users []*Value) {
+	for _, b := range f.Blocks {
+		for _, v := range b.Values {
+			for i, a := range v.Args {
+				if a == old {
+					v.Args[i] = val
+					if len(users) == 0 || users[len(users)-1] != v {
+						users = append(users, v)
+					}
+				}
+			}
+		}
+		if b.Control == old {
+			b.Control = val
+		}
+		for i, a := range b.Results {
+			if a == old {
+				b.Results[i] = val
+			}
+		}
+	}
+	if b := old.Block; b != nil {
+		for i, v := range b.Values {
+			if v == old {
+				b.Values = append(b.Values[:i], b.Values[i+1:]...)
+				break
+			}
+		}
+	}
+	return
+}
+
+// removeEdge 删除 from 到 to 的一条边, 以及 to 中 OpPhi 对应的参数.
+func removeEdge(from, to *Block) {
+	for i, p := range to.Preds {
+		if p != from {
+			continue
+		}
+		to.Preds = append(to.Preds[:i], to.Preds[i+1:]...)
+		for _, v := range to.Values {
+			if v.Op == OpPhi {
+				v.Args = append(v.Args[:i], v.Args[i+1:]...)
+			}
+		}
+		break
+	}
+	for i, s := range from.Succs {
+		if s == to {
+			from.Succs = append(from.Succs[:i], from.Succs[i+1:]...)
+			break
+		}
+	}
+}
+
+// removeUnreachable 删除从入口不可达的块并重新编号, 返回 f 是否改变.
+func removeUnreachable(f *Func) bool {
+	seen := map[*Block]bool{}
+	work := []*Block{f.Blocks[0]}
+	seen[f.Blocks[0]] = true
+	for len(work) != 0 {
+		b := work[len(work)-1]
+		work = work[:len(work)-1]
+		for _, s := range b.Succs {
+			if !seen[s] {
+				seen[s] = true
+				work = append(work, s)
+			}
+		}
+	}
+	if len(seen) == len(f.Blocks) {
+		return false
+	}
+	blocks := f.Blocks[:0]
+	for _, b := range f.Blocks {
+		if seen[b] {
+			b.Index = len(blocks)
+			blocks = append(blocks, b)
+			continue
+		}
+		for len(b.Succs) != 0 {
+			removeEdge(b, b.Succs[0])
+		}
+	}
+	for i := len(blocks); i < len(f.Blocks); i++ {
+		f.Blocks[i] = nil
+	}
+	f.Blocks = blocks
+	return true
+}
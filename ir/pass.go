@@ -0,0 +1,123 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ir
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/ZxxLang/zxx/ast"
+)
+
+// Program 是一个文件中全部 func, proc 的 IR
+type Program struct {
+	Funcs []*Func // 按声明顺序
+	names map[string]*Func
+}
+
+// BuildFile 降低 sf 中全部有函数体的 func, proc 声明, 同名的声明只取第一个.
+func BuildFile(sf *ast.SourceFile) (*Program, error) {
+	p := &Program{names: map[string]*Func{}}
+	for _, d := range sf.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || p.names[fn.Name.Name.Source] != nil {
+			continue
+		}
+		f, err := Build(fn)
+		if err != nil {
+			return nil, err
+		}
+		p.Funcs = append(p.Funcs, f)
+		p.names[f.Name] = f
+	}
+	return p, nil
+}
+
+// Func 返回名为 name 的函数, 没有时返回 nil.
+func (p *Program) Func(name string) *Func {
+	return p.names[name]
+}
+
+func (p *Program) String() string {
+	var buf bytes.Buffer
+	for i, f := range p.Funcs {
+		if i != 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(f.String())
+	}
+	return buf.String()
+}
+
+// Pass 是 Func 上的一个优化
+type Pass struct {
+	Name  string
+	Level int                            // 启用的最低优化级别
+	Run   func(p *Program, f *Func) bool // 返回 f 是否改变
+}
+
+var passes []*Pass
+
+// Register 注册 pass, Pipeline 按注册的顺序执行. 名字重复时 panic.
+func Register(pass *Pass) {
+	for _, x := range passes {
+		if x.Name == pass.Name {
+			panic("ir: pass " + pass.Name + " registered twice")
+		}
+	}
+	passes = append(passes, pass)
+}
+
+// Passes 返回已注册的 pass
+func Passes() []*Pass {
+	return append([]*Pass(nil), passes...)
+}
+
+func init() {
+	Register(&Pass{"inline", 2, func(p *Program, f *Func) bool { return Inline(p, f) }})
+	Register(&Pass{"fold", 1, func(_ *Program, f *Func) bool { return Propagate(f) }})
+	Register(&Pass{"dce", 1, func(_ *Program, f *Func) bool { return DeadCode(f) }})
+}
+
+// MaxRounds 是 Pipeline 重复执行全部 pass 的最多轮数, 限制了内联的深度.
+const MaxRounds = 4
+
+// Pipeline 按优化级别执行已注册的 pass.
+//
+// 级别 0 不优化, 1 进行常量传播和死代码删除, 2 另外内联小函数.
+type Pipeline struct {
+	Level int
+	Dump  io.Writer // 不为 nil 时输出每个改变了 Func 的 pass 之前和之后的 IR
+}
+
+// Run 对 p 的每个 Func 重复执行 pass, 直到不再改变或者达到 MaxRounds.
+func (c *Pipeline) Run(p *Program) {
+	for _, f := range p.Funcs {
+		for round := 1; round <= MaxRounds; round++ {
+			changed := false
+			for _, pass := range passes {
+				if pass.Level > c.Level {
+					continue
+				}
+				var before string
+				if c.Dump != nil {
+					before = f.String()
+				}
+				if !pass.Run(p, f) {
+					continue
+				}
+				changed = true
+				if c.Dump != nil {
+					fmt.Fprintf(c.Dump, "-- before %s, round %d\n%s", pass.Name, round, before)
+					fmt.Fprintf(c.Dump, "-- after %s, round %d\n%s", pass.Name, round, f)
+				}
+			}
+			if !changed {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexutil
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FormatFloat 返回重新解析后值不变的最短 VALFLOAT 写法, 与平台无关:
+//
+//	1.5, 3.0, 1.0e21, 1.5e21, 0.000001, nan, infinite
+//
+// 绝对值很小的数使用小数形式, 不使用负指数.
+// 负数的结果以 '-' 开始, 它在源码中是一元运算符.
+func FormatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "infinite"
+	case math.IsInf(f, -1):
+		return "-infinite"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	i := strings.IndexByte(s, 'e')
+	if i == -1 {
+		if !strings.ContainsRune(s, '.') {
+			s += ".0"
+		}
+		return s
+	}
+	if s[i+1] == '-' {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	mantissa := s[:i]
+	if !strings.ContainsRune(mantissa, '.') {
+		mantissa += ".0"
+	}
+	// 去掉指数的 '+' 和前导零, 例如 1e+21 为 1.0e21
+	return mantissa + "e" + strings.TrimLeft(s[i+2:], "0")
+}
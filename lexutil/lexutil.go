@@ -0,0 +1,348 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包识别并严格检查扫描器产生的字面值和标识符符号.
+//
+// 以数字或引号开始的符号必须是合法的字面值:
+//
+//	VALINTEGER   123, 1_000_000, 0x1F, 0o755, 0b1010_1010
+//	VALFLOAT     1.5, 1.5e10, 1.5e+10, 1e10, 以 'd' 结尾的十进制数 19.99d, 100d
+//	VALDATETIME  20160204T, 20160204T21:49:33Z, 21:49+08:00
+//	VALSTRING    'text', "a\tb", `raw`
+//
+// 数字分隔符 '_' 只能出现在两个数字之间. 前缀 0x, 0o, 0b 和指数符号 e 必须小写,
+// 十六进制数字不限. 20160204 这样的 8 位纯数字被识别为 VALINTEGER, 由使用者决定其含义.
+// 字面值的值由 token.Value 计算. 成员的每一段不能以数字开始, 例如 .5 和 a.5 是错误.
+package lexutil
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Error 表示非法的字面值, Offset 是出错字符在符号中的字节偏移量.
+type Error struct {
+	Offset int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return "lexutil: " + e.Msg + " at offset " + strconv.Itoa(e.Offset)
+}
+
+func errorf(code string, offset int, msg string) *Error {
+	if offset < len(code) {
+		msg += " '" + string(code[offset]) + "'"
+	}
+	return &Error{offset, msg + " in " + strconv.Quote(code)}
+}
+
+// Classify 返回符号 code 的 Token: VALINTEGER, VALFLOAT, VALDATETIME, VALSTRING,
+// IDENT, MEMBER, MEMBERS 之一, 非标识符的其它文本返回 PLACEHOLDER.
+// 以数字或引号开始却不是合法字面值, 或者成员的某段以数字开始时返回 *Error.
+func Classify(code string) (token.Token, error) {
+	if code == "" {
+		return token.PLACEHOLDER, nil
+	}
+	switch {
+	case isDigit(code[0]):
+		return number(code)
+	case code[0] == '\'' || code[0] == '"' || code[0] == '`':
+		if _, err := Unquote(code); err != nil {
+			return 0, err
+		}
+		return token.VALSTRING, nil
+	}
+	return ident(code)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isHex(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func isLetter(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// ident 识别标识符和成员, 成员的段以数字开始时返回 *Error
+func ident(code string) (token.Token, error) {
+	dot := 0
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		if c == '.' {
+			dot++
+			continue
+		}
+		if !isLetter(c) && !isDigit(c) {
+			return token.PLACEHOLDER, nil
+		}
+	}
+	for i := 0; i+1 < len(code); i++ {
+		if code[i] == '.' && isDigit(code[i+1]) {
+			return 0, errorf(code, i+1, "member starts with digit")
+		}
+	}
+	switch dot {
+	case 0:
+		return token.IDENT, nil
+	case 1:
+		return token.MEMBER, nil
+	}
+	return token.MEMBERS, nil
+}
+
+func isOctal(c byte) bool { return c >= '0' && c <= '7' }
+
+func isBinary(c byte) bool { return c == '0' || c == '1' }
+
+// digits 返回从 i 开始满足 valid 的数字及分隔符 '_' 的结束位置,
+// '_' 只能出现在两个数字之间.
+func digits(code string, i int, valid func(byte) bool) (int, error) {
+	start := i
+	for ; i < len(code); i++ {
+		if code[i] != '_' {
+			if !valid(code[i]) {
+				break
+			}
+			continue
+		}
+		if i == start || i+1 == len(code) || !valid(code[i+1]) {
+			return i, errorf(code, i, "misplaced separator")
+		}
+	}
+	return i, nil
+}
+
+func number(code string) (token.Token, error) {
+	if len(code) > 1 && code[0] == '0' && (code[1] == 'X' || code[1] == 'O' || code[1] == 'B') {
+		return 0, errorf(code, 1, "uppercase prefix")
+	}
+	if len(code) > 1 && code[0] == '0' && (code[1] == 'x' || code[1] == 'o' || code[1] == 'b') {
+		valid := isHex
+		switch code[1] {
+		case 'o':
+			valid = isOctal
+		case 'b':
+			valid = isBinary
+		}
+		if len(code) == 2 {
+			return 0, errorf(code, 2, "missing digits")
+		}
+		i, err := digits(code, 2, valid)
+		if err != nil {
+			return 0, err
+		}
+		if i != len(code) {
+			return 0, errorf(code, i, "invalid digit")
+		}
+		return token.VALINTEGER, nil
+	}
+
+	for i := 0; i < len(code); i++ {
+		switch code[i] {
+		case 'T', ':', 'Z':
+			if _, err := ParseDatetime(code); err != nil {
+				return 0, err
+			}
+			return token.VALDATETIME, nil
+		}
+	}
+
+	// 整数部分
+	i, err := digits(code, 0, isDigit)
+	if err != nil {
+		return 0, err
+	}
+	if i == len(code) {
+		return token.VALINTEGER, nil
+	}
+	if i+1 == len(code) && code[i] == 'd' {
+		return token.VALFLOAT, nil
+	}
+
+	// 小数部分
+	if code[i] == '.' {
+		start := i + 1
+		if i, err = digits(code, start, isDigit); err != nil {
+			return 0, err
+		}
+		if i == start {
+			return 0, errorf(code, i, "missing fraction digits")
+		}
+	}
+
+	// 指数部分, e 之后是可选的符号和数字
+	if i < len(code) && code[i] == 'E' {
+		return 0, errorf(code, i, "uppercase exponent")
+	}
+	if i < len(code) && code[i] == 'e' {
+		i++
+		if i < len(code) && (code[i] == '+' || code[i] == '-') {
+			i++
+		}
+		start := i
+		if i, err = digits(code, start, isDigit); err != nil {
+			return 0, err
+		}
+		if i == start {
+			return 0, errorf(code, i, "missing exponent digits")
+		}
+	}
+
+	// 十进制数后缀
+	if i+1 == len(code) && code[i] == 'd' {
+		i++
+	}
+	if i != len(code) {
+		return 0, errorf(code, i, "unexpected")
+	}
+	return token.VALFLOAT, nil
+}
+
+// ParseDatetime 解析 ISO 8601 基本格式的日期时间, 并按 RFC 3339 检查取值范围:
+//
+//	20160204            日期
+//	20160204T           日期, T 之后的时间可省略
+//	20160204T21:49      日期和时间, 时间也可以写作 2149, 214933
+//	20160204T21:49:33Z  时区可以是 Z, +08, +0800 或 +08:00
+//	21:49:33            时间, 日期为 0000-01-01
+//
+// 缺少时区时使用 time.Local. 错误总是 *Error 类型.
+func ParseDatetime(code string) (time.Time, error) {
+	p := 0
+
+	// digits 读取 n 位数字, 返回值 -1 表示失败
+	digits := func(n int) int {
+		if p+n > len(code) {
+			return -1
+		}
+		v := 0
+		for i := p; i < p+n; i++ {
+			if !isDigit(code[i]) {
+				return -1
+			}
+			v = v*10 + int(code[i]-'0')
+		}
+		p += n
+		return v
+	}
+	fail := func(msg string) (time.Time, error) {
+		return time.Time{}, errorf(code, p, msg)
+	}
+
+	year, month, day := 0, 1, 1
+	hour, min, sec := 0, 0, 0
+
+	n := 0
+	for n < len(code) && isDigit(code[n]) {
+		n++
+	}
+	if n > 8 {
+		p = 8
+		return fail("invalid date")
+	}
+
+	// 日期是 8 位数字
+	if n == 8 {
+		if year = digits(4); year < 0 {
+			return fail("invalid year")
+		}
+		at := p
+		if month = digits(2); month < 1 || month > 12 {
+			p = at
+			return fail("invalid month")
+		}
+		at = p
+		if day = digits(2); day < 1 || day > daysIn(year, month) {
+			p = at
+			return fail("invalid day")
+		}
+		if p < len(code) && code[p] == 'T' {
+			p++
+		}
+	}
+
+	// 时间
+	if p < len(code) && isDigit(code[p]) {
+		withDate := p != 0
+		at := p
+		if hour = digits(2); hour < 0 || hour > 23 {
+			p = at
+			return fail("invalid hour")
+		}
+		colon := p < len(code) && code[p] == ':'
+		if colon {
+			p++
+		} else if !withDate {
+			return fail("missing ':' in time")
+		}
+		at = p
+		if min = digits(2); min < 0 || min > 59 {
+			p = at
+			return fail("invalid minute")
+		}
+		if colon && p < len(code) && code[p] == ':' {
+			p++
+			at = p
+			if sec = digits(2); sec < 0 || sec > 60 {
+				p = at
+				return fail("invalid second")
+			}
+		} else if !colon && p < len(code) && isDigit(code[p]) {
+			at = p
+			if sec = digits(2); sec < 0 || sec > 60 {
+				p = at
+				return fail("invalid second")
+			}
+		}
+	}
+
+	// 时区
+	loc := time.Local
+	if p < len(code) && code[p] == 'Z' {
+		p++
+		loc = time.UTC
+	} else if p < len(code) && code[p] == '+' {
+		p++
+		at := p
+		zh, zm := digits(2), 0
+		if zh < 0 || zh > 23 {
+			p = at
+			return fail("invalid zone")
+		}
+		if p < len(code) && code[p] == ':' {
+			p++
+		}
+		if p < len(code) {
+			at = p
+			if zm = digits(2); zm < 0 || zm > 59 {
+				p = at
+				return fail("invalid zone")
+			}
+		}
+		loc = time.FixedZone("", zh*3600+zm*60)
+	}
+
+	if p != len(code) {
+		return fail("unexpected")
+	}
+	return time.Date(year, time.Month(month), day, hour, min, sec, 0, loc), nil
+}
+
+func daysIn(year, month int) int {
+	switch month {
+	case 2:
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	}
+	return 31
+}
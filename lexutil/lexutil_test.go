@@ -0,0 +1,220 @@
+package lexutil_test
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func TestClassify(t *testing.T) {
+	for code, tok := range map[string]token.Token{
+		"123":                  token.VALINTEGER,
+		"1_000":                token.VALINTEGER,
+		"0x1F":                 token.VALINTEGER,
+		"0b101":                token.VALINTEGER,
+		"1_000_000":            token.VALINTEGER,
+		"0o755":                token.VALINTEGER,
+		"0b1010_1010":          token.VALINTEGER,
+		"1_0.2_5e1_0":          token.VALFLOAT,
+		"20160204":             token.VALINTEGER,
+		"1.5":                  token.VALFLOAT,
+		"1.5e10":               token.VALFLOAT,
+		"1.5e+10":              token.VALFLOAT,
+		"1e10":                 token.VALFLOAT,
+		"1e-5":                 token.VALFLOAT,
+		"2e+3":                 token.VALFLOAT,
+		"19.99d":               token.VALFLOAT,
+		"100d":                 token.VALFLOAT,
+		"20160204T":            token.VALDATETIME,
+		"20160204T21:49:33Z":   token.VALDATETIME,
+		"20160204T214933+0800": token.VALDATETIME,
+		"21:49+08:00":          token.VALDATETIME,
+		"name":                 token.IDENT,
+		"a.b":                  token.MEMBER,
+		"a.b.c":                token.MEMBERS,
+		"a.b2":                 token.MEMBER,
+		"@x":                   token.PLACEHOLDER,
+	} {
+		got, err := lexutil.Classify(code)
+		if err != nil || got != tok {
+			t.Fatal(code, got, err)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	for code, offset := range map[string]int{
+		"0x":                 2,
+		"0x1G":               3,
+		"0b102":              4,
+		"0o8":                2,
+		"0x_1":               2,
+		"1__0":               1,
+		"10_":                2,
+		"1_.5":               1,
+		"1.e5":               2,
+		"1.5e":               4,
+		"1.5e+":              5,
+		"1e-":                3,
+		"12a":                2,
+		"12T99:99Z":          2,
+		"20161304T":          4,
+		"20160230T":          6,
+		"20160204T24:00":     9,
+		"20160204T21:60":     12,
+		"20160204T21:49:61":  15,
+		"20160204T21:49+24":  15,
+		"20160204T21:49:00X": 17,
+		"2149Z":              2,
+		"0X1F":               1,
+		"0B1":                1,
+		"2E+3":               1,
+		"1.5E10":             3,
+		".5":                 1,
+		"a.5":                2,
+		"a.b.3e":             4,
+	} {
+		_, err := lexutil.Classify(code)
+		e, ok := err.(*lexutil.Error)
+		if !ok || e.Offset != offset {
+			t.Fatal(code, err)
+		}
+	}
+}
+
+func TestParseDatetime(t *testing.T) {
+	for code, want := range map[string]time.Time{
+		"20160204":             time.Date(2016, 2, 4, 0, 0, 0, 0, time.Local),
+		"20160229T21:49Z":      time.Date(2016, 2, 29, 21, 49, 0, 0, time.UTC),
+		"20160204T21:49:33Z":   time.Date(2016, 2, 4, 21, 49, 33, 0, time.UTC),
+		"20160204T214933+0800": time.Date(2016, 2, 4, 13, 49, 33, 0, time.UTC),
+		"21:49:33":             time.Date(0, 1, 1, 21, 49, 33, 0, time.Local),
+	} {
+		got, err := lexutil.ParseDatetime(code)
+		if err != nil || !got.Equal(want) {
+			t.Fatal(code, got, err)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	for lit, want := range map[string]string{
+		`'a\nb'`:           `a\nb`,
+		`"a\"b"`:           `a"b`,
+		`"\t\\中\u{1F600}"`: "\t\\中\U0001F600",
+		"\"a\n\t  b\"":     "a\nb",
+		"`a\\n\n\tb`":      "a\\n\n\tb",
+		"'a\r\n  b'":       "a\nb",
+		"\"a\r\tb\"":       "a\nb",
+		`"a\rb"`:           "a\rb",
+		"`a\r\nb\rc`":      "a\nb\nc",
+	} {
+		got, err := lexutil.Unquote(lit)
+		if err != nil || got != want {
+			t.Fatal(lit, got, err)
+		}
+	}
+
+	for lit, offset := range map[string]int{
+		`"a\qb"`:       2,
+		`"\u12"`:       1,
+		`"\u{}"`:       1,
+		`"\u{110000}"`: 1,
+		`"\uD800"`:     1,
+		`"abc\"`:       6,
+		`'abc`:         4,
+		"`abc":         4,
+	} {
+		_, err := lexutil.Unquote(lit)
+		e, ok := err.(*lexutil.Error)
+		if !ok || e.Offset != offset {
+			t.Fatal(lit, err)
+		}
+	}
+}
+
+func TestSegment(t *testing.T) {
+	for lit, want := range map[string]string{
+		`"hello `:     "hello ",
+		`, `:          ", ",
+		`!\{x\}"`:     "!{x}",
+		`\t\"`:        "\t\"",
+		"\"a\n   b\"": "a\nb",
+		`\u{41}"`:     "A",
+		`"`:           "",
+		"\"a\n\tb ":   "a\nb ",
+	} {
+		got, err := lexutil.Segment(lit)
+		if err != nil || got != want {
+			t.Fatal(lit, got, err)
+		}
+	}
+	if _, err := lexutil.Segment(`x\q`); err == nil {
+		t.Fatal(`x\q`)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, s := range []string{"", "it's", "a\nb\t{c}\\", `"中"`} {
+		lit := lexutil.Quote(s)
+		if got, err := lexutil.Unquote(lit); err != nil || got != s {
+			t.Fatal(lit, got, err)
+		}
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	for f, want := range map[float64]string{
+		1.5:          "1.5",
+		3:            "3.0",
+		-2:           "-2.0",
+		1e21:         "1.0e21",
+		1.5e300:      "1.5e300",
+		1e-6:         "0.000001",
+		1 / 3.0:      "0.3333333333333333",
+		math.Inf(1):  "infinite",
+		math.Inf(-1): "-infinite",
+	} {
+		if got := lexutil.FormatFloat(f); got != want {
+			t.Fatal(f, got, want)
+		}
+	}
+	if lexutil.FormatFloat(math.NaN()) != "nan" {
+		t.Fatal("nan")
+	}
+
+	// 结果总是可以重新解析为相同的值
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		f := math.Float64frombits(r.Uint64())
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			continue
+		}
+		s := strings.TrimPrefix(lexutil.FormatFloat(f), "-")
+		tok, err := lexutil.Classify(s)
+		if err != nil || tok != token.VALFLOAT {
+			t.Fatal(f, s, tok, err)
+		}
+		v, err := token.Value(tok, s)
+		if err != nil || math.Abs(v.(float64)) != math.Abs(f) {
+			t.Fatal(f, s, v, err)
+		}
+	}
+}
+
+func TestQuoteStyle(t *testing.T) {
+	if lit, ok := lexutil.QuoteSingle("a{b}"); !ok || lit != "'a{b}'" {
+		t.Fatal(lit, ok)
+	}
+	if lit, ok := lexutil.QuoteSingle("it's"); ok {
+		t.Fatal(lit)
+	}
+	if lit := lexutil.QuoteDouble("a{b}\n"); lit != `"a\{b}\n"` {
+		t.Fatal(lit)
+	}
+}
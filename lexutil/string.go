@@ -0,0 +1,188 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexutil
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Unquote 返回字符串字面值 lit 的值, 错误总是 *Error 类型.
+//
+//	'text'  不支持转义
+//	"text"  支持转义 \n \t \r \0 \\ \" \' \{ \} \uXXXX \u{X...}
+//	`text`  原始字符串, 内容原样保留
+//
+// 单双引号的多行字符串续行的前置空白被剔除, 原始字符串的换行和缩进都被保留.
+// 源码中的换行符 CRLF 和 CR 在值中都是 LF, 值与文件的换行风格无关, 转义的 \r 不受影响.
+func Unquote(lit string) (string, error) {
+	if lit == "" || lit[0] != '\'' && lit[0] != '"' && lit[0] != '`' {
+		return "", errorf(lit, 0, "invalid quote")
+	}
+	quote := lit[0]
+	if len(lit) < 2 || lit[len(lit)-1] != quote || quote == '"' && escaped(lit, len(lit)-1) {
+		return "", errorf(lit, len(lit), "string is incomplete")
+	}
+	s := lit[1 : len(lit)-1]
+	if !utf8.ValidString(s) {
+		return "", errorf(lit, 0, "invalid UTF-8 encode")
+	}
+	if quote == '`' {
+		if strings.IndexByte(s, '\r') != -1 {
+			s = strings.Replace(strings.Replace(s, "\r\n", "\n", -1), "\r", "\n", -1)
+		}
+		return s, nil
+	}
+	return unescape(lit, 1, len(lit)-1, quote)
+}
+
+// Quote 返回值为 s 的字符串字面值, 是 Unquote 的逆操作.
+// 优先使用不需要转义的单引号字符串.
+func Quote(s string) string {
+	if lit, ok := QuoteSingle(s); ok {
+		return lit
+	}
+	return QuoteDouble(s)
+}
+
+// QuoteSingle 返回值为 s 的单引号字符串字面值. 单引号字符串不支持转义,
+// s 包含单引号, 换行, 回车, 制表符或反斜杠时 ok 为 false.
+func QuoteSingle(s string) (lit string, ok bool) {
+	if strings.ContainsAny(s, "'\n\r\t\\") {
+		return "", false
+	}
+	return "'" + s + "'", true
+}
+
+// QuoteDouble 返回值为 s 的双引号字符串字面值, '{' 被转义, 不会开始插值表达式.
+func QuoteDouble(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\\', '"', '{':
+			// '{' 开始插值表达式
+			buf.WriteByte('\\')
+			buf.WriteRune(c)
+		default:
+			buf.WriteRune(c)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// Segment 返回插值字符串的文本片段 STRINGLIT 的值, 错误总是 *Error 类型.
+// 首个片段以 '"' 开始, 最后的片段以 '"' 结束, 它们不属于值.
+func Segment(lit string) (string, error) {
+	start, end := 0, len(lit)
+	if start < end && lit[0] == '"' {
+		start++
+	}
+	if end > start && lit[end-1] == '"' && !escaped(lit, end-1) {
+		end--
+	}
+	if !utf8.ValidString(lit) {
+		return "", errorf(lit, 0, "invalid UTF-8 encode")
+	}
+	return unescape(lit, start, end, '"')
+}
+
+// unescape 返回 lit[start:end] 按 quote 规则转义后的值
+func unescape(lit string, start, end int, quote byte) (string, error) {
+	buf := make([]byte, 0, end-start)
+	for i := start; i < end; i++ {
+		c := lit[i]
+		if c == '\r' && i+1 < end && lit[i+1] == '\n' {
+			i++
+			c = '\n'
+		}
+		if c == '\n' || c == '\r' {
+			buf = append(buf, '\n')
+			for i+1 < end && (lit[i+1] == ' ' || lit[i+1] == '\t') {
+				i++
+			}
+			continue
+		}
+		if c != '\\' || quote == '\'' {
+			buf = append(buf, c)
+			continue
+		}
+
+		at := i
+		if i++; i == end {
+			return "", errorf(lit, at, "unknown escape sequence")
+		}
+		switch c = lit[i]; c {
+		case 'n':
+			buf = append(buf, '\n')
+		case 't':
+			buf = append(buf, '\t')
+		case 'r':
+			buf = append(buf, '\r')
+		case '0':
+			buf = append(buf, 0)
+		case '\\', '"', '\'', '{', '}':
+			buf = append(buf, c)
+		case 'u':
+			r, n := escapeRune(lit[i+1 : end])
+			if n == 0 {
+				return "", errorf(lit, at, "invalid unicode escape")
+			}
+			buf = append(buf, string(r)...)
+			i += n
+		default:
+			return "", errorf(lit, at, "unknown escape sequence")
+		}
+	}
+	return string(buf), nil
+}
+
+// escaped 返回 lit[i] 之前是否有奇数个 '\'
+func escaped(lit string, i int) bool {
+	n := 0
+	for i--; i > 0 && lit[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// escapeRune 解析 \u 之后的 XXXX 或 {X...}, 返回 rune 和使用的字节数, 0 表示非法.
+func escapeRune(s string) (rune, int) {
+	var hex string
+	n := 4
+	if s != "" && s[0] == '{' {
+		end := 1
+		for end < len(s) && s[end] != '}' {
+			end++
+		}
+		if end == len(s) || end == 1 || end > 7 {
+			return 0, 0
+		}
+		hex, n = s[1:end], end+1
+	} else if len(s) >= 4 {
+		hex = s[:4]
+	} else {
+		return 0, 0
+	}
+	for i := 0; i < len(hex); i++ {
+		if !isHex(hex[i]) {
+			return 0, 0
+		}
+	}
+	v, _ := strconv.ParseUint(hex, 16, 32)
+	if v > utf8.MaxRune || v >= 0xD800 && v < 0xE000 {
+		return 0, 0
+	}
+	return rune(v), n
+}
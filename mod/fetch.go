@@ -0,0 +1,110 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mod
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CacheDir 返回依赖的缓存目录: 环境变量 ZXXMODCACHE, 或者用户缓存目录中的 zxx/mod.
+func CacheDir() (string, error) {
+	if dir := os.Getenv("ZXXMODCACHE"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "zxx", "mod"), nil
+}
+
+// HashDir 返回目录 dir 中全部文件的散列, 形如 "h1:" 加 base64 编码的 SHA-256.
+// 散列的内容是每个文件一行 "文件内容的 SHA-256 的十六进制  相对路径", 按路径排序,
+// 路径使用 '/' 分隔. .git 目录不参与计算.
+func HashDir(dir string) (string, error) {
+	var lines []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(src)
+		lines = append(lines, hex.EncodeToString(sum[:])+"  "+filepath.ToSlash(rel)+"\n")
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][66:] < lines[j][66:] })
+	sum := sha256.Sum256([]byte(strings.Join(lines, "")))
+	return "h1:" + base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// Download 把依赖 r 下载到缓存目录 cache 中, 返回其目录. 已下载的依赖不再下载.
+// 内容的散列与 sums 中的记录不同时返回错误, 没有记录时写入 sums.
+// 下载使用 git clone, 只取 r.Version 对应的一次提交, 不保留 .git 目录.
+func Download(cache string, r Require, sums Sums) (string, error) {
+	dir := filepath.Join(cache, filepath.FromSlash(r.Path)+"@"+r.Version)
+	if _, err := os.Stat(dir); err != nil {
+		if err := clone(r, dir); err != nil {
+			return "", err
+		}
+	}
+	h, err := HashDir(dir)
+	if err != nil {
+		return "", err
+	}
+	key := r.Path + "@" + r.Version
+	if want, ok := sums[key]; ok && want != h {
+		return "", errors.New("mod: checksum mismatch for " + key + ": downloaded " + h + ", " + SumFile + " has " + want)
+	}
+	sums[key] = h
+	return dir, nil
+}
+
+// clone 把 r 克隆到 dir, 先克隆到同一目录中的临时目录, 成功后改名, 中断时不留下不完整的 dir.
+func clone(r Require, dir string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(filepath.Dir(dir), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "clone", "--quiet", "--depth", "1", "--branch", r.Version, r.Repo(), tmp)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.New("mod: git clone " + r.Repo() + " " + r.Version + ": " + strings.TrimSpace(stderr.String()))
+	}
+	if err := os.RemoveAll(filepath.Join(tmp, ".git")); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dir)
+}
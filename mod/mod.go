@@ -0,0 +1,261 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包读写模块清单 zxx.mod 和校验和文件 zxx.sum, 并把依赖下载到本地缓存.
+//
+// zxx.mod 位于模块根目录, 是 config 包的配置文档:
+//
+//	var module = 'example.org/app'
+//	var require = [
+//		{path: 'example.org/lib', version: 'v1.2.0'}
+//		{path: 'example.org/x', version: 'v0.1.0', url: 'https://git.example.org/x.git'}
+//	]
+//
+// version 是依赖的 git 仓库中的标签或分支, url 为空时是 https:// + path.
+// use 路径等于模块或依赖的路径, 或者以它加 '/' 开头时, 解析到模块根目录或依赖的缓存目录中对应的子目录.
+//
+// zxx.sum 每行是 "path version hash", 记录下载过的依赖的内容散列, 参见 HashDir.
+//
+// Vendor 把依赖复制到模块根目录的 vendor 目录中, 之后 Dir 优先使用其中版本相同的依赖,
+// 无需访问网络和缓存.
+package mod
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/config"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/platform"
+	"github.com/ZxxLang/zxx/token"
+)
+
+const (
+	File    = "zxx.mod" // 模块清单的文件名
+	SumFile = "zxx.sum" // 校验和文件的文件名
+)
+
+// Require 是一个依赖
+type Require struct {
+	Path    string `zxx:"path"`
+	Version string `zxx:"version"`
+	URL     string `zxx:"url"` // git 仓库的地址, 为空时是 https:// + Path
+}
+
+// Repo 返回 r 的 git 仓库地址
+func (r Require) Repo() string {
+	if r.URL != "" {
+		return r.URL
+	}
+	return "https://" + r.Path
+}
+
+// Manifest 是 zxx.mod 的内容
+type Manifest struct {
+	Module  string    `zxx:"module"`
+	Require []Require `zxx:"require"`
+}
+
+// Parse 解析 zxx.mod 的内容 src. module 不能为空, 依赖必须有 path 和 version, path 不能重复.
+// path 和 version 用作缓存和 vendor 中的目录名, 不能含有 "." 和 ".." 段, 反斜杠和冒号, version 不能含有 '/'.
+func Parse(src []byte) (*Manifest, error) {
+	m := new(Manifest)
+	if err := config.Decode(src, m); err != nil {
+		return nil, err
+	}
+	if m.Module == "" {
+		return nil, errors.New("mod: missing module path")
+	}
+	seen := map[string]bool{}
+	for _, r := range m.Require {
+		switch {
+		case r.Path == "" || r.Version == "":
+			return nil, errors.New("mod: require needs path and version")
+		case !validPath(r.Path) || !validPath(r.Version) || strings.Contains(r.Version, "/"):
+			return nil, errors.New("mod: invalid require " + r.Path + " " + r.Version)
+		case seen[r.Path]:
+			return nil, errors.New("mod: duplicate require " + r.Path)
+		}
+		seen[r.Path] = true
+	}
+	return m, nil
+}
+
+// Load 读取目录 dir 中的 zxx.mod, 文件不存在时返回的错误满足 os.IsNotExist.
+func Load(dir string) (*Manifest, error) {
+	src, err := ioutil.ReadFile(filepath.Join(dir, File))
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(src)
+	if err != nil {
+		return nil, errors.New(filepath.Join(dir, File) + ": " + err.Error())
+	}
+	return m, nil
+}
+
+// FindRoot 从目录 dir 开始向上查找含有 zxx.mod 的目录, 找不到时返回空字符串.
+func FindRoot(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, File)); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Format 返回 m 的 zxx.mod 内容, 依赖按 path 排序.
+func (m *Manifest) Format() ([]byte, error) {
+	sort.Slice(m.Require, func(i, j int) bool { return m.Require[i].Path < m.Require[j].Path })
+	return config.Encode(m)
+}
+
+// validPath 返回 path 是否是能在全部平台上使用的 '/' 分隔的相对路径, 参见 platform.CheckPath
+func validPath(path string) bool {
+	return platform.CheckPath(path) == nil
+}
+
+// within 返回 path 是否是 prefix 或者以 prefix 加 '/' 开头, 以及之后的部分
+func within(path, prefix string) (string, bool) {
+	if path == prefix {
+		return "", true
+	}
+	if strings.HasPrefix(path, prefix+"/") {
+		return path[len(prefix)+1:], true
+	}
+	return "", false
+}
+
+// Lookup 返回包含 use 路径 path 的依赖, 以及 path 在依赖中的子目录
+func (m *Manifest) Lookup(path string) (*Require, string, bool) {
+	for i := range m.Require {
+		if rest, ok := within(path, m.Require[i].Path); ok {
+			return &m.Require[i], rest, true
+		}
+	}
+	return nil, "", false
+}
+
+// Dir 返回 use 路径 path 对应的目录, root 是模块根目录, cache 是依赖的缓存目录.
+// vendor/modules.txt 中有版本相同的依赖时使用 vendor 中的目录.
+// path 不属于模块和依赖时返回 false.
+func (m *Manifest) Dir(root, cache, path string) (string, bool) {
+	if rest, ok := within(path, m.Module); ok {
+		return filepath.Join(root, filepath.FromSlash(rest)), true
+	}
+	r, rest, ok := m.Lookup(path)
+	if !ok {
+		return "", false
+	}
+	if vendored, _ := ReadVendor(root); vendored[r.Path] == r.Version {
+		return filepath.Join(root, VendorDir, filepath.FromSlash(r.Path), filepath.FromSlash(rest)), true
+	}
+	return filepath.Join(cache, filepath.FromSlash(r.Path)+"@"+r.Version, filepath.FromSlash(rest)), true
+}
+
+// Tidy 删除 m 中没有被 uses 中的 use 路径用到的依赖, 返回不属于模块和依赖的模块路径.
+// 第一段含有 '.' 的路径是模块路径, 例如 example.org/lib, 其它路径由导入搜索路径解析.
+func (m *Manifest) Tidy(uses []string) (missing []string) {
+	used := map[string]bool{}
+	seen := map[string]bool{}
+	for _, path := range uses {
+		if _, ok := within(path, m.Module); ok {
+			continue
+		}
+		if r, _, ok := m.Lookup(path); ok {
+			used[r.Path] = true
+		} else if first := strings.SplitN(path, "/", 2)[0]; strings.Contains(first, ".") && !seen[path] {
+			seen[path] = true
+			missing = append(missing, path)
+		}
+	}
+	list := m.Require[:0]
+	for _, r := range m.Require {
+		if used[r.Path] {
+			list = append(list, r)
+		}
+	}
+	m.Require = list
+	sort.Strings(missing)
+	return missing
+}
+
+// Imports 返回源码 src 中 use 声明的路径, 不包括引入 Go 包的路径
+func Imports(src []byte) []string {
+	sf, _ := parser.ParseSyntax(src)
+	if sf == nil {
+		return nil
+	}
+	var paths []string
+	for _, d := range sf.Decls {
+		d, ok := d.(*ast.GenDecl)
+		if !ok || d.Tok != token.USE {
+			continue
+		}
+		for _, spec := range d.Specs {
+			if len(spec.Values) == 0 {
+				continue
+			}
+			lit, ok := spec.Values[0].(*ast.BasicLit)
+			if !ok || len(lit.Value.Source) <= 2 {
+				continue
+			}
+			if path := lit.Value.Source[1 : len(lit.Value.Source)-1]; !strings.HasPrefix(path, ast.GoImportPrefix) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// Sums 是 zxx.sum 的内容, 键是 path@version, 值是 HashDir 的结果
+type Sums map[string]string
+
+// ParseSums 解析 zxx.sum 的内容 src, 忽略空行.
+func ParseSums(src []byte) (Sums, error) {
+	sums := Sums{}
+	s := bufio.NewScanner(bytes.NewReader(src))
+	for n := 1; s.Scan(); n++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, errors.New("mod: " + SumFile + ":" + strconv.Itoa(n) + ": want path version hash")
+		}
+		sums[fields[0]+"@"+fields[1]] = fields[2]
+	}
+	return sums, s.Err()
+}
+
+// Format 返回 sums 的 zxx.sum 内容, 按 path 和 version 排序.
+func (sums Sums) Format() []byte {
+	keys := make([]string, 0, len(sums))
+	for k := range sums {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		i := strings.LastIndexByte(k, '@')
+		b.WriteString(k[:i] + " " + k[i+1:] + " " + sums[k] + "\n")
+	}
+	return b.Bytes()
+}
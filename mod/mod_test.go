@@ -0,0 +1,194 @@
+package mod_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/mod"
+)
+
+const manifest = `var module = 'example.org/app'
+var require = [
+	{path: 'example.org/lib', version: 'v1.0.0'}
+	{path: 'example.org/old', version: 'v0.1.0', url: 'https://git.example.org/old.git'}
+]
+`
+
+func TestManifest(t *testing.T) {
+	m, err := mod.Parse([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Module != "example.org/app" || len(m.Require) != 2 || m.Require[0].Repo() != "https://example.org/lib" ||
+		m.Require[1].Repo() != "https://git.example.org/old.git" {
+		t.Fatal(m)
+	}
+	src, err := m.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m2, err := mod.Parse(src); err != nil || !reflect.DeepEqual(m, m2) {
+		t.Fatalf("%s\n%v %v", src, m2, err)
+	}
+
+	for path, want := range map[string]string{
+		"example.org/app/util":  filepath.Join("root", "util"),
+		"example.org/lib":       filepath.Join("cache", "example.org", "lib@v1.0.0"),
+		"example.org/lib/x/y":   filepath.Join("cache", "example.org", "lib@v1.0.0", "x", "y"),
+		"example.org/library":   "",
+		"example.org/other/pkg": "",
+	} {
+		if dir, ok := m.Dir("root", "cache", path); dir != want || ok != (want != "") {
+			t.Errorf("%s: %q %v", path, dir, ok)
+		}
+	}
+
+	missing := m.Tidy([]string{"example.org/lib/x", "example.org/app/util", "fmt", "util", "example.org/new", "example.org/new"})
+	if !reflect.DeepEqual(missing, []string{"example.org/new"}) || len(m.Require) != 1 || m.Require[0].Path != "example.org/lib" {
+		t.Fatal(missing, m.Require)
+	}
+
+	for _, src := range []string{
+		"var require = []",
+		"var module = 'a'\nvar require = [{path: 'b'}]",
+		"var module = 'a'\nvar require = [{path: 'b', version: 'v1'}, {path: 'b', version: 'v2'}]",
+		"var module = 'a'\nvar require = [{path: 'b/../../c', version: 'v1'}]",
+		"var module = 'a'\nvar require = [{path: 'b', version: 'x/y'}]",
+		"var module = 'a'\nvar require = [{path: 'b/aux', version: 'v1'}]",
+		"var module = 'a'\nvar require = [{path: 'b', version: 'v1.'}]",
+	} {
+		if _, err := mod.Parse([]byte(src)); err == nil {
+			t.Errorf("%q: no error", src)
+		}
+	}
+}
+
+func TestImports(t *testing.T) {
+	src := "use 'fmt'\nuse u 'example.org/lib/util'\nuse 'go:strings'\n\nproc main [\n]\n"
+	if got := mod.Imports([]byte(src)); !reflect.DeepEqual(got, []string{"fmt", "example.org/lib/util"}) {
+		t.Fatal(got)
+	}
+}
+
+func TestSums(t *testing.T) {
+	src := "a.org/x v1.0.0 h1:abc=\n\nb.org/y v0.2.0 h1:def=\n"
+	sums, err := mod.ParseSums([]byte(src))
+	if err != nil || sums["a.org/x@v1.0.0"] != "h1:abc=" {
+		t.Fatal(sums, err)
+	}
+	if got := string(sums.Format()); got != strings.Replace(src, "\n\n", "\n", 1) {
+		t.Fatalf("%q", got)
+	}
+	if _, err := mod.ParseSums([]byte("a.org/x v1.0.0\n")); err == nil || err.Error() != "mod: zxx.sum:1: want path version hash" {
+		t.Fatal(err)
+	}
+}
+
+// git 在目录 dir 中执行 git args
+func git(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{"-c", "user.name=zxx", "-c", "user.email=zxx@example.org"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	tmp, err := ioutil.TempDir("", "zxx-mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	repo := filepath.Join(tmp, "repo")
+	os.MkdirAll(filepath.Join(repo, "util"), 0755)
+	ioutil.WriteFile(filepath.Join(repo, "util", "a.zxx"), []byte("pub proc Helper [\n]\n"), 0644)
+	git(t, repo, "init", "--quiet")
+	git(t, repo, "add", ".")
+	git(t, repo, "commit", "--quiet", "-m", "lib")
+	git(t, repo, "tag", "v1.0.0")
+
+	cache := filepath.Join(tmp, "cache")
+	r := mod.Require{Path: "example.org/lib", Version: "v1.0.0", URL: "file://" + filepath.ToSlash(repo)}
+	sums := mod.Sums{}
+	dir, err := mod.Download(cache, r, sums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != filepath.Join(cache, "example.org", "lib@v1.0.0") {
+		t.Fatal(dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "util", "a.zxx")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); !os.IsNotExist(err) {
+		t.Fatal(".git kept", err)
+	}
+	h, err := mod.HashDir(dir)
+	if err != nil || sums["example.org/lib@v1.0.0"] != h || !strings.HasPrefix(h, "h1:") {
+		t.Fatal(sums, h, err)
+	}
+
+	// 缓存中的内容被修改后不再与 zxx.sum 一致
+	ioutil.WriteFile(filepath.Join(dir, "util", "a.zxx"), []byte("pub proc Evil [\n]\n"), 0644)
+	if _, err := mod.Download(cache, r, sums); err == nil || !strings.Contains(err.Error(), "checksum mismatch for example.org/lib@v1.0.0") {
+		t.Fatal(err)
+	}
+	r.Version = "v2.0.0"
+	if _, err := mod.Download(cache, r, sums); err == nil || !strings.HasPrefix(err.Error(), "mod: git clone") {
+		t.Fatal(err)
+	}
+}
+
+func TestVendor(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "zxx-mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	// 缓存中已有的依赖不再下载
+	cache := filepath.Join(tmp, "cache")
+	lib := filepath.Join(cache, "example.org", "lib@v1.0.0", "util")
+	os.MkdirAll(lib, 0755)
+	ioutil.WriteFile(filepath.Join(lib, "a.zxx"), []byte("pub proc Helper [\n]\n"), 0644)
+
+	root := filepath.Join(tmp, "app")
+	m := &mod.Manifest{Module: "example.org/app", Require: []mod.Require{{Path: "example.org/lib", Version: "v1.0.0"}}}
+	if dir, _ := m.Dir(root, cache, "example.org/lib/util"); dir != lib {
+		t.Fatal(dir)
+	}
+	if err := mod.Vendor(root, cache, m, mod.Sums{}); err != nil {
+		t.Fatal(err)
+	}
+	vendored := filepath.Join(root, "vendor", "example.org", "lib", "util")
+	if src, err := ioutil.ReadFile(filepath.Join(vendored, "a.zxx")); err != nil || string(src) != "pub proc Helper [\n]\n" {
+		t.Fatal(string(src), err)
+	}
+	if list, err := mod.ReadVendor(root); err != nil || !reflect.DeepEqual(list, map[string]string{"example.org/lib": "v1.0.0"}) {
+		t.Fatal(list, err)
+	}
+	if dir, _ := m.Dir(root, cache, "example.org/lib/util"); dir != vendored {
+		t.Fatal(dir)
+	}
+	// 版本不同时 vendor 中的副本过时, 使用缓存
+	m.Require[0].Version = "v1.1.0"
+	if dir, _ := m.Dir(root, cache, "example.org/lib"); dir != filepath.Join(cache, "example.org", "lib@v1.1.0") {
+		t.Fatal(dir)
+	}
+
+	m.Require = nil
+	if err := mod.Vendor(root, cache, m, mod.Sums{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "vendor")); !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+}
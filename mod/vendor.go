@@ -0,0 +1,90 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mod
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	VendorDir  = "vendor"      // 模块根目录中存放依赖副本的目录
+	VendorList = "modules.txt" // vendor 目录中记录依赖版本的文件, 每行 "path version"
+)
+
+// ReadVendor 返回模块 root 的 vendor/modules.txt 中的依赖路径到版本, 文件不存在时返回 nil.
+func ReadVendor(root string) (map[string]string, error) {
+	src, err := ioutil.ReadFile(filepath.Join(root, VendorDir, VendorList))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	list := map[string]string{}
+	for _, line := range strings.Split(string(src), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			list[fields[0]] = fields[1]
+		}
+	}
+	return list, nil
+}
+
+// Vendor 下载 m 的全部依赖到缓存 cache 并按 sums 校验, 然后重建模块 root 的 vendor 目录:
+// 每个依赖复制到 vendor/path, 版本写入 vendor/modules.txt. 没有依赖时删除 vendor 目录.
+func Vendor(root, cache string, m *Manifest, sums Sums) error {
+	dirs := make([]string, len(m.Require))
+	for i, r := range m.Require {
+		dir, err := Download(cache, r, sums)
+		if err != nil {
+			return err
+		}
+		dirs[i] = dir
+	}
+
+	vendor := filepath.Join(root, VendorDir)
+	if err := os.RemoveAll(vendor); err != nil {
+		return err
+	}
+	if len(m.Require) == 0 {
+		return nil
+	}
+	var list bytes.Buffer
+	for i, r := range m.Require {
+		if err := copyDir(filepath.Join(vendor, filepath.FromSlash(r.Path)), dirs[i]); err != nil {
+			return err
+		}
+		list.WriteString(r.Path + " " + r.Version + "\n")
+	}
+	return ioutil.WriteFile(filepath.Join(vendor, VendorList), list.Bytes(), 0644)
+}
+
+// copyDir 把目录 src 中的文件复制到 dst, 不复制符号链接等特殊文件
+func copyDir(dst, src string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(target, data, 0644)
+	})
+}
@@ -0,0 +1,117 @@
+package parser_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+// corpus 是有代表性的源码, 包含占位, 注释, 插值字符串和成组声明
+var corpus = []byte("--- 顶层占位 ---\nuse \"os\"\n" + strings.Repeat(`
+// 分组声明
+
+var (
+	string name = "hello {who}!"
+	int count = 0x1F // 尾注释
+	float ratio = 0.0015
+	datetime start = 20160202T22:48:33Z
+)
+
+func int add(int a, int b) {
+	out a + b
+}
+
+proc main() {
+	var s = 'it' + `+"`raw`"+`
+	count += add(count, 1)
+}
+`, 100))
+
+func TestShared(t *testing.T) {
+	for _, src := range append(seeds, string(corpus)) {
+		want, err := parser.Fast([]byte(src), nil)
+		got, e := parser.FastShared([]byte(src), nil)
+		if (err == nil) != (e == nil) || !reflect.DeepEqual(got, want) {
+			t.Fatalf("%q", src)
+		}
+
+		a, err := (&parser.Config{}).Parse([]byte(src))
+		b, e := (&parser.Config{Shared: true}).Parse([]byte(src))
+		if (err == nil) != (e == nil) || a.Len() != b.Len() {
+			t.Fatalf("%q", src)
+		}
+		for i := range a.Nodes {
+			if a.Nodes[i].Token() != b.Nodes[i].Token() || a.Nodes[i].Text() != b.Nodes[i].Text() {
+				t.Fatalf("%q %d", src, i)
+			}
+		}
+	}
+}
+
+func BenchmarkFast(b *testing.B) {
+	b.SetBytes(int64(len(corpus)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parser.Fast(corpus, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkFastShared(b *testing.B) {
+	b.SetBytes(int64(len(corpus)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parser.FastShared(corpus, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.SetBytes(int64(len(corpus)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := parser.Parse(corpus, ast.NewFile()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseArena(b *testing.B) {
+	c := &parser.Config{Mode: parser.ParseComments | parser.ParsePlaceholders, Arena: true}
+	b.SetBytes(int64(len(corpus)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		file, err := c.Parse(corpus)
+		if err != nil {
+			b.Fatal(err)
+		}
+		file.Release()
+	}
+}
+
+func BenchmarkParseSyntax(b *testing.B) {
+	src := []byte(strings.Repeat(syntaxSrc, 100))
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := parser.ParseSyntax(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseShared(b *testing.B) {
+	c := &parser.Config{Mode: parser.ParseComments | parser.ParsePlaceholders, Shared: true}
+	b.SetBytes(int64(len(corpus)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Parse(corpus); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"strconv"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Mode 控制 Config.Parse 保留哪些非语义节点
+type Mode uint
+
+const (
+	ParseComments     Mode = 1 << iota // 保留 COMMENT, COMMENTS 节点
+	ParsePlaceholders                  // 保留首个声明之前等顶层占位节点
+)
+
+// Config 配置解析过程. 格式化工具需要注释和占位, 编译器不需要.
+type Config struct {
+	Mode Mode
+
+	// MaxErrors 是停止解析前收集的错误数量, 0 和 1 表示遇到首个错误即停止.
+	// 非法的字面值被当做 PLACEHOLDER 继续解析, 混搭的缩进被合并,
+	// ast 拒绝的 Token 总是立即停止解析.
+	MaxErrors int
+
+	// TabWidth 大于 0 时允许 SPACES, TABS 混搭缩进, 一个 TAB 相当于 TabWidth 个空格.
+	TabWidth int
+
+	// Version 是项目默认的语言版本, 文件头部的版本指示优先, 参见 HeaderVersion.
+	Version string
+
+	// Trace 非 nil 时接收解析过程中的每个决定, 用于调试语法.
+	Trace func(TraceEvent)
+
+	// Shared 为 true 时节点源码直接引用 src 的内存, 参见 scanner.NewShared.
+	// 在 File 的使用期间不得修改 src.
+	Shared bool
+
+	// Lenient 为 true 时先用 RepairUTF8 修复非法的 UTF-8 编码再解析, 每段被替换的编码
+	// 作为错误排在 ErrorList 的前面, 不计入 MaxErrors. 此时节点和其它错误的位置
+	// 属于修复后的源码. 适用于从其它编码粘贴而来的文件.
+	Lenient bool
+
+	// Arena 为 true 时 Parse 返回的 File 的节点从池中的 ast.Arena 分配,
+	// 文件不再使用时调用 File.Release 复用它们的内存, 参见 ast.NewArenaFile.
+	Arena bool
+
+	// Target 非 nil 时 ParseDir 跳过 zxx:build 构建约束对它不成立的文件, 参见 HeaderBuild.
+	Target *Target
+}
+
+// defaultConfig 是 Parse 使用的配置
+var defaultConfig = &Config{Mode: ParseComments | ParsePlaceholders}
+
+// Parse 按配置 c 解析 zxx 源码 src.
+// 如果 c.MaxErrors 大于 1 或者 c.Lenient 修复了编码, 错误的类型是 ErrorList.
+// 返回的 File.Version 是文件头部指示的语言版本或者 c.Version,
+// 使用了该版本不支持的语法时返回 CheckVersion 的错误.
+func (c *Config) Parse(src []byte) (*ast.File, error) {
+	file := ast.NewFile()
+	if c.Arena {
+		file = ast.NewArenaFile()
+	}
+	err := c.parse(src, file, file.Push)
+	return file, c.version(file, src, err)
+}
+
+// TraceKind 是 TraceEvent 的种类
+type TraceKind int
+
+const (
+	TraceConsume TraceKind = iota // Token 被推送到 ast.File
+	TraceOpen                     // 容器节点成为活动节点
+	TraceClose                    // 容器节点结束
+	TraceRecover                  // 从错误中恢复并继续解析
+)
+
+var traceKinds = [...]string{"consume", "open", "close", "recover"}
+
+func (k TraceKind) String() string {
+	if k >= 0 && int(k) < len(traceKinds) {
+		return traceKinds[k]
+	}
+	return "TraceKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// TraceEvent 是解析过程中的一个决定.
+// Open, Close 的 Pos, Tok, Source 属于容器节点, Recover 的 Msg 是被恢复的错误.
+type TraceEvent struct {
+	Kind   TraceKind
+	Pos    scanner.Pos
+	Tok    token.Token
+	Source string
+	Depth  int // 事件之后活动节点的嵌套深度, File 为 0. Consume 是推送之前的深度
+	Msg    string
+}
+
+// trace 返回在 push 前后报告 TraceEvent 的 push 包装
+func (c *Config) trace(file *ast.File, push func(scanner.Pos, token.Token, string) error) func(scanner.Pos, token.Token, string) error {
+	return func(pos scanner.Pos, tok token.Token, code string) error {
+		before := file.Active
+		err := push(pos, tok, code)
+		after := file.Active
+		c.Trace(TraceEvent{Kind: TraceConsume, Pos: pos, Tok: tok, Source: code, Depth: depth(before)})
+		if after == before {
+			return err
+		}
+
+		// 从 before 向上关闭到共同祖先, 再从共同祖先向下打开到 after
+		var opened []ast.Node
+		for n := after; n != nil; n = n.Parent() {
+			if isAncestor(n, before) {
+				break
+			}
+			opened = append(opened, n)
+		}
+		common := after
+		if len(opened) != 0 {
+			common = opened[len(opened)-1].Parent()
+		}
+		for n := before; n != nil && n != common; n = n.Parent() {
+			c.Trace(event(TraceClose, n, depth(n)-1))
+		}
+		for i := len(opened) - 1; i >= 0; i-- {
+			c.Trace(event(TraceOpen, opened[i], depth(opened[i])))
+		}
+		return err
+	}
+}
+
+func event(kind TraceKind, n ast.Node, depth int) TraceEvent {
+	ev := TraceEvent{Kind: kind, Tok: n.Token(), Source: n.Text(), Depth: depth}
+	switch n := n.(type) {
+	case *ast.Decl:
+		ev.Pos = n.Pos
+	case *ast.Chunk:
+		ev.Pos = n.Pos
+	case *ast.Stmt:
+		ev.Pos = n.Pos
+	}
+	return ev
+}
+
+// isAncestor 返回 n 是否为 x 或者 x 的祖先
+func isAncestor(n, x ast.Node) bool {
+	for ; x != nil; x = x.Parent() {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
+
+// depth 返回 n 的嵌套深度, File 为 0
+func depth(n ast.Node) (d int) {
+	for n = n.Parent(); n != nil; n = n.Parent() {
+		d++
+	}
+	return
+}
+
+// ErrorList 是 Config.Parse 收集的多个错误
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return l[0].Error() + " (and " + strconv.Itoa(len(l)-1) + " more errors)"
+}
+
+// err 返回 l 作为 error 的值, max 不大于 1 时返回首个错误
+func (l ErrorList) err(max int) error {
+	switch {
+	case len(l) == 0:
+		return nil
+	case max <= 1:
+		return l[0]
+	}
+	return l
+}
@@ -0,0 +1,285 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func TestConfig(t *testing.T) {
+	src := []byte("note\nvar a = 1 // one\n--- block ---\nvar b = 2\n")
+
+	count := func(c *parser.Config, tok token.Token) int {
+		file, err := c.Parse(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, node := range file.Nodes {
+			if node.Token() == tok {
+				n++
+			}
+		}
+		return n
+	}
+
+	if n := count(&parser.Config{}, token.COMMENT); n != 0 {
+		t.Fatal("COMMENT", n)
+	}
+	if n := count(&parser.Config{}, token.PLACEHOLDER); n != 0 {
+		t.Fatal("PLACEHOLDER", n)
+	}
+	if n := count(&parser.Config{Mode: parser.ParseComments}, token.COMMENT); n != 1 {
+		t.Fatal("COMMENT", n)
+	}
+	if n := count(&parser.Config{Mode: parser.ParsePlaceholders}, token.PLACEHOLDER); n != 2 {
+		t.Fatal("PLACEHOLDER", n)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	src := []byte("var a = 0x1G\nvar b = 1.5e\nvar c = 12T99:99Z\n")
+
+	_, err := new(parser.Config).Parse(src)
+	if _, ok := err.(parser.ErrorList); err == nil || ok {
+		t.Fatal(err)
+	}
+
+	_, err = (&parser.Config{MaxErrors: 10}).Parse(src)
+	if list, ok := err.(parser.ErrorList); !ok || len(list) != 3 {
+		t.Fatal(err)
+	}
+
+	_, err = (&parser.Config{MaxErrors: 2}).Parse(src)
+	if list, ok := err.(parser.ErrorList); !ok || len(list) != 2 {
+		t.Fatal(err)
+	}
+}
+
+func TestUnclosedString(t *testing.T) {
+	// 缺少结尾引号的字符串只占用当前行, 之后的声明照常解析
+	src := []byte("var a = 'it // comment\nvar b = 1\nvar c = `x\nvar d = 2\n")
+	file, err := (&parser.Config{MaxErrors: 10}).Parse(src)
+	list, ok := err.(parser.ErrorList)
+	if !ok || len(list) != 2 || !strings.Contains(list[0].Error(), "offset 8, missing '") {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, n := range file.Nodes {
+		if n.Token() == token.IDENT {
+			names = append(names, n.Text())
+		}
+	}
+	if strings.Join(names, " ") != "a b c d" {
+		t.Fatal(names)
+	}
+
+	// 之后的行有引号时, 字符串在行尾结束, 错误位于开始的引号
+	for _, src := range []string{
+		"var a = 'it // comment\nvar b = 'x'\nvar c = 1\n",
+		"var a = \"it's\nvar b = \"x\"\nvar c = 1\n",
+		"var a = 'it\r\n\nvar b = 'x'\r\nvar c = 1\r\n",
+	} {
+		file, err := (&parser.Config{MaxErrors: 10}).Parse([]byte(src))
+		list, _ := err.(parser.ErrorList)
+		if len(list) != 1 {
+			t.Fatalf("%q: %v", src, err)
+		}
+		if e, ok := list[0].(*parser.Error); !ok || e.Pos != 8 || !strings.Contains(e.Msg, "offset 8, missing "+src[8:9]) {
+			t.Fatalf("%q: %v", src, err)
+		}
+		var text []string
+		for _, n := range file.Nodes {
+			if tok := n.Token(); tok == token.IDENT || tok == token.VALSTRING {
+				text = append(text, n.Text())
+			}
+		}
+		if want := "a b " + src[8:9] + "x" + src[8:9] + " c"; strings.Join(text, " ") != want {
+			t.Fatalf("%q: %q", src, text)
+		}
+	}
+
+	// 后续行缩进更深的跨行字符串照常解析
+	for _, src := range []string{"var a = 'x\n\ty'\nvar b = 1\n", "var (\n\ta = 'x\n\n\t\ty'\n)\n", "var a = `x\ny`\n"} {
+		if _, err := new(parser.Config).Parse([]byte(src)); err != nil {
+			t.Fatalf("%q: %v", src, err)
+		}
+	}
+}
+
+func TestLenient(t *testing.T) {
+	src := []byte("var a = '\xe4\xb8'\nvar b = \"x\xff\"\n// \xfe\nvar c = 1\n")
+	if _, err := new(parser.Config).Parse(src); err == nil {
+		t.Fatal("invalid UTF-8")
+	}
+
+	file, err := (&parser.Config{Lenient: true}).Parse(src)
+	list, ok := err.(parser.ErrorList)
+	if !ok || len(list) != 3 {
+		t.Fatal(err)
+	}
+	if e := list[0].(*parser.Error); e.Pos != 9 || !strings.Contains(e.Msg, `"\xe4\xb8" at offset 9`) {
+		t.Fatal(e)
+	}
+	if e := list[1].(*parser.Error); e.Pos != 24 || !strings.Contains(e.Msg, "offset 23") {
+		t.Fatal(e)
+	}
+
+	var texts []string
+	for _, n := range file.Nodes {
+		if tok := n.Token(); tok == token.IDENT || tok == token.VALSTRING {
+			texts = append(texts, n.Text())
+		}
+	}
+	if strings.Join(texts, " ") != "a '\uFFFD' b \"x\uFFFD\" c" {
+		t.Fatalf("%q", texts)
+	}
+
+	// 合法的源码不被复制
+	if got, errs := parser.RepairUTF8(src[:8]); &got[0] != &src[0] || errs != nil {
+		t.Fatal(errs)
+	}
+}
+
+func TestConfigTabWidth(t *testing.T) {
+	src := []byte("var (\n\ta = 1\n    b = 2\n)\n")
+	if _, err := new(parser.Config).Parse(src); err == nil {
+		t.Fatal("mixed indentation")
+	}
+	if _, err := (&parser.Config{TabWidth: 4}).Parse(src); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	for _, s := range []struct {
+		src, version string
+		ok           bool
+	}{
+		{"note\n// c\nuse \"-version=2\"\nvar a = 1\n", "2", true},
+		{"use \"-version=2\"", "2", true},
+		{"var a = 1\nuse \"-version=2\"\n", "1", false},
+		{"use \"-include=a.zxx\"\n", "1", false},
+		{"", "1", false},
+	} {
+		version, ok, err := parser.HeaderVersion([]byte(s.src))
+		if err != nil || ok != s.ok || ok && version != s.version {
+			t.Fatal(s.src, version, ok, err)
+		}
+		file, err := (&parser.Config{Version: "1"}).Parse([]byte(s.src))
+		if err != nil || file.Version != s.version {
+			t.Fatal(s.src, file.Version, err)
+		}
+	}
+
+	src := []byte("use \"-version=\"\n")
+	if _, _, err := parser.HeaderVersion(src); err == nil {
+		t.Fatal("want error")
+	}
+	if _, err := new(parser.Config).Parse(src); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	src := []byte("type Shape enum [\n\tEmpty\n]\n\nfunc first[T](list[T] l) out T [\n\tout l[0]\n]\n\nvar s = \"a {1} b\"\n")
+	err := parser.CheckVersion(src, "1")
+	list, ok := err.(parser.ErrorList)
+	if !ok || len(list) != 3 {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, err := range list {
+		e := err.(*parser.Error)
+		got = append(got, string(src[e.Pos])+" "+e.Msg)
+	}
+	want := []string{
+		"e parser: enum types requires language version 2, file uses version 1",
+		"[ parser: type parameters requires language version 2, file uses version 1",
+		"{ parser: string interpolation requires language version 2, file uses version 1",
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatal(got)
+		}
+	}
+	for _, v := range []string{"", "2"} {
+		if err := parser.CheckVersion(src, v); err != nil {
+			t.Fatal(v, err)
+		}
+	}
+	if err := parser.CheckVersion(src, "3"); err == nil {
+		t.Fatal("want error")
+	}
+
+	// 文件头部的版本指示优先于 Config.Version
+	if _, err := (&parser.Config{Version: "1"}).Parse([]byte("var s = \"{1}\"\n")); err == nil {
+		t.Fatal("want error")
+	}
+	if _, err := (&parser.Config{Version: "1"}).Parse([]byte("use \"-version=2\"\nvar s = \"{1}\"\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&parser.Config{Version: "x"}).Parse([]byte("var a = 1\n")); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+// TestVersionGate 检查同一段源码在当前版本可以解析, 在版本指示为 1 时是错误
+func TestVersionGate(t *testing.T) {
+	for _, src := range []string{
+		"var s = \"x {a+1} y\"\n",
+		"type Shape enum [\n\tEmpty\n]\n",
+		"func first[T](list[T] l) out T [\n\tout l[0]\n]\n",
+	} {
+		for _, head := range []string{"", "use \"-version=2\"\n"} {
+			if _, err := new(parser.Config).Parse([]byte(head + src)); err != nil {
+				t.Fatal(head+src, err)
+			}
+			if _, err := parser.ParseSyntax([]byte(head + src)); err != nil {
+				t.Fatal(head+src, err)
+			}
+		}
+		_, err := new(parser.Config).Parse([]byte("use \"-version=1\"\n" + src))
+		if err == nil || !strings.Contains(err.Error(), "requires language version 2, file uses version 1") {
+			t.Fatal(src, err)
+		}
+	}
+
+	file, err := parser.ParseSyntax([]byte("var s = \"x {a+1} y\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := file.Decls[0].(*ast.GenDecl).Specs[0].Values[0].(*ast.InterpExpr); !ok {
+		t.Fatalf("%#v", file.Decls[0])
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var events []string
+	c := &parser.Config{MaxErrors: 10, Trace: func(ev parser.TraceEvent) {
+		s := ev.Kind.String() + " " + ev.Tok.String()
+		if ev.Kind == parser.TraceRecover {
+			s = ev.Kind.String()
+		}
+		events = append(events, s)
+	}}
+	if _, err := c.Parse([]byte("var a = (0x1G)\n")); err == nil {
+		t.Fatal("want error")
+	}
+	want := []string{
+		"consume var", "open var",
+		"consume IDENT",
+		"consume =",
+		"consume LEFT", "open LEFT",
+		"recover", "consume PLACEHOLDER",
+		"consume RIGHT", "close LEFT",
+		"consume NEWLINE", "close var",
+	}
+	if strings.Join(events, ", ") != strings.Join(want, ", ") {
+		t.Fatal(events)
+	}
+}
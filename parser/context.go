@@ -0,0 +1,81 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"context"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// checkEvery 是两次检查 ctx.Err 之间的 Token 数
+const checkEvery = 256
+
+// canceler 每 checkEvery 个 Token 检查一次 ctx, 取消后 push 返回 ctx.Err.
+type canceler struct {
+	ctx context.Context
+	n   int
+	err error // 取消的原因
+}
+
+func (c *canceler) wrap(push func(scanner.Pos, token.Token, string) error) func(scanner.Pos, token.Token, string) error {
+	return func(pos scanner.Pos, tok token.Token, code string) error {
+		if c.n++; c.n%checkEvery == 0 {
+			if c.err = c.ctx.Err(); c.err != nil {
+				return c.err
+			}
+		}
+		return push(pos, tok, code)
+	}
+}
+
+// ParseContext 同 Parse, 但在 ctx 被取消或超时后尽快停止解析并返回 ctx.Err(),
+// 此时 file 只包含部分节点. 适用于编辑器在新的修改到来时放弃过时的解析.
+func ParseContext(ctx context.Context, src []byte, file *ast.File) error {
+	return defaultConfig.parseContext(ctx, src, file)
+}
+
+// ParseContext 同 c.Parse, 取消时的行为参见 ParseContext.
+func (c *Config) ParseContext(ctx context.Context, src []byte) (*ast.File, error) {
+	file := ast.NewFile()
+	err := c.parseContext(ctx, src, file)
+	return file, c.version(file, src, err)
+}
+
+func (c *Config) parseContext(ctx context.Context, src []byte, file *ast.File) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cc := &canceler{ctx: ctx}
+	err := c.parse(src, file, cc.wrap(file.Push))
+	if cc.err != nil {
+		return cc.err
+	}
+	return err
+}
+
+// FastContext 同 Fast, 但在 ctx 被取消或超时后尽快停止并返回 ctx.Err().
+func FastContext(ctx context.Context, src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []Symbol, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if cb == nil {
+		// 与 Fast 相同, 结果不包括 EOF
+		nodes = make([]Symbol, 0, len(src)/bytesPerSymbol)
+		cb = func(pos scanner.Pos, tok token.Token, code string) error {
+			if tok != token.EOF {
+				nodes = append(nodes, Symbol{Pos: pos, Tok: tok, Source: code})
+			}
+			return nil
+		}
+	}
+	cc := &canceler{ctx: ctx}
+	if _, err = fast(src, cc.wrap(cb), true, false, false); cc.err != nil {
+		err = cc.err
+	}
+	return
+}
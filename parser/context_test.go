@@ -0,0 +1,59 @@
+package parser_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+// lateContext 在 Err 被调用 n 次之后被取消
+type lateContext struct {
+	context.Context
+	n int
+}
+
+func (c *lateContext) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestParseContext(t *testing.T) {
+	src := []byte(strings.Repeat("var x = 1 + 2\n", 1000))
+
+	file := ast.NewFile()
+	if err := parser.ParseContext(context.Background(), src, file); err != nil {
+		t.Fatal(err)
+	}
+	want := file.Len()
+
+	file = ast.NewFile()
+	err := parser.ParseContext(&lateContext{context.Background(), 2}, src, file)
+	if err != context.Canceled || file.Len() == 1 || file.Len() >= want {
+		t.Fatal(err, file.Len(), want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (&parser.Config{}).ParseContext(ctx, src); err != context.Canceled {
+		t.Fatal(err)
+	}
+
+	syms, err := parser.FastContext(context.Background(), src, nil)
+	fast, _ := parser.Fast(src, nil)
+	if err != nil || len(syms) != len(fast) {
+		t.Fatal(err, len(syms), len(fast))
+	}
+	for i := range fast {
+		if syms[i] != fast[i] {
+			t.Fatal(i, syms[i], fast[i])
+		}
+	}
+	if _, err = parser.FastContext(&lateContext{context.Background(), 2}, src, nil); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
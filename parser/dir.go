@@ -0,0 +1,161 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ZxxLang/zxx/ast"
+)
+
+// ParseDir 按配置 cfg 并发解析目录 path 中全部 .zxx 文件, 不包括子目录.
+// 每个文件的解析错误保存在 Package.Errors 中, cfg.Target 排除的文件不在结果中.
+// 返回的 error 只表示读取目录或者文件失败.
+//
+// 文件路径是 CleanPath 规范化的路径, 同一个目录无论经由哪个名字访问, 结果都相同.
+// 指向同一文件的多个名字, 例如符号链接, 硬链接, 只解析一次, 保留按名字排序的第一个真实文件.
+func ParseDir(path string, cfg Config) (*ast.Package, error) {
+	path, err := CleanPath(path)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(filepath.FromSlash(path))
+	if err != nil {
+		return nil, err
+	}
+
+	// ReadDir 按名字排序, 真实文件优先于符号链接
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].Mode()&os.ModeSymlink == 0 && infos[j].Mode()&os.ModeSymlink != 0
+	})
+	var names []string
+	var seen []os.FileInfo
+	for _, info := range infos {
+		if !strings.HasSuffix(info.Name(), ".zxx") {
+			continue
+		}
+		name := path + "/" + info.Name()
+		if info.Mode()&os.ModeSymlink != 0 {
+			if info, err = os.Stat(filepath.FromSlash(name)); err != nil {
+				return nil, err
+			}
+		}
+		if info.IsDir() || same(seen, info) {
+			continue
+		}
+		seen = append(seen, info)
+		names = append(names, name)
+	}
+
+	pkg := &ast.Package{
+		Name:   filepath.Base(filepath.FromSlash(path)),
+		Files:  make(map[string]*ast.File, len(names)),
+		Errors: map[string]error{},
+	}
+
+	type result struct {
+		name string
+		file *ast.File
+		err  error
+		read bool // err 来自读取文件
+		skip bool // 构建约束对 cfg.Target 不成立
+	}
+
+	jobs := make(chan string)
+	results := make(chan result)
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(names) {
+		workers = len(names)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				r := result{name: name}
+				src, err := ioutil.ReadFile(filepath.FromSlash(name))
+				if err != nil {
+					r.err, r.read = err, true
+				} else {
+					c, cerr := HeaderBuild(src)
+					if r.skip = cerr == nil && cfg.Target != nil && !cfg.Target.Match(c); !r.skip {
+						r.file, r.err = cfg.Parse(src)
+						if r.err == nil {
+							r.err = cerr
+						}
+					}
+				}
+				results <- r
+			}
+		}()
+	}
+	go func() {
+		for _, name := range names {
+			jobs <- name
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	for r := range results {
+		switch {
+		case r.read:
+			if err == nil {
+				err = r.err
+			}
+			continue
+		case r.skip:
+			continue
+		case r.err != nil:
+			pkg.Errors[r.name] = r.err
+		}
+		pkg.Files[r.name] = r.file
+	}
+	return pkg, err
+}
+
+// CleanPath 返回 path 的规范形式: 绝对路径, 解析全部符号链接, 使用 '/' 分隔.
+// 用作缓存和去重的键时, 同一个文件不会因为不同的写法出现两次.
+// 不区分大小写的文件系统上大小写不同的写法仍然不同, 此时使用 SamePath 判断.
+func CleanPath(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err == nil {
+		path, err = filepath.EvalSymlinks(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(path), nil
+}
+
+// SamePath 返回 a, b 是否是同一个文件或目录, 包括符号链接, 硬链接,
+// 以及不区分大小写的文件系统上大小写不同的名字. 无法访问时返回 false.
+func SamePath(a, b string) bool {
+	x, err := os.Stat(filepath.FromSlash(a))
+	if err != nil {
+		return false
+	}
+	y, err := os.Stat(filepath.FromSlash(b))
+	return err == nil && os.SameFile(x, y)
+}
+
+// same 返回 info 是否和 seen 中的某个文件相同
+func same(seen []os.FileInfo, info os.FileInfo) bool {
+	for _, x := range seen {
+		if os.SameFile(x, info) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package parser_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestParseDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"a.zxx":   "var a = 1\n",
+		"b.zxx":   "var b = 0x1G\nvar c = 1.5e\n",
+		"c.txt":   "not zxx",
+		"sub.zxx": "",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pkg, err := parser.ParseDir(dir, parser.Config{MaxErrors: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := pkg.Names()
+	if len(names) != 3 || filepath.Base(names[0]) != "a.zxx" {
+		t.Fatal(names)
+	}
+	if len(pkg.Errors) != 1 {
+		t.Fatal(pkg.Errors)
+	}
+	list, ok := pkg.Errors[filepath.Join(dir, "b.zxx")].(parser.ErrorList)
+	if !ok || len(list) != 2 {
+		t.Fatal(pkg.Errors)
+	}
+
+	if _, err = parser.ParseDir(filepath.Join(dir, "none"), parser.Config{}); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestParseDirLinks(t *testing.T) {
+	dir := t.TempDir()
+	real := filepath.Join(dir, "real")
+	if err := os.Mkdir(real, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(real, "b.zxx"), []byte("var b = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for old, name := range map[string]string{
+		real:                         filepath.Join(dir, "link"),
+		filepath.Join(real, "b.zxx"): filepath.Join(real, "a.zxx"),
+	} {
+		if err := os.Symlink(old, name); err != nil {
+			t.Skip(err)
+		}
+	}
+
+	pkg, err := parser.ParseDir(filepath.Join(dir, "link", "."), parser.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clean, _ := parser.CleanPath(real)
+	names := pkg.Names()
+	if len(names) != 1 || names[0] != clean+"/b.zxx" || pkg.Name != "real" {
+		t.Fatal(pkg.Name, names)
+	}
+	if !parser.SamePath(filepath.Join(dir, "link", "a.zxx"), filepath.Join(real, "b.zxx")) ||
+		parser.SamePath(real, filepath.Join(real, "b.zxx")) {
+		t.Fatal("SamePath")
+	}
+}
+
+func TestParseDirTarget(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"a.zxx":       "var a = 1\n",
+		"b_linux.zxx": "// zxx:build linux && !arm64\nvar b = 1\n",
+		"b_other.zxx": "// zxx:build !linux || arm64\nvar b = 2\n",
+		"c.zxx":       "// zxx:build linux &&\nvar c = 1\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	target, err := parser.ParseTarget("linux/amd64,netgo")
+	if err != nil || target.String() != "linux/amd64,netgo" || !target.Tag("netgo") || target.Tag("arm64") {
+		t.Fatal(target, err)
+	}
+	pkg, err := parser.ParseDir(dir, parser.Config{Target: target})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, name := range pkg.Names() {
+		names = append(names, filepath.Base(name))
+	}
+	if strings.Join(names, " ") != "a.zxx b_linux.zxx c.zxx" || len(pkg.Errors) != 1 {
+		t.Fatal(names, pkg.Errors)
+	}
+	for _, s := range []string{"linux", "/amd64", "a/b/c"} {
+		if _, err := parser.ParseTarget(s); err == nil {
+			t.Errorf("%s: no error", s)
+		}
+	}
+}
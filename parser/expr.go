@@ -0,0 +1,360 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// ParseExpr 把单个表达式 src 解析为结构化的表达式树.
+// 运算符优先级来自 token.UnaryPrecedence, token.BinaryPrecedence, 例如
+//
+//	a + b * c   即 a + (b * c)
+//	not a == b  即 not (a == b)
+//	-f(x)[0]    即 -((f(x))[0])
+func ParseExpr(src []byte) (ast.Expression, error) {
+	syms, err := FastExpr(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ParseExprSymbols(syms)
+}
+
+// ParseExprSymbols 把 syms 解析为一个表达式, syms 通常来自 FastExpr 或者 ast.ToSymbols.
+// 换行, 缩进, 注释等非语义的 Symbol 被忽略, 多余的 Symbol 是错误.
+func ParseExprSymbols(syms []Symbol) (ast.Expression, error) {
+	return parseExpr(syms, false)
+}
+
+// parseExpr 把 syms 解析为一个表达式. command 为 true 时 syms 是命令调用
+// Fun Arg, Arg..., 参数之间可以省略逗号, 例如 echo 'hello ' word.
+func parseExpr(syms []Symbol, command bool) (ast.Expression, error) {
+	buf := exprSyms.Get().(*[]Symbol)
+	p := &exprParser{syms: (*buf)[:0]}
+	defer func() {
+		for i := range p.syms {
+			p.syms[i] = Symbol{}
+		}
+		*buf = p.syms[:0]
+		exprSyms.Put(buf)
+	}()
+	for _, sym := range syms {
+		if sym.Tok == token.NL {
+			p.breaks = append(p.breaks, len(p.syms))
+		} else if sym.Tok != token.EOF && (sym.Tok == token.PLACEHOLDER || !sym.Tok.Is(token.ClassTrivia)) {
+			p.syms = append(p.syms, sym)
+		}
+	}
+	if n := len(syms); n != 0 {
+		p.end = syms[n-1].Pos.Offset(len(syms[n-1].Source))
+	}
+
+	var x ast.Expression
+	var err error
+	if command {
+		x, err = p.command()
+	} else {
+		x, err = p.expr(0)
+	}
+	if err == nil && p.peek().Tok != token.EOF {
+		err = p.unexpected(p.peek())
+	}
+	return x, err
+}
+
+// exprSyms 复用 ParseExprSymbols 过滤后的 Symbol, 表达式树不引用它们.
+// 语句中的每个表达式都单独解析, 复用避免了每次的分配.
+var exprSyms = sync.Pool{New: func() interface{} { return new([]Symbol) }}
+
+// exprParser 是优先级爬升的表达式解析器
+type exprParser struct {
+	syms   []Symbol
+	i      int
+	end    scanner.Pos // 最后一个 Symbol 之后的位置, 即 EOF 的位置
+	breaks []int       // 之前有换行的 Symbol 的序号
+}
+
+func (p *exprParser) peek() Symbol {
+	if p.i < len(p.syms) {
+		return p.syms[p.i]
+	}
+	return Symbol{Pos: p.end, Tok: token.EOF}
+}
+
+func (p *exprParser) next() Symbol {
+	sym := p.peek()
+	if p.i < len(p.syms) {
+		p.i++
+	}
+	return sym
+}
+
+func (p *exprParser) unexpected(sym Symbol) error {
+	if sym.Tok == token.EOF {
+		return &Error{Pos: sym.Pos, Msg: "parser: unexpected EOF at offset " + strconv.Itoa(int(sym.Pos))}
+	}
+	return &Error{Pos: sym.Pos, Msg: "parser: unexpected " + sym.Tok.String() + " '" + sym.Source + "' at offset " + strconv.Itoa(int(sym.Pos))}
+}
+
+// newline 返回当前 Symbol 之前是否有换行
+func (p *exprParser) newline() bool {
+	for _, i := range p.breaks {
+		if i == p.i {
+			return true
+		}
+	}
+	return false
+}
+
+// expect 消费源码为 right 的 Symbol
+func (p *exprParser) expect(right string) (scanner.Pos, error) {
+	sym := p.next()
+	if sym.Source != right {
+		return sym.Pos, p.unexpected(sym)
+	}
+	return sym.Pos, nil
+}
+
+// expr 解析二元运算符优先级高于 prec 的表达式
+func (p *exprParser) expr(prec int) (ast.Expression, error) {
+	x, err := p.unary()
+	for err == nil {
+		op := p.peek()
+		prec2 := op.Tok.BinaryPrecedence()
+		if prec2 <= prec {
+			break
+		}
+		p.next()
+		var y ast.Expression
+		if y, err = p.expr(prec2); err == nil {
+			x = &ast.BinaryExpr{X: x, Op: op, Y: y}
+		}
+	}
+	return x, err
+}
+
+// command 解析命令调用 Fun Arg, Arg..., Fun 是名字或者成员, 参数之间的逗号可以省略
+func (p *exprParser) command() (ast.Expression, error) {
+	sym := p.next()
+	if sym.Tok != token.IDENT && sym.Tok != token.MEMBER {
+		return nil, p.unexpected(sym)
+	}
+	call := &ast.CallExpr{Fun: &ast.Ident{Name: sym}}
+	for p.peek().Tok != token.EOF {
+		x, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, x)
+		if p.peek().Tok == token.COMMA {
+			p.next()
+		}
+	}
+	return call, nil
+}
+
+func (p *exprParser) unary() (ast.Expression, error) {
+	op := p.peek()
+	if prec := op.Tok.UnaryPrecedence(); prec != 0 {
+		p.next()
+		var x ast.Expression
+		var err error
+		if prec == token.UnaryPrec {
+			x, err = p.unary()
+		} else {
+			x, err = p.expr(prec)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &ast.UnaryExpr{Op: op, X: x}, nil
+	}
+
+	x, err := p.primary()
+	// 紧随的 '(' 是调用, '[' 是下标
+	for err == nil {
+		left, prev := p.peek(), p.syms[p.i-1]
+		if left.Tok != token.LEFT || left.Pos != prev.Pos.Offset(len(prev.Source)) {
+			break
+		}
+		switch left.Source {
+		case "(":
+			p.next()
+			call := &ast.CallExpr{Fun: x, Lparen: left.Pos}
+			if call.Args, err = p.items(")"); err == nil {
+				call.Rparen = p.syms[p.i-1].Pos
+			}
+			x = call
+		case "[":
+			p.next()
+			index := &ast.IndexExpr{X: x, Lbrack: left.Pos}
+			if index.Index, err = p.expr(0); err == nil {
+				index.Rbrack, err = p.expect("]")
+			}
+			x = index
+		default:
+			return x, err
+		}
+	}
+	return x, err
+}
+
+func (p *exprParser) primary() (ast.Expression, error) {
+	sym := p.next()
+	switch sym.Tok {
+	case token.NULL, token.VALSTRING, token.VALINTEGER, token.VALFLOAT, token.VALBOOL, token.VALDATETIME:
+		return &ast.BasicLit{Value: sym}, nil
+	case token.TRUE, token.FALSE:
+		sym.Tok = token.VALBOOL
+		return &ast.BasicLit{Value: sym}, nil
+	case token.NAN, token.INFINITE:
+		sym.Tok = token.VALFLOAT
+		return &ast.BasicLit{Value: sym}, nil
+	case token.STRINGLIT:
+		return p.interp(sym)
+	case token.IDENT, token.MEMBER, token.MEMBERS:
+		return &ast.Ident{Name: sym}, nil
+	case token.LEFT:
+		if sym.Source == "{" {
+			return p.mapExpr(sym)
+		}
+		switch sym.Source {
+		case "(":
+			x, err := p.expr(0)
+			if err != nil {
+				return nil, err
+			}
+			paren := &ast.ParenExpr{Lparen: sym.Pos, X: x}
+			paren.Rparen, err = p.expect(")")
+			return paren, err
+		case "[":
+			if p.isMap() {
+				return p.mapExpr(sym)
+			}
+			list := &ast.ListExpr{Lbrack: sym.Pos}
+			var err error
+			if list.Elems, err = p.items("]"); err == nil {
+				list.Rbrack = p.syms[p.i-1].Pos
+			}
+			return list, err
+		}
+	}
+	// 类型也是值, 例如映射 [string: 'string'] 的键
+	if sym.Tok.As(token.Type) {
+		return &ast.Ident{Name: sym}, nil
+	}
+	return nil, p.unexpected(sym)
+}
+
+// interp 解析首个片段 first 之后的插值表达式和片段, 直到其后不再是 INTERPBEGIN 的片段
+func (p *exprParser) interp(first Symbol) (ast.Expression, error) {
+	x := &ast.InterpExpr{Parts: []ast.Expression{&ast.BasicLit{Value: first}}}
+	for {
+		if sym := p.next(); sym.Tok != token.INTERPBEGIN {
+			return nil, p.unexpected(sym)
+		}
+		e, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		if sym := p.next(); sym.Tok != token.INTERPEND {
+			return nil, p.unexpected(sym)
+		}
+		text := p.next()
+		if text.Tok != token.STRINGLIT {
+			return nil, p.unexpected(text)
+		}
+		x.Parts = append(x.Parts, e, &ast.BasicLit{Value: text})
+		if p.peek().Tok != token.INTERPBEGIN {
+			return x, nil
+		}
+	}
+}
+
+// isMap 返回 '[' 之后是否是映射, 即 [:] 或者首个元素之后是 ':'
+func (p *exprParser) isMap() bool {
+	if p.peek().Tok == token.COLON {
+		return true
+	}
+	depth := 0
+	for _, sym := range p.syms[p.i:] {
+		switch sym.Tok {
+		case token.LEFT:
+			depth++
+		case token.RIGHT:
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case token.COMMA:
+			if depth == 0 {
+				return false
+			}
+		case token.COLON:
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mapExpr 解析 '[' 或者 '{' 之后的 k: v, ... 直到对应的右括号.
+// 换行之后的元素可以省略之前的逗号, 空映射是 [:] 或者 {}.
+func (p *exprParser) mapExpr(left Symbol) (ast.Expression, error) {
+	m := &ast.MapExpr{Lbrack: left.Pos}
+	right := closer(left.Source)
+	if left.Source == "[" && p.peek().Tok == token.COLON {
+		p.next()
+		var err error
+		m.Rbrack, err = p.expect("]")
+		return m, err
+	}
+	for p.peek().Source != right {
+		key, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		kv := &ast.KeyValueExpr{Key: key}
+		if kv.Colon, err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		if kv.Value, err = p.expr(0); err != nil {
+			return nil, err
+		}
+		m.Elems = append(m.Elems, kv)
+		if p.peek().Tok == token.COMMA {
+			p.next()
+		} else if !p.newline() {
+			break
+		}
+	}
+	var err error
+	m.Rbrack, err = p.expect(right)
+	return m, err
+}
+
+// items 解析逗号分隔的表达式直到 right, 并消费 right. 换行之后的元素可以省略之前的逗号.
+func (p *exprParser) items(right string) (items []ast.Expression, err error) {
+	for p.peek().Source != right {
+		var x ast.Expression
+		if x, err = p.expr(0); err != nil {
+			return
+		}
+		items = append(items, x)
+		if p.peek().Tok == token.COMMA {
+			p.next()
+		} else if !p.newline() {
+			break
+		}
+	}
+	_, err = p.expect(right)
+	return
+}
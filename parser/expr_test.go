@@ -0,0 +1,129 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+// paren 以完全加括号的形式输出表达式树
+func paren(x ast.Expression) string {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		return x.Value.Source
+	case *ast.Ident:
+		return x.Name.Source
+	case *ast.ParenExpr:
+		return paren(x.X)
+	case *ast.ListExpr:
+		return "[" + list(x.Elems) + "]"
+	case *ast.MapExpr:
+		if len(x.Elems) == 0 {
+			return "[:]"
+		}
+		var ss []string
+		for _, kv := range x.Elems {
+			ss = append(ss, paren(kv.Key)+": "+paren(kv.Value))
+		}
+		return "[" + strings.Join(ss, ", ") + "]"
+	case *ast.InterpExpr:
+		var s string
+		for i, part := range x.Parts {
+			if i%2 == 0 {
+				s += paren(part)
+			} else {
+				s += "{" + paren(part) + "}"
+			}
+		}
+		return s
+	case *ast.UnaryExpr:
+		return "(" + x.Op.Source + " " + paren(x.X) + ")"
+	case *ast.BinaryExpr:
+		return "(" + paren(x.X) + " " + x.Op.Source + " " + paren(x.Y) + ")"
+	case *ast.CallExpr:
+		return paren(x.Fun) + "(" + list(x.Args) + ")"
+	case *ast.IndexExpr:
+		return paren(x.X) + "[" + paren(x.Index) + "]"
+	}
+	return "?"
+}
+
+func list(xs []ast.Expression) string {
+	var ss []string
+	for _, x := range xs {
+		ss = append(ss, paren(x))
+	}
+	return strings.Join(ss, ", ")
+}
+
+func TestParseExpr(t *testing.T) {
+	for src, want := range map[string]string{
+		"a + b * c":                 "(a + (b * c))",
+		"a * b + c":                 "((a * b) + c)",
+		"a - b - c":                 "((a - b) - c)",
+		"(a + b) * c":               "((a + b) * c)",
+		"not a == b and c":          "((not (a == b)) and c)",
+		"-f(x, 1)[0] mod 2":         "((- f(x, 1)[0]) mod 2)",
+		"a.b >= 18 or [1, 2] has x": "((a.b >= 18) or ([1, 2] has x))",
+		"f()":                       "f()",
+		"true":                      "true",
+		"['a': 1 + 2, b: [c]]":      "['a': (1 + 2), b: [c]]",
+		"['a':1, x:y]":              "['a': 1, x: y]",
+		"[:] has k":                 "([:] has k)",
+		"[[a, b]: f(x)][k]":         "[[a, b]: f(x)][k]",
+		`"x {a+1} y"`:               `"x {(a + 1)} y"`,
+		`"{a}{f(b)}" + c`:           `("{a}{f(b)}" + c)`,
+		"{'a': 1, b: 2}":            "['a': 1, b: 2]",
+		"{}":                        "[:]",
+		"['a': 1\n'b': 2\n]":        "['a': 1, 'b': 2]",
+		"[1,\n2\nf()]":              "[1, 2, f()]",
+		"[string: 'string']":        "[string: 'string']",
+	} {
+		x, err := parser.ParseExpr([]byte(src))
+		if err != nil {
+			t.Fatal(src, err)
+		}
+		if got := paren(x); got != want {
+			t.Fatal(src, got)
+		}
+		if x.Pos() != 0 || int(x.End()) != len(src) {
+			t.Fatal(src, x.Pos(), x.End())
+		}
+	}
+
+	for _, src := range []string{"", "a +", "(a", "f(a,", "a b", "a[1", "[a: 1, 2]", "[a: ]", "[:", `"{a"`, `"{}"`, "[1 2]", "{1}", "{:}", "{'a': 1 'b': 2}"} {
+		if _, err := parser.ParseExpr([]byte(src)); err == nil {
+			t.Fatal(src)
+		}
+	}
+}
+
+func TestParseInterp(t *testing.T) {
+	src := `"x {a+1} y"`
+	x, err := parser.ParseExpr([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, ok := x.(*ast.InterpExpr)
+	if !ok || len(in.Parts) != 3 {
+		t.Fatalf("%#v", x)
+	}
+	if _, ok := in.Parts[1].(*ast.BinaryExpr); !ok {
+		t.Fatalf("%#v", in.Parts[1])
+	}
+
+	// 片段是源码, 表达式写在 '{' '}' 之间, 依次连接即可还原源码
+	var out string
+	for i, part := range in.Parts {
+		if i%2 == 0 {
+			out += part.(*ast.BasicLit).Value.Source
+		} else {
+			out += "{" + src[part.Pos():part.End()] + "}"
+		}
+	}
+	if out != src {
+		t.Fatal(out)
+	}
+}
@@ -2,34 +2,74 @@ package parser
 
 import (
 	"errors"
+	"strconv"
 
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/lexutil"
 	"github.com/ZxxLang/zxx/scanner"
 	"github.com/ZxxLang/zxx/token"
 )
 
-// Symbol
-type Symbol struct {
-	Pos    scanner.Pos
-	Tok    token.Token
-	Source string
-}
+// Symbol 即 ast.Symbol, 可用 ast.FromSymbols 构建为 ast.File.
+type Symbol = ast.Symbol
+
+// bytesPerSymbol 是源码中平均每个 Token 的字节数, 用于预分配结果, 典型的源码约为 2 到 3.
+const bytesPerSymbol = 4
 
 // Fast 快速解析, 转换, 合并 zxx 源码 src 中的 Token.
 //
 // 参数 rec 用于逐个接收解析到的 Token, 包括 EOF.
 // 如果 rec 为 nil, 返回值 nodes 包含所有的 Token, 不包括 EOF.
 //
-// 缺陷:
-//
-// Fast 通过分析源码缩进判断顶层占位, 这可能对英文(非多字节)开始的顶层占位有影响.
-// 常规的缩进或用 '//', '---' 开始英文顶层占位可以弥补缺陷.
-//
+// 顶层占位按 Lexer 的规则识别: 所有括号之外没有缩进的行首开始占位, 直到某个行首是声明的保留字.
+// 与 Parse 不同, Fast 不知道声明是否结束, 声明跨行时后续的行应当缩进或者位于括号之中.
 func Fast(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []Symbol, err error) {
+	return fast(src, cb, true, false, false)
+}
+
+// FastExpr 和 Fast 相同, 但不识别顶层占位, 用于解析表达式等源码片段.
+func FastExpr(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []Symbol, err error) {
+	return fast(src, cb, false, false, false)
+}
+
+// FastShared 和 Fast 相同, 但 Symbol.Source 直接引用 src 的内存, 参见 scanner.NewShared.
+// 在结果的使用期间不得修改 src.
+func FastShared(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []Symbol, err error) {
+	return fast(src, cb, true, true, false)
+}
+
+// FastMixed 和 Fast 相同, 但允许 SPACES, TABS 混搭缩进, 一行的缩进合并为一个 INDENTATION.
+// 用于转换缩进风格等需要处理混搭缩进的工具.
+func FastMixed(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []Symbol, err error) {
+	return fast(src, cb, true, false, true)
+}
+
+// fast 是 Fast 的实现, isTop 表示是否识别顶层占位, shared 表示是否零拷贝,
+// mixed 表示是否允许混搭缩进.
+func fast(src []byte, cb func(scanner.Pos, token.Token, string) error, isTop, shared, mixed bool) (nodes []Symbol, err error) {
 	var eml, indent string
+	var emlPos scanner.Pos // eml 在源码中的开始位置
 	var delay, tok, prev token.Token
 
 	if cb == nil {
-		nodes = make([]Symbol, 0, len(src)/10)
+		nodes = make([]Symbol, 0, len(src)/bytesPerSymbol)
+	}
+
+	lex := newLexer(src, shared)
+	if !isTop {
+		lex.Top = func() bool { return false }
+	}
+
+	// more 追加位于 pos 的 code 到 eml, 与 eml 相邻时直接取源码
+	more := func(pos scanner.Pos, code string) {
+		switch {
+		case eml == "":
+			emlPos, eml = pos, code
+		case emlPos.Offset(len(eml)) == pos:
+			eml = lex.scan.Source(emlPos, pos.Offset(len(code)))
+		default:
+			eml += code
+		}
 	}
 
 	rec := func(pos scanner.Pos, tok token.Token, code string) (err error) {
@@ -41,7 +81,7 @@ func Fast(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []
 				break
 			}
 			if prev == tok {
-				eml += code
+				more(pos, code)
 				if delay == token.EOF {
 					delay = token.NL
 				} else {
@@ -51,7 +91,7 @@ func Fast(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []
 			}
 
 			if prev == token.INDENTATION {
-				eml += code
+				more(pos, code)
 				delay = token.PLACEHOLDER
 				return
 			}
@@ -59,10 +99,10 @@ func Fast(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []
 			break
 		case token.PLACEHOLDER, token.COMMENT:
 			if prev == token.INDENTATION {
-				eml += indent
+				more(pos.Offset(-len(indent)), indent)
 				indent = ""
 			}
-			eml += code
+			more(pos, code)
 			delay = token.PLACEHOLDER
 			return
 		case token.INDENTATION:
@@ -72,9 +112,9 @@ func Fast(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []
 
 		if eml != "" {
 			if cb == nil {
-				nodes = append(nodes, Symbol{pos.Offset(-len(eml)), token.PLACEHOLDER, eml})
+				nodes = append(nodes, Symbol{Pos: emlPos, Tok: token.PLACEHOLDER, Source: eml})
 			} else {
-				err = cb(pos.Offset(-len(eml)), token.PLACEHOLDER, eml)
+				err = cb(emlPos, token.PLACEHOLDER, eml)
 				if err != nil {
 					return
 				}
@@ -84,7 +124,7 @@ func Fast(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []
 
 		if prev == token.INDENTATION {
 			if cb == nil {
-				nodes = append(nodes, Symbol{pos.Offset(-len(indent)), token.INDENTATION, indent})
+				nodes = append(nodes, Symbol{Pos: pos.Offset(-len(indent)), Tok: token.INDENTATION, Source: indent})
 			} else {
 				err = cb(pos.Offset(-len(indent)), token.INDENTATION, indent)
 				if err != nil {
@@ -95,7 +135,7 @@ func Fast(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []
 		}
 
 		if cb == nil {
-			nodes = append(nodes, Symbol{pos, tok, code})
+			nodes = append(nodes, Symbol{Pos: pos, Tok: tok, Source: code})
 		} else {
 			err = cb(pos, tok, code)
 		}
@@ -103,60 +143,35 @@ func Fast(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []
 	}
 
 	tabKind := false
-	isTop := true
-	scan := scanner.New(src)
 
 	for err == nil {
-
-		pos := scan.Pos()
-		code, ok := scan.Symbol()
-		//fmt.Println(pos, code, token.Lookup(code))
-		if !ok {
-			err = errors.New("invalid UTF-8 encode")
-			return
-		}
-
+		var pos scanner.Pos
+		var code string
 		prev = tok
-		tok = token.Lookup(code)
-
-		if tok == token.EOF {
-			if nodes == nil {
-				err = rec(pos, tok, code)
-			}
+		if pos, tok, code, err = lex.next(); err != nil {
 			return
 		}
 
-		if isTop {
-			isTop = false
-			if !tok.As(token.Declare) {
-				var tmp string
-				posi := pos
-				for ok && tok != token.EOF && !tok.As(token.Declare) {
-					code += scan.Tail(true) + tmp
-					pos = scan.Pos()
-					tmp, ok = scan.Symbol()
-					tok = token.Lookup(tmp)
-				}
-
-				if !ok {
-					err = errors.New("invalid UTF-8 encode")
-					return
-				}
-				err = rec(posi, token.PLACEHOLDER, code)
-				code = tmp
-			}
-			if err == nil {
+		switch tok {
+		case token.EOF:
+			// 只有顶层占位时 nodes 包括占位和 EOF
+			if nodes == nil || prev == token.PLACEHOLDER && len(nodes) == 0 {
 				err = rec(pos, tok, code)
+			} else if eml != "" {
+				// 结尾的占位和注释
+				nodes = append(nodes, Symbol{Pos: emlPos, Tok: token.PLACEHOLDER, Source: eml})
 			}
-			continue
-		}
-
-		switch tok {
+			return
 
 		case token.SPACES:
+			if mixed && prev == token.INDENTATION {
+				// 合并混搭的缩进, prev 保持为 INDENTATION
+				indent, tok = lex.scan.Source(pos.Offset(-len(indent)), pos.Offset(len(code))), token.INDENTATION
+				continue
+			}
 			// 不支持 SPACES, TABS 混搭缩进
-			if prev == token.INDENTATION ||
-				tabKind && prev == token.NL {
+			if !mixed && (prev == token.INDENTATION ||
+				tabKind && prev == token.NL) {
 				err = errors.New("parser: bad indentation style for TABS + SPACES")
 				return
 			}
@@ -168,95 +183,29 @@ func Fast(src []byte, cb func(scanner.Pos, token.Token, string) error) (nodes []
 			continue
 
 		case token.TABS:
+			// Lexer 只在行首返回 TABS
+			if mixed && prev == token.INDENTATION {
+				indent, tok = lex.scan.Source(pos.Offset(-len(indent)), pos.Offset(len(code))), token.INDENTATION
+				continue
+			}
 			if prev == token.INDENTATION {
 				err = errors.New("parser: bad indentation style for SPACES + TABS")
 				return
 			}
-			if prev == token.NL {
-				tok = token.INDENTATION
-				tabKind = true
-			} else {
-				// TABS 尾注释
-				code += scan.Tail(false)
-				tok = token.COMMENT
-			}
-		case token.COMMENT:
-			err = rec(pos, tok, code+scan.Tail(false))
-			continue
-		case token.COMMENTS:
-			// 完整块注释
-			for {
-				tmp, _ := scan.Symbol()
-				code += tmp
-				tok = token.Lookup(tmp)
-				if tok == token.COMMENTS || tok == token.EOF {
-					break
-				}
-			}
-			if tok != token.COMMENTS {
-				err = errors.New("parser: COMMENTS is incomplete")
-				return
-			}
-			err = rec(pos, tok, code+scan.Tail(false))
-			continue
-		case token.TRUE, token.FALSE:
-			tok = token.VALBOOL
-		case token.NAN, token.INFINITE:
-			tok = token.VALFLOAT
-		case token.PLACEHOLDER:
-			// 识别语义, 只剩下字面值和标识符, 成员
-			if code == `"` || code == `'` {
-				// 完整字符串
-				code += scan.EndString(code == `"`)
-				if code[0] != code[len(code)-1] {
-					err = errors.New("parser: string is incomplete")
-					return
-				}
-				tok = token.VALSTRING
-				break
-			}
-			// 整数, 浮点数, datetime
-			// ??? 缺少严格检查
-			if code[0] >= '0' && code[0] <= '9' {
-				tok = token.VALINTEGER
-				if code[0] == '0' && len(code) > 2 && (code[1] == 'x' || code[1] == 'b') {
-				} else {
-					for _, c := range code {
-						if c == '.' || c == 'e' {
-							tok = token.VALFLOAT
-						} else if c == 'T' || c == ':' || c == 'Z' {
-							tok = token.VALDATETIME
-						} else if (c < '0' || c > '9') && c != '+' && c != '-' && c != '_' {
-							tok = token.PLACEHOLDER
-							break
-						}
-					}
-				}
-			} else {
-				// 标识符, 成员
-				tok = token.IDENT
-				dot := 0
-				for _, c := range code {
-					if c == '.' {
-						dot++
-						continue
-					}
-
-					if c != '_' && !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
-						tok = token.PLACEHOLDER
-						break
-					}
-				}
-				if dot != 0 && tok == token.IDENT {
-					if dot == 1 {
-						tok = token.MEMBER
-					} else {
-						tok = token.MEMBERS
-					}
-				}
-			}
+			tok = token.INDENTATION
+			tabKind = true
 		}
 		err = rec(pos, tok, code)
 	}
 	return
 }
+
+// classify 返回位于 pos 的符号 code 的 Token, 以数字开始的符号必须是合法的字面值.
+func classify(pos scanner.Pos, code string) (token.Token, error) {
+	tok, err := lexutil.Classify(code)
+	if err != nil {
+		e := err.(*lexutil.Error)
+		return tok, errors.New("parser: " + e.Msg + " at offset " + strconv.Itoa(int(pos)+e.Offset))
+	}
+	return tok, nil
+}
@@ -1,9 +1,12 @@
 package parser_test
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/ZxxLang/zxx/ast"
 	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
 )
 
 var good [][]string = [][]string{
@@ -23,6 +26,95 @@ var good [][]string = [][]string{
 		`use( a'b' )`,
 		`use`, `(`, `a`, `'b'`, `)`,
 	},
+	[]string{
+		"\nuse a",
+		"\n", `use`, `a`,
+	},
+	[]string{
+		`var now = 20160202T22:48:33`,
+		`var`, `now`, `=`, `20160202T22:48:33`,
+	},
+	[]string{
+		"var a = \"a\\\"b\" + `x\n\ty`",
+		`var`, `a`, `=`, `"a\"b"`, `+`, "`x\n\ty`",
+	},
+	[]string{
+		`var s = "hi {f({k: 1})}!\{x}"`,
+		`var`, `s`, `=`, `"hi `, `{`, `f`, `(`, `{`, `k`, `:`, `1`, `}`, `)`, `}`, `!\{x}"`,
+	},
+	[]string{
+		`var s = "\u{4e2d}{n}\u{41}"`,
+		`var`, `s`, `=`, `"\u{4e2d}`, `{`, `n`, `}`, `\u{41}"`,
+	},
+}
+
+func TestInterp(t *testing.T) {
+	src := []byte(`var s = "hello {name}, {a + 1}!"`)
+	nodes, err := parser.Fast(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []token.Token{
+		token.VAR, token.IDENT, token.ASSIGN,
+		token.STRINGLIT, token.INTERPBEGIN, token.IDENT, token.INTERPEND,
+		token.STRINGLIT, token.INTERPBEGIN, token.IDENT, token.PLUS, token.VALINTEGER, token.INTERPEND,
+		token.STRINGLIT,
+	}
+	if len(nodes) != len(want) {
+		t.Fatal(nodes)
+	}
+	for i, n := range nodes {
+		if n.Tok != want[i] {
+			t.Fatal(i, n)
+		}
+	}
+
+	// 插值表达式是 INTERPBEGIN 的子节点
+	file := ast.NewFile()
+	if err = parser.Parse(src, file); err != nil {
+		t.Fatal(err)
+	}
+	var begin ast.Node
+	for _, n := range file.Nodes {
+		switch n.Token() {
+		case token.INTERPBEGIN:
+			begin = n
+		case token.IDENT:
+			if n.Text() == "name" && n.Parent() != begin {
+				t.Fatal(n.Parent())
+			}
+		}
+	}
+	if begin == nil || begin.Kind(ast.FFinal) == 0 {
+		t.Fatal(begin)
+	}
+}
+
+func TestBadLiteral(t *testing.T) {
+	for _, src := range []string{
+		"var a = 0x1G",
+		"var a = 1.5e",
+		"var a = 12T99:99Z",
+		`var a = "a\qb"`,
+		"var a = `abc",
+		`var a = "a {x"`,
+		`var a = "a {x} b`,
+		`var a = "a {x} \q"`,
+		"var a = 1.2.3e",
+		"var a = 1.5.x",
+		"var a = .5",
+		"var a = x.5",
+		"var a = 1E5",
+		"var a = 0X1F",
+	} {
+		_, err := parser.Fast([]byte(src), nil)
+		if err == nil {
+			t.Fatal("Fast", src)
+		}
+		if parser.Parse([]byte(src), ast.NewFile()) == nil {
+			t.Fatal("Parse", src)
+		}
+	}
 }
 
 func Test_eq(t *testing.T) {
@@ -54,3 +146,140 @@ pub
 
 
 `
+
+func Test_hook(t *testing.T) {
+	// 常量替换 PI 并丢弃占位
+	hook := func(sym parser.Symbol) ([]parser.Symbol, error) {
+		switch {
+		case sym.Tok == token.IDENT && sym.Source == "PI":
+			sym.Tok, sym.Source = token.VALFLOAT, "3.14"
+		case sym.Tok == token.PLACEHOLDER:
+			return nil, nil
+		}
+		return []parser.Symbol{sym}, nil
+	}
+
+	nodes, err := parser.FastHook([]byte("var f64 x = PI // pi"), hook, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`var`, `f64`, `x`, `=`, `3.14`}
+	if len(nodes) != len(want) {
+		t.Fatal(nodes)
+	}
+	for i, n := range nodes {
+		if want[i] != n.Source {
+			t.Fatal(n)
+		}
+	}
+	if nodes[4].Tok != token.VALFLOAT {
+		t.Fatal(nodes[4])
+	}
+}
+
+func TestHookOrigin(t *testing.T) {
+	// 展开的 Token 记录来源, 源码中的 Token 不受影响
+	hook := func(sym parser.Symbol) ([]parser.Symbol, error) {
+		if sym.Tok == token.IDENT && sym.Source == "PI" {
+			sym.Tok, sym.Source, sym.Origin = token.VALFLOAT, "3.14", ast.OriginMacro
+		}
+		return []parser.Symbol{sym}, nil
+	}
+
+	file := ast.NewFile()
+	if err := parser.ParseHook([]byte("var f64 x = PI\n"), file, hook); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range file.Nodes {
+		want := ast.OriginSource
+		if n.Text() == "3.14" {
+			want = ast.OriginMacro
+		}
+		if got := ast.OriginOf(n); got != want {
+			t.Fatal(n.Text(), got)
+		}
+	}
+	if ast.OriginMacro.String() != "macro" {
+		t.Fatal(ast.OriginMacro)
+	}
+}
+
+func TestFastMixed(t *testing.T) {
+	src := []byte("var (\n\ta = 1\n    b = 2\n  \tc = 3\n)\n")
+	if _, err := parser.Fast(src, nil); err == nil {
+		t.Fatal("mixed indentation")
+	}
+	syms, err := parser.FastMixed(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var indents []string
+	for _, sym := range syms {
+		if sym.Tok == token.INDENTATION {
+			if string(src[sym.Pos:int(sym.Pos)+len(sym.Source)]) != sym.Source {
+				t.Fatal(sym.Pos, sym.Source)
+			}
+			indents = append(indents, sym.Source)
+		}
+	}
+	if len(indents) != 3 || indents[0] != "\t" || indents[1] != "    " || indents[2] != "  \t" {
+		t.Fatalf("%q", indents)
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	src := []byte("---\nblock\r\ncomment\n---\nvar x = 1\n")
+	syms, err := parser.Fast(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := syms[0]; c.Tok != token.PLACEHOLDER || c.Lines() != 4 || c.End() != 23 || syms[1].Lines() != 1 {
+		t.Fatal(c, c.Lines(), c.End())
+	}
+
+	syms, err = parser.FastHook(src, parser.SplitLines, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"---\n", "block\r\n", "comment\n", "---\n", "var"}
+	for i, s := range want {
+		if sym := syms[i]; sym.Source != s || string(src[sym.Pos:sym.End()]) != s {
+			t.Fatal(i, sym)
+		}
+	}
+
+	if _, err = parser.Fast([]byte("var x = 1\n---\nopen"), nil); err == nil || !strings.Contains(err.Error(), "offset 10") {
+		t.Fatal(err)
+	}
+}
+
+func TestFastPlaceholderPos(t *testing.T) {
+	src := "if i % 2 [\n]\n"
+	syms, err := parser.Fast([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sym := range syms {
+		if src[sym.Pos:sym.End()] != sym.Source {
+			t.Fatalf("%d %s %q", sym.Pos, sym.Tok, sym.Source)
+		}
+	}
+}
+
+func TestFastTopPlaceholder(t *testing.T) {
+	src := "proc main [\n]\nNote 20160204 it's\n  use is indented\nvar a = [\n1\n]\n\tindented\n// tail"
+	syms, err := parser.Fast([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, sym := range syms {
+		if sym.Tok == token.PLACEHOLDER {
+			got = append(got, sym.Source)
+		}
+	}
+	want := []string{"Note 20160204 it's\n  use is indented\n", "// tail"}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("%q", got)
+	}
+}
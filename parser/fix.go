@@ -0,0 +1,59 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// TextEdit 把源码 [Pos, End) 区间替换为 NewText, Pos == End 时是插入.
+type TextEdit struct {
+	Pos, End scanner.Pos
+	NewText  string
+}
+
+// Error 是带位置的解析错误. 对于常见的错误, 例如混搭的缩进, 缺少结尾的引号,
+// 未结束的块注释, Fixes 是建议的修改, 编辑器和 zxxfmt -fix 可以直接应用.
+type Error struct {
+	Pos   scanner.Pos
+	Msg   string
+	Fixes []TextEdit
+}
+
+func (e *Error) Error() string { return e.Msg }
+
+// Fixes 返回 err 中全部的建议修改, err 可以是 *Error 或 ErrorList.
+func Fixes(err error) (fixes []TextEdit) {
+	switch e := err.(type) {
+	case *Error:
+		return e.Fixes
+	case ErrorList:
+		for _, err := range e {
+			fixes = append(fixes, Fixes(err)...)
+		}
+	}
+	return
+}
+
+// ApplyEdits 返回对 src 应用 edits 之后的源码, edits 的区间不能重叠.
+func ApplyEdits(src []byte, edits []TextEdit) ([]byte, error) {
+	edits = append([]TextEdit(nil), edits...)
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].Pos < edits[j].Pos })
+
+	out := make([]byte, 0, len(src))
+	last := 0
+	for _, e := range edits {
+		if int(e.Pos) < last || e.End < e.Pos || int(e.End) > len(src) {
+			return nil, errors.New("parser: overlapping or invalid edit")
+		}
+		out = append(out, src[last:e.Pos]...)
+		out = append(out, e.NewText...)
+		last = int(e.End)
+	}
+	return append(out, src[last:]...), nil
+}
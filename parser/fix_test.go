@@ -0,0 +1,43 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+func TestFixes(t *testing.T) {
+	for src, want := range map[string]string{
+		"var a = (\n\t1\n    2\n)\n":          "var a = (\n\t1\n\t2\n)\n",
+		"var a = (\n    1\n  \t2\n)\n":        "var a = (\n    1\n    2\n)\n",
+		"var a = 'x\nvar b = 1\n":             "var a = 'x'\nvar b = 1\n",
+		"var a = \"x":                         "var a = \"x\"",
+		"var a = (\n--- x\n":                  "var a = (\n--- x\n---\n",
+		"var a = (\n\t1\n  \t2\n)\nvar b = `": "var a = (\n\t1\n\t2\n)\nvar b = ``",
+	} {
+		_, err := (&parser.Config{MaxErrors: 10}).Parse([]byte(src))
+		fixes := parser.Fixes(err)
+		if len(fixes) == 0 {
+			t.Fatal(src, err)
+		}
+		got, err := parser.ApplyEdits([]byte(src), fixes)
+		if err != nil || string(got) != want {
+			t.Fatalf("%q %q %v", src, got, err)
+		}
+		if _, err = (&parser.Config{}).Parse(got); err != nil {
+			t.Fatal(want, err)
+		}
+	}
+
+	if _, err := parser.ApplyEdits([]byte("abc"), []parser.TextEdit{{Pos: 0, End: 2}, {Pos: 1, End: 3}}); err == nil {
+		t.Fatal("overlapping edits")
+	}
+	if e, ok := parser.Parse([]byte("var a = (\n--- x"), ast.NewFile()).(*parser.Error); !ok || e.Pos != 10 {
+		t.Fatal(e)
+	}
+	e, ok := parser.Parse([]byte("proc f [\n\tx = \"a{b]\"\n]\n"), ast.NewFile()).(*parser.Error)
+	if !ok || e.Pos != 18 || e.Msg != "parser: expected INTERPEND, found RIGHT in INTERPBEGIN group at offset 18" {
+		t.Fatal(e)
+	}
+}
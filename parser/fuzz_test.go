@@ -0,0 +1,70 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+// seeds 是模糊测试的初始语料
+var seeds = []string{
+	"",
+	"note\nvar a = 1 // one\n--- block ---\nvar b = 2\n",
+	"var (\n\tstring name = \"a{b + 1}c\"\n\tint z, i = 0x1F\n)\n",
+	"func int add(int a, int b) {\n\tout a + b\n}\n",
+	"var s = 'a\n  b'\nvar r = `x`\nvar d = 20160202T22:48:33Z\n",
+	"var a = \"{\"\nvar b = '\n--- x",
+	"\t \n  \t-- - ' \" ` { } ( ) [ ] . .. 1. 1e 0x _1",
+}
+
+func FuzzFast(f *testing.F) {
+	for _, s := range seeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, src []byte) {
+		syms, err := parser.Fast(src, nil)
+		if err != nil {
+			return
+		}
+		// Symbol 按 Pos 递增且不越界
+		end := 0
+		for _, sym := range syms {
+			if int(sym.Pos) < end || int(sym.Pos)+len(sym.Source) > len(src) {
+				t.Fatalf("%d %v %q", sym.Pos, sym.Tok, sym.Source)
+			}
+			end = int(sym.Pos) + len(sym.Source)
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	for _, s := range seeds {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, src []byte) {
+		parser.Parse(src, ast.NewFile())
+		(&parser.Config{MaxErrors: 10, TabWidth: 4}).Parse(src)
+	})
+}
+
+func TestUnterminated(t *testing.T) {
+	// 未结束的结构返回错误, 而不是 panic 或者死循环
+	for _, src := range []string{
+		"var a = '", "var a = \"", "var a = `", "var a = \"{", "var a = \"x{b",
+		"var a = \"\\", "var a = (\n--- x",
+	} {
+		if _, err := parser.Fast([]byte(src), nil); err == nil {
+			t.Fatalf("Fast %q", src)
+		}
+		if err := parser.Parse([]byte(src), ast.NewFile()); err == nil {
+			t.Fatalf("Parse %q", src)
+		}
+	}
+	// 单字符的符号
+	for c := 0; c < 128; c++ {
+		src := []byte{'v', 'a', 'r', ' ', 'a', ' ', '=', ' ', byte(c)}
+		parser.Fast(src, nil)
+		parser.Parse(src, ast.NewFile())
+	}
+}
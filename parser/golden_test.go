@@ -0,0 +1,115 @@
+package parser_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// TestGolden 对比 testdata/golden 中每个 .zxx 文件的解析结果与同名的 golden 文件:
+// .tokens 是 Fast 的 Symbol, .ast 是 Parse 的节点. go test -update 重新生成 golden 文件,
+// 语法的改动因此体现在 golden 文件的 diff 中.
+// 另外检查从 Fast 的结果构建的 File 与 Parse 的非 trivia 节点一致,
+// Fast 把注释及其缩进合并为 PLACEHOLDER, 因此 trivia 不同.
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "golden", "*.zxx"))
+	if err != nil || len(files) == 0 {
+		t.Fatal("no golden inputs", err)
+	}
+	for _, name := range files {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		syms, err := parser.Fast(src, nil)
+		if err != nil {
+			t.Errorf("%s: Fast: %v", name, err)
+			continue
+		}
+		file := ast.NewFile()
+		if err := parser.Parse(src, file); err != nil {
+			t.Errorf("%s: Parse: %v", name, err)
+			continue
+		}
+		golden(t, strings.TrimSuffix(name, ".zxx")+".tokens", dumpSymbols(syms))
+		golden(t, strings.TrimSuffix(name, ".zxx")+".ast", dumpNodes(file, true))
+
+		fast, err := ast.FromSymbols(syms)
+		if err != nil {
+			t.Errorf("%s: FromSymbols: %v", name, err)
+		} else if got, want := dumpNodes(fast, false), dumpNodes(file, false); !bytes.Equal(got, want) {
+			t.Errorf("%s: Fast and Parse diverge:\n%s", name, firstDiff(got, want))
+		}
+	}
+}
+
+// golden 对比 got 与 golden 文件 name, -update 时写入 name
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := ioutil.WriteFile(name, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs (run go test -update to accept):\n%s", name, firstDiff(got, want))
+	}
+}
+
+// dumpSymbols 每行输出一个 Symbol: 偏移量, Token, 源码
+func dumpSymbols(syms []parser.Symbol) []byte {
+	var b bytes.Buffer
+	for _, sym := range syms {
+		fmt.Fprintf(&b, "%4d %-12v %q\n", sym.Pos, sym.Tok, sym.Source)
+	}
+	return b.Bytes()
+}
+
+// dumpNodes 每行输出一个节点: 偏移量, 按深度缩进的 Token, 源码. trivia 为 false 时跳过 trivia.
+func dumpNodes(file *ast.File, trivia bool) []byte {
+	var b bytes.Buffer
+	syms := ast.ToSymbols(file)
+	for i, n := range file.Nodes[1:] {
+		if !trivia && ast.IsTrivia(n.Token()) {
+			continue
+		}
+		d := 0
+		for p := n.Parent(); p != nil && p.Parent() != nil; p = p.Parent() {
+			d++
+		}
+		fmt.Fprintf(&b, "%4d %*s%-12v %q\n", syms[i].Pos, 2*d, "", n.Token(), n.Text())
+	}
+	return b.Bytes()
+}
+
+// firstDiff 返回 got 与 want 第一个不同的行
+func firstDiff(got, want []byte) string {
+	g, w := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < len(g) || i < len(w); i++ {
+		var gl, wl string
+		if i < len(g) {
+			gl = g[i]
+		}
+		if i < len(w) {
+			wl = w[i]
+		}
+		if gl != wl {
+			return fmt.Sprintf("line %d:\n\tgot  %s\n\twant %s", i+1, gl, wl)
+		}
+	}
+	return ""
+}
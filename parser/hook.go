@@ -0,0 +1,82 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Hook 在解析器消费 Symbol 之前对其进行后期处理.
+// 返回的 Symbols 依次替代 sym 被消费, 返回空表示丢弃 sym.
+// 合成的 Symbol 应该设置 Origin, ParseHook 把它记录到节点上.
+// 嵌入者可以用 Hook 实现常量替换, 包含文件等预处理, 而无需 fork 解析器.
+//
+// EOF 也会经过 Hook, 此时可以追加 Symbol, 但 EOF 总会被最后消费.
+type Hook func(sym Symbol) ([]Symbol, error)
+
+// SplitLines 是按行拆分多行占位和注释的 Hook, 参见 ast.Symbol.Spans.
+// Fast 把块注释合并到 PLACEHOLDER 中, 语法高亮等逐行处理的工具可以用
+//
+//	FastHook(src, SplitLines, cb)
+//
+// 逐行接收它们. 拆分后的 Symbol 不应再用于解析.
+func SplitLines(sym Symbol) ([]Symbol, error) {
+	switch sym.Tok {
+	case token.PLACEHOLDER, token.COMMENT, token.COMMENTS:
+		return sym.Spans(), nil
+	}
+	return []Symbol{sym}, nil
+}
+
+// FastHook 同 Fast, 但 Fast 产生的每个 Symbol 先经过 hook 处理.
+// 如果 hook 为 nil, 等同 Fast.
+func FastHook(src []byte, hook Hook, cb func(scanner.Pos, token.Token, string) error) (nodes []Symbol, err error) {
+	if hook == nil {
+		return Fast(src, cb)
+	}
+
+	if cb == nil {
+		nodes = make([]Symbol, 0, len(src)/bytesPerSymbol)
+	}
+
+	_, err = Fast(src, func(pos scanner.Pos, tok token.Token, code string) error {
+		syms, err := hook(Symbol{Pos: pos, Tok: tok, Source: code})
+		if err != nil {
+			return err
+		}
+		for _, sym := range syms {
+			if sym.Tok == token.EOF {
+				continue
+			}
+			if cb == nil {
+				nodes = append(nodes, sym)
+			} else if err = cb(sym.Pos, sym.Tok, sym.Source); err != nil {
+				return err
+			}
+		}
+		if tok == token.EOF && cb != nil {
+			err = cb(pos, tok, code)
+		}
+		return err
+	})
+	return
+}
+
+// ParseHook 同 Parse, 但推送到 file 的每个 Token 先经过 hook 处理.
+// 如果 hook 为 nil, 等同 Parse.
+func ParseHook(src []byte, file *ast.File, hook Hook) error {
+	if hook == nil {
+		return Parse(src, file)
+	}
+	return defaultConfig.parse(src, file, func(pos scanner.Pos, tok token.Token, code string) error {
+		syms, err := hook(Symbol{Pos: pos, Tok: tok, Source: code})
+		for i := 0; err == nil && i < len(syms); i++ {
+			err = file.PushSymbol(syms[i])
+		}
+		return err
+	})
+}
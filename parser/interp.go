@@ -0,0 +1,91 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// interp 记录插值字符串的扫描状态.
+//
+// 双引号字符串中的 '{' 开始一个插值表达式, 对应的 '}' 结束插值表达式,
+// 表达式中的括号必须成对出现. 例如 "hello {name}!" 被分解为
+//
+//	STRINGLIT   `"hello `
+//	INTERPBEGIN `{`
+//	IDENT       `name`
+//	INTERPEND   `}`
+//	STRINGLIT   `!"`
+//
+// 不含插值的双引号字符串仍然是 VALSTRING, 字面的 '{' 需要写作 "\{".
+type interp struct {
+	depth []int // 每层插值表达式中未闭合的括号数量
+	begin bool  // 下一个 "{" 开始插值表达式
+
+	source func(from, to scanner.Pos) string // 取得连续的源码, 避免拼接
+}
+
+// brace 返回括号 tok 在插值字符串中的 Token, 可能是 INTERPBEGIN, INTERPEND.
+func (in *interp) brace(tok token.Token, code string) token.Token {
+	if tok == token.LEFT && in.begin {
+		in.begin = false
+		in.depth = append(in.depth, 0)
+		return token.INTERPBEGIN
+	}
+	n := len(in.depth)
+	switch {
+	case n == 0:
+	case tok == token.LEFT:
+		in.depth[n-1]++
+	case in.depth[n-1] != 0:
+		in.depth[n-1]--
+	case code == "}":
+		in.depth = in.depth[:n-1]
+		return token.INTERPEND
+	}
+	return tok
+}
+
+// text 使用 end 扫描位于 pos 的字符串文本, quote 是已经扫描的开始引号,
+// 插值表达式之后的文本 quote 为空. 没有插值的字符串返回 VALSTRING.
+func (in *interp) text(pos scanner.Pos, quote string, end func() (string, bool)) (token.Token, string, error) {
+	text, more := end()
+	code := text
+	if quote != "" {
+		code = in.source(pos, pos.Offset(len(quote)+len(text)))
+	}
+	in.begin = more
+	if quote != "" && !more {
+		tok, err := classify(pos, code)
+		return tok, code, err
+	}
+
+	if _, err := lexutil.Segment(code); err != nil {
+		e := err.(*lexutil.Error)
+		return 0, code, errors.New("parser: " + e.Msg + " at offset " + strconv.Itoa(int(pos)+e.Offset))
+	}
+	if !more && !closed(code) {
+		return 0, code, errors.New("parser: string is incomplete at offset " + strconv.Itoa(int(pos)+len(code)))
+	}
+	return token.STRINGLIT, code, nil
+}
+
+// closed 返回文本是否以未逃逸的 '"' 结束
+func closed(code string) bool {
+	n := len(code) - 1
+	if n < 0 || code[n] != '"' {
+		return false
+	}
+	slash := 0
+	for i := n - 1; i >= 0 && code[i] == '\\'; i-- {
+		slash++
+	}
+	return slash%2 == 0
+}
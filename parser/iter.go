@@ -0,0 +1,80 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// TokenIterator 按需逐个产生 Fast 解析到的 Symbol, 不缓存全部结果.
+// 调用方不再读取时, 解析随之暂停, 提前结束时应当调用 Close 释放资源.
+//
+//	it := parser.NewTokenIterator(src)
+//	defer it.Close()
+//	for {
+//		sym, err := it.Next()
+//		if err != nil || sym.Tok == token.EOF {
+//			break
+//		}
+//		...
+//	}
+type TokenIterator struct {
+	next func() (Symbol, error, bool)
+	stop func()
+	sym  Symbol // 结束后重复返回的 Symbol 和 err
+	err  error
+	done bool
+}
+
+// NewTokenIterator 返回 src 的 TokenIterator, Symbol 与 Fast 产生的相同.
+func NewTokenIterator(src []byte) *TokenIterator {
+	next, stop := pull(fastSeq(src))
+	return &TokenIterator{next: next, stop: stop}
+}
+
+// Next 返回下一个 Symbol, 最后是 Tok 为 EOF 的 Symbol.
+// 出错或者到达 EOF 之后, 以及 Close 之后, 总是返回最后的结果.
+func (it *TokenIterator) Next() (Symbol, error) {
+	if it.done {
+		return it.sym, it.err
+	}
+	sym, err, ok := it.next()
+	if !ok {
+		sym = Symbol{Tok: token.EOF}
+	}
+	if !ok || err != nil || sym.Tok == token.EOF {
+		it.sym, it.err = sym, err
+		it.Close()
+	}
+	return sym, err
+}
+
+// Close 停止解析, 之后 Next 返回 EOF. 可以多次调用.
+func (it *TokenIterator) Close() {
+	if !it.done {
+		it.done = true
+		it.stop()
+	}
+	if it.err == nil && it.sym.Tok != token.EOF {
+		it.sym = Symbol{Tok: token.EOF}
+	}
+}
+
+// fastSeq 返回以 yield 逐个产生 Fast 结果的函数, 包括 EOF 和错误,
+// yield 返回 false 时停止解析.
+func fastSeq(src []byte) func(yield func(Symbol, error) bool) {
+	return func(yield func(Symbol, error) bool) {
+		_, err := Fast(src, func(pos scanner.Pos, tok token.Token, code string) error {
+			if !yield(Symbol{Pos: pos, Tok: tok, Source: code}, nil) {
+				return errStop
+			}
+			return nil
+		})
+		if err != nil && err != errStop {
+			yield(Symbol{}, err)
+		}
+	}
+}
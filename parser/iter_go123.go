@@ -0,0 +1,29 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.23
+
+package parser
+
+import (
+	"iter"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Tokens 返回 src 中 Fast 解析到的 Symbol 序列, 不包括 EOF, 出错时产生 err 之后结束.
+// 用于 for range, 循环提前结束时解析随之停止.
+func Tokens(src []byte) iter.Seq2[Symbol, error] {
+	return func(yield func(Symbol, error) bool) {
+		for sym, err := range fastSeq(src) {
+			if err == nil && sym.Tok == token.EOF || !yield(sym, err) {
+				return
+			}
+		}
+	}
+}
+
+func pull(seq func(yield func(Symbol, error) bool)) (func() (Symbol, error, bool), func()) {
+	return iter.Pull2(iter.Seq2[Symbol, error](seq))
+}
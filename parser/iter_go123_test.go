@@ -0,0 +1,32 @@
+//go:build go1.23
+
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func TestTokens(t *testing.T) {
+	var toks []token.Token
+	for sym, err := range parser.Tokens([]byte(iterSrc)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		toks = append(toks, sym.Tok)
+		if sym.Tok == token.PROC {
+			break
+		}
+	}
+	want := []token.Token{token.USE, token.IDENT, token.VALSTRING, token.NL, token.PROC}
+	if len(toks) != len(want) {
+		t.Fatalf("got %v", toks)
+	}
+	for i := range want {
+		if toks[i] != want[i] {
+			t.Fatalf("got %v", toks)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !go1.23
+
+package parser
+
+// pull 在 goroutine 中执行 seq, 每次调用 next 时恢复执行到下一个 Symbol.
+func pull(seq func(yield func(Symbol, error) bool)) (next func() (Symbol, error, bool), stop func()) {
+	type item struct {
+		sym Symbol
+		err error
+	}
+	items := make(chan item)
+	resume := make(chan bool)
+	started, finished := false, false
+
+	run := func() {
+		defer close(items)
+		if !<-resume {
+			return
+		}
+		seq(func(sym Symbol, err error) bool {
+			items <- item{sym, err}
+			return <-resume
+		})
+	}
+
+	next = func() (Symbol, error, bool) {
+		if finished {
+			return Symbol{}, nil, false
+		}
+		if !started {
+			started = true
+			go run()
+		}
+		resume <- true
+		it, ok := <-items
+		if !ok {
+			finished = true
+		}
+		return it.sym, it.err, ok
+	}
+	stop = func() {
+		if finished {
+			return
+		}
+		finished = true
+		if started {
+			resume <- false
+			for range items {
+			}
+		}
+	}
+	return
+}
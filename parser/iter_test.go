@@ -0,0 +1,61 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+const iterSrc = "use fmt 'fmt'\n\nproc main() [\n\tfmt.print('a{1 + 2}b') // c\n]\n"
+
+func TestTokenIterator(t *testing.T) {
+	want, err := parser.Fast([]byte(iterSrc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := parser.NewTokenIterator([]byte(iterSrc))
+	for i := 0; ; i++ {
+		sym, err := it.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sym.Tok == token.EOF {
+			if i != len(want) {
+				t.Fatalf("EOF after %d symbols, want %d", i, len(want))
+			}
+			break
+		}
+		if i >= len(want) || sym != want[i] {
+			t.Fatalf("%d: %v", i, sym)
+		}
+	}
+	if sym, err := it.Next(); sym.Tok != token.EOF || err != nil {
+		t.Fatal("Next after EOF", sym, err)
+	}
+
+	it = parser.NewTokenIterator([]byte(iterSrc))
+	if sym, _ := it.Next(); sym.Tok != token.USE {
+		t.Fatal(sym)
+	}
+	it.Close()
+	it.Close()
+	if sym, err := it.Next(); sym.Tok != token.EOF || err != nil {
+		t.Fatal("Next after Close", sym, err)
+	}
+
+	it = parser.NewTokenIterator([]byte("proc main() [\n\t  x\n]\n"))
+	defer it.Close()
+	for {
+		sym, err := it.Next()
+		if err != nil {
+			break
+		}
+		if sym.Tok == token.EOF {
+			t.Fatal("mixed indentation")
+		}
+	}
+	if _, err := it.Next(); err == nil {
+		t.Fatal("error not repeated")
+	}
+}
@@ -0,0 +1,373 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Lexer 是 Fast 和 Parse 共用的词法层, 把扫描到的符号转换为 Token:
+//
+//	COMMENT     '//' 尾注释, 以及行中 TABS 开始的尾注释
+//	COMMENTS    完整的块注释 '---'
+//	VALFLOAT    替代 NAN, INFINITE
+//	VALBOOL     替代 TRUE, FALSE
+//	字面值      字符串, 插值字符串, 数值, datetime, 标识符和成员
+//	PLACEHOLDER 顶层的非声明源码, 直到下一个声明
+//	PLACEHOLDER 源码开头的 shebang 行和 front-matter 块, 参见 ast.Header
+//
+// SPACES 原样返回, TABS 只在行首返回, 缩进的识别和检查由调用者完成.
+// 跨行的 ' 和 " 字符串的后续行必须比开始的行缩进更深, 否则字符串缺少结尾的引号, 错误位于开始的引号.
+//
+// Top 为 nil 时, 顶层由以下规则确定, 不依赖解析器的状态: 源码的开始, 以及所有括号之外,
+// 没有缩进, 不是空行, 注释和右括号的行首. 顶层的非声明源码是占位, 直到某个行首是声明的保留字,
+// 因此占位中以保留字开始的行需要缩进, 或者写成 '//', '---' 注释.
+type Lexer struct {
+	// Top 返回下一个 Token 是否位于顶层, 顶层的非声明源码合并为一个 PLACEHOLDER.
+	// 为 nil 时按上述规则判断.
+	Top func() bool
+
+	src     []byte
+	scan    *scanner.Scanner
+	in      interp
+	prev    token.Token // 上个 Token
+	indent  bool        // 上个 SPACES, TABS 是否位于行首
+	start   bool        // 是否位于源码的开始
+	depth   int         // 未闭合的 LEFT 个数
+	pending []lexeme    // 已经扫描, 随后返回的 Token
+	at      int         // pending 中下一个返回的 Token
+}
+
+// lexeme 是随后返回的 Token
+type lexeme struct {
+	sym Symbol
+	err error
+}
+
+// NewLexer 返回扫描 src 的 Lexer
+func NewLexer(src []byte) *Lexer {
+	return newLexer(src, false)
+}
+
+// NewSharedLexer 同 NewLexer, 但 Symbol.Source 直接引用 src 的内存, 参见 scanner.NewShared.
+func NewSharedLexer(src []byte) *Lexer {
+	return newLexer(src, true)
+}
+
+func newLexer(src []byte, shared bool) *Lexer {
+	l := &Lexer{src: src, start: true}
+	if shared {
+		l.scan = scanner.NewShared(src)
+	} else {
+		l.scan = scanner.New(src)
+	}
+	l.in.source = l.scan.Source
+	return l
+}
+
+// Next 返回下一个 Token, 源码结束时返回 EOF.
+//
+// 非法的字面值, 未结束的字符串或块注释返回错误, 此时 Symbol 是作为 PLACEHOLDER 的非法源码,
+// 可以继续调用 Next, 解析器据此从错误中恢复. 返回 EOF 时的错误, 例如非法的 UTF-8 编码,
+// 或者插值字符串未结束, 总是最后的结果.
+func (l *Lexer) Next() (Symbol, error) {
+	pos, tok, code, err := l.next()
+	return Symbol{Pos: pos, Tok: tok, Source: code}, err
+}
+
+// pop 返回 pending 中下一个 Token
+func (l *Lexer) pop() (scanner.Pos, token.Token, string, error) {
+	x := l.pending[l.at]
+	if l.at++; l.at == len(l.pending) {
+		// 复用 pending 的空间
+		l.pending, l.at = l.pending[:0], 0
+	}
+	return l.emit(x.sym, x.err)
+}
+
+// emit 调用 track 并返回 sym 的字段, 用于 pending 和 next 中不常见的 Token
+func (l *Lexer) emit(sym Symbol, err error) (scanner.Pos, token.Token, string, error) {
+	l.track(sym.Tok)
+	return sym.Pos, sym.Tok, sym.Source, err
+}
+
+// track 记录将要返回的 tok, 供随后的 lineStart 和 top 使用
+func (l *Lexer) track(tok token.Token) {
+	l.prev = tok
+	switch tok {
+	case token.LEFT:
+		l.depth++
+	case token.RIGHT:
+		if l.depth > 0 {
+			l.depth--
+		}
+	}
+}
+
+// lineTop 返回位于 pos 的 tok 是否为括号之外, 没有缩进的非空行的行首.
+// 多数 Token 不在行首, 因此先判断之前的字符.
+func (l *Lexer) lineTop(pos scanner.Pos, tok token.Token) bool {
+	if l.depth != 0 || pos != 0 && l.src[pos-1] != '\n' && l.src[pos-1] != '\r' {
+		return false
+	}
+	switch tok {
+	case token.NL, token.SPACES, token.TABS, token.COMMENT, token.COMMENTS, token.RIGHT:
+		return false
+	}
+	return true
+}
+
+// later 追加随后返回的 Token
+func (l *Lexer) later(pos scanner.Pos, tok token.Token, code string, err error) {
+	l.pending = append(l.pending, lexeme{Symbol{Pos: pos, Tok: tok, Source: code}, err})
+}
+
+// lineStart 返回当前是否位于行首的空白中
+func (l *Lexer) lineStart() bool {
+	if l.prev == token.NL {
+		return true
+	}
+	if l.prev == token.SPACES || l.prev == token.TABS {
+		return l.indent
+	}
+	return false
+}
+
+// errUTF8 是非法 UTF-8 编码的错误
+var errUTF8 = errors.New("invalid UTF-8 encode")
+
+// next 同 Next, 但分别返回 Symbol 的字段, 避免编译器经由栈复制 Symbol.
+// 这是 Fast 和 Parse 的热点路径, 不常见的 Token 由其它方法处理, 使 next 保持短小.
+// 一次扫描到的多个 Token 中随后的追加到 pending.
+func (l *Lexer) next() (scanner.Pos, token.Token, string, error) {
+	if l.at < len(l.pending) {
+		return l.pop()
+	}
+	scan := l.scan
+	if l.start && (l.Top == nil || l.Top()) {
+		if sym, ok, err := l.header(); ok {
+			l.start = false
+			return l.emit(sym, err)
+		}
+	}
+	pos := scan.Pos()
+	code, ok := scan.Symbol()
+	if !ok {
+		return l.emit(Symbol{Pos: pos, Tok: token.EOF}, errUTF8)
+	}
+	tok := token.Lookup(code)
+	if tok == token.EOF {
+		return l.emit(l.eof(pos, code))
+	}
+
+	// 顶层由 Top 确定, 为 nil 时按行首的规则
+	top := l.start
+	if l.Top != nil {
+		top = l.Top()
+	} else if !top {
+		top = l.lineTop(pos, tok)
+	}
+	l.start = false
+	if top && !tok.As(token.Declare) {
+		return l.emit(l.placeholder(pos, tok))
+	}
+
+	var err error
+	switch tok {
+	case token.SPACES:
+		l.indent = l.lineStart()
+	case token.TABS:
+		if !l.lineStart() {
+			// TABS 尾注释
+			scan.TailSameLine()
+			code, tok = scan.Source(pos, scan.Pos()), token.COMMENT
+		}
+		l.indent = true
+	case token.COMMENT:
+		scan.TailSameLine()
+		code = scan.Source(pos, scan.Pos())
+	case token.COMMENTS:
+		return l.emit(l.comments(pos))
+	case token.TRUE, token.FALSE:
+		tok = token.VALBOOL
+	case token.NAN, token.INFINITE:
+		tok = token.VALFLOAT
+	case token.LEFT, token.RIGHT:
+		if tok = l.in.brace(tok, code); tok == token.INTERPEND {
+			l.interpEnd()
+		}
+	case token.PLACEHOLDER:
+		// 识别语义, 只剩下字面值和标识符, 成员. 字符串由 literal 继续扫描
+		if c := code[0]; c == '"' || c == '\'' || c == '`' {
+			tok, code, err = l.literal(pos, code)
+		} else if tok, err = classify(pos, code); err != nil {
+			tok = token.PLACEHOLDER
+		} else if (tok == token.MEMBER || tok == token.MEMBERS) && numeric(l.prev) {
+			// 例如 1.2.3e 被扫描为 1.2 和 .3e
+			tok, err = token.PLACEHOLDER, errors.New("parser: member "+strconv.Quote(code)+" follows "+l.prev.String()+" at offset "+strconv.Itoa(int(pos)))
+		}
+	}
+	l.track(tok)
+	return pos, tok, code, err
+}
+
+// numeric 返回 tok 是否为数值或 datetime 字面值, 之后紧接的成员是错误
+func numeric(tok token.Token) bool {
+	return tok == token.VALINTEGER || tok == token.VALFLOAT || tok == token.VALDATETIME
+}
+
+// eof 返回位于 pos 的 EOF
+func (l *Lexer) eof(pos scanner.Pos, code string) (Symbol, error) {
+	var err error
+	if l.in.begin || len(l.in.depth) != 0 {
+		err = errors.New("parser: string is incomplete")
+	}
+	return Symbol{Pos: pos, Tok: token.EOF, Source: code}, err
+}
+
+// placeholder 返回从位于 pos 的 tok 开始的顶层占位, 直到某个行首是声明的保留字,
+// 该声明追加到 pending.
+func (l *Lexer) placeholder(pos scanner.Pos, tok token.Token) (Symbol, error) {
+	scan := l.scan
+	posi, ok := pos, true
+	var code string
+	for ok && tok != token.EOF && !tok.As(token.Declare) {
+		// 换行之后是新的一行, 由循环判断是否为声明
+		if tok != token.NL {
+			scan.TailWithNewline()
+		}
+		pos = scan.Pos()
+		code, ok = scan.Symbol()
+		tok = token.Lookup(code)
+	}
+	if !ok {
+		return Symbol{Pos: pos, Tok: token.EOF}, errUTF8
+	}
+	if tok != token.EOF {
+		l.later(pos, tok, code, nil)
+	}
+	return Symbol{Pos: posi, Tok: token.PLACEHOLDER, Source: scan.Source(posi, pos)}, nil
+}
+
+// comments 返回位于 pos 的完整块注释, 未结束时返回带有修复建议的 PLACEHOLDER
+func (l *Lexer) comments(pos scanner.Pos) (Symbol, error) {
+	scan := l.scan
+	tok := token.COMMENTS
+	for !scan.IsEOF() {
+		tmp, _ := scan.Symbol()
+		if tok = token.Lookup(tmp); tok == token.COMMENTS {
+			break
+		}
+	}
+	if tok != token.COMMENTS {
+		code := scan.Source(pos, scan.Pos())
+		end, text := scan.Pos(), "---\n"
+		if !strings.HasSuffix(code, "\n") {
+			text = "\n---"
+		}
+		err := &Error{pos, "parser: COMMENTS is incomplete at offset " + strconv.Itoa(int(pos)), []TextEdit{{end, end, text}}}
+		return Symbol{Pos: pos, Tok: token.PLACEHOLDER, Source: code}, err
+	}
+	scan.TailSameLine()
+	return Symbol{Pos: pos, Tok: tok, Source: scan.Source(pos, scan.Pos())}, nil
+}
+
+// interpEnd 在插值表达式结束后继续扫描字符串, 追加到 pending
+func (l *Lexer) interpEnd() {
+	at := l.scan.Pos()
+	t, text, e := l.in.text(at, "", l.scan.EndInterpString)
+	if e != nil {
+		t = token.PLACEHOLDER
+	}
+	l.later(at, t, text, e)
+}
+
+// literal 返回位于 pos 的符号 code 开始的字面值, 标识符或成员.
+// 出错时返回 PLACEHOLDER, 未结束的字符串的错误是带有修复建议的 *Error.
+func (l *Lexer) literal(pos scanner.Pos, code string) (token.Token, string, error) {
+	scan := l.scan
+	var tok token.Token
+	var err error
+	mark := scan.Mark()
+	if code == `"` {
+		// 双引号字符串可能包含插值
+		tok, code, err = l.in.text(pos, code, scan.EndInterpString)
+	} else {
+		switch code {
+		case `'`:
+			scan.EndString(false)
+			code = scan.Source(pos, scan.Pos())
+		case "`":
+			scan.EndRawString()
+			code = scan.Source(pos, scan.Pos())
+		}
+		// 字符串, 整数, 浮点数, datetime, 标识符, 成员
+		tok, err = classify(pos, code)
+	}
+	// 跨行的 ' 和 " 字符串的后续行必须比开始的行缩进更深, 否则多半是缺少结尾的引号,
+	// 例如注释中的 it's, 以免吞掉之后的源码
+	broken := len(code) > 1 && code[0] != '`' && strings.ContainsAny(code, "\r\n") && !indented(l.src, pos, code)
+	if err == nil && !broken {
+		return tok, code, nil
+	}
+
+	if broken || unclosed(code, scan.IsEOF()) {
+		var msg string
+		if err != nil {
+			msg = err.Error()
+		}
+		if strings.ContainsAny(code, "\r\n") {
+			// 跨行直到 EOF 的字符串也多半是缺少结尾的引号, 只把当前行作为占位
+			scan.Reset(mark)
+			scan.TailSameLine()
+			code, l.in.begin = scan.Source(pos, scan.Pos()), false
+			msg = "parser: string is incomplete at offset " + strconv.Itoa(int(pos)) + ", missing " + code[:1] + " before end of line"
+		}
+		fix := &Error{Pos: pos, Msg: msg}
+		if end := pos.Offset(len(code)); code[0] != '"' || closed(code+`"`) {
+			fix.Fixes = []TextEdit{{end, end, code[:1]}}
+		}
+		err = fix
+	}
+	return token.PLACEHOLDER, code, err
+}
+
+// header 扫描源码开头的 shebang 行 #!... 和之后的 front-matter 块, 返回包括二者的 PLACEHOLDER,
+// 都没有时返回 false. front-matter 从 +++ 行开始, 到下一个 +++ 行结束, 其中的源码不被识别.
+func (l *Lexer) header() (sym Symbol, ok bool, err error) {
+	scan := l.scan
+	pos := scan.Pos()
+	mark := scan.Mark()
+	line := scan.TailWithNewline()
+	if strings.HasPrefix(line, "#!") {
+		mark = scan.Mark()
+		line = scan.TailWithNewline()
+	}
+	if isFence(line) {
+		for !isFence(scan.TailWithNewline()) {
+			if scan.IsEOF() {
+				end := scan.Pos()
+				err = &Error{pos, "parser: front matter is incomplete at offset " + strconv.Itoa(int(pos)), []TextEdit{{end, end, "\n+++"}}}
+				break
+			}
+		}
+	} else {
+		scan.Reset(mark)
+	}
+	if end := scan.Pos(); end != pos {
+		return Symbol{Pos: pos, Tok: token.PLACEHOLDER, Source: scan.Source(pos, end)}, true, err
+	}
+	return
+}
+
+// isFence 返回 line 是否为 front-matter 的定界行 +++
+func isFence(line string) bool {
+	return strings.TrimRight(line, " \t\r\n") == "+++"
+}
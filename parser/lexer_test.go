@@ -0,0 +1,80 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func TestLexer(t *testing.T) {
+	src := "note\nvar a = true\t// t\nproc p() [\n\tx = 'y\n]\n"
+	lex := parser.NewLexer([]byte(src))
+	var toks []string
+	errs := 0
+	for {
+		sym, err := lex.Next()
+		if err != nil {
+			errs++
+			if sym.Tok != token.PLACEHOLDER || sym.Source != "'y" {
+				t.Fatal(sym, err)
+			}
+		}
+		if sym.Tok == token.EOF {
+			break
+		}
+		if sym.Tok != token.SPACES {
+			toks = append(toks, sym.Tok.String())
+		}
+	}
+	want := "PLACEHOLDER var IDENT = VALBOOL COMMENT NEWLINE proc IDENT LEFT RIGHT LEFT NEWLINE " +
+		"TABS IDENT = PLACEHOLDER NEWLINE RIGHT NEWLINE"
+	if errs != 1 || strings.Join(toks, " ") != want {
+		t.Fatal(errs, toks)
+	}
+}
+
+func TestLexerComments(t *testing.T) {
+	// 行首的 TABS 是缩进, 不因前一行的注释被当作尾注释
+	src := []byte("proc p [\n\t// c\n\tout 1\n]\n")
+	file, err := new(parser.Config).Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, node := range file.Nodes {
+		found = found || node.Token() == token.VALINTEGER
+	}
+	if !found {
+		t.Fatal("statement after comment is lost")
+	}
+
+	_, err = parser.Fast([]byte("var a = 1\n--- open\n"), nil)
+	if e, ok := err.(*parser.Error); !ok || len(e.Fixes) != 1 {
+		t.Fatal(err)
+	}
+}
+
+func TestLexerCRLF(t *testing.T) {
+	// 占位, 注释, 块注释, 未结束的字符串和 front-matter 都用 TailSameLine, TailWithNewline 取到行尾,
+	// CRLF 换行的结果与 LF 相同, 只是源码中的换行不同
+	for _, src := range []string{
+		"#!/usr/bin/env zxx\n+++\ntitle = 'x'\n\n+++\n\nproc main [\n]\n",
+		"顶层占位\n\n说明\nproc main [\n\tout 1 // 注释\n\tout 'a\n]\n",
+		"--- 块\n注释\n---\nvar x = 1\t// 尾注释\n",
+		"+++\nopen\n",
+	} {
+		crlf := strings.Replace(src, "\n", "\r\n", -1)
+		want, werr := parser.Fast([]byte(src), nil)
+		got, gerr := parser.Fast([]byte(crlf), nil)
+		if len(got) != len(want) || (werr == nil) != (gerr == nil) {
+			t.Fatalf("%q: %v %v", src, got, gerr)
+		}
+		for i, sym := range got {
+			if sym.Tok != want[i].Tok || sym.Source != strings.Replace(want[i].Source, "\n", "\r\n", -1) {
+				t.Fatalf("%q %d: %v %q, want %v %q", src, i, sym.Tok, sym.Source, want[i].Tok, want[i].Source)
+			}
+		}
+	}
+}
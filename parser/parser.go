@@ -5,7 +5,8 @@
 package parser
 
 import (
-	"errors"
+	"bytes"
+	"strings"
 
 	"github.com/ZxxLang/zxx/ast"
 	"github.com/ZxxLang/zxx/scanner"
@@ -31,44 +32,57 @@ func symbolIfy(s string, ok bool) string {
 //	逗号, 分号, 换行用于产生 FFinal 标记, 并切换当前节点.
 //
 func Parse(src []byte, file *ast.File) (err error) {
+	return defaultConfig.parse(src, file, file.Push)
+}
+
+// parse 通过 push 推送 Token 到 file, push 可以是 file.Push 的包装.
+func (c *Config) parse(src []byte, file *ast.File, push func(scanner.Pos, token.Token, string) error) error {
 	var (
 		tabKind bool // 缩进风格
+		errs    ErrorList
+		err     error
+		indent  = indentFixer(-1)
+		bad     ErrorList // Lenient 修复的编码
 	)
+	if c.Lenient {
+		src, bad = RepairUTF8(src)
+	}
 
-	scan := scanner.New(src)
-	for err == nil && !scan.IsEOF() {
-		pos := scan.Pos()
-		code, ok := scan.Symbol()
+	lex := newLexer(src, c.Shared)
+	lex.Top = func() bool { return file.Active == file }
 
-		if !ok {
-			err = errors.New("invalid UTF-8 encode")
-			break
+	// fail 记录可恢复的错误, 返回是否应该停止解析
+	var at scanner.Pos // 当前 Token 之后的位置
+	fail := func(e error) bool {
+		errs = append(errs, e)
+		stop := len(errs) >= c.MaxErrors
+		if !stop && c.Trace != nil {
+			c.Trace(TraceEvent{Kind: TraceRecover, Pos: at, Depth: depth(file.Active), Msg: e.Error()})
 		}
+		return stop
+	}
+	if c.Trace != nil {
+		push = c.trace(file, push)
+	}
 
-		tok := token.Lookup(code)
+	for err == nil {
+		pos, tok, code, e := lex.next()
+		at = pos.Offset(len(code))
+		if tok == token.EOF {
+			if e != nil {
+				fail(e)
+			}
+			break
+		}
+		if e != nil && fail(e) {
+			break
+		}
 
-		// 根节点, 只包含声明和占位, 非声明都转换为占位
 		if file.Active == file {
-			if !tok.As(token.Declare) {
-				// 占位扫描
-				var tmp string
-				for ok && tok != token.EOF && !tok.As(token.Declare) {
-					code += scan.Tail(true) + tmp
-					pos = scan.Pos()
-					tmp, ok = scan.Symbol()
-					tok = token.Lookup(tmp)
-				}
-				if !ok {
-					err = errors.New("invalid UTF-8 encode")
-					break
-				}
-
-				if err = file.Push(pos, token.PLACEHOLDER, code); err != nil {
-					break
-				}
-				code = tmp
+			// 根节点, 只包含声明和占位, Lexer 把非声明合并为占位
+			if tok != token.PLACEHOLDER || c.Mode&ParsePlaceholders != 0 {
+				err = push(pos, tok, code)
 			}
-			err = file.Push(pos, tok, code)
 			continue
 		}
 
@@ -78,114 +92,113 @@ func Parse(src []byte, file *ast.File) (err error) {
 		switch tok {
 
 		case token.SPACES:
-			// 不支持 SPACES, TABS 混搭缩进
+			// 不支持 SPACES, TABS 混搭缩进, 除非设置了 TabWidth
 			if last.Token() == token.INDENTATION ||
 				tabKind && last.Token() == token.NL {
-				err = errors.New("parser: bad indentation style for TABS + SPACES")
-				continue
+				if c.TabWidth == 0 && fail(&Error{pos, "parser: bad indentation style for TABS + SPACES", indent.fix(src, last, pos, tabKind)}) {
+					break
+				}
 			}
 			if last.Token() == token.NL {
 				tok = token.INDENTATION
 				break
 			}
+			if last.Token() == token.INDENTATION {
+				// 合并混搭的缩进
+				last.(*ast.Text).Source += code
+			}
 			// 丢弃分隔空格
 			continue
 
 		case token.TABS:
+			// Lexer 只在行首返回 TABS
 			if last.Token() == token.INDENTATION {
-				err = errors.New("parser: bad indentation style for SPACES + TABS")
-				continue
-			}
-			if last.Token() == token.NL {
-				tok = token.INDENTATION
-				tabKind = true
-			} else {
-				// TABS 尾注释
-				code += scan.Tail(false)
-				tok = token.COMMENT
-			}
-		case token.COMMENT:
-			err = file.Push(pos, tok, code+scan.Tail(false))
-			continue
-		case token.COMMENTS:
-			// 完整块注释
-			for !scan.IsEOF() {
-				tmp, _ := scan.Symbol()
-				code += tmp
-				tok = token.Lookup(tmp)
-				if tok == token.COMMENTS {
+				if c.TabWidth == 0 && fail(&Error{pos, "parser: bad indentation style for SPACES + TABS", indent.fix(src, last, pos, tabKind)}) {
 					break
 				}
+				last.(*ast.Text).Source += code
+				continue
 			}
-			if tok != token.COMMENTS {
-				err = errors.New("parser: COMMENTS is incomplete")
-			} else {
-				err = file.Push(pos, tok, code+scan.Tail(false))
-			}
-			continue
-		case token.DOT: // MEMBER, SUGAR
-		case token.TRUE, token.FALSE:
-			tok = token.VALBOOL
-		case token.NAN, token.INFINITE:
-			tok = token.VALFLOAT
-		// case token.NULL:
-		case token.PLACEHOLDER:
-			// 识别语义, 只剩下字面值和标识符, 成员
-			if code == "\"" || code == "'" {
-				// 完整字符串
-				code += scan.EndString(code == "\"")
-				if scan.IsEOF() {
-					err = errors.New("parser: string is incomplete")
-					continue
-				}
-				tok = token.VALSTRING
-				break
-			}
-			// 整数, 浮点数, datetime
-			// ??? 缺少严格检查
-			if code[0] >= '0' && code[0] <= '9' {
-				tok = token.VALINTEGER
-				if code[0] == '0' && len(code) > 2 && (code[1] == 'x' || code[1] == 'b') {
-				} else {
-					for _, c := range code {
-						if c == '.' || c == 'e' {
-							tok = token.VALFLOAT
-						} else if c == 'T' || c == ':' || c == 'Z' {
-							tok = token.VALDATETIME
-						} else if (c < '0' || c > '9') && c != '+' && c != '-' && c != '_' {
-							tok = token.PLACEHOLDER
-							break
-						}
-					}
-				}
-			} else {
-				// 标识符, 成员
-				tok = token.IDENT
-				dot := 0
-				for _, c := range code {
-					if c == '.' {
-						dot++
-						continue
-					}
-
-					if c != '_' && !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
-						tok = token.PLACEHOLDER
-						break
-					}
-				}
-				if dot != 0 && tok == token.IDENT {
-					if dot == 1 {
-						tok = token.MEMBER
-					} else {
-						tok = token.MEMBERS
-					}
-				}
+			tok = token.INDENTATION
+			tabKind = true
+		case token.COMMENT, token.COMMENTS:
+			if c.Mode&ParseComments == 0 {
+				continue
 			}
 		}
 
-		if err == nil {
-			err = file.Push(pos, tok, code)
+		if len(errs) != 0 && len(errs) >= c.MaxErrors {
+			break
+		}
+		err = push(pos, tok, code)
+	}
+	if e, ok := err.(*ast.PushError); ok {
+		// 消息形如 parser: expected X, found Y in RULE at offset N
+		err = &Error{Pos: e.Pos, Msg: "parser: " + strings.TrimPrefix(e.Error(), "ast: ")}
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(bad) != 0 {
+		return append(bad, errs...)
+	}
+	return errs.err(c.MaxErrors)
+}
+
+// fixTabWidth 是修复混搭缩进时一个 TAB 相当的空格数
+const fixTabWidth = 4
+
+// indentFixer 是已经建议修改的缩进的开始位置, 每行的缩进只修改一次
+type indentFixer scanner.Pos
+
+// fix 返回把位于 pos 的混搭缩进所在的整行缩进统一为 tabs 或空格风格的修改.
+// last 是之前的节点, 如果是 INDENTATION 那么缩进从它开始.
+func (f *indentFixer) fix(src []byte, last ast.Node, pos scanner.Pos, tabs bool) []TextEdit {
+	start := pos
+	if text, ok := last.(*ast.Text); ok && text.Tok == token.INDENTATION {
+		start = text.Pos
+	}
+	if scanner.Pos(*f) == start {
+		return nil
+	}
+	*f = indentFixer(start)
+
+	end, width := int(start), 0
+	for ; end < len(src) && (src[end] == ' ' || src[end] == '\t'); end++ {
+		if src[end] == '\t' {
+			width += fixTabWidth - width%fixTabWidth
+		} else {
+			width++
+		}
+	}
+	text := strings.Repeat(" ", width)
+	if tabs {
+		text = strings.Repeat("\t", (width+fixTabWidth-1)/fixTabWidth)
+	}
+	return []TextEdit{{start, scanner.Pos(end), text}}
+}
+
+// unclosed 返回扫描到 eof 的字符串 code 是否未结束
+func unclosed(code string, eof bool) bool {
+	if !eof || code == "" {
+		return false
+	}
+	q := code[0]
+	if q != '\'' && q != '"' && q != '`' {
+		return false
+	}
+	return len(code) == 1 || code[len(code)-1] != q || q == '"' && !closed(code)
+}
+
+// indented 返回 src 中位于 pos 的跨行字符串 code 的后续行是否都比开始的行缩进更深, 空行除外
+func indented(src []byte, pos scanner.Pos, code string) bool {
+	line := src[bytes.LastIndexAny(src[:pos], "\r\n")+1 : pos]
+	depth := len(line) - len(bytes.TrimLeft(line, " \t"))
+	lines := strings.FieldsFunc(code, func(r rune) bool { return r == '\r' || r == '\n' })
+	for _, s := range lines[1:] {
+		if strings.TrimSpace(s) != "" && len(s)-len(strings.TrimLeft(s, " \t")) <= depth {
+			return false
 		}
 	}
-	return
+	return true
 }
@@ -0,0 +1,81 @@
+package parser_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/parser"
+)
+
+// readmeBlocks 返回 README.md 中未标注语言或者标注为 zxx 的代码块
+func readmeBlocks(t *testing.T) []string {
+	b, err := os.ReadFile("../README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blocks, lines []string
+	in, lang := false, ""
+	for _, line := range strings.Split(string(b), "\n") {
+		switch {
+		case !strings.HasPrefix(line, "```"):
+			if in {
+				lines = append(lines, line)
+			}
+		case in:
+			if lang == "" || lang == "zxx" {
+				blocks = append(blocks, strings.Join(lines, "\n")+"\n")
+			}
+			in, lines = false, nil
+		default:
+			in, lang = true, strings.TrimPrefix(line, "```")
+		}
+	}
+	return blocks
+}
+
+// unsupported 是 ParseSyntax 尚不支持的 README 代码块中的一行, 值是原因
+var unsupported = map[string]string{
+	"proc long_function_name =\n        var": "混合使用制表符和空格缩进",
+	"        '这一行只是个注释'":                     "混合使用制表符和空格缩进",
+	"var array[int] = [1,2]":                 "没有变量名",
+	"var int x = i.add 1 mul 5":              "i.add 1 mul 5 式的方法调用",
+	"T t1 = T{x:1}":                          "复合字面值",
+	"var fruit f = [color='red']":            "[color='red'] 式的属性赋值",
+	"datetime.now().add(interval)":           "调用结果的成员",
+	"pub proc walk func callback":            "函数类型的参数",
+	"\tproc {":                               "匿名过程",
+	"null x()":                               "用 null 丢弃结果",
+	"if out is null [":                       "out 用作值",
+	"name='apple',":                          "name='apple' 式的属性赋值",
+	"add as integer out as integer":          "func 分组声明的实例方法",
+}
+
+func TestReadme(t *testing.T) {
+	blocks := readmeBlocks(t)
+	if len(blocks) == 0 {
+		t.Fatal("no code blocks in README.md")
+	}
+	seen := make(map[string]bool)
+	for _, src := range blocks {
+		_, err := parser.ParseSyntax([]byte(src))
+		var line string
+		for s := range unsupported {
+			if strings.Contains(src, s) {
+				line = s
+				seen[s] = true
+			}
+		}
+		switch {
+		case line != "" && err == nil:
+			t.Errorf("%q is supported now, remove it from unsupported", line)
+		case line == "" && err != nil:
+			t.Errorf("%v\n%s", err, src)
+		}
+	}
+	for line := range unsupported {
+		if !seen[line] {
+			t.Errorf("%q is not in README.md", line)
+		}
+	}
+}
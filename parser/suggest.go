@@ -0,0 +1,93 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import "github.com/ZxxLang/zxx/token"
+
+// keywords 是声明和语句保留字, 语句开头的未知单词与它们比较
+var keywords []token.Token
+
+func init() {
+	for tok := token.Declare + 1; tok < token.Divide; tok++ {
+		if tok != token.Statement {
+			keywords = append(keywords, tok)
+		}
+	}
+}
+
+// Suggest 返回 candidates 中与 word 拼写最接近的 Token, 用于 "did you mean" 提示.
+// 距离是允许相邻字符交换的编辑距离, 超过 word 长度的三分之一 (至少为 1) 时返回 false.
+// 距离相同时取 candidates 中靠前的.
+func Suggest(word string, candidates []token.Token) (token.Token, bool) {
+	i := closest(word, len(candidates), func(i int) string { return candidates[i].String() })
+	if i < 0 {
+		return token.EOF, false
+	}
+	return candidates[i], true
+}
+
+// SuggestName 与 Suggest 相同, 但候选是名字, 例如其它包 pub 声明的名字.
+func SuggestName(word string, candidates []string) (string, bool) {
+	i := closest(word, len(candidates), func(i int) string { return candidates[i] })
+	if i < 0 {
+		return "", false
+	}
+	return candidates[i], true
+}
+
+// closest 返回 n 个候选中与 word 最接近的下标, 没有时返回 -1, name 返回第 i 个候选
+func closest(word string, n int, name func(i int) string) int {
+	limit := len(word) / 3
+	if limit == 0 {
+		limit = 1
+	}
+	best := -1
+	for i := 0; i < n; i++ {
+		s := name(i)
+		if s == word {
+			continue
+		}
+		if d := distance(word, s); d <= limit {
+			best, limit = i, d-1
+		}
+	}
+	return best
+}
+
+// distance 返回 a, b 的 optimal string alignment 距离, 以字节计算
+func distance(a, b string) int {
+	// d[i][j] 是 a[:i], b[:j] 的距离, 只保留三行
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && prev2[j-2]+1 < cur[j] {
+				cur[j] = prev2[j-2] + 1
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,991 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// ParseSyntax 把源码 src 解析为类型化的语法树, 参见 ast.Syntax.
+func ParseSyntax(src []byte) (*ast.SourceFile, error) {
+	syms, err := Fast(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSyntaxSymbols(syms)
+}
+
+// ParseSyntaxSymbols 把 syms 解析为类型化的语法树, syms 通常来自 Fast 或者 ast.ToSymbols.
+//
+// 语句以完整的一行结束, 行尾是运算符, 逗号, 赋值号或者左括号时下一行是续行.
+// 块是 [ ... ], 左方括号之前必须有空白, 以区别于下标.
+// 无法识别的声明和语句成为 *ast.BadSyntax 并继续解析, 此时返回首个错误.
+func ParseSyntaxSymbols(syms []Symbol) (*ast.SourceFile, error) {
+	p := newSyntaxParser(syms)
+	file := new(ast.SourceFile)
+	if len(syms) != 0 && syms[0].Tok == token.PLACEHOLDER {
+		file.Header = newHeader(syms[0])
+	}
+	file.Build, p.err = buildConstraint(syms)
+	for p.skipNL(); p.peek().Tok != token.EOF; p.skipNL() {
+		file.Decls = append(file.Decls, p.guard(p.decl))
+	}
+	return file, p.err
+}
+
+// newHeader 返回顶层占位 sym 开头的 shebang 行和 front-matter 块, 都没有时返回 nil.
+// 参见 Lexer 中的 header.
+func newHeader(sym Symbol) *ast.Header {
+	h := &ast.Header{From: sym.Pos}
+	src := sym.Source
+	line, rest := cutLine(src)
+	if strings.HasPrefix(line, "#!") {
+		h.Shebang = strings.TrimRight(line[2:], "\r\n")
+		src = strings.TrimLeft(rest, "\r\n")
+		line, rest = cutLine(src)
+	}
+	if isFence(line) {
+		h.HasFront = true
+		for src = rest; src != ""; {
+			line, src = cutLine(src)
+			if isFence(line) {
+				break
+			}
+			h.FrontMatter += line
+		}
+	}
+	if src == sym.Source {
+		return nil
+	}
+	h.To = sym.End() - scanner.Pos(len(src))
+	return h
+}
+
+// cutLine 返回 s 的首行, 包括换行符, 以及之后的部分
+func cutLine(s string) (line, rest string) {
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		return s[:i+1], s[i+1:]
+	}
+	return s, ""
+}
+
+// ParseDecl 把单个声明 src 解析为类型化的语法树, 用于解析合成的源码片段.
+// src 不识别顶层占位, 声明之前和之后只能有空行和注释.
+func ParseDecl(src []byte) (ast.Syntax, error) {
+	syms, err := FastExpr(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDeclSymbols(syms)
+}
+
+// ParseDeclSymbols 把 syms 解析为一个声明, syms 通常来自 FastExpr 或者 ast.ToSymbols.
+// 无法识别的声明返回 *ast.BadSyntax 和错误, 多余的 Symbol 是错误.
+func ParseDeclSymbols(syms []Symbol) (ast.Syntax, error) {
+	p := newSyntaxParser(syms)
+	if p.skipNL(); p.peek().Tok == token.EOF {
+		return nil, p.unexpected(p.peek())
+	}
+	d := p.guard(p.decl)
+	if p.skipNL(); p.err == nil && p.peek().Tok != token.EOF {
+		p.err = p.unexpected(p.peek())
+	}
+	return d, p.err
+}
+
+// newSyntaxParser 返回解析 syms 的 syntaxParser
+func newSyntaxParser(syms []Symbol) *syntaxParser {
+	p := &syntaxParser{syms: make([]Symbol, 0, len(syms))}
+	for _, sym := range syms {
+		if sym.Tok == token.NL || sym.Tok != token.EOF && !sym.Tok.Is(token.ClassTrivia) {
+			p.syms = append(p.syms, sym)
+		}
+	}
+	if n := len(syms); n != 0 {
+		p.end = syms[n-1].Pos.Offset(len(syms[n-1].Source))
+	}
+	return p
+}
+
+// syntaxParser 是语句和声明的递归下降解析器, 表达式由 ParseExprSymbols 解析
+type syntaxParser struct {
+	syms []Symbol // 不包括 NL 以外的非语义 Symbol
+	i    int
+	end  scanner.Pos
+	err  error // 首个错误
+}
+
+func (p *syntaxParser) peek() Symbol {
+	if p.i < len(p.syms) {
+		return p.syms[p.i]
+	}
+	return Symbol{Pos: p.end, Tok: token.EOF}
+}
+
+func (p *syntaxParser) next() Symbol {
+	sym := p.peek()
+	if p.i < len(p.syms) {
+		p.i++
+	}
+	return sym
+}
+
+func (p *syntaxParser) skipNL() {
+	for p.peek().Tok == token.NL {
+		p.i++
+	}
+}
+
+// unexpected 返回遇到 sym 的错误, sym 是拼错的 expected 中的保留字时提示正确的拼写.
+func (p *syntaxParser) unexpected(sym Symbol, expected ...token.Token) error {
+	if sym.Tok == token.EOF {
+		return errors.New("parser: unexpected EOF at offset " + strconv.Itoa(int(sym.Pos)))
+	}
+	msg := "parser: unexpected " + sym.Tok.String() + " '" + sym.Source + "' at offset " + strconv.Itoa(int(sym.Pos))
+	if tok, ok := Suggest(sym.Source, expected); ok && sym.Tok == token.IDENT {
+		msg += ", did you mean '" + tok.String() + "'?"
+	}
+	return errors.New(msg)
+}
+
+// misspelled 返回从 start 开始的语句是否以拼错的保留字开头, 例如 fnuc f [, 是时返回提示.
+// 只检查之后还有 IDENT 的单词, 以免把调用或赋值当做保留字.
+func (p *syntaxParser) misspelled(start int) error {
+	if start+1 >= len(p.syms) || p.syms[start].Tok != token.IDENT || p.syms[start+1].Tok != token.IDENT {
+		return nil
+	}
+	sym := p.syms[start]
+	if tok, ok := Suggest(sym.Source, keywords); ok {
+		return errors.New("parser: unknown keyword '" + sym.Source + "' at offset " + strconv.Itoa(int(sym.Pos)) + ", did you mean '" + tok.String() + "'?")
+	}
+	return nil
+}
+
+// guard 调用 parse 解析一个声明或语句, 之后必须是行尾.
+// 出错时跳过整个语句, 返回 *ast.BadSyntax.
+func (p *syntaxParser) guard(parse func() (ast.Syntax, error)) ast.Syntax {
+	start := p.i
+	x, err := parse()
+	if _, ok := x.(*ast.CaseClause); err == nil && !ok {
+		if sym := p.peek(); sym.Tok != token.NL && sym.Tok != token.EOF && !isRight(sym, "") {
+			var expected []token.Token
+			if s, ok := x.(*ast.IfStmt); ok && s.Else == nil {
+				expected = append(expected, token.ELSE)
+			}
+			err = p.unexpected(sym, expected...)
+		}
+	}
+	if err == nil {
+		return x
+	}
+	if e := p.misspelled(start); e != nil {
+		err = e
+	}
+	if p.err == nil {
+		p.err = err
+	}
+	p.i = start
+	p.i = p.stmtEnd()
+	if p.i == start {
+		p.i++
+	}
+	bad := &ast.BadSyntax{From: p.syms[start].Pos, To: p.end}
+	if p.i < len(p.syms) {
+		last := p.syms[p.i-1]
+		bad.To = last.Pos.Offset(len(last.Source))
+	}
+	return bad
+}
+
+// stmtEnd 返回从当前位置开始的语句之后的序号, 即行尾的 NL, 未配对的右括号或者 EOF
+func (p *syntaxParser) stmtEnd() int {
+	depth := 0
+	for i := p.i; i < len(p.syms); i++ {
+		switch tok := p.syms[i].Tok; tok {
+		case token.LEFT:
+			depth++
+		case token.RIGHT:
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case token.NL:
+			if depth == 0 && (i == 0 || !continued(p.syms[i-1].Tok)) {
+				return i
+			}
+		}
+	}
+	return len(p.syms)
+}
+
+// continued 返回行尾的 tok 之后是否是续行
+func continued(tok token.Token) bool {
+	return tok == token.LEFT || tok == token.COMMA || tok == token.DOT ||
+		tok == token.ASSIGN || tok.As(token.Operator) || tok.As(token.Declare)
+}
+
+// blockStart 返回从当前位置开始, 语句之内首个块的 '[' 或者 '{' 的序号, 没有时返回 -1
+func (p *syntaxParser) blockStart() int {
+	depth, end := 0, p.stmtEnd()
+	for i := p.i; i < end; i++ {
+		sym := p.syms[i]
+		switch sym.Tok {
+		case token.LEFT:
+			if depth == 0 && p.isBlock(i) {
+				return i
+			}
+			depth++
+		case token.RIGHT:
+			depth--
+		}
+	}
+	return -1
+}
+
+// isBlock 返回 syms[i] 是否可以开始块或者分组, 即 '{' 或者之前有空白的 '[',
+// 紧随前一个 Symbol 的 '[' 是下标或者类型参数
+func (p *syntaxParser) isBlock(i int) bool {
+	sym := p.syms[i]
+	if sym.Tok != token.LEFT {
+		return false
+	}
+	return sym.Source == "{" || sym.Source == "[" && (i == 0 || p.syms[i-1].Pos.Offset(len(p.syms[i-1].Source)) != sym.Pos)
+}
+
+// closer 返回左括号 left 对应的右括号
+func closer(left string) string {
+	switch left {
+	case "(":
+		return ")"
+	case "{":
+		return "}"
+	}
+	return "]"
+}
+
+func isRight(sym Symbol, source string) bool {
+	return sym.Tok == token.RIGHT && (source == "" || sym.Source == source)
+}
+
+func (p *syntaxParser) decl() (ast.Syntax, error) {
+	pub := scanner.Pos(-1)
+	if p.peek().Tok == token.PUB {
+		pub = p.next().Pos
+	}
+	switch p.peek().Tok {
+	case token.USE, token.CONST, token.STATIC, token.VAR:
+		return p.genDecl(pub)
+	case token.TYPE:
+		return p.typeDecl(pub)
+	case token.FUNC, token.PROC:
+		return p.funcDecl(pub)
+	}
+	return nil, p.unexpected(p.peek())
+}
+
+// genDecl 解析 use, const, static, var 声明. 分组可以用 (...), [...] 或者 {...},
+// 之前可以有各项共用的类型, 例如 var datetime ( ... ), var array[int] ( ... ).
+func (p *syntaxParser) genDecl(pub scanner.Pos) (ast.Syntax, error) {
+	kw := p.next()
+	d := &ast.GenDecl{Pub: pub, TokPos: kw.Pos, Tok: kw.Tok}
+	if kw.Tok != token.USE && p.i < len(p.syms) {
+		if end := typeEnd(p.syms, p.i); end != p.i && p.groupStart(end) {
+			d.Type = p.syms[p.i:end]
+			p.i = end
+		}
+	}
+	if !p.groupStart(p.i) {
+		var err error
+		d.Specs, err = p.specs(kw.Tok, p.stmtEnd())
+		return d, err
+	}
+
+	left := p.next()
+	d.Lparen = left.Pos
+	right := closer(left.Source)
+	for p.skipSep(kw.Tok); !isRight(p.peek(), right); p.skipSep(kw.Tok) {
+		if sym := p.peek(); sym.Tok == token.EOF || sym.Tok == token.RIGHT {
+			return d, p.unexpected(sym)
+		}
+		specs, err := p.specs(kw.Tok, p.itemEnd(kw.Tok))
+		d.Specs = append(d.Specs, specs...)
+		if err != nil {
+			return d, err
+		}
+	}
+	d.Rparen = p.next().Pos
+	return d, nil
+}
+
+// groupStart 返回 syms[i] 是否开始分组, 即 '(', '{' 或者之前有空白的 '['
+func (p *syntaxParser) groupStart(i int) bool {
+	if i >= len(p.syms) {
+		return false
+	}
+	sym := p.syms[i]
+	return sym.Tok == token.LEFT && (sym.Source == "(" || p.isBlock(i))
+}
+
+// isTypeStart 返回 tok 是否可以开始类型, 即类型保留字, 名字, 成员或者 func, proc
+func isTypeStart(tok token.Token) bool {
+	return tok.As(token.Type) || tok == token.IDENT || tok == token.MEMBER || tok == token.FUNC || tok == token.PROC
+}
+
+// skipSep 跳过分组中声明项之间的换行和 ';', use 声明还有 ','
+func (p *syntaxParser) skipSep(tok token.Token) {
+	for sym := p.peek(); sym.Tok == token.NL || sym.Tok == token.SEMICOLON || tok == token.USE && sym.Tok == token.COMMA; sym = p.peek() {
+		p.i++
+	}
+}
+
+// itemEnd 返回分组中从当前位置开始的声明项之后的序号, 即行尾, 括号之外的 ';',
+// use 声明还有 ','
+func (p *syntaxParser) itemEnd(tok token.Token) int {
+	end := p.stmtEnd()
+	syms := p.syms[p.i:end]
+	if i := find(syms, token.SEMICOLON); i != -1 {
+		syms = syms[:i]
+	}
+	if tok == token.USE {
+		if i := find(syms, token.COMMA); i != -1 {
+			syms = syms[:i]
+		}
+	}
+	return p.i + len(syms)
+}
+
+// specs 解析直到 syms[end] 的声明项. use 声明项是 [alias] 'path',
+// 其它声明的一行可以有多项, 参见 splitSpecs.
+func (p *syntaxParser) specs(tok token.Token, end int) ([]*ast.ValueSpec, error) {
+	syms := p.syms[p.i:end]
+	p.i = end
+	if len(syms) == 0 {
+		return nil, p.unexpected(p.peek())
+	}
+
+	if tok == token.USE {
+		spec := new(ast.ValueSpec)
+		for _, sym := range syms {
+			switch {
+			case sym.Tok == token.IDENT && len(spec.Names) == 0 && len(spec.Values) == 0:
+				spec.Names = append(spec.Names, &ast.Ident{Name: sym})
+			case sym.Tok == token.VALSTRING && len(spec.Values) == 0:
+				spec.Values = append(spec.Values, &ast.BasicLit{Value: sym})
+			default:
+				return []*ast.ValueSpec{spec}, p.unexpected(sym)
+			}
+		}
+		if len(spec.Values) == 0 {
+			return []*ast.ValueSpec{spec}, p.unexpected(p.peek())
+		}
+		return []*ast.ValueSpec{spec}, nil
+	}
+
+	var list []*ast.ValueSpec
+	for _, part := range splitSpecs(syms) {
+		spec, err := p.spec(part)
+		list = append(list, spec)
+		if err != nil {
+			return list, err
+		}
+	}
+	return list, nil
+}
+
+// splitSpecs 按括号之外的逗号把一行声明分为声明项. 逗号之后是类型和名字,
+// 或者已有初值之后又是名字和初值时开始新的声明项, 否则逗号分隔名字或者初值:
+//
+//	int a, string b      两项
+//	day = 1, now = 2     两项
+//	int z, i = 4         一项, 名字是 z, i
+//	b, c = 2, 3          一项
+func splitSpecs(syms []Symbol) [][]Symbol {
+	var list [][]Symbol
+	start, seg, assigned := 0, 0, false
+	for _, part := range split(syms, token.COMMA) {
+		eq := find(part, token.ASSIGN)
+		lhs := len(part)
+		if eq != -1 {
+			lhs = eq
+		}
+		if seg > start && (assigned && eq != -1 || !assigned && lhs > 1) {
+			list = append(list, syms[start:seg-1])
+			start, assigned = seg, false
+		}
+		assigned = assigned || eq != -1
+		seg += len(part) + 1
+	}
+	return append(list, syms[start:])
+}
+
+// spec 解析一个声明项 syms, 即 [Type] Names [= Values]
+func (p *syntaxParser) spec(syms []Symbol) (*ast.ValueSpec, error) {
+	spec := new(ast.ValueSpec)
+	if len(syms) == 0 {
+		return spec, p.unexpected(p.peek())
+	}
+	lhs, rhs := syms, []Symbol(nil)
+	if i := find(syms, token.ASSIGN); i != -1 {
+		lhs, rhs = syms[:i], syms[i+1:]
+		var err error
+		if spec.Values, err = exprList(rhs, syms[i]); err != nil {
+			return spec, err
+		}
+	}
+	// 名字是末尾逗号分隔的 IDENT, 之前是类型
+	i := len(lhs)
+	for i != 0 && lhs[i-1].Tok == token.IDENT {
+		spec.Names = append([]*ast.Ident{{Name: lhs[i-1]}}, spec.Names...)
+		if i--; i == 0 || lhs[i-1].Tok != token.COMMA {
+			break
+		}
+		i--
+	}
+	if len(spec.Names) == 0 {
+		if i == 0 {
+			return spec, p.unexpected(syms[0])
+		}
+		return spec, p.unexpected(lhs[i-1])
+	}
+	spec.Type = lhs[:i]
+	return spec, nil
+}
+
+// funcDecl 解析 func, proc 声明. 参数表可以是 (Type Name, ...), 也可以不带括号,
+// 例如 proc hello string word [, 参见 fields. out 之后是结果, 函数体是块.
+// 结果类型也可以写在名字之前, 此时参数表带括号, 例如 func int f(int a, int b).
+// proc 的名字可以是类型方法, 例如 proc root.count.
+func (p *syntaxParser) funcDecl(pub scanner.Pos) (ast.Syntax, error) {
+	kw := p.next()
+	d := &ast.FuncDecl{Pub: pub, TokPos: kw.Pos, Tok: kw.Tok}
+	if p.i < len(p.syms) {
+		if end := typeEnd(p.syms, p.i); end != p.i && end+1 < len(p.syms) && p.syms[end].Tok == token.IDENT &&
+			p.syms[end+1].Source == "(" && p.syms[end+1].Pos == p.syms[end].Pos.Offset(len(p.syms[end].Source)) {
+			d.Results = p.syms[p.i:end]
+			p.i = end
+		}
+	}
+	name := p.next()
+	if name.Tok != token.IDENT && (kw.Tok != token.PROC || name.Tok != token.MEMBER) {
+		return d, p.unexpected(name)
+	}
+	d.Name = &ast.Ident{Name: name}
+	var err error
+	if d.TypeParams, err = p.typeParams(name); err != nil {
+		return d, err
+	}
+
+	end := p.blockStart()
+	if end == -1 {
+		end = p.stmtEnd()
+	}
+	if left := p.peek(); left.Tok == token.LEFT && left.Source == "(" {
+		if d.Lparen, d.Params, d.Rparen, err = p.params(); err != nil {
+			return d, err
+		}
+	} else {
+		out := p.i + len(p.syms[p.i:end])
+		if i := find(p.syms[p.i:end], token.OUT); i != -1 {
+			out = p.i + i
+		}
+		if d.Params, err = fields(p.syms[p.i:out], kw.Tok == token.PROC); err != nil {
+			return d, err
+		}
+		p.i = out
+	}
+
+	if p.peek().Tok == token.OUT && len(d.Results) == 0 {
+		p.next()
+		d.Results = p.syms[p.i:end]
+		p.i = end
+	}
+
+	if p.i < len(p.syms) && p.isBlock(p.i) {
+		d.Body, err = p.block()
+		return d, err
+	}
+	return d, nil
+}
+
+// fields 解析不带括号的参数表 syms, 例如 proc 的 int a, b bool c, 之间可以有逗号.
+// named 为 false 时每个参数只有类型, 例如 func 的 int string bool.
+func fields(syms []Symbol, named bool) ([]*ast.Field, error) {
+	var list []*ast.Field
+	for i := 0; i < len(syms); {
+		if syms[i].Tok == token.COMMA {
+			i++
+			continue
+		}
+		end := typeEnd(syms, i)
+		if end == i {
+			return list, errors.New("parser: bad parameter at offset " + strconv.Itoa(int(syms[i].Pos)))
+		}
+		typ := syms[i:end]
+		if i = end; !named {
+			list = append(list, &ast.Field{Type: typ})
+			continue
+		}
+		if i == len(syms) || syms[i].Tok != token.IDENT {
+			return list, errors.New("parser: missing parameter name at offset " + strconv.Itoa(int(typ[0].Pos)))
+		}
+		list = append(list, &ast.Field{Type: typ, Name: &ast.Ident{Name: syms[i]}})
+		// 逗号之后的名字共用类型, 之后还有名字时它是下一个参数的类型
+		for i++; i+1 < len(syms) && syms[i].Tok == token.COMMA && syms[i+1].Tok == token.IDENT && typeEnd(syms, i+1) == i+2 &&
+			(i+2 == len(syms) || syms[i+2].Tok != token.IDENT); i += 2 {
+			list = append(list, &ast.Field{Type: typ, Name: &ast.Ident{Name: syms[i+1]}})
+		}
+	}
+	return list, nil
+}
+
+// typeEnd 返回从 syms[i] 开始的类型之后的序号, 类型是一个 Token 和紧随的 [...],
+// 例如 int, T, list[int]. syms[i] 不能开始类型时返回 i.
+func typeEnd(syms []Symbol, i int) int {
+	if !isTypeStart(syms[i].Tok) {
+		return i
+	}
+	end := i + 1
+	if end < len(syms) && syms[end].Tok == token.LEFT && syms[end].Source == "[" && syms[end].Pos == syms[i].Pos.Offset(len(syms[i].Source)) {
+		depth := 0
+		for ; end < len(syms); end++ {
+			switch syms[end].Tok {
+			case token.LEFT:
+				depth++
+			case token.RIGHT:
+				depth--
+			}
+			if depth == 0 {
+				return end + 1
+			}
+		}
+	}
+	return end
+}
+
+// typeDecl 解析 type Name Type, type Name [ 字段 ] 或者 type Name enum [ 变体 ], 块也可以写作 { }.
+// 字段以换行或者 ';' 分隔, 变体每行一个. enum 只在之后是块时是保留字
+func (p *syntaxParser) typeDecl(pub scanner.Pos) (ast.Syntax, error) {
+	kw := p.next()
+	d := &ast.TypeDecl{Pub: pub, TokPos: kw.Pos}
+	name := p.next()
+	if name.Tok != token.IDENT {
+		return d, p.unexpected(name)
+	}
+	d.Name = &ast.Ident{Name: name}
+	var err error
+	if d.TypeParams, err = p.typeParams(name); err != nil {
+		return d, err
+	}
+
+	if p.enumStart() {
+		d.Enum = p.next().Pos
+		left := p.next()
+		d.Lbrack = left.Pos
+		right := closer(left.Source)
+		for p.skipNL(); !isRight(p.peek(), right); p.skipNL() {
+			v, err := p.variant()
+			if err != nil {
+				return d, err
+			}
+			d.Variants = append(d.Variants, v)
+		}
+		d.Rbrack = p.next().Pos
+		return d, nil
+	}
+	if p.i < len(p.syms) && p.isBlock(p.i) {
+		left := p.next()
+		d.Lbrack = left.Pos
+		right := closer(left.Source)
+		for p.skipSep(token.VAR); !isRight(p.peek(), right); p.skipSep(token.VAR) {
+			if sym := p.peek(); sym.Tok == token.EOF || sym.Tok == token.RIGHT {
+				return d, p.unexpected(sym)
+			}
+			end := p.itemEnd(token.VAR)
+			if typeEnd(p.syms, p.i) == end {
+				// 匿名字段只有类型, 例如 array[int]
+				d.Fields = append(d.Fields, &ast.ValueSpec{Type: p.syms[p.i:end]})
+				p.i = end
+				continue
+			}
+			specs, err := p.specs(token.VAR, end)
+			d.Fields = append(d.Fields, specs...)
+			if err != nil {
+				return d, err
+			}
+		}
+		d.Rbrack = p.next().Pos
+		return d, nil
+	}
+	d.Type = p.syms[p.i:p.stmtEnd()]
+	if len(d.Type) == 0 {
+		return d, p.unexpected(p.peek())
+	}
+	p.i += len(d.Type)
+	return d, nil
+}
+
+// enumStart 返回当前位置是否是 enum 和之后的块 [ 或者 {, 而不是名为 enum 的类型
+func (p *syntaxParser) enumStart() bool {
+	if sym := p.peek(); sym.Tok != token.IDENT || sym.Source != "enum" || p.i+1 >= len(p.syms) {
+		return false
+	}
+	return p.isBlock(p.i + 1)
+}
+
+// variant 解析一行枚举变体 Name 或者 Name(Type Name, ...)
+func (p *syntaxParser) variant() (*ast.Variant, error) {
+	name := p.next()
+	if name.Tok != token.IDENT {
+		return nil, p.unexpected(name)
+	}
+	v := &ast.Variant{Name: &ast.Ident{Name: name}}
+	if left := p.peek(); left.Tok == token.LEFT && left.Source == "(" {
+		var err error
+		if v.Lparen, v.Params, v.Rparen, err = p.params(); err != nil {
+			return v, err
+		}
+	}
+	if sym := p.peek(); sym.Tok != token.NL && !isRight(sym, "") {
+		return v, p.unexpected(sym)
+	}
+	return v, nil
+}
+
+// params 解析参数表 (Type Name, ...), 返回括号的位置
+func (p *syntaxParser) params() (lparen scanner.Pos, list []*ast.Field, rparen scanner.Pos, err error) {
+	lparen = p.next().Pos
+	start := p.i
+	close := p.stmtEnd()
+	if close == len(p.syms) || !isRight(p.syms[close], ")") {
+		p.i = close
+		return lparen, nil, 0, p.unexpected(p.peek())
+	}
+	for _, part := range split(p.syms[start:close], token.COMMA) {
+		if len(part) == 0 || part[len(part)-1].Tok != token.IDENT {
+			return lparen, list, 0, errors.New("parser: bad parameter at offset " + strconv.Itoa(int(lparen)))
+		}
+		n := len(part) - 1
+		list = append(list, &ast.Field{Type: part[:n], Name: &ast.Ident{Name: part[n]}})
+	}
+	p.i = close
+	return lparen, list, p.next().Pos, nil
+}
+
+// typeParams 解析紧随 name 的类型参数 [Constraint Name, ...], 没有时返回 nil
+func (p *syntaxParser) typeParams(name Symbol) (*ast.TypeParams, error) {
+	left := p.peek()
+	if left.Tok != token.LEFT || left.Source != "[" || left.Pos != name.Pos.Offset(len(name.Source)) {
+		return nil, nil
+	}
+	p.next()
+	tp := &ast.TypeParams{Lbrack: left.Pos}
+	start := p.i
+	close := p.stmtEnd()
+	if close == len(p.syms) || !isRight(p.syms[close], "]") {
+		p.i = close
+		return tp, p.unexpected(p.peek())
+	}
+	for _, part := range split(p.syms[start:close], token.COMMA) {
+		if len(part) == 0 || part[len(part)-1].Tok != token.IDENT {
+			return tp, errors.New("parser: bad type parameter at offset " + strconv.Itoa(int(left.Pos)))
+		}
+		n := len(part) - 1
+		tp.List = append(tp.List, &ast.Field{Type: part[:n], Name: &ast.Ident{Name: part[n]}})
+	}
+	if len(tp.List) == 0 {
+		return tp, errors.New("parser: empty type parameter list at offset " + strconv.Itoa(int(left.Pos)))
+	}
+	p.i = close
+	tp.Rbrack = p.next().Pos
+	return tp, nil
+}
+
+// block 解析 [ 语句 ] 或者 { 语句 }
+func (p *syntaxParser) block() (*ast.BlockStmt, error) {
+	left := p.next()
+	if left.Tok != token.LEFT || left.Source != "[" && left.Source != "{" {
+		return nil, p.unexpected(left)
+	}
+	b := &ast.BlockStmt{Lbrack: left.Pos}
+	right := closer(left.Source)
+	for p.skipNL(); !isRight(p.peek(), right); p.skipNL() {
+		if sym := p.peek(); sym.Tok == token.EOF || sym.Tok == token.RIGHT {
+			return b, p.unexpected(sym)
+		}
+		b.List = append(b.List, p.guard(p.stmt))
+	}
+	b.Rbrack = p.next().Pos
+	return b, nil
+}
+
+func (p *syntaxParser) stmt() (ast.Syntax, error) {
+	sym := p.peek()
+	switch tok := sym.Tok; {
+	case tok.As(token.Declare):
+		return p.decl()
+	case tok == token.IF:
+		return p.ifStmt()
+	case tok == token.FOR:
+		return p.forStmt()
+	case tok == token.SWITCH:
+		p.next()
+		s := &ast.SwitchStmt{Switch: sym.Pos}
+		tag, err := p.header()
+		if err == nil && len(tag) != 0 {
+			s.Tag, err = ParseExprSymbols(tag)
+		}
+		if err == nil {
+			s.Body, err = p.block()
+		}
+		return s, err
+	case tok == token.CASE, tok == token.DEFAULT:
+		p.next()
+		c := &ast.CaseClause{TokPos: sym.Pos, Tok: tok}
+		syms := p.syms[p.i:p.stmtEnd()]
+		colon := find(syms, token.COLON)
+		if colon == -1 {
+			return c, errors.New("parser: missing ':' after " + tok.String() + " at offset " + strconv.Itoa(int(sym.Pos)))
+		}
+		var err error
+		if colon != 0 {
+			c.List, err = exprList(syms[:colon], sym)
+		}
+		c.Colon = syms[colon].Pos
+		p.i += colon + 1
+		return c, err
+	case tok == token.BREAK, tok == token.CONTINUE, tok == token.GOTO:
+		p.next()
+		s := &ast.BranchStmt{TokPos: sym.Pos, Tok: tok}
+		if p.peek().Tok == token.IDENT {
+			s.Label = &ast.Ident{Name: p.next()}
+		}
+		return s, nil
+	case tok == token.GO, tok == token.DEFER:
+		p.next()
+		s := &ast.GoStmt{TokPos: sym.Pos, Tok: tok}
+		var err error
+		s.Call, err = ParseExprSymbols(p.rest())
+		if _, ok := s.Call.(*ast.CallExpr); err == nil && !ok {
+			err = errors.New("parser: " + tok.String() + " requires a call at offset " + strconv.Itoa(int(sym.Pos)))
+		}
+		return s, err
+	case tok == token.OUT:
+		p.next()
+		s := &ast.OutStmt{Out: sym.Pos}
+		var err error
+		if rest := p.rest(); len(rest) != 0 {
+			s.Results, err = exprList(rest, sym)
+		}
+		return s, err
+	}
+	return simple(p.rest())
+}
+
+// rest 返回并消费当前语句剩余的 Symbol
+func (p *syntaxParser) rest() []Symbol {
+	end := p.stmtEnd()
+	syms := p.syms[p.i:end]
+	p.i = end
+	return syms
+}
+
+// header 返回并消费 if, for, switch 之后直到块的 Symbol
+func (p *syntaxParser) header() ([]Symbol, error) {
+	end := p.blockStart()
+	if end == -1 {
+		p.i = p.stmtEnd()
+		return nil, errors.New("parser: missing block at offset " + strconv.Itoa(int(p.peek().Pos)))
+	}
+	syms := p.syms[p.i:end]
+	p.i = end
+	return syms, nil
+}
+
+func (p *syntaxParser) ifStmt() (ast.Syntax, error) {
+	s := &ast.IfStmt{If: p.next().Pos}
+	cond, err := p.header()
+	if err != nil {
+		return s, err
+	}
+	if len(cond) == 0 {
+		return s, p.unexpected(p.peek())
+	}
+	if s.Cond, err = ParseExprSymbols(cond); err != nil {
+		return s, err
+	}
+	if s.Body, err = p.block(); err != nil {
+		return s, err
+	}
+	if p.peek().Tok == token.ELSE {
+		p.next()
+		if p.peek().Tok == token.IF {
+			s.Else, err = p.ifStmt()
+		} else {
+			s.Else, err = p.block()
+		}
+	}
+	return s, err
+}
+
+func (p *syntaxParser) forStmt() (ast.Syntax, error) {
+	s := &ast.ForStmt{For: p.next().Pos}
+	head, err := p.header()
+	if err != nil {
+		return s, err
+	}
+	if i := rangeAs(head); i != -1 {
+		return p.rangeStmt(s.For, head, i)
+	}
+	parts := split(head, token.SEMICOLON)
+	switch len(parts) {
+	case 1:
+	case 3:
+		if len(parts[0]) != 0 {
+			if s.Init, err = simple(parts[0]); err != nil {
+				return s, err
+			}
+		}
+		if len(parts[2]) != 0 {
+			if s.Post, err = simple(parts[2]); err != nil {
+				return s, err
+			}
+		}
+		parts = parts[1:2]
+	default:
+		return s, errors.New("parser: bad for clause at offset " + strconv.Itoa(int(s.For)))
+	}
+	if len(parts[0]) != 0 {
+		if s.Cond, err = ParseExprSymbols(parts[0]); err != nil {
+			return s, err
+		}
+	}
+	s.Body, err = p.block()
+	return s, err
+}
+
+// rangeAs 返回 for 头部 X as Key Value 中 as 的序号, 不是这种写法时返回 -1.
+// as 不是保留字, 之后必须是一到两个名字.
+func rangeAs(head []Symbol) int {
+	for i := len(head) - 2; i > 0 && i >= len(head)-3; i-- {
+		if head[i].Tok == token.IDENT && head[i].Source == "as" {
+			for _, sym := range head[i+1:] {
+				if sym.Tok != token.IDENT {
+					return -1
+				}
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *syntaxParser) rangeStmt(pos scanner.Pos, head []Symbol, as int) (ast.Syntax, error) {
+	s := &ast.RangeStmt{For: pos, As: head[as].Pos, Key: &ast.Ident{Name: head[as+1]}}
+	if as+2 < len(head) {
+		s.Value = &ast.Ident{Name: head[as+2]}
+	}
+	var err error
+	if s.X, err = ParseExprSymbols(head[:as]); err != nil {
+		return s, err
+	}
+	s.Body, err = p.block()
+	return s, err
+}
+
+// simple 把 syms 解析为赋值语句或者表达式语句
+func simple(syms []Symbol) (ast.Syntax, error) {
+	if n := len(syms); n > 1 && (syms[n-1].Tok == token.INC || syms[n-1].Tok == token.DEC) {
+		lhs, err := exprList(syms[:n-1], syms[n-1])
+		return &ast.AssignStmt{Lhs: lhs, TokPos: syms[n-1].Pos, Tok: syms[n-1].Tok}, err
+	}
+	if i := find(syms, token.ASSIGN); i > 0 {
+		s := &ast.AssignStmt{TokPos: syms[i].Pos, Tok: token.ASSIGN}
+		var err error
+		if s.Lhs, err = exprList(syms[:i], syms[i]); err == nil {
+			s.Rhs, err = exprList(syms[i+1:], syms[i])
+		}
+		return s, err
+	}
+	x, err := parseExpr(syms, isCommand(syms))
+	return &ast.ExprStmt{X: x}, err
+}
+
+// isCommand 返回 syms 是否是不带括号的命令调用, 即名字之后是字面值, 名字或者之前有空白的 '(',
+// 例如 echo 'hello ' word. 与保留字拼写相近的名字不是命令, 以便提示拼错的保留字.
+func isCommand(syms []Symbol) bool {
+	if len(syms) < 2 || syms[0].Tok != token.IDENT && syms[0].Tok != token.MEMBER {
+		return false
+	}
+	switch arg := syms[1]; arg.Tok {
+	case token.IDENT:
+		if _, ok := Suggest(syms[0].Source, keywords); ok {
+			return false
+		}
+	case token.NULL, token.TRUE, token.FALSE, token.NAN, token.INFINITE, token.STRINGLIT,
+		token.VALSTRING, token.VALINTEGER, token.VALFLOAT, token.VALBOOL, token.VALDATETIME:
+	case token.LEFT:
+		if arg.Source != "(" || arg.Pos == syms[0].Pos.Offset(len(syms[0].Source)) {
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// exprList 解析逗号分隔的表达式, at 用于报告空列表的位置
+func exprList(syms []Symbol, at Symbol) ([]ast.Expression, error) {
+	var list []ast.Expression
+	for _, part := range split(syms, token.COMMA) {
+		if len(part) == 0 {
+			return list, errors.New("parser: missing expression after " + at.Tok.String() + " at offset " + strconv.Itoa(int(at.Pos)))
+		}
+		x, err := ParseExprSymbols(part)
+		if err != nil {
+			return list, err
+		}
+		list = append(list, x)
+	}
+	return list, nil
+}
+
+// find 返回 syms 中不在括号之内的首个 tok 的序号, 没有时返回 -1
+func find(syms []Symbol, tok token.Token) int {
+	depth := 0
+	for i, sym := range syms {
+		switch sym.Tok {
+		case token.LEFT, token.INTERPBEGIN:
+			depth++
+		case token.RIGHT, token.INTERPEND:
+			depth--
+		case tok:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// split 按不在括号之内的 sep 分割 syms, syms 为空时返回 nil
+func split(syms []Symbol, sep token.Token) (parts [][]Symbol) {
+	if len(syms) == 0 {
+		return nil
+	}
+	for {
+		i := find(syms, sep)
+		if i == -1 {
+			return append(parts, syms)
+		}
+		parts = append(parts, syms[:i])
+		syms = syms[i+1:]
+	}
+}
@@ -0,0 +1,447 @@
+package parser_test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/token"
+)
+
+const syntaxSrc = `use fmt 'fmt'
+pub const (
+	a = 1
+	b, c = 2, 3
+)
+var int x = f(1,
+	2)
+proc main(int n, m) out int [
+	if n > 1 [
+		x = n + 1
+	] else if n < 0 [
+		out 0
+	] else [
+		x++
+	]
+	for i = 0; i < n; i++ [
+		fmt.print(i)
+		break
+	]
+	switch n [
+	case a, b: out 1
+	default:
+		out n
+	]
+	out n
+]
+`
+
+func TestParseSyntax(t *testing.T) {
+	src := []byte(syntaxSrc)
+	file, err := parser.ParseSyntax(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Decls) != 4 || file.Pos() != 0 || int(file.End()) != len(src)-1 {
+		t.Fatal(len(file.Decls), file.Pos(), file.End())
+	}
+	text := func(x ast.Syntax) string { return string(src[x.Pos():x.End()]) }
+
+	use := file.Decls[0].(*ast.GenDecl)
+	if use.Tok != token.USE || use.Specs[0].Names[0].Name.Source != "fmt" || text(use.Specs[0].Values[0]) != "'fmt'" {
+		t.Fatal(text(use))
+	}
+	consts := file.Decls[1].(*ast.GenDecl)
+	if consts.Pub != 14 || len(consts.Specs) != 2 || len(consts.Specs[1].Names) != 2 || text(consts.Specs[1]) != "b, c = 2, 3" {
+		t.Fatal(text(consts))
+	}
+	x := file.Decls[2].(*ast.GenDecl).Specs[0]
+	if len(x.Type) != 1 || x.Type[0].Tok != token.INT || text(x) != "int x = f(1,\n\t2)" {
+		t.Fatal(text(x))
+	}
+
+	main := file.Decls[3].(*ast.FuncDecl)
+	if main.Name.Name.Source != "main" || len(main.Params) != 2 || len(main.Params[1].Type) != 0 ||
+		len(main.Results) != 1 || len(main.Body.List) != 4 {
+		t.Fatal(text(main))
+	}
+	ifs := main.Body.List[0].(*ast.IfStmt)
+	if text(ifs.Cond) != "n > 1" || ifs.Body.List[0].(*ast.AssignStmt).Tok != token.ASSIGN {
+		t.Fatal(text(ifs))
+	}
+	elif := ifs.Else.(*ast.IfStmt)
+	if _, ok := elif.Body.List[0].(*ast.OutStmt); !ok || elif.Else.(*ast.BlockStmt).List[0].(*ast.AssignStmt).Tok != token.INC {
+		t.Fatal(text(elif))
+	}
+	loop := main.Body.List[1].(*ast.ForStmt)
+	if text(loop.Init) != "i = 0" || text(loop.Cond) != "i < n" || text(loop.Post) != "i++" ||
+		loop.Body.List[1].(*ast.BranchStmt).Tok != token.BREAK {
+		t.Fatal(text(loop))
+	}
+	if _, ok := loop.Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr); !ok {
+		t.Fatal(text(loop.Body.List[0]))
+	}
+	sw := main.Body.List[2].(*ast.SwitchStmt)
+	if len(sw.Body.List) != 4 || len(sw.Body.List[0].(*ast.CaseClause).List) != 2 || sw.Body.List[2].(*ast.CaseClause).Tok != token.DEFAULT {
+		t.Fatal(text(sw))
+	}
+}
+
+// decl 以紧凑的形式输出声明的结构
+func decl(src string, d ast.Syntax) string {
+	join := func(list []ast.Symbol) string {
+		var ss []string
+		for _, sym := range list {
+			ss = append(ss, sym.Source)
+		}
+		return strings.Join(ss, " ")
+	}
+	specs := func(list []*ast.ValueSpec) (s string) {
+		for _, spec := range list {
+			var names, values []string
+			for _, id := range spec.Names {
+				names = append(names, id.Name.Source)
+			}
+			for _, x := range spec.Values {
+				values = append(values, src[x.Pos():x.End()])
+			}
+			s += " ("
+			if len(spec.Type) != 0 {
+				s += join(spec.Type) + " "
+			}
+			s += strings.Join(names, ",")
+			if len(values) != 0 {
+				s += " = " + strings.Join(values, ",")
+			}
+			s += ")"
+		}
+		return
+	}
+
+	switch d := d.(type) {
+	case *ast.GenDecl:
+		s := d.Tok.String()
+		if len(d.Type) != 0 {
+			s += " <" + join(d.Type) + ">"
+		}
+		return s + specs(d.Specs)
+	case *ast.FuncDecl:
+		s := d.Tok.String() + " " + d.Name.Name.Source
+		for _, f := range d.Params {
+			s += " (" + join(f.Type)
+			if f.Name != nil {
+				s += " " + f.Name.Name.Source
+			}
+			s += ")"
+		}
+		if len(d.Results) != 0 {
+			s += " out " + join(d.Results)
+		}
+		if d.Body != nil {
+			s += " body " + strconv.Itoa(len(d.Body.List))
+		}
+		return s
+	case *ast.TypeDecl:
+		s := "type " + d.Name.Name.Source
+		if len(d.Type) != 0 {
+			return s + " = " + join(d.Type)
+		}
+		return s + specs(d.Fields)
+	}
+	return fmt.Sprintf("%T", d)
+}
+
+// TestDeclForms 检查 README 和 ast 规则测试中的声明写法
+func TestDeclForms(t *testing.T) {
+	for src, want := range map[string]string{
+		"var [\n\tint x\n\tint y=5\n\tint z,i=4\n]": "var (int x) (int y = 5) (int z,i = 4)",
+		"var {\n\tint x\n}":                         "var (int x)",
+		"var int a, string b":                       "var (int a) (string b)",
+		"var (\n\tf32 f = 9.0\n)":                   "var (f32 f = 9.0)",
+		"var datetime (\n\tday = 20160202, now = 20160202T22:48:33\n\torz = 20160202T22:48:33Z\n)": "var <datetime> (day = 20160202) (now = 20160202T22:48:33) (orz = 20160202T22:48:33Z)",
+		"var datetime{\n\tday = 20160202\n}":                       "var <datetime> (day = 20160202)",
+		"var b, c = 2, 3":                                          "var (b,c = 2,3)",
+		"var list[int] l = [1], m = [2]":                           "var (list [ int ] l = [1]) (m = [2])",
+		"proc hello string word [\n\tprint('hello ' + word)\n]":    "proc hello (string word) body 1",
+		"proc hello string word {\n\tprint('hello ' + word)\n}":    "proc hello (string word) body 1",
+		"proc noret {}":                                            "proc noret body 0",
+		"proc a int b,c out bool d,e":                              "proc a (int b) (int c) out bool d , e",
+		"proc a int a bool b":                                      "proc a (int a) (bool b)",
+		"proc a int b, T c":                                        "proc a (int b) (T c)",
+		"proc sum int x,y,out int [\n\tout x + y\n]":               "proc sum (int x) (int y) out int body 1",
+		"proc a list[int] l":                                       "proc a (list [ int ] l)",
+		"func a int string bool":                                   "func a (int) (string) (bool)",
+		"func a b out int":                                         "func a (b) out int",
+		"const {a ='b'}":                                           "const (a = 'b')",
+		"const {a ='b'\n c=[]}":                                    "const (a = 'b') (c = [])",
+		"var {string a ='b';int c=[]}":                             "var (string a = 'b') (int c = [])",
+		"use (a 'b', c 'd')":                                       "use (a = 'b') (c = 'd')",
+		"use (a 'b', c 'd',)":                                      "use (a = 'b') (c = 'd')",
+		"use ( 'b'\n, c 'd')":                                      "use ( = 'b') (c = 'd')",
+		"type a{int b}":                                            "type a (int b)",
+		"type a{}":                                                 "type a",
+		"type a{int b;int c}":                                      "type a (int b) (int c)",
+		"type a{i b,c}":                                            "type a (i b,c)",
+		"type a int":                                               "type a = int",
+		"type Seq {\n\tarray[int]\n\tint n\n}":                     "type Seq (array [ int ] ) (int n)",
+		"var array[int] (\n\tc\n\td = [\n\t\t1,2\n\t\tf()\n\t]\n)": "var <array [ int ]> (c) (d = [\n\t\t1,2\n\t\tf()\n\t])",
+		"var map[string,int] m = {\n\t'a': 1\n\t'b': 2\n}":         "var (map [ string , int ] m = {\n\t'a': 1\n\t'b': 2\n})",
+		"func int f(int a, int b)":                                 "func f (int a) (int b) out int",
+		"func list[int] f(T a)":                                    "func f (T a) out list [ int ]",
+		"proc root.count out int [\n\tout 1\n]":                    "proc root.count out int body 1",
+	} {
+		file, err := parser.ParseSyntax([]byte(src))
+		if err != nil || len(file.Decls) != 1 {
+			t.Fatalf("%q: %v", src, err)
+		}
+		if got := decl(src, file.Decls[0]); got != want {
+			t.Fatalf("%q: %s", src, got)
+		}
+		if d := file.Decls[0]; d.Pos() != 0 || int(d.End()) != len(src) {
+			t.Fatalf("%q: %d %d", src, d.Pos(), d.End())
+		}
+	}
+
+	for _, src := range []string{"use", "use a", "use (a,'d')", "use ('b' c 'd')", "use (a 'b' 'd')", "use a 'b', c 'd'", "proc a int", "var {int a", "type a{int b",
+		"func int f out int", "func int f(int a) out int", "func a.b", "var m = {1}", "var m = {'a': 1 'b': 2}"} {
+		if _, err := parser.ParseSyntax([]byte(src)); err == nil {
+			t.Fatal(src)
+		}
+	}
+
+	// 没有参数的声明之后的空行不是续行
+	if file, err := parser.ParseSyntax([]byte("func a\t说明\n\nfunc b\n")); err != nil || len(file.Decls) != 2 {
+		t.Fatal(err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	src := "proc f [\n\techo 'hello ' word\n\techo x, y + 1\n\tprint (x)\n]\n"
+	file, err := parser.ParseSyntax([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"echo('hello ', word)", "echo(x, (y + 1))", "print(x)"} {
+		x := file.Decls[0].(*ast.FuncDecl).Body.List[i].(*ast.ExprStmt).X
+		if got := paren(x); got != want {
+			t.Fatal(got)
+		}
+		if call := x.(*ast.CallExpr); call.Lparen != 0 || src[x.End()] != '\n' {
+			t.Fatal(want, call.Lparen, x.End())
+		}
+	}
+
+	for _, src := range []string{"proc f [\n\techo +\n]", "proc f [\n\t1 x\n]", "proc f [\n\tx [1]\n]"} {
+		if _, err := parser.ParseSyntax([]byte(src)); err == nil {
+			t.Fatal(src)
+		}
+	}
+}
+
+func TestParseSyntaxBad(t *testing.T) {
+	src := []byte("var = 1\nproc f [\n\tx = = 1\n\tout 2\n]\n")
+	file, err := parser.ParseSyntax(src)
+	if err == nil || len(file.Decls) != 2 {
+		t.Fatal(err, file.Decls)
+	}
+	body := file.Decls[1].(*ast.FuncDecl).Body
+	if bad, ok := body.List[0].(*ast.BadSyntax); !ok || string(src[bad.From:bad.To]) != "x = = 1" {
+		t.Fatal(body.List)
+	}
+	if _, ok := body.List[1].(*ast.OutStmt); !ok {
+		t.Fatal(body.List)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	for src, want := range map[string]string{
+		"proc f [\n\tfnuc g [\n\t]\n]\n":                "parser: unknown keyword 'fnuc' at offset 10, did you mean 'func'?",
+		"proc f [\n\tswich x [\n\t]\n]\n":               "parser: unknown keyword 'swich' at offset 10, did you mean 'switch'?",
+		"proc f [\n\tif a [\n\t] esle [\n\t]\n]\n":      "parser: unexpected IDENT 'esle' at offset 20, did you mean 'else'?",
+		"proc f [\n\tif a [\n\t] else [\n\t] esle\n]\n": "parser: unexpected IDENT 'esle' at offset 30",
+		"proc f [\n\tiff a [\n\t]\n]\n":                 "parser: unknown keyword 'iff' at offset 10, did you mean 'if'?",
+	} {
+		if _, err := parser.ParseSyntax([]byte(src)); err == nil || err.Error() != want {
+			t.Errorf("%q: %v", src, err)
+		}
+	}
+
+	if tok, ok := parser.Suggest("defualt", []token.Token{token.DEFER, token.DEFAULT}); !ok || tok != token.DEFAULT {
+		t.Fatal(tok, ok)
+	}
+	if _, ok := parser.Suggest("x", []token.Token{token.IF}); ok {
+		t.Fatal("x is not if")
+	}
+}
+
+func TestParseDecl(t *testing.T) {
+	src := []byte("// doc\npub proc f(int n) out int [\n\tout n\n]\n")
+	d, err := parser.ParseDecl(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn, ok := d.(*ast.FuncDecl); !ok || fn.Name.Name.Source != "f" || string(src[d.Pos():d.End()]) != string(src[7:len(src)-1]) {
+		t.Fatal(d)
+	}
+
+	d, err = parser.ParseDecl([]byte("var (\n\ta = 1\n\tb = 2\n)"))
+	if g, ok := d.(*ast.GenDecl); err != nil || !ok || len(g.Specs) != 2 {
+		t.Fatal(d, err)
+	}
+
+	for _, src := range []string{"", "x = 1", "var a = 1\nvar b = 2", "var a = 1 2"} {
+		if _, err := parser.ParseDecl([]byte(src)); err == nil {
+			t.Fatalf("%q: want error", src)
+		}
+	}
+}
+
+func TestTypeParams(t *testing.T) {
+	src := []byte("pub type Pair[K, V] [\n\tK key\n\tV value\n]\ntype Ints list[int]\nfunc max[integer T](T a, T b) out T [\n\tout a\n]\n")
+	file, err := parser.ParseSyntax(src)
+	if err != nil || len(file.Decls) != 3 {
+		t.Fatal(err)
+	}
+	text := func(x ast.Syntax) string { return string(src[x.Pos():x.End()]) }
+
+	pair := file.Decls[0].(*ast.TypeDecl)
+	if pair.Pub != 0 || text(pair.TypeParams) != "[K, V]" || len(pair.TypeParams.List) != 2 || len(pair.Fields) != 2 || text(pair.Fields[1]) != "V value" {
+		t.Fatal(text(pair))
+	}
+	ints := file.Decls[1].(*ast.TypeDecl)
+	if ints.TypeParams != nil || len(ints.Type) != 4 || text(ints) != "type Ints list[int]" {
+		t.Fatal(text(ints))
+	}
+	max := file.Decls[2].(*ast.FuncDecl)
+	if tp := max.TypeParams.List[0]; len(tp.Type) != 1 || tp.Type[0].Source != "integer" || tp.Name.Name.Source != "T" || len(max.Params) != 2 {
+		t.Fatal(text(max))
+	}
+
+	for _, src := range []string{"type A[] int", "type A[T,] int", "func f[T(T a) [\n]", "type A"} {
+		if _, err := parser.ParseSyntax([]byte(src)); err == nil {
+			t.Fatalf("%q: want error", src)
+		}
+	}
+}
+
+func TestEnum(t *testing.T) {
+	src := []byte("type Shape enum [\n\tcircle(float r)\n\trect(float w, float h)\n\tempty\n]\ntype E enum\n")
+	file, err := parser.ParseSyntax(src)
+	if err != nil || len(file.Decls) != 2 {
+		t.Fatal(err)
+	}
+	text := func(x ast.Syntax) string { return string(src[x.Pos():x.End()]) }
+
+	shape := file.Decls[0].(*ast.TypeDecl)
+	if shape.Enum != 11 || len(shape.Variants) != 3 || text(shape.Variants[1]) != "rect(float w, float h)" || len(shape.Variants[1].Params) != 2 || text(shape.Variants[2]) != "empty" {
+		t.Fatal(text(shape))
+	}
+	if e := file.Decls[1].(*ast.TypeDecl); e.Enum != 0 || len(e.Type) != 1 {
+		t.Fatal(text(e))
+	}
+
+	for _, src := range []string{"type A enum [\n\ta b\n]", "type A enum [\n\ta(int)\n]", "type A enum [\n\ta"} {
+		if _, err := parser.ParseSyntax([]byte(src)); err == nil {
+			t.Fatalf("%q: want error", src)
+		}
+	}
+}
+
+func TestParseSyntaxInterp(t *testing.T) {
+	src := []byte("var s = \"x {a+1} y\"\nproc f [\n\tg(\"{s}!\", 1)\n]\n")
+	file, err := parser.ParseSyntax(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := file.Decls[0].(*ast.GenDecl).Specs[0].Values[0]
+	if _, ok := x.(*ast.InterpExpr); !ok || string(src[x.Pos():x.End()]) != `"x {a+1} y"` {
+		t.Fatalf("%#v", x)
+	}
+	call := file.Decls[1].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr)
+	if in, ok := call.Args[0].(*ast.InterpExpr); !ok || len(call.Args) != 2 || len(in.Parts) != 3 {
+		t.Fatalf("%#v", call.Args)
+	}
+}
+
+func TestRangeStmt(t *testing.T) {
+	src := []byte("proc main [\n\tfor 1..n + 1 as i [\n\t]\n\tfor m as k v [\n\t]\n\tfor as > 1 [\n\t]\n]\n")
+	file, err := parser.ParseSyntax(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := file.Decls[0].(*ast.FuncDecl).Body.List
+	r := body[0].(*ast.RangeStmt)
+	if x, ok := r.X.(*ast.BinaryExpr); !ok || x.Op.Tok != token.DOTDOT || r.Key.Name.Source != "i" || r.Value != nil {
+		t.Fatal(string(src[r.Pos():r.End()]))
+	}
+	if r := body[1].(*ast.RangeStmt); r.Key.Name.Source != "k" || r.Value.Name.Source != "v" {
+		t.Fatal(string(src[r.Pos():r.End()]))
+	}
+	if _, ok := body[2].(*ast.ForStmt); !ok {
+		t.Fatalf("%T", body[2])
+	}
+}
+
+func TestGoStmt(t *testing.T) {
+	src := []byte("proc f [\n\tdefer close(c)\n\tgo g\n]\n")
+	file, err := parser.ParseSyntax(src)
+	if err == nil || err.Error() != "parser: go requires a call at offset 26" {
+		t.Fatal(err)
+	}
+	s, ok := file.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.GoStmt)
+	if !ok || s.Tok != token.DEFER || string(src[s.Call.Pos():s.Call.End()]) != "close(c)" {
+		t.Fatal(file.Decls[0].(*ast.FuncDecl).Body.List)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	src := "#!/usr/bin/env zxx run\n+++\ntype = 'script'\n\n+++\nproc main [\n]\n"
+	file, err := parser.ParseSyntax([]byte(src))
+	if err != nil || len(file.Decls) != 1 {
+		t.Fatal(err)
+	}
+	want := ast.Header{From: 0, To: 48, Shebang: "/usr/bin/env zxx run", FrontMatter: "type = 'script'\n\n", HasFront: true}
+	if h := file.Header; h == nil || *h != want || src[h.To:h.To+4] != "proc" {
+		t.Fatalf("%+v", h)
+	}
+
+	for src, want := range map[string]ast.Header{
+		"#!zxx\nvar a = 1\n":         {To: 6, Shebang: "zxx"},
+		"+++\n+++\nvar a = 1\n":      {To: 8, HasFront: true},
+		"#!zxx\n\n+++\nx\n+++\n":     {To: 17, Shebang: "zxx", FrontMatter: "x\n", HasFront: true},
+		"\xef\xbb\xbf#!zxx\nvar a\n": {From: 3, To: 9, Shebang: "zxx"},
+	} {
+		file, err := parser.ParseSyntax([]byte(src))
+		if err != nil || file.Header == nil || *file.Header != want {
+			t.Errorf("%q: %v %+v", src, err, file.Header)
+		}
+	}
+
+	if file, err := parser.ParseSyntax([]byte("note\nvar a = 1\n")); err != nil || file.Header != nil {
+		t.Fatal(err, file.Header)
+	}
+	if _, err := parser.ParseSyntax([]byte("+++\nvar a = 1\n")); err == nil {
+		t.Fatal("want front matter is incomplete")
+	}
+}
+
+func TestBuildConstraint(t *testing.T) {
+	src := "#!/usr/bin/env zxx\n// 只用于 linux\n// zxx:build linux && !arm\n\nproc main [\n]\n"
+	file, err := parser.ParseSyntax([]byte(src))
+	if err != nil || file.Build == nil || file.Build.String() != "linux && !arm" {
+		t.Fatal(file.Build, err)
+	}
+	if c, err := parser.HeaderBuild([]byte(src)); err != nil || c.String() != "linux && !arm" {
+		t.Fatal(c, err)
+	}
+	// 声明之后的注释不是构建约束
+	if file, err := parser.ParseSyntax([]byte("proc main [\n]\n// zxx:build linux\n")); err != nil || file.Build != nil {
+		t.Fatal(file.Build, err)
+	}
+	if _, err := parser.ParseSyntax([]byte("// zxx:build a\n// zxx:build b\nproc main [\n]\n")); err == nil || err.Error() != "parser: multiple zxx:build constraints at offset 15" {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Target 是构建的目标平台, 写法与 Go 的 GOOS/GOARCH 相同, 例如 linux/amd64.
+// 构建约束中等于 OS, Arch 或者 Tags 之一的标签成立.
+type Target struct {
+	OS   string
+	Arch string
+	Tags []string
+}
+
+// DefaultTarget 返回当前运行的平台
+func DefaultTarget() *Target {
+	return &Target{OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+// ParseTarget 解析 os/arch 形式的目标平台, 之后可以有逗号分隔的标签, 例如 "linux/arm64,netgo".
+func ParseTarget(s string) (*Target, error) {
+	list := strings.Split(s, ",")
+	i := strings.IndexByte(list[0], '/')
+	if i <= 0 || i == len(list[0])-1 || strings.Count(list[0], "/") != 1 {
+		return nil, errors.New("parser: invalid target " + strconv.Quote(s) + ", want os/arch")
+	}
+	t := &Target{OS: list[0][:i], Arch: list[0][i+1:]}
+	for _, tag := range list[1:] {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+	return t, nil
+}
+
+func (t *Target) String() string {
+	s := t.OS + "/" + t.Arch
+	for _, tag := range t.Tags {
+		s += "," + tag
+	}
+	return s
+}
+
+// Tag 返回构建约束中的标签 name 对于 t 是否成立
+func (t *Target) Tag(name string) bool {
+	if name == t.OS || name == t.Arch {
+		return true
+	}
+	for _, tag := range t.Tags {
+		if tag == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Match 返回约束 c 对于 t 是否成立, c 为 nil 时总是成立.
+func (t *Target) Match(c ast.Constraint) bool {
+	return c == nil || c.Eval(t.Tag)
+}
+
+// GoEnv 返回 Go 后端调用 go 命令时为 t 设置的环境变量
+func (t *Target) GoEnv() []string {
+	return []string{"GOOS=" + t.OS, "GOARCH=" + t.Arch}
+}
+
+// buildConstraint 返回 syms 中首个声明之前的构建约束, 多于一个时返回错误.
+// 相邻的注释行和文件头可能合并为一个 Symbol, 因此逐行检查.
+func buildConstraint(syms []Symbol) (c ast.Constraint, err error) {
+	for _, sym := range syms {
+		if !ast.IsTrivia(sym.Tok) && sym.Tok != token.EOF {
+			break
+		}
+		if sym.Tok != token.COMMENT && sym.Tok != token.PLACEHOLDER {
+			continue
+		}
+		for off, src := 0, sym.Source; src != ""; {
+			line, rest := cutLine(src)
+			pos := int(sym.Pos) + off
+			off += len(line)
+			src = rest
+			if !ast.IsConstraint(line) {
+				continue
+			}
+			if c != nil {
+				return c, errors.New("parser: multiple " + ast.BuildPrefix + " constraints at offset " + strconv.Itoa(pos))
+			}
+			if c, err = ast.ParseConstraint(line); err != nil {
+				return nil, errors.New("parser: " + err.Error() + " at offset " + strconv.Itoa(pos))
+			}
+		}
+	}
+	return c, nil
+}
+
+// HeaderBuild 返回 src 首个声明之前的构建约束, 没有时为 nil, 用于加载包时快速过滤文件.
+func HeaderBuild(src []byte) (ast.Constraint, error) {
+	var syms []Symbol
+	_, err := Fast(src, func(pos scanner.Pos, tok token.Token, code string) error {
+		if !ast.IsTrivia(tok) && tok != token.EOF {
+			return errStop
+		}
+		syms = append(syms, Symbol{Pos: pos, Tok: tok, Source: code})
+		return nil
+	})
+	if err != nil && err != errStop {
+		return nil, err
+	}
+	return buildConstraint(syms)
+}
@@ -0,0 +1,45 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// RepairUTF8 把 src 中每段连续的非法 UTF-8 编码替换为一个 U+FFFD, 返回修复后的源码.
+// 每段被替换的编码对应一个 *Error, Pos 是修复后的源码中 U+FFFD 的位置,
+// 消息中的 offset 是编码在 src 中的位置.
+// src 合法时原样返回 src.
+func RepairUTF8(src []byte) ([]byte, ErrorList) {
+	if utf8.Valid(src) {
+		return src, nil
+	}
+	var errs ErrorList
+	out := make([]byte, 0, len(src)+8)
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRune(src[i:])
+		if r != utf8.RuneError || size != 1 {
+			out = append(out, src[i:i+size]...)
+			i += size
+			continue
+		}
+		start := i
+		for i < len(src) {
+			if r, size = utf8.DecodeRune(src[i:]); r != utf8.RuneError || size != 1 {
+				break
+			}
+			i++
+		}
+		errs = append(errs, &Error{
+			Pos: scanner.Pos(len(out)),
+			Msg: "parser: invalid UTF-8 encode " + strconv.Quote(string(src[start:i])) + " at offset " + strconv.Itoa(start) + ", replaced by U+FFFD",
+		})
+		out = append(out, "\uFFFD"...)
+	}
+	return out, errs
+}
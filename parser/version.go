@@ -0,0 +1,179 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parser
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// 语言版本指示使用编译参数写法, 必须是文件的首个声明:
+//
+//	use "-version=2"
+//
+// 之前可以有占位和注释. 版本指示优先于 Config.Version,
+// 使得代码库可以逐个文件地迁移到新的语法修订.
+const versionPrefix = "-version="
+
+var errStop = errors.New("stop")
+
+// HeaderVersion 返回 src 头部版本指示的语言版本, 只扫描到首个声明为止.
+// 没有版本指示时 ok 为 false.
+func HeaderVersion(src []byte) (version string, ok bool, err error) {
+	use := false
+	_, err = Fast(src, func(pos scanner.Pos, tok token.Token, code string) error {
+		switch {
+		case use:
+			version, ok = versionOf(tok, code)
+			return errStop
+		case tok == token.USE:
+			use = true
+		case tok == token.EOF:
+		case !ast.IsTrivia(tok):
+			return errStop
+		}
+		return nil
+	})
+	if err == errStop {
+		err = nil
+	}
+	if err == nil && ok && version == "" {
+		err = errors.New("parser: missing language version")
+	}
+	return
+}
+
+// fileVersion 返回 file 头部版本指示的语言版本, 没有时返回 def
+func fileVersion(file *ast.File, def string) (string, error) {
+	use := false
+	for _, n := range file.Nodes[1:] {
+		tok := n.Token()
+		switch {
+		case use:
+			if v, ok := versionOf(tok, n.Text()); ok {
+				if v == "" {
+					return "", errors.New("parser: missing language version")
+				}
+				return v, nil
+			}
+			return def, nil
+		case tok == token.USE:
+			use = true
+		case !ast.IsTrivia(tok):
+			return def, nil
+		}
+	}
+	return def, nil
+}
+
+// version 设置 file.Version 为文件头部指示的语言版本或者 c.Version, err 是解析 src 的错误.
+// 没有其它错误时返回版本不合法, 以及使用了该版本不支持的语法的错误.
+func (c *Config) version(file *ast.File, src []byte, err error) error {
+	v, e := fileVersion(file, c.Version)
+	if e != nil {
+		if err == nil {
+			err = e
+		}
+		return err
+	}
+	file.Version = v
+	if err == nil {
+		err = CheckVersion(src, v)
+	}
+	return err
+}
+
+// versionOf 返回 VALSTRING 版本指示中的版本
+func versionOf(tok token.Token, code string) (string, bool) {
+	if tok != token.VALSTRING || len(code) < 2 {
+		return "", false
+	}
+	s := code[1 : len(code)-1]
+	if !strings.HasPrefix(s, versionPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(s[len(versionPrefix):]), true
+}
+
+// LatestVersion 是最新的语言版本, 没有版本指示和 Config.Version 时使用最新版本.
+const LatestVersion = 2
+
+// Feature 是从语言版本 Since 开始可用的语法. 文件声明的版本较旧时使用它是错误,
+// 新语法因此可以逐步引入而不改变已有代码的含义.
+type Feature struct {
+	Name  string
+	Since int
+}
+
+// 受版本限制的语法
+var (
+	Interpolation  = &Feature{"string interpolation", 2}
+	TypeParameters = &Feature{"type parameters", 2}
+	Enums          = &Feature{"enum types", 2}
+)
+
+// ParseVersion 返回语言版本 v 的序号, v 必须是 1 到 LatestVersion 的整数, 为空时返回 LatestVersion.
+func ParseVersion(v string) (int, error) {
+	if v == "" {
+		return LatestVersion, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > LatestVersion {
+		return 0, errors.New("parser: unknown language version " + strconv.Quote(v))
+	}
+	return n, nil
+}
+
+// CheckVersion 返回 src 中语言版本 version 不支持的语法, 每处一个 *Error, 没有时返回 nil.
+// version 不合法时返回 ParseVersion 的错误. 不能被解析的源码只检查能识别的部分.
+func CheckVersion(src []byte, version string) error {
+	v, err := ParseVersion(version)
+	if err != nil || v >= LatestVersion {
+		return err
+	}
+	syms, err := Fast(src, nil)
+	if err != nil {
+		return nil
+	}
+	var errs ErrorList
+	use := func(f *Feature, pos scanner.Pos) {
+		if f.Since > v {
+			errs = append(errs, &Error{Pos: pos, Msg: "parser: " + f.Name + " requires language version " +
+				strconv.Itoa(f.Since) + ", file uses version " + strconv.Itoa(v)})
+		}
+	}
+	for _, sym := range syms {
+		if sym.Tok == token.INTERPBEGIN {
+			use(Interpolation, sym.Pos)
+		}
+	}
+	sf, _ := ParseSyntaxSymbols(syms)
+	for _, d := range sf.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if d.TypeParams != nil {
+				use(TypeParameters, d.TypeParams.Pos())
+			}
+		case *ast.TypeDecl:
+			if d.TypeParams != nil {
+				use(TypeParameters, d.TypeParams.Pos())
+			}
+			if d.Enum != 0 {
+				use(Enums, d.Enum)
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].(*Error).Pos < errs[j].(*Error).Pos })
+	return errs
+}
@@ -0,0 +1,32 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package platform
+
+import "strings"
+
+// Lines 按 LF, CRLF 和单独的 CR 分割 s, 与扫描器的换行规则相同, 行中不包括换行.
+// 在 Windows 上检出的文本文件常被转换为 CRLF, 按行解析的文本应该使用它而不是按 "\n" 分割.
+// s 以换行结束时最后一个元素为空, 同 strings.Split(s, "\n").
+func Lines(s string) []string {
+	if strings.IndexByte(s, '\r') == -1 {
+		return strings.Split(s, "\n")
+	}
+	var lines []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\n':
+			lines = append(lines, s[start:i])
+			start = i + 1
+		case '\r':
+			lines = append(lines, s[start:i])
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+			start = i + 1
+		}
+	}
+	return append(lines, s[start:])
+}
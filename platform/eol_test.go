@@ -0,0 +1,24 @@
+package platform_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ZxxLang/zxx/platform"
+)
+
+func TestLines(t *testing.T) {
+	for s, want := range map[string][]string{
+		"":                {""},
+		"a":               {"a"},
+		"a\nb\n":          {"a", "b", ""},
+		"a\r\nb\r\n":      {"a", "b", ""},
+		"a\rb\r\n\r\nc":   {"a", "b", "", "c"},
+		"a\r\r\nb\n\rc\r": {"a", "", "b", "", "c", ""},
+		"# 标题\r\nwant: 3": {"# 标题", "want: 3"},
+	} {
+		if got := platform.Lines(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: %q", s, got)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package platform
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// reserved 是 Windows 的设备名, 加上扩展名也不能用作文件名
+var reserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// invalidName 返回 name 不能在全部平台上用作文件名的原因, 可以使用时返回 ""
+func invalidName(name string) string {
+	switch {
+	case name == "":
+		return "is empty"
+	case name == "." || name == "..":
+		return "is . or .."
+	case strings.ContainsAny(name, `<>:"/\|?*`):
+		return "contains a character reserved on Windows"
+	case strings.IndexFunc(name, func(r rune) bool { return r < 0x20 || r == 0x7f }) != -1:
+		return "contains a control character"
+	case strings.HasSuffix(name, ".") || strings.HasSuffix(name, " "):
+		return "ends with a dot or space"
+	}
+	base := name
+	if i := strings.IndexByte(base, '.'); i != -1 {
+		base = base[:i]
+	}
+	if reserved[strings.ToUpper(strings.TrimRight(base, " "))] {
+		return "is a device name on Windows"
+	}
+	return ""
+}
+
+// CheckName 检查 name 能否在全部平台上用作文件名: 不能为空, 不能是 "." 或 "..",
+// 不能包含控制字符和 Windows 保留的字符 <>:"/\|?*, 不能以 '.' 或空格结尾,
+// 去掉扩展名后不能是 Windows 的设备名, 例如 CON, aux.zxx.
+func CheckName(name string) error {
+	if why := invalidName(name); why != "" {
+		return errors.New("platform: file name " + strconv.Quote(name) + " " + why)
+	}
+	return nil
+}
+
+// CheckPath 检查 '/' 分隔的相对路径 p 能否在全部平台上使用, 每一段都必须满足 CheckName.
+// use 路径, 模块路径和归档中的文件名使用这种路径.
+func CheckPath(p string) error {
+	for _, elem := range strings.Split(p, "/") {
+		if why := invalidName(elem); why != "" {
+			return errors.New("platform: path " + strconv.Quote(p) + ": element " + strconv.Quote(elem) + " " + why)
+		}
+	}
+	return nil
+}
+
+// CaseError 表示路径的大小写和文件系统中的名字不同.
+// 不区分大小写的文件系统上这样的路径可以打开, 但是在其它平台上找不到.
+type CaseError struct {
+	Path string // 给出的路径
+	Disk string // 文件系统中的写法, 到第一个不同的段为止
+}
+
+func (e *CaseError) Error() string {
+	return "platform: " + e.Path + " differs in case from " + e.Disk + " on disk"
+}
+
+// CheckCase 检查 fsys 中 '/' 分隔的路径 name 的每一段是否和目录项的大小写完全相同.
+// 大小写不同时返回 *CaseError, 不存在时返回的错误满足 errors.Is(err, fs.ErrNotExist).
+// 结果与 fsys 是否区分大小写无关, 在 Linux 上也能发现只在 Windows 和 macOS 上有效的路径.
+func CheckCase(fsys fs.FS, name string) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "checkcase", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return nil
+	}
+	dir := "."
+	for _, elem := range strings.Split(name, "/") {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return err
+		}
+		found := ""
+		for _, e := range entries {
+			if e.Name() == elem {
+				found = elem
+				break
+			}
+			if strings.EqualFold(e.Name(), elem) {
+				found = e.Name()
+			}
+		}
+		switch found {
+		case "":
+			return &fs.PathError{Op: "checkcase", Path: name, Err: fs.ErrNotExist}
+		case elem:
+			dir = path.Join(dir, elem)
+		default:
+			return &CaseError{name, path.Join(dir, found)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package platform_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ZxxLang/zxx/platform"
+)
+
+func TestCheckName(t *testing.T) {
+	for _, name := range []string{"a.zxx", "cons.d", "COM10", "auxiliary.zxx", ".hidden", "中文.zxx", "a b"} {
+		if err := platform.CheckName(name); err != nil {
+			t.Error(err)
+		}
+	}
+	for name, want := range map[string]string{
+		"":         `platform: file name "" is empty`,
+		"..":       `platform: file name ".." is . or ..`,
+		"a:b":      `platform: file name "a:b" contains a character reserved on Windows`,
+		`a\b`:      `platform: file name "a\\b" contains a character reserved on Windows`,
+		"a\tb":     `platform: file name "a\tb" contains a control character`,
+		"a.":       `platform: file name "a." ends with a dot or space`,
+		"a ":       `platform: file name "a " ends with a dot or space`,
+		"CON":      `platform: file name "CON" is a device name on Windows`,
+		"aux.zxx":  `platform: file name "aux.zxx" is a device name on Windows`,
+		"lpt1 .tx": `platform: file name "lpt1 .tx" is a device name on Windows`,
+	} {
+		if err := platform.CheckName(name); err == nil || err.Error() != want {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+
+	if err := platform.CheckPath("example.org/lib/util"); err != nil {
+		t.Error(err)
+	}
+	for _, p := range []string{"", "a//b", "/a", "a/", "a/../b", "lib/nul", `lib\util`, "C:/lib"} {
+		if err := platform.CheckPath(p); err == nil {
+			t.Errorf("%q: no error", p)
+		}
+	}
+	if err := platform.CheckPath("lib/Nul.zxx"); err == nil || err.Error() != `platform: path "lib/Nul.zxx": element "Nul.zxx" is a device name on Windows` {
+		t.Error(err)
+	}
+}
+
+func TestCheckCase(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/Util/a.zxx": {},
+		"lib/text/b.zxx": {},
+	}
+	for _, name := range []string{".", "lib", "lib/Util", "lib/text/b.zxx"} {
+		if err := platform.CheckCase(fsys, name); err != nil {
+			t.Error(err)
+		}
+	}
+
+	err := platform.CheckCase(fsys, "lib/util/a.zxx")
+	var ce *platform.CaseError
+	if !errors.As(err, &ce) || ce.Disk != "lib/Util" || err.Error() != "platform: lib/util/a.zxx differs in case from lib/Util on disk" {
+		t.Fatal(err)
+	}
+	if err := platform.CheckCase(fsys, "lib/none"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+	if err := platform.CheckCase(fsys, "../lib"); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package platform
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// IsSep 返回 c 是否是路径分隔符
+func (o *OS) IsSep(c byte) bool {
+	return c == '/' || c == o.Separator
+}
+
+// VolumeName 返回 p 开头的卷名, 例如 Windows 的 "C:" 和 `\\host\share`, 其它平台总是 "".
+func (o *OS) VolumeName(p string) string {
+	if o.Separator != '\\' {
+		return ""
+	}
+	if len(p) >= 2 && p[1] == ':' && ('a' <= p[0]|0x20 && p[0]|0x20 <= 'z') {
+		return p[:2]
+	}
+	// UNC 路径 \\host\share, host 和 share 都不能为空
+	if len(p) < 5 || !o.IsSep(p[0]) || !o.IsSep(p[1]) || o.IsSep(p[2]) || p[2] == '.' {
+		return ""
+	}
+	i := 3
+	for i < len(p) && !o.IsSep(p[i]) {
+		i++
+	}
+	if i+1 >= len(p) || o.IsSep(p[i+1]) {
+		return ""
+	}
+	for i++; i < len(p) && !o.IsSep(p[i]); i++ {
+	}
+	return p[:i]
+}
+
+// IsAbs 返回 p 是否是绝对路径. Windows 的绝对路径必须有卷名, `\a` 和 "C:a" 不是绝对路径.
+func (o *OS) IsAbs(p string) bool {
+	vol := o.VolumeName(p)
+	switch {
+	case len(vol) > 2:
+		return true
+	case vol != "":
+		return len(p) > 2 && o.IsSep(p[2])
+	}
+	return p != "" && o.IsSep(p[0]) && o.Separator == '/'
+}
+
+// ToSlash 返回把分隔符替换为 '/' 的 p
+func (o *OS) ToSlash(p string) string {
+	if o.Separator == '/' {
+		return p
+	}
+	return strings.Replace(p, string(o.Separator), "/", -1)
+}
+
+// FromSlash 返回把 '/' 替换为分隔符的 p
+func (o *OS) FromSlash(p string) string {
+	if o.Separator == '/' {
+		return p
+	}
+	return strings.Replace(p, "/", string(o.Separator), -1)
+}
+
+// Clean 返回 p 的最短等价形式, 规则同 path.Clean, 卷名保持不变, 分隔符统一为 Separator.
+func (o *OS) Clean(p string) string {
+	vol := o.VolumeName(p)
+	rest := o.ToSlash(p[len(vol):])
+	switch {
+	case rest != "":
+		return vol + o.FromSlash(path.Clean(rest))
+	case len(vol) > 2:
+		return vol + string(o.Separator)
+	}
+	return vol + "."
+}
+
+// Join 用 Separator 连接非空的 elem 并 Clean, 全部为空时返回 "".
+func (o *OS) Join(elem ...string) string {
+	var list []string
+	for _, e := range elem {
+		if e != "" {
+			list = append(list, e)
+		}
+	}
+	if len(list) == 0 {
+		return ""
+	}
+	return o.Clean(strings.Join(list, string(o.Separator)))
+}
+
+// URI 返回 p 的 URI 引用, 例如 SARIF 中的文件位置. 绝对路径是 file URI,
+// 例如 file:///C:/src/a.zxx 和 file://host/share/a.zxx, 相对路径是 '/' 分隔的相对引用.
+func (o *OS) URI(p string) string {
+	s := o.ToSlash(p)
+	if !o.IsAbs(p) {
+		return (&url.URL{Path: s}).String()
+	}
+	if vol := o.VolumeName(p); len(vol) > 2 {
+		host := s[2:]
+		i := strings.IndexByte(host, '/')
+		return (&url.URL{Scheme: "file", Host: host[:i], Path: host[i:]}).String()
+	} else if vol != "" {
+		s = "/" + s
+	}
+	return (&url.URL{Scheme: "file", Path: s}).String()
+}
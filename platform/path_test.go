@@ -0,0 +1,77 @@
+package platform_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/platform"
+)
+
+func TestWindowsPaths(t *testing.T) {
+	w := platform.Windows
+	for _, c := range []struct {
+		path, vol string
+		abs       bool
+		clean     string
+		uri       string
+	}{
+		{`C:\src\a.zxx`, "C:", true, `C:\src\a.zxx`, "file:///C:/src/a.zxx"},
+		{`c:/src/../lib/./b.zxx`, "c:", true, `c:\lib\b.zxx`, "file:///c:/src/../lib/./b.zxx"},
+		{`C:src\a.zxx`, "C:", false, `C:src\a.zxx`, "./C:src/a.zxx"},
+		{`C:`, "C:", false, `C:.`, "./C:"},
+		{`\src\a.zxx`, "", false, `\src\a.zxx`, "/src/a.zxx"},
+		{`\\host\share\my docs\a.zxx`, `\\host\share`, true, `\\host\share\my docs\a.zxx`, "file://host/share/my%20docs/a.zxx"},
+		{`//host/share`, `//host/share`, true, `//host/share\`, "file://host/share"},
+		{`\\host`, "", false, `\host`, "//host"},
+		{`lib\..\..\a b.zxx`, "", false, `..\a b.zxx`, "lib/../../a%20b.zxx"},
+		{``, "", false, `.`, ""},
+	} {
+		if vol := w.VolumeName(c.path); vol != c.vol {
+			t.Errorf("VolumeName(%q) = %q", c.path, vol)
+		}
+		if abs := w.IsAbs(c.path); abs != c.abs {
+			t.Errorf("IsAbs(%q) = %v", c.path, abs)
+		}
+		if clean := w.Clean(c.path); clean != c.clean {
+			t.Errorf("Clean(%q) = %q", c.path, clean)
+		}
+		if uri := w.URI(c.path); uri != c.uri {
+			t.Errorf("URI(%q) = %q", c.path, uri)
+		}
+	}
+
+	if p := w.Join(`C:\src`, "", "lib/x", `..\a.zxx`); p != `C:\src\lib\a.zxx` {
+		t.Error(p)
+	}
+	if p := w.Join("", ""); p != "" {
+		t.Error(p)
+	}
+	if p := w.ToSlash(`a\b/c`); p != "a/b/c" || w.FromSlash(p) != `a\b\c` {
+		t.Error(p)
+	}
+}
+
+func TestUnixPaths(t *testing.T) {
+	u := platform.Unix
+	for path, want := range map[string]string{
+		"/src/a.zxx":   "file:///src/a.zxx",
+		"/a%b/c d.zxx": "file:///a%25b/c%20d.zxx",
+		"dir/a.zxx":    "dir/a.zxx",
+		`C:\a.zxx`:     `./C:%5Ca.zxx`,
+	} {
+		if uri := u.URI(path); uri != want {
+			t.Errorf("URI(%q) = %q", path, uri)
+		}
+	}
+	if u.VolumeName(`C:\a`) != "" || u.IsAbs(`C:\a`) || !u.IsAbs("/a") || u.IsAbs("a") {
+		t.Error("Unix treats volumes as file names")
+	}
+	if p := u.Clean(`a\b/../c`); p != "c" {
+		t.Error(p)
+	}
+	if p := u.Join("/src", "../lib", "a.zxx"); p != "/lib/a.zxx" {
+		t.Error(p)
+	}
+	if platform.Local != platform.Unix && platform.Local != platform.Windows {
+		t.Error(platform.Local)
+	}
+}
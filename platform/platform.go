@@ -0,0 +1,32 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包隔离与操作系统相关的路径, 文件名和换行规则.
+//
+// 规则是 *OS 类型的值而不是编译时确定的常量, 与 path/filepath 不同,
+// Windows 的路径可以在任何平台上处理和测试, 不需要交叉编译.
+// 命令行工具使用 Local, 处理来自其它平台的路径时使用对应的值.
+package platform
+
+import "runtime"
+
+// OS 是一种操作系统的路径规则
+type OS struct {
+	// Separator 是路径分隔符, '/' 总是被接受.
+	Separator byte
+}
+
+var (
+	Unix    = &OS{'/'}
+	Windows = &OS{'\\'}
+)
+
+// Local 是当前平台的规则, 除 Windows 之外的平台, 包括 js/wasm, 都使用 Unix 的规则.
+var Local = Unix
+
+func init() {
+	if runtime.GOOS == "windows" {
+		Local = Windows
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包在资源限制下求值代码片段, 用于在网页 playground 等服务中安全地嵌入 zxx.
+//
+// 片段的每一行是一个表达式或者一个绑定:
+//
+//	var name = x    求值 x 并绑定到 name, 之后的行可以使用 name
+//	const name = x  同 var
+//	x               求值 x 并输出它的 zxx 字面值写法
+//
+// 空行和 // 开始的行被忽略. 片段在求值之前被完整检查, 有语法错误时不求值任何一行.
+// 片段不能访问宿主环境, 没有可调用的函数.
+package playground
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Limits 是片段的资源限制, 零值表示不限制.
+type Limits struct {
+	Time   time.Duration // 全部行的求值时间
+	Memory int           // 绑定的值和结果的估计字节数
+	Output int           // 输出的字节数, 超出的部分被截断
+	Expr   eval.Limits   // 每个表达式的限制, Watchdog 被 Run 占用
+}
+
+// DefaultLimits 是适合公开服务的限制
+var DefaultLimits = Limits{
+	Time:   time.Second,
+	Memory: 1 << 20,
+	Output: 64 << 10,
+	Expr:   eval.Limits{MaxSource: 4096, MaxDepth: 64, MaxSteps: 1000, MaxString: 1 << 16},
+}
+
+// 超出限制的错误
+var (
+	ErrTime   = errors.New("playground: time limit exceeded")
+	ErrMemory = errors.New("playground: memory limit exceeded")
+	ErrOutput = errors.New("playground: output limit exceeded")
+)
+
+// Output 是片段的输出
+type Output struct {
+	Text    string        // 每个表达式行的结果各占一行
+	Elapsed time.Duration // 求值用时
+}
+
+// Error 是片段中某一行的错误, Pos 是片段中的位置.
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return "playground: " + e.Pos.String("") + ": " + e.Msg
+}
+
+// step 是片段中的一行
+type step struct {
+	name   string // 绑定的名字, 表达式行为空
+	prog   *eval.Program
+	line   int
+	offset int // 表达式在片段中的偏移量
+	column int // 表达式的列号
+}
+
+// Run 检查并求值片段 src. 出错时 Output 包含出错之前的输出, 错误是 *Error,
+// ctx 的错误或者 ErrTime, ErrMemory, ErrOutput 之一.
+func Run(ctx context.Context, src []byte, limits Limits) (out Output, err error) {
+	start := time.Now()
+	defer func() { out.Elapsed = time.Since(start) }()
+
+	// 求值的时间由 Watchdog 检查, 每 chunk 步检查一次
+	chunk := limits.Expr.MaxSteps
+	if chunk == 0 {
+		chunk = 1000
+		limits.Expr.MaxSteps = chunk
+	}
+	var stopped error // Watchdog 停止求值的原因
+	limits.Expr.Watchdog = func(steps, offset int) int {
+		if stopped = ctx.Err(); stopped == nil && limits.Time != 0 && time.Since(start) > limits.Time {
+			stopped = ErrTime
+		}
+		if stopped != nil {
+			return 0
+		}
+		return chunk
+	}
+
+	steps, err := check(string(src), limits.Expr)
+	if err != nil {
+		return out, err
+	}
+
+	var text strings.Builder
+	env := map[string]eval.Value{}
+	memory := 0
+	for _, s := range steps {
+		if err := ctx.Err(); err != nil {
+			return out, err
+		}
+		v, err := s.prog.Eval(env)
+		if stopped != nil {
+			return out, stopped
+		}
+		if err != nil {
+			return out, s.errorf(err)
+		}
+		if limits.Time != 0 && time.Since(start) > limits.Time {
+			return out, ErrTime
+		}
+
+		if memory += size(v); limits.Memory != 0 && memory > limits.Memory {
+			return out, ErrMemory
+		}
+		if s.name != "" {
+			if old, ok := env[s.name]; ok {
+				memory -= size(old)
+			}
+			env[s.name] = v
+			continue
+		}
+		memory -= size(v)
+		text.WriteString(show(v))
+		text.WriteByte('\n')
+		if limits.Output != 0 && text.Len() > limits.Output {
+			out.Text = text.String()[:limits.Output]
+			return out, ErrOutput
+		}
+		out.Text = text.String()
+	}
+	return out, nil
+}
+
+// check 把 src 拆分为行并编译每一行
+func check(src string, limits eval.Limits) (steps []step, err error) {
+	offset := 0
+	for i, line := range strings.Split(src, "\n") {
+		start := offset
+		offset += len(line) + 1
+		code := strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(code, " \t")
+		if trimmed == "" || strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		s := step{line: i + 1, column: len(code) - len(trimmed) + 1}
+		expr := trimmed
+		for _, kw := range []string{"var ", "const "} {
+			if !strings.HasPrefix(trimmed, kw) {
+				continue
+			}
+			eq := strings.IndexByte(trimmed, '=')
+			if eq == -1 {
+				return nil, &Error{token.Position{Offset: start, Line: s.line, Column: s.column}, "missing '=' in " + strings.TrimSpace(kw)}
+			}
+			s.name = strings.TrimSpace(trimmed[len(kw):eq])
+			if tok, _ := lexutil.Classify(s.name); tok != token.IDENT {
+				return nil, &Error{token.Position{Offset: start, Line: s.line, Column: s.column}, "invalid name " + s.name}
+			}
+			rest := trimmed[eq+1:]
+			expr = strings.TrimLeft(rest, " \t")
+			s.column += len(trimmed) - len(expr)
+		}
+		s.offset = start + s.column - 1
+		if s.prog, err = limits.Compile(expr); err != nil {
+			return nil, s.errorf(err)
+		}
+		steps = append(steps, s)
+	}
+	return
+}
+
+// errorf 返回表达式的错误 err 在片段中的位置
+func (s *step) errorf(err error) *Error {
+	off, msg := 0, err.Error()
+	if e, ok := err.(*eval.Error); ok {
+		off, msg = e.Offset, e.Msg
+	}
+	return &Error{token.Position{Offset: s.offset + off, Line: s.line, Column: s.column + off}, msg}
+}
+
+// size 返回值 v 占用内存的估计字节数
+func size(v eval.Value) int {
+	switch v := v.(type) {
+	case string:
+		return 16 + len(v)
+	case []eval.Value:
+		n := 24
+		for _, x := range v {
+			n += size(x)
+		}
+		return n
+	case map[string]eval.Value:
+		n := 48
+		for k, x := range v {
+			n += 16 + len(k) + size(x)
+		}
+		return n
+	}
+	return 16
+}
+
+// show 返回值 v 的 zxx 字面值写法
+func show(v eval.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return lexutil.Quote(v)
+	case float64:
+		return lexutil.FormatFloat(v)
+	case time.Time:
+		return v.Format("20060102T15:04:05Z07:00")
+	case []eval.Value:
+		items := make([]string, len(v))
+		for i, x := range v {
+			items[i] = show(x)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,60 @@
+package playground_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZxxLang/zxx/playground"
+)
+
+func TestRun(t *testing.T) {
+	src := "// demo\nvar a = 1 + 2\n\na * 2\nconst s = 'x' + 'y'\n[s, a, 1.5]\n"
+	out, err := playground.Run(context.Background(), []byte(src), playground.DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Text != "6\n['xy', 3, 1.5]\n" {
+		t.Fatalf("%q", out.Text)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	ctx := context.Background()
+	// 语法错误时不求值任何一行
+	out, err := playground.Run(ctx, []byte("1\n  var b = 2 +\n"), playground.DefaultLimits)
+	if e, ok := err.(*playground.Error); !ok || e.Pos.Line != 2 || e.Pos.Column != 14 || out.Text != "" {
+		t.Fatal(err, out.Text)
+	}
+
+	out, err = playground.Run(ctx, []byte("1\n  x + 1\n"), playground.DefaultLimits)
+	if e, ok := err.(*playground.Error); !ok || e.Error() != "playground: 2:3: undefined x" || out.Text != "1\n" {
+		t.Fatal(err, out.Text)
+	}
+
+	l := playground.DefaultLimits
+	l.Output = 4
+	if out, err = playground.Run(ctx, []byte("'abc'\n'def'\n"), l); err != playground.ErrOutput || out.Text != "'abc" {
+		t.Fatal(err, out.Text)
+	}
+
+	l = playground.DefaultLimits
+	l.Memory = 100
+	if _, err = playground.Run(ctx, []byte("var a = '"+strings.Repeat("x", 100)+"'\n"), l); err != playground.ErrMemory {
+		t.Fatal(err)
+	}
+
+	l = playground.DefaultLimits
+	l.Time = time.Nanosecond
+	l.Expr.MaxSteps = 1
+	if _, err = playground.Run(ctx, []byte("1 + 2 + 3\n"), l); err != playground.ErrTime {
+		t.Fatal(err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err = playground.Run(canceled, []byte("1\n"), playground.DefaultLimits); err != context.Canceled {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包在 scanner.FileSet 之上转换字节偏移量, rune 偏移量和 UTF-16 编码单元偏移量.
+//
+// LSP 协议使用从 0 开始的行号和 UTF-16 编码单元的行内偏移量, 而 zxx 的 Pos 是字节偏移量.
+// 本包的行号和行内偏移量都从 0 开始, 与 LSP 一致.
+package position
+
+import (
+	"unicode/utf8"
+
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// Unit 是行内偏移量的单位
+type Unit int
+
+const (
+	Byte  Unit = iota // 字节
+	Rune              // rune
+	UTF16             // UTF-16 编码单元
+)
+
+// Converter 在一个源文件上转换位置.
+type Converter struct {
+	file *scanner.File
+	src  []byte
+}
+
+// New 返回 file 的 Converter, src 必须是添加 file 时的源码.
+func New(file *scanner.File, src []byte) *Converter {
+	return &Converter{file, src}
+}
+
+// Offset 返回 pos 所在的行号和以 u 为单位的行内偏移量. pos 不属于该文件时 ok 为 false.
+func (c *Converter) Offset(pos scanner.Pos, u Unit) (line, char int, ok bool) {
+	p := c.file.Position(pos)
+	if !p.IsValid() {
+		return
+	}
+	start := p.Offset - p.Column + 1
+	return p.Line - 1, count(c.src[start:p.Offset], u), true
+}
+
+// Pos 返回第 line 行以 u 为单位的行内偏移量 char 对应的 Pos.
+// char 超出行尾时返回行尾的 Pos, 落在 UTF-16 代理对中间时返回该 rune 的 Pos.
+// line 超出范围时 ok 为 false.
+func (c *Converter) Pos(line, char int, u Unit) (pos scanner.Pos, ok bool) {
+	start, end, ok := c.line(line)
+	if !ok {
+		return
+	}
+	offset := start
+	for n := 0; offset < end; {
+		r, size := rune(c.src[offset]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRune(c.src[offset:end])
+		}
+		switch u {
+		case Byte:
+			n += size
+		case Rune:
+			n++
+		case UTF16:
+			n += width16(r)
+		}
+		if n > char {
+			break
+		}
+		offset += size
+	}
+	return c.file.Pos(offset), true
+}
+
+// Convert 把第 line 行以 from 为单位的行内偏移量 char 转换为以 to 为单位.
+func (c *Converter) Convert(line, char int, from, to Unit) (int, bool) {
+	pos, ok := c.Pos(line, char, from)
+	if !ok {
+		return 0, false
+	}
+	_, char, ok = c.Offset(pos, to)
+	return char, ok
+}
+
+// line 返回第 line 行不含换行符的字节区间
+func (c *Converter) line(line int) (start, end int, ok bool) {
+	n := c.file.LineCount()
+	if line < 0 || line >= n {
+		return
+	}
+	start = c.file.Offset(c.file.LineStart(line + 1))
+	if line+1 == n {
+		return start, c.file.Size(), true
+	}
+	end = c.file.Offset(c.file.LineStart(line + 2))
+	if end > start && c.src[end-1] == '\n' {
+		end--
+	}
+	if end > start && c.src[end-1] == '\r' {
+		end--
+	}
+	return start, end, true
+}
+
+// count 返回 b 以 u 为单位的长度
+func count(b []byte, u Unit) int {
+	switch u {
+	case Rune:
+		return utf8.RuneCount(b)
+	case UTF16:
+		n := 0
+		for _, r := range string(b) {
+			n += width16(r)
+		}
+		return n
+	}
+	return len(b)
+}
+
+// width16 返回 r 的 UTF-16 编码单元数
+func width16(r rune) int {
+	if r >= 0x10000 && r <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
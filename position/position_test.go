@@ -0,0 +1,57 @@
+package position_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/position"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+func TestConverter(t *testing.T) {
+	src := []byte("var a\r\n中😀x\nb")
+	fset := scanner.NewFileSet()
+	fset.AddFile("other", []byte("skip"))
+	file := fset.AddFile("", src)
+	c := position.New(file, src)
+
+	for _, s := range []struct {
+		offset, line        int
+		bytes, runes, utf16 int
+	}{
+		{0, 0, 0, 0, 0},
+		{4, 0, 4, 4, 4},
+		{7, 1, 0, 0, 0},  // 中
+		{10, 1, 3, 1, 1}, // 😀
+		{14, 1, 7, 2, 3}, // x
+		{16, 2, 0, 0, 0}, // b
+		{17, 2, 1, 1, 1}, // EOF
+	} {
+		pos := file.Pos(s.offset)
+		for u, want := range []int{s.bytes, s.runes, s.utf16} {
+			line, char, ok := c.Offset(pos, position.Unit(u))
+			if !ok || line != s.line || char != want {
+				t.Fatal(s.offset, u, line, char)
+			}
+			if got, ok := c.Pos(line, char, position.Unit(u)); !ok || got != pos {
+				t.Fatal(s.offset, u, got)
+			}
+		}
+	}
+
+	// 超出行尾, 代理对中间
+	if pos, _ := c.Pos(0, 100, position.UTF16); pos != file.Pos(5) {
+		t.Fatal(pos)
+	}
+	if pos, _ := c.Pos(1, 2, position.UTF16); pos != file.Pos(10) {
+		t.Fatal(pos)
+	}
+	if char, ok := c.Convert(1, 3, position.UTF16, position.Byte); !ok || char != 7 {
+		t.Fatal(char)
+	}
+	if _, ok := c.Pos(3, 0, position.Byte); ok {
+		t.Fatal("want false")
+	}
+	if _, _, ok := c.Offset(0, position.Byte); ok {
+		t.Fatal("want false")
+	}
+}
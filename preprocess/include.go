@@ -0,0 +1,110 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包实现可选的预处理阶段, 用于配置风格的 zxx 源码.
+//
+// 包含指示使用编译参数写法, 必须是完整的 use 声明:
+//
+//	use "-include=path/to/file.zxx"
+//
+// 该声明被替换为目标文件的 Token, 相对路径以包含者所在目录为准.
+// 所有 Symbol 的 Pos 都是 FileSet 中的绝对位置, 可经 FileSet 映射回原文件.
+//
+// 宏指示 use "-macro=name" 调用注册的 Go 函数为之后的声明生成代码, 参见 Expand.
+package preprocess
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+const includePrefix = "-include="
+
+// Loader 返回文件 name 的内容.
+type Loader func(name string) ([]byte, error)
+
+// Include 添加 src 到 fset, 返回展开所有包含指示后的 Symbols, 不包括 EOF.
+func Include(fset *scanner.FileSet, name string, src []byte, load Loader) ([]parser.Symbol, error) {
+	return include(fset, name, src, load, nil)
+}
+
+// Hook 返回展开包含指示的 parser.Hook, file 是被解析源码在 fset 中的 File.
+// Hook 把所有 Pos 转换为 fset 中的绝对位置.
+func Hook(fset *scanner.FileSet, file *scanner.File, load Loader) parser.Hook {
+	return hook(fset, file, load, []string{file.Name()})
+}
+
+func include(fset *scanner.FileSet, name string, src []byte, load Loader, stack []string) ([]parser.Symbol, error) {
+	file := fset.AddFile(name, src)
+	return parser.FastHook(src, hook(fset, file, load, append(stack, name)), nil)
+}
+
+func hook(fset *scanner.FileSet, file *scanner.File, load Loader, stack []string) parser.Hook {
+	var (
+		held bool
+		use  parser.Symbol
+	)
+
+	return func(sym parser.Symbol) (syms []parser.Symbol, err error) {
+		sym.Pos = file.Pos(int(sym.Pos))
+
+		if held {
+			held = false
+			if name, ok := directive(sym); ok {
+				return expand(fset, file, use, name, load, stack)
+			}
+			syms = append(syms, use)
+		}
+
+		if sym.Tok == token.USE {
+			held, use = true, sym
+			return
+		}
+		return append(syms, sym), nil
+	}
+}
+
+// directive 返回 sym 是否为包含指示及目标文件名
+func directive(sym parser.Symbol) (string, bool) {
+	if sym.Tok != token.VALSTRING || len(sym.Source) < 2 {
+		return "", false
+	}
+	s := sym.Source[1 : len(sym.Source)-1]
+	if !strings.HasPrefix(s, includePrefix) {
+		return "", false
+	}
+	return s[len(includePrefix):], true
+}
+
+func expand(fset *scanner.FileSet, file *scanner.File, use parser.Symbol, name string, load Loader, stack []string) ([]parser.Symbol, error) {
+	if name == "" {
+		return nil, posError(fset, use.Pos, "preprocess: missing include file name")
+	}
+	if !path.IsAbs(name) {
+		name = path.Join(path.Dir(file.Name()), name)
+	}
+
+	for _, s := range stack {
+		if s == name {
+			return nil, posError(fset, use.Pos,
+				"preprocess: include cycle "+strings.Join(append(stack, name), " -> "))
+		}
+	}
+
+	src, err := load(name)
+	if err != nil {
+		return nil, posError(fset, use.Pos, "preprocess: "+err.Error())
+	}
+	return include(fset, name, src, load, stack)
+}
+
+func posError(fset *scanner.FileSet, pos scanner.Pos, msg string) error {
+	name, position := fset.Position(pos)
+	return errors.New(position.String(name) + ": " + msg)
+}
@@ -0,0 +1,62 @@
+package preprocess_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/preprocess"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+var files = map[string]string{
+	"conf/main.zxx": "var int a = 1\nuse \"-include=base.zxx\"\nvar int c = 3\n",
+	"conf/base.zxx": "var int b = 2\n",
+	"loop/a.zxx":    "use \"-include=b.zxx\"\n",
+	"loop/b.zxx":    "use \"-include=a.zxx\"\n",
+}
+
+func load(name string) ([]byte, error) {
+	src, ok := files[name]
+	if !ok {
+		return nil, errors.New("not found " + name)
+	}
+	return []byte(src), nil
+}
+
+func TestInclude(t *testing.T) {
+	fset := scanner.NewFileSet()
+	name := "conf/main.zxx"
+	syms, err := preprocess.Include(fset, name, []byte(files[name]), load)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var codes []string
+	for _, sym := range syms {
+		codes = append(codes, strings.TrimSpace(sym.Source))
+	}
+	got := strings.Join(codes, " ")
+	want := "var int a = 1  var int b = 2   var int c = 3 "
+	if got != want {
+		t.Fatalf("%q", got)
+	}
+
+	for _, sym := range syms {
+		if sym.Source != "b" {
+			continue
+		}
+		file, pos := fset.Position(sym.Pos)
+		if file != "conf/base.zxx" || pos.Line != 1 || pos.Column != 9 {
+			t.Fatal(file, pos)
+		}
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	name := "loop/a.zxx"
+	_, err := preprocess.Include(scanner.NewFileSet(), name, []byte(files[name]), load)
+	if err == nil || !strings.Contains(err.Error(), "loop/a.zxx -> loop/b.zxx -> loop/a.zxx") {
+		t.Fatal(err)
+	}
+}
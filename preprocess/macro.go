@@ -0,0 +1,211 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package preprocess
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+const macroPrefix = "-macro="
+
+// Macro 为声明生成代码, 例如为类型派生样板方法.
+// decl 是宏指示之后的首个顶层声明, syms 是它的 Symbol, Pos 是源码中的偏移量.
+// 返回的源码只能包含声明, 这些声明被插入到 decl 之后.
+type Macro func(decl ast.Syntax, syms []parser.Symbol) (string, error)
+
+// Macros 是按名字注册的宏
+type Macros map[string]Macro
+
+// Expand 添加 src 到 fset, 返回展开所有宏指示后的 Symbols, 不包括 EOF.
+// 宏指示使用编译参数写法, 必须是完整的 use 声明, 作用于之后的首个顶层声明:
+//
+//	use "-macro=getters"
+//	var int count = 0
+//
+// 宏指示被删除. 生成的 Symbol 的 Origin 是 ast.OriginMacro, Pos 都是宏指示的位置,
+// 诊断信息据此指向触发展开的指示. 其它 Symbol 的 Pos 是 fset 中的绝对位置.
+//
+// 展开是卫生的: 生成的源码必须能解析为声明, 声明的名字不能与源码中的声明
+// 或者其它宏生成的声明同名, 否则返回错误. 生成的源码中的宏指示不会被展开.
+func Expand(fset *scanner.FileSet, name string, src []byte, macros Macros) ([]parser.Symbol, error) {
+	file := fset.AddFile(name, src)
+	syms, err := parser.Fast(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	sf, err := parser.ParseSyntaxSymbols(syms)
+	if err != nil {
+		return nil, err
+	}
+	errorf := func(pos scanner.Pos, msg string) error {
+		return posError(fset, file.Pos(int(pos)), "preprocess: "+msg)
+	}
+
+	names := map[string]bool{}
+	for _, decl := range sf.Decls {
+		declNames(decl, func(id *ast.Ident) { names[id.Name.Source] = true })
+	}
+
+	var out []parser.Symbol
+	var pending []parser.Symbol // 待插入到 end 之前的生成代码
+	end := scanner.Pos(-1)
+	for i := 0; i < len(syms); i++ {
+		sym := syms[i]
+		if end >= 0 && sym.Pos >= end {
+			out, pending, end = append(out, pending...), nil, -1
+		}
+		if sym.Tok == token.USE && i+1 < len(syms) {
+			if macro, ok := macroDirective(syms[i+1]); ok {
+				gen, to, err := expandMacro(sf, syms, sym.Pos, macro, macros, names, errorf)
+				if err != nil {
+					return nil, err
+				}
+				for j := range gen {
+					gen[j].Pos, gen[j].Origin = file.Pos(int(sym.Pos)), ast.OriginMacro
+				}
+				// 连续的宏指示作用于同一个声明, 按指示的顺序插入
+				pending, end = append(pending, gen...), to
+				i++
+				continue
+			}
+		}
+		sym.Pos = file.Pos(int(sym.Pos))
+		out = append(out, sym)
+	}
+	return append(out, pending...), nil
+}
+
+// macroDirective 返回 sym 是否为宏指示及宏的名字
+func macroDirective(sym parser.Symbol) (string, bool) {
+	if sym.Tok != token.VALSTRING || len(sym.Source) < 2 {
+		return "", false
+	}
+	s := sym.Source[1 : len(sym.Source)-1]
+	if !strings.HasPrefix(s, macroPrefix) {
+		return "", false
+	}
+	return s[len(macroPrefix):], true
+}
+
+// isDirective 返回 decl 是否为宏指示 use "-macro=name"
+func isDirective(decl ast.Syntax) bool {
+	d, ok := decl.(*ast.GenDecl)
+	if !ok || d.Tok != token.USE || len(d.Specs) != 1 || len(d.Specs[0].Values) != 1 {
+		return false
+	}
+	lit, ok := d.Specs[0].Values[0].(*ast.BasicLit)
+	if !ok {
+		return false
+	}
+	_, ok = macroDirective(lit.Value)
+	return ok
+}
+
+// expandMacro 展开位于 at 的宏指示, 返回生成的 Symbol 及其插入位置, 即目标声明的结束位置.
+// 生成的声明名字被加入 names.
+func expandMacro(sf *ast.SourceFile, syms []parser.Symbol, at scanner.Pos, name string,
+	macros Macros, names map[string]bool, errorf func(scanner.Pos, string) error) ([]parser.Symbol, scanner.Pos, error) {
+
+	macro := macros[name]
+	if macro == nil {
+		return nil, 0, errorf(at, "unknown macro "+strconv.Quote(name))
+	}
+
+	var decl ast.Syntax
+	for _, d := range sf.Decls {
+		if d.Pos() >= at && !isDirective(d) {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		return nil, 0, errorf(at, "macro "+name+" has no declaration to expand")
+	}
+	if _, ok := decl.(*ast.BadSyntax); ok {
+		return nil, 0, errorf(decl.Pos(), "macro "+name+" applied to invalid declaration")
+	}
+
+	var target []parser.Symbol
+	for _, sym := range syms {
+		if sym.Pos >= decl.Pos() && sym.Pos < decl.End() {
+			target = append(target, sym)
+		}
+	}
+	code, err := macro(decl, target)
+	if err != nil {
+		return nil, 0, errorf(at, "macro "+name+": "+err.Error())
+	}
+
+	gen, err := parser.Fast([]byte(code), nil)
+	for _, sym := range gen {
+		if err == nil && sym.Tok == token.PLACEHOLDER {
+			err = errors.New("generated code is not a declaration: " + strconv.Quote(sym.Source))
+		}
+	}
+	if err == nil {
+		var gf *ast.SourceFile
+		if gf, err = parser.ParseSyntaxSymbols(gen); err == nil {
+			err = hygiene(gf, code, names)
+		}
+	}
+	if err != nil {
+		return nil, 0, errorf(at, "macro "+name+": "+err.Error())
+	}
+
+	// 生成的声明与目标声明之间换行
+	gen = append([]parser.Symbol{{Tok: token.NL, Source: "\n"}}, gen...)
+	return gen, decl.End(), nil
+}
+
+// hygiene 检查生成的源码 code 只包含声明, 且声明的名字未被占用
+func hygiene(gf *ast.SourceFile, code string, names map[string]bool) (err error) {
+	for _, decl := range gf.Decls {
+		switch d := decl.(type) {
+		case *ast.BadSyntax:
+			return errors.New("generated code is not a declaration: " + strconv.Quote(code[d.From:d.To]))
+		case *ast.GenDecl:
+			if d.Tok == token.USE {
+				return errors.New("generated code cannot use packages")
+			}
+		}
+		declNames(decl, func(id *ast.Ident) {
+			if err == nil && names[id.Name.Source] {
+				err = errors.New("generated declaration " + id.Name.Source + " conflicts with existing declaration")
+			}
+			names[id.Name.Source] = true
+		})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// declNames 对顶层声明 decl 声明的每个名字调用 f
+func declNames(decl ast.Syntax, f func(*ast.Ident)) {
+	switch d := decl.(type) {
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			for _, id := range spec.Names {
+				f(id)
+			}
+		}
+	case *ast.FuncDecl:
+		if d.Name != nil {
+			f(d.Name)
+		}
+	case *ast.TypeDecl:
+		if d.Name != nil {
+			f(d.Name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package preprocess_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/preprocess"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// getters 为 var 声明的每个名字生成 func getName() out Type
+func getters(decl ast.Syntax, syms []parser.Symbol) (string, error) {
+	d, ok := decl.(*ast.GenDecl)
+	if !ok || len(d.Specs) == 0 {
+		return "", errors.New("want var declaration")
+	}
+	var b strings.Builder
+	for _, spec := range d.Specs {
+		typ := spec.Type[0].Source
+		for _, id := range spec.Names {
+			name := id.Name.Source
+			b.WriteString("func get" + strings.ToUpper(name[:1]) + name[1:] + "() out " + typ + " [\n\tout " + name + "\n]\n")
+		}
+	}
+	return b.String(), nil
+}
+
+var macros = preprocess.Macros{
+	"getters": getters,
+	"dup": func(ast.Syntax, []parser.Symbol) (string, error) {
+		return "var int count = 1\n", nil
+	},
+	"stmt": func(ast.Syntax, []parser.Symbol) (string, error) {
+		return "x = 1\n", nil
+	},
+}
+
+func TestExpand(t *testing.T) {
+	src := "use \"-macro=getters\"\nvar int count = 0\n\nproc main() [\n]\n"
+	fset := scanner.NewFileSet()
+	fset.AddFile("other.zxx", []byte("var int x = 1\n"))
+	syms, err := preprocess.Expand(fset, "main.zxx", []byte(src), macros)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var codes []string
+	// syms[0] 是宏指示之后的换行
+	directive := syms[0].Pos.Offset(-len(`use "-macro=getters"`))
+	for _, sym := range syms {
+		if sym.Origin == ast.OriginMacro {
+			if sym.Pos != directive {
+				t.Fatalf("synthetic %q at %d, want %d", sym.Source, sym.Pos, directive)
+			}
+		} else if sym.Pos < directive {
+			t.Fatalf("source %q at %d", sym.Source, sym.Pos)
+		}
+		codes = append(codes, strings.TrimSpace(sym.Source))
+	}
+	got := strings.Join(strings.Fields(strings.Join(codes, " ")), " ")
+	want := "var int count = 0 func getCount ( ) out int [ out count ] proc main ( ) [ ]"
+	if got != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+
+	sf, err := parser.ParseSyntaxSymbols(syms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sf.Decls) != 3 {
+		t.Fatalf("%d declarations", len(sf.Decls))
+	}
+	if fn, ok := sf.Decls[1].(*ast.FuncDecl); !ok || fn.Name.Name.Source != "getCount" {
+		t.Fatalf("generated %#v", sf.Decls[1])
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	tests := []struct{ src, err string }{
+		{"use \"-macro=nope\"\nvar int a = 1\n", `unknown macro "nope"`},
+		{"var int a = 1\nuse \"-macro=getters\"\n", "no declaration"},
+		{"use \"-macro=dup\"\nvar int count = 0\n", "count conflicts"},
+		{"use \"-macro=getters\"\nvar int count = 0\nfunc getCount() out int [\n\tout 1\n]\n", "getCount conflicts"},
+		{"use \"-macro=stmt\"\nvar int a = 1\n", "not a declaration"},
+		{"use \"-macro=getters\"\nproc main() [\n]\n", "want var declaration"},
+	}
+	for _, tt := range tests {
+		_, err := preprocess.Expand(scanner.NewFileSet(), "a.zxx", []byte(tt.src), macros)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%q: err = %v, want %q", tt.src, err, tt.err)
+		}
+	}
+}
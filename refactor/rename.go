@@ -0,0 +1,89 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包实现源码到源码的重构, 结果是 edit.Edit 列表, 可以直接应用或者转换为编辑器的文本编辑.
+package refactor
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ZxxLang/zxx/edit"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Source 是参与重构的源文件, File 必须属于调用时的 FileSet.
+type Source struct {
+	File *scanner.File
+	Src  []byte
+}
+
+// Rename 把 files 中的名字 oldName 重命名为 newName, 返回每个文件的编辑.
+//
+// pos 必须位于某个 oldName 的出现处, 用于确认重命名的对象.
+// 目前没有作用域信息, 名字在全部 files 中是同一个对象:
+// IDENT 以及 MEMBER, MEMBERS 中与 oldName 相同的段都被改写.
+// 字符串, 注释和占位文本不会被修改.
+// 如果 newName 不是合法的标识符, 或者已经在 files 中出现, 返回错误.
+func Rename(fset *scanner.FileSet, files []Source, oldName string, pos scanner.Pos, newName string) (map[*scanner.File][]edit.Edit, error) {
+	if !isIdent(oldName) || !isIdent(newName) {
+		return nil, errors.New("refactor: invalid identifier")
+	}
+	at := fset.File(pos)
+	if at == nil {
+		return nil, errors.New("refactor: pos is not in the FileSet")
+	}
+
+	found := false
+	result := map[*scanner.File][]edit.Edit{}
+	for _, f := range files {
+		syms, err := parser.Fast(f.Src, nil)
+		if err != nil {
+			return nil, errors.New("refactor: " + f.File.Name() + ": " + err.Error())
+		}
+
+		var edits []edit.Edit
+		for _, sym := range syms {
+			if sym.Tok != token.IDENT && sym.Tok != token.MEMBER && sym.Tok != token.MEMBERS {
+				continue
+			}
+			offset := int(sym.Pos)
+			for _, name := range strings.Split(sym.Source, ".") {
+				if name == newName {
+					return nil, errors.New("refactor: " + newName + " already exists in " + f.File.Name())
+				}
+				if name == oldName {
+					if f.File == at && at.Pos(offset) <= pos && pos < at.Pos(offset+len(name)) {
+						found = true
+					}
+					edits = append(edits, edit.Edit{Start: offset, End: offset + len(name), New: newName})
+				}
+				offset += len(name) + 1
+			}
+		}
+		if edits != nil {
+			result[f.File] = edits
+		}
+	}
+
+	if !found {
+		return nil, errors.New("refactor: no " + oldName + " at pos")
+	}
+	return result, nil
+}
+
+// isIdent 返回 s 是否为合法的标识符
+func isIdent(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for _, c := range s {
+		if c != '_' && !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return token.Lookup(s) == token.PLACEHOLDER
+}
@@ -0,0 +1,47 @@
+package refactor_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/edit"
+	"github.com/ZxxLang/zxx/refactor"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+func TestRename(t *testing.T) {
+	a := []byte("use 'b'\nvar int sum = 1 // sum 不变\nproc add int x [\n\tout sum.value add x\n]\n")
+	b := []byte("var string s = 'sum'\nvar t = a.sum\n")
+
+	fset := scanner.NewFileSet()
+	fa, fb := fset.AddFile("a.zxx", a), fset.AddFile("b.zxx", b)
+	files := []refactor.Source{{fa, a}, {fb, b}}
+
+	result, err := refactor.Rename(fset, files, "sum", fa.Pos(16), "total")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[*scanner.File]string{
+		fa: "use 'b'\nvar int total = 1 // sum 不变\nproc add int x [\n\tout total.value add x\n]\n",
+		fb: "var string s = 'sum'\nvar t = a.total\n",
+	}
+	for f, src := range map[*scanner.File][]byte{fa: a, fb: b} {
+		out, err := edit.Apply(src, result[f])
+		if err != nil || string(out) != want[f] {
+			t.Fatalf("%s %q %v", f.Name(), out, err)
+		}
+	}
+
+	for _, c := range []struct {
+		pos  scanner.Pos
+		name string
+	}{
+		{fa.Pos(0), "total"}, // pos 不在 sum 上
+		{fa.Pos(16), "x"},    // 已经存在
+		{fa.Pos(16), "var"},  // 保留字
+	} {
+		if _, err = refactor.Rename(fset, files, "sum", c.pos, c.name); err == nil {
+			t.Fatal(c)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Position 的列以字节为单位. 源码常含有中文等多字节文本,
+// 编辑器和终端需要其它单位的列号, 下列方法在 pos 所在行的源码上计算.
+// src 必须是添加 f 时的源码, pos 无效时返回 0.
+
+// Column 返回 pos 的列号, 从 1 开始, 以 rune 为单位.
+func (f *File) Column(src []byte, pos Pos) int {
+	line, ok := f.linePrefix(src, pos)
+	if !ok {
+		return 0
+	}
+	return utf8.RuneCount(line) + 1
+}
+
+// UTF16Column 返回 pos 的列号, 从 1 开始, 以 UTF-16 编码单元为单位, 供 LSP 使用.
+func (f *File) UTF16Column(src []byte, pos Pos) int {
+	line, ok := f.linePrefix(src, pos)
+	if !ok {
+		return 0
+	}
+	col := 1
+	for _, r := range string(line) {
+		if r >= 0x10000 {
+			col++
+		}
+		col++
+	}
+	return col
+}
+
+// VisualColumn 返回 pos 在终端中显示的列号, 从 1 开始.
+// 全角字符占两列, 组合字符不占列, TAB 前进到下一个 tabWidth 的整数倍.
+// tabWidth 不大于 0 时 TAB 占一列.
+func (f *File) VisualColumn(src []byte, pos Pos, tabWidth int) int {
+	line, ok := f.linePrefix(src, pos)
+	if !ok {
+		return 0
+	}
+	col := 0
+	for _, r := range string(line) {
+		if r == '\t' && tabWidth > 0 {
+			col += tabWidth - col%tabWidth
+			continue
+		}
+		col += RuneWidth(r)
+	}
+	return col + 1
+}
+
+// RuneWidth 返回 r 在终端中显示的宽度: 全角字符为 2, 组合字符和控制字符为 0, 其它为 1.
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7F:
+		return 0
+	case r < 0x300:
+		return 1
+	case r == 0x200B || r == 0x200D || unicode.In(r, unicode.Mn, unicode.Me):
+		return 0
+	}
+	for _, w := range wide {
+		if r < w[0] {
+			break
+		}
+		if r <= w[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// wide 是东亚全角和宽字符的主要区间, 按起点排序
+var wide = [][2]rune{
+	{0x1100, 0x115F},   // 谚文字母
+	{0x2E80, 0x303E},   // 部首, 康熙部首, CJK 符号和标点
+	{0x3041, 0x33FF},   // 假名, 注音, 谚文兼容字母, CJK 兼容
+	{0x3400, 0x4DBF},   // CJK 扩展 A
+	{0x4E00, 0x9FFF},   // CJK 统一汉字
+	{0xA000, 0xA4CF},   // 彝文
+	{0xAC00, 0xD7A3},   // 谚文音节
+	{0xF900, 0xFAFF},   // CJK 兼容汉字
+	{0xFE30, 0xFE4F},   // CJK 兼容形式
+	{0xFF00, 0xFF60},   // 全角 ASCII
+	{0xFFE0, 0xFFE6},   // 全角符号
+	{0x1F300, 0x1F64F}, // 符号和表情
+	{0x1F900, 0x1F9FF}, // 补充符号和表情
+	{0x20000, 0x2FFFD}, // CJK 扩展 B 及之后
+	{0x30000, 0x3FFFD},
+}
+
+// linePrefix 返回 pos 所在行从行首到 pos 的源码
+func (f *File) linePrefix(src []byte, pos Pos) ([]byte, bool) {
+	p := f.Position(pos)
+	if !p.IsValid() || p.Offset > len(src) {
+		return nil, false
+	}
+	return src[p.Offset-p.Column+1 : p.Offset], true
+}
@@ -0,0 +1,132 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// FileSet 为多个源文件分配互不重叠的 Pos 区间, 使 Pos 可映射回源文件.
+// 首个文件的 base 为 0, 因此单文件时 Pos 就是字节偏移量.
+// FileSet 可以被多个 goroutine 同时使用, 查找只持有读锁.
+type FileSet struct {
+	mu    sync.RWMutex
+	base  int
+	files []*File
+}
+
+// File 记录源文件在 FileSet 中的 Pos 区间和行首偏移量. File 创建后不再改变.
+type File struct {
+	name  string
+	base  int
+	size  int
+	lines []int // 行首字节偏移量, lines[0] 总是 0
+}
+
+// NewFileSet 返回一个空的 FileSet.
+func NewFileSet() *FileSet {
+	return &FileSet{}
+}
+
+// AddFile 添加名为 name 的源文件 src, 返回的 File 占用 [base, base+len(src)] 区间.
+// 区间末尾的 Pos 表示该文件的 EOF.
+func (s *FileSet) AddFile(name string, src []byte) *File {
+	// 在锁外扫描行首
+	return s.AddFileLines(name, len(src), lineStarts(src))
+}
+
+// AddFileLines 同 AddFile, 但使用扫描时记录的行首偏移量 lines, 不再重新扫描源码.
+// lines 通常来自扫描到 EOF 之后的 scanner.Lines, lines[0] 必须是 0.
+func (s *FileSet) AddFileLines(name string, size int, lines []int) *File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := &File{name: name, base: s.base, size: size, lines: lines}
+	s.base += f.size + 1
+	s.files = append(s.files, f)
+	return f
+}
+
+// File 返回 pos 所属的 File, 如果 pos 不属于任何文件返回 nil.
+func (s *FileSet) File(pos Pos) *File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := sort.Search(len(s.files), func(i int) bool {
+		return s.files[i].base > int(pos)
+	}) - 1
+	if i < 0 || int(pos) > s.files[i].base+s.files[i].size {
+		return nil
+	}
+	return s.files[i]
+}
+
+// Position 返回 pos 所属文件名和位置.
+// 如果 pos 不属于任何文件返回 "" 和无效位置.
+func (s *FileSet) Position(pos Pos) (string, token.Position) {
+	f := s.File(pos)
+	if f == nil {
+		return "", token.Position{}
+	}
+	return f.name, f.Position(pos)
+}
+
+func (f *File) Name() string { return f.name }
+func (f *File) Base() int    { return f.base }
+func (f *File) Size() int    { return f.size }
+
+// Pos 返回文件内字节偏移量 offset 对应的 Pos.
+func (f *File) Pos(offset int) Pos {
+	return Pos(f.base + offset)
+}
+
+// Offset 返回 pos 对应的文件内字节偏移量.
+func (f *File) Offset(pos Pos) int {
+	return int(pos) - f.base
+}
+
+// LineCount 返回文件的行数.
+func (f *File) LineCount() int { return len(f.lines) }
+
+// LineStart 返回第 line 行行首的 Pos, line 从 1 开始.
+// line 超出范围时返回 -1.
+func (f *File) LineStart(line int) Pos {
+	if line < 1 || line > len(f.lines) {
+		return -1
+	}
+	return Pos(f.base + f.lines[line-1])
+}
+
+// Position 返回 pos 对应的行列位置, 列以字节为单位.
+func (f *File) Position(pos Pos) token.Position {
+	offset := f.Offset(pos)
+	if offset < 0 || offset > f.size {
+		return token.Position{}
+	}
+	i := sort.SearchInts(f.lines, offset+1) - 1
+	return token.Position{
+		Offset: offset,
+		Line:   i + 1,
+		Column: offset - f.lines[i] + 1,
+	}
+}
+
+// lineStarts 返回 src 的行首偏移量, 换行符可以是 LF, CR 或 CRLF.
+func lineStarts(src []byte) []int {
+	lines := []int{0}
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '\r':
+			if i+1 < len(src) && src[i+1] == '\n' {
+				i++
+			}
+			fallthrough
+		case '\n':
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,92 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanner
+
+// Mark 记录扫描状态, 用于 Reset 回溯.
+type Mark struct {
+	offset int
+	nl     uint16
+}
+
+// Mark 返回当前扫描状态. 预读的 Symbol 不影响 Mark.
+func (s *scanner) Mark() Mark {
+	if s.ahead.n != 0 {
+		m := s.ahead.front()
+		return Mark{m.offset, m.nl}
+	}
+	return Mark{s.offset, s.nl}
+}
+
+// Reset 回溯到 mark 记录的扫描状态, 并丢弃预读的 Symbol.
+func (s *scanner) Reset(mark Mark) {
+	s.ahead.n = 0
+	s.offset, s.nl = mark.offset, mark.nl
+}
+
+// Peek 返回之后第 n 个 Symbol 但不前进, n 从 0 开始.
+// 即 Peek(0) 和下次 Symbol() 的返回值相同.
+func (s *scanner) Peek(n int) (symbol string, ok bool) {
+	for s.ahead.n <= n {
+		m := ahead{offset: s.offset, nl: s.nl}
+		m.symbol, m.ok = s.symbol()
+		s.lineTo(s.offset)
+		s.ahead.push(m)
+	}
+	m := s.ahead.at(n)
+	return m.symbol, m.ok
+}
+
+// sync 丢弃预读的 Symbol, 使扫描位置回到首个预读 Symbol 之前.
+// 直接操作 offset 的方法必须先调用 sync.
+func (s *scanner) sync() {
+	if s.ahead.n != 0 {
+		s.Reset(s.Mark())
+	}
+}
+
+// ahead 是一个预读的 Symbol 及其之前的扫描状态
+type ahead struct {
+	offset int
+	nl     uint16
+	symbol string
+	ok     bool
+}
+
+// ring 是预读 Symbol 的环形缓冲, 容量总是 2 的幂
+type ring struct {
+	buf     []ahead
+	head, n int
+}
+
+func (r *ring) front() *ahead {
+	return &r.buf[r.head]
+}
+
+func (r *ring) at(i int) *ahead {
+	return &r.buf[(r.head+i)&(len(r.buf)-1)]
+}
+
+func (r *ring) push(m ahead) {
+	if r.n == len(r.buf) {
+		size := 4
+		if len(r.buf) != 0 {
+			size = 2 * len(r.buf)
+		}
+		buf := make([]ahead, size)
+		for i := 0; i < r.n; i++ {
+			buf[i] = *r.at(i)
+		}
+		r.buf, r.head = buf, 0
+	}
+	r.buf[(r.head+r.n)&(len(r.buf)-1)] = m
+	r.n++
+}
+
+func (r *ring) pop() ahead {
+	m := r.buf[r.head]
+	r.head = (r.head + 1) & (len(r.buf) - 1)
+	r.n--
+	return m
+}
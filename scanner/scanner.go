@@ -4,15 +4,48 @@
 
 package scanner
 
-import "unicode/utf8"
+import (
+	"unicode/utf8"
+	"unsafe"
+)
+
+// isWord 表示可以连续构成标识符或数值的 ASCII 字符: 字母, 数字和 '_'
+var isWord = [256]bool{'_': true}
+
+// single 是单个 ASCII 字符的字符串, 避免为常见的单字符符号分配内存
+var single [utf8.RuneSelf]string
+
+func init() {
+	for c := range single {
+		single[c] = string(rune(c))
+	}
+	for c := '0'; c <= '9'; c++ {
+		isWord[c] = true
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		isWord[c] = true
+		isWord[c-'a'+'A'] = true
+	}
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// Scanner 是 New 和 NewShared 返回的扫描器, 调用者可以保存它而不必经过接口.
+type Scanner = scanner
 
 // scanner 每次扫描一个字符.
 type scanner struct {
 	src []byte // source
+	str string // 零拷贝模式下与 src 共享内存的源码
 
 	size   int
 	offset int // 当前扫描所处 src 的字节偏移量
 	nl     uint16
+
+	ahead ring // Peek 预读的 Symbol
+
+	lines []int // 已扫描部分的行首偏移量
+	seen  int   // lines 已经检查到的偏移量
 }
 
 type Pos int
@@ -26,7 +59,7 @@ func (pos Pos) Offset(offset int) Pos {
 // New 返回一个 scanner 并进行一些前期处理.
 // 如果有 BOM 头则移动当前位置到 BOM 之后, scanner.Pos() 一定为 3.
 func New(source []byte) (scan *scanner) {
-	scan = &scanner{src: source, size: len(source)}
+	scan = &scanner{src: source, size: len(source), lines: []int{0}}
 
 	// BOM 0xFEFF
 	if len(source) > 2 && source[0] == 0xef && source[1] == 0xbb && source[2] == 0xbf {
@@ -35,7 +68,20 @@ func New(source []byte) (scan *scanner) {
 	return scan
 }
 
+// NewShared 同 New, 但返回的符号都直接引用 source 的内存, 扫描不再为符号分配内存.
+// 调用者在符号的使用期间不得修改 source.
+func NewShared(source []byte) (scan *scanner) {
+	scan = New(source)
+	if len(source) != 0 {
+		scan.str = unsafe.String(&source[0], len(source))
+	}
+	return scan
+}
+
 func (s *scanner) Pos() Pos {
+	if s.ahead.n != 0 {
+		return Pos(s.ahead.front().offset)
+	}
 	return Pos(s.offset)
 }
 
@@ -44,6 +90,9 @@ func (s *scanner) Pos() Pos {
 // Rune()   得不到  0, 0
 // Symbol() 得不到 "", true
 func (s *scanner) IsEOF() bool {
+	if s.ahead.n != 0 {
+		return s.ahead.front().offset >= s.size
+	}
 	return s.offset >= s.size
 }
 
@@ -53,12 +102,22 @@ func (s *scanner) IsEOF() bool {
 //	13  CR   风格 "\r"   0xd
 //	218 CRLF 风格 "\r\n" 0xda
 func (s *scanner) Eol() uint16 {
+	if s.ahead.n != 0 {
+		return s.ahead.front().nl
+	}
 	return s.nl
 }
 
 // Rune 返回当前位置的 rune 和 UTF-8 编码长度, 并前进 size 个字节.
 // 如果 r == 0 && size == 0 , 表示扫描结束
 func (s *scanner) Rune() (r rune, size int) {
+	s.sync()
+	r, size = s.rune()
+	s.lineTo(s.offset)
+	return
+}
+
+func (s *scanner) rune() (r rune, size int) {
 	if s.offset >= s.size {
 		return
 	}
@@ -79,13 +138,31 @@ func (s *scanner) Rune() (r rune, size int) {
 //	"" 表示扫描结束
 //	连续的			' ', '\t', '/', '-', '+', '\n', '\r', '\r\n'
 //	两个字符		运算符, 操作符
-//	单个字符		运算符, 定界符, 单双引号
+//	单个字符		运算符, 定界符, 单双引号, 反引号
 //  多字节字符		直到行尾
 //	连续的字符		直到空白, 运算符, 操作符, 定界符, 换行
 //  连续的成员		a.b.c
 func (s *scanner) Symbol() (symbol string, ok bool) {
+	if s.ahead.n != 0 {
+		m := s.ahead.pop()
+		return m.symbol, m.ok
+	}
+	symbol, ok = s.symbol()
+	s.lineTo(s.offset)
+	return
+}
+
+func (s *scanner) symbol() (symbol string, ok bool) {
 	offset := s.offset
-	r, size := s.Rune()
+	var r rune
+	var size int
+	if s.offset < s.size && s.src[s.offset] < utf8.RuneSelf {
+		// ASCII 不需要解码
+		r, size = rune(s.src[s.offset]), 1
+		s.offset++
+	} else {
+		r, size = s.rune()
+	}
 
 	if size == 0 {
 		ok = r == 0
@@ -94,7 +171,7 @@ func (s *scanner) Symbol() (symbol string, ok bool) {
 	ok = true
 
 	if s.offset >= s.size {
-		symbol = string(r)
+		symbol = s.text(offset)
 		return
 	}
 
@@ -103,7 +180,7 @@ func (s *scanner) Symbol() (symbol string, ok bool) {
 		for s.offset != s.size && s.src[s.offset] != '\n' && s.src[s.offset] != '\r' {
 			s.offset++
 		}
-		symbol = string(s.src[offset:s.offset])
+		symbol = s.text(offset)
 		return
 	}
 
@@ -120,12 +197,11 @@ func (s *scanner) Symbol() (symbol string, ok bool) {
 			}
 		}
 
-		for s.offset != s.size && (nc == '\r' || nc == '\n') {
-			nc = s.src[s.offset]
+		for s.offset != s.size && (s.src[s.offset] == '\r' || s.src[s.offset] == '\n') {
 			s.offset++
 		}
 
-		symbol = string(s.src[offset:s.offset])
+		symbol = s.text(offset)
 
 	case ' ', '\t': // 连续的
 
@@ -133,7 +209,7 @@ func (s *scanner) Symbol() (symbol string, ok bool) {
 			s.offset++
 		}
 
-		symbol = string(s.src[offset:s.offset])
+		symbol = s.text(offset)
 
 	case '-', '/': // 可后跟 '=' 或者连续多个的
 		if s.src[s.offset] == '=' {
@@ -143,35 +219,55 @@ func (s *scanner) Symbol() (symbol string, ok bool) {
 				s.offset++
 			}
 		}
-		symbol = string(s.src[offset:s.offset])
+		symbol = s.text(offset)
 
 	case '&', '|', '!', '~', '*', '=': // 可后跟 '='
 		if s.src[s.offset] == '=' {
 			s.offset++
 		}
-		symbol = string(s.src[offset:s.offset])
+		symbol = s.text(offset)
 
 	case '>', '<', '+': // 可后跟 '=', 或者重复一个
 		if s.src[s.offset] == '=' || s.src[s.offset] == c {
 			s.offset++
 		}
-		symbol = string(s.src[offset:s.offset])
+		symbol = s.text(offset)
 
-	case ',', '"', '\'', '{', '}', '(', ')', '[', ']', ';': // 单个
-		symbol = string(s.src[offset:s.offset])
+	case ',', '"', '\'', '`', '{', '}', '(', ')', '[', ']', ';': // 单个
+		symbol = s.text(offset)
 	default:
+		// 范围运算符 ..
+		if c == '.' && s.offset != s.size && s.src[s.offset] == '.' {
+			s.offset++
+			symbol = s.text(offset)
+			break
+		}
+		// 单独的 ':', 映射的键值之间可以没有空白, 例如 ['a':1], [x:y]
+		if c == ':' {
+			symbol = s.text(offset)
+			break
+		}
 		// 不严格的判断 integer, float, datetime, 标识符
 		var num byte
 		if c >= '0' && c <= '9' {
 			num = 3 // 三种可能 integer, float, datetime
-		} else if c >= 'a' && c <= 'z' || c >= 'Z' && c <= 'Z' || c == '_' {
+		} else if isWord[c] {
 			num = 1 // 标识符
 		}
 
 		for s.offset != s.size {
+			c = s.src[s.offset]
+			// 快速路径, 连续的字母, 数字和 '_'
+			if isWord[c] {
+				s.offset++
+				continue
+			}
 
-			switch s.src[s.offset] {
+			switch c {
 			case '.':
+				if num == 3 && s.offset+1 != s.size && s.src[s.offset+1] == '.' {
+					break // 整数之后的范围运算符, 例如 1..10
+				}
 				if num == 3 {
 					num = 'f' // float
 					s.offset++
@@ -181,68 +277,121 @@ func (s *scanner) Symbol() (symbol string, ok bool) {
 					break
 				}
 				// 成员写法
-				s.offset++
-				if s.offset != s.size {
-					c = s.src[s.offset]
-					if !(c >= 'a' && c <= 'z' || c >= 'Z' && c <= 'Z' || c == '_') {
-						s.offset--
-						break
-					}
+				if s.offset+1 == s.size || !isWord[s.src[s.offset+1]] || isDigit(s.src[s.offset+1]) {
+					break
 				}
+				s.offset++
 				continue
 			case ':':
-				if num == 3 {
+				if num == 3 || num == 'd' {
 					num = 'd' // datetime
 					s.offset++
 					continue
 				}
-			case '+':
-				if num == 'd' || num == 'f' {
+			case '+', '-':
+				if num == 'd' && c == '+' {
 					s.offset++
 					continue
 				}
-			default:
-				c = s.src[s.offset]
-				if c < '0' ||
-					c > '9' && c < 'A' ||
-					c > 'Z' && c < 'a' ||
-					c > 'z' {
-					break
+				// 十进制数的指数符号, 例如 1e-5, 2E+3
+				if (num == 3 || num == 'f') && s.offset+1 != s.size && isDigit(s.src[s.offset+1]) &&
+					exponent(s.src[offset:s.offset]) {
+					num = 'f'
+					s.offset++
+					continue
 				}
-				s.offset++
-				continue
 			}
 			break
 		}
-		symbol = string(s.src[offset:s.offset])
+		symbol = s.text(offset)
 	}
 
 	return
 }
 
-// Tail 返回当前位置到行尾的字符串.
-// 如果参数 nl 为 true, 返回字符讲包含连续的换行符.
-// 如果当前位置已经是换行, 那么会返回 "".
-// 该方法不检查非法 UTF-8 编码, 下一个符号将是换行符或 EOF.
+// exponent 返回以数字开始的 code 是否为以 e 或 E 结尾的十进制数, 之后的 '+', '-' 是指数的符号
+func exponent(code []byte) bool {
+	last := code[len(code)-1]
+	if last != 'e' && last != 'E' || len(code) < 2 {
+		return false
+	}
+	if code[0] == '0' && (code[1] == 'x' || code[1] == 'X') {
+		return false
+	}
+	for _, c := range code[:len(code)-1] {
+		if !isDigit(c) && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// text 返回 src[offset:s.offset], 单个 ASCII 字符使用预先分配的字符串
+func (s *scanner) text(offset int) string {
+	if s.offset-offset == 1 && s.src[offset] < utf8.RuneSelf {
+		return single[s.src[offset]]
+	}
+	return s.slice(offset, s.offset)
+}
+
+// slice 返回 src[from:to] 的字符串, 零拷贝模式下不分配内存
+func (s *scanner) slice(from, to int) string {
+	if s.str != "" {
+		return s.str[from:to]
+	}
+	return string(s.src[from:to])
+}
+
+// Source 返回 [from, to) 区间的源码, 通常用于取得跨越多个符号的连续源码,
+// 避免逐个拼接符号. 零拷贝模式下不分配内存.
+func (s *scanner) Source(from, to Pos) string {
+	return s.slice(int(from), int(to))
+}
+
+// TailSameLine 返回当前位置到行尾的源码, 不包括换行符, 之后的下一个符号是换行符或 EOF.
+// 换行符可以是 LF, CR 或 CRLF, 当前位置已经是换行符时返回 "".
+// 该方法不检查非法 UTF-8 编码.
+func (s *scanner) TailSameLine() string {
+	return s.tail(false)
+}
+
+// TailWithNewline 同 TailSameLine, 但包括行尾的换行符以及紧随其后的换行符, 即之后的空行,
+// 之后的下一个符号位于非空行的行首或者是 EOF. 当前位置已经是换行符时返回这些换行符.
+// 只含空白的行不是空行.
+func (s *scanner) TailWithNewline() string {
+	return s.tail(true)
+}
+
+// Tail 在 nl 为 true 时同 TailWithNewline, 否则同 TailSameLine.
+//
+// Deprecated: 使用 TailSameLine 或 TailWithNewline.
 func (s *scanner) Tail(nl bool) string {
+	return s.tail(nl)
+}
+
+// tail 是 TailSameLine, TailWithNewline 的实现
+func (s *scanner) tail(nl bool) string {
+	s.sync()
 	offset := s.offset
 
 	for s.offset < s.size && s.src[s.offset] != '\n' && s.src[s.offset] != '\r' {
 		s.offset++
 	}
 
-	for nl && (s.src[s.offset] == '\n' || s.src[s.offset] == '\r') {
+	for nl && s.offset < s.size && (s.src[s.offset] == '\n' || s.src[s.offset] == '\r') {
 		s.offset++
 	}
 
-	return string(s.src[offset:s.offset])
+	s.lineTo(s.offset)
+	return s.slice(offset, s.offset)
 }
 
 // EndString 返回当前位置到 escape 指示的 Zxx 的字符串结尾.
 // escape 为 true 表示支持 "\" 逃逸的双引号结尾的字符串.
 // 否则表示单引号结尾字符串.
-// 目前只支持简单的单个字符逃逸
+// 逃逸只用于确定结尾, 其合法性由 lexutil.Unquote 检查.
 func (s *scanner) EndString(escape bool) string {
+	s.sync()
 	offset := s.offset
 	for ; s.offset < s.size; s.offset++ {
 		if escape && s.src[s.offset] == '\\' {
@@ -255,5 +404,80 @@ func (s *scanner) EndString(escape bool) string {
 			break
 		}
 	}
-	return string(s.src[offset:s.offset])
+	if s.offset > s.size {
+		s.offset = s.size
+	}
+	s.lineTo(s.offset)
+	return s.slice(offset, s.offset)
+}
+
+// EndInterpString 返回当前位置到双引号字符串结尾或者插值 '{' 之前的文本.
+// 如果 interp 为 true, 表示遇到了插值, 下一个符号是 "{".
+// '\' 逃逸的 '{' 和 '"' 以及 "\u{...}" 中的 '{' 不会结束文本.
+func (s *scanner) EndInterpString() (text string, interp bool) {
+	s.sync()
+	offset := s.offset
+	for ; s.offset < s.size; s.offset++ {
+		switch s.src[s.offset] {
+		case '\\':
+			s.offset++
+			if s.offset+1 < s.size && s.src[s.offset] == 'u' && s.src[s.offset+1] == '{' {
+				// 跳到 '}', 未闭合时停在 '"' 之前
+				for s.offset++; s.offset+1 < s.size && s.src[s.offset] != '}' && s.src[s.offset+1] != '"'; s.offset++ {
+				}
+			}
+		case '{':
+			s.lineTo(s.offset)
+			return s.slice(offset, s.offset), true
+		case '"':
+			s.offset++
+			s.lineTo(s.offset)
+			return s.slice(offset, s.offset), false
+		}
+	}
+	if s.offset > s.size {
+		s.offset = s.size
+	}
+	s.lineTo(s.offset)
+	return s.slice(offset, s.offset), false
+}
+
+// EndRawString 返回当前位置到反引号结尾的原始字符串, 可以跨越多行.
+func (s *scanner) EndRawString() string {
+	s.sync()
+	offset := s.offset
+	for ; s.offset < s.size; s.offset++ {
+		if s.src[s.offset] == '`' {
+			s.offset++
+			break
+		}
+	}
+	s.lineTo(s.offset)
+	return s.slice(offset, s.offset)
+}
+
+// Lines 返回已扫描部分的行首字节偏移量, lines[0] 总是 0.
+// 扫描到 EOF 之后即是全部源码的行首偏移量, 可用于 FileSet.AddFileLines.
+// 换行符可以是 LF, CR 或 CRLF. 返回值不应被修改.
+func (s *scanner) Lines() []int {
+	return s.lines
+}
+
+// lineTo 记录 end 之前尚未检查的行首偏移量, 每个字节只被检查一次
+func (s *scanner) lineTo(end int) {
+	i := s.seen
+	for ; i < end; i++ {
+		switch s.src[i] {
+		case '\r':
+			if i+1 < s.size && s.src[i+1] == '\n' {
+				i++
+			}
+			fallthrough
+		case '\n':
+			s.lines = append(s.lines, i+1)
+		}
+	}
+	if i > s.seen {
+		s.seen = i
+	}
 }
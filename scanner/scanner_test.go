@@ -1,7 +1,10 @@
 package scanner_test
 
 import (
+	"strings"
+	"sync"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/ZxxLang/zxx/scanner"
 )
@@ -13,6 +16,26 @@ var good []seq = []seq{
 		`use a 'b'`,
 		`use`, ` `, `a`, ` `, `'`, `b`, `'`,
 	},
+	seq{
+		`max_len = 1_000`,
+		`max_len`, ` `, `=`, ` `, `1_000`,
+	},
+	seq{
+		"a\n\n\r\nb",
+		"a", "\n\n\r\n", "b",
+	},
+	seq{
+		`a.Name.b2 + 1.5 + x.`,
+		`a.Name.b2`, ` `, `+`, ` `, `1.5`, ` `, `+`, ` `, `x`, `.`,
+	},
+	seq{
+		`1e-5 2E+3 1.5e+10 0x1e-5 1e-x 1.5+2`,
+		`1e-5`, ` `, `2E+3`, ` `, `1.5e+10`, ` `, `0x1e`, `-`, `5`, ` `, `1e`, `-`, `x`, ` `, `1.5`, `+`, `2`,
+	},
+	seq{
+		`['a':1, x:y, 21:49]`,
+		`[`, `'`, `a`, `'`, `:`, `1`, `,`, ` `, `x`, `:`, `y`, `,`, ` `, `21:49`, `]`,
+	},
 }
 
 func Test_eq(t *testing.T) {
@@ -26,3 +49,257 @@ func Test_eq(t *testing.T) {
 		}
 	}
 }
+
+func Test_peek(t *testing.T) {
+	scan := scanner.New([]byte("use a 'b'\nvar"))
+
+	for i, s := range []string{`use`, ` `, `a`, ` `, `'`, `b`, `'`, "\n", `var`, ``} {
+		code, ok := scan.Peek(i)
+		if !ok || s != code {
+			t.Fatal(i, ok, s, code)
+		}
+	}
+	if scan.Pos() != 0 {
+		t.Fatal(scan.Pos())
+	}
+
+	code, _ := scan.Symbol()
+	mark := scan.Mark()
+	if code != `use` || scan.Pos() != 3 {
+		t.Fatal(code, scan.Pos())
+	}
+
+	scan.Symbol()
+	scan.Symbol()
+	if tail := scan.TailSameLine(); tail != ` 'b'` {
+		t.Fatalf("%q", tail)
+	}
+
+	scan.Reset(mark)
+	for _, s := range []string{` `, `a`} {
+		if code, _ = scan.Symbol(); code != s {
+			t.Fatal(s, code)
+		}
+	}
+}
+
+func TestTail(t *testing.T) {
+	for _, c := range []struct {
+		src       string
+		skip      int // 之前的符号个数
+		same, all string
+		next      string // TailWithNewline 之后的符号
+	}{
+		{"a // c\nb", 1, " // c", " // c\n", "b"},
+		{"a // c\r\nb", 1, " // c", " // c\r\n", "b"},
+		{"a // c\rb", 1, " // c", " // c\r", "b"},
+		{"a // c\r\n\r\n\nb", 1, " // c", " // c\r\n\r\n\n", "b"},
+		{"a // c\r\n \r\nb", 1, " // c", " // c\r\n", " "},
+		{"a\r\nb", 1, "", "\r\n", "b"},
+		{"a // c", 1, " // c", " // c", ""},
+		{"a // c\r", 1, " // c", " // c\r", ""},
+	} {
+		for _, nl := range []bool{false, true} {
+			scan := scanner.New([]byte(c.src))
+			for i := 0; i < c.skip; i++ {
+				scan.Symbol()
+			}
+			var tail, want string
+			if nl {
+				tail, want = scan.TailWithNewline(), c.all
+			} else {
+				tail, want = scan.TailSameLine(), c.same
+			}
+			if tail != want {
+				t.Fatalf("%q %v: %q, want %q", c.src, nl, tail, want)
+			}
+			next, _ := scan.Symbol()
+			if !nl && strings.Trim(next, "\r\n") != "" {
+				t.Fatalf("%q: %q follows TailSameLine", c.src, next)
+			}
+			if nl && next != c.next {
+				t.Fatalf("%q: %q follows TailWithNewline, want %q", c.src, next, c.next)
+			}
+			// 行首偏移量与 FileSet 的计算一致
+			scan.Tail(true)
+			want2 := scanner.NewFileSet().AddFile("", []byte(c.src)).LineCount()
+			if len(scan.Lines()) != want2 {
+				t.Fatalf("%q: %v", c.src, scan.Lines())
+			}
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	for _, src := range []string{
+		"", "a", "a\nb", "a\r\nb\rc\n", "'x\ny'\n// z\r\n\n", "\"a{b}\nc\"\n",
+	} {
+		scan := scanner.New([]byte(src))
+		// Peek 和 Reset 造成的回溯不应重复记录
+		mark := scan.Mark()
+		scan.Peek(3)
+		scan.Reset(mark)
+		for {
+			s, ok := scan.Symbol()
+			if !ok {
+				t.Fatal(src)
+			}
+			if s == "'" {
+				scan.EndString(false)
+			} else if s == "\"" {
+				scan.EndInterpString()
+			} else if s == "" {
+				break
+			}
+		}
+
+		fs := scanner.NewFileSet()
+		want := fs.AddFile("want", []byte(src))
+		got := fs.AddFileLines("got", len(src), scan.Lines())
+		if want.LineCount() != got.LineCount() {
+			t.Fatalf("%q %v", src, scan.Lines())
+		}
+		for i := 0; i <= len(src); i++ {
+			w := want.Position(want.Pos(i))
+			g := got.Position(got.Pos(i))
+			if w.Line != g.Line || w.Column != g.Column {
+				t.Fatalf("%q %d %v %v", src, i, w, g)
+			}
+		}
+	}
+}
+
+func TestColumn(t *testing.T) {
+	src := []byte("a\n\t中文 x\r\n😀e\u0301 b")
+	file := scanner.NewFileSet().AddFile("", src)
+	for _, c := range []struct {
+		offset, col, utf16, visual int
+	}{
+		{0, 1, 1, 1},
+		{3, 2, 2, 5},        // 中
+		{10, 5, 5, 10},      // x
+		{13, 1, 1, 1},       // 😀
+		{20, 4, 5, 4},       // é 之后的空格, é 由 e 和组合字符组成
+		{len(src), 6, 7, 6}, // EOF
+	} {
+		pos := file.Pos(c.offset)
+		col, utf16, visual := file.Column(src, pos), file.UTF16Column(src, pos), file.VisualColumn(src, pos, 4)
+		if col != c.col || utf16 != c.utf16 || visual != c.visual {
+			t.Fatal(c.offset, col, utf16, visual)
+		}
+	}
+	if file.Column(src, scanner.Pos(len(src)+1)) != 0 {
+		t.Fatal("want 0")
+	}
+}
+
+// symbols 返回 src 的全部符号, 遇到非法编码时 ok 为 false
+func symbols(src []byte) (ss []string, ok bool) {
+	scan := scanner.New(src)
+	for {
+		s, ok := scan.Symbol()
+		if !ok || s == "" {
+			return ss, ok
+		}
+		ss = append(ss, s)
+	}
+}
+
+func FuzzSymbol(f *testing.F) {
+	f.Add([]byte("use a 'b'\nvar x = 1_000 + a.b.c"))
+	f.Add([]byte("20160202T22:48:33Z 1.5e+10 \t\t// 注释\r\n"))
+	f.Add([]byte(benchSource))
+	f.Fuzz(func(t *testing.T, src []byte) {
+		if !utf8.Valid(src) || len(src) > 2 && string(src[:3]) == "\xef\xbb\xbf" {
+			return
+		}
+		ss, ok := symbols(src)
+		if !ok {
+			t.Fatal("invalid UTF-8")
+		}
+		// 符号首尾相接, 覆盖全部源码
+		if strings.Join(ss, "") != string(src) {
+			t.Fatalf("%q", ss)
+		}
+		// Peek 和 Symbol 一致
+		scan := scanner.New(src)
+		for i, s := range ss {
+			if code, _ := scan.Peek(i); code != s {
+				t.Fatal(i, code, s)
+			}
+		}
+	})
+}
+
+const benchSource = `
+pub
+	type Point
+		int x, y
+
+	func int add(int a, int b)
+		out a + b
+
+var (
+	string name = 'zxx' // name
+	count = 0x10 shl 2
+	start = 20160202T22:48:33
+)
+
+proc main()
+	for i = 0, i < 10, i++
+		if i mod 2 == 0 and not done
+			count += add(i, 1)
+		else
+			break
+`
+
+func BenchmarkSymbol(b *testing.B) {
+	src := []byte(strings.Repeat(benchSource, 100))
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scan := scanner.New(src)
+		for s, ok := scan.Symbol(); ok && s != ""; s, ok = scan.Symbol() {
+		}
+	}
+}
+
+func BenchmarkSymbolShared(b *testing.B) {
+	src := []byte(strings.Repeat(benchSource, 100))
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scan := scanner.NewShared(src)
+		for s, ok := scan.Symbol(); ok && s != ""; s, ok = scan.Symbol() {
+		}
+	}
+}
+
+func TestFileSetConcurrent(t *testing.T) {
+	fs := scanner.NewFileSet()
+	first := fs.AddFile("a", []byte("a\nb\n"))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				fs.AddFile("b", []byte("x\ny"))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if name, pos := fs.Position(first.Pos(2)); name != "a" || pos.Line != 2 {
+					t.Error(name, pos)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if f := fs.File(scanner.Pos(5 + 400*4 - 1)); f == nil || f.Name() != "b" {
+		t.Fatal(f)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包识别源文件的编码并转换为 scanner 处理的 UTF-8, 同时保留偏移量的映射,
+// 使报告的位置指向原始的字节.
+//
+// 识别的顺序是 BOM, 包含 NUL 的 UTF-16, 合法的 UTF-8, 合法的 GB18030,
+// 都不符合时按 UTF-8 处理. 非法的编码被替换为 U+FFFD.
+package source
+
+import (
+	"sort"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+)
+
+// Encoding 是源文件的编码
+type Encoding int
+
+const (
+	UTF8 Encoding = iota
+	UTF16LE
+	UTF16BE
+	GB18030
+)
+
+var encodings = [...]string{"UTF-8", "UTF-16LE", "UTF-16BE", "GB18030"}
+
+func (e Encoding) String() string {
+	if e >= 0 && int(e) < len(encodings) {
+		return encodings[e]
+	}
+	return "Encoding(" + strconv.Itoa(int(e)) + ")"
+}
+
+// boms 是各编码的 BOM
+var boms = [...]string{
+	UTF8:    "\xef\xbb\xbf",
+	UTF16LE: "\xff\xfe",
+	UTF16BE: "\xfe\xff",
+	GB18030: "\x84\x31\x95\x33",
+}
+
+// File 是转换为 UTF-8 的源文件
+type File struct {
+	Encoding Encoding
+	BOM      bool   // 原始字节是否有 BOM, Text 不包含 BOM
+	Text     []byte // 合法的 UTF-8 源码
+	Invalid  []int  // 被替换为 U+FFFD 的非法编码在原始字节中的偏移量
+
+	marks []mark
+}
+
+// mark 表示 Text 中从 text 开始的字节对应原始字节中从 src 开始的字节,
+// 直到下一个 mark. inside 为 true 时这些字节属于同一个字符, 都对应 src.
+type mark struct {
+	text, src int
+	inside    bool
+}
+
+// Detect 返回 src 的编码和 BOM 的长度
+func Detect(src []byte) (enc Encoding, bom int) {
+	for e, b := range boms {
+		if len(src) >= len(b) && string(src[:len(b)]) == b {
+			return Encoding(e), len(b)
+		}
+	}
+	// zxx 源码不包含 NUL, 大量的 NUL 是 ASCII 字符的 UTF-16 高位
+	var even, odd, pairs int
+	for i := 0; i+1 < len(src) && i < 1024; i += 2 {
+		pairs++
+		if src[i] == 0 {
+			even++
+		}
+		if src[i+1] == 0 {
+			odd++
+		}
+	}
+	switch {
+	case odd != 0 && odd >= pairs/4 && even == 0:
+		return UTF16LE, 0
+	case even != 0 && even >= pairs/4 && odd == 0:
+		return UTF16BE, 0
+	}
+	if utf8.Valid(src) {
+		return UTF8, 0
+	}
+
+	for i := 0; i < len(src); {
+		_, size := decodeGB18030(src[i:])
+		if size == 0 {
+			return UTF8, 0
+		}
+		i += size
+	}
+	return GB18030, 0
+}
+
+// Decode 识别 src 的编码并转换为 UTF-8
+func Decode(src []byte) *File {
+	enc, _ := Detect(src)
+	return DecodeAs(src, enc)
+}
+
+// DecodeAs 按编码 enc 转换 src 为 UTF-8, src 开头的 enc 的 BOM 被去掉.
+func DecodeAs(src []byte, enc Encoding) *File {
+	f := &File{Encoding: enc}
+	in := 0
+	if b := boms[enc]; len(src) >= len(b) && string(src[:len(b)]) == b {
+		f.BOM, in = true, len(b)
+		f.marks = append(f.marks, mark{0, in, false})
+	}
+	if enc == UTF8 && utf8.Valid(src[in:]) {
+		f.Text = src[in:]
+		return f
+	}
+
+	text := make([]byte, 0, len(src)+len(src)/2)
+	for in < len(src) {
+		r, size := decode(src[in:], enc)
+		if size == 0 {
+			// 连续的非法编码替换为一个 U+FFFD
+			f.Invalid = append(f.Invalid, in)
+			for size = 1; in+size < len(src); size++ {
+				if _, n := decode(src[in+size:], enc); n != 0 {
+					break
+				}
+			}
+			r = utf8.RuneError
+		}
+		n := len(text)
+		text = utf8.AppendRune(text, r)
+		if len(text)-n != size {
+			if len(text)-n > 1 {
+				f.marks = append(f.marks, mark{n + 1, in, true})
+			}
+			f.marks = append(f.marks, mark{len(text), in + size, false})
+		}
+		in += size
+	}
+	f.Text = text
+	return f
+}
+
+// decode 返回 p 开头的 enc 编码的字符及其长度, 编码非法时 size 为 0.
+func decode(p []byte, enc Encoding) (r rune, size int) {
+	switch enc {
+	case UTF16LE, UTF16BE:
+		unit := func(i int) rune {
+			if enc == UTF16LE {
+				return rune(p[i]) | rune(p[i+1])<<8
+			}
+			return rune(p[i])<<8 | rune(p[i+1])
+		}
+		if len(p) < 2 {
+			return
+		}
+		r = unit(0)
+		if !utf16.IsSurrogate(r) {
+			return r, 2
+		}
+		if len(p) < 4 {
+			return 0, 0
+		}
+		if r = utf16.DecodeRune(r, unit(2)); r == utf8.RuneError {
+			return 0, 0
+		}
+		return r, 4
+	case GB18030:
+		return decodeGB18030(p)
+	}
+	r, size = utf8.DecodeRune(p)
+	if r == utf8.RuneError && size == 1 {
+		return 0, 0
+	}
+	return
+}
+
+// Offset 返回 Text 中的位置 pos 在原始字节中的偏移量.
+// 位于一个转换后的多字节字符内部的位置对应该字符的开头.
+func (f *File) Offset(pos scanner.Pos) int {
+	i := sort.Search(len(f.marks), func(i int) bool { return f.marks[i].text > int(pos) }) - 1
+	if i < 0 {
+		return int(pos)
+	}
+	m := f.marks[i]
+	if m.inside {
+		return m.src
+	}
+	return m.src + int(pos) - m.text
+}
+
+// Parse 按配置 c 解析 f.Text. 错误中 *parser.Error 的 Pos 被转换为原始字节的偏移量,
+// 原始编码不是 UTF-8 时 Fixes 无法直接应用于原始字节, 被去掉.
+func (f *File) Parse(c *parser.Config) (*ast.File, error) {
+	file, err := c.Parse(f.Text)
+	switch e := err.(type) {
+	case *parser.Error:
+		err = f.convert(e)
+	case parser.ErrorList:
+		list := make(parser.ErrorList, len(e))
+		for i, err := range e {
+			if x, ok := err.(*parser.Error); ok {
+				err = f.convert(x)
+			}
+			list[i] = err
+		}
+		err = list
+	}
+	return file, err
+}
+
+func (f *File) convert(e *parser.Error) *parser.Error {
+	x := &parser.Error{Pos: scanner.Pos(f.Offset(e.Pos)), Msg: e.Msg}
+	if f.Encoding == UTF8 {
+		for _, fix := range e.Fixes {
+			x.Fixes = append(x.Fixes, parser.TextEdit{
+				Pos:     scanner.Pos(f.Offset(fix.Pos)),
+				End:     scanner.Pos(f.Offset(fix.End)),
+				NewText: fix.NewText,
+			})
+		}
+	}
+	return x
+}
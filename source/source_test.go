@@ -0,0 +1,72 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/source"
+)
+
+func TestDecode(t *testing.T) {
+	for _, s := range []struct {
+		src  string
+		enc  source.Encoding
+		bom  bool
+		text string
+	}{
+		{"var a = 1\n", source.UTF8, false, "var a = 1\n"},
+		{"\xef\xbb\xbfvar 名 = 1", source.UTF8, true, "var 名 = 1"},
+		{"var \xc3\xfb = '\xd6\xd0\x810\x898\x949\xfc6'\n", source.GB18030, false, "var 名 = '中ß😀'\n"},
+		{"\x84\x31\x95\x33a", source.GB18030, true, "a"},
+		{"v\x00a\x00r\x00 \x00\rT", source.UTF16LE, false, "var 名"},
+		{"\xfe\xff\x00v\xd8\x3d\xde\x00", source.UTF16BE, true, "v😀"},
+		{"var a = '\x80\x80'", source.UTF8, false, "var a = '�'"},
+	} {
+		f := source.Decode([]byte(s.src))
+		if f.Encoding != s.enc || f.BOM != s.bom || string(f.Text) != s.text {
+			t.Fatalf("%q: %v %v %q", s.src, f.Encoding, f.BOM, f.Text)
+		}
+	}
+
+	f := source.Decode([]byte("a\x80b\xd6"))
+	if string(f.Text) != "a�b�" || len(f.Invalid) != 2 || f.Invalid[0] != 1 || f.Invalid[1] != 3 {
+		t.Fatalf("%q %v", f.Text, f.Invalid)
+	}
+	if source.Encoding(9).String() != "Encoding(9)" || source.GB18030.String() != "GB18030" {
+		t.Fatal(source.GB18030)
+	}
+}
+
+func TestOffset(t *testing.T) {
+	f := source.Decode([]byte("var \xc3\xfb = '\xd6\xd0\x810\x898\x949\xfc6'\n"))
+	for text, want := range map[int]int{0: 0, 4: 4, 5: 4, 7: 6, 11: 10, 14: 12, 15: 12, 16: 16, 20: 20, 22: 22} {
+		if got := f.Offset(scanner.Pos(text)); got != want {
+			t.Fatal(text, got, want)
+		}
+	}
+
+	f = source.Decode([]byte("\xef\xbb\xbfvar"))
+	if f.Offset(0) != 3 || f.Offset(3) != 6 {
+		t.Fatal(f.Offset(0))
+	}
+}
+
+func TestParse(t *testing.T) {
+	// 缺少结尾引号的字符串, 错误位置是原始字节中引号的位置
+	f := source.Decode([]byte("var a = '\xd6\xd0' + '\xd6\xd0\nvar b = 1\n"))
+	_, err := f.Parse(&parser.Config{MaxErrors: 10})
+	list, ok := err.(parser.ErrorList)
+	if !ok || len(list) == 0 {
+		t.Fatal(err)
+	}
+	if e, ok := list[0].(*parser.Error); !ok || e.Pos != 15 || e.Fixes != nil {
+		t.Fatal(list[0])
+	}
+
+	f = source.Decode([]byte("\xef\xbb\xbfvar a = 'x\n"))
+	_, err = f.Parse(&parser.Config{MaxErrors: 10})
+	if fixes := parser.Fixes(err); len(fixes) != 1 || fixes[0].Pos != 13 {
+		t.Fatal(err)
+	}
+}
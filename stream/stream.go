@@ -0,0 +1,190 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包编码 parser.Fast 产生的 Symbol 序列, 使其它语言编写的工具无需链接 Go
+// 即可使用 zxx 的 Token 流. 提供两种格式:
+//
+// 二进制格式以 "zxxs" 和版本字节 1 开始, 之后每个 Symbol 依次是
+//
+//	varint  与上一个 Symbol 的 Pos 之差, 首个 Symbol 与 0 相比
+//	uvarint Tok
+//	byte    Origin
+//	uvarint Source 的字节数, 之后是 Source
+//
+// JSON 格式每行一个对象, 例如
+//
+//	{"pos":4,"tok":7,"name":"IDENT","source":"x"}
+//
+// 其中 name 是 Tok 的名称, 只用于阅读, 解码只使用 tok. origin 为 0 时省略.
+//
+// Encoder.Push, JSONEncoder.Push 的签名与 parser.Fast 的回调相同, 可以流式编码.
+package stream
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+const magic = "zxxs\x01"
+
+// maxSource 是解码时接受的 Source 字节数上限, 防止损坏的输入导致巨大的分配
+const maxSource = 1 << 30
+
+// ErrFormat 表示输入不是本包的二进制格式
+var ErrFormat = errors.New("stream: invalid format")
+
+// Encoder 以二进制格式写出 Symbol, 写完之后必须调用 Flush.
+type Encoder struct {
+	w      *bufio.Writer
+	pos    scanner.Pos
+	header bool
+	buf    [binary.MaxVarintLen64]byte
+}
+
+// NewEncoder 返回写入 w 的 Encoder
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode 写出 sym
+func (e *Encoder) Encode(sym ast.Symbol) error {
+	if !e.header {
+		e.header = true
+		e.w.WriteString(magic)
+	}
+	e.w.Write(e.buf[:binary.PutVarint(e.buf[:], int64(sym.Pos)-int64(e.pos))])
+	e.w.Write(e.buf[:binary.PutUvarint(e.buf[:], uint64(sym.Tok))])
+	e.w.WriteByte(byte(sym.Origin))
+	e.w.Write(e.buf[:binary.PutUvarint(e.buf[:], uint64(len(sym.Source)))])
+	_, err := e.w.WriteString(sym.Source)
+	e.pos = sym.Pos
+	return err
+}
+
+// Push 写出来自源码的 Symbol, 可以作为 parser.Fast 的回调. EOF 被忽略.
+func (e *Encoder) Push(pos scanner.Pos, tok token.Token, source string) error {
+	if tok == token.EOF {
+		return nil
+	}
+	return e.Encode(ast.Symbol{Pos: pos, Tok: tok, Source: source})
+}
+
+// Flush 写出缓冲的数据. 没有 Symbol 时也写出格式头.
+func (e *Encoder) Flush() error {
+	if !e.header {
+		e.header = true
+		e.w.WriteString(magic)
+	}
+	return e.w.Flush()
+}
+
+// Decoder 读取二进制格式的 Symbol
+type Decoder struct {
+	r      *bufio.Reader
+	pos    scanner.Pos
+	header bool
+}
+
+// NewDecoder 返回读取 r 的 Decoder
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode 返回下一个 Symbol, 输入结束时返回 io.EOF.
+func (d *Decoder) Decode() (sym ast.Symbol, err error) {
+	if !d.header {
+		var head [len(magic)]byte
+		if _, err = io.ReadFull(d.r, head[:]); err != nil || string(head[:]) != magic {
+			return sym, ErrFormat
+		}
+		d.header = true
+	}
+
+	delta, err := binary.ReadVarint(d.r)
+	if err != nil {
+		if err == io.EOF {
+			return sym, io.EOF
+		}
+		return sym, ErrFormat
+	}
+	tok, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		return sym, ErrFormat
+	}
+	origin, err := d.r.ReadByte()
+	if err != nil {
+		return sym, ErrFormat
+	}
+	n, err := binary.ReadUvarint(d.r)
+	if err != nil || n > maxSource {
+		return sym, ErrFormat
+	}
+	source := make([]byte, n)
+	if _, err = io.ReadFull(d.r, source); err != nil {
+		return sym, ErrFormat
+	}
+
+	d.pos += scanner.Pos(delta)
+	return ast.Symbol{Pos: d.pos, Tok: token.Token(tok), Source: string(source), Origin: ast.Origin(origin)}, nil
+}
+
+// jsonSymbol 是 JSON 格式的一行
+type jsonSymbol struct {
+	Pos    int    `json:"pos"`
+	Tok    int    `json:"tok"`
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Origin int    `json:"origin,omitempty"`
+}
+
+// JSONEncoder 以 JSON 格式写出 Symbol, 每行一个.
+type JSONEncoder struct {
+	enc *json.Encoder
+}
+
+// NewJSONEncoder 返回写入 w 的 JSONEncoder
+func NewJSONEncoder(w io.Writer) *JSONEncoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &JSONEncoder{enc}
+}
+
+// Encode 写出 sym
+func (e *JSONEncoder) Encode(sym ast.Symbol) error {
+	return e.enc.Encode(jsonSymbol{int(sym.Pos), int(sym.Tok), sym.Tok.String(), sym.Source, int(sym.Origin)})
+}
+
+// Push 写出来自源码的 Symbol, 可以作为 parser.Fast 的回调. EOF 被忽略.
+func (e *JSONEncoder) Push(pos scanner.Pos, tok token.Token, source string) error {
+	if tok == token.EOF {
+		return nil
+	}
+	return e.Encode(ast.Symbol{Pos: pos, Tok: tok, Source: source})
+}
+
+// JSONDecoder 读取 JSON 格式的 Symbol
+type JSONDecoder struct {
+	dec *json.Decoder
+}
+
+// NewJSONDecoder 返回读取 r 的 JSONDecoder
+func NewJSONDecoder(r io.Reader) *JSONDecoder {
+	return &JSONDecoder{json.NewDecoder(r)}
+}
+
+// Decode 返回下一个 Symbol, 输入结束时返回 io.EOF.
+func (d *JSONDecoder) Decode() (ast.Symbol, error) {
+	var s jsonSymbol
+	if err := d.dec.Decode(&s); err != nil {
+		return ast.Symbol{}, err
+	}
+	return ast.Symbol{Pos: scanner.Pos(s.Pos), Tok: token.Token(s.Tok), Source: s.Source, Origin: ast.Origin(s.Origin)}, nil
+}
@@ -0,0 +1,71 @@
+package stream_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/parser"
+	. "github.com/ZxxLang/zxx/stream"
+)
+
+const src = "top\nvar x = 1 + 2 // 说明\nvar s = \"a{x}b\"\n"
+
+func TestEncoder(t *testing.T) {
+	want, err := parser.Fast([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want[1].Origin = ast.OriginMacro
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	for _, sym := range want {
+		if err := enc.Encode(sym); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// 每个 Symbol 的额外开销最多 4 字节
+	if buf.Len() > 5+len(src)+4*len(want) {
+		t.Fatal("not compact", buf.Len())
+	}
+
+	dec := NewDecoder(&buf)
+	for i, w := range want {
+		if sym, err := dec.Decode(); err != nil || sym != w {
+			t.Fatal(i, sym, w, err)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Fatal(err)
+	}
+	if _, err := NewDecoder(bytes.NewReader([]byte("zxx"))).Decode(); err != ErrFormat {
+		t.Fatal(err)
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewJSONEncoder(&buf)
+	if _, err := parser.Fast([]byte(src), enc.Push); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := parser.Fast([]byte(src), nil)
+	if !bytes.Contains(buf.Bytes(), []byte(`"name":"IDENT","source":"x"}`+"\n")) {
+		t.Fatal(buf.String())
+	}
+
+	dec := NewJSONDecoder(&buf)
+	for i, w := range want {
+		if sym, err := dec.Decode(); err != nil || sym != w {
+			t.Fatal(i, sym, w, err)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Fatal(err)
+	}
+}
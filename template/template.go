@@ -0,0 +1,264 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包实现嵌入 zxx 表达式的文本模板, 表达式由 eval 解析和求值.
+//
+// 模板是文本和 {{ }} 包裹的动作:
+//
+//	{{x}}                           输出表达式 x 的值
+//	{{if x}} A {{else}} B {{end}}   x 为真时输出 A, 否则输出 B, else 可省略
+//	{{for v in x}} A {{end}}        对列表 x 的每个元素输出 A, 名字 v 是当前元素
+//
+// 字符串原样输出, null 不输出, 列表输出为 [a, b], 其它值使用 zxx 字面值写法.
+// 文本中的 "{{" 可以写作 {{'{{'}}. 解析和执行的错误都是带行列位置的 *Error.
+package template
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/lexutil"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Error 是模板的解析或执行错误
+type Error struct {
+	Name string
+	Pos  token.Position
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return "template: " + e.Pos.String(e.Name) + ": " + e.Msg
+}
+
+// Template 是解析后的模板, 创建后不再改变, 可以被多个 goroutine 同时执行.
+type Template struct {
+	name  string
+	lines *scanner.File
+	root  []*node
+}
+
+// kind 是模板节点的种类
+type kind int
+
+const (
+	textNode kind = iota
+	exprNode
+	ifNode
+	forNode
+)
+
+// node 是模板节点, offset 是表达式在模板中的偏移量
+type node struct {
+	kind   kind
+	text   string
+	prog   *eval.Program
+	offset int
+	name   string  // for 的循环变量
+	body   []*node // if, for 的主体
+	alt    []*node // if 的 else 部分
+}
+
+// Name 返回模板的名字
+func (t *Template) Name() string { return t.name }
+
+// Parse 解析名为 name 的模板 src, 表达式使用 eval.DefaultLimits 编译.
+func Parse(name, src string) (*Template, error) {
+	t := &Template{name: name, lines: scanner.NewFileSet().AddFile(name, []byte(src))}
+	p := &reader{t: t, src: src}
+	root, end, err := p.list()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, t.errorf(p.at, "unexpected {{"+end+"}}")
+	}
+	t.root = root
+	return t, nil
+}
+
+func (t *Template) errorf(offset int, msg string) *Error {
+	return &Error{Name: t.name, Pos: t.lines.Position(t.lines.Pos(offset)), Msg: msg}
+}
+
+// reader 是解析状态, at 是最近读取的动作的偏移量
+type reader struct {
+	t   *Template
+	src string
+	pos int
+	at  int
+}
+
+// list 读取节点直到 src 结束或者 {{else}}, {{end}}, 返回结束的动作, src 结束时为空.
+func (p *reader) list() (list []*node, end string, err error) {
+	for p.pos < len(p.src) {
+		i := strings.Index(p.src[p.pos:], "{{")
+		if i == -1 {
+			list = append(list, &node{kind: textNode, text: p.src[p.pos:]})
+			p.pos = len(p.src)
+			break
+		}
+		if i != 0 {
+			list = append(list, &node{kind: textNode, text: p.src[p.pos : p.pos+i]})
+		}
+		p.at = p.pos + i
+		j := strings.Index(p.src[p.at+2:], "}}")
+		if j == -1 {
+			return nil, "", p.t.errorf(p.at, "unclosed action")
+		}
+		start := p.at + 2
+		action := p.src[start : start+j]
+		p.pos = start + j + 2
+
+		word := strings.TrimSpace(action)
+		if word == "else" || word == "end" {
+			return list, word, nil
+		}
+		n, err := p.action(action, start)
+		if err != nil {
+			return nil, "", err
+		}
+		list = append(list, n)
+	}
+	return
+}
+
+// action 解析开始于 offset 的动作内容 action
+func (p *reader) action(action string, offset int) (n *node, err error) {
+	at := p.at
+	trimmed := strings.TrimLeft(action, " \t")
+	offset += len(action) - len(trimmed)
+	switch {
+	case strings.HasPrefix(trimmed, "if "):
+		n = &node{kind: ifNode}
+		if err = p.compile(n, trimmed[3:], offset+3); err != nil {
+			return
+		}
+		var end string
+		if n.body, end, err = p.list(); err == nil && end == "else" {
+			n.alt, end, err = p.list()
+		}
+		if err == nil && end != "end" {
+			err = p.t.errorf(at, "missing {{end}} for {{if}}")
+		}
+	case strings.HasPrefix(trimmed, "for "):
+		fields := strings.Fields(trimmed[4:])
+		if len(fields) < 3 || fields[1] != "in" {
+			return nil, p.t.errorf(offset, "want {{for name in list}}")
+		}
+		if tok, _ := lexutil.Classify(fields[0]); tok != token.IDENT {
+			return nil, p.t.errorf(offset, "invalid loop name "+fields[0])
+		}
+		n = &node{kind: forNode, name: fields[0]}
+		i := strings.Index(trimmed, " in ") + 4
+		if err = p.compile(n, trimmed[i:], offset+i); err != nil {
+			return
+		}
+		var end string
+		if n.body, end, err = p.list(); err == nil && end != "end" {
+			err = p.t.errorf(at, "missing {{end}} for {{for}}")
+		}
+	default:
+		n = &node{kind: exprNode}
+		err = p.compile(n, trimmed, offset)
+	}
+	return
+}
+
+func (p *reader) compile(n *node, src string, offset int) error {
+	prog, err := eval.Compile(src)
+	if err != nil {
+		return p.t.evalError(offset, err)
+	}
+	n.prog, n.offset = prog, offset
+	return nil
+}
+
+// evalError 把表达式的错误转换为模板中位置的 *Error, offset 是表达式的偏移量
+func (t *Template) evalError(offset int, err error) *Error {
+	if e, ok := err.(*eval.Error); ok {
+		return t.errorf(offset+e.Offset, e.Msg)
+	}
+	return t.errorf(offset, err.Error())
+}
+
+// Execute 在 w 上输出模板, 表达式中的名字在 env 中查找.
+func (t *Template) Execute(w io.Writer, env map[string]eval.Value) error {
+	return t.exec(w, t.root, env)
+}
+
+func (t *Template) exec(w io.Writer, list []*node, env map[string]eval.Value) error {
+	for _, n := range list {
+		if n.kind == textNode {
+			if _, err := io.WriteString(w, n.text); err != nil {
+				return err
+			}
+			continue
+		}
+		v, err := n.prog.Eval(env)
+		if err != nil {
+			return t.evalError(n.offset, err)
+		}
+		switch n.kind {
+		case exprNode:
+			_, err = io.WriteString(w, text(v))
+		case ifNode:
+			if eval.Truth(v) {
+				err = t.exec(w, n.body, env)
+			} else {
+				err = t.exec(w, n.alt, env)
+			}
+		case forNode:
+			items, ok := v.([]eval.Value)
+			if !ok && v != nil {
+				return t.errorf(n.offset, "cannot loop over non-list")
+			}
+			scope := make(map[string]eval.Value, len(env)+1)
+			for k, x := range env {
+				scope[k] = x
+			}
+			for _, x := range items {
+				scope[n.name] = x
+				if err = t.exec(w, n.body, scope); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// text 返回值 v 在模板中的输出
+func text(v eval.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return lexutil.FormatFloat(v)
+	case time.Time:
+		return v.Format("20060102T15:04:05Z07:00")
+	case []eval.Value:
+		items := make([]string, len(v))
+		for i, x := range v {
+			items[i] = text(x)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
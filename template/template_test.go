@@ -0,0 +1,62 @@
+package template_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/template"
+)
+
+func TestExecute(t *testing.T) {
+	env := map[string]eval.Value{
+		"name":  "zxx",
+		"items": []eval.Value{int64(1), 2.5, "c"},
+		"nums":  []eval.Value{int64(1), int64(2), int64(3)},
+		"user":  map[string]eval.Value{"age": int64(3)},
+	}
+	for src, want := range map[string]string{
+		"hello {{name}}!":                                  "hello zxx!",
+		"{{ user.age + 1 }} {{null}}{{'{{'}}":              "4 {{",
+		"{{if user.age > 2}}old{{else}}young{{end}}":       "old",
+		"{{if ''}}yes{{else}}{{end}}.":                     ".",
+		"{{for x in items}}<{{x}}>{{end}}":                 "<1><2.5><c>",
+		"{{for x in nums}}{{if x > 1}}{{x}}{{end}}{{end}}": "23",
+		"{{items}} {{for x in null}}x{{end}}":              "[1, 2.5, c] ",
+	} {
+		tmpl, err := template.Parse("t", src)
+		if err != nil {
+			t.Fatal(src, err)
+		}
+		var out strings.Builder
+		if err = tmpl.Execute(&out, env); err != nil {
+			t.Fatal(src, err)
+		}
+		if out.String() != want {
+			t.Fatalf("%s: %q", src, out.String())
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	for src, want := range map[string]string{
+		"a\n{{1 +}}":                "template: t:2:6: unexpected EOF",
+		"{{if true}}x":              "template: t:1:1: missing {{end}} for {{if}}",
+		"x{{end}}":                  "template: t:1:2: unexpected {{end}}",
+		"{{name":                    "template: t:1:1: unclosed action",
+		"{{for 1 in items}}{{end}}": "template: t:1:3: invalid loop name 1",
+	} {
+		if _, err := template.Parse("t", src); err == nil || err.Error() != want {
+			t.Fatal(src, err)
+		}
+	}
+
+	tmpl, err := template.Parse("t", "ok\n  {{ missing }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tmpl.Execute(&strings.Builder{}, nil)
+	if e, ok := err.(*template.Error); !ok || e.Pos.Line != 2 || e.Pos.Column != 6 || e.Msg != "undefined missing" {
+		t.Fatal(err)
+	}
+}
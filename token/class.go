@@ -0,0 +1,87 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package token
+
+// Class 是 Token 的分类位掩码, 一个 Token 可以同时属于多个分类.
+// 例如 xor 既是 ClassOperator 也是 ClassKeyword, true 既是 ClassLiteral 也是 ClassKeyword.
+//
+// 与 As 使用的分类标记不同, Class 是面向工具的词法分类,
+// 包括解析器转义之前的 Token, 例如 TRUE, SPACES.
+type Class uint16
+
+const (
+	ClassLiteral   Class = 1 << iota // 字面值, 包括 STRINGLIT 和 null, true 等保留字
+	ClassOperator                    // 运算符和赋值符号
+	ClassKeyword                     // 由字母组成的保留字
+	ClassDelimiter                   // 分界符号, 括号, 插值括号
+	ClassTrivia                      // 换行, 缩进, 空白, 占位, 注释
+	ClassDeclare                     // 声明保留字
+	ClassStatement                   // 语句保留字
+	ClassType                        // 预定义类型
+)
+
+// classes 是每个 Token 的分类
+var classes [len(tokens)]Class
+
+func init() {
+	for i := range classes {
+		tok := Token(i)
+		var c Class
+		switch {
+		case tok.As(Operator) && tok != Operator, tok.As(Assign) && tok != Assign:
+			c = ClassOperator
+		case tok.As(Declare) && tok != Declare:
+			c = ClassDeclare
+		case tok.As(Statement) && tok != Statement:
+			c = ClassStatement
+		case tok.As(Divide) && tok != Divide:
+			c = ClassDelimiter
+		case tok.As(Type) && tok != Type:
+			c = ClassType
+		case tok.As(Literal) && tok != Literal:
+			c = ClassLiteral
+		}
+
+		switch tok {
+		case STRINGLIT, NAN, INFINITE, TRUE, FALSE:
+			c |= ClassLiteral
+		case LEFT, RIGHT, INTERPBEGIN, INTERPEND:
+			c |= ClassDelimiter
+		case NL, INDENTATION, PLACEHOLDER, COMMENT, EMPTYLINE, SPACES, TABS, COMMENTS:
+			c |= ClassTrivia
+		}
+
+		if c != 0 && isLetters(tokens[i]) {
+			c |= ClassKeyword
+		}
+		classes[i] = c
+	}
+}
+
+// isLetters 返回 s 是否以小写字母开始并由小写字母和数字组成, 即保留字的写法
+func isLetters(s string) bool {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if (s[i] < 'a' || s[i] > 'z') && (s[i] < '0' || s[i] > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// ClassOf 返回 tok 的分类, 未知的 Token 返回 0.
+func ClassOf(tok Token) Class {
+	if tok < 0 || int(tok) >= len(classes) {
+		return 0
+	}
+	return classes[tok]
+}
+
+// Is 返回 tok 是否属于 class 中的任一分类.
+func (tok Token) Is(class Class) bool {
+	return ClassOf(tok)&class != 0
+}
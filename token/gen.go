@@ -0,0 +1,116 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+// +build ignore
+
+// gen 生成 keywords.go, 使用方法:
+//
+//	go generate github.com/ZxxLang/zxx/token
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	gotoken "go/token"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+// 不能从 Token 名称得到的符号
+var extra = map[string]string{
+	"[": "LEFT",
+	"{": "LEFT",
+	"(": "LEFT",
+	"]": "RIGHT",
+	")": "RIGHT",
+	"}": "RIGHT",
+
+	"!": "NOT",
+
+	"nan":      "NAN",
+	"infinite": "INFINITE",
+	"false":    "FALSE",
+	"true":     "TRUE",
+}
+
+func main() {
+	names := constNames()
+	words := map[string]string{}
+	for i := token.ANTI; i < token.Alone; i++ {
+		s := i.String()
+		if s == "" || s[0] >= 'A' && s[0] <= 'Z' {
+			continue
+		}
+		words[s] = names[i]
+	}
+	for s, n := range extra {
+		words[s] = n
+	}
+
+	// 按长度分组, 同组按字典序
+	byLen := map[int][]string{}
+	var lens []int
+	for s := range words {
+		if byLen[len(s)] == nil {
+			lens = append(lens, len(s))
+		}
+		byLen[len(s)] = append(byLen[len(s)], s)
+	}
+	sort.Ints(lens)
+
+	var buf bytes.Buffer
+	buf.WriteString(`// Code generated by gen.go; DO NOT EDIT.
+
+package token
+
+// keyword 返回运算符, 保留字等固定符号 s 对应的 Token.
+func keyword(s string) (Token, bool) {
+	switch len(s) {
+`)
+	for _, n := range lens {
+		ss := byLen[n]
+		sort.Strings(ss)
+		buf.WriteString("\tcase " + strconv.Itoa(n) + ":\n\t\tswitch s {\n")
+		for _, s := range ss {
+			buf.WriteString("\t\tcase " + strconv.Quote(s) + ":\n\t\t\treturn " + words[s] + ", true\n")
+		}
+		buf.WriteString("\t\t}\n")
+	}
+	buf.WriteString("\t}\n\treturn PLACEHOLDER, false\n}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = ioutil.WriteFile("keywords.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// constNames 从 token.go 的 iota 常量声明得到每个 Token 的常量名
+func constNames() map[token.Token]string {
+	f, err := parser.ParseFile(gotoken.NewFileSet(), "token.go", nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	names := map[token.Token]string{}
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != gotoken.CONST {
+			continue
+		}
+		for i, spec := range gen.Specs {
+			names[token.Token(i)] = spec.(*ast.ValueSpec).Names[0].Name
+		}
+		break
+	}
+	return names
+}
@@ -2,6 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
+//go:generate go run gen.go
+
 package token
 
 import "strconv"
@@ -131,6 +133,12 @@ const (
 	Alone // 分类标记
 	// 下列每个都是单独的
 
+	// 插值字符串 "hello {name}" 被分解为
+	// STRINGLIT INTERPBEGIN IDENT INTERPEND STRINGLIT
+	STRINGLIT   // 插值字符串的文本片段, 首尾片段包括引号
+	INTERPBEGIN // 插值表达式开始 '{'
+	INTERPEND   // 插值表达式结束 '}'
+
 	// 成对符号
 	LEFT  // [{(
 	RIGHT // ]})
@@ -179,6 +187,10 @@ var tokens = [...]string{
 	TABS:        "TABS",
 	EXPR:        "EXPR",
 
+	STRINGLIT:   "STRINGLIT",
+	INTERPBEGIN: "INTERPBEGIN",
+	INTERPEND:   "INTERPEND",
+
 	VALSTRING:   "VALSTRING",
 	VALINTEGER:  "VALINTEGER",
 	VALFLOAT:    "VALFLOAT",
@@ -333,36 +345,32 @@ func (op Token) Precedence() int {
 	return 0
 }
 
-func (t Token) Token() Token {
-	return t
-}
-
-var letters map[string]Token // fixed
-
-func init() {
-	letters = map[string]Token{
-		"[": LEFT,
-		"{": LEFT,
-		"(": LEFT,
-		"]": RIGHT,
-		")": RIGHT,
-		"}": RIGHT,
-
-		"!": NOT,
+// UnaryPrec 是前缀 SUB, PLUS 的优先级, 高于所有二元运算符
+const UnaryPrec = 11
 
-		"nan":      NAN,
-		"infinite": INFINITE,
-		"false":    FALSE,
-		"true":     TRUE,
+// UnaryPrecedence 返回前缀运算符 op 的优先级, op 不能作为前缀时返回 0.
+// 前缀运算符的操作数是优先级高于该值的表达式, 例如 not a == b 即 not (a == b).
+func (op Token) UnaryPrecedence() int {
+	switch op {
+	case NOT, ANTI:
+		return op.Precedence()
+	case SUB, PLUS:
+		return UnaryPrec
 	}
+	return 0
+}
 
-	for i := ANTI; i < Alone; i++ {
-		s := tokens[i]
-		if s == "" || s[0] >= 'A' && s[0] <= 'Z' {
-			continue
-		}
-		letters[s] = i
+// BinaryPrecedence 返回二元运算符 op 的优先级, op 不是二元运算符时返回 0.
+// 二元运算符都是左结合的.
+func (op Token) BinaryPrecedence() int {
+	if op == NOT || op == ANTI {
+		return 0
 	}
+	return op.Precedence()
+}
+
+func (t Token) Token() Token {
+	return t
 }
 
 // Lookup 分析 letter 的前缀字符串, 猜测它相应的 Token.
@@ -393,7 +401,7 @@ func Lookup(letter string) Token {
 		return NL
 	}
 
-	if tok, is := letters[letter]; is {
+	if tok, is := keyword(letter); is {
 		return tok
 	}
 
@@ -0,0 +1,153 @@
+package token_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+func TestLookup(t *testing.T) {
+	for i := token.ANTI; i < token.Alone; i++ {
+		s := i.String()
+		if s[0] >= 'A' && s[0] <= 'Z' {
+			continue
+		}
+		if got := token.Lookup(s); got != i {
+			t.Fatal(s, got, i)
+		}
+	}
+
+	for s, tok := range map[string]token.Token{
+		"(": token.LEFT, "}": token.RIGHT, "!": token.NOT,
+		"true": token.TRUE, "nan": token.NAN,
+		"name": token.PLACEHOLDER, "vars": token.PLACEHOLDER,
+		"// x": token.COMMENT, "---": token.COMMENTS, "\n": token.NL,
+	} {
+		if got := token.Lookup(s); got != tok {
+			t.Fatal(s, got, tok)
+		}
+	}
+}
+
+const corpus = `
+pub
+	type Point
+		int x, y
+
+	func int add(int a, int b)
+		out a + b
+
+var (
+	string name = 'zxx' // name
+	count = 0x10 shl 2
+)
+
+proc main()
+	for i = 0, i < 10, i++
+		if i mod 2 == 0 and not done
+			count += add(i, 1)
+		else
+			break
+`
+
+// symbols 返回 corpus 重复 n 次后的全部符号
+func symbols(n int) []string {
+	scan := scanner.New([]byte(strings.Repeat(corpus, n)))
+	var ss []string
+	for {
+		s, _ := scan.Symbol()
+		if s == "" {
+			return ss
+		}
+		ss = append(ss, s)
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	ss := symbols(100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, s := range ss {
+			token.Lookup(s)
+		}
+	}
+}
+
+// BenchmarkLookupMap 是以前基于 map 的实现, 作为对比
+func BenchmarkLookupMap(b *testing.B) {
+	letters := map[string]token.Token{
+		"[": token.LEFT, "{": token.LEFT, "(": token.LEFT,
+		"]": token.RIGHT, ")": token.RIGHT, "}": token.RIGHT,
+		"!": token.NOT, "nan": token.NAN, "infinite": token.INFINITE,
+		"false": token.FALSE, "true": token.TRUE,
+	}
+	for i := token.ANTI; i < token.Alone; i++ {
+		if s := i.String(); s[0] < 'A' || s[0] > 'Z' {
+			letters[s] = i
+		}
+	}
+
+	ss := symbols(100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, s := range ss {
+			lookupMap(letters, s)
+		}
+	}
+}
+
+func lookupMap(letters map[string]token.Token, s string) token.Token {
+	switch {
+	case s == "":
+		return token.EOF
+	case s[0] > 127:
+		return token.COMMENT
+	case s[0] == ' ':
+		return token.SPACES
+	case s[0] == '\t':
+		return token.TABS
+	case s[0] == '\n' || s[0] == '\r':
+		return token.NL
+	}
+	if tok, ok := letters[s]; ok {
+		return tok
+	}
+	if strings.HasPrefix(s, "//") {
+		return token.COMMENT
+	}
+	if strings.HasPrefix(s, "---") {
+		return token.COMMENTS
+	}
+	return token.PLACEHOLDER
+}
+
+func TestClass(t *testing.T) {
+	for tok, want := range map[token.Token]token.Class{
+		token.XOR:         token.ClassOperator | token.ClassKeyword,
+		token.PLUS:        token.ClassOperator,
+		token.ASSIGN:      token.ClassOperator,
+		token.VAR:         token.ClassDeclare | token.ClassKeyword,
+		token.IF:          token.ClassStatement | token.ClassKeyword,
+		token.U8:          token.ClassType | token.ClassKeyword,
+		token.TRUE:        token.ClassLiteral | token.ClassKeyword,
+		token.VALSTRING:   token.ClassLiteral,
+		token.STRINGLIT:   token.ClassLiteral,
+		token.COMMA:       token.ClassDelimiter,
+		token.INTERPBEGIN: token.ClassDelimiter,
+		token.COMMENT:     token.ClassTrivia,
+		token.NL:          token.ClassTrivia,
+		token.IDENT:       0,
+		token.Operator:    0,
+		token.EOF:         0,
+		token.Token(-1):   0,
+	} {
+		if got := token.ClassOf(tok); got != want {
+			t.Fatal(tok, got, want)
+		}
+	}
+	if !token.MOD.Is(token.ClassKeyword|token.ClassLiteral) || token.MOD.Is(token.ClassLiteral) {
+		t.Fatal("Is")
+	}
+}
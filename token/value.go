@@ -0,0 +1,52 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package token
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Value 返回已识别的字面值 lit 的值, tok 可以是 NULL, VALBOOL, VALINTEGER, VALFLOAT,
+// 对应的值类型为 nil, bool, int64, float64.
+//
+// 整数可以使用 0x, 0o, 0b 前缀, 前导 0 不表示八进制, 数字分隔符 '_' 被剔除.
+// 字符串和 datetime 的值需要转义或时区等额外的规则, 不由 Value 计算.
+// 超出 int64 的整数和以 'd' 结尾的十进制数返回错误, 它们的精确值由 constant 包计算.
+func Value(tok Token, lit string) (interface{}, error) {
+	switch tok {
+	case NULL:
+		return nil, nil
+	case VALBOOL:
+		return strconv.ParseBool(lit)
+	case VALINTEGER:
+		lit = strings.Replace(lit, "_", "", -1)
+		base := 10
+		if len(lit) > 2 && lit[0] == '0' {
+			switch lit[1] {
+			case 'x':
+				base = 16
+			case 'o':
+				base = 8
+			case 'b':
+				base = 2
+			}
+			if base != 10 {
+				lit = lit[2:]
+			}
+		}
+		return strconv.ParseInt(lit, base, 64)
+	case VALFLOAT:
+		switch lit {
+		case "nan":
+			return math.NaN(), nil
+		case "infinite":
+			return math.Inf(1), nil
+		}
+		return strconv.ParseFloat(strings.Replace(lit, "_", "", -1), 64)
+	}
+	return nil, strconv.ErrSyntax
+}
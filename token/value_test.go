@@ -0,0 +1,36 @@
+package token_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ZxxLang/zxx/token"
+)
+
+func TestValue(t *testing.T) {
+	for lit, want := range map[string]int64{
+		"1_000_000":   1000000,
+		"0o755":       0755,
+		"0x1F":        31,
+		"0b1010_1010": 170,
+		"0755":        755,
+	} {
+		v, err := token.Value(token.VALINTEGER, lit)
+		if err != nil || v != want {
+			t.Fatal(lit, v, err)
+		}
+	}
+
+	if v, err := token.Value(token.VALFLOAT, "1_0.5e+1"); err != nil || v != 105.0 {
+		t.Fatal(v, err)
+	}
+	if v, err := token.Value(token.VALFLOAT, "nan"); err != nil || !math.IsNaN(v.(float64)) {
+		t.Fatal(v, err)
+	}
+	if v, err := token.Value(token.VALBOOL, "true"); err != nil || v != true {
+		t.Fatal(v, err)
+	}
+	if _, err := token.Value(token.VALSTRING, "'a'"); err == nil {
+		t.Fatal("VALSTRING")
+	}
+}
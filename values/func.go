@@ -0,0 +1,60 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package values
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/ZxxLang/zxx/eval"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// goFunc 把 Go 函数 v 包装为 eval.Func, 参数用 ToGo 转换, 结果用 FromGo 转换.
+// 函数的结果可以是 (), (T), (error) 或 (T, error), 支持可变参数.
+func goFunc(v reflect.Value, path string) (eval.Value, error) {
+	t := v.Type()
+	switch {
+	case t.NumOut() > 2,
+		t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, &Error{path, "cannot convert " + t.String() + ": want results (T), (error) or (T, error)"}
+	}
+	if v.IsNil() {
+		return nil, nil
+	}
+
+	return eval.Func(func(args ...eval.Value) (eval.Value, error) {
+		n := t.NumIn()
+		if t.IsVariadic() && len(args) < n-1 || !t.IsVariadic() && len(args) != n {
+			return nil, &Error{path, "want " + strconv.Itoa(n) + " arguments, got " + strconv.Itoa(len(args))}
+		}
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var typ reflect.Type
+			if t.IsVariadic() && i >= n-1 {
+				typ = t.In(n - 1).Elem()
+			} else {
+				typ = t.In(i)
+			}
+			in[i] = reflect.New(typ).Elem()
+			if err := toGo(arg, in[i], "arg"+strconv.Itoa(i+1)); err != nil {
+				return nil, err
+			}
+		}
+
+		out := v.Call(in)
+		if len(out) != 0 && out[len(out)-1].Type() == errorType {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return nil, err
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return nil, nil
+		}
+		return fromGo(out[0], path)
+	}), nil
+}
@@ -0,0 +1,352 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包在 eval.Value 和 Go 类型之间转换数据, 用于向表达式传入环境和取出结果.
+//
+// 转换规则:
+//
+//	bool                    bool
+//	int64                   有符号和无符号整数, 溢出是错误
+//	float64                 浮点数, 值为整数时也可以转换为整数
+//	string                  string
+//	time.Time               time.Time
+//	time.Duration           time.Duration
+//	constant.Value          decimal, 可以转换为 string, 最接近的浮点数, 值为整数时也可以转换为整数
+//	[]Value                 slice, array
+//	map[string]Value        键为字符串的 map, struct
+//	Func                    函数, 参数和结果按本规则转换
+//	nil                     零值
+//
+// struct 的导出字段按标签 `zxx:"name"` 或字段名对应, 标签 "-" 表示忽略该字段,
+// ",omitempty" 表示 FromGo 时忽略零值. 指针被解引用, ToGo 时按需分配.
+package values
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZxxLang/zxx/constant"
+	"github.com/ZxxLang/zxx/eval"
+)
+
+// Error 是转换错误, Path 是出错的值在数据中的路径, 例如 "user.tags[1]".
+type Error struct {
+	Path string
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return "values: " + e.Msg
+	}
+	return "values: " + e.Path + ": " + e.Msg
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	funcType     = reflect.TypeOf(eval.Func(nil))
+)
+
+// FromGo 把 Go 值 x 转换为 eval.Value
+func FromGo(x interface{}) (eval.Value, error) {
+	if x == nil {
+		return nil, nil
+	}
+	return fromGo(reflect.ValueOf(x), "")
+}
+
+func fromGo(v reflect.Value, path string) (eval.Value, error) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return fromGo(v.Elem(), path)
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			return time.Duration(v.Int()), nil
+		}
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, &Error{path, "integer " + strconv.FormatUint(v.Uint(), 10) + " overflows int64"}
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		list := make([]eval.Value, v.Len())
+		for i := range list {
+			x, err := fromGo(v.Index(i), path+"["+strconv.Itoa(i)+"]")
+			if err != nil {
+				return nil, err
+			}
+			list[i] = x
+		}
+		return list, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, &Error{path, "map key must be string, not " + v.Type().Key().String()}
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		rec := make(map[string]eval.Value, v.Len())
+		for _, key := range v.MapKeys() {
+			x, err := fromGo(v.MapIndex(key), join(path, key.String()))
+			if err != nil {
+				return nil, err
+			}
+			rec[key.String()] = x
+		}
+		return rec, nil
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface(), nil
+		}
+		rec := map[string]eval.Value{}
+		for _, f := range fields(v.Type()) {
+			fv := v.Field(f.index)
+			if f.omitempty && fv.IsZero() {
+				continue
+			}
+			x, err := fromGo(fv, join(path, f.name))
+			if err != nil {
+				return nil, err
+			}
+			rec[f.name] = x
+		}
+		return rec, nil
+	case reflect.Func:
+		if v.Type().ConvertibleTo(funcType) {
+			return v.Convert(funcType).Interface(), nil
+		}
+		return goFunc(v, path)
+	}
+	return nil, &Error{path, "cannot convert " + v.Type().String()}
+}
+
+// ToGo 把 v 转换后保存到 target 指向的 Go 值, target 必须是非 nil 指针.
+func ToGo(v eval.Value, target interface{}) error {
+	dst := reflect.ValueOf(target)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() {
+		return &Error{"", "target must be a non-nil pointer"}
+	}
+	return toGo(v, dst.Elem(), "")
+}
+
+func toGo(v eval.Value, dst reflect.Value, path string) error {
+	if v == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return toGo(v, dst.Elem(), path)
+	}
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		dst.Set(reflect.ValueOf(v))
+		return nil
+	}
+
+	switch x := v.(type) {
+	case bool:
+		if dst.Kind() == reflect.Bool {
+			dst.SetBool(x)
+			return nil
+		}
+	case int64:
+		return setInt(x, dst, path)
+	case float64:
+		switch dst.Kind() {
+		case reflect.Float32, reflect.Float64:
+			dst.SetFloat(x)
+			return nil
+		}
+		if x == math.Trunc(x) && x >= math.MinInt64 && x < math.MaxInt64 {
+			if err := setInt(int64(x), dst, path); err == nil {
+				return nil
+			}
+		}
+	case string:
+		if dst.Kind() == reflect.String {
+			dst.SetString(x)
+			return nil
+		}
+	case time.Time:
+		if dst.Type() == timeType {
+			dst.Set(reflect.ValueOf(x))
+			return nil
+		}
+	case constant.Value:
+		switch dst.Kind() {
+		case reflect.String:
+			dst.SetString(x.String())
+			return nil
+		case reflect.Float32, reflect.Float64:
+			f, _ := x.Float64()
+			dst.SetFloat(f)
+			return nil
+		}
+		if i, ok := x.Int64(); ok {
+			return setInt(i, dst, path)
+		}
+	case []eval.Value:
+		switch dst.Kind() {
+		case reflect.Slice:
+			s := reflect.MakeSlice(dst.Type(), len(x), len(x))
+			for i, item := range x {
+				if err := toGo(item, s.Index(i), path+"["+strconv.Itoa(i)+"]"); err != nil {
+					return err
+				}
+			}
+			dst.Set(s)
+			return nil
+		case reflect.Array:
+			if len(x) != dst.Len() {
+				return &Error{path, "list of length " + strconv.Itoa(len(x)) + " cannot be stored in " + dst.Type().String()}
+			}
+			for i, item := range x {
+				if err := toGo(item, dst.Index(i), path+"["+strconv.Itoa(i)+"]"); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	case map[string]eval.Value:
+		switch {
+		case dst.Kind() == reflect.Map && dst.Type().Key().Kind() == reflect.String:
+			m := reflect.MakeMapWithSize(dst.Type(), len(x))
+			for key, item := range x {
+				elem := reflect.New(dst.Type().Elem()).Elem()
+				if err := toGo(item, elem, join(path, key)); err != nil {
+					return err
+				}
+				m.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), elem)
+			}
+			dst.Set(m)
+			return nil
+		case dst.Kind() == reflect.Struct && dst.Type() != timeType:
+			for _, f := range fields(dst.Type()) {
+				if item, ok := x[f.name]; ok {
+					if err := toGo(item, dst.Field(f.index), join(path, f.name)); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+	case eval.Func:
+		if dst.Type() == funcType {
+			dst.Set(reflect.ValueOf(x))
+			return nil
+		}
+	}
+	// 环境中未经转换的 Go 值
+	if rv := reflect.ValueOf(v); rv.Type().AssignableTo(dst.Type()) {
+		dst.Set(rv)
+		return nil
+	}
+	return &Error{path, "cannot convert " + typeName(v) + " to " + dst.Type().String()}
+}
+
+func setInt(x int64, dst reflect.Value, path string) error {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !dst.OverflowInt(x) {
+			dst.SetInt(x)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if x >= 0 && !dst.OverflowUint(uint64(x)) {
+			dst.SetUint(uint64(x))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(float64(x))
+		return nil
+	default:
+		return &Error{path, "cannot convert integer to " + dst.Type().String()}
+	}
+	return &Error{path, "integer " + strconv.FormatInt(x, 10) + " overflows " + dst.Type().String()}
+}
+
+// typeName 返回 v 在错误信息中的类型名
+func typeName(v eval.Value) string {
+	switch v.(type) {
+	case bool:
+		return "bool"
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case time.Time:
+		return "datetime"
+	case time.Duration:
+		return "duration"
+	case constant.Value:
+		return "decimal"
+	case []eval.Value:
+		return "list"
+	case map[string]eval.Value:
+		return "record"
+	case eval.Func:
+		return "function"
+	}
+	return reflect.TypeOf(v).String()
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// field 是 struct 的可转换字段
+type field struct {
+	name      string
+	index     int
+	omitempty bool
+}
+
+// fields 返回 struct 类型 t 的导出字段
+func fields(t reflect.Type) (list []field) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		f := field{name: sf.Name, index: i}
+		if tag, ok := sf.Tag.Lookup("zxx"); ok {
+			if tag == "-" {
+				continue
+			}
+			parts := strings.Split(tag, ",")
+			if parts[0] != "" {
+				f.name = parts[0]
+			}
+			for _, opt := range parts[1:] {
+				f.omitempty = f.omitempty || opt == "omitempty"
+			}
+		}
+		list = append(list, f)
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package values_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/values"
+)
+
+type user struct {
+	Name    string   `zxx:"name"`
+	Age     uint8    `zxx:"age"`
+	Tags    []string `zxx:"tags,omitempty"`
+	Score   *float64 `zxx:"score"`
+	Born    time.Time
+	Secret  string `zxx:"-"`
+	private int
+}
+
+func TestFromGo(t *testing.T) {
+	v, err := values.FromGo(user{Name: "ann", Age: 20, Secret: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := v.(map[string]eval.Value)
+	if len(rec) != 4 || rec["name"] != "ann" || rec["age"] != int64(20) || rec["score"] != nil {
+		t.Fatal(rec)
+	}
+
+	// 转换后的值可以直接作为环境
+	env, err := values.FromGo(map[string]interface{}{"user": user{Name: "bob", Age: 17, Tags: []string{"beta"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := eval.Expr("user.age < 18 and user.tags has 'beta'", env.(map[string]eval.Value))
+	if err != nil || got != true {
+		t.Fatal(got, err)
+	}
+
+	if _, err := values.FromGo(map[string]chan int{"c": nil}); err == nil || err.Error() != "values: c: cannot convert chan int" {
+		t.Fatal(err)
+	}
+}
+
+func TestToGo(t *testing.T) {
+	v, err := eval.Expr("[1, 2.0, 3]", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ints []int
+	if err := values.ToGo(v, &ints); err != nil || len(ints) != 3 || ints[1] != 2 {
+		t.Fatal(ints, err)
+	}
+
+	var u user
+	in := map[string]eval.Value{"name": "ann", "age": int64(20), "score": 1.5, "tags": []eval.Value{"a"}}
+	if err := values.ToGo(in, &u); err != nil || u.Name != "ann" || u.Age != 20 || *u.Score != 1.5 || u.Tags[0] != "a" {
+		t.Fatal(u, err)
+	}
+
+	in = map[string]eval.Value{"tags": []eval.Value{"a", int64(1)}}
+	if err := values.ToGo(in, &u); err == nil || err.Error() != "values: tags[1]: cannot convert integer to string" {
+		t.Fatal(err)
+	}
+	if err := values.ToGo(map[string]eval.Value{"age": int64(300)}, &u); err == nil || err.Error() != "values: age: integer 300 overflows uint8" {
+		t.Fatal(err)
+	}
+	if err := values.ToGo(int64(1), u); err == nil {
+		t.Fatal("non-pointer target")
+	}
+
+	var any interface{}
+	if err := values.ToGo("s", &any); err != nil || any != "s" {
+		t.Fatal(any, err)
+	}
+}
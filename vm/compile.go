@@ -0,0 +1,283 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包把表达式编译为字节码, 并在栈式虚拟机上执行, 语义与 eval 相同.
+//
+// 与逐节点遍历语法树的 eval 相比, 编译只进行一次, 名字的成员路径预先拆分,
+// 执行时没有递归调用, 适合反复求值的场景. 程序可以用 Disassemble 反汇编.
+//
+// 字节码的每条指令是一个字节的操作码, 部分指令后跟两个字节的操作数,
+// 常量和名字保存在 Program 的常量池中.
+package vm
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Op 是操作码
+type Op byte
+
+const (
+	OpConst       Op = iota // 压入常量 consts[k]
+	OpName                  // 压入名字 names[k] 在环境中的值
+	OpList                  // 弹出 n 个值, 压入由它们组成的列表
+	OpMap                   // 弹出 n 对键和值, 压入由它们组成的映射
+	OpUnary                 // 弹出 x, 压入 tok x, 操作数是 Token
+	OpBinary                // 弹出 y, x, 压入 x tok y, 操作数是 Token
+	OpIndex                 // 弹出 i, x, 压入 x[i]
+	OpCall                  // 弹出 n 个参数和函数, 压入调用结果
+	OpJumpIfFalse           // 栈顶为假时跳转到 pc, 否则弹出栈顶, 用于 and 短路
+	OpJumpIfTrue            // 栈顶为真时跳转到 pc, 否则弹出栈顶, 用于 or 短路
+	OpReturn                // 弹出并返回栈顶
+	OpText                  // 把栈顶替换为它在插值字符串中的文本, 参见 eval.Text
+	OpInterp                // 弹出 n 个文本, 压入它们连接成的字符串
+)
+
+var ops = [...]string{
+	"CONST", "NAME", "LIST", "MAP", "UNARY", "BINARY", "INDEX", "CALL",
+	"JUMPIFFALSE", "JUMPIFTRUE", "RETURN", "TEXT", "INTERP",
+}
+
+func (op Op) String() string {
+	if int(op) < len(ops) {
+		return ops[op]
+	}
+	return "Op(" + strconv.Itoa(int(op)) + ")"
+}
+
+// hasOperand 返回 op 之后是否有两个字节的操作数
+func (op Op) hasOperand() bool {
+	return op != OpIndex && op != OpReturn && op != OpText
+}
+
+// Program 是编译后的表达式, 创建后不再改变, 可以被多个 goroutine 同时执行.
+type Program struct {
+	file   string // 用于错误的调用栈
+	src    string // 源码, 由语法树编译时为空
+	code   []byte
+	consts []eval.Value
+	names  [][]string     // 拆分后的名字, 例如 user.age 为 ["user", "age"]
+	source []string       // 名字的源码
+	pos    []scanner.Pos  // 每条指令对应的源码位置, 按 pc 索引
+	depth  int            // 执行需要的最大栈深度
+	funcs  map[int]string // CALL 指令的 pc 到被调用的名字, 用于错误的调用栈
+}
+
+// maxOperand 是两个字节的操作数的上限
+const maxOperand = 1<<16 - 1
+
+// CompileString 解析并编译表达式 src
+func CompileString(src string) (*Program, error) {
+	return CompileFile("", src)
+}
+
+// CompileFile 同 CompileString, file 是表达式所在的文件, 用于执行错误的调用栈.
+func CompileFile(file, src string) (*Program, error) {
+	x, err := parser.ParseExpr([]byte(src))
+	if err != nil {
+		return nil, err
+	}
+	p, err := Compile(x)
+	if err == nil {
+		p.file, p.src = file, src
+	}
+	return p, err
+}
+
+// Compile 把表达式树 x 编译为 Program. 字面值在编译时求值, 非法的字面值是错误.
+func Compile(x ast.Expression) (*Program, error) {
+	c := &compiler{p: &Program{}}
+	if err := c.expr(x); err != nil {
+		return nil, err
+	}
+	c.emit(OpReturn, 0, x.End())
+	return c.p, nil
+}
+
+// compiler 是编译状态, depth 是当前的栈深度
+type compiler struct {
+	p     *Program
+	depth int
+}
+
+func (c *compiler) emit(op Op, operand int, pos scanner.Pos) int {
+	at := len(c.p.code)
+	c.p.code = append(c.p.code, byte(op))
+	c.p.pos = append(c.p.pos, pos)
+	if op.hasOperand() {
+		c.p.code = append(c.p.code, byte(operand>>8), byte(operand))
+		c.p.pos = append(c.p.pos, pos, pos)
+	}
+	return at
+}
+
+// push 记录压入 n 个值, 负数表示弹出
+func (c *compiler) push(n int) {
+	if c.depth += n; c.depth > c.p.depth {
+		c.p.depth = c.depth
+	}
+}
+
+func (c *compiler) operand(n int, what string) error {
+	if n > maxOperand {
+		return errors.New("vm: too many " + what)
+	}
+	return nil
+}
+
+func (c *compiler) expr(x ast.Expression) (err error) {
+	switch x := x.(type) {
+	case *ast.BasicLit:
+		v, err := eval.Literal(x.Value.Tok, x.Value.Source)
+		if err != nil {
+			return &eval.Error{Offset: int(x.Pos()), Msg: err.Error()}
+		}
+		if err = c.operand(len(c.p.consts), "constants"); err != nil {
+			return err
+		}
+		c.emit(OpConst, len(c.p.consts), x.Pos())
+		c.p.consts = append(c.p.consts, v)
+		c.push(1)
+	case *ast.Ident:
+		if err = c.operand(len(c.p.names), "names"); err != nil {
+			return err
+		}
+		c.emit(OpName, len(c.p.names), x.Pos())
+		c.p.names = append(c.p.names, strings.Split(x.Name.Source, "."))
+		c.p.source = append(c.p.source, x.Name.Source)
+		c.push(1)
+	case *ast.ParenExpr:
+		return c.expr(x.X)
+	case *ast.ListExpr:
+		if err = c.items(x.Elems); err == nil {
+			c.emit(OpList, len(x.Elems), x.Pos())
+			c.push(1 - len(x.Elems))
+		}
+	case *ast.MapExpr:
+		if err = c.operand(len(x.Elems), "items"); err != nil {
+			return
+		}
+		for _, kv := range x.Elems {
+			if err = c.expr(kv.Key); err != nil {
+				return
+			}
+			if err = c.expr(kv.Value); err != nil {
+				return
+			}
+		}
+		c.emit(OpMap, len(x.Elems), x.Pos())
+		c.push(1 - 2*len(x.Elems))
+	case *ast.InterpExpr:
+		// 片段是字符串常量, 插值表达式由 TEXT 转换, 错误的位置同 eval
+		if err = c.operand(len(x.Parts), "items"); err != nil {
+			return
+		}
+		for i, part := range x.Parts {
+			if err = c.expr(part); err != nil {
+				return
+			}
+			if i%2 == 1 {
+				c.emit(OpText, 0, at(part))
+			}
+		}
+		c.emit(OpInterp, len(x.Parts), x.Pos())
+		c.push(1 - len(x.Parts))
+	case *ast.UnaryExpr:
+		if err = c.expr(x.X); err == nil {
+			c.emit(OpUnary, int(x.Op.Tok), x.Pos())
+		}
+	case *ast.BinaryExpr:
+		if err = c.expr(x.X); err != nil {
+			return
+		}
+		op := x.Op.Tok
+		if op == token.AND || op == token.OR {
+			// 短路: 决定结果的左操作数留在栈顶, 否则弹出并求值右操作数
+			jump := OpJumpIfFalse
+			if op == token.OR {
+				jump = OpJumpIfTrue
+			}
+			at := c.emit(jump, 0, x.Op.Pos)
+			c.push(-1)
+			if err = c.expr(x.Y); err != nil {
+				return
+			}
+			return c.patch(at)
+		}
+		if err = c.expr(x.Y); err == nil {
+			c.emit(OpBinary, int(op), x.Op.Pos)
+			c.push(-1)
+		}
+	case *ast.IndexExpr:
+		if err = c.expr(x.X); err != nil {
+			return
+		}
+		if err = c.expr(x.Index); err == nil {
+			c.emit(OpIndex, 0, x.Lbrack)
+			c.push(-1)
+		}
+	case *ast.CallExpr:
+		if err = c.expr(x.Fun); err != nil {
+			return
+		}
+		if err = c.items(x.Args); err == nil {
+			at := c.emit(OpCall, len(x.Args), x.Lparen)
+			if id, ok := x.Fun.(*ast.Ident); ok {
+				if c.p.funcs == nil {
+					c.p.funcs = map[int]string{}
+				}
+				c.p.funcs[at] = id.Name.Source
+			}
+			c.push(-len(x.Args))
+		}
+	default:
+		return errors.New("vm: unsupported expression")
+	}
+	return
+}
+
+// at 返回 eval 报告 x 处的错误的位置, 即运算符或者左括号, 其它表达式是首个 Token
+func at(x ast.Expression) scanner.Pos {
+	switch x := x.(type) {
+	case *ast.ParenExpr:
+		return at(x.X)
+	case *ast.BinaryExpr:
+		return x.Op.Pos
+	case *ast.CallExpr:
+		return x.Lparen
+	case *ast.IndexExpr:
+		return x.Lbrack
+	}
+	return x.Pos()
+}
+
+func (c *compiler) items(list []ast.Expression) error {
+	if err := c.operand(len(list), "items"); err != nil {
+		return err
+	}
+	for _, x := range list {
+		if err := c.expr(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patch 把位于 at 的跳转指令的目标设为当前位置
+func (c *compiler) patch(at int) error {
+	pc := len(c.p.code)
+	if err := c.operand(pc, "instructions"); err != nil {
+		return err
+	}
+	c.p.code[at+1], c.p.code[at+2] = byte(pc>>8), byte(pc)
+	return nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// WritePprof 在 w 上输出 gzip 压缩的 pprof 格式 (profile.proto) 的 f, 可以用 go tool pprof 查看.
+// 每条执行过的指令是一个 location, 地址是 pc, 函数名是指令的描述, 例如 "CALL max",
+// 行号来自程序的位置表, 因此 pprof -lines 按 zxx 源码行汇总.
+func (f *Profile) WritePprof(w io.Writer) error {
+	strs := map[string]int{"": 0}
+	table := []string{""}
+	str := func(s string) uint64 {
+		i, ok := strs[s]
+		if !ok {
+			i = len(table)
+			strs[s] = i
+			table = append(table, s)
+		}
+		return uint64(i)
+	}
+	valueType := func(kind, unit string) []byte {
+		var b protobuf
+		b.uint(1, str(kind))
+		b.uint(2, str(unit))
+		return b
+	}
+
+	var out protobuf
+	out.bytes(1, valueType("instructions", "count"))
+
+	type function struct{ name, file string }
+	funcs := map[function]uint64{}
+	var funcTable []protobuf
+	for i, s := range f.Samples() {
+		loc := uint64(i + 1)
+		var sample protobuf
+		sample.packed(1, []uint64{loc})
+		sample.packed(2, []uint64{s.Count})
+		out.bytes(2, sample)
+
+		fn := function{s.Name, s.File}
+		id, ok := funcs[fn]
+		if !ok {
+			id = uint64(len(funcs) + 1)
+			funcs[fn] = id
+			var b protobuf
+			b.uint(1, id)
+			b.uint(2, str(fn.name))
+			b.uint(3, str(fn.name))
+			b.uint(4, str(fn.file))
+			funcTable = append(funcTable, b)
+		}
+		var line, location protobuf
+		line.uint(1, id)
+		line.uint(2, uint64(s.Pos.Line))
+		location.uint(1, loc)
+		location.uint(3, uint64(s.PC))
+		location.bytes(4, line)
+		out.bytes(4, location)
+	}
+	for _, b := range funcTable {
+		out.bytes(5, b)
+	}
+	// 字符串表的下标在上面确定, 最后输出
+	periodType := valueType("instructions", "count")
+	for _, s := range table {
+		out.bytes(6, []byte(s))
+	}
+	out.bytes(11, periodType)
+	out.uint(12, 1)
+
+	z := gzip.NewWriter(w)
+	if _, err := z.Write(out); err != nil {
+		return err
+	}
+	return z.Close()
+}
+
+// protobuf 是 protocol buffers 编码的消息, 只支持 pprof 用到的 varint 和 length-delimited 字段
+type protobuf []byte
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		*b = append(*b, byte(x)|0x80)
+		x >>= 7
+	}
+	*b = append(*b, byte(x))
+}
+
+// uint 添加 varint 字段, 0 是默认值, 省略
+func (b *protobuf) uint(field int, x uint64) {
+	if x != 0 {
+		b.varint(uint64(field) << 3)
+		b.varint(x)
+	}
+}
+
+// bytes 添加 length-delimited 字段, 包括字符串和嵌套的消息
+func (b *protobuf) bytes(field int, data []byte) {
+	b.varint(uint64(field)<<3 | 2)
+	b.varint(uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+// packed 添加 packed repeated varint 字段
+func (b *protobuf) packed(field int, xs []uint64) {
+	var data protobuf
+	for _, x := range xs {
+		data.varint(x)
+	}
+	b.bytes(field, data)
+}
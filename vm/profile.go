@@ -0,0 +1,145 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Profile 累计程序每条指令的执行次数, 用于找出反复求值中的热点.
+// 一个 Profile 可以记录多个程序, 可以被多个 goroutine 同时使用.
+type Profile struct {
+	// Locate 返回程序 p 中字节偏移量为 offset 的源码所在的文件和位置,
+	// 为 nil 时按编译时的文件名和表达式源码计算.
+	Locate func(p *Program, offset int) (string, token.Position)
+
+	mu     sync.Mutex
+	progs  []*Program // 按第一次执行的顺序
+	counts map[*Program][]uint64
+}
+
+// Run 同 vm.Run, 并在 f 中累计 p 的每条指令的执行次数.
+func (f *Profile) Run(p *Program, env map[string]eval.Value) (eval.Value, error) {
+	return run(p, env, f.counter(p))
+}
+
+// counter 返回 p 的计数器, 按 pc 索引
+func (f *Profile) counter(p *Program) []uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	counts := f.counts[p]
+	if counts == nil {
+		if f.counts == nil {
+			f.counts = map[*Program][]uint64{}
+		}
+		counts = make([]uint64, len(p.code))
+		f.counts[p] = counts
+		f.progs = append(f.progs, p)
+	}
+	return counts
+}
+
+// Sample 是一条指令的执行次数
+type Sample struct {
+	Prog  *Program
+	PC    int
+	Op    Op
+	Name  string // 指令的描述, 例如 "CALL max", "BINARY +"
+	File  string
+	Pos   token.Position // 指令对应的源码位置, 来自程序的位置表
+	Count uint64
+}
+
+// Samples 返回执行过的指令, 按次数降序, 次数相同时按程序第一次执行的顺序和 pc.
+func (f *Profile) Samples() []Sample {
+	f.mu.Lock()
+	progs := append([]*Program(nil), f.progs...)
+	f.mu.Unlock()
+
+	var list []Sample
+	for _, p := range progs {
+		locate := f.Locate
+		if locate == nil {
+			lines := scanner.NewFileSet().AddFile(p.file, []byte(p.src))
+			locate = func(p *Program, offset int) (string, token.Position) {
+				return p.file, lines.Position(scanner.Pos(offset))
+			}
+		}
+		counts := f.counter(p)
+		for pc := 0; pc < len(p.code); {
+			op := Op(p.code[pc])
+			if n := atomic.LoadUint64(&counts[pc]); n != 0 {
+				file, pos := locate(p, int(p.pos[pc]))
+				list = append(list, Sample{p, pc, op, p.describe(pc), file, pos, n})
+			}
+			if pc++; op.hasOperand() {
+				pc += 2
+			}
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Count > list[j].Count })
+	return list
+}
+
+// Line 是源码中一行的全部指令的执行次数
+type Line struct {
+	File  string
+	Line  int
+	Count uint64
+}
+
+// Lines 按行合计 Samples, 按次数降序, 次数相同时按文件名和行号.
+// 位置无效的指令计入行号 0.
+func (f *Profile) Lines() []Line {
+	type key struct {
+		file string
+		line int
+	}
+	sum := map[key]uint64{}
+	for _, s := range f.Samples() {
+		sum[key{s.File, s.Pos.Line}] += s.Count
+	}
+	list := make([]Line, 0, len(sum))
+	for k, n := range sum {
+		list = append(list, Line{k.file, k.line, n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return list
+}
+
+// describe 返回 pc 处指令的描述, 包括 NAME 的名字, CALL 调用的名字和 UNARY, BINARY 的运算符
+func (p *Program) describe(pc int) string {
+	op := Op(p.code[pc])
+	if !op.hasOperand() {
+		return op.String()
+	}
+	operand := int(p.code[pc+1])<<8 | int(p.code[pc+2])
+	switch op {
+	case OpName:
+		return op.String() + " " + p.source[operand]
+	case OpCall:
+		if name := p.funcs[pc]; name != "" {
+			return op.String() + " " + name
+		}
+	case OpUnary, OpBinary:
+		return op.String() + " " + token.Token(operand).String()
+	}
+	return op.String()
+}
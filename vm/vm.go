@@ -0,0 +1,160 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/token"
+)
+
+// Run 执行程序 p, 名字在 env 中查找. 错误的类型是 *eval.Error, 与 eval 的错误相同,
+// 包括错误的种类和调用栈.
+func Run(p *Program, env map[string]eval.Value) (eval.Value, error) {
+	return run(p, env, nil)
+}
+
+// run 执行程序 p, counts 不为 nil 时按 pc 累计每条指令的执行次数.
+func run(p *Program, env map[string]eval.Value, counts []uint64) (eval.Value, error) {
+	stack := make([]eval.Value, 0, p.depth)
+	code := p.code
+	for pc := 0; pc < len(code); {
+		op := Op(code[pc])
+		at := pc
+		if counts != nil {
+			atomic.AddUint64(&counts[at], 1)
+		}
+		operand := 0
+		if pc++; op.hasOperand() {
+			operand = int(code[pc])<<8 | int(code[pc+1])
+			pc += 2
+		}
+
+		switch op {
+		case OpConst:
+			stack = append(stack, p.consts[operand])
+		case OpName:
+			v, err := eval.Lookup(env, p.names[operand])
+			if err != nil {
+				return nil, p.fail(at, err, "")
+			}
+			stack = append(stack, v)
+		case OpList:
+			list := make([]eval.Value, operand)
+			copy(list, stack[len(stack)-operand:])
+			stack = append(stack[:len(stack)-operand], list)
+		case OpMap:
+			n := len(stack) - 2*operand
+			v, err := eval.Map(stack[n:]...)
+			if err != nil {
+				return nil, p.fail(at, err, "")
+			}
+			stack = append(stack[:n], v)
+		case OpUnary:
+			v, err := eval.Unary(token.Token(operand), stack[len(stack)-1])
+			if err != nil {
+				return nil, p.fail(at, err, "")
+			}
+			stack[len(stack)-1] = v
+		case OpBinary:
+			n := len(stack)
+			v, err := eval.Binary(token.Token(operand), stack[n-2], stack[n-1])
+			if err != nil {
+				return nil, p.fail(at, err, "")
+			}
+			stack[n-2] = v
+			stack = stack[:n-1]
+		case OpIndex:
+			n := len(stack)
+			v, err := eval.Index(stack[n-2], stack[n-1])
+			if err != nil {
+				return nil, p.fail(at, err, "")
+			}
+			stack[n-2] = v
+			stack = stack[:n-1]
+		case OpCall:
+			n := len(stack) - operand
+			f, ok := stack[n-1].(eval.Func)
+			if !ok {
+				return nil, p.fail(at, eval.ErrNotFunc, "")
+			}
+			args := make([]eval.Value, operand)
+			copy(args, stack[n:])
+			v, err := f(args...)
+			if err != nil {
+				return nil, p.fail(at, err, p.funcs[at])
+			}
+			v = eval.Normalize(v)
+			if _, ok := v.(eval.Tuple); ok && Op(code[pc]) != OpReturn {
+				return nil, p.fail(at, eval.MultiValue(p.funcs[at]), "")
+			}
+			stack[n-1] = v
+			stack = stack[:n]
+		case OpJumpIfFalse, OpJumpIfTrue:
+			if eval.Truth(stack[len(stack)-1]) == (op == OpJumpIfTrue) {
+				pc = operand
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+		case OpReturn:
+			return stack[len(stack)-1], nil
+		case OpText:
+			s, err := eval.Text(stack[len(stack)-1])
+			if err != nil {
+				return nil, p.fail(at, err, "")
+			}
+			stack[len(stack)-1] = s
+		case OpInterp:
+			n := len(stack) - operand
+			var b strings.Builder
+			for _, s := range stack[n:] {
+				b.WriteString(s.(string))
+			}
+			stack = append(stack[:n], b.String())
+		default:
+			return nil, p.fail(at, errors.New("invalid instruction "+op.String()), "")
+		}
+	}
+	return nil, p.fail(len(code)-1, errors.New("missing return"), "")
+}
+
+// fail 返回在 pc 处发生的原因为 err 的错误, name 是调用的环境的函数名.
+func (p *Program) fail(pc int, err error, name string) error {
+	return eval.Wrap(err, name, eval.NewFrame(p.file, p.src, int(p.pos[pc])))
+}
+
+// Disassemble 在 w 上逐行输出 p 的指令, 每行是 pc, 操作码, 操作数及其含义,
+// UNARY, BINARY 的操作数显示为运算符.
+func (p *Program) Disassemble(w io.Writer) error {
+	var b strings.Builder
+	for pc := 0; pc < len(p.code); {
+		op := Op(p.code[pc])
+		if pc++; !op.hasOperand() {
+			fmt.Fprintf(&b, "%04d %s\n", pc-1, op)
+			continue
+		}
+		operand := int(p.code[pc])<<8 | int(p.code[pc+1])
+		fmt.Fprintf(&b, "%04d %-11s ", pc-1, op)
+		pc += 2
+		switch op {
+		case OpConst:
+			fmt.Fprintf(&b, "%d\t%#v", operand, p.consts[operand])
+		case OpName:
+			fmt.Fprintf(&b, "%d\t%s", operand, p.source[operand])
+		case OpUnary, OpBinary:
+			b.WriteString(token.Token(operand).String())
+		default:
+			fmt.Fprint(&b, operand)
+		}
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,193 @@
+package vm_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/vm"
+)
+
+var env = map[string]eval.Value{
+	"user": map[string]eval.Value{"age": 20, "country": "cn", "tags": []eval.Value{"beta", "vip"}},
+	"max": eval.Func(func(args ...eval.Value) (eval.Value, error) {
+		if len(args) == 0 {
+			return nil, errors.New("max of nothing")
+		}
+		m := args[0].(int64)
+		for _, x := range args[1:] {
+			if x.(int64) > m {
+				m = x.(int64)
+			}
+		}
+		return m, nil
+	}),
+}
+
+func TestRun(t *testing.T) {
+	for _, src := range []string{
+		"1 + 2 * 3",
+		"-(1 + 2) * 3.5",
+		"not 1 == 2",
+		"'ab' + 'cd'",
+		"[1, 'a', [2, 3]]",
+		"['a': 1, 'b': [2, user.age]]",
+		"[:]",
+		"1..user.age",
+		"'a'..2",
+		"['a': 1, user.age: 2]",
+		"user.age >= 18 and user.country == 'cn' or user.tags has 'beta'",
+		"0 and nosuch",
+		"1 or nosuch",
+		"user.tags[1]",
+		"max(1, 5, user.age)",
+		"[1, 2][0] + max(3)",
+		"nosuch + 1",
+		"max()",
+		"user.tags[5]",
+		"1 + 'a'",
+		"9007199254740993 > 9007199254740992",
+		"[9007199254740993] has 9007199254740992",
+		"(-9223372036854775807 - 1) div -1",
+		"-(-9223372036854775807 - 1)",
+		`"age {user.age + 1}, {user.tags[0]}{null}!"`,
+		`"{"{1}"} {(user.age)}"`,
+		`"x {user.tags} y"`,
+		`"x {[1] + 1} y"`,
+		`"x {(user.tags)} y"`,
+	} {
+		want, werr := eval.Expr(src, env)
+		p, err := vm.CompileString(src)
+		if err != nil {
+			t.Fatal(src, err)
+		}
+		got, gerr := vm.Run(p, env)
+		if !reflect.DeepEqual(got, want) || (werr == nil) != (gerr == nil) ||
+			werr != nil && werr.Error() != gerr.Error() {
+			t.Fatalf("%s: %v %v, want %v %v", src, got, gerr, want, werr)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	pair := eval.Func(func(args ...eval.Value) (eval.Value, error) { return eval.Tuple{1, 2}, nil })
+	env := map[string]eval.Value{"none": nil, "max": env["max"], "pair": pair}
+	for src, kind := range map[string]error{
+		"pair() + 1": eval.ErrMultiValue,
+		"none.x":     eval.ErrNilMember,
+		"1 +\nmax()": nil,
+		"[1][\n3]":   eval.ErrIndexRange,
+		"1 div 0":    eval.ErrDivideByZero,
+	} {
+		p, err := vm.CompileFile("a.zxx", src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q, _ := eval.DefaultLimits.CompileFile("a.zxx", src)
+		_, gerr := vm.Run(p, env)
+		_, werr := q.Eval(env)
+		g, ok := gerr.(*eval.Error)
+		w := werr.(*eval.Error)
+		if !ok || g.Trace() != w.Trace() || kind != nil && !errors.Is(g, kind) {
+			t.Errorf("%s: %v, want %v", src, gerr, w.Trace())
+		}
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	p, err := vm.CompileString("a.b + 1 or f(2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := p.Disassemble(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `0000 NAME        0	a.b
+0003 CONST       0	1
+0006 BINARY      +
+0009 JUMPIFTRUE  21
+0012 NAME        1	f
+0015 CONST       1	2
+0018 CALL        1
+0021 RETURN
+`
+	if b.String() != want {
+		t.Fatal(b.String())
+	}
+}
+
+func TestProfile(t *testing.T) {
+	p, err := vm.CompileFile("a.zxx", "user.age > 30 or\nmax(user.age, 1) > 18")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prof vm.Profile
+	for i := 0; i < 3; i++ {
+		if _, err := prof.Run(p, env); err != nil {
+			t.Fatal(err)
+		}
+	}
+	samples := prof.Samples()
+	if len(samples) == 0 || samples[0].Count != 3 || samples[0].Name != "NAME user.age" || samples[0].Pos.Line != 1 {
+		t.Fatalf("%+v", samples)
+	}
+	var call vm.Sample
+	for _, s := range samples {
+		if s.Op == vm.OpCall {
+			call = s
+		}
+	}
+	if call.Name != "CALL max" || call.File != "a.zxx" || call.Pos.Line != 2 || call.Count != 3 {
+		t.Fatalf("%+v", call)
+	}
+	lines := prof.Lines()
+	if len(lines) != 2 || lines[0].Line != 2 || lines[0].Count != 21 || lines[1].Count != 12 {
+		t.Fatalf("%+v", lines)
+	}
+
+	var b bytes.Buffer
+	if err := prof.WritePprof(&b); err != nil {
+		t.Fatal(err)
+	}
+	z, err := gzip.NewReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"instructions", "count", "CALL max", "a.zxx"} {
+		if !bytes.Contains(data, []byte(s)) {
+			t.Errorf("missing %q in profile", s)
+		}
+	}
+}
+
+const benchSrc = "user.age >= 18 and user.country == 'cn' or user.tags has 'beta'"
+
+func BenchmarkRun(b *testing.B) {
+	p, err := vm.CompileString(benchSrc)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		vm.Run(p, env)
+	}
+}
+
+func BenchmarkEval(b *testing.B) {
+	p, err := eval.Compile(benchSrc)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		p.Eval(env)
+	}
+}
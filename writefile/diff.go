@@ -0,0 +1,218 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package writefile
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// context 是 unified diff 每个 hunk 前后保留的相同行数
+const context = 3
+
+// op 是行编辑: ' ' 相同, '-' 删除, '+' 插入
+type op struct {
+	kind byte
+	line string
+}
+
+// Unified 返回 a 到 b 的 unified diff, 文件名都是 name, 原文件标记为 name.orig.
+func Unified(name string, a, b []byte) []byte {
+	ops := lineDiff(splitLines(a), splitLines(b))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s.orig\n+++ %s\n", name, name)
+	for i := 0; i < len(ops); {
+		// 找到下一个变化
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// hunk 包括间隔不超过 2*context 行的相邻变化
+		end, same := i, 0
+		for ; end < len(ops) && same <= 2*context; end++ {
+			if ops[end].kind == ' ' {
+				same++
+			} else {
+				same = 0
+			}
+		}
+		end -= same - context
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		aStart, bStart := lineNo(ops[:start])
+		var aLen, bLen int
+		for _, o := range ops[start:end] {
+			if o.kind != '+' {
+				aLen++
+			}
+			if o.kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+		for _, o := range ops[start:end] {
+			buf.WriteByte(o.kind)
+			buf.WriteString(o.line)
+			if !strings.HasSuffix(o.line, "\n") {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return buf.Bytes()
+}
+
+// lineNo 返回 ops 之后的 a, b 行号, 从 1 开始
+func lineNo(ops []op) (a, b int) {
+	a, b = 1, 1
+	for _, o := range ops {
+		if o.kind != '+' {
+			a++
+		}
+		if o.kind != '-' {
+			b++
+		}
+	}
+	return
+}
+
+func hunkRange(start, n int) string {
+	if n == 0 {
+		start--
+	}
+	if n == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, n)
+}
+
+// splitLines 分割 src 为行, 每行包括行尾的换行符
+func splitLines(src []byte) []string {
+	var lines []string
+	for len(src) != 0 {
+		i := bytes.IndexByte(src, '\n') + 1
+		if i == 0 {
+			i = len(src)
+		}
+		lines = append(lines, string(src[:i]))
+		src = src[i:]
+	}
+	return lines
+}
+
+// maxEdits 限制 Myers 算法的编辑距离, 超过时中间部分整体替换, 避免消耗过多内存
+const maxEdits = 1000
+
+// lineDiff 返回 a 到 b 的编辑序列. 去掉相同的首尾之后使用 Myers 算法求最短编辑序列.
+func lineDiff(a, b []string) []op {
+	var head, tail []op
+	for len(a) != 0 && len(b) != 0 && a[0] == b[0] {
+		head = append(head, op{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) != 0 && len(b) != 0 && a[len(a)-1] == b[len(b)-1] {
+		tail = append(tail, op{' ', a[len(a)-1]})
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	ops := myers(a, b)
+	if ops == nil {
+		for _, line := range a {
+			ops = append(ops, op{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, op{'+', line})
+		}
+	}
+	ops = append(head, ops...)
+	for i := len(tail) - 1; i >= 0; i-- {
+		ops = append(ops, tail[i])
+	}
+	return ops
+}
+
+// myers 返回 a 到 b 的最短编辑序列, 编辑距离超过 maxEdits 时返回 nil
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return []op{}
+	}
+	max := n + m
+	if max > maxEdits {
+		max = maxEdits
+	}
+	// v[k] 是对角线 k 上最远的 x, trace[d] 保存第 d 步之前对角线 [-d, d] 的 v
+	v := make([]int, 2*max+3)
+	off := max + 1
+	var trace [][]int
+	for d := 0; d <= max; d++ {
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[off+k-1] < v[off+k+1] {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, d)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack 根据 trace 还原第 d 步到达终点的编辑序列
+func backtrack(trace [][]int, a, b []string, d int) []op {
+	ops := make([]op, 0, len(a)+len(b))
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		get := func(k int) int { return v[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || k != d && get(k-1) < get(k+1) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := get(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x, y = x-1, y-1
+			ops = append(ops, op{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			ops = append(ops, op{'+', b[y]})
+		} else {
+			x--
+			ops = append(ops, op{'-', a[x]})
+		}
+	}
+	for x > 0 {
+		x, y = x-1, y-1
+		ops = append(ops, op{' ', a[x]})
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
@@ -0,0 +1,42 @@
+package writefile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	a := "a\nb  \nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm \n"
+	b := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	want := `--- x.zxx.orig
++++ x.zxx
+@@ -1,5 +1,5 @@
+ a
+-b  
++b
+ c
+ d
+ e
+@@ -10,4 +10,4 @@
+ j
+ k
+ l
+-m 
++m
+`
+	if got := string(Unified("x.zxx", []byte(a), []byte(b))); got != want {
+		t.Fatal(got)
+	}
+
+	want = "--- x.zxx.orig\n+++ x.zxx\n@@ -1 +1,2 @@\n-a\n\\ No newline at end of file\n+a\n+b\n"
+	if got := string(Unified("x.zxx", []byte("a"), []byte("a\nb\n"))); got != want {
+		t.Fatal(got)
+	}
+
+	// 超出 maxEdits 时整体替换
+	long := strings.Repeat("x\r\n", maxEdits)
+	ops := lineDiff(splitLines([]byte(long)), splitLines([]byte(strings.Replace(long, "\r", "", -1))))
+	if len(ops) != 2*maxEdits || ops[0].kind != '-' || ops[maxEdits].kind != '+' {
+		t.Fatal(len(ops))
+	}
+}
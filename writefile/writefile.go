@@ -0,0 +1,131 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包为就地改写源码的工具安全地写文件.
+//
+// 新内容先写入同一目录的临时文件并同步到磁盘, 再改名替换目标文件,
+// 崩溃或出错时目标文件要么是原来的内容, 要么是完整的新内容.
+// 一次写多个文件时, 全部临时文件写成功之后才开始改名.
+// 可选地把原文件保存为 name.orig, 或者只输出 unified diff 而不修改文件.
+package writefile
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Options 配置写文件的方式
+type Options struct {
+	Backup bool      // 覆盖已有文件之前把原内容保存为 name.orig
+	DryRun bool      // 不修改文件, 把每个文件的 unified diff 写到 Diff
+	Diff   io.Writer // DryRun 时接收 diff, nil 表示 os.Stdout
+}
+
+// newPerm 是新建文件的权限
+const newPerm = 0644
+
+// File 把 data 写入文件 name, 参见 Files.
+func File(name string, data []byte, opt Options) error {
+	return Files(map[string][]byte{name: data}, opt)
+}
+
+// Files 按文件名的顺序把 files 中的内容写入对应的文件, 内容未变的文件被跳过.
+// 已有文件保持原来的权限, 新文件的目录不存在时被创建.
+// 出错时删除已经写入的临时文件, 但是已经完成改名的文件不会被还原.
+func Files(files map[string][]byte, opt Options) error {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type pending struct {
+		name, tmp string
+	}
+	var todo []pending
+	defer func() {
+		for _, p := range todo {
+			os.Remove(p.tmp)
+		}
+	}()
+
+	for _, name := range names {
+		data := files[name]
+		old, err := ioutil.ReadFile(name)
+		exists := err == nil
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if exists && string(old) == string(data) {
+			continue
+		}
+		if opt.DryRun {
+			w := opt.Diff
+			if w == nil {
+				w = os.Stdout
+			}
+			if _, err = w.Write(Unified(name, old, data)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		perm := os.FileMode(newPerm)
+		if exists {
+			info, err := os.Stat(name)
+			if err != nil {
+				return err
+			}
+			perm = info.Mode().Perm()
+			if opt.Backup {
+				tmp, err := temp(name+".orig", old, perm)
+				if err != nil {
+					return err
+				}
+				todo = append(todo, pending{name + ".orig", tmp})
+			}
+		} else if err = os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+			return err
+		}
+		tmp, err := temp(name, data, perm)
+		if err != nil {
+			return err
+		}
+		todo = append(todo, pending{name, tmp})
+	}
+
+	for len(todo) != 0 {
+		if err := os.Rename(todo[0].tmp, todo[0].name); err != nil {
+			return err
+		}
+		todo = todo[1:]
+	}
+	return nil
+}
+
+// temp 在 name 所在的目录中创建内容为 data, 权限为 perm 的临时文件, 返回它的路径.
+func temp(name string, data []byte, perm os.FileMode) (string, error) {
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp-")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), perm)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
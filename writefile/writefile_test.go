@@ -0,0 +1,42 @@
+package writefile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.zxx")
+	b := filepath.Join(dir, "sub", "b.zxx")
+	if err := ioutil.WriteFile(a, []byte("old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var diff strings.Builder
+	err := Files(map[string][]byte{a: []byte("new\n"), b: []byte("b\n")}, Options{DryRun: true, Diff: &diff})
+	if err != nil || !strings.Contains(diff.String(), "-old\n+new\n") || !strings.Contains(diff.String(), "+b\n") {
+		t.Fatal(diff.String(), err)
+	}
+	if _, err := os.Stat(b); !os.IsNotExist(err) {
+		t.Fatal("dry run wrote", b)
+	}
+
+	if err = Files(map[string][]byte{a: []byte("new\n"), b: []byte("b\n")}, Options{Backup: true}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{a: "new\n", a + ".orig": "old\n", b: "b\n"} {
+		if data, err := ioutil.ReadFile(name); err != nil || string(data) != want {
+			t.Fatal(name, string(data), err)
+		}
+	}
+	if info, err := os.Stat(a); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatal("permission", info.Mode(), err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 3 {
+		t.Fatal("temporary files left", len(entries))
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2016 The Zxx Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// 本包把 zxx 作为脚本语言嵌入 Go 程序.
+//
+//	e := zxx.New()
+//	e.Set("price", 9.5)
+//	e.Set("discount", func(x float64, rate int) float64 { return x * float64(100-rate) / 100 })
+//	v, err := e.Eval("discount(price, 10)")
+//
+// Go 值按 values 包的规则转换, 函数的参数和结果也被转换, 参见 values.FromGo.
+// 源码可以是单个表达式, 也可以是 var, const 声明, 声明的名字保存在 Engine 中,
+// 之后的求值可以使用:
+//
+//	e.Eval("var total = price * 3")
+//	e.Eval("total > 20")
+package zxx
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ZxxLang/zxx/ast"
+	"github.com/ZxxLang/zxx/eval"
+	"github.com/ZxxLang/zxx/parser"
+	"github.com/ZxxLang/zxx/scanner"
+	"github.com/ZxxLang/zxx/token"
+	"github.com/ZxxLang/zxx/values"
+)
+
+// Value 是求值的结果, 参见 eval.Value. 可以用 values.ToGo 转换为 Go 类型.
+type Value = eval.Value
+
+// Engine 保存求值的环境. Engine 不能被多个 goroutine 同时使用.
+type Engine struct {
+	Limits eval.Limits // 每个表达式的限制, New 设置为 eval.DefaultLimits
+	Hooks  *eval.Hooks // 求值的事件回调, 可以为 nil
+
+	env map[string]eval.Value
+}
+
+// New 返回环境为空的 Engine
+func New() *Engine {
+	return &Engine{Limits: eval.DefaultLimits, env: map[string]eval.Value{}}
+}
+
+// Set 把 Go 值 x 转换后以 name 保存到环境中.
+func (e *Engine) Set(name string, x interface{}) error {
+	v, err := values.FromGo(x)
+	if err != nil {
+		return err
+	}
+	e.env[name] = v
+	return nil
+}
+
+// Get 返回环境中名为 name 的值
+func (e *Engine) Get(name string) (Value, bool) {
+	v, ok := e.env[name]
+	return v, ok
+}
+
+// Eval 求值 src. src 是表达式时返回它的值,
+// 是 var, const 声明时按顺序求值并保存声明的名字, 返回最后一个名字的值.
+// 出错时, 之前的声明已经保存, eval.Error 的 Offset 是 src 中的偏移量.
+func (e *Engine) Eval(src string) (Value, error) {
+	if !isDecl(src) {
+		return e.expr(src, 0)
+	}
+	file, err := parser.ParseSyntax([]byte(src))
+	if err != nil {
+		return nil, err
+	}
+
+	var last Value
+	for _, decl := range file.Decls {
+		d, ok := decl.(*ast.GenDecl)
+		if !ok || d.Tok != token.VAR && d.Tok != token.CONST {
+			return nil, errors.New("zxx: only var and const declarations are supported: " +
+				strings.TrimSpace(src[decl.Pos():decl.End()]))
+		}
+		for _, spec := range d.Specs {
+			if len(spec.Values) != len(spec.Names) {
+				return nil, errors.New("zxx: declaration needs one value per name: " +
+					strings.TrimSpace(src[spec.Pos():spec.End()]))
+			}
+			for i, x := range spec.Values {
+				v, err := e.expr(src[x.Pos():x.End()], int(x.Pos()))
+				if err != nil {
+					return nil, err
+				}
+				e.env[spec.Names[i].Name.Source] = v
+				last = v
+			}
+		}
+	}
+	return last, nil
+}
+
+// Call 调用环境中名为 fn 的函数, args 是 Go 值.
+func (e *Engine) Call(fn string, args ...interface{}) (Value, error) {
+	f, ok := e.env[fn].(eval.Func)
+	if !ok {
+		return nil, errors.New("zxx: " + fn + " is not a function")
+	}
+	list := make([]eval.Value, len(args))
+	for i, x := range args {
+		v, err := values.FromGo(x)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	v, err := f(list...)
+	if err != nil {
+		return nil, err
+	}
+	return eval.Normalize(v), nil
+}
+
+// expr 求值位于 src 中偏移量 offset 的表达式 code
+func (e *Engine) expr(code string, offset int) (Value, error) {
+	p, err := e.Limits.Compile(code)
+	if err == nil {
+		var v Value
+		if v, err = p.EvalHooks(e.env, e.Hooks); err == nil {
+			return v, nil
+		}
+	}
+	if x, ok := err.(*eval.Error); ok {
+		x.Offset += offset
+	}
+	return nil, err
+}
+
+// isDecl 返回 src 是否以声明开始
+func isDecl(src string) bool {
+	decl := false
+	parser.FastExpr([]byte(src), func(_ scanner.Pos, tok token.Token, _ string) error {
+		if ast.IsTrivia(tok) {
+			return nil
+		}
+		decl = tok.As(token.Declare)
+		return errStop
+	})
+	return decl
+}
+
+var errStop = errors.New("stop")
@@ -0,0 +1,68 @@
+package zxx_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ZxxLang/zxx"
+	"github.com/ZxxLang/zxx/eval"
+)
+
+type item struct {
+	Name  string  `zxx:"name"`
+	Price float64 `zxx:"price"`
+}
+
+func TestEngine(t *testing.T) {
+	e := zxx.New()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(e.Set("item", item{"pen", 2.5}))
+	must(e.Set("discount", func(x float64, rate int) float64 { return x * float64(100-rate) / 100 }))
+	must(e.Set("join", func(sep string, list ...string) string { return strings.Join(list, sep) }))
+	must(e.Set("fail", func() error { return errors.New("boom") }))
+
+	v, err := e.Eval("discount(item.price * 4, 10)")
+	if err != nil || v != 9.0 {
+		t.Fatal(v, err)
+	}
+	if v, err = e.Eval("join('-', item.name, 'a', 'b')"); err != nil || v != "pen-a-b" {
+		t.Fatal(v, err)
+	}
+
+	if v, err = e.Eval("var total = item.price * 2\nconst (\n\tlimit = 4\n)\n"); err != nil || v != int64(4) {
+		t.Fatal(v, err)
+	}
+	if v, err = e.Eval("total > limit"); err != nil || v != true {
+		t.Fatal(v, err)
+	}
+	if v, ok := e.Get("total"); !ok || v != 5.0 {
+		t.Fatal(v)
+	}
+
+	if v, err = e.Call("discount", 50, 20); err != nil || v != 40.0 {
+		t.Fatal(v, err)
+	}
+	if _, err = e.Call("total"); err == nil {
+		t.Fatal("called non-function")
+	}
+	if _, err = e.Eval("fail()"); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatal(err)
+	}
+	if _, err = e.Eval("discount('x', 1)"); err == nil {
+		t.Fatal("converted string to float64")
+	}
+
+	_, err = e.Eval("var a = 1\nvar b = nope")
+	if x, ok := err.(*eval.Error); !ok || x.Offset != len("var a = 1\nvar b = ") {
+		t.Fatal(err)
+	}
+	if _, err = e.Eval("proc main() [\n]\n"); err == nil {
+		t.Fatal("proc accepted")
+	}
+}